@@ -0,0 +1,50 @@
+package cleanup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/systemd"
+)
+
+type PathUnitsCleanup struct {
+	unitsPath string
+}
+
+func init() {
+	AddCleaner(&PathUnitsCleanup{unitsPath: filepath.Join(config.ContainerBasePath(), "units")})
+}
+
+// Remove the path units that restart containers whose unit file no longer
+// exists.
+func (r *PathUnitsCleanup) Clean(ctx *CleanerContext) {
+	ctx.LogInfo.Println("--- PATH UNITS CLEANUP ---")
+
+	paths, err := filepath.Glob(filepath.Join(r.unitsPath, "*", "*.path"))
+	if err != nil {
+		ctx.LogError.Printf("Failed to find path units in %s: %v", r.unitsPath, err)
+		return
+	}
+
+	for _, path := range paths {
+		unitPath := strings.TrimSuffix(path, ".path") + ".service"
+		if fileExist(unitPath) {
+			continue
+		}
+
+		ctx.LogInfo.Printf("Removing path unit %s as its container unit %s does not exist", path, filepath.Base(unitPath))
+		if ctx.DryRun {
+			continue
+		}
+
+		systemd.StartConnection()
+		if _, err := systemd.Connection().DisableUnitFiles([]string{path}, false); err != nil {
+			ctx.LogError.Printf("Failed to disable %s: %v", path, err)
+		}
+		if err := os.Remove(path); err != nil {
+			ctx.LogError.Printf("Failed to remove %s: %v", path, err)
+		}
+	}
+}
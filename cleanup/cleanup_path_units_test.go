@@ -0,0 +1,46 @@
+package cleanup
+
+import (
+	gocheck "launchpad.net/gocheck"
+	"os"
+	"path/filepath"
+)
+
+type CleanupPathUnitsTestSuite struct{}
+
+var _ = gocheck.Suite(&CleanupPathUnitsTestSuite{})
+
+func (s *CleanupPathUnitsTestSuite) SetUpTest(c *gocheck.C) {
+	os.MkdirAll(filepath.Join(basePath, "units", "te"), (os.FileMode)(0775))
+}
+
+func (s *CleanupPathUnitsTestSuite) TearDownTest(c *gocheck.C) {
+	os.RemoveAll(basePath)
+}
+
+func createFile(c *gocheck.C, path string) {
+	file, err := os.Create(path)
+	c.Assert(err, gocheck.IsNil)
+	file.Close()
+}
+
+func (s *CleanupPathUnitsTestSuite) Test_PathUnitsCleanup_Clean(c *gocheck.C) {
+	unitFile := filepath.Join(basePath, "units", "te", "ctr-test-service.service")
+	pathUnit := filepath.Join(basePath, "units", "te", "ctr-test-service.path")
+	orphanedPathUnit := filepath.Join(basePath, "units", "te", "ctr-test-removed.path")
+	for _, path := range []string{unitFile, pathUnit, orphanedPathUnit} {
+		createFile(c, path)
+	}
+
+	context, _, _ := newContext(true, false)
+	plugin := &PathUnitsCleanup{unitsPath: filepath.Join(basePath, "units")}
+	plugin.Clean(context)
+	c.Assert(fileExist(orphanedPathUnit), gocheck.Equals, true, gocheck.Commentf("A dry run removed %s", orphanedPathUnit))
+
+	context, info, _ := newContext(false, false)
+	plugin.Clean(context)
+
+	c.Assert(fileExist(pathUnit), gocheck.Equals, true, gocheck.Commentf("pathUnit: %s", pathUnit))
+	c.Assert(fileExist(orphanedPathUnit), gocheck.Equals, false, gocheck.Commentf("orphanedPathUnit: %s", orphanedPathUnit))
+	c.Assert(info.String(), gocheck.Matches, "(?s).*Removing path unit .*ctr-test-removed.path.*")
+}
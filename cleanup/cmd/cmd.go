@@ -6,11 +6,13 @@ import (
 	"os"
 
 	"github.com/openshift/geard/cleanup"
+	gcmd "github.com/openshift/geard/cmd"
 )
 
 var (
 	dryRun bool
 	repair bool
+	yes    bool
 )
 
 func RegisterCleanup(parent *cobra.Command) {
@@ -22,10 +24,19 @@ func RegisterCleanup(parent *cobra.Command) {
 	}
 	cleanCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "List the cleanups, but do not execute.")
 	cleanCmd.Flags().BoolVarP(&repair, "repair", "", false, "Perform potentially unrecoverable cleanups.")
+	gcmd.AddConfirmFlag(cleanCmd, &yes)
 	parent.AddCommand(cleanCmd)
 }
 
 func clean(cmd *cobra.Command, args []string) {
+	if !dryRun {
+		if repair {
+			gcmd.RequireConfirmation(yes, "This will remove failed containers and leftover files from this host, including cleanups that can't be undone")
+		} else {
+			gcmd.RequireConfirmation(yes, "This will remove failed containers and leftover files from this host")
+		}
+	}
+
 	logInfo := log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime)
 	logError := log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime)
 
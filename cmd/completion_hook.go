@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/openshift/geard/utils"
+	"github.com/spf13/cobra"
+)
+
+// Programs run on the client once a command has finished, set with
+// --on-success and --on-failure.  Unlike the failure hook of the daemon,
+// which runs on the server for each job that fails, a completion hook runs
+// once with the outcome of every container the command acted on.
+type CompletionHooks struct {
+	OnSuccess string
+	OnFailure string
+}
+
+// Register --on-success and --on-failure on a command.
+func AddCompletionHookFlags(c *cobra.Command, h *CompletionHooks) {
+	c.Flags().StringVar(&h.OnSuccess, "on-success", "", "Run this program on the client once the command has finished, if every container succeeded, with the outcome and the ids of the containers")
+	c.Flags().StringVar(&h.OnFailure, "on-failure", "", "Run this program on the client once the command has finished, if any container failed, with the outcome and the ids of the containers")
+}
+
+// The result of a command passed to a completion hook.  Ids are sorted,
+// and errors have secret values masked.
+type CommandOutcome struct {
+	Command   string
+	Succeeded []string
+	Failed    []string
+	Errors    []string
+}
+
+func (o CommandOutcome) Success() bool {
+	return len(o.Failed) == 0 && len(o.Errors) == 0
+}
+
+func NewCommandOutcome(command string, outcome ExecutorOutcome) CommandOutcome {
+	o := CommandOutcome{
+		Command:   command,
+		Succeeded: make([]string, 0, len(outcome.Succeeded)),
+		Failed:    make([]string, 0, len(outcome.Failed)),
+		Errors:    make([]string, 0, len(outcome.Failures)),
+	}
+	for i := range outcome.Succeeded {
+		o.Succeeded = append(o.Succeeded, progressName(outcome.Succeeded[i]))
+	}
+	for i := range outcome.Failed {
+		o.Failed = append(o.Failed, progressName(outcome.Failed[i]))
+	}
+	for i := range outcome.Failures {
+		o.Errors = append(o.Errors, utils.MaskSecrets(outcome.Failures[i].Error()))
+	}
+	sort.Strings(o.Succeeded)
+	sort.Strings(o.Failed)
+	return o
+}
+
+// A function for Executor.OnComplete that runs the hook for the outcome of
+// the command, or nil if no hook is set.  A hook that fails is reported on
+// stderr but does not change the exit code of the command.
+func (h CompletionHooks) After(command string) func(ExecutorOutcome) {
+	if h.OnSuccess == "" && h.OnFailure == "" {
+		return nil
+	}
+	return func(outcome ExecutorOutcome) {
+		if err := h.Run(NewCommandOutcome(command, outcome)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		}
+	}
+}
+
+// Run the hook for the outcome, if one is set, and wait for it to exit.
+// The program is passed 'success' or 'failure', the command, and the ids
+// of every container as arguments, and the same in GEAR_OUTCOME,
+// GEAR_COMMAND, GEAR_SUCCEEDED_IDS and GEAR_FAILED_IDS (separated by
+// spaces) along with GEAR_ERRORS (one per line).  Its output is written to
+// stderr, so it doesn't mix with the output of the command.
+func (h CompletionHooks) Run(o CommandOutcome) error {
+	path, result, flag := h.OnSuccess, "success", "--on-success"
+	if !o.Success() {
+		path, result, flag = h.OnFailure, "failure", "--on-failure"
+	}
+	if path == "" {
+		return nil
+	}
+
+	args := append([]string{result, o.Command}, o.Succeeded...)
+	args = append(args, o.Failed...)
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(os.Environ(),
+		"GEAR_OUTCOME="+result,
+		"GEAR_COMMAND="+o.Command,
+		"GEAR_SUCCEEDED_IDS="+strings.Join(o.Succeeded, " "),
+		"GEAR_FAILED_IDS="+strings.Join(o.Failed, " "),
+		"GEAR_ERRORS="+strings.Join(o.Errors, "\n"),
+	)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("The %s hook %s failed: %s", flag, path, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/transport"
+	"github.com/openshift/geard/utils"
+)
+
+// Fails the jobs of hosts named "down" with an error that includes a secret
+type hookTransport struct{}
+
+func (t hookTransport) LocatorFor(value string) (transport.Locator, error) {
+	return progressLocator(value), nil
+}
+func (t hookTransport) RemoteJobFor(locator transport.Locator, job interface{}) (jobs.Job, error) {
+	return jobs.JobFunction(func(res jobs.Response) {
+		if locator.String() == "down" {
+			res.Failure(errors.New("the registry rejected the token hook-s3cret"))
+			return
+		}
+		res.Success(jobs.ResponseOk)
+	}), nil
+}
+
+// A hook that writes its arguments and environment to a file, which is
+// returned with the path of the hook.
+func writeHook(t *testing.T, dir, name string) (string, string) {
+	out := filepath.Join(dir, name+".out")
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\n" +
+		"echo \"args=$*\" > " + out + "\n" +
+		"echo \"outcome=$GEAR_OUTCOME command=$GEAR_COMMAND\" >> " + out + "\n" +
+		"echo \"succeeded=$GEAR_SUCCEEDED_IDS failed=$GEAR_FAILED_IDS\" >> " + out + "\n" +
+		"echo \"errors=$GEAR_ERRORS\" >> " + out + "\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Unable to write the hook: %v", err)
+	}
+	return path, out
+}
+
+func TestCompletionHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-hooks")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	utils.AddSecretValue("hook-s3cret")
+
+	onSuccess, successOut := writeHook(t, dir, "on-success")
+	onFailure, failureOut := writeHook(t, dir, "on-failure")
+	hooks := CompletionHooks{OnSuccess: onSuccess, OnFailure: onFailure}
+
+	run := func(hosts ...string) {
+		on := Locators{}
+		for i := range hosts {
+			on = append(on, &ResourceLocator{ResourceTypeContainer, "test-" + string(rune('a'+i)), progressLocator(hosts[i])})
+		}
+		Executor{
+			On:         on,
+			Serial:     func(Locator) JobRequest { return nil },
+			OnComplete: hooks.After("install"),
+			Transport:  hookTransport{},
+		}.Stream()
+	}
+
+	run("up", "up")
+	data, err := ioutil.ReadFile(successOut)
+	if err != nil {
+		t.Fatalf("Expected the success hook to run: %v", err)
+	}
+	expected := "args=success install up/test-a up/test-b\n" +
+		"outcome=success command=install\n" +
+		"succeeded=up/test-a up/test-b failed=\n" +
+		"errors=\n"
+	if string(data) != expected {
+		t.Errorf("Expected the success hook to be passed:\n%s\ngot:\n%s", expected, string(data))
+	}
+	if _, err := os.Stat(failureOut); !os.IsNotExist(err) {
+		t.Errorf("Expected the failure hook to not run on success, got %v", err)
+	}
+	os.Remove(successOut)
+
+	run("up", "down", "up")
+	data, err = ioutil.ReadFile(failureOut)
+	if err != nil {
+		t.Fatalf("Expected the failure hook to run: %v", err)
+	}
+	expected = "args=failure install up/test-a up/test-c down/test-b\n" +
+		"outcome=failure command=install\n" +
+		"succeeded=up/test-a up/test-c failed=down/test-b\n" +
+		"errors=the registry rejected the token " + utils.MaskedValue + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected the failure hook to be passed:\n%s\ngot:\n%s", expected, string(data))
+	}
+	if _, err := os.Stat(successOut); !os.IsNotExist(err) {
+		t.Errorf("Expected the success hook to not run on failure, got %v", err)
+	}
+}
+
+func TestCompletionHooksUnset(t *testing.T) {
+	if (CompletionHooks{}).After("install") != nil {
+		t.Error("Expected no completion function without hooks")
+	}
+	hooks := CompletionHooks{OnFailure: "/nonexistent/hook"}
+	if err := hooks.Run(CommandOutcome{Command: "start", Succeeded: []string{"test-a"}}); err != nil {
+		t.Errorf("Expected a success without an --on-success hook to run nothing, got %v", err)
+	}
+	if err := hooks.Run(CommandOutcome{Command: "start", Failed: []string{"test-a"}}); err == nil || !strings.Contains(err.Error(), "--on-failure hook /nonexistent/hook failed") {
+		t.Errorf("Expected a hook that can't run to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var ErrNotConfirmed = errors.New("Aborted, nothing was changed.")
+
+// Register --yes (-y) on a destructive command, to take the action without
+// asking.
+func AddConfirmFlag(c *cobra.Command, yes *bool) {
+	c.Flags().BoolVarP(yes, "yes", "y", false, "Don't ask for confirmation.  Required when the command is not run from a terminal.")
+}
+
+// Ask whether to go ahead with a destructive action, described by the
+// formatted message such as "This will remove 3 containers", unless yes is
+// set.  The user is only asked when stdin is a terminal; otherwise no one
+// can answer and an error asking for --yes is returned.
+func Confirm(yes bool, format string, args ...interface{}) error {
+	return confirm(os.Stdin, os.Stdout, yes, fmt.Sprintf(format, args...))
+}
+
+// True if r is a terminal a user can answer from.  Replaced in tests.
+var isInputTerminal = func(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && isTerminal(f)
+}
+
+func confirm(in io.Reader, out io.Writer, yes bool, message string) error {
+	if yes {
+		return nil
+	}
+	if !isInputTerminal(in) {
+		return fmt.Errorf("%s.  Pass --yes to confirm when not running in a terminal.", message)
+	}
+	fmt.Fprintf(out, "%s, continue? [y/N] ", message)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return ErrNotConfirmed
+}
+
+// Confirm, or exit if the action is not confirmed.
+func RequireConfirmation(yes bool, format string, args ...interface{}) {
+	if err := Confirm(yes, format, args...); err != nil {
+		Fail(ExitFailure, "%s", err.Error())
+	}
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConfirmYes(t *testing.T) {
+	for _, terminal := range []bool{true, false} {
+		previous := isInputTerminal
+		isInputTerminal = func(io.Reader) bool { return terminal }
+		out := &bytes.Buffer{}
+		err := confirm(strings.NewReader("n\n"), out, true, "This will remove 2 containers")
+		isInputTerminal = previous
+		if err != nil {
+			t.Errorf("Expected --yes to confirm without asking, got %v", err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("Expected nothing to be asked with --yes, got %q", out.String())
+		}
+	}
+}
+
+func TestConfirmNotTerminal(t *testing.T) {
+	previous := isInputTerminal
+	defer func() { isInputTerminal = previous }()
+	isInputTerminal = func(io.Reader) bool { return false }
+
+	out := &bytes.Buffer{}
+	err := confirm(strings.NewReader("y\n"), out, false, "This will remove 2 containers")
+	if err == nil || !strings.Contains(err.Error(), "--yes") || !strings.HasPrefix(err.Error(), "This will remove 2 containers") {
+		t.Errorf("Expected the action to be refused with guidance to pass --yes, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing to be asked without a terminal, got %q", out.String())
+	}
+}
+
+func TestConfirmTerminal(t *testing.T) {
+	previous := isInputTerminal
+	defer func() { isInputTerminal = previous }()
+	isInputTerminal = func(io.Reader) bool { return true }
+
+	for answer, confirmed := range map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		" y ":   true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+		"sure":  false,
+	} {
+		out := &bytes.Buffer{}
+		err := confirm(strings.NewReader(answer), out, false, "This will remove 1 container")
+		if out.String() != "This will remove 1 container, continue? [y/N] " {
+			t.Errorf("Expected the user to be asked, got %q", out.String())
+		}
+		if confirmed && err != nil {
+			t.Errorf("Expected %q to confirm, got %v", answer, err)
+		}
+		if !confirmed && err != ErrNotConfirmed {
+			t.Errorf("Expected %q not to confirm, got %v", answer, err)
+		}
+	}
+}
+
+func TestConfirmChecksInput(t *testing.T) {
+	previous := isTerminal
+	defer func() { isTerminal = previous }()
+	isTerminal = func(io.Writer) bool { return true }
+
+	// stdout may be a terminal while the answer would be read from a pipe
+	err := confirm(strings.NewReader("y\n"), &bytes.Buffer{}, false, "This will remove 1 container")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("Expected input that isn't a terminal to be refused, got %v", err)
+	}
+}
@@ -43,6 +43,49 @@ type Executor struct {
 	OnSuccess FuncReact
 	// Optional: respond to errors when they occur
 	OnFailure FuncReact
+	// Optional: the most destinations to act on at once, all of them if
+	// zero
+	Limit int
+	// Optional: the most jobs to run at once across every destination,
+	// DefaultMaxInFlight if zero.  Unlike Limit, a destination only holds
+	// a slot while one of its jobs runs, so a host with many jobs does not
+	// keep others waiting until all of them finish.
+	MaxInFlight int
+	// Optional: sent an event as each job starts and finishes, and closed
+	// once every job has finished
+	Events chan<- ExecutorEvent
+	// Optional: called once every job has finished with the destinations
+	// that succeeded and failed
+	OnComplete func(ExecutorOutcome)
+}
+
+// The most jobs an executor runs at once when it does not set MaxInFlight,
+// as set by 'gear --max-in-flight'.  Zero or less places no cap.
+var DefaultMaxInFlight int
+
+type ExecutorEventType int
+
+const (
+	JobStarted ExecutorEventType = iota
+	JobFinished
+)
+
+// A job of an executor changed state.  A job created with Group is
+// reported against the first of its locators.
+type ExecutorEvent struct {
+	Type    ExecutorEventType
+	Locator Locator
+	// The result of the job, once it has finished
+	Response *CliJobResponse
+}
+
+// The destinations an executor acted on, split by whether their job
+// succeeded.  The destinations of a job created with Group share its
+// result.
+type ExecutorOutcome struct {
+	Succeeded Locators
+	Failed    Locators
+	Failures  []error
 }
 
 // Invoke the appropriate job on each server and return the set of data
@@ -53,17 +96,21 @@ func (e Executor) Gather() (data []interface{}, failures []error) {
 	data = make([]interface{}, 0, len(e.On))
 	failures = make([]error, 0)
 
-	responses, err := e.run(true)
+	results, err := e.run(true)
+	e.complete(results, err)
 	if err != nil {
 		failures = append(failures, err)
 		return
 	}
-	for i := range responses {
-		if responses[i].Error != nil {
-			failures = append(failures, responses[i].Error)
+	for i := range results {
+		if results[i].Response.Error != nil {
+			failures = append(failures, results[i].Response.Error)
+			continue
+		}
+		if results[i].Response.NotModified {
 			continue
 		}
-		datum := responses[i].Data
+		datum := results[i].Response.Data
 		if datum == nil {
 			failures = append(failures, errors.New(fmt.Sprintf("Response %d did not return any data", i)))
 			continue
@@ -80,14 +127,15 @@ func (e Executor) Stream() (failures []error) {
 	}
 	failures = make([]error, 0)
 
-	responses, err := e.run(false)
+	results, err := e.run(false)
+	e.complete(results, err)
 	if err != nil {
 		failures = append(failures, err)
 		return
 	}
-	for i := range responses {
-		if responses[i].Error != nil {
-			failures = append(failures, responses[i].Error)
+	for i := range results {
+		if results[i].Response.Error != nil {
+			failures = append(failures, results[i].Response.Error)
 		}
 	}
 	return
@@ -100,16 +148,25 @@ func (e Executor) StreamAndExit() {
 				fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i].Error())
 			}
 		}
-		os.Exit(1)
+		os.Exit(ExitCodeFor(errors...))
 	}
 	os.Exit(0)
 }
 
-func (e *Executor) run(gather bool) ([]*CliJobResponse, error) {
+// The response to a job and the destinations it acted on
+type jobResult struct {
+	On       Locators
+	Response *CliJobResponse
+}
+
+func (e *Executor) run(gather bool) ([]jobResult, error) {
+	if e.Events != nil {
+		defer close(e.Events)
+	}
 	on := e.On
 	remote := on.Group()
 	single := len(on) == 1
-	responses := []*CliJobResponse{}
+	responses := []jobResult{}
 
 	// Check each job first, return the first error (coding bugs)
 	byDestination := make([]requestedJobs, len(remote))
@@ -129,9 +186,21 @@ func (e *Executor) run(gather bool) ([]*CliJobResponse, error) {
 		}
 	}
 
-	respch := make(chan *CliJobResponse, len(on))
+	respch := make(chan jobResult, len(on))
 	tasks := &sync.WaitGroup{}
 	stdout := log.New(e.Output, "", 0)
+	var limit chan struct{}
+	if e.Limit > 0 {
+		limit = make(chan struct{}, e.Limit)
+	}
+	maxInFlight := e.MaxInFlight
+	if maxInFlight == 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	var inFlight chan struct{}
+	if maxInFlight > 0 {
+		inFlight = make(chan struct{}, maxInFlight)
+	}
 
 	// Executes jobs against each destination in parallel, but serial on each destination.
 	for i := range byDestination {
@@ -143,11 +212,23 @@ func (e *Executor) run(gather bool) ([]*CliJobResponse, error) {
 			w := logstreamer.NewLogstreamer(stdout, prefixUnless(host.String()+" ", single), false)
 			defer w.Close()
 			defer tasks.Done()
+			if limit != nil {
+				limit <- struct{}{}
+				defer func() { <-limit }()
+			}
 
 			for _, job := range allJobs {
+				if inFlight != nil {
+					inFlight <- struct{}{}
+				}
 				response := &CliJobResponse{Output: w, Gather: gather}
+				e.event(JobStarted, job.Locator, nil)
 				job.Job.Execute(response)
-				respch <- e.react(response, w, job.Request)
+				if inFlight != nil {
+					<-inFlight
+				}
+				respch <- jobResult{job.On, e.react(response, w, job.Request)}
+				e.event(JobFinished, job.Locator, response)
 			}
 		}()
 	}
@@ -176,6 +257,34 @@ func (e *Executor) react(response *CliJobResponse, w io.Writer, job JobRequest)
 	return response
 }
 
+// Pass the outcome of the jobs to OnComplete.  If the jobs could not be
+// run, every destination failed with err.
+func (e *Executor) complete(results []jobResult, err error) {
+	if e.OnComplete == nil {
+		return
+	}
+	outcome := ExecutorOutcome{}
+	if err != nil {
+		outcome.Failed = append(outcome.Failed, e.On...)
+		outcome.Failures = append(outcome.Failures, err)
+	}
+	for i := range results {
+		if results[i].Response.Error != nil {
+			outcome.Failed = append(outcome.Failed, results[i].On...)
+			outcome.Failures = append(outcome.Failures, results[i].Response.Error)
+		} else {
+			outcome.Succeeded = append(outcome.Succeeded, results[i].On...)
+		}
+	}
+	e.OnComplete(outcome)
+}
+
+func (e *Executor) event(t ExecutorEventType, locator Locator, response *CliJobResponse) {
+	if e.Events != nil {
+		e.Events <- ExecutorEvent{t, locator, response}
+	}
+}
+
 // Find all jobs that apply for these locators.
 func (e *Executor) requests(on []Locator) requestedJobs {
 	if (e.Serial == nil && e.Group == nil) || (e.Serial != nil && e.Group != nil) {
@@ -186,12 +295,12 @@ func (e *Executor) requests(on []Locator) requestedJobs {
 		return requestedJobs{}
 	}
 	if e.Group != nil {
-		return requestedJobs{requestedJob{Request: e.Group(on...), Locator: on[0]}}
+		return requestedJobs{requestedJob{Request: e.Group(on...), Locator: on[0], On: on}}
 	}
 
 	jobs := make(requestedJobs, 0, len(on))
 	for i := range on {
-		jobs = append(jobs, requestedJob{Request: e.Serial(on[i]), Locator: on[i]})
+		jobs = append(jobs, requestedJob{Request: e.Serial(on[i]), Locator: on[i], On: Locators{on[i]}})
 	}
 	return jobs
 }
@@ -201,20 +310,61 @@ type requestedJob struct {
 	Request JobRequest
 	Job     jobs.Job
 	Locator Locator
+	// Every locator the job acts on
+	On Locators
 }
 
-func (jobs requestedJobs) check() error {
-	for i := range jobs {
-		job := jobs[i]
+// A request that fails its check is invalid, whatever the error.
+func (requests requestedJobs) check() error {
+	for i := range requests {
+		job := requests[i]
 		if check, ok := job.Request.(check); ok {
 			if err := check.Check(); err != nil {
-				return err
+				if _, ok := err.(jobs.JobError); ok {
+					return err
+				}
+				return jobs.SimpleError{jobs.ResponseInvalidRequest, err.Error()}
 			}
 		}
 	}
 	return nil
 }
 
+// Exit codes that distinguish the kind of failure of a job
+const (
+	ExitFailure  = 1
+	ExitInvalid  = 2
+	ExitNotFound = 3
+	ExitConflict = 4
+)
+
+// The exit code for a set of failed jobs.  If every failure is of the same
+// kind its code is returned, otherwise ExitFailure.
+func ExitCodeFor(failures ...error) int {
+	if len(failures) == 0 {
+		return 0
+	}
+	code := exitCodeFor(failures[0])
+	for i := range failures[1:] {
+		if exitCodeFor(failures[i+1]) != code {
+			return ExitFailure
+		}
+	}
+	return code
+}
+
+func exitCodeFor(err error) int {
+	switch jobs.FailureFor(err) {
+	case jobs.ResponseInvalidRequest:
+		return ExitInvalid
+	case jobs.ResponseNotFound:
+		return ExitNotFound
+	case jobs.ResponseAlreadyExists:
+		return ExitConflict
+	}
+	return ExitFailure
+}
+
 func Fail(code int, format string, other ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, other...)
 	if !strings.HasSuffix(format, "\n") {
@@ -130,3 +130,59 @@ func TestShoulCheckContainerArgsArgs(t *testing.T) {
 	}
 
 }
+
+func TestExitCodeFor(t *testing.T) {
+	for _, test := range []struct {
+		errs []error
+		code int
+	}{
+		{nil, 0},
+		{[]error{jobs.ErrNotFound}, ExitNotFound},
+		{[]error{jobs.ErrConflict, jobs.NewConflictError("exists")}, ExitConflict},
+		{[]error{jobs.ErrInvalid}, ExitInvalid},
+		{[]error{jobs.ErrNotFound, jobs.ErrConflict}, ExitFailure},
+		{[]error{fmt.Errorf("unknown")}, ExitFailure},
+	} {
+		if code := ExitCodeFor(test.errs...); code != test.code {
+			t.Errorf("Expected %v to exit with %d, got %d", test.errs, test.code, code)
+		}
+	}
+}
+
+func TestMaxInFlightAcrossHosts(t *testing.T) {
+	values := []string{}
+	for i := 1; i <= 20; i++ {
+		values = append(values, fmt.Sprintf("host%d/web-1", i), fmt.Sprintf("host%d/web-2", i))
+	}
+
+	for _, gather := range []bool{true, false} {
+		trans := &fleetTransport{}
+		locators, err := NewContainerLocators(trans, values...)
+		if err != nil {
+			t.Fatalf("Unable to locate the containers: %v", err)
+		}
+		e := Executor{
+			On: locators,
+			Serial: func(on Locator) JobRequest {
+				return &cjobs.ContainerStatusRequest{Id: AsIdentifier(on)}
+			},
+			Transport:   trans,
+			MaxInFlight: 3,
+		}
+		if gather {
+			e.Gather()
+		} else {
+			e.Stream()
+		}
+
+		if len(trans.answered) != len(values) {
+			t.Errorf("Expected every job to run once, got %d", len(trans.answered))
+		}
+		if trans.peak > 3 {
+			t.Errorf("Expected at most 3 jobs at once across all hosts (gather %t), got %d", gather, trans.peak)
+		}
+		if trans.peak < 2 {
+			t.Errorf("Expected jobs on different hosts to run at the same time (gather %t), got %d", gather, trans.peak)
+		}
+	}
+}
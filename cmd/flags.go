@@ -3,11 +3,15 @@ package cmd
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/port"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
+	"time"
 )
 
 func GenerateId() string {
@@ -71,27 +75,267 @@ func (n *NetworkLinks) Set(s string) error {
 	return nil
 }
 
+// A flag that may be repeated, each value a <key>=<value> label
+type Labels struct {
+	containers.Labels
+}
+
+func (l *Labels) String() string {
+	return l.Labels.String()
+}
+
+func (l *Labels) Set(s string) error {
+	key, value, err := containers.NewLabelFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	if l.Labels == nil {
+		l.Labels = make(containers.Labels)
+	}
+	l.Labels[key] = value
+	return nil
+}
+
+// A flag that may be repeated, each value a <name>=<value> secret
+type Secrets struct {
+	containers.Secrets
+}
+
+func (s *Secrets) String() string {
+	return s.Secrets.String()
+}
+
+func (s *Secrets) Set(value string) error {
+	env := containers.Environment{}
+	match, err := env.FromString(value)
+	if err == nil && !match {
+		err = fmt.Errorf("The secret '%s' must be of the form <name>=<value>", value)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	s.Secrets = append(s.Secrets, env)
+	return nil
+}
+
+// A flag that may be repeated, each value a <name>=<value> variable that
+// overrides the environment of a container for a single start
+type StartEnvironment struct {
+	containers.StartEnvironment
+}
+
+func (s *StartEnvironment) String() string {
+	values := make([]string, len(s.StartEnvironment))
+	for i := range s.StartEnvironment {
+		values[i] = s.StartEnvironment[i].String()
+	}
+	return strings.Join(values, ",")
+}
+
+func (s *StartEnvironment) Set(value string) error {
+	env := containers.Environment{}
+	match, err := env.FromString(value)
+	if err == nil && !match {
+		err = fmt.Errorf("The variable '%s' must be of the form <name>=<value>", value)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	s.StartEnvironment = append(s.StartEnvironment, env)
+	return nil
+}
+
+// A flag that may be repeated, each value a <name>:<ip> host entry
+type HostEntries struct {
+	containers.HostEntries
+}
+
+func (h *HostEntries) String() string {
+	return h.HostEntries.String()
+}
+
+func (h *HostEntries) Set(s string) error {
+	entry, err := containers.NewHostEntryFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	h.HostEntries = append(h.HostEntries, entry)
+	return nil
+}
+
+// A flag that may be repeated, each value a <name>[:<alias>] link to another
+// container
+type ContainerAliases struct {
+	containers.ContainerAliases
+}
+
+func (a *ContainerAliases) String() string {
+	return a.ContainerAliases.String()
+}
+
+func (a *ContainerAliases) Set(s string) error {
+	link, err := containers.NewContainerAliasFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	a.ContainerAliases = append(a.ContainerAliases, link)
+	return nil
+}
+
+// A flag that may be repeated, each value a <key>=<value> log driver option
+type LogOptions struct {
+	containers.LogOptions
+}
+
+func (o *LogOptions) String() string {
+	return o.LogOptions.String()
+}
+
+func (o *LogOptions) Set(s string) error {
+	option, err := containers.NewLogOptionFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	o.LogOptions = append(o.LogOptions, option)
+	return nil
+}
+
+// A flag that may be repeated, each value a <name>=<soft>[:<hard>] ulimit
+type Ulimits struct {
+	containers.Ulimits
+}
+
+func (u *Ulimits) String() string {
+	return u.Ulimits.String()
+}
+
+func (u *Ulimits) Set(s string) error {
+	limit, err := containers.NewUlimitFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	u.Ulimits = append(u.Ulimits, limit)
+	return nil
+}
+
+// A flag that may be repeated, each value a <section>.<key>=<value>
+// unit directive
+type UnitProperties struct {
+	containers.UnitProperties
+}
+
+func (u *UnitProperties) String() string {
+	values := make([]string, len(u.UnitProperties))
+	for i := range u.UnitProperties {
+		values[i] = u.UnitProperties[i].String()
+	}
+	return strings.Join(values, " ")
+}
+
+func (u *UnitProperties) Set(s string) error {
+	property, err := containers.NewUnitPropertyFromString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return err
+	}
+	u.UnitProperties = append(u.UnitProperties, property)
+	return nil
+}
+
+// A flag that may be repeated, collecting each value in order
+type StringList []string
+
+func (l *StringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *StringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// A flag for a point in time, given as an RFC 3339 time such as
+// 2014-05-01T12:00:00Z or as a duration before now such as 90m
+type SinceTime struct {
+	time.Time
+}
+
+func (t *SinceTime) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (t *SinceTime) Set(s string) error {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return fmt.Errorf("The duration %s must not be negative", s)
+		}
+		t.Time = time.Now().Add(-d)
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("%s must be a duration such as 90m or a time such as 2014-05-01T12:00:00Z", s)
+	}
+	t.Time = parsed
+	return nil
+}
+
 type EnvironmentDescription struct {
 	Description containers.EnvironmentDescription
 	Path        string
+	// If set, the file at Path is rendered as a template with these values
+	ValuesPath string
+	// A JSON object of variables, and the path of a file holding one
+	JSON     string
+	JSONPath string
+	// If set, the path of a schema the environment must match
+	SchemaPath string
 }
 
 func (e *EnvironmentDescription) ExtractVariablesFrom(args *[]string, generateId bool) error {
+	if e.ValuesPath != "" && e.Path == "" {
+		return errors.New("An environment file must be provided to use environment values")
+	}
 	if e.Path != "" {
 		file, err := os.Open(e.Path)
 		if err != nil {
 			return err
 		}
 		defer file.Close()
-		if err := e.Description.ReadFrom(file); err != nil {
+		if e.ValuesPath != "" {
+			values, err := e.readValues()
+			if err != nil {
+				return err
+			}
+			if err := e.Description.ReadTemplateFrom(file, values); err != nil {
+				return err
+			}
+		} else if err := e.Description.ReadDotenvFrom(file); err != nil {
 			return err
 		}
 	}
+	if err := e.readJSON(); err != nil {
+		return err
+	}
 	env, err := containers.ExtractEnvironmentVariablesFrom(args)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Failed to extract env: "+err.Error())
 		return err
 	}
+	if err := readVariableFiles(env); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to extract env: "+err.Error())
+		return err
+	}
 	e.Description.Variables = append(e.Description.Variables, env...)
 	if generateId && !e.Description.Empty() && e.Description.Id == "" {
 		e.Description.Id = containers.Identifier(GenerateId())
@@ -99,3 +343,83 @@ func (e *EnvironmentDescription) ExtractVariablesFrom(args *[]string, generateId
 	}
 	return nil
 }
+
+// Replace each value of the form @<path> with the contents of the file
+// at path, less a trailing newline.  A value starting with \@ is the
+// literal value after the backslash.
+func readVariableFiles(env containers.EnvironmentVariables) error {
+	for i := range env {
+		value := env[i].Value
+		switch {
+		case strings.HasPrefix(value, "\\@"):
+			env[i].Value = value[1:]
+		case strings.HasPrefix(value, "@"):
+			path := value[1:]
+			if path == "" {
+				return fmt.Errorf("The variable %s must name a file after '@', or start with '\\@' for a literal '@'", env[i].Name)
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("Unable to read the value of %s: %v", env[i].Name, err)
+			}
+			value = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+			// environment files hold one variable per line
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("The value of %s read from %s must be a single line", env[i].Name, path)
+			}
+			env[i].Value = value
+			if err := env[i].Check(); err != nil {
+				return fmt.Errorf("The value of %s read from %s is not valid: %v", env[i].Name, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Add the variables of JSONPath and then of JSON, so that each replaces
+// those of the environment file and is replaced by those passed as
+// arguments.
+func (e *EnvironmentDescription) readJSON() error {
+	if e.JSONPath != "" {
+		file, err := os.Open(e.JSONPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		env, err := containers.ReadEnvironmentJSON(file)
+		if err != nil {
+			return fmt.Errorf("Unable to read %s: %s", e.JSONPath, err.Error())
+		}
+		e.Description.Variables = append(e.Description.Variables, env...)
+	}
+	if e.JSON != "" {
+		env, err := containers.ReadEnvironmentJSON(strings.NewReader(e.JSON))
+		if err != nil {
+			return err
+		}
+		e.Description.Variables = append(e.Description.Variables, env...)
+	}
+	return nil
+}
+
+// The schema read from SchemaPath, nil if there is none.
+func (e *EnvironmentDescription) ReadSchema() (*containers.EnvironmentSchema, error) {
+	if e.SchemaPath == "" {
+		return nil, nil
+	}
+	file, err := os.Open(e.SchemaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return containers.ReadEnvironmentSchema(file)
+}
+
+func (e *EnvironmentDescription) readValues() (map[string]string, error) {
+	file, err := os.Open(e.ValuesPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return containers.ReadEnvironmentValues(file)
+}
@@ -2,7 +2,12 @@ package cmd_test
 
 import (
 	. "github.com/openshift/geard/cmd"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGenerateId(t *testing.T) {
@@ -17,7 +22,7 @@ func TestExtractVariablesFrom_NoAppend(t *testing.T) {
 
 	env := EnvironmentDescription{}
 
-	if err := env.ExtractVariablesFrom(args, false); err != nil {
+	if err := env.ExtractVariablesFrom(&args, false); err != nil {
 		t.Error("Unexpected error parsing arguments")
 	}
 
@@ -32,3 +37,98 @@ func TestExtractVariablesFrom_NoAppend(t *testing.T) {
 		t.Error("Incorrect argument parsing")
 	}
 }
+
+func TestExtractVariablesFrom_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-env-file")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cert")
+	ioutil.WriteFile(path, []byte("LS0tLS1CRUdJTi==\n"), 0600)
+
+	args := []string{"arg1", "CERT=@" + path, "EMAIL=\\@example.com", "PLAIN=b"}
+	env := EnvironmentDescription{}
+	if err := env.ExtractVariablesFrom(&args, false); err != nil {
+		t.Fatalf("Unexpected error reading a file value: %v", err)
+	}
+	if len(args) != 1 || len(env.Description.Variables) != 3 {
+		t.Fatalf("Expected three variables, got %v %+v", args, env.Description.Variables)
+	}
+	if v := env.Description.Variables[0]; v.Name != "CERT" || v.Value != "LS0tLS1CRUdJTi==" {
+		t.Errorf("Expected the value to be read from the file, got %+v", v)
+	}
+	if v := env.Description.Variables[1]; v.Value != "@example.com" {
+		t.Errorf("Expected an escaped '@' to be kept literally, got %+v", v)
+	}
+	if v := env.Description.Variables[2]; v.Value != "b" {
+		t.Errorf("Expected a plain value to be unchanged, got %+v", v)
+	}
+}
+
+func TestExtractVariablesFrom_FileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-env-file")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	multiline := filepath.Join(dir, "multiline")
+	ioutil.WriteFile(multiline, []byte("a\nb\n"), 0600)
+	missing := filepath.Join(dir, "missing")
+
+	for value, message := range map[string]string{
+		"@" + missing:   "Unable to read the value of KEY",
+		"@" + multiline: "must be a single line",
+		"@":             "must name a file",
+	} {
+		args := []string{"KEY=" + value}
+		env := EnvironmentDescription{}
+		err := env.ExtractVariablesFrom(&args, false)
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q to fail with %q, got %v", value, message, err)
+		}
+	}
+}
+
+func TestExtractVariablesFrom_JSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-env-json")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "env.json")
+	ioutil.WriteFile(path, []byte(`{"A": "file", "B": "file"}`), 0600)
+
+	args := []string{"arg1", "C=arg"}
+	env := EnvironmentDescription{JSONPath: path, JSON: `{"B": "json", "C": "json"}`}
+	if err := env.ExtractVariablesFrom(&args, false); err != nil {
+		t.Fatalf("Unexpected error reading the JSON environment: %v", err)
+	}
+	if values := env.Description.Map(); len(values) != 3 || values["A"] != "file" || values["B"] != "json" || values["C"] != "arg" {
+		t.Errorf("Expected --env-json to replace --env-json-file and arguments to replace both, got %+v", env.Description.Variables)
+	}
+
+	env = EnvironmentDescription{JSON: `{"PORT": 8080}`}
+	if err := env.ExtractVariablesFrom(&[]string{}, false); err == nil || !strings.Contains(err.Error(), "must be a string") {
+		t.Errorf("Expected a number to be rejected, got %v", err)
+	}
+}
+
+func TestSinceTime(t *testing.T) {
+	since := SinceTime{}
+	before := time.Now()
+	if err := since.Set("90m"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if since.Before(before.Add(-90*time.Minute)) || since.After(time.Now().Add(-90*time.Minute)) {
+		t.Errorf("Expected a time 90 minutes ago, got %v", since.Time)
+	}
+	if err := since.Set("2014-05-01T12:00:00Z"); err != nil || !since.Equal(time.Date(2014, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the time to be parsed, got %v %v", since.Time, err)
+	}
+	for _, s := range []string{"yesterday", "-1h", "2014-05-01"} {
+		if err := since.Set(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
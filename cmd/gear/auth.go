@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/openshift/geard/http"
+	"github.com/openshift/geard/transport"
+)
+
+// Implement the flag.Value interface for a shared API token. The daemon
+// requires the token on mutating requests, and the http transport sends
+// it with every request.
+type AuthTokenFlag struct {
+	token string
+}
+
+func (f *AuthTokenFlag) String() string {
+	if f.token == "" {
+		return ""
+	}
+	return "<hidden>"
+}
+
+func (f *AuthTokenFlag) Set(s string) error {
+	f.token = s
+	if s == "" {
+		conf.Auth = nil
+		return nil
+	}
+	token := http.BearerToken(s)
+	conf.Auth = token
+	if t, ok := transport.GetTransport("http"); ok {
+		if remote, ok := t.(*http.HttpTransport); ok {
+			remote.SetAuthorizer(token)
+		}
+	}
+	return nil
+}
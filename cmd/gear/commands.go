@@ -16,25 +16,125 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 	// "github.com/openshift/geard/encrypted"
 	"github.com/openshift/geard/http"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/port"
 	"github.com/openshift/geard/sti"
+	"github.com/openshift/geard/systemd"
 	"github.com/openshift/geard/transport"
 )
 
+const envJSONUsage = "A JSON object of variables, such as '{\"KEY\":\"value\"}'.  Values must be strings.  Replaces the variables of --env-file, and is replaced by <key>=<value> arguments."
+
+const revisionUsage = "The deployment or release the containers are installed from, such as a commit or v1.4.2.  Shown by status and inspect, and kept until the container is reinstalled."
+
 var (
 	follow bool
 
-	resetEnv bool
-
-	start   bool
-	isolate bool
-	sockAct bool
+	resetEnv  bool
+	envSource string
+	envMerge  gcmd.StringList
+	envDiff   bool
+	envReload bool
+
+	ifNoneMatch string
+	ifMatch     string
+	showETag    bool
+	showEnvSize bool
+	envKeys     gcmd.StringList
+	envExport   bool
+	envPrefix   string
+	envSort     string
+	contentType string
+	contentPath string
+
+	start    bool
+	isolate  bool
+	sockAct  bool
+	pullOnly bool
+	scale    int
+	variant  string
+
+	startRetries       int
+	startRetryInterval time.Duration
+
+	pullAtStart bool
+	pullPolicy  string
+	idFile      string
+	watchPath   string
+	requestId   string
+
+	labels     gcmd.Labels
+	labelFile  string
+	selector   string
+	network    string
+	dnsServers gcmd.StringList
+	extraHosts gcmd.HostEntries
+	links      gcmd.ContainerAliases
+
+	envReloadSignal string
+	envFileWatch    string
+	envWatchRestart bool
+
+	unitDescription   string
+	unitDocumentation gcmd.StringList
+
+	revision string
+
+	logDriver  string
+	logOptions gcmd.LogOptions
+	ulimits    gcmd.Ulimits
+	cpusetCPUs string
+	cpusetMems string
+
+	inheritEnv     string
+	decryptInherit string
+
+	entrypoint string
+	runCmd     gcmd.StringList
+	workingDir string
+	secrets    gcmd.Secrets
+	startEnv   gcmd.StartEnvironment
+	unitProps  gcmd.UnitProperties
+	dockerArgs gcmd.StringList
+
+	completionHooks gcmd.CompletionHooks
+
+	interactive bool
+	tty         bool
+
+	outputFormat string
+	jsonLines    bool
+
+	watchStatus   bool
+	watchInterval time.Duration
+	groupVariant  bool
+
+	hostsFile   string
+	concurrency int
+
+	stopTimeout        int
+	installStopTimeout int
+
+	detach   bool
+	priority string
+	yes      bool
+	atomic   bool
+	resetAll bool
+
+	envKeyFile string
+
+	daemonLogsOpts systemd.JournalOptions
 
 	keyPath   string
 	expiresAt int64
@@ -54,17 +154,58 @@ var (
 	writeAccess bool
 	hostIp      string
 
-	insecure   bool
-	timeout    int64
-	listenAddr string
+	insecure      bool
+	timeout       int64
+	listenAddr    string
+	proxyProtocol bool
+	systemdSocket bool
+	maintenance   bool
+	readOnly      bool
+
+	onFailureExec    string
+	onFailureTimeout time.Duration
+
+	installTimeout time.Duration
+	startTimeout   time.Duration
+	serverTimeouts http.ServerTimeouts
+
+	accessLog        bool
+	logFormat        string
+	showSecretValues bool
+
+	unitSections gcmd.StringList
+
+	reassignInternal uint
+
+	buildContext string
+
+	pingTimeout time.Duration
+
+	eventType      string
+	eventContainer string
+
+	historyStatus   string
+	historyType     string
+	historySince    gcmd.SinceTime
+	jobHistoryCount int
+	jobHistoryAge   time.Duration
+
+	reconcileDir      string
+	reconcileInterval time.Duration
+	envWatchInterval  time.Duration
+
+	noTty bool
 
 	defaultTransport LocalTransportFlag
+	authToken        AuthTokenFlag
+	defaultHost      HostFlag
+	runtime          RuntimeFlag
 )
 
 var conf = http.HttpConfiguration{
 	Dispatcher: &dispatcher.Dispatcher{
 		QueueFast:         10,
-		QueueSlow:         1,
+		QueueSlow:         10,
 		Concurrent:        2,
 		TrackDuplicateIds: 1000,
 	},
@@ -85,11 +226,20 @@ func Execute() {
 	}
 	gearCmd.PersistentFlags().StringVar(&(keyPath), "key-path", "", "Specify the directory containing the server private key and trusted client public keys")
 	gearCmd.PersistentFlags().StringVarP(&(conf.Docker.Socket), "docker-socket", "S", "unix:///var/run/docker.sock", "Set the docker socket to use")
+	gearCmd.PersistentFlags().Var(&runtime, "runtime", "The container runtime to use, docker or containerd")
+	gearCmd.PersistentFlags().StringVar(&(containers.ContainerdAddress), "containerd-address", containers.DefaultContainerdAddress, "Set the containerd socket to use with --runtime=containerd")
 	gearCmd.PersistentFlags().BoolVar(&(config.SystemDockerFeatures.EnvironmentFile), "has-env-file", true, "Use --env-file with Docker, set false if older than 0.11")
 	gearCmd.PersistentFlags().BoolVar(&(config.SystemDockerFeatures.ForegroundRun), "has-foreground", false, "(experimental) Use --foreground with Docker, requires alexlarsson/forking-run")
 	gearCmd.PersistentFlags().StringVar(&deploymentPath, "with", "", "Provide a deployment descriptor to operate on")
 	gearCmd.PersistentFlags().Var(&defaultTransport, "transport", "Specify an alternate mechanism to connect to the gear agent")
+	gearCmd.PersistentFlags().VarP(&defaultHost, "host", "H", "The agent to send commands to when no host is named, as tcp://<host>[:<port>], https://<host>[:<port>] or unix://<socket>")
+	gearCmd.PersistentFlags().Var(&defaultHost, "server", "Alias for --host")
+	gearCmd.PersistentFlags().BoolVar(&noTty, "no-tty", false, "Stream the output of commands on several containers rather than showing their progress, even on a terminal")
+	gearCmd.PersistentFlags().IntVar(&gcmd.DefaultMaxInFlight, "max-in-flight", 0, "The most jobs to run at once across all hosts, such as installs pulling from a shared registry (0 for no limit)")
 	gearCmd.PersistentFlags().BoolVarP(&insecure, "insecure", "k", false, "Do not verify CA certificate on SSL connections and transfers")
+	gearCmd.PersistentFlags().Var(&authToken, "auth-token", "A token sent to authenticate API requests. The daemon will require it for every request but /healthz.")
+	gearCmd.PersistentFlags().BoolVar(&detach, "detach", false, "Queue jobs on remote servers and return without waiting for them to complete")
+	gearCmd.PersistentFlags().StringVar(&priority, "priority", "", "Queue jobs on remote servers as 'low', 'normal' or 'high' priority instead of the priority of their type")
 
 	deployCmd := &cobra.Command{
 		Use:   "deploy <file|url> <host>...",
@@ -99,32 +249,77 @@ func Execute() {
 	}
 	deployCmd.Flags().BoolVar(&isolate, "isolate", false, "Use an isolated container running as a user")
 	deployCmd.Flags().Int64VarP(&timeout, "timeout", "", 300, "Number of seconds to wait for HTTP/S server")
+	deployCmd.Flags().BoolVar(&jsonLines, "json-lines", false, "Print each event of the deployment as a line of JSON with a type and payload as it happens")
+	deployCmd.Flags().StringVar(&revision, "revision", "", revisionUsage)
 	gcmd.AddCommand(gearCmd, deployCmd, false)
 
 	installImageCmd := &cobra.Command{
 		Use:   "install <image> <name>... [<env>]",
 		Short: "Install a docker image as a systemd service",
-		Long:  "Install a docker image as one or more systemd services on one or more servers.\n\nSpecify a location on a remote server with <host>[:<port>]/<name> instead of <name>.  The default port is 2223.",
+		Long:  "Install a docker image as one or more systemd services on one or more servers.\n\nSpecify a location on a remote server with <host>[:<port>]/<name> instead of <name>.  The default port is 2223.\n\nUse <name>@<index> or --scale to install numbered instances of a service.  Instances are named <name>-<index>, as systemd reserves '@' for instances of template units.",
 		Run:   installImage,
 	}
-	installImageCmd.Flags().VarP(&portPairs, "ports", "p", "List of comma separated port pairs to bind '<internal>:<external>,...'. Use zero to request a port be assigned.")
-	installImageCmd.Flags().VarP(&networkLinks, "net-links", "n", "List of comma separated port pairs to wire '<local_host>:<local_port>:<remote_host>:<remote_port>,...'. local_host may be empty. It defaults to 127.0.0.1.")
-	installImageCmd.Flags().BoolVar(&start, "start", false, "Start the container immediately")
-	installImageCmd.Flags().BoolVar(&isolate, "isolate", false, "Use an isolated container running as a user")
-	installImageCmd.Flags().BoolVar(&sockAct, "socket-activated", false, "Use a socket-activated container (experimental, requires Docker branch)")
-	installImageCmd.Flags().StringVar(&environment.Path, "env-file", "", "Path to an environment file to load")
+	addInstallUnitFlags(installImageCmd)
+	installImageCmd.Flags().IntVar(&scale, "scale", 0, "Install numbered instances <name>-1 through <name>-N of each name, each assigned its own ports")
+	installImageCmd.Flags().IntVar(&startRetries, "start-retries", 0, "With --start, wait for the container to start and start it again if it fails, up to this many more times")
+	installImageCmd.Flags().DurationVar(&startRetryInterval, "start-retry-interval", 5*time.Second, "How long to wait before starting a container again with --start-retries, in whole seconds")
+	installImageCmd.Flags().StringVar(&variant, "variant", "", "Install a variant of each container, such as 'canary', named <name>--<variant> and run in a slice of its own, alongside the container without a variant")
+	installImageCmd.Flags().StringVar(&buildContext, "build", "", "Build the image with Docker from a tar archive of a build context ('-' to read it from stdin) and install it, instead of passing <image>")
+	installImageCmd.Flags().BoolVar(&pullOnly, "pull-only", false, "Download the image without creating or changing the container")
+	installImageCmd.Flags().StringVar(&pullPolicy, "pull-policy", "", "When to pull the image: 'always' to pull it even if it is present, 'missing' to pull it only if it is absent (the default), or 'never' to fail if it is absent")
+	installImageCmd.Flags().StringVar(&idFile, "id-file", "", "Write the id and unit name of each container that is installed to this file, one per line")
+	installImageCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Print the result of each install - the image, assigned ports, whether it was started, and any error - as 'json'")
+	installImageCmd.Flags().BoolVar(&jsonLines, "json-lines", false, "Print each event of the install as a line of JSON with a type and payload as it happens, ending with an 'installed' event for each container")
 	installImageCmd.Flags().StringVar(&environment.Description.Source, "env-url", "", "A url to download environment files from")
-	installImageCmd.Flags().StringVar((*string)(&environment.Description.Id), "env-id", "", "An optional identifier for the environment being set")
+	gcmd.AddCompletionHookFlags(installImageCmd, &completionHooks)
 	gcmd.AddCommand(gearCmd, installImageCmd, false)
 
+	renderCmd := &cobra.Command{
+		Use:   "render <image> <name> [<env>]",
+		Short: "(Local) Print the systemd unit install would generate",
+		Long:  "Print the systemd unit file that installing the image as <name> with the same flags would generate, without contacting a server or Docker.\n\nPorts the server would assign are shown as 0.  The environment id defaults to <name> rather than a generated id, and the request id recorded in the unit differs on each install unless --request-id is given.",
+		Run:   renderUnit,
+	}
+	addInstallUnitFlags(renderCmd)
+	renderCmd.Flags().StringVar(&requestId, "request-id", "", "The request id to record in the unit, as 32 hexadecimal or 22 base64 characters (default a new id)")
+	gcmd.AddCommand(gearCmd, renderCmd, true)
+
 	deleteCmd := &cobra.Command{
 		Use:   "delete <name>...",
 		Short: "Delete an installed container",
 		Long:  "Deletes one or more installed containers from the system.  Will not clean up unused images.",
 		Run:   deleteContainer,
 	}
+	gcmd.AddConfirmFlag(deleteCmd, &yes)
+	gcmd.AddCompletionHookFlags(deleteCmd, &completionHooks)
 	gcmd.AddCommand(gearCmd, deleteCmd, false)
 
+	upCmd := &cobra.Command{
+		Use:   "up <manifest>",
+		Short: "Install the containers of a manifest in dependency order",
+		Long:  "Install each container listed in a JSON manifest after the containers it is after, one at a time.  A container after one that fails to install is skipped.  With --atomic, a failure of a container that isn't optional stops the install and removes every container installed so far.",
+		Run:   upContainers,
+	}
+	upCmd.Flags().BoolVar(&atomic, "atomic", false, "Remove every installed container if a container that isn't optional fails to install")
+	gcmd.AddCommand(gearCmd, upCmd, false)
+
+	groupStatusCmd := &cobra.Command{
+		Use:   "group-status <base-name> [<host>...]",
+		Short: "Show the health of a group of containers as a whole",
+		Long:  "Shows whether every container of a group is up, some are (degraded), or none are (down), followed by the status of each.  The group is the container named <base-name>, its variants such as <base-name>--canary, and the containers labelled " + cjobs.GroupLabel + "=<base-name>, on the named hosts or this host if none are named.",
+		Run:   groupStatus,
+	}
+	groupStatusCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format: json")
+	gcmd.AddCommand(gearCmd, groupStatusCmd, false)
+
+	compareHostsCmd := &cobra.Command{
+		Use:   "compare-hosts <host> <host>",
+		Short: "Compare the containers installed on two hosts",
+		Long:  "List the containers on both hosts and report those installed on only one, and those whose image, ports, or environment variable names differ.  Exits with a non-zero code if the hosts differ.",
+		Run:   compareHosts,
+	}
+	gcmd.AddCommand(gearCmd, compareHostsCmd, false)
+
 	buildCmd := &cobra.Command{
 		Use:   "build <source> <image> <tag> [<env>]",
 		Short: "(Local) Build a new image on this host",
@@ -137,18 +332,28 @@ func Execute() {
 	buildCmd.Flags().BoolVar(&(buildReq.Verbose), "verbose", false, "Enable verbose output")
 	buildCmd.Flags().StringVar(&(buildReq.CallbackUrl), "callbackUrl", "", "Specify a URL to invoke via HTTP POST upon build completion")
 	buildCmd.Flags().StringVar(&environment.Path, "env-file", "", "Path to an environment file to load")
+	buildCmd.Flags().StringVar(&environment.ValuesPath, "env-values", "", "Path to a YAML file of values used to render the environment file as a template (supports {{ required \"message\" .Key }} and {{ default \"value\" .Key }})")
 	buildCmd.Flags().StringVar(&environment.Description.Source, "env-url", "", "A url to download environment files from")
 	buildCmd.Flags().StringVarP(&(buildReq.ScriptsUrl), "scripts", "s", "", "Specify a URL for the assemble and run scripts")
 	gcmd.AddCommand(gearCmd, buildCmd, false)
 
 	setEnvCmd := &cobra.Command{
-		Use:   "set-env <name>... [<env>]",
+		Use:   "set-env <name>... [<env>] [--from <source>]",
 		Short: "Set environment variable values on servers",
 		Long:  "Adds the listed environment values to the specified locations. The name is the environment id that multiple containers may reference. You can pass an environment file or key value pairs on the commandline.",
 		Run:   setEnvironment,
 	}
 	setEnvCmd.Flags().BoolVar(&resetEnv, "reset", false, "Remove any existing values")
 	setEnvCmd.Flags().StringVar(&environment.Path, "env-file", "", "Path to an environment file to load")
+	setEnvCmd.Flags().StringVar(&environment.ValuesPath, "env-values", "", "Path to a YAML file of values used to render the environment file as a template (supports {{ required \"message\" .Key }} and {{ default \"value\" .Key }})")
+	setEnvCmd.Flags().StringVar(&environment.JSON, "env-json", "", envJSONUsage)
+	setEnvCmd.Flags().StringVar(&environment.JSONPath, "env-json-file", "", "Path to a file holding a JSON object of variables, as for --env-json")
+	setEnvCmd.Flags().StringVar(&environment.SchemaPath, "env-schema", "", "Path to a JSON schema the environment must match, declaring variables that are Required, their Type (string, int, number, or bool), allowed Values, or a Pattern")
+	setEnvCmd.Flags().BoolVar(&envDiff, "diff", false, "Show the variables that would be added, changed, or removed (with --reset) without changing anything")
+	setEnvCmd.Flags().StringVar(&ifMatch, "if-match", "", "Only change an environment whose current ETag matches this value, as shown by 'gear env --etag'")
+	setEnvCmd.Flags().StringVar(&envSource, "from", "", "Copy the environment of another container on the same server")
+	setEnvCmd.Flags().Var(&envMerge, "merge-from", "Merge the environment of another container on the same server, after --from and any earlier --merge-from (may be repeated).  The last value of each variable wins.")
+	setEnvCmd.Flags().BoolVar(&envReload, "reload", false, "Signal the container with the same name to reload its environment without a restart.  The container must have been installed with --env-reload-signal.")
 	gcmd.AddCommand(gearCmd, setEnvCmd, false)
 
 	envCmd := &cobra.Command{
@@ -157,8 +362,25 @@ func Execute() {
 		Long:  "Return the environment variables matching the provided ids",
 		Run:   showEnvironment,
 	}
+	envCmd.Flags().StringVar(&ifNoneMatch, "if-none-match", "", "Only return an environment whose ETag does not match this value")
+	envCmd.Flags().BoolVar(&showETag, "etag", false, "Print the ETag of each environment to stderr")
+	envCmd.Flags().BoolVar(&showEnvSize, "size", false, "Print the size in bytes of each environment to stderr")
+	envCmd.Flags().Var(&envKeys, "key", "Only return the variable with this name, failing if it is not set (may be repeated)")
+	envCmd.Flags().BoolVar(&envExport, "export", false, "Print the variables as 'export NAME=value' lines, quoted so that the output can be sourced by a shell")
+	envCmd.Flags().StringVar(&envPrefix, "prefix", "", "Prefix the name of each variable printed with --export, so that sourcing it doesn't clobber variables of the current shell")
+	envCmd.Flags().StringVar(&envSort, "env-sort", containers.EnvironmentSortKey, "Print the variables sorted by 'key', or in the 'insertion' order of the environment file")
 	gcmd.AddCommand(gearCmd, envCmd, false)
 
+	showCmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: "Retrieve a file from the home directory of a container",
+		Long:  "Write the contents of a file in the home directory of a container to stdout, unchanged.  A directory is listed one name per line, with a trailing '/' on each subdirectory.",
+		Run:   showContent,
+	}
+	showCmd.Flags().StringVar(&contentType, "type", cjobs.ContentTypeFile, "The type of content to retrieve, only 'file' is supported")
+	showCmd.Flags().StringVar(&contentPath, "path", "", "The path of the file relative to the home directory of the container")
+	gcmd.AddCommand(gearCmd, showCmd, false)
+
 	linkCmd := &cobra.Command{
 		Use:   "link <name>...",
 		Short: "Set network links for the named containers",
@@ -175,6 +397,8 @@ func Execute() {
 		Run:   startContainer,
 	}
 	//startCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Attach to the logs after startup")
+	startCmd.Flags().Var(&startEnv, "env", "Override a '<name>=<value>' variable of the environment until the container next stops, without changing the stored environment (may be repeated)")
+	gcmd.AddCompletionHookFlags(startCmd, &completionHooks)
 	gcmd.AddCommand(gearCmd, startCmd, false)
 
 	stopCmd := &cobra.Command{
@@ -183,6 +407,8 @@ func Execute() {
 		Long:  ``,
 		Run:   stopContainer,
 	}
+	stopCmd.Flags().IntVarP(&stopTimeout, "time", "t", 0, "Seconds to wait for the container to exit before killing it, 0 to kill it immediately.  Defaults to the --stop-timeout the container was installed with.")
+	gcmd.AddCompletionHookFlags(stopCmd, &completionHooks)
 	gcmd.AddCommand(gearCmd, stopCmd, false)
 
 	restartCmd := &cobra.Command{
@@ -192,24 +418,156 @@ func Execute() {
 		Run:   restartContainer,
 	}
 	//startCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Attach to the logs after startup")
+	gcmd.AddCompletionHookFlags(restartCmd, &completionHooks)
 	gcmd.AddCommand(gearCmd, restartCmd, false)
 
+	pauseCmd := &cobra.Command{
+		Use:   "pause <name>...",
+		Short: "Freeze the processes of a running container",
+		Long:  "Freezes every process of the container without stopping it, for debugging.  The container keeps its memory and ports, and 'gear status' reports it as paused until 'gear unpause' thaws it.",
+		Run:   pauseContainer,
+	}
+	gcmd.AddCommand(gearCmd, pauseCmd, false)
+
+	unpauseCmd := &cobra.Command{
+		Use:   "unpause <name>...",
+		Short: "Thaw the processes of a paused container",
+		Run:   unpauseContainer,
+	}
+	gcmd.AddCommand(gearCmd, unpauseCmd, false)
+
+	resetFailedCmd := &cobra.Command{
+		Use:   "reset-failed <name>...",
+		Short: "Clear the failed state and restart count of a container",
+		Long:  "Runs 'systemctl reset-failed' for the unit of each container, so that a container that crashed until it hit the systemd start limit can be started again, and its restart count shown by 'gear status' starts from zero.  With --all, every container on the named hosts is reset.",
+		Run:   resetFailedContainer,
+	}
+	resetFailedCmd.Flags().BoolVar(&resetAll, "all", false, "Reset every container on the named hosts, or on this host if none are named")
+	gcmd.AddCommand(gearCmd, resetFailedCmd, false)
+
+	reassignPortCmd := &cobra.Command{
+		Use:   "reassign-port <name> [<new-external>]",
+		Short: "Move the external port of a container",
+		Long:  "Releases the external port of the container and reserves the requested port, or a newly allocated one if none is given, without reinstalling the container.  Fails if the requested port is already reserved.  Restart the container to listen on the new port.",
+		Run:   reassignPort,
+	}
+	reassignPortCmd.Flags().UintVar(&reassignInternal, "internal", 0, "The internal port whose mapping is moved, required if the container has more than one port")
+	reassignPortCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format: json")
+	gcmd.AddCommand(gearCmd, reassignPortCmd, false)
+
+	renameCmd := &cobra.Command{
+		Use:   "rename <name> <new-name>",
+		Short: "Give a container a new id",
+		Long:  "Moves the unit, environment, home directory, ports and data container of the container to the new id.  The container is stopped while it is renamed and started again under the new id if it was running.  Fails without changing the container if a container or environment already uses the new id.",
+		Run:   renameContainer,
+	}
+	gcmd.AddCommand(gearCmd, renameCmd, false)
+
+	execCmd := &cobra.Command{
+		Use:   "exec <name> -- <command> [<arg>...]",
+		Short: "Run a command inside a running container",
+		Long:  "Runs the command inside the named container and streams its output.  The exit code of the command is returned as the exit code of gear.",
+		Run:   execInContainer,
+	}
+	execCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Forward stdin to the command")
+	execCmd.Flags().BoolVarP(&tty, "tty", "t", false, "Allocate a pseudo-TTY for the command")
+	gcmd.AddCommand(gearCmd, execCmd, false)
+
 	statusCmd := &cobra.Command{
 		Use:   "status <name>...",
 		Short: "Retrieve the systemd status of one or more containers",
-		Long:  "Shows the equivalent of 'systemctl status ctr-<name>' for each listed unit",
+		Long:  "Shows the equivalent of 'systemctl status ctr-<name>' for each listed unit.\n\nWith --selector, pass zero or more hosts instead of names to show the containers on those hosts whose labels match.\n\nWith --hosts, pass names without a host to show the state of those containers on every host in the file, one row per host.  Hosts that can't be reached are reported separately from containers that fail.",
 		Run:   containerStatus,
 	}
+	statusCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Show the state and resource limits of each container instead, as 'wide' or 'json'")
+	statusCmd.Flags().BoolVarP(&watchStatus, "watch", "w", false, "Refresh the state and resource limits of each container until interrupted")
+	statusCmd.Flags().DurationVar(&watchInterval, "interval", 2*time.Second, "How often to refresh the status when watching")
+	statusCmd.Flags().StringVarP(&selector, "selector", "l", "", "Show the containers whose labels match, such as 'env=prod,tier in (web,api)'")
+	statusCmd.Flags().StringVar(&hostsFile, "hosts", "", "Read a list of hosts from this file, one per line, and show the named containers on each of them")
+	statusCmd.Flags().IntVar(&concurrency, "concurrency", 10, "The most hosts to query at once with --hosts")
+	statusCmd.Flags().BoolVar(&groupVariant, "group", false, "Show the state of each container grouped with its variants, by base name and variant.  Implies --output=wide unless json is chosen.")
 	gcmd.AddCommand(gearCmd, statusCmd, false)
 
+	inspectCmd := &cobra.Command{
+		Use:   "inspect <name>...",
+		Short: "(Local) Show everything known about one or more containers",
+		Long:  "Prints a document for each container combining what was recorded when it was installed, the contents of its unit file, the state systemd reports, the ports allocated to it and where its files are kept.",
+		Run:   inspectContainer,
+	}
+	inspectCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Print the documents as 'json' (the default) or 'yaml'")
+	gcmd.AddCommand(gearCmd, inspectCmd, true)
+
 	listUnitsCmd := &cobra.Command{
 		Use:   "list-units <host>...",
 		Short: "Retrieve the list of services across all hosts",
 		Long:  "Shows the equivalent of 'systemctl list-units ctr-<name>' for each installed container",
 		Run:   listUnits,
 	}
+	listUnitsCmd.Flags().StringVarP(&selector, "selector", "l", "", "Only list the containers whose labels match, such as 'env=prod,tier in (web,api)'")
+	listUnitsCmd.Flags().BoolVar(&groupVariant, "group", false, "List each container grouped with its variants, by base name and variant")
 	gcmd.AddCommand(gearCmd, listUnitsCmd, false)
 
+	hostStatusCmd := &cobra.Command{
+		Use:   "host-status <host>...",
+		Short: "Summarize the resources used by containers on each host",
+		Long:  "Shows the number of running and stopped containers, the total of their memory and CPU limits, and the external ports reserved and still free on each host.",
+		Run:   hostStatus,
+	}
+	hostStatusCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Print the summary as 'json'")
+	gcmd.AddCommand(gearCmd, hostStatusCmd, false)
+
+	pingCmd := &cobra.Command{
+		Use:   "ping <host>...",
+		Short: "Check that the daemon on each host is reachable",
+		Long:  "Requests the health check of the daemon on each host at the same time, and reports whether it is up and the round trip time.  Exits non-zero if any host is down.",
+		Run:   ping,
+	}
+	pingCmd.Flags().DurationVar(&pingTimeout, "timeout", 3*time.Second, "How long to wait for each host to answer")
+	pingCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Print the results as 'json'")
+	gcmd.AddCommand(gearCmd, pingCmd, false)
+
+	eventsCmd := &cobra.Command{
+		Use:   "events [<host>]",
+		Short: "Print the jobs run by the daemon as they are accepted, started, and completed",
+		Long:  "Streams the lifecycle events of the jobs run by the daemon on a host until interrupted.  Events are dropped rather than delay the daemon if they are not read quickly enough.",
+		Run:   events,
+	}
+	eventsCmd.Flags().StringVar(&eventType, "type", "", "Only print the events of this type of job, such as InstallContainerRequest")
+	eventsCmd.Flags().StringVar(&eventContainer, "container", "", "Only print the events of jobs acting on this container id")
+	eventsCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Print each event as a line of 'json'")
+	gcmd.AddCommand(gearCmd, eventsCmd, false)
+
+	jobsCmd := &cobra.Command{
+		Use:   "jobs [<host>]",
+		Short: "List the jobs the daemon recently ran",
+		Long:  "Lists the jobs the daemon on a host has accepted, oldest first, with the time each was accepted, started, and finished.  The daemon only remembers as many jobs as --job-history and --job-history-age allow.",
+		Run:   listJobs,
+	}
+	jobsCmd.Flags().StringVar(&historyStatus, "status", "", "Only list jobs whose last event was 'accepted', 'started', 'completed', or 'failed'")
+	jobsCmd.Flags().StringVar(&historyType, "type", "", "Only list jobs of this type, such as InstallContainerRequest")
+	jobsCmd.Flags().Var(&historySince, "since", "Only list jobs accepted after this time, given as RFC3339 or as a duration before now such as 1h")
+	jobsCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Print the jobs as 'json'")
+	gcmd.AddCommand(gearCmd, jobsCmd, false)
+
+	jobCmd := &cobra.Command{
+		Use:   "job",
+		Short: "Inspect or cancel jobs queued with --detach",
+	}
+	jobStatusCmd := &cobra.Command{
+		Use:   "status <host>/<job>...",
+		Short: "Retrieve the status of a queued job",
+		Long:  "Shows whether a job queued with --detach has completed, and its result if it has.  The job id is printed when the job is queued.",
+		Run:   jobStatus,
+	}
+	jobCancelCmd := &cobra.Command{
+		Use:   "cancel <host>/<job>...",
+		Short: "Cancel a queued or running job",
+		Long:  "Removes a job queued with --detach from the queue, or asks it to stop if it is already running.  Not every job can be stopped once it has started.",
+		Run:   cancelJob,
+	}
+	jobCmd.AddCommand(jobStatusCmd, jobCancelCmd)
+	gcmd.AddCommand(gearCmd, jobCmd, false)
+
 	gcmd.ExtendCommands(gearCmd, false)
 
 	daemonCmd := &cobra.Command{
@@ -219,14 +577,72 @@ func Execute() {
 		Run:   daemon,
 	}
 	daemonCmd.Flags().StringVarP(&listenAddr, "listen-address", "A", ":43273", "Set the address for the http endpoint to listen on")
+	daemonCmd.Flags().BoolVar(&conf.CompressStreams, "compress-streams", false, "Compress streamed output, such as logs and builds, for clients that accept gzip")
+	daemonCmd.Flags().BoolVar(&systemdSocket, "systemd-socket", false, "Serve on the socket passed by systemd when the agent is socket activated, listening on --listen-address if none was passed")
+	daemonCmd.Flags().BoolVar(&proxyProtocol, "proxy-protocol", false, "Require a PROXY protocol header, sent within --read-timeout (or 10s if it is 0), on each connection and use the client address it contains")
+	daemonCmd.Flags().StringVar(&envKeyFile, "env-encryption-key-file", "", "Encrypt stored environments with the first key in this file of '<key id> <base64 key>' lines. Older keys are used to read existing environments.")
+	daemonCmd.Flags().IntVar(&containers.EnvironmentSizeLimits.MaxValueSize, "env-max-value-size", containers.DefaultEnvironmentLimits.MaxValueSize, "Reject environment changes that set a value larger than this many bytes (at most 8192)")
+	daemonCmd.Flags().IntVar(&containers.EnvironmentSizeLimits.MaxTotalSize, "env-max-size", containers.DefaultEnvironmentLimits.MaxTotalSize, "Reject environment changes that make an environment larger than this many bytes (0 for no limit)")
+	daemonCmd.Flags().Var(&unitSections, "allow-unit-section", "Allow installs to add directives to this section of a container unit, in addition to Unit and Service (may be repeated)")
+	daemonCmd.Flags().BoolVar(&containers.AllowDockerArgs, "allow-docker-args", false, "Allow installs to append arguments to docker run with --docker-arg.  The arguments are passed to Docker unchecked and can give a container full access to the host.")
+	daemonCmd.Flags().StringVar(&reconcileDir, "reconcile-dir", "", "Keep the containers described by the install manifests (<id>.json) in this directory installed, and remove them when their manifest is removed")
+	daemonCmd.Flags().DurationVar(&reconcileInterval, "reconcile-interval", 30*time.Second, "How often to compare the containers to the manifests in --reconcile-dir")
+	daemonCmd.Flags().DurationVar(&envWatchInterval, "env-file-watch-interval", 10*time.Second, "How often to check the environment files containers were installed with --env-file-watch for changes")
+	daemonCmd.Flags().StringVar(&containers.EnvFileWatchDir, "env-watch-dir", "", "The directory files passed to --env-file-watch must be in.  Containers can't watch environment files unless it is set.")
+	daemonCmd.Flags().BoolVar(&maintenance, "maintenance", false, "Start in maintenance mode, rejecting jobs that change state until 'gear daemon maintenance off'")
+	daemonCmd.Flags().IntVar(&jobHistoryCount, "job-history", 1000, "Remember at most this many jobs for 'gear jobs', forgetting the oldest first (0 for no limit)")
+	daemonCmd.Flags().DurationVar(&jobHistoryAge, "job-history-age", 24*time.Hour, "Forget jobs for 'gear jobs' once they finished this long ago (0 for no limit)")
+	daemonCmd.Flags().StringVar(&onFailureExec, "on-failure-exec", "", "Run this program whenever a job fails, passing the request id, job type, container id, and error as arguments and as GEARD_JOB_ID, GEARD_JOB_TYPE, GEARD_CONTAINER_ID, and GEARD_JOB_ERROR")
+	daemonCmd.Flags().DurationVar(&onFailureTimeout, "on-failure-timeout", 30*time.Second, "Kill the --on-failure-exec program if it runs longer than this")
+	daemonCmd.Flags().DurationVar(&installTimeout, "install-timeout", 30*time.Minute, "Cancel an install that has not finished pulling its image after this long and report it as timed out (0 for no limit)")
+	daemonCmd.Flags().DurationVar(&startTimeout, "start-timeout", 5*time.Minute, "Cancel a start that has not been queued with systemd after this long and report it as timed out (0 for no limit)")
+	daemonCmd.Flags().DurationVar(&serverTimeouts.Read, "read-timeout", time.Minute, "Close a connection whose request headers and body have not been read after this long (0 for no limit)")
+	daemonCmd.Flags().DurationVar(&serverTimeouts.Write, "write-timeout", 2*time.Minute, "Close a connection whose response has not been written after this long.  Responses that wait for a job or stream events are exempt (0 for no limit)")
+	daemonCmd.Flags().DurationVar(&serverTimeouts.Idle, "idle-timeout", 2*time.Minute, "Close a keep-alive connection that has been idle between requests for this long (0 for no limit)")
+	daemonCmd.Flags().BoolVar(&readOnly, "read-only", false, "Serve status and other reads only, rejecting every job that changes state with 403 Forbidden")
+	daemonCmd.Flags().BoolVar(&accessLog, "access-log", false, "Log the method, path, status, size, duration, client, and request id of every API request")
+	daemonCmd.Flags().StringVar(&logFormat, "log-format", "text", "The format of access log entries, 'text' or 'json' (one object per line)")
+	daemonCmd.Flags().BoolVar(&showSecretValues, "show-secret-values", false, "Show the values of secrets and of variables named *_TOKEN, *_SECRET or *PASSWORD* in logs and errors instead of '***' (for debugging only)")
 	gcmd.AddCommand(gearCmd, daemonCmd, true)
 
+	maintenanceCmd := &cobra.Command{
+		Use:   "maintenance [on|off]",
+		Short: "(Local) Turn maintenance mode of the gear agent on or off",
+		Long:  "While in maintenance mode the agent rejects jobs that change state with 503 Service Unavailable, but continues to serve status and other reads.  Jobs that are already running are allowed to complete.  Takes effect immediately without restarting the agent.  With no arguments, shows whether maintenance mode is on.",
+		Run:   daemonMaintenance,
+	}
+	daemonCmd.AddCommand(maintenanceCmd)
+
+	daemonLogsCmd := &cobra.Command{
+		Use:   "daemon-logs",
+		Short: "(Local) Show the journal of the gear agent",
+		Long:  "Shows the systemd journal of the gear agent on this host.  Equivalent to 'journalctl -u geard.service'.",
+		Run:   daemonLogs,
+	}
+	daemonLogsCmd.Flags().StringVar(&daemonLogsOpts.Unit, "unit", "geard.service", "The systemd unit the agent runs as")
+	daemonLogsCmd.Flags().BoolVarP(&daemonLogsOpts.Follow, "follow", "f", false, "Wait for and show new entries until interrupted")
+	daemonLogsCmd.Flags().IntVarP(&daemonLogsOpts.Lines, "lines", "n", 10, "The number of recent entries to show, or -1 for all")
+	daemonLogsCmd.Flags().StringVar(&daemonLogsOpts.Since, "since", "", "Only show entries after a time, such as '2014-05-01 10:00' or '1 hour ago'")
+	daemonLogsCmd.Flags().BoolVar(&jsonLines, "json-lines", false, "Print each entry as a line of JSON with a type of 'log' as it is read")
+	gcmd.AddCommand(gearCmd, daemonLogsCmd, true)
+
+	decryptEnvCmd := &cobra.Command{
+		Use:   "decrypt-env <env_id> <path>",
+		Short: "(Local) Write a decrypted copy of an encrypted environment",
+		Long:  "Decrypts a stored environment to the given path so that it can be read by systemd and Docker.  Used by container units when environments are encrypted or inherited.",
+		Run:   decryptEnvironment,
+	}
+	decryptEnvCmd.Flags().StringVar(&envKeyFile, "env-encryption-key-file", "", "The file of encryption keys the environment was stored with")
+	decryptEnvCmd.Flags().StringVar(&decryptInherit, "inherit", "", "Merge the environment over this stored environment, which must exist.  The environment itself may not.")
+	gcmd.AddCommand(gearCmd, decryptEnvCmd, true)
+
 	purgeCmd := &cobra.Command{
 		Use:   "purge",
 		Short: "Stop and disable all containers",
 		Long:  "Disable all registered resources from systemd to allow them to be removed from the system.  Will reload the systemd daemon config.",
 		Run:   purge,
 	}
+	gcmd.AddConfirmFlag(purgeCmd, &yes)
 	gcmd.AddCommand(gearCmd, purgeCmd, true)
 
 	// createTokenCmd := &cobra.Command{
@@ -281,6 +697,9 @@ func deployContainers(cmd *cobra.Command, args []string) {
 	if nil != err {
 		gcmd.Fail(1, "Unable to load deployment from %s: %s", path, err.Error())
 	}
+	if err := containers.Revision(revision).Check(); err != nil {
+		gcmd.Fail(1, "%s", err.Error())
+	}
 
 	if len(args) == 1 {
 		args = append(args, transport.Local.String())
@@ -290,13 +709,31 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		gcmd.Fail(1, "You must pass zero or more valid host names (use '%s' or pass no arguments for the current server): %s", transport.Local.String(), err.Error())
 	}
 
+	var lines *gcmd.JSONLines
+	if jsonLines {
+		lines = gcmd.NewJSONLines(os.Stdout)
+	}
+	stream := func(e gcmd.Executor) []error {
+		if lines != nil {
+			return e.StreamJSONLines(lines)
+		}
+		return e.Stream()
+	}
+	report := func(t string, payload map[string]interface{}, format string, args ...interface{}) {
+		if lines != nil {
+			lines.Emit(t, payload)
+			return
+		}
+		fmt.Printf(format, args...)
+	}
+
 	re := regexp.MustCompile("\\.\\d{8}\\-\\d{6}\\z")
 	now := time.Now().Format(".20060102-150405")
 	base := filepath.Base(path)
 	base = re.ReplaceAllString(base, "")
 	newPath := base + now
 
-	fmt.Printf("==> Deploying %s\n", path)
+	report("deploying", map[string]interface{}{"path": path}, "==> Deploying %s\n", path)
 	changes, removed, err := deploy.Describe(deployment.SimplePlacement(servers), t)
 	if err != nil {
 		gcmd.Fail(1, "Deployment is not valid: %s", err.Error())
@@ -308,7 +745,7 @@ func deployContainers(cmd *cobra.Command, args []string) {
 			gcmd.Fail(1, "Unable to generate deployment info: %s", err.Error())
 		}
 
-		failures := gcmd.Executor{
+		failures := stream(gcmd.Executor{
 			On: removedIds,
 			Serial: func(on gcmd.Locator) gcmd.JobRequest {
 				return &cjobs.DeleteContainerRequest{
@@ -320,7 +757,7 @@ func deployContainers(cmd *cobra.Command, args []string) {
 				fmt.Fprintf(w, "==> Deleted %s", string(job.(*cjobs.DeleteContainerRequest).Id))
 			},
 			Transport: t,
-		}.Stream()
+		})
 		for i := range failures {
 			fmt.Fprintf(os.Stderr, failures[i].Error())
 		}
@@ -331,7 +768,7 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		gcmd.Fail(1, "Unable to generate deployment info: %s", err.Error())
 	}
 
-	errors := gcmd.Executor{
+	errors := stream(gcmd.Executor{
 		On: addedIds,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
 			instance, _ := changes.Instances.Find(gcmd.AsIdentifier(on))
@@ -339,12 +776,15 @@ func deployContainers(cmd *cobra.Command, args []string) {
 			return &cjobs.InstallContainerRequest{
 				RequestIdentifier: jobs.NewRequestIdentifier(),
 
-				Id:      instance.Id,
-				Image:   instance.Image,
-				Isolate: isolate,
+				Id:       instance.Id,
+				Image:    instance.Image,
+				Isolate:  isolate,
+				Revision: containers.Revision(revision),
 
 				Ports:        instance.Ports.PortPairs(),
 				NetworkLinks: &links,
+
+				DockerSocket: conf.Docker.Socket,
 			}
 		},
 		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
@@ -358,7 +798,7 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		},
 		Output:    os.Stdout,
 		Transport: t,
-	}.Stream()
+	})
 
 	changes.UpdateLinks()
 
@@ -366,7 +806,8 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		instances := c.Instances()
 		if len(instances) > 0 {
 			for _, link := range instances[0].NetworkLinks() {
-				fmt.Printf("==> Linking %s: %s:%d -> %s:%d\n", c.Name, link.FromHost, link.FromPort, link.ToHost, link.ToPort)
+				report("linking", map[string]interface{}{"container": c.Name, "fromHost": link.FromHost, "fromPort": link.FromPort, "toHost": link.ToHost, "toPort": link.ToPort},
+					"==> Linking %s: %s:%d -> %s:%d\n", c.Name, link.FromHost, link.FromPort, link.ToHost, link.ToPort)
 			}
 		}
 	}
@@ -382,7 +823,7 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		gcmd.Fail(1, "Unable to generate deployment info: %s", err.Error())
 	}
 
-	gcmd.Executor{
+	stream(gcmd.Executor{
 		On: linkedIds,
 		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
 			links := []containers.ContainerLink{}
@@ -398,9 +839,9 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		},
 		Output:    os.Stdout,
 		Transport: t,
-	}.Stream()
+	})
 
-	gcmd.Executor{
+	stream(gcmd.Executor{
 		On: addedIds,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
 			return &cjobs.StartedContainerStateRequest{
@@ -409,14 +850,233 @@ func deployContainers(cmd *cobra.Command, args []string) {
 		},
 		Output:    os.Stdout,
 		Transport: t,
-	}.Stream()
+	})
 
-	fmt.Printf("==> Deployed as %s\n", newPath)
+	report("deployed", map[string]interface{}{"path": newPath}, "==> Deployed as %s\n", newPath)
 	if len(errors) > 0 {
 		for i := range errors {
 			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
 		}
-		os.Exit(1)
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+}
+
+// Register the flags of install that determine the unit of the container,
+// which render accepts too.
+func addInstallUnitFlags(c *cobra.Command) {
+	c.Flags().VarP(&portPairs, "ports", "p", "List of comma separated port pairs to bind '<internal>:<external>,...'. Use zero to request a port be assigned.")
+	c.Flags().VarP(&networkLinks, "net-links", "n", "List of comma separated port pairs to wire '<local_host>:<local_port>:<remote_host>:<remote_port>,...'. local_host may be empty. It defaults to 127.0.0.1.")
+	c.Flags().BoolVar(&start, "start", false, "Start the container immediately")
+	c.Flags().BoolVar(&isolate, "isolate", false, "Use an isolated container running as a user")
+	c.Flags().BoolVar(&sockAct, "socket-activated", false, "Use a socket-activated container (experimental, requires Docker branch)")
+	c.Flags().Var(&labels, "label", "A label '<key>=<value>' used to select the container (repeat for each label)")
+	c.Flags().StringVar(&labelFile, "label-file", "", "Path to a file of '<key>=<value>' labels, one per line.  Labels passed with --label take precedence.")
+	c.Flags().Var(&secrets, "secret", "Pass a '<name>=<value>' variable to the container when it starts without storing it in the environment (may be repeated)")
+	c.Flags().Var(&dockerArgs, "docker-arg", "An argument appended verbatim to the docker run command of the container, for options geard has no flag for (may be repeated).  The server must be started with --allow-docker-args.")
+	c.Flags().StringVar(&unitDescription, "description", "", "A description of the container shown by systemctl status, instead of 'Container <name>'")
+	c.Flags().StringVar(&revision, "revision", "", revisionUsage)
+	c.Flags().Var(&unitDocumentation, "documentation", "The URL of documentation for the container shown by systemctl status, such as https://example.com/runbook (may be repeated)")
+	c.Flags().Var(&unitProps, "unit-property", "Add a '<section>.<key>=<value>' directive to the container unit, such as 'Service.MemoryLimit=1G' (may be repeated).  Only the Unit and Service sections are allowed unless the server allows others, and only resource limits and the Restart*, StartLimit* and Environment directives in Service.")
+	c.Flags().StringVar(&network, "network", "", "The network to attach the container to: bridge (the default), host, none, or container:<name> to share the network of another container")
+	c.Flags().Var(&dnsServers, "dns", "The IP address of a DNS server for the container to use instead of those of the host (may be repeated)")
+	c.Flags().Var(&extraHosts, "add-host", "Add a '<name>:<ip>' entry to /etc/hosts in the container (may be repeated)")
+	c.Flags().Var(&links, "link", "Link to another container on the same server as '<name>:<alias>', which the container reaches at the host name <alias> (may be repeated).  The linked container must be installed and is started first.")
+	c.Flags().StringVar(&logDriver, "log-driver", "", "The Docker logging driver for the output of the container, such as json-file or journald")
+	c.Flags().Var(&logOptions, "log-opt", "Pass a '<key>=<value>' option to the logging driver, such as max-size=10m (may be repeated)")
+	c.Flags().Var(&ulimits, "ulimit", "Limit a resource of the container processes as '<name>=<soft>[:<hard>]', such as nofile=4096:8192 (may be repeated)")
+	c.Flags().StringVar(&cpusetCPUs, "cpuset-cpus", "", "Pin the container to these CPUs, as a list of numbers and ranges such as 0-3,5")
+	c.Flags().StringVar(&cpusetMems, "cpuset-mems", "", "Allocate the memory of the container from these NUMA nodes, as a list of numbers and ranges such as 0,1")
+	c.Flags().StringVar(&inheritEnv, "inherit-env", "", "Inherit the variables of this stored environment each time the container starts. Variables in the container's own environment replace those it inherits.")
+	c.Flags().StringVar(&envReloadSignal, "env-reload-signal", "", "The signal, such as HUP, that 'set-env --reload' sends the container after changing its environment.  The current environment is kept in "+containers.EnvReloadMountPath+"/environment in the container.")
+	c.Flags().StringVar(&entrypoint, "entrypoint", "", "Override the entrypoint of the image")
+	c.Flags().Var(&runCmd, "cmd", "An argument of the command passed to the entrypoint, replacing the image command (repeat for each argument)")
+	c.Flags().StringVar(&workingDir, "workdir", "", "Override the working directory of the image, must be an absolute path")
+	c.Flags().StringVar(&watchPath, "watch-path", "", "Restart the container whenever this file or directory changes. The path must be within the home directory of the container.")
+	c.Flags().StringVar(&envFileWatch, "env-file-watch", "", "An environment file on the server that the daemon watches, applying its variables to the environment of the container whenever they change.  A container with --env-reload-signal is signalled to reload.")
+	c.Flags().BoolVar(&envWatchRestart, "env-file-watch-restart", false, "Restart the container, if it is running, after a change to --env-file-watch is applied")
+	c.Flags().IntVar(&installStopTimeout, "stop-timeout", cjobs.DefaultStopTimeout, "Seconds the container is given to exit when it is stopped, by systemd or by 'gear stop' without -t, before it is killed (0 to kill it immediately)")
+	c.Flags().BoolVar(&pullAtStart, "pull-at-start", false, "Download the image when the container is started instead of during the install, if it is not already present")
+	c.Flags().StringVar(&environment.Path, "env-file", "", "Path to an environment file to load")
+	c.Flags().StringVar(&environment.ValuesPath, "env-values", "", "Path to a YAML file of values used to render the environment file as a template (supports {{ required \"message\" .Key }} and {{ default \"value\" .Key }})")
+	c.Flags().StringVar(&environment.JSON, "env-json", "", envJSONUsage)
+	c.Flags().StringVar(&environment.JSONPath, "env-json-file", "", "Path to a file holding a JSON object of variables, as for --env-json")
+	c.Flags().StringVar(&environment.SchemaPath, "env-schema", "", "Path to a JSON schema the environment must match, declaring variables that are Required, their Type (string, int, number, or bool), allowed Values, or a Pattern")
+	c.Flags().StringVar((*string)(&environment.Description.Id), "env-id", "", "An optional identifier for the environment being set")
+}
+
+// The request a container is installed with, built from the flags shared by
+// install and render.  The id, image, and options of the install alone are
+// left to the caller.
+func installRequestFromFlags(cmd *cobra.Command) cjobs.InstallContainerRequest {
+	ports := *portPairs.Get().(*port.PortPairs)
+
+	// the environment of a new container is the one given, so it is checked
+	// before anything is sent
+	schema, err := environment.ReadSchema()
+	if err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "Unable to read the environment schema: %s", err.Error())
+	}
+	if schema != nil {
+		if environment.Description.Source != "" {
+			gcmd.Fail(gcmd.ExitInvalid, "--env-schema can't be combined with --env-url")
+		}
+		if err := schema.Validate(environment.Description.Map()); err != nil {
+			gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+		}
+	}
+
+	installLabels := labels.Labels
+	if labelFile != "" {
+		fromFile, err := containers.ReadLabelsFile(labelFile)
+		if err != nil {
+			gcmd.Fail(gcmd.ExitInvalid, "Unable to read labels: %s", err.Error())
+		}
+		installLabels = fromFile.Merge(labels.Labels)
+	}
+
+	networkMode, err := containers.NewNetworkModeFromString(network)
+	if err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+	}
+	if !networkMode.AllowsPorts() && len(ports) > 0 {
+		gcmd.Fail(gcmd.ExitInvalid, "Ports can't be mapped for a container using the %s network mode", networkMode)
+	}
+	dns := containers.DNSServers(dnsServers)
+	if err := dns.Check(); err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+	}
+	if !networkMode.AllowsResolverConfig() && (len(dns) > 0 || len(extraHosts.HostEntries) > 0) {
+		gcmd.Fail(gcmd.ExitInvalid, "--dns and --add-host can't be used with the %s network mode", networkMode)
+	}
+
+	var inheritEnvId containers.Identifier
+	if inheritEnv != "" {
+		if inheritEnvId, err = containers.NewIdentifier(inheritEnv); err != nil {
+			gcmd.Fail(gcmd.ExitInvalid, "The inherited environment id is not valid: %s", err.Error())
+		}
+	}
+
+	driver := containers.LogDriver(logDriver)
+	if err := driver.Check(); err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+	}
+
+	description := containers.UnitDescription(unitDescription)
+	if err := description.Check(); err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+	}
+	if err := containers.Revision(revision).Check(); err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+	}
+	documentation := containers.UnitDocumentation(unitDocumentation)
+	if err := documentation.Check(); err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+	}
+
+	reloadSignal := ""
+	if envReloadSignal != "" {
+		signal, err := containers.NewReloadSignal(envReloadSignal)
+		if err != nil {
+			gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+		}
+		reloadSignal = signal
+	}
+
+	if cmd.Flags().Lookup("entrypoint").Changed && strings.TrimSpace(entrypoint) == "" {
+		gcmd.Fail(1, "The entrypoint may not be empty")
+	}
+
+	var stopTimeoutSeconds *int
+	if cmd.Flags().Lookup("stop-timeout").Changed {
+		if installStopTimeout < 0 {
+			gcmd.Fail(gcmd.ExitInvalid, "--stop-timeout must be zero or more seconds")
+		}
+		stopTimeoutSeconds = &installStopTimeout
+	}
+
+	return cjobs.InstallContainerRequest{
+		Started:          start,
+		Isolate:          isolate,
+		SocketActivation: sockAct,
+
+		Labels:     installLabels,
+		Network:    networkMode,
+		DNS:        dns,
+		ExtraHosts: extraHosts.HostEntries,
+		Links:      links.ContainerAliases,
+		LogDriver:  driver,
+		LogOptions: logOptions.LogOptions,
+		Ulimits:    ulimits.Ulimits,
+		CPUSetCPUs: containers.CPUSet(cpusetCPUs),
+		CPUSetMems: containers.CPUSet(cpusetMems),
+		Entrypoint: entrypoint,
+		Cmd:        runCmd,
+		WorkingDir: workingDir,
+		Secrets:    secrets.Secrets,
+
+		Description:   description,
+		Documentation: documentation,
+		Revision:      containers.Revision(revision),
+
+		UnitProperties:  unitProps.UnitProperties,
+		EnvReloadSignal: reloadSignal,
+		DockerArgs:      containers.DockerArgs(dockerArgs),
+
+		Ports:        ports,
+		Environment:  &environment.Description,
+		NetworkLinks: networkLinks.NetworkLinks,
+
+		InheritEnvironment: inheritEnvId,
+
+		PullAtStart: pullAtStart,
+		WatchPath:   watchPath,
+
+		EnvFileWatch:        envFileWatch,
+		EnvFileWatchRestart: envWatchRestart,
+
+		StopTimeout: stopTimeoutSeconds,
+	}
+}
+
+func renderUnit(cmd *cobra.Command, args []string) {
+	if err := environment.ExtractVariablesFrom(&args, false); err != nil {
+		gcmd.Fail(1, "%s", err.Error())
+	}
+	if len(args) != 2 {
+		gcmd.Fail(1, "Valid arguments: <image_name> <id>")
+	}
+	id, err := containers.NewIdentifier(args[1])
+	if err != nil {
+		gcmd.Fail(1, "You must pass a valid service name: %s", err.Error())
+	}
+	if args[0] == string(id) {
+		gcmd.Fail(1, "Image name and container id must not be the same: %s", args[0])
+	}
+
+	r := installRequestFromFlags(cmd)
+	r.Id = id
+	r.Image = args[0]
+	r.RequestIdentifier = jobs.NewRequestIdentifier()
+	if requestId != "" {
+		if r.RequestIdentifier, err = jobs.NewRequestIdentifierFromString(requestId); err != nil {
+			gcmd.Fail(gcmd.ExitInvalid, "The request id is not valid: %s", err.Error())
+		}
+	}
+	var env *containers.EnvironmentDescription
+	if !r.Environment.Empty() {
+		if r.Environment.Id == "" {
+			r.Environment.Id = id
+		}
+		env = r.Environment
+	}
+	if err := r.Check(); err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "%s", err.Error())
+	}
+
+	// an image pinned to a digest resolves to it or the install fails
+	_, digest := containers.SplitImageDigest(r.Image)
+	if err := r.WriteUnit(os.Stdout, r.Ports, env, digest); err != nil {
+		gcmd.Fail(1, "Unable to render the unit: %s", err.Error())
 	}
 }
 
@@ -425,6 +1085,26 @@ func installImage(cmd *cobra.Command, args []string) {
 		gcmd.Fail(1, err.Error())
 	}
 
+	if buildContext != "" {
+		// the image is built, not named
+		args = append([]string{""}, args...)
+		if pullOnly {
+			gcmd.Fail(1, "--build may not be combined with --pull-only")
+		}
+	}
+	if pullOnly && pullAtStart {
+		gcmd.Fail(1, "--pull-only may not be combined with --pull-at-start")
+	}
+	if err := containers.PullPolicy(pullPolicy).Check(); err != nil {
+		gcmd.Fail(1, "%s", err.Error())
+	}
+	if pullAtStart && pullPolicy != "" && containers.PullPolicy(pullPolicy) != containers.PullIfMissing {
+		gcmd.Fail(1, "--pull-at-start only pulls a missing image and may not be combined with --pull-policy=%s", pullPolicy)
+	}
+	if pullOnly && idFile != "" {
+		gcmd.Fail(1, "--pull-only does not install a container to write to --id-file")
+	}
+
 	if len(args) < 2 {
 		gcmd.Fail(1, "Valid arguments: <image_name> <id> ...")
 	}
@@ -432,41 +1112,220 @@ func installImage(cmd *cobra.Command, args []string) {
 	t := defaultTransport.Get()
 
 	imageId := args[0]
-	if imageId == "" {
+	if imageId == "" && buildContext == "" {
 		gcmd.Fail(1, "Argument 1 must be a Docker image to base the service on")
 	}
-	ids, err := gcmd.NewContainerLocators(t, args[1:]...)
+	names, err := gcmd.ExpandContainerInstances(args[1:], scale)
+	if err != nil {
+		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
+	}
+	if names, err = gcmd.ExpandContainerVariant(names, variant); err != nil {
+		gcmd.Fail(1, "The variant is not valid: %s", err.Error())
+	}
+	ids, err := gcmd.NewContainerLocators(t, names...)
 	if err != nil {
 		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
 
+	base := installRequestFromFlags(cmd)
+	base.Variant = variant
+	if startRetries != 0 {
+		if !start {
+			gcmd.Fail(1, "--start-retries requires --start")
+		}
+		if startRetryInterval < time.Second || startRetryInterval%time.Second != 0 {
+			gcmd.Fail(1, "--start-retry-interval must be a whole number of seconds")
+		}
+		base.StartRetries = startRetries
+		base.StartRetryInterval = int(startRetryInterval / time.Second)
+	}
+	if scale > 1 {
+		for i := range base.Ports {
+			if base.Ports[i].External != 0 {
+				gcmd.Fail(1, "Each instance must be assigned its own external ports, use <internal>:0 with --scale")
+			}
+		}
+	}
+
 	for _, locator := range ids {
 		if imageId == string(gcmd.AsIdentifier(locator)) {
 			gcmd.Fail(1, "Image name and container id must not be the same: %s", imageId)
 		}
 	}
 
-	gcmd.Executor{
-		On: ids,
-		Serial: func(on gcmd.Locator) gcmd.JobRequest {
-			r := cjobs.InstallContainerRequest{
-				RequestIdentifier: jobs.NewRequestIdentifier(),
+	var output io.Writer = os.Stdout
+	switch outputFormat {
+	case "":
+	case "json":
+		output = ioutil.Discard
+	default:
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	var lines *gcmd.JSONLines
+	if jsonLines {
+		if outputFormat != "" {
+			gcmd.Fail(1, "--json-lines can't be used with --output")
+		}
+		lines = gcmd.NewJSONLines(os.Stdout)
+	}
+
+	if buildContext != "" {
+		if len(ids.Group()) > 1 {
+			gcmd.Fail(1, "--build can only install containers on a single server")
+		}
+		var context io.Reader = os.Stdin
+		if buildContext != "-" {
+			file, err := os.Open(buildContext)
+			if err != nil {
+				gcmd.Fail(1, "Unable to open the build context: %s", err.Error())
+			}
+			defer file.Close()
+			context = file
+		}
+		buildOutput := output
+		if outputFormat == "json" {
+			buildOutput = os.Stderr
+		}
+		if lines != nil {
+			w := lines.LineWriter("build")
+			imageId = buildFromContext(t, ids[0], context, w)
+			w.Close()
+		} else {
+			imageId = buildFromContext(t, ids[0], context, buildOutput)
+		}
+	}
 
-				Id:               gcmd.AsIdentifier(on),
-				Image:            imageId,
-				Started:          start,
-				Isolate:          isolate,
-				SocketActivation: sockAct,
+	var lock sync.Mutex
+	servers := make(map[*cjobs.InstallContainerRequest]string)
+	requested := make([]*cjobs.InstallContainerRequest, 0, len(ids))
+	reported := make(map[*cjobs.InstallContainerRequest]bool)
+	succeeded := make(map[*cjobs.InstallContainerRequest]bool)
+	installed := make([]cjobs.InstallContainerResponse, 0, len(ids))
 
-				Ports:        *portPairs.Get().(*port.PortPairs),
-				Environment:  &environment.Description,
-				NetworkLinks: networkLinks.NetworkLinks,
+	installer := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			r := base
+			r.RequestIdentifier = jobs.NewRequestIdentifier()
+			r.Id = gcmd.AsIdentifier(on)
+			r.Image = imageId
+			r.Ports = append(port.PortPairs{}, base.Ports...)
+			r.PullOnly = pullOnly
+			r.PullPolicy = containers.PullPolicy(pullPolicy)
+			r.DockerSocket = conf.Docker.Socket
+			if on.TransportLocator() != transport.Local {
+				servers[&r] = on.TransportLocator().String()
 			}
+			requested = append(requested, &r)
 			return &r
 		},
-		Output:    os.Stdout,
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			installJob := job.(*cjobs.InstallContainerRequest)
+			pairs, _ := installJob.PortMappingsFrom(r.Pending)
+			if len(pairs) > 0 {
+				fmt.Fprintf(w, "Ports %s\n", pairs.String())
+			}
+			if previous, ok := r.Pending[cjobs.PendingPreviousImageDigestName]; ok {
+				fmt.Fprintf(w, "Image %s now resolves to %s, the container had %s\n", installJob.Image, r.Pending[cjobs.PendingImageDigestName], previous)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			reported[installJob] = true
+			succeeded[installJob] = true
+			installed = append(installed, installJob.ResponseFor(servers[installJob], r.Pending, nil))
+			if lines != nil {
+				lines.Emit("installed", installed[len(installed)-1])
+			}
+		},
+		OnFailure: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			installJob := job.(*cjobs.InstallContainerRequest)
+			lock.Lock()
+			defer lock.Unlock()
+			reported[installJob] = true
+			installed = append(installed, installJob.ResponseFor(servers[installJob], nil, r.Error))
+			if lines != nil {
+				lines.Emit("installed", installed[len(installed)-1])
+			}
+		},
+		Output:     output,
+		OnComplete: completionHooks.After("install"),
+		Transport:  t,
+	}
+	var failures []error
+	if lines != nil {
+		failures = installer.StreamJSONLines(lines)
+	} else {
+		failures = installer.Stream()
+	}
+
+	if outputFormat == "json" {
+		// requests that were rejected before any job ran share the error
+		for _, r := range requested {
+			if !reported[r] && len(failures) > 0 {
+				installed = append(installed, r.ResponseFor(servers[r], nil, failures[0]))
+			}
+		}
+		json.NewEncoder(os.Stdout).Encode(installed)
+	}
+	if idFile != "" {
+		// only the containers whose install completed are written
+		ids := make([]containers.Identifier, 0, len(requested))
+		for _, r := range requested {
+			if succeeded[r] {
+				ids = append(ids, r.Id)
+			}
+		}
+		if len(ids) > 0 {
+			if err := gcmd.WriteIdFile(idFile, ids); err != nil {
+				gcmd.Fail(1, "Unable to write the container ids to %s: %s", idFile, err.Error())
+			}
+		}
+	}
+	if len(failures) > 0 {
+		for i := range failures {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", failures[i].Error())
+		}
+		os.Exit(gcmd.ExitCodeFor(failures...))
+	}
+	os.Exit(0)
+}
+
+// Build an image with Docker on the server of a container from a build
+// context, streaming the build output, and return the tag of the image.
+// Exits if the build fails.
+func buildFromContext(t transport.Transport, on gcmd.Locator, context io.Reader, output io.Writer) string {
+	id := gcmd.AsIdentifier(on)
+	var built, buildErr string
+	failures := gcmd.Executor{
+		On: gcmd.Locators{on},
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.BuildImageRequest{
+				Context:      context,
+				Id:           id,
+				DockerSocket: conf.Docker.Socket,
+			}
+		},
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			built = r.Trailers[cjobs.TrailerBuildImage]
+			buildErr = r.Trailers[cjobs.TrailerBuildError]
+		},
+		Output:    output,
 		Transport: t,
-	}.StreamAndExit()
+	}.Stream()
+
+	if len(failures) > 0 {
+		for i := range failures {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", failures[i].Error())
+		}
+		os.Exit(gcmd.ExitCodeFor(failures...))
+	}
+	if built == "" {
+		if buildErr == "" {
+			buildErr = "the server did not report the built image"
+		}
+		gcmd.Fail(1, "The image for %s could not be built: %s", id, buildErr)
+	}
+	return built
 }
 
 func buildImage(cmd *cobra.Command, args []string) {
@@ -529,34 +1388,50 @@ func setEnvironment(cmd *cobra.Command, args []string) {
 		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
 
+	if envReload && (envDiff || envSource != "" || len(envMerge) > 0) {
+		gcmd.Fail(1, "--reload can't be combined with --diff, --from, or --merge-from")
+	}
+	if environment.SchemaPath != "" && (envDiff || envSource != "" || len(envMerge) > 0) {
+		gcmd.Fail(1, "--env-schema can't be combined with --diff, --from, or --merge-from")
+	}
+	if envSource != "" || len(envMerge) > 0 {
+		if envDiff {
+			gcmd.Fail(1, "--diff can't be combined with --from or --merge-from")
+		}
+		copyEnvironment(t, ids)
+		return
+	}
+	if envDiff {
+		diffEnvironment(t, ids)
+		return
+	}
+	// checked by the server against the environment as changed
+	schema, err := environment.ReadSchema()
+	if err != nil {
+		gcmd.Fail(gcmd.ExitInvalid, "Unable to read the environment schema: %s", err.Error())
+	}
+
 	gcmd.Executor{
 		On: ids,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
 			environment.Description.Id = gcmd.AsIdentifier(on)
+			reload := cjobs.EnvironmentReload{Reload: envReload, DockerSocket: conf.Docker.Socket}
 			if resetEnv {
-				return &cjobs.PutEnvironmentRequest{environment.Description}
+				return &cjobs.PutEnvironmentRequest{EnvironmentDescription: environment.Description, EnvironmentReload: reload, Schema: schema, IfMatch: ifMatch}
 			}
 
-			return &cjobs.PatchEnvironmentRequest{environment.Description}
+			return &cjobs.PatchEnvironmentRequest{EnvironmentDescription: environment.Description, EnvironmentReload: reload, Schema: schema, IfMatch: ifMatch}
 		},
 		Output:    os.Stdout,
 		Transport: t,
 	}.StreamAndExit()
 }
 
-func showEnvironment(cmd *cobra.Command, args []string) {
-	if len(args) < 1 {
-		gcmd.Fail(1, "Valid arguments: <id> ...")
-	}
-
-	t := defaultTransport.Get()
-
-	ids, err := gcmd.NewContainerLocators(t, args[0:]...)
-	if err != nil {
-		gcmd.Fail(1, "You must pass one or more valid environment ids: %s", err.Error())
-	}
-
-	data, errors := gcmd.Executor{
+// Print the changes set-env would make to each environment, comparing the
+// current content with the new values.  A missing environment is treated
+// as empty.
+func diffEnvironment(t transport.Transport, ids gcmd.Locators) {
+	_, errors := gcmd.Executor{
 		On: ids,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
 			return &cjobs.ContentRequest{
@@ -564,63 +1439,250 @@ func showEnvironment(cmd *cobra.Command, args []string) {
 				Type:    cjobs.ContentTypeEnvironment,
 			}
 		},
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			current := containers.EnvironmentDescription{}
+			if buf, ok := r.Data.(*bytes.Buffer); ok {
+				if err := current.ReadFrom(buf); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: Unable to read the environment %s: %s\n", job.(*cjobs.ContentRequest).Locator, err.Error())
+					return
+				}
+			}
+			writeEnvironmentDiff(w, job, current.Map())
+		},
+		OnFailure: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			if jobs.IsNotFound(r.Error) {
+				writeEnvironmentDiff(w, job, map[string]string{})
+			}
+		},
 		Output:    os.Stdout,
 		Transport: t,
 	}.Gather()
 
-	for i := range data {
-		if buf, ok := data[i].(*bytes.Buffer); ok {
-			buf.WriteTo(os.Stdout)
-		}
-	}
-	if len(errors) > 0 {
-		for i := range errors {
+	failed := []error{}
+	for i := range errors {
+		if !jobs.IsNotFound(errors[i]) {
 			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+			failed = append(failed, errors[i])
 		}
-		os.Exit(1)
+	}
+	if len(failed) > 0 {
+		os.Exit(gcmd.ExitCodeFor(failed...))
 	}
 	os.Exit(0)
 }
 
-func deleteContainer(cmd *cobra.Command, args []string) {
-	t := defaultTransport.Get()
+func writeEnvironmentDiff(w io.Writer, job gcmd.JobRequest, current map[string]string) {
+	changes := containers.DiffEnvironment(current, environment.Description.Variables, resetEnv)
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s:\n", job.(*cjobs.ContentRequest).Locator)
+	changes.WriteDiffTo(buf)
+	buf.WriteTo(w)
+}
 
-	if err := gcmd.ExtractContainerLocatorsFromDeployment(t, deploymentPath, &args); err != nil {
-		gcmd.Fail(1, err.Error())
+func copyEnvironment(t transport.Transport, ids gcmd.Locators) {
+	if len(environment.Description.Variables) > 0 || environment.Path != "" || environment.JSON != "" || environment.JSONPath != "" {
+		gcmd.Fail(1, "You may not pass environment values with --from or --merge-from")
 	}
 
-	if len(args) < 1 {
-		gcmd.Fail(1, "Valid arguments: <id> ...")
+	names := []string{}
+	if envSource != "" {
+		names = append(names, envSource)
 	}
-
-	ids, err := gcmd.NewContainerLocators(t, args...)
+	names = append(names, envMerge...)
+	sources, err := gcmd.NewContainerLocators(t, names...)
 	if err != nil {
-		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
+		gcmd.Fail(1, "You must pass valid source environment ids: %s", err.Error())
+	}
+	// the environments are merged by the server, so every source must be
+	// reachable from the same location as every target
+	from := sources[0].TransportLocator().String()
+	for i := range sources {
+		if sources[i].TransportLocator().String() != from {
+			gcmd.Fail(1, "The environments of %s and %s cannot be merged because they are on different servers", sources[0].Identity(), sources[i].Identity())
+		}
+	}
+	for i := range ids {
+		if ids[i].TransportLocator().String() != from {
+			gcmd.Fail(1, "The environment of %s cannot be copied to %s because they are on different servers", sources[0].Identity(), ids[i].Identity())
+		}
+	}
+	others := make([]containers.Identifier, 0, len(sources)-1)
+	for _, source := range sources[1:] {
+		others = append(others, gcmd.AsIdentifier(source))
 	}
 
 	gcmd.Executor{
 		On: ids,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
-			return &cjobs.DeleteContainerRequest{
-				Id: gcmd.AsIdentifier(on),
+			return &cjobs.CopyEnvironmentRequest{
+				Id:      gcmd.AsIdentifier(on),
+				Source:  gcmd.AsIdentifier(sources[0]),
+				Sources: others,
+				Reset:   resetEnv,
 			}
 		},
-		Output: os.Stdout,
-		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
-			fmt.Fprintf(w, "Deleted %s", string(job.(*cjobs.DeleteContainerRequest).Id))
-		},
+		Output:    os.Stdout,
 		Transport: t,
 	}.StreamAndExit()
 }
 
-func linkContainers(cmd *cobra.Command, args []string) {
+func showEnvironment(cmd *cobra.Command, args []string) {
 	if len(args) < 1 {
 		gcmd.Fail(1, "Valid arguments: <id> ...")
 	}
+	if envPrefix != "" && !envExport {
+		gcmd.Fail(1, "--prefix can only be used with --export")
+	}
+	if err := containers.CheckEnvironmentSort(envSort); err != nil {
+		gcmd.Fail(1, "--env-sort is not valid: %s", err.Error())
+	}
+	if envExport && envSort != containers.EnvironmentSortKey {
+		gcmd.Fail(1, "--export always sorts the variables by key")
+	}
 
 	t := defaultTransport.Get()
 
-	ids, err := gcmd.NewContainerLocators(t, args...)
+	ids, err := gcmd.NewContainerLocators(t, args[0:]...)
+	if err != nil {
+		gcmd.Fail(1, "You must pass one or more valid environment ids: %s", err.Error())
+	}
+
+	data, errors := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ContentRequest{
+				Locator:     string(gcmd.AsIdentifier(on)),
+				Type:        cjobs.ContentTypeEnvironment,
+				Keys:        envKeys,
+				IfNoneMatch: ifNoneMatch,
+			}
+		},
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			locator := job.(*cjobs.ContentRequest).Locator
+			if etag, ok := r.Pending[cjobs.PendingETagName]; ok && showETag {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", locator, etag)
+			}
+			if size, ok := r.Pending[cjobs.PendingEnvironmentSizeName]; ok && showEnvSize {
+				fmt.Fprintf(os.Stderr, "%s: %v bytes\n", locator, size)
+			}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.Gather()
+
+	for i := range data {
+		if buf, ok := data[i].(*bytes.Buffer); ok {
+			if envSort == containers.EnvironmentSortInsertion {
+				buf.WriteTo(os.Stdout)
+				continue
+			}
+			env := containers.EnvironmentDescription{}
+			if err := env.ReadFrom(buf); err != nil {
+				gcmd.Fail(1, "Unable to read the environment: %s", err.Error())
+			}
+			if !envExport {
+				if err := containers.WriteEnvironment(os.Stdout, containers.EnvironmentVariables(env.Variables).Sorted()); err != nil {
+					gcmd.Fail(1, "Unable to write the environment: %s", err.Error())
+				}
+				continue
+			}
+			if err := containers.WriteShellExports(os.Stdout, env.Variables, envPrefix); err != nil {
+				gcmd.Fail(1, "Unable to export the environment: %s", err.Error())
+			}
+		}
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
+
+func showContent(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		gcmd.Fail(1, "Valid arguments: <name>")
+	}
+	if contentType != cjobs.ContentTypeFile {
+		gcmd.Fail(1, "The content type '%s' is not supported, only '%s' may be shown", contentType, cjobs.ContentTypeFile)
+	}
+
+	t := defaultTransport.Get()
+
+	ids, err := gcmd.NewContainerLocators(t, args[0])
+	if err != nil {
+		gcmd.Fail(1, "You must pass a valid container name: %s", err.Error())
+	}
+
+	data, errors := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ContentRequest{
+				Locator: string(gcmd.AsIdentifier(on)),
+				Type:    contentType,
+				Subpath: contentPath,
+			}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.Gather()
+
+	for i := range data {
+		if buf, ok := data[i].(*bytes.Buffer); ok {
+			buf.WriteTo(os.Stdout)
+		}
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
+
+func deleteContainer(cmd *cobra.Command, args []string) {
+	t := defaultTransport.Get()
+
+	if err := gcmd.ExtractContainerLocatorsFromDeployment(t, deploymentPath, &args); err != nil {
+		gcmd.Fail(1, err.Error())
+	}
+
+	if len(args) < 1 {
+		gcmd.Fail(1, "Valid arguments: <id> ...")
+	}
+
+	ids, err := gcmd.NewContainerLocators(t, args...)
+	if err != nil {
+		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
+	}
+	gcmd.RequireConfirmation(yes, "This will remove %s", pluralize(len(ids), "container", "containers"))
+
+	streamAndExit(gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.DeleteContainerRequest{
+				Id: gcmd.AsIdentifier(on),
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			fmt.Fprintf(w, "Deleted %s", string(job.(*cjobs.DeleteContainerRequest).Id))
+		},
+		OnComplete: completionHooks.After("delete"),
+		Transport:  t,
+	})
+}
+
+func linkContainers(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		gcmd.Fail(1, "Valid arguments: <id> ...")
+	}
+
+	t := defaultTransport.Get()
+
+	ids, err := gcmd.NewContainerLocators(t, args...)
 	if err != nil {
 		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
@@ -645,6 +1707,15 @@ func linkContainers(cmd *cobra.Command, args []string) {
 	}.StreamAndExit()
 }
 
+// Stream the output of the jobs, or show the progress of each container
+// when stdout is a terminal.
+func streamAndExit(e gcmd.Executor) {
+	if gcmd.ShowProgress(os.Stdout, len(e.On), noTty, outputFormat) {
+		e.ProgressAndExit(os.Stdout)
+	}
+	e.StreamAndExit()
+}
+
 func startContainer(cmd *cobra.Command, args []string) {
 	t := defaultTransport.Get()
 
@@ -658,17 +1729,22 @@ func startContainer(cmd *cobra.Command, args []string) {
 	if err != nil {
 		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
+	if err := startEnv.Check(); err != nil {
+		gcmd.Fail(1, "%s", err.Error())
+	}
 
-	gcmd.Executor{
+	streamAndExit(gcmd.Executor{
 		On: ids,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
 			return &cjobs.StartedContainerStateRequest{
-				Id: gcmd.AsIdentifier(on),
+				Id:          gcmd.AsIdentifier(on),
+				Environment: startEnv.StartEnvironment,
 			}
 		},
-		Output:    os.Stdout,
-		Transport: t,
-	}.StreamAndExit()
+		Output:     os.Stdout,
+		OnComplete: completionHooks.After("start"),
+		Transport:  t,
+	})
 }
 
 func stopContainer(cmd *cobra.Command, args []string) {
@@ -684,17 +1760,27 @@ func stopContainer(cmd *cobra.Command, args []string) {
 	if err != nil {
 		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
+	var timeout *int
+	if cmd.Flags().Lookup("time").Changed {
+		if stopTimeout < 0 {
+			gcmd.Fail(1, "The stop timeout must be zero or more seconds")
+		}
+		timeout = &stopTimeout
+	}
 
-	gcmd.Executor{
+	streamAndExit(gcmd.Executor{
 		On: ids,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
 			return &cjobs.StoppedContainerStateRequest{
-				Id: gcmd.AsIdentifier(on),
+				Id:           gcmd.AsIdentifier(on),
+				Timeout:      timeout,
+				DockerSocket: conf.Docker.Socket,
 			}
 		},
-		Output:    os.Stdout,
-		Transport: t,
-	}.StreamAndExit()
+		Output:     os.Stdout,
+		OnComplete: completionHooks.After("stop"),
+		Transport:  t,
+	})
 }
 
 func restartContainer(cmd *cobra.Command, args []string) {
@@ -711,23 +1797,96 @@ func restartContainer(cmd *cobra.Command, args []string) {
 		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
 
-	gcmd.Executor{
+	streamAndExit(gcmd.Executor{
 		On: ids,
 		Serial: func(on gcmd.Locator) gcmd.JobRequest {
 			return &cjobs.RestartContainerRequest{
 				Id: gcmd.AsIdentifier(on),
 			}
 		},
+		Output:     os.Stdout,
+		OnComplete: completionHooks.After("restart"),
+		Transport:  t,
+	})
+}
+
+func pauseContainer(cmd *cobra.Command, args []string) {
+	changePausedState(args, func(on gcmd.Locator) gcmd.JobRequest {
+		return &cjobs.PausedContainerStateRequest{
+			Id:           gcmd.AsIdentifier(on),
+			DockerSocket: conf.Docker.Socket,
+		}
+	})
+}
+
+func unpauseContainer(cmd *cobra.Command, args []string) {
+	changePausedState(args, func(on gcmd.Locator) gcmd.JobRequest {
+		return &cjobs.UnpausedContainerStateRequest{
+			Id:           gcmd.AsIdentifier(on),
+			DockerSocket: conf.Docker.Socket,
+		}
+	})
+}
+
+func resetFailedContainer(cmd *cobra.Command, args []string) {
+	t := defaultTransport.Get()
+
+	var ids gcmd.Locators
+	if resetAll {
+		list, errors := listContainers(args...)
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		names := make([]string, 0, len(list.Containers))
+		for i := range list.Containers {
+			c := &list.Containers[i]
+			if c.Server == "" {
+				names = append(names, c.Id)
+			} else {
+				names = append(names, c.Server+"/"+c.Id)
+			}
+		}
+		if len(names) == 0 {
+			if len(errors) > 0 {
+				os.Exit(gcmd.ExitCodeFor(errors...))
+			}
+			fmt.Fprintf(os.Stdout, "No containers to reset\n")
+			os.Exit(0)
+		}
+		located, err := gcmd.NewContainerLocators(t, names...)
+		if err != nil {
+			gcmd.Fail(1, "Unable to locate the containers: %s", err.Error())
+		}
+		ids = located
+	} else {
+		if err := gcmd.ExtractContainerLocatorsFromDeployment(t, deploymentPath, &args); err != nil {
+			gcmd.Fail(1, "%s", err.Error())
+		}
+		if len(args) < 1 {
+			gcmd.Fail(1, "Valid arguments: <id> ... or --all [<host> ...]")
+		}
+		located, err := gcmd.NewContainerLocators(t, args...)
+		if err != nil {
+			gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
+		}
+		ids = located
+	}
+
+	streamAndExit(gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ResetFailedContainerRequest{Id: gcmd.AsIdentifier(on)}
+		},
 		Output:    os.Stdout,
 		Transport: t,
-	}.StreamAndExit()
+	})
 }
 
-func containerStatus(cmd *cobra.Command, args []string) {
+func changePausedState(args []string, job func(gcmd.Locator) gcmd.JobRequest) {
 	t := defaultTransport.Get()
 
 	if err := gcmd.ExtractContainerLocatorsFromDeployment(t, deploymentPath, &args); err != nil {
-		gcmd.Fail(1, err.Error())
+		gcmd.Fail(1, "%s", err.Error())
 	}
 	if len(args) < 1 {
 		gcmd.Fail(1, "Valid arguments: <id> ...")
@@ -737,100 +1896,897 @@ func containerStatus(cmd *cobra.Command, args []string) {
 		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
 	}
 
-	data, errors := gcmd.Executor{
-		On: ids,
-		Serial: func(on gcmd.Locator) gcmd.JobRequest {
-			return &cjobs.ContainerStatusRequest{
-				Id: gcmd.AsIdentifier(on),
-			}
-		},
+	streamAndExit(gcmd.Executor{
+		On:        ids,
+		Serial:    job,
 		Output:    os.Stdout,
 		Transport: t,
-	}.Gather()
+	})
+}
 
-	for i := range data {
-		if buf, ok := data[i].(*bytes.Buffer); ok {
-			if i > 0 {
-				fmt.Fprintf(os.Stdout, "\n-------------\n")
-			}
-			buf.WriteTo(os.Stdout)
-		}
+func reassignPort(cmd *cobra.Command, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		gcmd.Fail(1, "Valid arguments: <id> [<new-external>]")
 	}
-	if len(errors) > 0 {
-		for i := range errors {
-			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+	if outputFormat != "" && outputFormat != "json" {
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	var external port.Port
+	if len(args) == 2 {
+		p, err := port.NewPortFromString(args[1])
+		if err != nil || p.Check() != nil {
+			gcmd.Fail(1, "The new external port must be between 1 and 65535")
 		}
-		os.Exit(1)
+		external = p
 	}
-	os.Exit(0)
-}
 
-func listUnits(cmd *cobra.Command, args []string) {
-	t, servers := transportAndHosts(args...)
+	t := defaultTransport.Get()
+
+	ids, err := gcmd.NewContainerLocators(t, args[0])
+	if err != nil {
+		gcmd.Fail(1, "You must pass one valid service name: %s", err.Error())
+	}
 
 	data, errors := gcmd.Executor{
-		On: servers,
-		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
-			return &cjobs.ListContainersRequest{}
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ReassignPortRequest{
+				RequestIdentifier: jobs.NewRequestIdentifier(),
+				Id:                gcmd.AsIdentifier(on),
+				Internal:          port.Port(reassignInternal),
+				External:          external,
+			}
 		},
 		Output:    os.Stdout,
 		Transport: t,
 	}.Gather()
 
-	combined := cjobs.ListServerContainersResponse{}
 	for i := range data {
-		// if r, ok := data[i].(*cjobs.ListServerContainersResponse); ok {
-		// 	combined.Append(&r.ListContainersResponse)
-		// } else
-		if j, ok := data[i].(*cjobs.ListContainersResponse); ok {
-			combined.Append(j)
+		r, ok := data[i].(*cjobs.ReassignPortResponse)
+		if !ok {
+			continue
+		}
+		switch {
+		case outputFormat == "json":
+			json.NewEncoder(os.Stdout).Encode(r)
+		case r.External == r.Previous:
+			fmt.Fprintf(os.Stdout, "Container %s port %d is already mapped to %d\n", r.Id, r.Internal, r.External)
+		default:
+			fmt.Fprintf(os.Stdout, "Container %s port %d moved from %d to %d, restart the container to use it\n", r.Id, r.Internal, r.Previous, r.External)
 		}
 	}
-	combined.Sort()
-	combined.WriteTableTo(os.Stdout)
 	if len(errors) > 0 {
 		for i := range errors {
 			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
 		}
-		os.Exit(1)
+		os.Exit(gcmd.ExitCodeFor(errors...))
 	}
 	os.Exit(0)
 }
 
-func purge(cmd *cobra.Command, args []string) {
-	t, servers := transportAndHosts(args...)
+func renameContainer(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		gcmd.Fail(1, "Valid arguments: <id> <new-id>")
+	}
+
+	t := defaultTransport.Get()
+
+	ids, err := gcmd.NewContainerLocators(t, args...)
+	if err != nil {
+		gcmd.Fail(1, "You must pass two valid service names: %s", err.Error())
+	}
+	if _, host, _, _ := gcmd.SplitTypeHostSuffix(args[1]); host != "" && ids[1].TransportLocator().String() != ids[0].TransportLocator().String() {
+		gcmd.Fail(1, "The container can only be renamed on the server it is on")
+	}
 
 	gcmd.Executor{
-		On: servers,
-		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
-			return &cjobs.PurgeContainersRequest{}
+		On: ids[:1],
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.RenameContainerRequest{
+				RequestIdentifier: jobs.NewRequestIdentifier(),
+				Id:                gcmd.AsIdentifier(on),
+				NewId:             gcmd.AsIdentifier(ids[1]),
+			}
 		},
 		Output:    os.Stdout,
 		Transport: t,
 	}.StreamAndExit()
 }
 
-// func createToken(cmd *cobra.Command, args []string) {
-// 	if len(args) != 2 {
-// 		gcmd.Fail(1, "Valid arguments: <type> <content_id>")
-// 	}
+func execInContainer(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		gcmd.Fail(1, "Valid arguments: <id> -- <command> ...")
+	}
 
-// 	if keyPath == "" {
-// 		gcmd.Fail(1, "You must specify --key-path to create a token")
-// 	}
-// 	config, err := encrypted.NewTokenConfiguration(filepath.Join(keyPath, "client"), filepath.Join(keyPath, "server.pub"))
-// 	if err != nil {
-// 		gcmd.Fail(1, "Unable to load token configuration: %s", err.Error())
-// 	}
+	t := defaultTransport.Get()
 
-// 	job := &cjobs.ContentRequest{Locator: args[1], Type: args[0]}
-// 	value, err := config.Sign(job, "key", expiresAt)
-// 	if err != nil {
-// 		gcmd.Fail(1, "Unable to sign this request: %s", err.Error())
-// 	}
-// 	fmt.Printf("%s", value)
-// 	os.Exit(0)
-// }
+	ids, err := gcmd.NewContainerLocators(t, args[0])
+	if err != nil {
+		gcmd.Fail(1, "You must pass one valid service name: %s", err.Error())
+	}
+
+	exitCode := 1
+	failures := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ExecRequest{
+				Id:          gcmd.AsIdentifier(on),
+				Command:     args[1:],
+				Interactive: interactive,
+				Tty:         tty,
+
+				Stdin:        os.Stdin,
+				DockerSocket: conf.Docker.Socket,
+			}
+		},
+		Output: os.Stdout,
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			if value, ok := r.Trailers[cjobs.TrailerExitCode]; ok {
+				if code, err := strconv.Atoi(value); err == nil {
+					exitCode = code
+				}
+			}
+		},
+		Transport: t,
+	}.Stream()
+
+	for i := range failures {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", failures[i].Error())
+	}
+	os.Exit(exitCode)
+}
+
+func containerStatus(cmd *cobra.Command, args []string) {
+	t := defaultTransport.Get()
+
+	if err := gcmd.ExtractContainerLocatorsFromDeployment(t, deploymentPath, &args); err != nil {
+		gcmd.Fail(1, err.Error())
+	}
+	var ids gcmd.Locators
+	if hostsFile != "" {
+		if selector != "" || watchStatus {
+			gcmd.Fail(1, "--hosts may not be combined with --selector or --watch")
+		}
+		hosts, err := gcmd.ReadHostsFile(hostsFile)
+		if err != nil {
+			gcmd.Fail(1, "%s", err.Error())
+		}
+		values, err := gcmd.ExpandHosts(hosts, args...)
+		if err != nil {
+			gcmd.Fail(1, "%s", err.Error())
+		}
+		locators, err := gcmd.NewContainerLocators(t, values...)
+		if err != nil {
+			gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
+		}
+		if groupVariant {
+			gcmd.Fail(1, "--hosts may not be combined with --group")
+		}
+		containerStatusByHost(t, locators)
+		return
+	} else if selector != "" {
+		ids = selectContainers(args...)
+	} else {
+		if len(args) < 1 {
+			gcmd.Fail(1, "Valid arguments: <id> ...")
+		}
+		locators, err := gcmd.NewContainerLocators(t, args...)
+		if err != nil {
+			gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
+		}
+		ids = locators
+	}
+
+	if groupVariant {
+		if watchStatus {
+			gcmd.Fail(1, "--watch may not be combined with --group")
+		}
+		if outputFormat == "" {
+			outputFormat = "wide"
+		}
+	}
+	switch outputFormat {
+	case "":
+		if watchStatus {
+			containerStatusWatch(t, ids)
+			return
+		}
+	case "wide", "json":
+		if watchStatus {
+			containerStatusWatch(t, ids)
+			return
+		}
+		containerStatusStructured(t, ids)
+		return
+	default:
+		gcmd.Fail(1, "Valid output formats: wide, json")
+	}
+
+	data, errors := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ContainerStatusRequest{
+				Id: gcmd.AsIdentifier(on),
+			}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.Gather()
+
+	for i := range data {
+		if buf, ok := data[i].(*bytes.Buffer); ok {
+			if i > 0 {
+				fmt.Fprintf(os.Stdout, "\n-------------\n")
+			}
+			buf.WriteTo(os.Stdout)
+		}
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
+
+func inspectContainer(cmd *cobra.Command, args []string) {
+	if outputFormat != "" && outputFormat != "json" && outputFormat != "yaml" {
+		gcmd.Fail(1, "Valid output formats: json, yaml")
+	}
+	if len(args) < 1 {
+		gcmd.Fail(1, "Valid arguments: <name>...")
+	}
+	t := defaultTransport.Get()
+	ids, err := gcmd.NewContainerLocators(t, args...)
+	if err != nil {
+		gcmd.Fail(1, "You must pass one or more valid service names: %s", err.Error())
+	}
+	for i := range ids {
+		if ids[i].TransportLocator() != transport.Local {
+			gcmd.Fail(1, "Containers can only be inspected on this host: %s", args[i])
+		}
+	}
+
+	data, errors := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.InspectContainerRequest{Id: gcmd.AsIdentifier(on)}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.Gather()
+
+	inspected := make(cjobs.InspectContainerResponses, 0, len(data))
+	for i := range data {
+		if r, ok := data[i].(*cjobs.InspectContainerResponse); ok {
+			inspected = append(inspected, *r)
+		}
+	}
+	if outputFormat == "yaml" {
+		gcmd.WriteYAML(os.Stdout, inspected)
+	} else {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(inspected)
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
+
+func containerStatusStructured(t transport.Transport, ids gcmd.Locators) {
+	statuses, errors := gatherContainerStatus(t, ids)
+	switch {
+	case outputFormat == "json":
+		if groupVariant {
+			statuses.GroupByVariant()
+		}
+		json.NewEncoder(os.Stdout).Encode(statuses)
+	case groupVariant:
+		statuses.WriteGroupedTableTo(os.Stdout)
+	default:
+		statuses.WriteTableTo(os.Stdout)
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
+
+// Show the state of each container on its host, querying at most
+// --concurrency hosts at once.
+func containerStatusByHost(t transport.Transport, ids gcmd.Locators) {
+	if outputFormat != "" && outputFormat != "json" {
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	if concurrency < 1 {
+		gcmd.Fail(1, "--concurrency must be at least 1")
+	}
+
+	var lock sync.Mutex
+	requested := make(map[*cjobs.ContainerStatusRequest]gcmd.Locator)
+	results := make(gcmd.HostResults, 0, len(ids))
+	record := func(job gcmd.JobRequest, fn func(*gcmd.HostResult)) {
+		on := requested[job.(*cjobs.ContainerStatusRequest)]
+		result := gcmd.HostResult{Host: on.TransportLocator().String(), Id: string(gcmd.AsIdentifier(on))}
+		fn(&result)
+		lock.Lock()
+		defer lock.Unlock()
+		results = append(results, result)
+	}
+
+	_, errs := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			req := &cjobs.ContainerStatusRequest{
+				Id:           gcmd.AsIdentifier(on),
+				Structured:   true,
+				DockerSocket: conf.Docker.Socket,
+			}
+			requested[req] = on
+			return req
+		},
+		OnSuccess: func(res *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			record(job, func(result *gcmd.HostResult) {
+				if status, ok := res.Data.(*cjobs.ContainerStatusResponse); ok {
+					result.ActiveState, result.SubState = status.ActiveState, status.SubState
+				}
+			})
+		},
+		OnFailure: func(res *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			record(job, func(result *gcmd.HostResult) {
+				result.Unreachable = http.IsUnreachable(res.Error)
+				result.Error = res.Error.Error()
+			})
+		},
+		Output:    os.Stdout,
+		Transport: t,
+		Limit:     concurrency,
+	}.Gather()
+
+	if outputFormat == "json" {
+		json.NewEncoder(os.Stdout).Encode(results)
+	} else {
+		results.WriteTableTo(os.Stdout)
+	}
+	if unreachable, failed := results.Failures(); unreachable > 0 || failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d unreachable, %d failed\n", unreachable, failed)
+	}
+	if len(results) == 0 {
+		// the jobs could not be created, so no host was queried
+		for i := range errs {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errs[i])
+		}
+	}
+	if len(errs) > 0 {
+		os.Exit(gcmd.ExitCodeFor(errs...))
+	}
+	os.Exit(0)
+}
+
+func containerStatusWatch(t transport.Transport, ids gcmd.Locators) {
+	stop := make(chan struct{})
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	go func() {
+		<-interrupted
+		close(stop)
+	}()
+
+	var previous cjobs.ContainerStatusResponses
+	gcmd.Watch(os.Stdout, watchInterval, outputFormat != "json", stop, func(w io.Writer) {
+		statuses, errors := gatherContainerStatus(t, ids)
+		statuses = statuses.WithRemoved(previous)
+		previous = statuses
+		if outputFormat == "json" {
+			json.NewEncoder(w).Encode(statuses)
+			return
+		}
+		fmt.Fprintf(w, "Every %s: %s\n\n", watchInterval, time.Now().Format(time.RFC1123))
+		statuses.WriteTableTo(w)
+		for i := range errors {
+			fmt.Fprintf(w, "\nError: %s", errors[i])
+		}
+	})
+	os.Exit(0)
+}
+
+func gatherContainerStatus(t transport.Transport, ids gcmd.Locators) (cjobs.ContainerStatusResponses, []error) {
+	data, errors := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ContainerStatusRequest{
+				Id:           gcmd.AsIdentifier(on),
+				Structured:   true,
+				DockerSocket: conf.Docker.Socket,
+			}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.Gather()
+
+	statuses := make(cjobs.ContainerStatusResponses, 0, len(data))
+	for i := range data {
+		if status, ok := data[i].(*cjobs.ContainerStatusResponse); ok {
+			statuses = append(statuses, *status)
+		}
+	}
+	return statuses, errors
+}
+
+type jobLocator struct {
+	At transport.Locator
+	Id jobs.RequestIdentifier
+}
+
+// Jobs are queued on a server by the http transport, so job ids are
+// always resolved against it.
+func jobLocatorsFrom(args []string) (*http.HttpTransport, []jobLocator) {
+	if len(args) < 1 {
+		gcmd.Fail(1, "Valid arguments: <host>/<job> ...")
+	}
+	t, _ := transport.GetTransport("http")
+	remote, ok := t.(*http.HttpTransport)
+	if !ok {
+		gcmd.Fail(1, "Jobs can only be inspected over the http transport")
+	}
+
+	locators := make([]jobLocator, 0, len(args))
+	for i := range args {
+		_, host, value, err := gcmd.SplitTypeHostSuffix(args[i])
+		if err != nil {
+			gcmd.Fail(1, "You must pass one or more valid job ids: %s", err.Error())
+		}
+		if host == "" {
+			host = "localhost"
+		}
+		at, err := remote.LocatorFor(host)
+		if err != nil {
+			gcmd.Fail(1, "You must pass one or more valid job ids: %s", err.Error())
+		}
+		id, err := jobs.NewRequestIdentifierFromString(value)
+		if err != nil {
+			gcmd.Fail(1, "You must pass one or more valid job ids: %s", err.Error())
+		}
+		locators = append(locators, jobLocator{at, id})
+	}
+	return remote, locators
+}
+
+func daemonLogs(cmd *cobra.Command, args []string) {
+	stop := make(chan struct{})
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	go func() {
+		<-interrupted
+		close(stop)
+	}()
+
+	var output io.Writer = os.Stdout
+	var entries io.WriteCloser
+	if jsonLines {
+		entries = gcmd.NewJSONLines(os.Stdout).LineWriter("log")
+		output = entries
+	}
+	err := systemd.CopyJournal(output, systemd.Journal, daemonLogsOpts, stop)
+	if entries != nil {
+		entries.Close()
+	}
+	if err != nil {
+		gcmd.Fail(1, "Unable to read the journal for %s: %s", daemonLogsOpts.Unit, err.Error())
+	}
+}
+
+func daemonMaintenance(cmd *cobra.Command, args []string) {
+	mode := http.DefaultMaintenanceMode()
+	if len(args) == 0 {
+		if mode.Enabled() {
+			fmt.Fprintln(os.Stdout, "Maintenance mode is on")
+		} else {
+			fmt.Fprintln(os.Stdout, "Maintenance mode is off")
+		}
+		os.Exit(0)
+	}
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		gcmd.Fail(gcmd.ExitInvalid, "Valid arguments: [on|off]")
+	}
+	if err := mode.Set(args[0] == "on"); err != nil {
+		gcmd.Fail(1, "Unable to change maintenance mode: %s", err.Error())
+	}
+	fmt.Fprintf(os.Stdout, "Maintenance mode is %s\n", args[0])
+}
+
+func decryptEnvironment(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		gcmd.Fail(1, "Valid arguments: <env_id> <path>")
+	}
+	id, err := containers.NewIdentifier(args[0])
+	if err != nil {
+		gcmd.Fail(1, "Argument 1 must be a valid environment identifier: %s", err.Error())
+	}
+	if envKeyFile != "" {
+		keys, err := containers.ReadEnvironmentKeys(envKeyFile)
+		if err != nil {
+			gcmd.Fail(1, "Unable to read encryption keys: %s", err.Error())
+		}
+		containers.EnvironmentEncryptionKeys = keys
+	}
+
+	var data []byte
+	if decryptInherit != "" {
+		parent, err := containers.NewIdentifier(decryptInherit)
+		if err != nil {
+			gcmd.Fail(1, "The inherited environment id is not valid: %s", err.Error())
+		}
+		if data, err = containers.ResolveInheritedEnvironment(id, parent); err != nil {
+			gcmd.Fail(2, "Unable to read environment %s inheriting from %s: %s", id, parent, err.Error())
+		}
+	} else if data, err = containers.ReadEnvironmentFile(id.EnvironmentPathFor()); err != nil {
+		gcmd.Fail(2, "Unable to read environment %s: %s", id, err.Error())
+	}
+	if err := os.MkdirAll(filepath.Dir(args[1]), 0770); err != nil {
+		gcmd.Fail(2, "Unable to create directory for %s: %s", args[1], err.Error())
+	}
+	if err := ioutil.WriteFile(args[1], data, 0600); err != nil {
+		gcmd.Fail(2, "Unable to write environment to %s: %s", args[1], err.Error())
+	}
+}
+
+func jobStatus(cmd *cobra.Command, args []string) {
+	remote, locators := jobLocatorsFrom(args)
+
+	failed := false
+	for i := range locators {
+		status, err := remote.JobStatus(locators[i].At, locators[i].Id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %s\n", args[i], err.Error())
+			failed = true
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintf(os.Stdout, "\n")
+		}
+		fmt.Fprintf(os.Stdout, "Job %s %s\n", status.Id, status.State)
+		if status.State != http.JobStateCompleted {
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "Status %d\n", status.StatusCode)
+		names := make([]string, 0, len(status.Headers))
+		for k := range status.Headers {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			fmt.Fprintf(os.Stdout, "%s: %s\n", k, status.Headers[k])
+		}
+		if status.Body != "" {
+			fmt.Fprintf(os.Stdout, "\n%s", status.Body)
+		}
+		if status.StatusCode >= 400 {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func cancelJob(cmd *cobra.Command, args []string) {
+	remote, locators := jobLocatorsFrom(args)
+
+	failed := false
+	for i := range locators {
+		cancellation, err := remote.CancelJob(locators[i].At, locators[i].Id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %s\n", args[i], err.Error())
+			failed = true
+			continue
+		}
+		switch cancellation.State {
+		case dispatcher.JobQueued:
+			fmt.Fprintf(os.Stdout, "Job %s was queued and will not run\n", cancellation.Id)
+		case dispatcher.JobRunning:
+			fmt.Fprintf(os.Stdout, "Job %s is running and has been asked to stop\n", cancellation.Id)
+		case dispatcher.JobUncancellable:
+			fmt.Fprintf(os.Stderr, "Error: %s: job %s is running and can't be cancelled, it will run until it finishes\n", args[i], cancellation.Id)
+			failed = true
+		default:
+			fmt.Fprintf(os.Stdout, "Job %s had already finished\n", cancellation.Id)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func listUnits(cmd *cobra.Command, args []string) {
+	combined, errors := listContainers(args...)
+	if groupVariant {
+		combined.WriteGroupedTableTo(os.Stdout)
+	} else {
+		combined.WriteTableTo(os.Stdout)
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
+
+func hostStatus(cmd *cobra.Command, args []string) {
+	if outputFormat != "" && outputFormat != "json" {
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	t, servers := transportAndHosts(args...)
+
+	data, errors := gcmd.Executor{
+		On: servers,
+		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.HostStatusRequest{}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.Gather()
+
+	statuses := make(cjobs.HostStatusResponses, 0, len(data))
+	for i := range data {
+		if status, ok := data[i].(*cjobs.HostStatusResponse); ok {
+			statuses = append(statuses, *status)
+		}
+	}
+	if outputFormat == "json" {
+		json.NewEncoder(os.Stdout).Encode(statuses)
+	} else {
+		statuses.WriteTableTo(os.Stdout)
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
+
+// List the containers on each host whose labels match the selector.
+func listContainers(hosts ...string) (*cjobs.ListServerContainersResponse, []error) {
+	t, servers := transportAndHosts(hosts...)
+	parsed, err := containers.ParseSelector(selector)
+	if err != nil {
+		gcmd.Fail(1, "The selector is not valid: %s", err.Error())
+	}
+
+	data, errors := gcmd.Executor{
+		On: servers,
+		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ListContainersRequest{Selector: parsed}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.Gather()
+
+	combined := &cjobs.ListServerContainersResponse{}
+	for i := range data {
+		// if r, ok := data[i].(*cjobs.ListServerContainersResponse); ok {
+		// 	combined.Append(&r.ListContainersResponse)
+		// } else
+		if j, ok := data[i].(*cjobs.ListContainersResponse); ok {
+			combined.Append(j)
+		}
+	}
+	combined.Sort()
+	return combined, errors
+}
+
+// The containers on each host whose labels match the selector.  Exits if
+// none match.
+func selectContainers(hosts ...string) gcmd.Locators {
+	t := defaultTransport.Get()
+	list, errors := listContainers(hosts...)
+	for i := range errors {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+	}
+
+	names := make([]string, 0, len(list.Containers))
+	for i := range list.Containers {
+		c := &list.Containers[i]
+		if c.Server == "" {
+			names = append(names, c.Id)
+		} else {
+			names = append(names, c.Server+"/"+c.Id)
+		}
+	}
+	if len(names) == 0 {
+		gcmd.Fail(1, "No containers match the selector %s", selector)
+	}
+	ids, err := gcmd.NewContainerLocators(t, names...)
+	if err != nil {
+		gcmd.Fail(1, "Unable to locate the selected containers: %s", err.Error())
+	}
+	return ids
+}
+
+func purge(cmd *cobra.Command, args []string) {
+	t, servers := transportAndHosts(args...)
+	gcmd.RequireConfirmation(yes, "This will stop and disable every container on %s", pluralize(len(servers), "host", "hosts"))
+
+	gcmd.Executor{
+		On: servers,
+		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.PurgeContainersRequest{}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.StreamAndExit()
+}
+
+// func createToken(cmd *cobra.Command, args []string) {
+// 	if len(args) != 2 {
+// 		gcmd.Fail(1, "Valid arguments: <type> <content_id>")
+// 	}
+
+// 	if keyPath == "" {
+// 		gcmd.Fail(1, "You must specify --key-path to create a token")
+// 	}
+// 	config, err := encrypted.NewTokenConfiguration(filepath.Join(keyPath, "client"), filepath.Join(keyPath, "server.pub"))
+// 	if err != nil {
+// 		gcmd.Fail(1, "Unable to load token configuration: %s", err.Error())
+// 	}
+
+// 	job := &cjobs.ContentRequest{Locator: args[1], Type: args[0]}
+// 	value, err := config.Sign(job, "key", expiresAt)
+// 	if err != nil {
+// 		gcmd.Fail(1, "Unable to sign this request: %s", err.Error())
+// 	}
+// 	fmt.Printf("%s", value)
+// 	os.Exit(0)
+// }
+
+func ping(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		gcmd.Fail(1, "Valid arguments: <host> ...")
+	}
+	if outputFormat != "" && outputFormat != "json" {
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	t, ok := transport.GetTransport("http")
+	remote, isHttp := t.(*http.HttpTransport)
+	if !ok || !isHttp {
+		gcmd.Fail(1, "The http transport is not available")
+	}
+
+	locators := make([]transport.Locator, 0, len(args))
+	for i := range args {
+		// accept the server part of a container locator, 'host/'
+		locator, err := remote.LocatorFor(strings.TrimSuffix(args[i], "/"))
+		if err != nil {
+			gcmd.Fail(1, "You must pass one or more valid host names: %s", err.Error())
+		}
+		locators = append(locators, locator)
+	}
+
+	results := remote.PingAll(locators, pingTimeout)
+	if outputFormat == "json" {
+		json.NewEncoder(os.Stdout).Encode(results)
+	} else {
+		results.WriteTableTo(os.Stdout)
+	}
+	if results.Down() > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func events(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		gcmd.Fail(1, "Valid arguments: [<host>]")
+	}
+	if outputFormat != "" && outputFormat != "json" {
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	t, ok := transport.GetTransport("http")
+	remote, isHttp := t.(*http.HttpTransport)
+	if !ok || !isHttp {
+		gcmd.Fail(1, "The http transport is not available")
+	}
+	var locator transport.Locator = transport.Local
+	if len(args) == 1 {
+		var err error
+		if locator, err = remote.LocatorFor(strings.TrimSuffix(args[0], "/")); err != nil {
+			gcmd.Fail(1, "You must pass a valid host name: %s", err.Error())
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	filter := dispatcher.EventFilter{JobType: eventType, ContainerId: eventContainer}
+	err := remote.Events(locator, filter, func(e dispatcher.Event) error {
+		if outputFormat == "json" {
+			return encoder.Encode(&e)
+		}
+		line := fmt.Sprintf("%s %-9s %s %s %s %s", e.Time.Format(time.RFC3339), e.Type, e.Id, e.JobType, e.ContainerId, e.Error)
+		_, err := fmt.Fprintln(os.Stdout, strings.TrimRight(line, " "))
+		return err
+	}, func(message string) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+	})
+	if err != nil {
+		gcmd.Fail(1, "Unable to read events: %s", err.Error())
+	}
+	os.Exit(0)
+}
+
+func listJobs(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		gcmd.Fail(1, "Valid arguments: [<host>]")
+	}
+	if outputFormat != "" && outputFormat != "json" {
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	switch dispatcher.EventType(historyStatus) {
+	case "", dispatcher.EventAccepted, dispatcher.EventStarted, dispatcher.EventCompleted, dispatcher.EventFailed:
+	default:
+		gcmd.Fail(1, "Valid statuses: accepted, started, completed, failed")
+	}
+	t, ok := transport.GetTransport("http")
+	remote, isHttp := t.(*http.HttpTransport)
+	if !ok || !isHttp {
+		gcmd.Fail(1, "The http transport is not available")
+	}
+	var locator transport.Locator = transport.Local
+	if len(args) == 1 {
+		var err error
+		if locator, err = remote.LocatorFor(strings.TrimSuffix(args[0], "/")); err != nil {
+			gcmd.Fail(1, "You must pass a valid host name: %s", err.Error())
+		}
+	}
+
+	filter := dispatcher.HistoryFilter{Status: dispatcher.EventType(historyStatus), JobType: historyType, Since: historySince.Time}
+	records, err := remote.JobHistory(locator, filter)
+	if err != nil {
+		gcmd.Fail(1, "Unable to list jobs: %s", err.Error())
+	}
+	if outputFormat == "json" {
+		if err := json.NewEncoder(os.Stdout).Encode(records); err != nil {
+			gcmd.Fail(1, "%s", err.Error())
+		}
+		os.Exit(0)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tCONTAINER\tSTATUS\tACCEPTED\tSTARTED\tFINISHED\tERROR")
+	for i := range records {
+		r := &records[i]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Id, dispatcher.ShortJobType(r.JobType), r.ContainerId, r.Status, r.Accepted.Format(time.RFC3339), formatJobTime(r.Started), formatJobTime(r.Finished), r.Error)
+	}
+	w.Flush()
+	os.Exit(0)
+}
+
+func formatJobTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+// The count followed by the singular or plural noun.
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", count, plural)
+}
 
 func transportAndHosts(args ...string) (transport.Transport, gcmd.Locators) {
 	t := defaultTransport.Get()
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers/inventory"
+)
+
+func compareHosts(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		gcmd.Fail(1, "Valid arguments: <host> <host>")
+	}
+	// Hosts may be written as in a container name, such as 'remote:43273/'
+	names := []string{strings.TrimSuffix(args[0], "/"), strings.TrimSuffix(args[1], "/")}
+	t, hosts := transportAndHosts(names...)
+
+	// A container that couldn't be read would show as missing, so nothing
+	// is compared unless both hosts were read in full
+	inventories, errors := inventory.ReadAll(t, hosts...)
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+
+	diffs := inventory.Compare(inventories[0], inventories[1])
+	if len(diffs) == 0 {
+		fmt.Fprintf(os.Stdout, "The %d containers on %s and %s match\n", len(inventories[0]), names[0], names[1])
+	} else {
+		diffs.WriteTableTo(os.Stdout, names[0], names[1])
+		fmt.Fprintf(os.Stdout, "%d differences between %s (%d containers) and %s (%d containers)\n", len(diffs), names[0], len(inventories[0]), names[1], len(inventories[1]))
+	}
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -3,19 +3,88 @@ package main
 import (
 	"github.com/spf13/cobra"
 	"log"
+	"net"
 	nethttp "net/http"
-	// "path/filepath"
+	"os"
+	"path/filepath"
 
 	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/containers/envwatch"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	containermetrics "github.com/openshift/geard/containers/metrics"
+	"github.com/openshift/geard/containers/reconcile"
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/http"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/utils"
 	// "github.com/openshift/geard/encrypted"
 )
 
 func daemon(c *cobra.Command, args []string) {
+	// secret environment values never reach the log unless asked for
+	utils.ShowSecretValues = showSecretValues
+	log.SetOutput(utils.NewMaskingWriter(os.Stderr))
+	if showSecretValues {
+		log.Printf("Showing secret environment values in logs and errors, do not use in production")
+	}
+
+	if envKeyFile != "" {
+		keys, err := containers.ReadEnvironmentKeys(envKeyFile)
+		if err != nil {
+			cmd.Fail(1, "Unable to read environment encryption keys: %s", err.Error())
+		}
+		containers.EnvironmentEncryptionKeys = keys
+		log.Printf("Encrypting environments with key %s", keys.Current)
+	}
+
+	if limits := containers.EnvironmentSizeLimits; limits.MaxValueSize < 0 || limits.MaxValueSize > containers.MaxEnvironmentValueSize || limits.MaxTotalSize < 0 {
+		cmd.Fail(1, "The environment value size limit must be between 0 and %d bytes, and the environment size limit may not be negative.", containers.MaxEnvironmentValueSize)
+	}
+
+	for _, section := range unitSections {
+		containers.AllowedUnitSections[section] = true
+	}
+
+	conf.Maintenance = http.DefaultMaintenanceMode()
+	if maintenance {
+		if err := conf.Maintenance.Set(true); err != nil {
+			cmd.Fail(1, "Unable to enter maintenance mode: %s", err.Error())
+		}
+	}
+	if conf.Maintenance.Enabled() {
+		log.Printf("In maintenance mode, jobs that change state will be rejected until 'gear daemon maintenance off'")
+	}
+
+	if readOnly {
+		if reconcileDir != "" {
+			cmd.Fail(1, "A read-only agent cannot reconcile containers with --reconcile-dir")
+		}
+		conf.ReadOnly = true
+		port.DisablePortAllocator()
+		log.Printf("Read-only, jobs that change state will be rejected")
+	}
+
+	if err := http.AccessLogFormat(logFormat).Check(); err != nil {
+		cmd.Fail(1, "%s", err.Error())
+	}
+	if err := serverTimeouts.Check(); err != nil {
+		cmd.Fail(1, "%s", err.Error())
+	}
+
 	api, err := conf.Handler()
 	if err != nil {
 		cmd.Fail(1, "Unable to start server: %s", err.Error())
 	}
 	nethttp.Handle("/", api)
+	nethttp.HandleFunc("/healthz", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	metrics := []http.Metric{
+		http.Counter{"geard_port_allocation_failures_total", "External port allocations that failed because every port in the range was in use.", port.AllocationFailures},
+	}
+	metrics = append(metrics, containermetrics.NewCollector(conf.Docker.Socket).Gauges()...)
+	nethttp.Handle("/metrics", conf.MetricsHandler(metrics...))
 
 	// if keyPath != "" {
 	// 	config, err := encrypted.NewTokenConfiguration(filepath.Join(keyPath, "server"), filepath.Join(keyPath, "client.pub"))
@@ -25,8 +94,87 @@ func daemon(c *cobra.Command, args []string) {
 	// 	nethttp.Handle("/token/", nethttp.StripPrefix("/token", config.Handler(api)))
 	// }
 
+	if onFailureExec != "" {
+		if onFailureTimeout <= 0 {
+			cmd.Fail(1, "The failure hook timeout must be greater than zero")
+		}
+		if info, err := os.Stat(onFailureExec); err != nil || info.IsDir() || info.Mode().Perm()&0111 == 0 {
+			cmd.Fail(1, "The failure hook %s must be an executable file", onFailureExec)
+		}
+		conf.Dispatcher.OnFailure = dispatcher.FailureExec(onFailureExec, onFailureTimeout)
+		log.Printf("Running %s when a job fails", onFailureExec)
+	}
+	if installTimeout < 0 || startTimeout < 0 {
+		cmd.Fail(1, "Job timeouts must be zero or greater")
+	}
+	conf.Dispatcher.SetTimeout(&cjobs.InstallContainerRequest{}, installTimeout)
+	conf.Dispatcher.SetTimeout(&cjobs.StartedContainerStateRequest{}, startTimeout)
+	// stopping or restarting a container during an incident should not
+	// wait behind queued installs
+	conf.Dispatcher.SetPriority(&cjobs.StoppedContainerStateRequest{}, dispatcher.PriorityHigh)
+	conf.Dispatcher.SetPriority(&cjobs.RestartContainerRequest{}, dispatcher.PriorityHigh)
+	conf.Dispatcher.SetPriority(&cjobs.InstallContainerRequest{}, dispatcher.PriorityLow)
+	conf.Dispatcher.SetPriority(&cjobs.BuildImageRequest{}, dispatcher.PriorityLow)
+	conf.Dispatcher.Events = dispatcher.NewEvents()
+	if jobHistoryCount < 0 || jobHistoryAge < 0 {
+		cmd.Fail(1, "The job history limits must be zero or greater")
+	}
+	conf.Dispatcher.History = dispatcher.NewHistory(jobHistoryCount, jobHistoryAge)
 	conf.Dispatcher.Start()
 
-	log.Printf("Listening (HTTP) on %s ...", listenAddr)
-	log.Fatal(nethttp.ListenAndServe(listenAddr, nil))
+	if reconcileDir != "" {
+		if reconcileInterval <= 0 {
+			cmd.Fail(1, "The reconcile interval must be greater than zero")
+		}
+		r := reconcile.NewReconciler(reconcileDir, conf.Dispatcher)
+		r.DockerSocket = conf.Docker.Socket
+		r.Paused = conf.Maintenance.Enabled
+		log.Printf("Reconciling containers with the manifests in %s every %s", reconcileDir, reconcileInterval)
+		go r.Run(reconcileInterval, nil)
+	}
+
+	if !conf.ReadOnly {
+		if envWatchInterval <= 0 {
+			cmd.Fail(1, "The environment file watch interval must be greater than zero")
+		}
+		if containers.EnvFileWatchDir != "" {
+			if !filepath.IsAbs(containers.EnvFileWatchDir) {
+				cmd.Fail(1, "The environment file watch directory must be an absolute path")
+			}
+			containers.EnvFileWatchDir = filepath.Clean(containers.EnvFileWatchDir)
+		}
+		w := envwatch.NewWatcher(conf.Dispatcher)
+		w.DockerSocket = conf.Docker.Socket
+		w.Paused = conf.Maintenance.Enabled
+		go w.Run(envWatchInterval, nil)
+	}
+
+	var listener net.Listener
+	if systemdSocket {
+		if listener, err = http.SystemdListener(); err != nil {
+			cmd.Fail(1, "Unable to use the socket passed by systemd: %s", err.Error())
+		}
+		if listener == nil {
+			log.Printf("No socket was passed by systemd, listening on %s", listenAddr)
+		}
+	}
+	addr := listenAddr
+	if listener == nil {
+		if listener, err = net.Listen("tcp", listenAddr); err != nil {
+			cmd.Fail(1, "Unable to listen on %s: %s", listenAddr, err.Error())
+		}
+	} else {
+		addr = listener.Addr().String() + " (from systemd)"
+	}
+	if proxyProtocol {
+		listener = http.NewProxyProtocolListener(listener, serverTimeouts.Read)
+		log.Printf("Listening (HTTP, PROXY protocol) on %s ...", addr)
+	} else {
+		log.Printf("Listening (HTTP) on %s ...", addr)
+	}
+	var handler nethttp.Handler = nethttp.DefaultServeMux
+	if accessLog {
+		handler = http.AccessLogHandler(handler, log.New(utils.NewMaskingWriter(os.Stderr), "", 0), http.AccessLogFormat(logFormat))
+	}
+	log.Fatal(http.NewServer(handler, serverTimeouts).Serve(listener))
 }
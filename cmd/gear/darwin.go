@@ -19,6 +19,7 @@ func init() {
 	cmd.AddCommandExtension(sshcmd.RegisterAuthorizedKeys, true)
 	b := &sshcmd.Command{&defaultTransport.TransportFlag}
 	cmd.AddCommandExtension(b.RegisterAddKeys, false)
+	cmd.AddCommandExtension(b.RegisterAddKey, false)
 
 	http.AddHttpExtension(&chttp.HttpExtension{})
 	http.AddHttpExtension(&githttp.HttpExtension{})
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/transport"
+)
+
+func groupStatus(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		gcmd.Fail(1, "Valid arguments: <base-name> [<host>...]")
+	}
+	if outputFormat != "" && outputFormat != "json" {
+		gcmd.Fail(1, "Valid output formats: json")
+	}
+	base := args[0]
+	if _, err := containers.NewIdentifier(base); err != nil {
+		gcmd.Fail(1, "You must pass a valid base name: %s", err.Error())
+	}
+	labelled, err := containers.ParseSelector(cjobs.GroupLabel + "=" + base)
+	if err != nil {
+		gcmd.Fail(1, "The group %s can't be selected by label: %s", base, err.Error())
+	}
+	t, hosts := transportAndHosts(args[1:]...)
+
+	// the members named after the group, and those labelled with it
+	byName, errors := gcmd.Executor{
+		On: hosts,
+		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ListContainersRequest{}
+		},
+		Transport: t,
+	}.Gather()
+	byLabel, failures := gcmd.Executor{
+		On: hosts,
+		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ListContainersRequest{Selector: labelled}
+		},
+		Transport: t,
+	}.Gather()
+	errors = append(errors, failures...)
+
+	names := []string{}
+	seen := make(map[string]bool)
+	for i, data := range append(byName, byLabel...) {
+		list, ok := data.(*cjobs.ListContainersResponse)
+		if !ok {
+			continue
+		}
+		for j := range list.Containers {
+			c := &list.Containers[j]
+			if i < len(byName) && !cjobs.IsGroupMember(base, containers.Identifier(c.Id)) {
+				continue
+			}
+			name := c.Id
+			if c.Server != "" {
+				name = c.Server + "/" + c.Id
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		gcmd.Fail(1, "No containers belong to the group %s", base)
+	}
+	ids, err := gcmd.NewContainerLocators(t, names...)
+	if err != nil {
+		gcmd.Fail(1, "Unable to locate the members of the group: %s", err.Error())
+	}
+
+	// a member whose status can't be read counts against the group
+	var lock sync.Mutex
+	requested := make(map[*cjobs.ContainerStatusRequest]gcmd.Locator)
+	statuses := make(cjobs.ContainerStatusResponses, 0, len(ids))
+	_, failures = gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			req := &cjobs.ContainerStatusRequest{Id: gcmd.AsIdentifier(on), Structured: true, DockerSocket: conf.Docker.Socket}
+			requested[req] = on
+			return req
+		},
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			if status, ok := r.Data.(*cjobs.ContainerStatusResponse); ok {
+				lock.Lock()
+				defer lock.Unlock()
+				statuses = append(statuses, *status)
+			}
+		},
+		OnFailure: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			on := requested[job.(*cjobs.ContainerStatusRequest)]
+			status := cjobs.ContainerStatusResponse{UnitResponse: cjobs.UnitResponse{Id: string(gcmd.AsIdentifier(on)), ActiveState: "unknown"}}
+			if on.TransportLocator() != transport.Local {
+				status.Server = on.TransportLocator().String()
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			statuses = append(statuses, status)
+		},
+		Transport: t,
+	}.Gather()
+	errors = append(errors, failures...)
+
+	group := cjobs.NewGroupStatus(base, statuses)
+	if outputFormat == "json" {
+		json.NewEncoder(os.Stdout).Encode(group)
+	} else {
+		group.WriteTableTo(os.Stdout)
+	}
+	if len(errors) > 0 {
+		for i := range errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errors[i])
+		}
+		os.Exit(gcmd.ExitCodeFor(errors...))
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/openshift/geard/http"
+	"github.com/openshift/geard/transport"
+)
+
+// Implement the flag.Value interface for the daemon commands are sent to
+// when no host is named, given as a URL like docker's -H.  Ids that name
+// a host (<host>/<name>) are still sent to that host.
+type HostFlag struct {
+	locator *http.URLLocator
+}
+
+func (f *HostFlag) String() string {
+	if f.locator == nil {
+		return ""
+	}
+	return f.locator.String()
+}
+
+func (f *HostFlag) Set(s string) error {
+	var locator *http.URLLocator
+	if s != "" {
+		l, err := http.NewURLLocator(s)
+		if err != nil {
+			return err
+		}
+		locator = l
+	}
+	f.locator = locator
+	if t, ok := transport.GetTransport("http"); ok {
+		if remote, ok := t.(*http.HttpTransport); ok {
+			remote.SetDefaultHost(locator)
+		}
+	}
+	return nil
+}
+
+// True if commands without a host go to a remote daemon rather than
+// running locally.
+func (f *HostFlag) IsSet() bool {
+	return f.locator != nil
+}
@@ -8,12 +8,16 @@ import (
 	chttp "github.com/openshift/geard/containers/http"
 	cjobs "github.com/openshift/geard/containers/jobs"
 	initcmd "github.com/openshift/geard/containers/systemd/init"
+	reloadcmd "github.com/openshift/geard/containers/systemd/reload"
+	doctorcmd "github.com/openshift/geard/doctor/cmd"
 	gitcmd "github.com/openshift/geard/git/cmd"
 	githttp "github.com/openshift/geard/git/http"
 	gitjobs "github.com/openshift/geard/git/jobs"
 	"github.com/openshift/geard/http"
 	"github.com/openshift/geard/jobs"
+	portcmd "github.com/openshift/geard/port/cmd"
 	routercmd "github.com/openshift/geard/router/cmd"
+	selftestcmd "github.com/openshift/geard/selftest/cmd"
 	sshcmd "github.com/openshift/geard/ssh/cmd"
 	sshhttp "github.com/openshift/geard/ssh/http"
 	sshjobs "github.com/openshift/geard/ssh/jobs"
@@ -27,10 +31,16 @@ func init() {
 	cmd.AddCommandExtension(sshcmd.RegisterAuthorizedKeys, true)
 	b := &sshcmd.Command{&defaultTransport.TransportFlag}
 	cmd.AddCommandExtension(b.RegisterAddKeys, false)
+	cmd.AddCommandExtension(b.RegisterAddKey, false)
 
 	cmd.AddCommandExtension(cleancmd.RegisterCleanup, true)
-	cmd.AddCommandExtension(initcmd.RegisterInit, true)
+	i := &initcmd.Command{&defaultTransport.TransportFlag}
+	cmd.AddCommandExtension(i.RegisterInit, true)
+	cmd.AddCommandExtension(reloadcmd.RegisterReload, true)
+	cmd.AddCommandExtension(doctorcmd.RegisterDoctor, true)
+	cmd.AddCommandExtension(portcmd.RegisterPorts, true)
 	cmd.AddCommandExtension(routercmd.RegisterRouter, true)
+	cmd.AddCommandExtension(selftestcmd.RegisterSelfTest, true)
 
 	jobs.AddJobExtension(cjobs.NewContainerExtension())
 	jobs.AddJobExtension(gitjobs.NewGitExtension())
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/openshift/geard/http"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/transport"
 )
@@ -17,6 +18,19 @@ func (t *LocalTransportFlag) Set(name string) error {
 	return nil
 }
 
+// Return the transport, asking remote servers to queue jobs rather than
+// wait for them if --detach was passed, and to queue them with the
+// --priority that was passed.
+func (t *LocalTransportFlag) Get() transport.Transport {
+	if local, ok := t.Transport.(*localTransport); ok {
+		if remote, ok := local.remote.(*http.HttpTransport); ok {
+			remote.SetDetach(detach)
+			remote.SetPriority(priority)
+		}
+	}
+	return t.Transport
+}
+
 // Create a transport that will invoke the default job implementation for a given
 // request with a local locator, and pass any other requests to the remote transport.
 type localTransport struct {
@@ -24,7 +38,7 @@ type localTransport struct {
 }
 
 func (h *localTransport) LocatorFor(value string) (transport.Locator, error) {
-	if transport.Local.String() != value {
+	if transport.Local.String() != value || defaultHost.IsSet() {
 		return h.remote.LocatorFor(value)
 	}
 	return transport.Local, nil
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/openshift/geard/containers"
+)
+
+// Implement the flag.Value interface for the container runtime, rejecting
+// runtimes that are not registered.
+type RuntimeFlag struct{}
+
+func (f *RuntimeFlag) String() string {
+	return containers.RuntimeName
+}
+
+func (f *RuntimeFlag) Set(name string) error {
+	if err := containers.CheckRuntimeName(name); err != nil {
+		return err
+	}
+	containers.RuntimeName = name
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gcmd "github.com/openshift/geard/cmd"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/containers/pipeline"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/transport"
+)
+
+func upContainers(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		gcmd.Fail(1, "Valid arguments: <manifest>")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		gcmd.Fail(1, "Unable to open the manifest: %s", err.Error())
+	}
+	manifest, err := pipeline.ReadManifest(file)
+	file.Close()
+	if err != nil {
+		gcmd.Fail(1, "%s", err.Error())
+	}
+	ordered, err := manifest.Order()
+	if err != nil {
+		gcmd.Fail(1, "%s", err.Error())
+	}
+
+	t := defaultTransport.Get()
+	installer := &upInstaller{t, make(map[*pipeline.Container]gcmd.Locator)}
+	for _, c := range ordered {
+		ids, err := gcmd.NewContainerLocators(t, c.Name)
+		if err != nil {
+			gcmd.Fail(1, "The container %s does not have a valid name: %s", c.Name, err.Error())
+		}
+		installer.locators[c] = ids[0]
+	}
+
+	result := pipeline.Run(ordered, installer, atomic, os.Stdout)
+	if len(result.Errors) > 0 {
+		for i := range result.Errors {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", result.Errors[i].Error())
+		}
+		os.Exit(gcmd.ExitCodeFor(result.Errors...))
+	}
+	os.Exit(0)
+}
+
+// Installs and removes the containers of a manifest one at a time with the
+// same jobs as 'gear install' and 'gear delete'.
+type upInstaller struct {
+	transport transport.Transport
+	locators  map[*pipeline.Container]gcmd.Locator
+}
+
+func (u *upInstaller) Install(c *pipeline.Container, out io.Writer) error {
+	return u.run(c, out, func(on gcmd.Locator) gcmd.JobRequest {
+		r := c.Install
+		r.RequestIdentifier = jobs.NewRequestIdentifier()
+		r.Id = gcmd.AsIdentifier(on)
+		r.DockerSocket = conf.Docker.Socket
+		return &r
+	})
+}
+
+func (u *upInstaller) Remove(c *pipeline.Container, out io.Writer) error {
+	return u.run(c, out, func(on gcmd.Locator) gcmd.JobRequest {
+		return &cjobs.DeleteContainerRequest{Id: gcmd.AsIdentifier(on)}
+	})
+}
+
+func (u *upInstaller) run(c *pipeline.Container, out io.Writer, request func(gcmd.Locator) gcmd.JobRequest) error {
+	failures := gcmd.Executor{
+		On:        gcmd.Locators{u.locators[c]},
+		Serial:    request,
+		Output:    out,
+		Transport: u.transport,
+	}.Stream()
+	if len(failures) > 0 {
+		return failures[0]
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Read a list of hosts, one per line.  Blank lines and lines starting
+// with '#' are ignored, and a host listed twice is only returned once.
+func ReadHostsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hosts := []string{}
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// accept the server part of a container locator, 'host/'
+		host := strings.TrimSuffix(line, "/")
+		if strings.ContainsAny(host, "/ \t") {
+			return nil, fmt.Errorf("The hosts file %s may only list one host per line: %q", path, line)
+		}
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("The hosts file %s does not list any hosts", path)
+	}
+	return hosts, nil
+}
+
+// Locate each name on every host as '<host>/<name>', in host order.  The
+// names may not already name a host.
+func ExpandHosts(hosts []string, names ...string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, errors.New("You must pass one or more names to look for on each host")
+	}
+	for _, name := range names {
+		if strings.Contains(name, "/") {
+			return nil, fmt.Errorf("The name %s may not include a host when hosts are read from a file", name)
+		}
+	}
+	values := make([]string, 0, len(hosts)*len(names))
+	for _, host := range hosts {
+		for _, name := range names {
+			values = append(values, host+"/"+name)
+		}
+	}
+	return values, nil
+}
+
+// The outcome of a job run against a container on one of many hosts.
+type HostResult struct {
+	Host        string
+	Id          string
+	ActiveState string `json:"ActiveState,omitempty"`
+	SubState    string `json:"SubState,omitempty"`
+	// The host could not be reached, as opposed to the job failing on it
+	Unreachable bool   `json:"Unreachable,omitempty"`
+	Error       string `json:"Error,omitempty"`
+}
+
+type HostResults []HostResult
+
+func (r HostResults) Len() int      { return len(r) }
+func (r HostResults) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r HostResults) Less(i, j int) bool {
+	if r[i].Host != r[j].Host {
+		return r[i].Host < r[j].Host
+	}
+	return r[i].Id < r[j].Id
+}
+
+// The number of hosts that could not be reached, and of jobs that failed
+// on hosts that were.
+func (r HostResults) Failures() (unreachable, failed int) {
+	for i := range r {
+		switch {
+		case r[i].Unreachable:
+			unreachable++
+		case r[i].Error != "":
+			failed++
+		}
+	}
+	return
+}
+
+// Write one row per host and container, in host order.
+func (r HostResults) WriteTableTo(w io.Writer) error {
+	sorted := make(HostResults, len(r))
+	copy(sorted, r)
+	sort.Sort(sorted)
+
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", "HOST", "ID", "ACTIVE", "SUB", "ERROR"); err != nil {
+		return err
+	}
+	for i := range sorted {
+		result := &sorted[i]
+		active, sub, message := result.ActiveState, result.SubState, result.Error
+		switch {
+		case result.Unreachable:
+			active, sub = "unreachable", "-"
+		case message != "":
+			active, sub = "failed", "-"
+		}
+		if message == "" {
+			message = "-"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.Host, result.Id, active, sub, message); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
@@ -0,0 +1,145 @@
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/openshift/geard/cmd"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/transport"
+)
+
+// Answers status requests from many hosts at once, failing those listed
+// in down, and records the most requests in flight.
+type fleetTransport struct {
+	down map[string]bool
+
+	lock     sync.Mutex
+	running  int
+	peak     int
+	answered []string
+}
+
+func (t *fleetTransport) LocatorFor(value string) (transport.Locator, error) {
+	return &testLocator{value}, nil
+}
+
+func (t *fleetTransport) RemoteJobFor(locator transport.Locator, job interface{}) (jobs.Job, error) {
+	host := locator.String()
+	return jobs.JobFunction(func(res jobs.Response) {
+		t.lock.Lock()
+		t.running++
+		if t.running > t.peak {
+			t.peak = t.running
+		}
+		t.answered = append(t.answered, host)
+		t.lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		t.lock.Lock()
+		t.running--
+		t.lock.Unlock()
+		if t.down[host] {
+			res.Failure(errors.New("connection refused"))
+			return
+		}
+		res.SuccessWithData(jobs.ResponseOk, &cjobs.ContainerStatusResponse{})
+	}), nil
+}
+
+func TestStatusFromHostsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-hosts")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts.txt")
+	ioutil.WriteFile(path, []byte("# web tier\nhost1\nhost2:43273/\n\n  host3  \nhost1\nhost4\n"), 0644)
+
+	hosts, err := ReadHostsFile(path)
+	if err != nil {
+		t.Fatalf("Unable to read the hosts file: %v", err)
+	}
+	if strings.Join(hosts, ",") != "host1,host2:43273,host3,host4" {
+		t.Fatalf("Unexpected hosts %v", hosts)
+	}
+	values, err := ExpandHosts(hosts, "web-1")
+	if err != nil {
+		t.Fatalf("Unable to expand the hosts: %v", err)
+	}
+	trans := &fleetTransport{down: map[string]bool{"host3": true}}
+	locators, err := NewContainerLocators(trans, values...)
+	if err != nil {
+		t.Fatalf("Unable to locate the containers: %v", err)
+	}
+
+	var lock sync.Mutex
+	results := HostResults{}
+	_, errs := Executor{
+		On: locators,
+		Serial: func(on Locator) JobRequest {
+			return &cjobs.ContainerStatusRequest{Id: AsIdentifier(on)}
+		},
+		OnFailure: func(res *CliJobResponse, w io.Writer, job JobRequest) {
+			lock.Lock()
+			defer lock.Unlock()
+			results = append(results, HostResult{Id: string(job.(*cjobs.ContainerStatusRequest).Id), Unreachable: true, Error: res.Error.Error()})
+		},
+		Transport: trans,
+		Limit:     2,
+	}.Gather()
+
+	if len(trans.answered) != 4 {
+		t.Errorf("Expected every host to be queried once, got %v", trans.answered)
+	}
+	if trans.peak > 2 {
+		t.Errorf("Expected at most 2 hosts to be queried at once, got %d", trans.peak)
+	}
+	if len(errs) != 1 || len(results) != 1 || results[0].Id != "web-1" {
+		t.Errorf("Expected the down host to fail, got %v %+v", errs, results)
+	}
+
+	if _, err := ExpandHosts(hosts, "host1/web-1"); err == nil {
+		t.Error("Expected a name that includes a host to be rejected")
+	}
+	ioutil.WriteFile(path, []byte("# nothing\n"), 0644)
+	if _, err := ReadHostsFile(path); err == nil {
+		t.Error("Expected a file without hosts to be rejected")
+	}
+	ioutil.WriteFile(path, []byte("host1 host2\n"), 0644)
+	if _, err := ReadHostsFile(path); err == nil {
+		t.Error("Expected several hosts on a line to be rejected")
+	}
+}
+
+func TestHostResultsTable(t *testing.T) {
+	results := HostResults{
+		{Host: "host2", Id: "web-1", Unreachable: true, Error: "connection refused"},
+		{Host: "host1", Id: "web-1", ActiveState: "active", SubState: "running"},
+		{Host: "host3", Id: "web-1", Error: "not found"},
+	}
+	if unreachable, failed := results.Failures(); unreachable != 1 || failed != 1 {
+		t.Errorf("Expected one unreachable host and one failure, got %d %d", unreachable, failed)
+	}
+	buf := &bytes.Buffer{}
+	results.WriteTableTo(buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a row per host, got %q", buf.String())
+	}
+	for i, expected := range []string{"host1 web-1 active running -", "host2 web-1 unreachable - connection refused", "host3 web-1 failed - not found"} {
+		if strings.Join(strings.Fields(lines[i+1]), " ") != expected {
+			t.Errorf("Expected row %q, got %q", expected, lines[i+1])
+		}
+	}
+}
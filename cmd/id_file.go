@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/openshift/geard/containers"
+)
+
+// Write the id and unit name of each container, one '<id> <unit name>'
+// line per container, so that scripts can find what was created without
+// parsing the output of a command.
+func WriteIdFile(path string, ids []containers.Identifier) error {
+	var buf bytes.Buffer
+	for _, id := range ids {
+		fmt.Fprintf(&buf, "%s %s\n", id, id.UnitNameFor())
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,35 @@
+package cmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+)
+
+func TestWriteIdFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-id-file")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ids")
+
+	if err := WriteIdFile(path, []containers.Identifier{"web"}); err != nil {
+		t.Fatalf("Unable to write the id file: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "web ctr-web.service\n" {
+		t.Errorf("Unexpected id file for a single container %q", string(data))
+	}
+
+	// the file is replaced, not appended to
+	if err := WriteIdFile(path, []containers.Identifier{"web-1", "web-2"}); err != nil {
+		t.Fatalf("Unable to write the id file: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "web-1 ctr-web-1.service\nweb-2 ctr-web-2.service\n" {
+		t.Errorf("Unexpected id file for several containers %q", string(data))
+	}
+}
@@ -18,10 +18,14 @@ type CliJobResponse struct {
 
 	// Data gathered during request parsing FIXME: move to marshal?
 	Pending map[string]interface{}
+	// Data written after the response has been streamed
+	Trailers map[string]string
 	// Data gathered from the response
 	Data interface{}
 	// The error set on the response
 	Error error
+	// True if the content was unchanged and so not returned
+	NotModified bool
 
 	succeeded bool
 	failed    bool
@@ -43,6 +47,7 @@ func (s *CliJobResponse) Success(t jobs.ResponseSuccess) {
 		panic("Cannot call Success() twice")
 	}
 	s.succeeded = true
+	s.NotModified = t == jobs.ResponseNotModified
 	if !s.Gather {
 		s.WritePending(s.Output)
 	}
@@ -77,6 +82,13 @@ func (s *CliJobResponse) WritePendingSuccess(name string, value interface{}) {
 	s.Pending[name] = value
 }
 
+func (s *CliJobResponse) WriteTrailer(name string, value string) {
+	if s.Trailers == nil {
+		s.Trailers = make(map[string]string)
+	}
+	s.Trailers[name] = value
+}
+
 func (s *CliJobResponse) Failure(e error) {
 	if s.succeeded {
 		panic("May not invoke failure after Success()")
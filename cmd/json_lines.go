@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// One line of --json-lines output.
+type JSONLine struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// A line of text written by a job or read from a log.
+type JSONLinesText struct {
+	Line string `json:"line"`
+}
+
+// A job of an executor that started or finished, or a failure that
+// happened before any job ran.
+type JSONLinesJob struct {
+	Id    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Writes events to an output as they happen, one JSON object per line.
+// Each line is flushed as soon as it is written so that consumers reading
+// the output incrementally see every event without waiting for the
+// command to finish.  Safe for use by several goroutines.
+type JSONLines struct {
+	lock sync.Mutex
+	out  io.Writer
+}
+
+type flusher interface {
+	Flush() error
+}
+
+func NewJSONLines(out io.Writer) *JSONLines {
+	return &JSONLines{out: out}
+}
+
+// Write an event of type t with payload as a single line.
+func (j *JSONLines) Emit(t string, payload interface{}) error {
+	data, err := json.Marshal(JSONLine{t, payload})
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if _, err := j.out.Write(data); err != nil {
+		return err
+	}
+	if f, ok := j.out.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// A writer that emits each line written to it as an event of type t with
+// a JSONLinesText payload.  Close emits any final partial line.
+func (j *JSONLines) LineWriter(t string) io.WriteCloser {
+	return &jsonLinesWriter{lines: j, t: t}
+}
+
+type jsonLinesWriter struct {
+	lines *JSONLines
+	t     string
+	lock  sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (w *jsonLinesWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(bytes.TrimRight(w.buf.Next(i+1), "\r\n"))
+		if err := w.lines.Emit(w.t, JSONLinesText{line}); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (w *jsonLinesWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := w.buf.String()
+	w.buf.Reset()
+	return w.lines.Emit(w.t, JSONLinesText{line})
+}
+
+// Run the jobs, emitting a "started" and a "finished" event as each job
+// starts and finishes, and each line of their output as an "output"
+// event.  Executors that use Group report their jobs against the first of
+// their locators.  A failure before any job ran is emitted as an "error"
+// event.
+func (e Executor) StreamJSONLines(j *JSONLines) []error {
+	output := j.LineWriter("output")
+	events := make(chan ExecutorEvent)
+	e.Output = output
+	e.Events = events
+
+	started := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			started = true
+			job := JSONLinesJob{Id: progressName(event.Locator)}
+			if event.Type == JobStarted {
+				j.Emit("started", job)
+				continue
+			}
+			if event.Response != nil && event.Response.Error != nil {
+				job.Error = event.Response.Error.Error()
+			}
+			j.Emit("finished", job)
+		}
+	}()
+	failures := e.Stream()
+	<-done
+	output.Close()
+	if !started {
+		for i := range failures {
+			j.Emit("error", JSONLinesJob{Error: failures[i].Error()})
+		}
+	}
+	return failures
+}
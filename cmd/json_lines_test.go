@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Records what had been written each time it was flushed
+type flushRecorder struct {
+	bytes.Buffer
+	flushes []string
+}
+
+func (f *flushRecorder) Flush() error {
+	f.flushes = append(f.flushes, f.String())
+	return nil
+}
+
+func decodeJSONLines(t *testing.T, out string) []JSONLine {
+	if !strings.HasSuffix(out, "\n") {
+		t.Fatalf("Expected the output to end with a newline: %q", out)
+	}
+	lines := []JSONLine{}
+	for _, s := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		line := JSONLine{}
+		if err := json.Unmarshal([]byte(s), &line); err != nil {
+			t.Fatalf("Expected each line to be a JSON object, got %q: %v", s, err)
+		}
+		if line.Type == "" {
+			t.Errorf("Expected each line to have a type: %q", s)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestJSONLinesFlushesEachLine(t *testing.T) {
+	out := &flushRecorder{}
+	j := NewJSONLines(out)
+	w := j.LineWriter("log")
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\r\nthi"))
+	j.Emit("status", map[string]string{"state": "running"})
+	w.Write([]byte("rd"))
+	w.Close()
+
+	if len(out.flushes) != 4 {
+		t.Fatalf("Expected a flush per line, got %d: %q", len(out.flushes), out.flushes)
+	}
+	for i, flushed := range out.flushes {
+		if !strings.HasSuffix(flushed, "\n") || strings.Count(flushed, "\n") != i+1 {
+			t.Errorf("Expected flush %d to follow a complete line, got %q", i, flushed)
+		}
+	}
+
+	lines := decodeJSONLines(t, out.String())
+	expected := []string{
+		`{"type":"log","payload":{"line":"first"}}`,
+		`{"type":"log","payload":{"line":"second"}}`,
+		`{"type":"status","payload":{"state":"running"}}`,
+		`{"type":"log","payload":{"line":"third"}}`,
+	}
+	for i := range lines {
+		if s := strings.Split(out.String(), "\n")[i]; s != expected[i] {
+			t.Errorf("Expected line %d to be %s, got %s", i, expected[i], s)
+		}
+	}
+}
+
+func TestStreamJSONLines(t *testing.T) {
+	on := Locators{
+		&ResourceLocator{ResourceTypeContainer, "test-a", progressLocator("up")},
+		&ResourceLocator{ResourceTypeContainer, "test-b", progressLocator("down")},
+	}
+	out := &flushRecorder{}
+	failures := Executor{
+		On:        on,
+		Serial:    func(on Locator) JobRequest { return on },
+		Transport: progressTransport{},
+	}.StreamJSONLines(NewJSONLines(out))
+
+	if len(failures) != 1 {
+		t.Fatalf("Expected one job to fail, got %v", failures)
+	}
+	lines := decodeJSONLines(t, out.String())
+	if len(lines) != 4 || len(out.flushes) != 4 {
+		t.Fatalf("Expected a started and a finished event for each job, each flushed:\n%s", out.String())
+	}
+	finished := map[string]string{}
+	for _, line := range lines {
+		payload := line.Payload.(map[string]interface{})
+		if line.Type == "finished" {
+			err, _ := payload["error"].(string)
+			finished[payload["id"].(string)] = err
+		}
+	}
+	if err, ok := finished["up/test-a"]; !ok || err != "" {
+		t.Errorf("Expected test-a to finish without an error: %v", finished)
+	}
+	if finished["down/test-b"] != "connection refused" {
+		t.Errorf("Expected test-b to finish with its error: %v", finished)
+	}
+
+	out = &flushRecorder{}
+	Executor{
+		On:        on,
+		Serial:    func(on Locator) JobRequest { return invalidRequest{} },
+		Transport: progressTransport{},
+	}.StreamJSONLines(NewJSONLines(out))
+	if s := strings.TrimSpace(out.String()); s != `{"type":"error","payload":{"error":"not valid"}}` {
+		t.Errorf("Expected a failure before any job to be an error event, got %s", s)
+	}
+}
+
+type invalidRequest struct{}
+
+func (invalidRequest) Check() error { return errors.New("not valid") }
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/transport"
+	"strconv"
 	"strings"
 )
 
@@ -125,6 +127,74 @@ func NewContainerLocators(t transport.Transport, values ...string) (Locators, er
 	return locators, nil
 }
 
+// Expand container names into the identifiers of numbered instances.  A
+// name of the form <name>@<index> becomes the single instance
+// <name>-<index>, and if scale is greater than zero every other name
+// becomes the instances <name>-1 through <name>-<scale>.  Instances are
+// never named with '@', which systemd reserves for instances of template
+// units, so web@1 would otherwise be taken as an instance of web@.service.
+func ExpandContainerInstances(values []string, scale int) ([]string, error) {
+	out := make([]string, 0, len(values))
+	for i := range values {
+		_, _, id, err := SplitTypeHostSuffix(values[i])
+		if err != nil {
+			return nil, err
+		}
+		prefix := strings.TrimSuffix(values[i], id)
+
+		if at := strings.LastIndex(id, "@"); at != -1 {
+			if scale > 0 {
+				return nil, fmt.Errorf("%s already names a single instance and cannot be scaled", values[i])
+			}
+			index, err := strconv.Atoi(id[at+1:])
+			if err != nil || index < 1 {
+				return nil, fmt.Errorf("The instance index of %s must be a positive number", values[i])
+			}
+			instance, err := containers.NewIndexedIdentifier(id[:at], index)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, prefix+string(instance))
+			continue
+		}
+
+		if scale < 1 {
+			out = append(out, values[i])
+			continue
+		}
+		for index := 1; index <= scale; index++ {
+			instance, err := containers.NewIndexedIdentifier(id, index)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, prefix+string(instance))
+		}
+	}
+	return out, nil
+}
+
+// Name the variant of each container, as in "web--canary" for the canary
+// variant of "web", keeping any host prefix.  An empty variant leaves the
+// names unchanged.
+func ExpandContainerVariant(values []string, variant string) ([]string, error) {
+	if variant == "" {
+		return values, nil
+	}
+	out := make([]string, 0, len(values))
+	for i := range values {
+		_, _, id, err := SplitTypeHostSuffix(values[i])
+		if err != nil {
+			return nil, err
+		}
+		named, err := containers.NewVariantIdentifier(id, variant)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, strings.TrimSuffix(values[i], id)+string(named))
+	}
+	return out, nil
+}
+
 // Given a command line string representing a resource, break it into type, host identity, and suffix
 func SplitTypeHostSuffix(value string) (res ResourceType, host string, suffix string, err error) {
 	if value == "" {
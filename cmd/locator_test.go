@@ -0,0 +1,53 @@
+package cmd_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/openshift/geard/cmd"
+)
+
+func TestExpandContainerInstances(t *testing.T) {
+	names, err := ExpandContainerInstances([]string{"web@1", "host:2223/web@2", "web-3", "db"}, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"web-1", "host:2223/web-2", "web-3", "db"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	names, err = ExpandContainerInstances([]string{"web", "host:2223/api"}, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"web-1", "web-2", "web-3", "host:2223/api-1", "host:2223/api-2", "host:2223/api-3"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+
+	for _, invalid := range []string{"web@", "web@0", "web@a"} {
+		if _, err := ExpandContainerInstances([]string{invalid}, 0); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+	if _, err := ExpandContainerInstances([]string{"web@1"}, 2); err == nil {
+		t.Error("Expected a single instance to be rejected when scaling")
+	}
+}
+
+func TestExpandContainerVariant(t *testing.T) {
+	names, err := ExpandContainerVariant([]string{"web-1", "host:2223/web-2"}, "canary")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"web-1--canary", "host:2223/web-2--canary"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+	if names, _ := ExpandContainerVariant([]string{"web"}, ""); !reflect.DeepEqual(names, []string{"web"}) {
+		t.Errorf("Expected the names to be unchanged without a variant, got %v", names)
+	}
+	for _, invalid := range []string{"Canary", "can-ary"} {
+		if _, err := ExpandContainerVariant([]string{"web"}, invalid); err == nil {
+			t.Errorf("Expected the variant %s to be rejected", invalid)
+		}
+	}
+}
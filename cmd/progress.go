@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// True if w is a terminal.  Replaced in tests.
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// True if jobs against count destinations should be shown as a live
+// progress view on out rather than streamed: out must be a terminal, there
+// must be more than one destination, the user must not have passed
+// --no-tty, and no structured output format may be requested.
+func ShowProgress(out io.Writer, count int, noTty bool, format string) bool {
+	return !noTty && format == "" && count > 1 && isTerminal(out)
+}
+
+// Run the jobs showing one line per destination on out, redrawn in place
+// as each job starts and finishes, then exit with the code for any
+// failures.  The output of the jobs is discarded and the error of a failed
+// job is shown on its line.  Executors that use Group are streamed.
+func (e Executor) ProgressAndExit(out io.Writer) {
+	if e.Group != nil {
+		e.StreamAndExit()
+	}
+	events := make(chan ExecutorEvent)
+	e.Events = events
+	e.Output = ioutil.Discard
+
+	view := newProgressView(out, e.On)
+	done := make(chan struct{})
+	go func() {
+		view.watch(events, 100*time.Millisecond)
+		close(done)
+	}()
+	failures := e.Stream()
+	<-done
+
+	if len(failures) > 0 {
+		// failures from before any job ran have no line to be shown on
+		if !view.started {
+			for i := range failures {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", failures[i].Error())
+			}
+		}
+		os.Exit(ExitCodeFor(failures...))
+	}
+	os.Exit(0)
+}
+
+type progressState int
+
+const (
+	progressWaiting progressState = iota
+	progressRunning
+	progressDone
+	progressFailed
+)
+
+type progressLine struct {
+	name   string
+	state  progressState
+	status string
+}
+
+type progressView struct {
+	out     io.Writer
+	lines   []progressLine
+	index   map[string]int
+	width   int
+	frame   int
+	drawn   int
+	started bool
+}
+
+func newProgressView(out io.Writer, on Locators) *progressView {
+	p := &progressView{out: out, index: make(map[string]int)}
+	for i := range on {
+		name := progressName(on[i])
+		if _, ok := p.index[name]; ok {
+			continue
+		}
+		p.index[name] = len(p.lines)
+		p.lines = append(p.lines, progressLine{name: name, status: "waiting"})
+		if len(name) > p.width {
+			p.width = len(name)
+		}
+	}
+	return p
+}
+
+// The name of a container, without its resource type, as shown in the
+// progress view and --json-lines output
+func progressName(l Locator) string {
+	if r, ok := l.(*ResourceLocator); ok {
+		return strings.TrimPrefix(r.Identity(), string(r.Type)+"://")
+	}
+	return l.Identity()
+}
+
+// Redraw the view as events arrive, and every interval to advance the
+// spinners, until events is closed.
+func (p *progressView) watch(events <-chan ExecutorEvent, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	p.draw()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				p.draw()
+				return
+			}
+			p.update(e)
+		case <-ticker.C:
+			p.frame++
+		}
+		p.draw()
+	}
+}
+
+func (p *progressView) update(e ExecutorEvent) {
+	i, ok := p.index[progressName(e.Locator)]
+	if !ok {
+		return
+	}
+	p.started = true
+	line := &p.lines[i]
+	switch {
+	case e.Type == JobStarted:
+		line.state, line.status = progressRunning, "running"
+	case e.Response != nil && e.Response.Error != nil:
+		line.state, line.status = progressFailed, "failed: "+e.Response.Error.Error()
+	default:
+		line.state, line.status = progressDone, "done"
+	}
+}
+
+// Move back over the lines drawn last time and write each line again.
+func (p *progressView) draw() {
+	buf := &bytes.Buffer{}
+	if p.drawn > 0 {
+		fmt.Fprintf(buf, "\033[%dA", p.drawn)
+	}
+	for _, line := range p.lines {
+		var symbol string
+		switch line.state {
+		case progressWaiting:
+			symbol = " "
+		case progressRunning:
+			symbol = spinnerFrames[p.frame%len(spinnerFrames)]
+		case progressDone:
+			symbol = "✓"
+		case progressFailed:
+			symbol = "✗"
+		}
+		fmt.Fprintf(buf, "\r\033[2K%s %-*s  %s\n", symbol, p.width, line.name, line.status)
+	}
+	p.drawn = len(p.lines)
+	buf.WriteTo(p.out)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/transport"
+)
+
+type progressLocator string
+
+func (l progressLocator) String() string                   { return string(l) }
+func (l progressLocator) ResolveHostname() (string, error) { return string(l), nil }
+
+// Fails the jobs of hosts named "down"
+type progressTransport struct{}
+
+func (t progressTransport) LocatorFor(value string) (transport.Locator, error) {
+	return progressLocator(value), nil
+}
+func (t progressTransport) RemoteJobFor(locator transport.Locator, job interface{}) (jobs.Job, error) {
+	return jobs.JobFunction(func(res jobs.Response) {
+		if locator.String() == "down" {
+			res.Failure(errors.New("connection refused"))
+			return
+		}
+		res.Success(jobs.ResponseOk)
+	}), nil
+}
+
+func TestShowProgress(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if ShowProgress(w, 2, false, "") {
+		t.Error("Expected output to a pipe to be streamed")
+	}
+
+	previous := isTerminal
+	defer func() { isTerminal = previous }()
+	isTerminal = func(io.Writer) bool { return true }
+
+	if !ShowProgress(os.Stdout, 2, false, "") {
+		t.Error("Expected progress to be shown on a terminal")
+	}
+	if ShowProgress(os.Stdout, 2, true, "") {
+		t.Error("Expected --no-tty to stream the output")
+	}
+	if ShowProgress(os.Stdout, 2, false, "json") {
+		t.Error("Expected --output json to stream the output")
+	}
+	if ShowProgress(os.Stdout, 1, false, "") {
+		t.Error("Expected the output of a single container to be streamed")
+	}
+}
+
+func TestProgressView(t *testing.T) {
+	on := Locators{
+		&ResourceLocator{ResourceTypeContainer, "test-a", progressLocator("up")},
+		&ResourceLocator{ResourceTypeContainer, "test-b", progressLocator("down")},
+	}
+	out := &bytes.Buffer{}
+	view := newProgressView(out, on)
+	events := make(chan ExecutorEvent)
+	done := make(chan struct{})
+	go func() {
+		view.watch(events, time.Millisecond)
+		close(done)
+	}()
+
+	failures := Executor{
+		On:        on,
+		Serial:    func(on Locator) JobRequest { return on },
+		Transport: progressTransport{},
+		Events:    events,
+	}.Stream()
+	<-done
+
+	if len(failures) != 1 || !view.started {
+		t.Fatalf("Expected one job to fail, got %v", failures)
+	}
+	frames := strings.Split(out.String(), "\033[2A")
+	last := frames[len(frames)-1]
+	expected := "\r\033[2K✓ up/test-a    done\n\r\033[2K✗ down/test-b  failed: connection refused\n"
+	if last != expected {
+		t.Errorf("Expected the view to end with\n%q\ngot\n%q", expected, last)
+	}
+	if !strings.HasPrefix(out.String(), "\r\033[2K  up/test-a    waiting\n") {
+		t.Errorf("Expected each container to be shown waiting first:\n%q", out.String())
+	}
+}
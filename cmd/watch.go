@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+// Call refresh every interval until stop is closed.  If clear is true the
+// terminal is cleared before each refresh so that the output is redrawn
+// in place.  Each refresh is buffered and written at once to avoid
+// flickering.
+func Watch(w io.Writer, interval time.Duration, clear bool, stop <-chan struct{}, refresh func(io.Writer)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		buf := &bytes.Buffer{}
+		refresh(buf)
+		if clear {
+			fmt.Fprint(w, clearScreen)
+		}
+		buf.WriteTo(w)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/openshift/geard/cmd"
+)
+
+func TestWatchRedrawsUntilStopped(t *testing.T) {
+	out := &bytes.Buffer{}
+	stop := make(chan struct{})
+	polls := 0
+	Watch(out, time.Millisecond, true, stop, func(w io.Writer) {
+		polls++
+		fmt.Fprintf(w, "poll %d\n", polls)
+		if polls == 3 {
+			close(stop)
+		}
+	})
+
+	if polls != 3 {
+		t.Fatalf("Expected 3 polls before stopping, got %d", polls)
+	}
+	if n := strings.Count(out.String(), "\033[H\033[2J"); n != 3 {
+		t.Errorf("Expected the screen to be cleared before each poll, got %d clears", n)
+	}
+	if !strings.HasSuffix(out.String(), "poll 3\n") {
+		t.Errorf("Expected the last poll to be drawn, got %q", out.String())
+	}
+}
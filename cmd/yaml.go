@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Write v to w as a YAML document.  v is marshalled to JSON first, so
+// the document has the same keys in the same order as the JSON output of
+// the same value.  Multi-line strings are written as literal blocks so
+// that files such as units stay readable.
+func WriteYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := readYAMLValue(decoder)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	switch t := value.(type) {
+	case yamlMap:
+		if len(t) == 0 {
+			buf.WriteString("{}\n")
+		}
+		writeYAMLMap(buf, t, 0)
+	case []interface{}:
+		if len(t) == 0 {
+			buf.WriteString("[]\n")
+		}
+		writeYAMLArray(buf, t, 0)
+	default:
+		writeYAMLField(buf, value, 0)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// The fields of a JSON object, in the order they were written
+type yamlMap []yamlField
+
+type yamlField struct {
+	Key   string
+	Value interface{}
+}
+
+// Read the next value from the decoder as a yamlMap, an []interface{} or
+// a scalar.
+func readYAMLValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		m := yamlMap{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readYAMLValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, yamlField{key.(string), value})
+		}
+		_, err := decoder.Token()
+		return m, err
+	case json.Delim('['):
+		a := []interface{}{}
+		for decoder.More() {
+			value, err := readYAMLValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err := decoder.Token()
+		return a, err
+	}
+	return token, nil
+}
+
+func writeYAMLMap(buf *bytes.Buffer, m yamlMap, indent int) {
+	for _, field := range m {
+		buf.WriteString(strings.Repeat(" ", indent))
+		buf.WriteString(yamlScalar(field.Key))
+		buf.WriteString(":")
+		writeYAMLField(buf, field.Value, indent)
+	}
+}
+
+func writeYAMLArray(buf *bytes.Buffer, a []interface{}, indent int) {
+	for _, value := range a {
+		// the first line of a nested map or array follows the dash
+		item := &bytes.Buffer{}
+		switch t := value.(type) {
+		case yamlMap:
+			writeYAMLMap(item, t, indent+2)
+		case []interface{}:
+			writeYAMLArray(item, t, indent+2)
+		}
+		if item.Len() > 0 {
+			buf.WriteString(strings.Repeat(" ", indent))
+			buf.WriteString("- ")
+			buf.Write(item.Bytes()[indent+2:])
+			continue
+		}
+		buf.WriteString(strings.Repeat(" ", indent))
+		buf.WriteString("-")
+		writeYAMLField(buf, value, indent)
+	}
+}
+
+// Write the value of a key or an item of an array whose line has already
+// been started at indent.
+func writeYAMLField(buf *bytes.Buffer, value interface{}, indent int) {
+	prefix := " "
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] == '\n' {
+		prefix = ""
+	}
+	switch t := value.(type) {
+	case yamlMap:
+		if len(t) == 0 {
+			buf.WriteString(prefix + "{}\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAMLMap(buf, t, indent+2)
+	case []interface{}:
+		if len(t) == 0 {
+			buf.WriteString(prefix + "[]\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAMLArray(buf, t, indent+2)
+	case string:
+		if lines, chomp, ok := yamlLiteralLines(t); ok {
+			buf.WriteString(prefix + "|" + chomp + "\n")
+			for _, line := range lines {
+				if line != "" {
+					buf.WriteString(strings.Repeat(" ", indent+2))
+					buf.WriteString(line)
+				}
+				buf.WriteString("\n")
+			}
+			return
+		}
+		buf.WriteString(prefix + yamlScalar(t) + "\n")
+	case json.Number:
+		buf.WriteString(prefix + t.String() + "\n")
+	case bool:
+		if t {
+			buf.WriteString(prefix + "true\n")
+		} else {
+			buf.WriteString(prefix + "false\n")
+		}
+	default:
+		buf.WriteString(prefix + "null\n")
+	}
+}
+
+var (
+	yamlPlainScalar    = regexp.MustCompile("\\A[a-zA-Z_/][-a-zA-Z0-9_/.@+=,: ]*\\z")
+	yamlReservedScalar = regexp.MustCompile("(?i)\\A(y|n|yes|no|true|false|on|off|null)\\z")
+)
+
+// A string written plainly if YAML would read it back as the same string,
+// and as a quoted JSON string otherwise.
+func yamlScalar(s string) string {
+	if yamlPlainScalar.MatchString(s) && !yamlReservedScalar.MatchString(s) &&
+		!strings.Contains(s, ": ") && !strings.HasSuffix(s, ":") && !strings.HasSuffix(s, " ") {
+		return s
+	}
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// The lines of a multi-line string and the chomping indicator that keeps
+// its trailing newlines, or false if it can't be written as a literal
+// block.
+func yamlLiteralLines(s string) ([]string, string, bool) {
+	// the indentation of the block is taken from its first line that
+	// isn't empty
+	first := strings.TrimLeft(s, "\n")
+	if !strings.Contains(s, "\n") || strings.HasPrefix(first, " ") || strings.ContainsAny(s, "\r\x00") {
+		return nil, "", false
+	}
+	chomp := "-"
+	switch {
+	case strings.HasSuffix(s, "\n\n"):
+		chomp = "+"
+	case strings.HasSuffix(s, "\n"):
+		chomp = ""
+	}
+	if chomp != "-" {
+		s = strings.TrimSuffix(s, "\n")
+	}
+	return strings.Split(s, "\n"), chomp, true
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+type yamlTestDocument struct {
+	Name   string
+	Count  int
+	Ready  bool
+	Labels map[string]string
+	Ports  []yamlTestPort
+	Tags   []string
+	Unit   string
+	Empty  map[string]string
+	None   *string
+}
+
+type yamlTestPort struct {
+	Internal int
+	External int
+}
+
+func TestWriteYAML(t *testing.T) {
+	doc := yamlTestDocument{
+		Name:   "web-1",
+		Count:  2,
+		Ready:  true,
+		Labels: map[string]string{"tier": "web", "env": "yes"},
+		Ports:  []yamlTestPort{{8080, 14000}, {8443, 14001}},
+		Tags:   []string{"a: b", "", "10", "/var/lib/containers"},
+		Unit:   "[Unit]\nDescription=web\n\n[Service]\nExecStart=/usr/bin/docker run\n",
+		Empty:  map[string]string{},
+	}
+	expected := `Name: web-1
+Count: 2
+Ready: true
+Labels:
+  env: "yes"
+  tier: web
+Ports:
+  - Internal: 8080
+    External: 14000
+  - Internal: 8443
+    External: 14001
+Tags:
+  - "a: b"
+  - ""
+  - "10"
+  - /var/lib/containers
+Unit: |
+  [Unit]
+  Description=web
+
+  [Service]
+  ExecStart=/usr/bin/docker run
+Empty: {}
+None: null
+`
+	buf := &bytes.Buffer{}
+	if err := WriteYAML(buf, doc); err != nil {
+		t.Fatalf("Unable to write YAML: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteYAMLTopLevelArray(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := WriteYAML(buf, []interface{}{[]string{"a", "b"}, map[string]int{}, "x"}); err != nil {
+		t.Fatalf("Unable to write YAML: %v", err)
+	}
+	if expected := "- - a\n  - b\n- {}\n- x\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestYAMLLiteralChomping(t *testing.T) {
+	tests := map[string]string{
+		"a\nb":     "Unit: |-\n  a\n  b\n",
+		"a\nb\n":   "Unit: |\n  a\n  b\n",
+		"a\nb\n\n": "Unit: |+\n  a\n  b\n\n",
+		" a\nb":    "Unit: \" a\\nb\"\n",
+	}
+	for value, expected := range tests {
+		buf := &bytes.Buffer{}
+		if err := WriteYAML(buf, struct{ Unit string }{value}); err != nil {
+			t.Fatalf("Unable to write YAML: %v", err)
+		}
+		if buf.String() != expected {
+			t.Errorf("Expected %q to be written as %q, got %q", value, expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package containers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Docker link from a container to another container on the same host,
+// which it reaches by Alias.  Docker adds the alias to /etc/hosts and
+// passes the address and ports of the other container as <ALIAS>_PORT_*
+// variables.
+type ContainerAlias struct {
+	Id    Identifier
+	Alias string
+}
+
+// Parse a '<name>[:<alias>]' link.  The alias defaults to the name.
+func NewContainerAliasFromString(s string) (ContainerAlias, error) {
+	name, alias := s, s
+	if i := strings.Index(s, ":"); i != -1 {
+		name, alias = s[:i], s[i+1:]
+	}
+	id, err := NewIdentifier(name)
+	if err != nil {
+		return ContainerAlias{}, fmt.Errorf("The link '%s' must name a valid container: %s", s, err.Error())
+	}
+	link := ContainerAlias{id, alias}
+	if err := link.Check(); err != nil {
+		return ContainerAlias{}, err
+	}
+	return link, nil
+}
+
+func (l ContainerAlias) Check() error {
+	if _, err := NewIdentifier(string(l.Id)); err != nil {
+		return fmt.Errorf("The link to '%s' must name a valid container: %s", l.Id, err.Error())
+	}
+	if len(l.Alias) > 253 || !hostnamePattern.MatchString(l.Alias) {
+		return fmt.Errorf("The alias '%s' of the link to %s is not a valid hostname", l.Alias, l.Id)
+	}
+	return nil
+}
+
+func (l ContainerAlias) String() string {
+	return string(l.Id) + ":" + l.Alias
+}
+
+type ContainerAliases []ContainerAlias
+
+func (a ContainerAliases) Check() error {
+	aliases := make(map[string]bool)
+	for i := range a {
+		if err := a[i].Check(); err != nil {
+			return err
+		}
+		if aliases[a[i].Alias] {
+			return fmt.Errorf("The alias '%s' is used by more than one link", a[i].Alias)
+		}
+		aliases[a[i].Alias] = true
+	}
+	return nil
+}
+
+func (a ContainerAliases) String() string {
+	links := make([]string, len(a))
+	for i := range a {
+		links[i] = a[i].String()
+	}
+	return strings.Join(links, ",")
+}
+
+// The links written to the unit file of a container.
+func GetContainerAliases(id Identifier) (ContainerAliases, error) {
+	values, err := readUnitValues(id, "X-ContainerLink")
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(ContainerAliases, 0, len(values))
+	for _, value := range values {
+		if link, err := NewContainerAliasFromString(value); err == nil {
+			aliases = append(aliases, link)
+		}
+	}
+	return aliases, nil
+}
+
+// The containers on this host with a link to id, which would fail to
+// start without it.
+func LinkedFrom(id Identifier) ([]Identifier, error) {
+	installed, err := InstalledIdentifiers()
+	if err != nil {
+		return nil, err
+	}
+	linked := []Identifier{}
+	for _, other := range installed {
+		if other == id {
+			continue
+		}
+		aliases, err := GetContainerAliases(other)
+		if err != nil {
+			continue
+		}
+		for _, link := range aliases {
+			if link.Id == id {
+				linked = append(linked, other)
+				break
+			}
+		}
+	}
+	return linked, nil
+}
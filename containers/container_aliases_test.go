@@ -0,0 +1,70 @@
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/openshift/geard/config"
+)
+
+func TestContainerAlias(t *testing.T) {
+	for value, expected := range map[string]ContainerAlias{
+		"test-db:db":       {"test-db", "db"},
+		"test-db:db.local": {"test-db", "db.local"},
+		"test-db":          {"test-db", "test-db"},
+	} {
+		link, err := NewContainerAliasFromString(value)
+		if err != nil {
+			t.Errorf("Unable to parse link %q: %v", value, err)
+			continue
+		}
+		if link != expected {
+			t.Errorf("Expected link %q to be %+v, got %+v", value, expected, link)
+		}
+	}
+
+	for _, value := range []string{"", ":db", "test-db:", "test db:db", "test-db:db_1", "test-db:-db"} {
+		if _, err := NewContainerAliasFromString(value); err == nil {
+			t.Errorf("Expected link %q to be rejected", value)
+		}
+	}
+	if err := (ContainerAliases{{"test-a", "db"}, {"test-b", "db"}}).Check(); err == nil {
+		t.Error("Expected two links with the same alias to be rejected")
+	}
+}
+
+func TestLinkedFrom(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-links")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+	defer func() {
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(dir)
+	}()
+
+	for id, unit := range map[Identifier]string{
+		"test-db":    "X-ContainerId=test-db\n",
+		"test-web":   "X-ContainerId=test-web\nX-ContainerLink=test-cache:cache\nX-ContainerLink=test-db:db\n",
+		"test-cache": "X-ContainerId=test-cache\n",
+	} {
+		if err := ioutil.WriteFile(id.UnitPathFor(), []byte(unit), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	aliases, err := GetContainerAliases("test-web")
+	if err != nil || len(aliases) != 2 || aliases[1] != (ContainerAlias{"test-db", "db"}) {
+		t.Errorf("Expected the links of the unit to be read, got %v (%v)", aliases, err)
+	}
+	linked, err := LinkedFrom("test-db")
+	if err != nil || len(linked) != 1 || linked[0] != "test-web" {
+		t.Errorf("Expected test-web to link to test-db, got %v (%v)", linked, err)
+	}
+	if linked, err := LinkedFrom("test-web"); err != nil || len(linked) != 0 {
+		t.Errorf("Expected no container to link to test-web, got %v (%v)", linked, err)
+	}
+}
@@ -0,0 +1,245 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// The containerd namespace that holds the images and containers
+	// geard creates
+	ContainerdNamespace = "geard"
+
+	DefaultContainerdAddress = "/run/containerd/containerd.sock"
+)
+
+// The address of the containerd socket, set with --containerd-address.
+var ContainerdAddress = DefaultContainerdAddress
+
+// The containerd client used to drive the daemon.  Units run their
+// containers with the same command.
+var ContainerdCommand = "/usr/bin/ctr"
+
+func init() {
+	RegisterRuntime(RuntimeContainerd, func(socket string) (Runtime, error) {
+		return &containerdRuntime{ContainerdAddress}, nil
+	})
+}
+
+// The runtime backed by containerd, driven through its command line
+// client.  The Docker socket a job was given does not apply.
+type containerdRuntime struct {
+	address string
+}
+
+func (r *containerdRuntime) Name() string {
+	return RuntimeContainerd
+}
+
+func (r *containerdRuntime) ctr(args ...string) ([]byte, error) {
+	args = append([]string{"--address", r.address, "--namespace", ContainerdNamespace}, args...)
+	var stderr bytes.Buffer
+	cmd := exec.Command(ContainerdCommand, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "not found") {
+			return out, ErrNoSuchContainer
+		}
+		return out, fmt.Errorf("%s %s: %v: %s", ContainerdCommand, args[4], err, msg)
+	}
+	return out, nil
+}
+
+func (r *containerdRuntime) PullImage(image string) error {
+	if present, err := r.ImagePresent(image); err != nil || present {
+		return err
+	}
+	return r.UpdateImage(image)
+}
+
+func (r *containerdRuntime) UpdateImage(image string) error {
+	_, err := r.ctr("images", "pull", ContainerdImageRef(image))
+	return err
+}
+
+func (r *containerdRuntime) ImagePresent(image string) (bool, error) {
+	ref := ContainerdImageRef(image)
+	out, err := r.ctr("images", "ls", "-q")
+	if err != nil {
+		return false, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == ref {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *containerdRuntime) ImageDigests(image string) ([]ImageDigest, error) {
+	ref := ContainerdImageRef(image)
+	out, err := r.ctr("images", "ls")
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// REF TYPE DIGEST SIZE PLATFORMS LABELS
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[0] == ref {
+			return []ImageDigest{ImageDigest(fields[2])}, nil
+		}
+	}
+	return []ImageDigest{}, nil
+}
+
+func (r *containerdRuntime) StopContainer(id Identifier, timeout uint) error {
+	if _, err := r.ctr("tasks", "kill", "--signal", "SIGTERM", id.ContainerFor()); err != nil {
+		return err
+	}
+	for deadline := time.Now().Add(time.Duration(timeout) * time.Second); time.Now().Before(deadline); {
+		if running, err := r.ContainerRunning(id); err != nil || !running {
+			return err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	if _, err := r.ctr("tasks", "kill", "--signal", "SIGKILL", id.ContainerFor()); err != nil && err != ErrNoSuchContainer {
+		return err
+	}
+	return nil
+}
+
+func (r *containerdRuntime) SignalContainer(id Identifier, signal string) error {
+	_, err := r.ctr("tasks", "kill", "--signal", signal, id.ContainerFor())
+	return err
+}
+
+func (r *containerdRuntime) ContainerRunning(id Identifier) (bool, error) {
+	status, err := r.taskStatus(id)
+	if err != nil {
+		return false, err
+	}
+	return status == "RUNNING", nil
+}
+
+func (r *containerdRuntime) PauseContainer(id Identifier) error {
+	_, err := r.ctr("tasks", "pause", id.ContainerFor())
+	return err
+}
+
+func (r *containerdRuntime) UnpauseContainer(id Identifier) error {
+	_, err := r.ctr("tasks", "resume", id.ContainerFor())
+	return err
+}
+
+func (r *containerdRuntime) ContainerPaused(id Identifier) (bool, error) {
+	status, err := r.taskStatus(id)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case "RUNNING":
+		return false, nil
+	case "PAUSED", "PAUSING":
+		return true, nil
+	}
+	return false, ErrNoSuchContainer
+}
+
+// The status of the task of a container, such as RUNNING or PAUSED.
+// Returns ErrNoSuchContainer if it has no task.
+func (r *containerdRuntime) taskStatus(id Identifier) (string, error) {
+	out, err := r.ctr("tasks", "ls")
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// TASK PID STATUS
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == id.ContainerFor() {
+			return fields[2], nil
+		}
+	}
+	return "", ErrNoSuchContainer
+}
+
+// The metrics of a task, which are laid out differently under cgroups v1
+// and v2.
+type containerdMetrics struct {
+	Memory struct {
+		Usage json.RawMessage `json:"usage"`
+		Limit uint64          `json:"usage_limit"`
+	} `json:"memory"`
+	CPU struct {
+		Usage struct {
+			Total uint64 `json:"total"`
+		} `json:"usage"`
+		UsageUsec uint64 `json:"usage_usec"`
+	} `json:"cpu"`
+}
+
+func (r *containerdRuntime) ContainerStats(id Identifier) (*ContainerStats, error) {
+	out, err := r.ctr("tasks", "metrics", "--format", "json", id.ContainerFor())
+	if err != nil {
+		return nil, err
+	}
+	return parseContainerdMetrics(out)
+}
+
+func parseContainerdMetrics(data []byte) (*ContainerStats, error) {
+	metrics := containerdMetrics{}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, err
+	}
+	stats := &ContainerStats{
+		MemoryLimit: metrics.Memory.Limit,
+		CPUUsage:    metrics.CPU.Usage.Total,
+	}
+	if stats.CPUUsage == 0 {
+		stats.CPUUsage = metrics.CPU.UsageUsec * 1000
+	}
+
+	// v1 reports an object with the usage and limit, v2 a number
+	v1 := struct {
+		Usage uint64 `json:"usage"`
+		Limit uint64 `json:"limit"`
+	}{}
+	if len(metrics.Memory.Usage) > 0 {
+		if err := json.Unmarshal(metrics.Memory.Usage, &v1); err == nil {
+			stats.MemoryUsage, stats.MemoryLimit = v1.Usage, v1.Limit
+		} else if err := json.Unmarshal(metrics.Memory.Usage, &stats.MemoryUsage); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// Containerd requires fully qualified image references, where Docker
+// assumes the Docker Hub registry, the library namespace, and the latest
+// tag.
+func ContainerdImageRef(image string) string {
+	name, suffix := image, ""
+	if i := strings.Index(name, "@"); i != -1 {
+		name, suffix = name[:i], name[i:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, suffix = name[:i], name[i:]
+	} else {
+		suffix = ":latest"
+	}
+
+	if i := strings.Index(name, "/"); i == -1 {
+		name = "docker.io/library/" + name
+	} else if host := name[:i]; !strings.ContainsAny(host, ".:") && host != "localhost" {
+		name = "docker.io/" + name
+	}
+	return name + suffix
+}
@@ -0,0 +1,45 @@
+package containers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The CPUs or memory nodes the processes of a container may run on, as a
+// list of numbers and ranges such as "0-3,5", passed to Docker as
+// --cpuset-cpus or --cpuset-mems.
+type CPUSet string
+
+func (s CPUSet) Check() error {
+	if s == "" {
+		return nil
+	}
+	for _, part := range strings.Split(string(s), ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := cpuSetNumber(bounds[0])
+		if err != nil {
+			return fmt.Errorf("The cpuset '%s' must be a list of numbers and ranges such as 0-3,5", s)
+		}
+		if len(bounds) == 2 {
+			last, err := cpuSetNumber(bounds[1])
+			if err != nil {
+				return fmt.Errorf("The cpuset '%s' must be a list of numbers and ranges such as 0-3,5", s)
+			}
+			if last < first {
+				return fmt.Errorf("The range '%s' of the cpuset '%s' must not end before it starts", part, s)
+			}
+		}
+	}
+	return nil
+}
+
+// A number of a cpuset, which must be written without a sign or leading
+// zeros so that the set reads the same to Docker and the cgroup.
+func cpuSetNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || strconv.Itoa(n) != s {
+		return 0, fmt.Errorf("'%s' is not a CPU or memory node number", s)
+	}
+	return n, nil
+}
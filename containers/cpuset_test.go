@@ -0,0 +1,35 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestCPUSet(t *testing.T) {
+	for _, value := range []CPUSet{"", "0", "0-3", "0-3,5", "1,3,5", "0-1,4-7,12", "2-2"} {
+		if err := value.Check(); err != nil {
+			t.Errorf("Expected cpuset %q to be valid: %v", value, err)
+		}
+	}
+}
+
+func TestCPUSetRejected(t *testing.T) {
+	for _, value := range []CPUSet{
+		"a",      // not a number
+		"-1",     // negative
+		"0-",     // no end
+		"-3",     // no start
+		"3-1",    // backwards
+		"0,,1",   // empty entry
+		"0,",     // trailing comma
+		"0-1-2",  // too many bounds
+		"01",     // leading zero
+		"+1",     // sign
+		" 0",     // white space
+		"0;rm",   // not a list
+		"0-3, 5", // white space
+	} {
+		if err := value.Check(); err == nil {
+			t.Errorf("Expected cpuset %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package containers
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// The name servers a container resolves names with, replacing those of
+// the host.
+type DNSServers []string
+
+func (d DNSServers) Check() error {
+	for _, server := range d {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("The DNS server '%s' must be an IP address", server)
+		}
+	}
+	return nil
+}
+
+// An entry added to the /etc/hosts file of a container.
+type HostEntry struct {
+	Name string
+	IP   string
+}
+
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// Parse a '<name>:<ip>' entry.  The address may be IPv6, so only the
+// first colon separates the name.
+func NewHostEntryFromString(s string) (HostEntry, error) {
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return HostEntry{}, fmt.Errorf("The host entry '%s' must be of the form <name>:<ip>", s)
+	}
+	entry := HostEntry{s[:i], s[i+1:]}
+	if err := entry.Check(); err != nil {
+		return HostEntry{}, err
+	}
+	return entry, nil
+}
+
+func (h HostEntry) Check() error {
+	if len(h.Name) > 253 || !hostnamePattern.MatchString(h.Name) {
+		return fmt.Errorf("The host entry name '%s' is not a valid hostname", h.Name)
+	}
+	if net.ParseIP(h.IP) == nil {
+		return fmt.Errorf("The host entry '%s' must have an IP address, not '%s'", h.Name, h.IP)
+	}
+	return nil
+}
+
+func (h HostEntry) String() string {
+	return h.Name + ":" + h.IP
+}
+
+type HostEntries []HostEntry
+
+func (h HostEntries) Check() error {
+	for i := range h {
+		if err := h[i].Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h HostEntries) String() string {
+	entries := make([]string, len(h))
+	for i := range h {
+		entries[i] = h[i].String()
+	}
+	return strings.Join(entries, ",")
+}
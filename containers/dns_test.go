@@ -0,0 +1,43 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestDNSServers(t *testing.T) {
+	if err := (DNSServers{"8.8.8.8", "2001:4860:4860::8888"}).Check(); err != nil {
+		t.Errorf("Expected the DNS servers to be valid: %v", err)
+	}
+	for _, server := range []string{"", "dns.example.com", "8.8.8", "256.1.1.1", "8.8.8.8:53"} {
+		if err := (DNSServers{server}).Check(); err == nil {
+			t.Errorf("Expected DNS server %q to be rejected", server)
+		}
+	}
+}
+
+func TestHostEntry(t *testing.T) {
+	for value, expected := range map[string]HostEntry{
+		"db:10.0.0.5":              {"db", "10.0.0.5"},
+		"db.example.com:10.0.0.5":  {"db.example.com", "10.0.0.5"},
+		"ipv6-host:fe80::1":        {"ipv6-host", "fe80::1"},
+		"A1.b-2:2001:db8::ff00:42": {"A1.b-2", "2001:db8::ff00:42"},
+	} {
+		entry, err := NewHostEntryFromString(value)
+		if err != nil {
+			t.Errorf("Unable to parse host entry %q: %v", value, err)
+			continue
+		}
+		if entry != expected {
+			t.Errorf("Expected host entry %q to be %+v, got %+v", value, expected, entry)
+		}
+		if entry.String() != value {
+			t.Errorf("Expected host entry %q to print as itself, got %q", value, entry.String())
+		}
+	}
+
+	for _, value := range []string{"db", "db:", ":10.0.0.5", "db:10.0.0", "db:300.0.0.1", "db:example.com", "-db:10.0.0.5", "db_1:10.0.0.5", "db..local:10.0.0.5"} {
+		if _, err := NewHostEntryFromString(value); err == nil {
+			t.Errorf("Expected host entry %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package containers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Arguments appended verbatim to the docker run command of a container,
+// for Docker options that have no dedicated setting.  They are passed to
+// Docker unchecked, so they can grant a container anything Docker can -
+// the daemon only accepts them when AllowDockerArgs is set.
+type DockerArgs []string
+
+// Whether installs may pass arguments to docker run.
+var AllowDockerArgs = false
+
+// Characters that systemd or a shell would interpret instead of passing
+// to Docker, or that would end the ExecStart line.
+const dockerArgMetacharacters = " \t\r\n\\\"'`$%;&|<>(){}*?!#~"
+
+func (a DockerArgs) Check() error {
+	for _, arg := range a {
+		if arg == "" {
+			return fmt.Errorf("A docker argument may not be empty")
+		}
+		if strings.ContainsAny(arg, dockerArgMetacharacters) {
+			return fmt.Errorf("The docker argument '%s' may not contain whitespace, quotes, or any of %s", arg, "\\`$%;&|<>(){}*?!#~")
+		}
+	}
+	return nil
+}
+
+func (a DockerArgs) String() string {
+	return strings.Join(a, " ")
+}
@@ -0,0 +1,142 @@
+package containers
+
+import (
+	"github.com/openshift/geard/docker"
+)
+
+func init() {
+	RegisterRuntime(RuntimeDocker, func(socket string) (Runtime, error) {
+		return &dockerRuntime{socket}, nil
+	})
+}
+
+// The runtime backed by the Docker daemon listening on socket.  A new
+// connection is made for each operation, as the jobs have always done.
+type dockerRuntime struct {
+	socket string
+}
+
+func (r *dockerRuntime) Name() string {
+	return RuntimeDocker
+}
+
+func (r *dockerRuntime) PullImage(image string) error {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return err
+	}
+	_, err = client.GetImage(image)
+	return err
+}
+
+func (r *dockerRuntime) UpdateImage(image string) error {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return err
+	}
+	return client.PullImage(image)
+}
+
+func (r *dockerRuntime) ImagePresent(image string) (bool, error) {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return false, err
+	}
+	return client.HasImage(image)
+}
+
+func (r *dockerRuntime) ImageDigests(image string) ([]ImageDigest, error) {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := client.GetImageRepoDigests(image)
+	if err != nil {
+		return nil, err
+	}
+	digests := make([]ImageDigest, 0, len(refs))
+	for _, ref := range refs {
+		if _, digest := SplitImageDigest(ref); digest != "" {
+			digests = append(digests, digest)
+		}
+	}
+	return digests, nil
+}
+
+func (r *dockerRuntime) StopContainer(id Identifier, timeout uint) error {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return err
+	}
+	return dockerError(client.StopContainer(id.ContainerFor(), timeout))
+}
+
+func (r *dockerRuntime) SignalContainer(id Identifier, signal string) error {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return err
+	}
+	return dockerError(client.KillContainer(id.ContainerFor(), ReloadSignalNumber(signal)))
+}
+
+func (r *dockerRuntime) ContainerRunning(id Identifier) (bool, error) {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return false, err
+	}
+	container, err := client.InspectContainer(id.ContainerFor())
+	if err != nil {
+		return false, dockerError(err)
+	}
+	return container.State.Running, nil
+}
+
+func (r *dockerRuntime) ContainerStats(id Identifier) (*ContainerStats, error) {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := client.ContainerStats(id.ContainerFor())
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	return &ContainerStats{stats.MemoryUsage, stats.MemoryLimit, stats.CPUUsage}, nil
+}
+
+func (r *dockerRuntime) PauseContainer(id Identifier) error {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return err
+	}
+	return dockerError(client.PauseContainer(id.ContainerFor()))
+}
+
+func (r *dockerRuntime) UnpauseContainer(id Identifier) error {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return err
+	}
+	return dockerError(client.UnpauseContainer(id.ContainerFor()))
+}
+
+func (r *dockerRuntime) ContainerPaused(id Identifier) (bool, error) {
+	client, err := docker.GetConnection(r.socket)
+	if err != nil {
+		return false, err
+	}
+	running, paused, err := client.ContainerPausedState(id.ContainerFor())
+	if err != nil {
+		return false, dockerError(err)
+	}
+	if !running {
+		return false, ErrNoSuchContainer
+	}
+	return paused, nil
+}
+
+func dockerError(err error) error {
+	if err == docker.ErrNoSuchContainer {
+		return ErrNoSuchContainer
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package containers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The directory a container installed with a reload signal finds its
+// current environment in, as the file "environment".  Variables passed to
+// a process when it starts can't be changed, so applications that reload
+// their environment must read it from this file.
+const EnvReloadMountPath = "/.container.env"
+
+// The signals a container may be sent to reload its environment, and their
+// Linux numbers.  Signals that stop a process are not allowed.
+var reloadSignals = map[string]int{
+	"SIGHUP":   1,
+	"SIGINT":   2,
+	"SIGQUIT":  3,
+	"SIGUSR1":  10,
+	"SIGUSR2":  12,
+	"SIGWINCH": 28,
+}
+
+// Parse a signal name such as "HUP" or "SIGHUP" into its full name.
+func NewReloadSignal(s string) (string, error) {
+	name := strings.ToUpper(s)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if _, ok := reloadSignals[name]; !ok {
+		names := make([]string, 0, len(reloadSignals))
+		for name := range reloadSignals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("The reload signal '%s' must be one of %s", s, strings.Join(names, ", "))
+	}
+	return name, nil
+}
+
+func ReloadSignalNumber(name string) int {
+	return reloadSignals[name]
+}
+
+// The copy of the environment a container with a reload signal reads,
+// which is mounted at EnvReloadMountPath.
+func (i Identifier) ReloadEnvironmentPathFor() string {
+	return filepath.Join(i.RunPathFor(), "env", "environment")
+}
+
+// The signal the container was installed to reload its environment on, or
+// an empty string if it was not.
+func GetEnvReloadSignal(id Identifier) (string, error) {
+	return readUnitValue(id, "X-EnvReloadSignal")
+}
+
+// The directory the environment files containers watch must be in, set by
+// the daemon with --env-watch-dir.  The daemon reads the files as root and
+// copies them into environments clients can read, so no container may
+// watch a file unless it is set.
+var EnvFileWatchDir = ""
+
+// Return an error unless path is inside EnvFileWatchDir, including after
+// any symlinks in it are resolved.  A file that does not exist yet is
+// checked by its path alone, and again each time it is read.
+func CheckEnvFileWatchPath(path string) error {
+	if EnvFileWatchDir == "" {
+		return fmt.Errorf("This server does not allow environment files to be watched, start the daemon with --env-watch-dir to allow them.")
+	}
+	if !pathInside(EnvFileWatchDir, path) {
+		return fmt.Errorf("The watched environment file %s must be inside %s.", path, EnvFileWatchDir)
+	}
+	dir, err := filepath.EvalSymlinks(EnvFileWatchDir)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !pathInside(dir, resolved) {
+		return fmt.Errorf("The watched environment file %s links outside of %s.", path, EnvFileWatchDir)
+	}
+	return nil
+}
+
+func pathInside(dir, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// The file on the host the daemon applies to the environment of the
+// container when it changes, and whether the container is restarted
+// afterwards.  The path is empty if the container doesn't watch one.
+func GetEnvFileWatch(id Identifier) (path string, restart bool, err error) {
+	if path, err = readUnitValue(id, "X-EnvFileWatch"); err != nil || path == "" {
+		return "", false, err
+	}
+	value, err := readUnitValue(id, "X-EnvFileWatchRestart")
+	return path, value == "true", err
+}
+
+// Replace the environment a container reads on reload.  The file is
+// renamed into place so that the container never reads a partial write.
+func WriteReloadEnvironment(id Identifier, data []byte) error {
+	path := id.ReloadEnvironmentPathFor()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), ".environment")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
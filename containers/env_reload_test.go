@@ -0,0 +1,18 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestNewReloadSignal(t *testing.T) {
+	for value, expected := range map[string]string{"HUP": "SIGHUP", "sighup": "SIGHUP", "USR1": "SIGUSR1", "SIGUSR2": "SIGUSR2"} {
+		if signal, err := NewReloadSignal(value); err != nil || signal != expected {
+			t.Errorf("Expected %q to be %s, got %q %v", value, expected, signal, err)
+		}
+	}
+	for _, value := range []string{"", "KILL", "SIGTERM", "STOP", "9"} {
+		if _, err := NewReloadSignal(value); err == nil {
+			t.Errorf("Expected the reload signal %q to be rejected", value)
+		}
+	}
+}
@@ -2,15 +2,18 @@ package containers
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Environment struct {
@@ -25,7 +28,7 @@ func (e *Environment) Check() error {
 	if len(e.Name) > 1024 {
 		return errors.New("Name must be shorter than 1024 characters.")
 	}
-	if len(e.Value) > 8*1024 {
+	if len(e.Value) > MaxEnvironmentValueSize {
 		return errors.New("Value must be less than 8KB.")
 	}
 	return nil
@@ -59,6 +62,47 @@ func (e *Environment) FromString(s string) (bool, error) {
 	return true, nil
 }
 
+// Reduce a line of an environment file to the form <name>=<value>
+// accepted by FromString.  Blank lines, lines starting with '#', and
+// comments after an unquoted value are dropped, as is a leading 'export'.
+// A '#' inside a quoted value is kept.
+func dotenvLine(s string) (string, bool) {
+	line := strings.Trim(s, whiteSpaces)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	if strings.HasPrefix(line, "export") && strings.IndexAny(line, whiteSpaces) == len("export") {
+		line = strings.TrimLeft(line[len("export"):], whiteSpaces)
+	}
+	pair := strings.SplitN(line, "=", 2)
+	if len(pair) != 2 {
+		return "", false
+	}
+	name := strings.Trim(pair[0], whiteSpaces)
+	value := strings.TrimLeft(pair[1], whiteSpaces)
+
+	switch {
+	case strings.HasPrefix(value, "\""):
+		if quoted, err := strconv.QuotedPrefix(value); err == nil {
+			value = quoted
+		}
+	case strings.HasPrefix(value, "'"):
+		// single quoted values are taken literally
+		if end := strings.IndexByte(value[1:], '\''); end != -1 {
+			value = strconv.Quote(value[1 : end+1])
+		}
+	default:
+		// a comment must follow white space, so 'A=#B' has the value '#B'
+		for i := 1; i < len(pair[1]); i++ {
+			if pair[1][i] == '#' && strings.IndexByte(whiteSpaces, pair[1][i-1]) != -1 {
+				value = strings.TrimLeft(pair[1][:i], whiteSpaces)
+				break
+			}
+		}
+	}
+	return name + "=" + value, true
+}
+
 type EnvironmentVariables []Environment
 
 func ExtractEnvironmentVariablesFrom(existing *[]string) (EnvironmentVariables, error) {
@@ -101,6 +145,7 @@ func (d *EnvironmentDescription) Empty() bool {
 }
 
 func (d *EnvironmentDescription) Check() error {
+	trackSecretValues(d.Variables, false)
 	for i := range d.Variables {
 		e := &d.Variables[i]
 		if err := e.Check(); err != nil {
@@ -139,14 +184,40 @@ func (j *EnvironmentDescription) Fetch(upto int64) error {
 	if upto > 0 {
 		r = &io.LimitedReader{r, upto}
 	}
-	if err := j.ReadFrom(r); err != nil {
+	if err := j.ReadDotenvFrom(r); err != nil {
 		return err
 	}
+	trackSecretValues(j.Variables, false)
 	return nil
 }
 
+// Changes to the environment of each container are made one at a time, so
+// that a read, modify, and write can't lose a concurrent change.
+var environmentLocks = struct {
+	sync.Mutex
+	ids map[Identifier]*sync.Mutex
+}{ids: make(map[Identifier]*sync.Mutex)}
+
+// Lock the environment of a container for changes by this process, and
+// return a function that releases it.
+func LockEnvironment(id Identifier) func() {
+	environmentLocks.Lock()
+	lock, ok := environmentLocks.ids[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		environmentLocks.ids[id] = lock
+	}
+	environmentLocks.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
 // Write the provided enviroment data to an appropriate location
 func (j *EnvironmentDescription) Write(appends bool) error {
+	if EnvironmentEncryptionKeys != nil {
+		return j.writeEncrypted(appends)
+	}
 	envPath := j.Id.EnvironmentPathFor()
 
 	var file *os.File
@@ -180,12 +251,68 @@ func (j *EnvironmentDescription) Write(appends bool) error {
 	return nil
 }
 
+// Encrypted files can't be appended to, so the existing content is
+// decrypted and written back along with the new values.
+func (j *EnvironmentDescription) writeEncrypted(appends bool) error {
+	envPath := j.Id.EnvironmentPathFor()
+
+	content := &bytes.Buffer{}
+	if appends {
+		existing, err := ReadEnvironmentFile(envPath)
+		if err != nil && !os.IsNotExist(err) {
+			log.Print("job_environment: Unable to read existing environment file: ", err)
+			return err
+		}
+		content.Write(existing)
+	}
+	env := j.Variables
+	for i := range env {
+		fmt.Fprintf(content, "%s=%s\n", env[i].Name, env[i].Value)
+	}
+
+	data, err := EnvironmentEncryptionKeys.Encrypt(content.Bytes())
+	if err != nil {
+		log.Print("job_environment: Unable to encrypt environment file: ", err)
+		return err
+	}
+	tmpPath := envPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0660); err != nil {
+		log.Print("job_environment: Unable to write environment file: ", err)
+		return err
+	}
+	if err := os.Rename(tmpPath, envPath); err != nil {
+		log.Print("job_environment: Unable to replace environment file: ", err)
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Read the variables of an environment file written by the daemon, one
+// <name>=<value> per line, in the order they first appear, each with the
+// last value it is given.  Values are taken as written, so a '#' or a
+// quote inside a stored value is kept.
 func (j *EnvironmentDescription) ReadFrom(r io.Reader) error {
+	return j.readLines(r, func(s string) (string, bool) { return s, true })
+}
+
+// Read the variables of an environment file provided by a user, such as
+// one passed with --env-file, which may be in the dotenv format with
+// comments, blank lines, quoted values, and 'export' prefixes.
+func (j *EnvironmentDescription) ReadDotenvFrom(r io.Reader) error {
+	return j.readLines(r, dotenvLine)
+}
+
+func (j *EnvironmentDescription) readLines(r io.Reader, line func(string) (string, bool)) error {
 	all := make(map[string]string)
+	order := []string{}
 	scanner := bufio.NewScanner(r)
 	e := Environment{}
 	for scanner.Scan() {
-		s := scanner.Text()
+		s, ok := line(scanner.Text())
+		if !ok {
+			continue
+		}
 		match, err := e.FromString(s)
 		if err != nil {
 			continue
@@ -194,6 +321,9 @@ func (j *EnvironmentDescription) ReadFrom(r io.Reader) error {
 			continue
 		}
 		if match {
+			if _, ok := all[e.Name]; !ok {
+				order = append(order, e.Name)
+			}
 			all[e.Name] = e.Value
 		}
 	}
@@ -201,10 +331,9 @@ func (j *EnvironmentDescription) ReadFrom(r io.Reader) error {
 		return err
 	}
 
-	env := make(EnvironmentVariables, 0, len(all))
-	for name := range all {
-		e := Environment{name, all[name]}
-		env = append(env, e)
+	env := make(EnvironmentVariables, 0, len(order))
+	for _, name := range order {
+		env = append(env, Environment{name, all[name]})
 	}
 	j.Variables = env
 	return nil
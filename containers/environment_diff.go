@@ -0,0 +1,84 @@
+package containers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A variable that would be added, changed, or removed by an update to an
+// environment.
+type EnvironmentChange struct {
+	Name string
+	// The current value, empty if the variable is added
+	Old string
+	// The new value, empty if the variable is removed
+	New     string
+	Added   bool
+	Removed bool
+}
+
+type EnvironmentChanges []EnvironmentChange
+
+// The changes, ordered by name, that writing variables to an environment
+// with the current values would make.  If replace is true the variables
+// replace the environment (as with a reset), otherwise they are merged
+// into it.
+func DiffEnvironment(current map[string]string, variables []Environment, replace bool) EnvironmentChanges {
+	next := make(map[string]string)
+	if !replace {
+		for k, v := range current {
+			next[k] = v
+		}
+	}
+	for i := range variables {
+		next[variables[i].Name] = variables[i].Value
+	}
+
+	changes := EnvironmentChanges{}
+	for name, value := range next {
+		old, ok := current[name]
+		switch {
+		case !ok:
+			changes = append(changes, EnvironmentChange{Name: name, New: value, Added: true})
+		case old != value:
+			changes = append(changes, EnvironmentChange{Name: name, Old: old, New: value})
+		}
+	}
+	for name, old := range current {
+		if _, ok := next[name]; !ok {
+			changes = append(changes, EnvironmentChange{Name: name, Old: old, Removed: true})
+		}
+	}
+	sort.Sort(changes)
+	return changes
+}
+
+func (c EnvironmentChanges) Len() int           { return len(c) }
+func (c EnvironmentChanges) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c EnvironmentChanges) Less(i, j int) bool { return c[i].Name < c[j].Name }
+
+// Write one line per change: '+ NAME=new' for an added variable,
+// '~ NAME=old -> new' for a changed one, and '- NAME=old' for a removed
+// one.
+func (c EnvironmentChanges) WriteDiffTo(w io.Writer) error {
+	if len(c) == 0 {
+		_, err := fmt.Fprintln(w, "No changes")
+		return err
+	}
+	for i := range c {
+		var err error
+		switch change := &c[i]; {
+		case change.Added:
+			_, err = fmt.Fprintf(w, "+ %s=%s\n", change.Name, change.New)
+		case change.Removed:
+			_, err = fmt.Fprintf(w, "- %s=%s\n", change.Name, change.Old)
+		default:
+			_, err = fmt.Fprintf(w, "~ %s=%s -> %s\n", change.Name, change.Old, change.New)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
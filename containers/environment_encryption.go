@@ -0,0 +1,170 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Environment files may be encrypted at rest with AES-GCM.  An encrypted
+// file starts with a header naming the key it was sealed with, followed
+// by the base64 encoded nonce and ciphertext:
+//
+//	# geard-encrypted-environment v1 <key id>
+//	<base64 data>
+//
+// Keys are read from a file with one "<key id> <base64 key>" pair per
+// line.  The first key encrypts new content, and the others are only used
+// to read content written before the key was rotated.
+type EnvironmentKeys struct {
+	// The file the keys were read from
+	Path string
+	// The id of the key used to encrypt
+	Current string
+
+	keys map[string][]byte
+}
+
+// If set, environment files are encrypted when written.  Encrypted files
+// can only be read if the key they were encrypted with is present.
+var EnvironmentEncryptionKeys *EnvironmentKeys
+
+const encryptedEnvironmentHeader = "# geard-encrypted-environment v1 "
+
+// An encrypted environment that could not be read
+type EnvironmentDecryptError string
+
+func (e EnvironmentDecryptError) Error() string {
+	return string(e)
+}
+
+var (
+	ErrEnvironmentEncrypted       = EnvironmentDecryptError("The environment is encrypted and no encryption keys are configured")
+	ErrEnvironmentDecryptFailed   = EnvironmentDecryptError("The environment could not be decrypted, the encryption key may be wrong")
+	ErrEnvironmentEncryptedFormat = EnvironmentDecryptError("The encrypted environment is not in a recognized format")
+)
+
+func ReadEnvironmentKeys(path string) (*EnvironmentKeys, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys := &EnvironmentKeys{Path: path, keys: make(map[string][]byte)}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		fields := strings.Fields(s)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected '<key id> <base64 key>'", path, line)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: the key is not valid base64: %s", path, line, err.Error())
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("%s:%d: the key must be 16, 24, or 32 bytes", path, line)
+		}
+		if _, exists := keys.keys[fields[0]]; exists {
+			return nil, fmt.Errorf("%s:%d: the key id %s is used more than once", path, line, fields[0])
+		}
+		keys.keys[fields[0]] = key
+		if keys.Current == "" {
+			keys.Current = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if keys.Current == "" {
+		return nil, fmt.Errorf("%s: no encryption keys are defined", path)
+	}
+	return keys, nil
+}
+
+func (k *EnvironmentKeys) aead(id string) (cipher.AEAD, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, EnvironmentDecryptError(fmt.Sprintf("The environment was encrypted with key %s, which is not configured", id))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt the contents of an environment file with the current key.
+func (k *EnvironmentKeys) Encrypt(plain []byte) ([]byte, error) {
+	gcm, err := k.aead(k.Current)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header := encryptedEnvironmentHeader + k.Current
+	sealed := gcm.Seal(nonce, nonce, plain, []byte(header))
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "%s\n%s\n", header, base64.StdEncoding.EncodeToString(sealed))
+	return out.Bytes(), nil
+}
+
+// Decrypt the contents of an encrypted environment file with the key named
+// in its header.
+func (k *EnvironmentKeys) Decrypt(data []byte) ([]byte, error) {
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], encryptedEnvironmentHeader) {
+		return nil, ErrEnvironmentEncryptedFormat
+	}
+	header := lines[0]
+	gcm, err := k.aead(strings.TrimPrefix(header, encryptedEnvironmentHeader))
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return nil, ErrEnvironmentEncryptedFormat
+	}
+	nonce := sealed[:gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, sealed[gcm.NonceSize():], []byte(header))
+	if err != nil {
+		return nil, ErrEnvironmentDecryptFailed
+	}
+	return plain, nil
+}
+
+func IsEncryptedEnvironment(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedEnvironmentHeader))
+}
+
+// Read the contents of an environment file, decrypting it with
+// EnvironmentEncryptionKeys if it is encrypted.
+func ReadEnvironmentFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncryptedEnvironment(data) {
+		return data, nil
+	}
+	if EnvironmentEncryptionKeys == nil {
+		return nil, ErrEnvironmentEncrypted
+	}
+	return EnvironmentEncryptionKeys.Decrypt(data)
+}
@@ -0,0 +1,121 @@
+package containers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/geard/config"
+)
+
+func writeKeys(t *testing.T, dir string, keys ...string) *EnvironmentKeys {
+	content := &bytes.Buffer{}
+	for i := 0; i < len(keys); i += 2 {
+		fmt.Fprintf(content, "%s %s\n", keys[i], base64.StdEncoding.EncodeToString(bytes.Repeat([]byte(keys[i+1]), 32)))
+	}
+	path := filepath.Join(dir, "keys")
+	if err := ioutil.WriteFile(path, content.Bytes(), 0600); err != nil {
+		t.Fatalf("Unable to write keys: %v", err)
+	}
+	k, err := ReadEnvironmentKeys(path)
+	if err != nil {
+		t.Fatalf("Unable to read keys: %v", err)
+	}
+	return k
+}
+
+func withEncryptedEnvironment(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "geard-env")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+	return dir, func() {
+		EnvironmentEncryptionKeys = nil
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestEncryptedEnvironmentRoundTrip(t *testing.T) {
+	dir, cleanup := withEncryptedEnvironment(t)
+	defer cleanup()
+	EnvironmentEncryptionKeys = writeKeys(t, dir, "key1", "a")
+
+	env := &EnvironmentDescription{Id: "test-secret", Variables: []Environment{{"PASSWORD", "hunter2"}}}
+	if err := env.Write(false); err != nil {
+		t.Fatalf("Unable to write environment: %v", err)
+	}
+	appended := &EnvironmentDescription{Id: "test-secret", Variables: []Environment{{"USER", "admin"}}}
+	if err := appended.Write(true); err != nil {
+		t.Fatalf("Unable to append to environment: %v", err)
+	}
+
+	raw, _ := ioutil.ReadFile(env.Id.EnvironmentPathFor())
+	if bytes.Contains(raw, []byte("hunter2")) || !IsEncryptedEnvironment(raw) {
+		t.Fatalf("Expected the environment to be encrypted on disk, got %q", raw)
+	}
+
+	data, err := ReadEnvironmentFile(env.Id.EnvironmentPathFor())
+	if err != nil {
+		t.Fatalf("Unable to read environment: %v", err)
+	}
+	read := &EnvironmentDescription{}
+	read.ReadFrom(bytes.NewReader(data))
+	if values := read.Map(); values["PASSWORD"] != "hunter2" || values["USER"] != "admin" {
+		t.Errorf("Expected the environment to be decrypted, got %v", values)
+	}
+}
+
+func TestEncryptedEnvironmentKeyRotation(t *testing.T) {
+	dir, cleanup := withEncryptedEnvironment(t)
+	defer cleanup()
+	EnvironmentEncryptionKeys = writeKeys(t, dir, "key1", "a")
+
+	env := &EnvironmentDescription{Id: "test-rotate", Variables: []Environment{{"PASSWORD", "hunter2"}}}
+	if err := env.Write(false); err != nil {
+		t.Fatalf("Unable to write environment: %v", err)
+	}
+
+	EnvironmentEncryptionKeys = writeKeys(t, dir, "key2", "b", "key1", "a")
+	if EnvironmentEncryptionKeys.Current != "key2" {
+		t.Errorf("Expected the first key to be used for encryption, got %s", EnvironmentEncryptionKeys.Current)
+	}
+	if _, err := ReadEnvironmentFile(env.Id.EnvironmentPathFor()); err != nil {
+		t.Errorf("Expected content encrypted with an older key to be readable: %v", err)
+	}
+}
+
+func TestEncryptedEnvironmentWrongKey(t *testing.T) {
+	dir, cleanup := withEncryptedEnvironment(t)
+	defer cleanup()
+	EnvironmentEncryptionKeys = writeKeys(t, dir, "key1", "a")
+
+	env := &EnvironmentDescription{Id: "test-wrong", Variables: []Environment{{"PASSWORD", "hunter2"}}}
+	if err := env.Write(false); err != nil {
+		t.Fatalf("Unable to write environment: %v", err)
+	}
+	path := env.Id.EnvironmentPathFor()
+
+	EnvironmentEncryptionKeys = writeKeys(t, dir, "key1", "b")
+	if data, err := ReadEnvironmentFile(path); err != ErrEnvironmentDecryptFailed {
+		t.Errorf("Expected decryption with the wrong key to fail, got %q %v", data, err)
+	}
+
+	EnvironmentEncryptionKeys = writeKeys(t, dir, "key2", "a")
+	if _, err := ReadEnvironmentFile(path); err == nil {
+		t.Error("Expected decryption with an unknown key id to fail")
+	} else if _, ok := err.(EnvironmentDecryptError); !ok {
+		t.Errorf("Expected a decryption error, got %v", err)
+	}
+
+	EnvironmentEncryptionKeys = nil
+	if _, err := ReadEnvironmentFile(path); err != ErrEnvironmentEncrypted {
+		t.Errorf("Expected reading without keys to fail, got %v", err)
+	}
+}
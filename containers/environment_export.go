@@ -0,0 +1,48 @@
+package containers
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var shellVariableName = regexp.MustCompile("\\A[a-zA-Z_][a-zA-Z0-9_]*\\z")
+
+// Return an error unless the name of the variable can be exported by a
+// shell.
+func (e *Environment) CheckShellName() error {
+	if !shellVariableName.MatchString(e.Name) {
+		return fmt.Errorf("%s is not a valid shell variable name", e.Name)
+	}
+	return nil
+}
+
+// Write the variables, sorted by name, as lines of 'export NAME=value' that
+// a shell can source.  Each name is prefixed by prefix, so that sourcing
+// the file doesn't clobber variables of the current shell, and each value
+// is single quoted so that the shell takes it literally.  Nothing is
+// written if any prefixed name isn't a valid shell variable name.
+func WriteShellExports(w io.Writer, env EnvironmentVariables, prefix string) error {
+	sorted := make(EnvironmentVariables, len(env))
+	copy(sorted, env)
+	sort.Sort(environmentByName(sorted))
+	for i := range sorted {
+		if !shellVariableName.MatchString(prefix + sorted[i].Name) {
+			return fmt.Errorf("%s%s is not a valid shell variable name", prefix, sorted[i].Name)
+		}
+	}
+	for i := range sorted {
+		if _, err := fmt.Fprintf(w, "export %s%s=%s\n", prefix, sorted[i].Name, ShellQuote(sorted[i].Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Quote s for a POSIX shell.  Nothing is special between single quotes, so
+// only a single quote needs to be ended, escaped and reopened.
+func ShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "'\\''", -1) + "'"
+}
@@ -0,0 +1,89 @@
+package containers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteShellExports(t *testing.T) {
+	buf := &bytes.Buffer{}
+	env := EnvironmentVariables{{"PORT", "8080"}, {"GREETING", "it's"}}
+	if err := WriteShellExports(buf, env, "APP_"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "export APP_GREETING='it'\\''s'\nexport APP_PORT='8080'\n"; buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+	if env[0].Name != "PORT" {
+		t.Errorf("Expected the variables not to be reordered, got %v", env)
+	}
+
+	for _, c := range []struct {
+		name, prefix string
+	}{{"1PORT", ""}, {"MY-VAR", ""}, {"a.b", ""}, {"PORT", "APP-"}, {"PORT", "1"}} {
+		buf := &bytes.Buffer{}
+		if err := WriteShellExports(buf, EnvironmentVariables{{"A", "b"}, {c.name, "x"}}, c.prefix); err == nil || buf.Len() != 0 {
+			t.Errorf("Expected %s%s to be rejected without writing anything, got %v %q", c.prefix, c.name, err, buf.String())
+		}
+	}
+}
+
+func TestWriteShellExportsSources(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("Sourcing the exports requires a shell")
+	}
+	dir, err := ioutil.TempDir("", "geard-exports")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := EnvironmentVariables{
+		{"SINGLE", "it's 'quoted'"},
+		{"DOUBLE", `say "hi"`},
+		{"DOLLAR", "$HOME ${PATH} $(touch " + filepath.Join(dir, "ran") + ")"},
+		{"BACKTICK", "`touch " + filepath.Join(dir, "ran") + "`"},
+		{"BACKSLASH", `a\b\\c\'`},
+		{"GLOB", "* ? [a-z]"},
+		{"SPACES", "  leading and trailing  "},
+		{"SEMICOLON", "a; exit 1 && b | c > d"},
+		{"HASH", "#not a comment"},
+		{"EMPTY", ""},
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteShellExports(buf, env, "TEST_"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	script := filepath.Join(dir, "vars.sh")
+	if err := ioutil.WriteFile(script, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("Unable to write the exports: %v", err)
+	}
+
+	// print each value followed by a NUL, which no value can contain
+	printf := `printf '%s\0'`
+	for _, e := range env {
+		printf += ` "$TEST_` + e.Name + `"`
+	}
+	out, err := exec.Command(sh, "-c", `set -e; . "$1"; `+printf, "sh", script).Output()
+	if err != nil {
+		t.Fatalf("Unable to source the exports: %v\n%s", err, buf.String())
+	}
+	values := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if len(values) != len(env) {
+		t.Fatalf("Expected %d values, got %q", len(env), values)
+	}
+	for i, e := range env {
+		if values[i] != e.Value {
+			t.Errorf("Expected TEST_%s to be %q, got %q", e.Name, e.Value, values[i])
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err == nil {
+		t.Error("Expected no command in a value to run")
+	}
+}
@@ -0,0 +1,58 @@
+package containers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// The environment the container was installed to inherit from, or an
+// empty identifier if it does not inherit one.
+func GetInheritedEnvironment(id Identifier) (Identifier, error) {
+	parent, err := readUnitValue(id, "X-ContainerInheritEnv")
+	return Identifier(parent), err
+}
+
+// The environment a container starts with when it inherits from parent:
+// every variable of the parent, replaced by the variables of the same name
+// in the environment id.  The environment id need not exist, but the parent
+// must.  Variables are written in name order, one NAME=value line each.
+func ResolveInheritedEnvironment(id, parent Identifier) ([]byte, error) {
+	inherited, err := ReadEnvironmentVariables(parent)
+	if err != nil {
+		return nil, err
+	}
+	own, err := ReadEnvironmentVariables(id)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for name, value := range own {
+		inherited[name] = value
+	}
+
+	names := make([]string, 0, len(inherited))
+	for name := range inherited {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(buf, "%s=%s\n", name, inherited[name])
+	}
+	return buf.Bytes(), nil
+}
+
+// The variables of the stored environment id, decrypted if it is
+// encrypted.
+func ReadEnvironmentVariables(id Identifier) (map[string]string, error) {
+	data, err := ReadEnvironmentFile(id.EnvironmentPathFor())
+	if err != nil {
+		return nil, err
+	}
+	env := &EnvironmentDescription{}
+	if err := env.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return env.Map(), nil
+}
@@ -0,0 +1,45 @@
+package containers
+
+import (
+	"os"
+	"testing"
+)
+
+func TestResolveInheritedEnvironment(t *testing.T) {
+	_, cleanup := withEncryptedEnvironment(t)
+	defer cleanup()
+
+	base := &EnvironmentDescription{Id: "test-base", Variables: []Environment{{"DB_HOST", "db"}, {"LOG_LEVEL", "info"}}}
+	if err := base.Write(false); err != nil {
+		t.Fatalf("Unable to write environment: %v", err)
+	}
+	own := &EnvironmentDescription{Id: "test-child", Variables: []Environment{{"LOG_LEVEL", "debug"}, {"PORT", "8080"}}}
+	if err := own.Write(false); err != nil {
+		t.Fatalf("Unable to write environment: %v", err)
+	}
+
+	data, err := ResolveInheritedEnvironment("test-child", "test-base")
+	if err != nil {
+		t.Fatalf("Unable to resolve the environment: %v", err)
+	}
+	if expected := "DB_HOST=db\nLOG_LEVEL=debug\nPORT=8080\n"; string(data) != expected {
+		t.Errorf("Expected the container's variables to replace the inherited ones, got %q", string(data))
+	}
+
+	// changes to the parent are seen the next time it is resolved
+	base.Variables = []Environment{{"DB_HOST", "db2"}}
+	if err := base.Write(false); err != nil {
+		t.Fatalf("Unable to write environment: %v", err)
+	}
+	if data, _ := ResolveInheritedEnvironment("test-child", "test-base"); string(data) != "DB_HOST=db2\nLOG_LEVEL=debug\nPORT=8080\n" {
+		t.Errorf("Expected the changed parent to be inherited, got %q", string(data))
+	}
+
+	// a container without its own environment inherits everything
+	if data, err := ResolveInheritedEnvironment("test-none", "test-base"); err != nil || string(data) != "DB_HOST=db2\n" {
+		t.Errorf("Expected only the inherited environment, got %q %v", string(data), err)
+	}
+	if _, err := ResolveInheritedEnvironment("test-child", "test-missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing parent to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package containers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Read environment variables from a JSON object of names to values, such
+// as {"KEY":"value"}.  Every value must be a string - numbers, booleans,
+// null, arrays and objects are rejected rather than converted, so that a
+// value is never written differently than the caller expects - quote a
+// number to pass it.  The variables are sorted by name.
+func ReadEnvironmentJSON(r io.Reader) (EnvironmentVariables, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("The environment must be a JSON object of names to string values: %s", err.Error())
+	}
+	if values == nil {
+		return nil, fmt.Errorf("The environment must be a JSON object of names to string values")
+	}
+
+	env := make(EnvironmentVariables, 0, len(values))
+	for name, raw := range values {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil || strings.TrimSpace(string(raw)) == "null" {
+			return nil, fmt.Errorf("The value of %s must be a string, got %s", name, raw)
+		}
+		// environment files hold one variable per line
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("The value of %s must be a single line", name)
+		}
+		if strings.ContainsAny(name, whiteSpaces+"=") {
+			return nil, fmt.Errorf("The variable name '%s' may not contain white space or '='", name)
+		}
+		e := Environment{name, value}
+		if err := e.Check(); err != nil {
+			return nil, fmt.Errorf("The variable '%s' is invalid: %s", name, err.Error())
+		}
+		env = append(env, e)
+	}
+	sort.Sort(environmentByName(env))
+	return env, nil
+}
+
+type environmentByName EnvironmentVariables
+
+func (e environmentByName) Len() int           { return len(e) }
+func (e environmentByName) Less(i, j int) bool { return e[i].Name < e[j].Name }
+func (e environmentByName) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
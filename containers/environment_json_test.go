@@ -0,0 +1,51 @@
+package containers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadEnvironmentJSON(t *testing.T) {
+	env, err := ReadEnvironmentJSON(strings.NewReader(`{"PORT": "8080", "GREETING": "hello \"world\"", "EMPTY": ""}`))
+	if err != nil {
+		t.Fatalf("Unable to read the environment: %v", err)
+	}
+	expected := EnvironmentVariables{{"EMPTY", ""}, {"GREETING", `hello "world"`}, {"PORT", "8080"}}
+	if len(env) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, env)
+	}
+	for i := range expected {
+		if env[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], env[i])
+		}
+	}
+
+	env, err = ReadEnvironmentJSON(strings.NewReader(`{}`))
+	if err != nil || len(env) != 0 {
+		t.Errorf("Expected an empty object to have no variables, got %v %v", env, err)
+	}
+}
+
+func TestReadEnvironmentJSONRejected(t *testing.T) {
+	for value, message := range map[string]string{
+		``:                  "must be a JSON object",
+		`null`:              "must be a JSON object",
+		`["A"]`:             "must be a JSON object",
+		`{"A": "b"`:         "must be a JSON object",
+		`{"A": "b"} {}`:     "must be a JSON object",
+		`{"PORT": 8080}`:    "The value of PORT must be a string",
+		`{"DEBUG": true}`:   "The value of DEBUG must be a string",
+		`{"A": null}`:       "The value of A must be a string",
+		`{"A": {"B": "c"}}`: "The value of A must be a string",
+		`{"A": ["b"]}`:      "The value of A must be a string",
+		`{"A": "b\nc"}`:     "must be a single line",
+		`{"": "b"}`:         "Name may not be empty",
+		`{"A B": "c"}`:      "may not contain white space",
+		`{"A=B": "c"}`:      "may not contain white space or '='",
+	} {
+		_, err := ReadEnvironmentJSON(strings.NewReader(value))
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q to fail with %q, got %v", value, message, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package containers
+
+import (
+	"fmt"
+)
+
+// The largest value a variable may have, regardless of the limits the
+// daemon enforces.
+const MaxEnvironmentValueSize = 8 * 1024
+
+// Limits on the size of a stored environment, so that a runaway value
+// can't bloat the units that read it.  A limit of zero is not enforced.
+type EnvironmentLimits struct {
+	// Bytes in the value of a single variable
+	MaxValueSize int
+	// Bytes in the environment as written, one NAME=value line per
+	// variable
+	MaxTotalSize int
+}
+
+var DefaultEnvironmentLimits = EnvironmentLimits{MaxEnvironmentValueSize, 64 * 1024}
+
+// The limits enforced when an environment is changed, set with the daemon
+// flags --env-max-value-size and --env-max-size.
+var EnvironmentSizeLimits = DefaultEnvironmentLimits
+
+// An environment change that would exceed a limit, naming the variable
+// responsible.
+type EnvironmentTooLargeError struct {
+	Name string
+	// The size of the value, or of the whole environment if Total is true
+	Size  int
+	Limit int
+	Total bool
+}
+
+func (e EnvironmentTooLargeError) Error() string {
+	if e.Total {
+		return fmt.Sprintf("Setting %s would make the environment %d bytes, more than the limit of %d bytes.", e.Name, e.Size, e.Limit)
+	}
+	return fmt.Sprintf("The value of %s is %d bytes, more than the limit of %d bytes.", e.Name, e.Size, e.Limit)
+}
+
+// The size in bytes of a variable as written to an environment file.
+func environmentLineSize(name, value string) int {
+	return len(name) + len(value) + 2
+}
+
+// The size in bytes of an environment as written, one NAME=value line per
+// variable.
+func EnvironmentSize(env map[string]string) int {
+	size := 0
+	for name, value := range env {
+		size += environmentLineSize(name, value)
+	}
+	return size
+}
+
+// Return an EnvironmentTooLargeError if writing variables to an
+// environment with the current values would exceed the limits.  If
+// replace is true the variables replace the environment, otherwise they
+// are merged into it.  When the total is exceeded the first variable that
+// takes the environment over the limit is named.
+func (l EnvironmentLimits) Check(current map[string]string, variables []Environment, replace bool) error {
+	next := make(map[string]string)
+	if !replace {
+		for name, value := range current {
+			next[name] = value
+		}
+	}
+	size := EnvironmentSize(next)
+
+	for i := range variables {
+		e := &variables[i]
+		if l.MaxValueSize > 0 && len(e.Value) > l.MaxValueSize {
+			return EnvironmentTooLargeError{e.Name, len(e.Value), l.MaxValueSize, false}
+		}
+		if old, ok := next[e.Name]; ok {
+			size -= environmentLineSize(e.Name, old)
+		}
+		next[e.Name] = e.Value
+		size += environmentLineSize(e.Name, e.Value)
+		if l.MaxTotalSize > 0 && size > l.MaxTotalSize {
+			return EnvironmentTooLargeError{e.Name, size, l.MaxTotalSize, true}
+		}
+	}
+	return nil
+}
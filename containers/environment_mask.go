@@ -0,0 +1,34 @@
+package containers
+
+import (
+	"strings"
+
+	"github.com/openshift/geard/utils"
+)
+
+// True if a variable named name is assumed to hold a secret: names ending
+// in _TOKEN or _SECRET, or containing PASSWORD, in any case.
+func IsSecretEnvironmentName(name string) bool {
+	name = strings.ToUpper(name)
+	return strings.HasSuffix(name, "_TOKEN") || strings.HasSuffix(name, "_SECRET") || strings.Contains(name, "PASSWORD")
+}
+
+// The variable as NAME=value, with the value masked if the name marks it
+// as a secret.
+func (e Environment) String() string {
+	if IsSecretEnvironmentName(e.Name) {
+		return e.Name + "=" + utils.MaskSecret(e.Value)
+	}
+	return e.Name + "=" + e.Value
+}
+
+// Remember the values of the variables whose names mark them as secrets,
+// or of every variable if all is set, so that they are masked wherever
+// they appear in logs and errors.
+func trackSecretValues(vars []Environment, all bool) {
+	for i := range vars {
+		if all || IsSecretEnvironmentName(vars[i].Name) {
+			utils.AddSecretValue(vars[i].Value)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package containers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The types a variable of an environment schema may declare
+const (
+	VariableTypeString = "string"
+	VariableTypeInt    = "int"
+	VariableTypeNumber = "number"
+	VariableTypeBool   = "bool"
+)
+
+// The variables an environment must or may have, read from JSON such as
+// {"Variables": {"LOG_LEVEL": {"Required": true, "Values": ["debug", "info"]},
+// "WORKERS": {"Type": "int"}, "REGION": {"Pattern": "[a-z]+-[0-9]"}}}
+type EnvironmentSchema struct {
+	Variables map[string]VariableSchema
+	// Reject variables the schema does not declare
+	Strict bool `json:"Strict,omitempty"`
+}
+
+// What a single variable of an environment schema allows.
+type VariableSchema struct {
+	Required bool `json:"Required,omitempty"`
+	// One of the VariableType constants, string if empty
+	Type string `json:"Type,omitempty"`
+	// If set, the only values the variable may have
+	Values []string `json:"Values,omitempty"`
+	// A regular expression the whole value must match
+	Pattern string `json:"Pattern,omitempty"`
+}
+
+func ReadEnvironmentSchema(r io.Reader) (*EnvironmentSchema, error) {
+	s := &EnvironmentSchema{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("The environment schema is not valid JSON: %s", err.Error())
+	}
+	if err := s.Check(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *EnvironmentSchema) Check() error {
+	for _, name := range s.names() {
+		v := s.Variables[name]
+		switch v.Type {
+		case "", VariableTypeString, VariableTypeInt, VariableTypeNumber, VariableTypeBool:
+		default:
+			return fmt.Errorf("The type '%s' of %s in the environment schema must be one of string, int, number, or bool", v.Type, name)
+		}
+		if _, err := v.pattern(); err != nil {
+			return fmt.Errorf("The pattern of %s in the environment schema is not valid: %s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// A failure of an environment to match a schema, with a message for each
+// variable that doesn't.
+type EnvironmentSchemaError struct {
+	Problems []string
+}
+
+func (e EnvironmentSchemaError) Error() string {
+	return "The environment does not match the schema: " + strings.Join(e.Problems, "; ")
+}
+
+// Return an EnvironmentSchemaError listing every variable of env that
+// doesn't match the schema, or is required and missing, sorted by name.
+// Values are never included in the messages, since they may be secret.
+func (s *EnvironmentSchema) Validate(env map[string]string) error {
+	if err := s.Check(); err != nil {
+		return err
+	}
+	problems := []string{}
+	for _, name := range s.names() {
+		v := s.Variables[name]
+		value, ok := env[name]
+		if !ok {
+			if v.Required {
+				problems = append(problems, name+" is required")
+			}
+			continue
+		}
+		if problem := v.check(value); problem != "" {
+			problems = append(problems, name+" "+problem)
+		}
+	}
+	if s.Strict {
+		undeclared := []string{}
+		for name := range env {
+			if _, ok := s.Variables[name]; !ok {
+				undeclared = append(undeclared, name+" is not declared by the schema")
+			}
+		}
+		sort.Strings(undeclared)
+		problems = append(problems, undeclared...)
+	}
+	if len(problems) > 0 {
+		return EnvironmentSchemaError{problems}
+	}
+	return nil
+}
+
+func (s *EnvironmentSchema) names() []string {
+	names := make([]string, 0, len(s.Variables))
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The problem with value, or an empty string if it is allowed
+func (v *VariableSchema) check(value string) string {
+	var err error
+	switch v.Type {
+	case VariableTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case VariableTypeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case VariableTypeBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return "must be of type " + v.Type
+	}
+	if len(v.Values) > 0 {
+		allowed := false
+		for i := range v.Values {
+			if v.Values[i] == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "must be one of " + strings.Join(v.Values, ", ")
+		}
+	}
+	if re, _ := v.pattern(); re != nil && !re.MatchString(value) {
+		return "must match the pattern " + v.Pattern
+	}
+	return ""
+}
+
+// The pattern anchored to match the whole value, nil if there is none
+func (v *VariableSchema) pattern() (*regexp.Regexp, error) {
+	if v.Pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + v.Pattern + ")$")
+}
@@ -0,0 +1,79 @@
+package containers_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/openshift/geard/containers"
+)
+
+const testEnvironmentSchema = `{"Variables": {
+  "LOG_LEVEL": {"Required": true, "Values": ["debug", "info", "warn"]},
+  "WORKERS": {"Type": "int"},
+  "RATIO": {"Type": "number"},
+  "DEBUG": {"Type": "bool"},
+  "REGION": {"Pattern": "[a-z]+-[0-9]"}
+}}`
+
+func TestEnvironmentSchemaPasses(t *testing.T) {
+	schema, err := ReadEnvironmentSchema(strings.NewReader(testEnvironmentSchema))
+	if err != nil {
+		t.Fatalf("Unable to read the schema: %v", err)
+	}
+	env := map[string]string{"LOG_LEVEL": "info", "WORKERS": "4", "RATIO": "0.5", "DEBUG": "false", "REGION": "us-1", "OTHER": "anything"}
+	if err := schema.Validate(env); err != nil {
+		t.Errorf("Expected the environment to match the schema, got %v", err)
+	}
+	if err := schema.Validate(map[string]string{"LOG_LEVEL": "debug"}); err != nil {
+		t.Errorf("Expected optional variables to be left out, got %v", err)
+	}
+}
+
+func TestEnvironmentSchemaViolations(t *testing.T) {
+	schema, err := ReadEnvironmentSchema(strings.NewReader(testEnvironmentSchema))
+	if err != nil {
+		t.Fatalf("Unable to read the schema: %v", err)
+	}
+	env := map[string]string{"LOG_LEVEL": "verbose", "WORKERS": "four", "RATIO": "half", "DEBUG": "maybe", "REGION": "us-1a"}
+	err = schema.Validate(env)
+	schemaErr, ok := err.(EnvironmentSchemaError)
+	if !ok {
+		t.Fatalf("Expected a schema error, got %v", err)
+	}
+	expected := []string{
+		"DEBUG must be of type bool",
+		"LOG_LEVEL must be one of debug, info, warn",
+		"RATIO must be of type number",
+		"REGION must match the pattern [a-z]+-[0-9]",
+		"WORKERS must be of type int",
+	}
+	if strings.Join(schemaErr.Problems, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected a problem for each variable:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(schemaErr.Problems, "\n"))
+	}
+	if strings.Contains(err.Error(), "verbose") || strings.Contains(err.Error(), "four") {
+		t.Errorf("Expected the values to be left out of the error, got %v", err)
+	}
+
+	err = schema.Validate(map[string]string{"WORKERS": "2"})
+	if err == nil || err.Error() != "The environment does not match the schema: LOG_LEVEL is required" {
+		t.Errorf("Expected a missing required variable to be reported, got %v", err)
+	}
+
+	schema.Strict = true
+	err = schema.Validate(map[string]string{"LOG_LEVEL": "info", "B_EXTRA": "1", "A_EXTRA": "1"})
+	if err == nil || !strings.HasSuffix(err.Error(), "A_EXTRA is not declared by the schema; B_EXTRA is not declared by the schema") {
+		t.Errorf("Expected undeclared variables to be rejected by a strict schema, got %v", err)
+	}
+}
+
+func TestReadEnvironmentSchemaInvalid(t *testing.T) {
+	for _, s := range []string{
+		`{"Variables": {"A": {"Type": "integer"}}}`,
+		`{"Variables": {"A": {"Pattern": "[a-"}}}`,
+		`{"Variables": `,
+	} {
+		if _, err := ReadEnvironmentSchema(strings.NewReader(s)); err == nil {
+			t.Errorf("Expected the schema %s to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package containers
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The orders an environment can be printed in
+const (
+	// Sorted by variable name, so that the output can be diffed
+	EnvironmentSortKey = "key"
+	// The order of the environment file
+	EnvironmentSortInsertion = "insertion"
+)
+
+func CheckEnvironmentSort(order string) error {
+	switch order {
+	case EnvironmentSortKey, EnvironmentSortInsertion:
+		return nil
+	}
+	return fmt.Errorf("the sort order must be '%s' or '%s'", EnvironmentSortKey, EnvironmentSortInsertion)
+}
+
+// A copy of the variables, sorted by name.
+func (e EnvironmentVariables) Sorted() EnvironmentVariables {
+	sorted := make(EnvironmentVariables, len(e))
+	copy(sorted, e)
+	sort.Stable(environmentByName(sorted))
+	return sorted
+}
+
+// Write the variables as lines of an environment file, in the order given.
+// A value that would read back differently unquoted, such as one with a
+// newline in it, is double quoted so that it stays on one line.
+func WriteEnvironment(w io.Writer, env EnvironmentVariables) error {
+	for i := range env {
+		if _, err := fmt.Fprintln(w, environmentLine(env[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func environmentLine(e Environment) string {
+	line := e.Name + "=" + e.Value
+	if s, ok := dotenvLine(line); ok && !strings.ContainsAny(e.Value, "\r\n") {
+		read := Environment{}
+		if match, err := read.FromString(s); match && err == nil && read.Value == e.Value {
+			return line
+		}
+	}
+	return e.Name + "=" + strconv.Quote(e.Value)
+}
@@ -0,0 +1,59 @@
+package containers_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/openshift/geard/containers"
+)
+
+func TestWriteEnvironmentSorted(t *testing.T) {
+	file := `ZED=last
+MULTI="first line\nsecond line"
+APP_PORT=8080
+# a comment
+B=first
+B=overridden
+QUOTED="'single'"
+`
+	expected := `APP_PORT=8080
+B=overridden
+MULTI="first line\nsecond line"
+QUOTED="'single'"
+ZED=last
+`
+	for i := 0; i < 10; i++ {
+		env := EnvironmentDescription{}
+		if err := env.ReadFrom(strings.NewReader(file)); err != nil {
+			t.Fatalf("Unable to read environment: %v", err)
+		}
+		out := &bytes.Buffer{}
+		if err := WriteEnvironment(out, EnvironmentVariables(env.Variables).Sorted()); err != nil {
+			t.Fatalf("Unable to write environment: %v", err)
+		}
+		if out.String() != expected {
+			t.Fatalf("Expected the variables sorted by name:\n%s\ngot:\n%s", expected, out.String())
+		}
+
+		read := EnvironmentDescription{}
+		if err := read.ReadFrom(out); err != nil {
+			t.Fatalf("Unable to read the sorted environment: %v", err)
+		}
+		if !reflect.DeepEqual(read.Map(), env.Map()) {
+			t.Errorf("Expected the sorted environment to read back the same, got %v", read.Map())
+		}
+	}
+}
+
+func TestReadEnvironmentInsertionOrder(t *testing.T) {
+	env := EnvironmentDescription{}
+	if err := env.ReadFrom(strings.NewReader("C=1\nA=2\nB=3\nA=4\n")); err != nil {
+		t.Fatalf("Unable to read environment: %v", err)
+	}
+	expected := []Environment{{"C", "1"}, {"A", "4"}, {"B", "3"}}
+	if !reflect.DeepEqual(env.Variables, expected) {
+		t.Errorf("Expected the variables in the order they first appear, got %v", env.Variables)
+	}
+}
@@ -0,0 +1,110 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// An environment file may be a Go template (see text/template) that is
+// rendered with a set of values before it is read, allowing one file to
+// serve several deployments.  Values are referenced as {{ .Key }}, and a
+// key that has no value renders as an empty string.  In addition to the
+// builtin template functions the following are available:
+//
+//	required "message" .Key   fail with message if Key is unset or empty
+//	default "value" .Key      use value if Key is unset or empty
+var EnvironmentTemplateFuncs = template.FuncMap{
+	"required": func(message string, value string) (string, error) {
+		if value == "" {
+			return "", errors.New(message)
+		}
+		return value, nil
+	},
+	"default": func(def string, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+}
+
+// Render the environment template in r with values and read the result.
+func (j *EnvironmentDescription) ReadTemplateFrom(r io.Reader, values map[string]string) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New("env").Funcs(EnvironmentTemplateFuncs).Option("missingkey=zero").Parse(string(data))
+	if err != nil {
+		return err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, values); err != nil {
+		return err
+	}
+	return j.ReadDotenvFrom(&rendered)
+}
+
+// Read the values for an environment template from a flat YAML mapping
+// with one "key: value" pair per line.  Nested mappings and lists are not
+// supported.
+func ReadEnvironmentValues(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := scanner.Text()
+		trimmed := strings.TrimSpace(s)
+		if trimmed == "" || trimmed == "---" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if s != strings.TrimLeft(s, whiteSpaces) || strings.HasPrefix(trimmed, "- ") {
+			return nil, fmt.Errorf("line %d: only a flat list of 'key: value' pairs is supported", line)
+		}
+		pair := strings.SplitN(trimmed, ":", 2)
+		if len(pair) != 2 || strings.TrimSpace(pair[0]) == "" {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", line)
+		}
+		value, err := yamlScalar(strings.TrimSpace(pair[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		values[strings.TrimSpace(pair[0])] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func yamlScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		end := strings.LastIndex(s, `"`)
+		if end == 0 {
+			return "", errors.New("unterminated double quoted value")
+		}
+		return strconv.Unquote(s[:end+1])
+	case strings.HasPrefix(s, "'"):
+		end := strings.LastIndex(s, "'")
+		if end == 0 {
+			return "", errors.New("unterminated single quoted value")
+		}
+		return strings.Replace(s[1:end], "''", "'", -1), nil
+	}
+	if i := strings.Index(s, " #"); i != -1 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "~" || s == "null" {
+		return "", nil
+	}
+	return s, nil
+}
@@ -0,0 +1,61 @@
+package containers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadEnvironmentTemplate(t *testing.T) {
+	env := &EnvironmentDescription{}
+	values := map[string]string{"DbHost": "db.example.com", "DbPassword": "secret"}
+	tmpl := `DB_HOST={{ .DbHost }}
+DB_PASSWORD={{ required "DbPassword must be set" .DbPassword }}
+DB_PORT={{ default "5432" .DbPort }}
+`
+	if err := env.ReadTemplateFrom(strings.NewReader(tmpl), values); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"DB_HOST": "db.example.com", "DB_PASSWORD": "secret", "DB_PORT": "5432"}
+	if len(env.Variables) != len(expected) {
+		t.Fatalf("Expected %d variables, got %+v", len(expected), env.Variables)
+	}
+	for _, v := range env.Variables {
+		if expected[v.Name] != v.Value {
+			t.Errorf("Expected %s=%s, got %s", v.Name, expected[v.Name], v.Value)
+		}
+	}
+}
+
+func TestReadEnvironmentTemplateRequired(t *testing.T) {
+	env := &EnvironmentDescription{}
+	err := env.ReadTemplateFrom(strings.NewReader(`DB_PASSWORD={{ required "DbPassword must be set" .DbPassword }}`), map[string]string{})
+	if err == nil {
+		t.Fatal("Expected a missing required value to fail")
+	}
+	if !strings.Contains(err.Error(), "DbPassword must be set") {
+		t.Errorf("Expected the required message in the error, got %v", err)
+	}
+}
+
+func TestReadEnvironmentValues(t *testing.T) {
+	values, err := ReadEnvironmentValues(strings.NewReader(`---
+# database settings
+DbHost: db.example.com
+DbPassword: "p@ss: word"
+DbUser: 'o''brien'
+DbPort: 5432 # default port
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"DbHost": "db.example.com", "DbPassword": "p@ss: word", "DbUser": "o'brien", "DbPort": "5432"}
+	for k, v := range expected {
+		if values[k] != v {
+			t.Errorf("Expected %s to be %q, got %q", k, v, values[k])
+		}
+	}
+
+	if _, err := ReadEnvironmentValues(strings.NewReader("db:\n  host: example.com\n")); err == nil {
+		t.Error("Expected nested values to be rejected")
+	}
+}
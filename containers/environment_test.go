@@ -1,8 +1,13 @@
 package containers_test
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/openshift/geard/config"
 	. "github.com/openshift/geard/containers"
 )
 
@@ -45,3 +50,106 @@ func (e *envTest) assert(t *testing.T) {
 		t.Errorf("Expected value %s to equal %s", env.Value, e.value)
 	}
 }
+
+func TestReadEnvironmentFile(t *testing.T) {
+	file := `# settings for the app
+
+export A=1
+  B = 2
+C="has # inside" # and a comment
+D='single # quoted'
+E=unquoted # comment
+F=#not-a-comment
+G= # empty
+#H=commented out
+	export	I="exported"
+exported=3
+`
+	env := EnvironmentDescription{}
+	if err := env.ReadDotenvFrom(strings.NewReader(file)); err != nil {
+		t.Fatalf("Unable to read environment: %v", err)
+	}
+	expected := map[string]string{
+		"A":        "1",
+		"B":        "2",
+		"C":        "has # inside",
+		"D":        "single # quoted",
+		"E":        "unquoted",
+		"F":        "#not-a-comment",
+		"G":        "",
+		"I":        "exported",
+		"exported": "3",
+	}
+	actual := env.Map()
+	if len(actual) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	for k, v := range expected {
+		if value, ok := actual[k]; !ok || value != v {
+			t.Errorf("Expected %s to be '%s', got '%s' (%t)", k, v, value, ok)
+		}
+	}
+}
+
+func TestWriteEnvironmentRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-env")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+	defer config.SetContainerBasePath(previous)
+
+	variables := []Environment{{"PASSWORD", "abc #def"}, {"Q", "\"x\"y"}, {"E", "a=#b"}}
+	env := &EnvironmentDescription{Id: "test-roundtrip", Variables: variables}
+	if err := env.Write(false); err != nil {
+		t.Fatalf("Unable to write environment: %v", err)
+	}
+	values, err := ReadEnvironmentVariables(env.Id)
+	if err != nil {
+		t.Fatalf("Unable to read environment: %v", err)
+	}
+	for _, v := range variables {
+		if values[v.Name] != v.Value {
+			t.Errorf("Expected %s to read back as %q, got %q", v.Name, v.Value, values[v.Name])
+		}
+	}
+}
+
+func TestDiffEnvironment(t *testing.T) {
+	current := map[string]string{"A": "1", "B": "2", "C": "3"}
+	variables := []Environment{{"B", "2"}, {"C", "changed"}, {"D", "4"}}
+
+	for _, test := range []struct {
+		replace  bool
+		expected string
+	}{
+		{false, "~ C=3 -> changed\n+ D=4\n"},
+		{true, "- A=1\n~ C=3 -> changed\n+ D=4\n"},
+	} {
+		changes := DiffEnvironment(current, variables, test.replace)
+		buf := &bytes.Buffer{}
+		if err := changes.WriteDiffTo(buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Expected the diff with replace=%t to be\n%s\ngot\n%s", test.replace, test.expected, buf.String())
+		}
+	}
+
+	changes := DiffEnvironment(current, variables[:1], false)
+	if len(changes) != 0 {
+		t.Errorf("Expected setting an existing value to be no change, got %+v", changes)
+	}
+	buf := &bytes.Buffer{}
+	changes.WriteDiffTo(buf)
+	if buf.String() != "No changes\n" {
+		t.Errorf("Expected no changes to be reported, got %q", buf.String())
+	}
+
+	changes = DiffEnvironment(map[string]string{}, variables[2:], false)
+	if len(changes) != 1 || !changes[0].Added || changes[0].New != "4" {
+		t.Errorf("Expected a missing environment to have every variable added, got %+v", changes)
+	}
+}
@@ -0,0 +1,205 @@
+// Applies environment files on the host to the containers installed to
+// watch them, for secrets rotated by an agent outside of geard.
+package envwatch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/systemd"
+)
+
+// Watches the environment file of each installed container that was given
+// one, by container id.  The containers are found from their unit files on
+// each check, so a container that is removed stops being watched.
+type Watcher struct {
+	// The Docker daemon used to signal containers that reload their
+	// environment
+	DockerSocket string
+
+	// Runs the job for a request, by default the job registered for it
+	// through the dispatcher
+	Execute func(request interface{}) error
+	// Whether the container is running, by default from systemd
+	Active func(id containers.Identifier) bool
+	// If set and true, Run skips checking, for instance while the daemon
+	// is in maintenance mode
+	Paused func() bool
+
+	watches map[containers.Identifier]*watch
+}
+
+type watch struct {
+	path    string
+	restart bool
+	// The checksum of the file when it was last applied
+	checksum string
+	// The last error reading the file, so that it is only logged once
+	failure string
+}
+
+func NewWatcher(d *dispatcher.Dispatcher) *Watcher {
+	return &Watcher{
+		Execute: d.Execute,
+		Active:  unitActive,
+		watches: make(map[containers.Identifier]*watch),
+	}
+}
+
+// Check immediately and then every interval until stop is closed.
+func (w *Watcher) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if w.Paused != nil && w.Paused() {
+			log.Printf("envwatch: Paused, skipping environment files")
+		} else if err := w.Check(); err != nil {
+			log.Printf("envwatch: Unable to check environment files: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Start and stop watching the files of containers that were installed or
+// removed since the last check, and apply each watched file whose variables
+// differ from the environment of its container.  A file that can't be
+// applied is tried again on the next check.
+func (w *Watcher) Check() error {
+	ids, err := containers.InstalledIdentifiers()
+	if err != nil {
+		return err
+	}
+	installed := make(map[containers.Identifier]bool, len(ids))
+	for _, id := range ids {
+		installed[id] = true
+		path, restart, err := containers.GetEnvFileWatch(id)
+		if err != nil {
+			log.Printf("envwatch: Unable to read the unit of %s: %v", id, err)
+			continue
+		}
+		existing := w.watches[id]
+		if path == "" {
+			if existing != nil {
+				log.Printf("envwatch: Stopped watching %s for %s", existing.path, id)
+				delete(w.watches, id)
+			}
+			continue
+		}
+		if existing == nil || existing.path != path || existing.restart != restart {
+			log.Printf("envwatch: Watching %s for %s", path, id)
+			existing = &watch{path: path, restart: restart}
+			w.watches[id] = existing
+		}
+		w.apply(id, existing)
+	}
+	for id, existing := range w.watches {
+		if !installed[id] {
+			log.Printf("envwatch: Stopped watching %s, %s was removed", existing.path, id)
+			delete(w.watches, id)
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) apply(id containers.Identifier, watch *watch) {
+	// the file may have been replaced by a link since it was installed
+	data, err := readWatchedFile(watch.path)
+	if err != nil {
+		if err.Error() != watch.failure {
+			log.Printf("envwatch: Unable to read %s for %s: %v", watch.path, id, err)
+			watch.failure = err.Error()
+		}
+		return
+	}
+	watch.failure = ""
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if checksum == watch.checksum {
+		return
+	}
+
+	env := containers.EnvironmentDescription{Id: id}
+	if err := env.ReadDotenvFrom(bytes.NewReader(data)); err != nil {
+		log.Printf("envwatch: Unable to read %s for %s: %v", watch.path, id, err)
+		return
+	}
+	current, err := containers.ReadEnvironmentVariables(id)
+	if os.IsNotExist(err) {
+		current = map[string]string{}
+	} else if err != nil {
+		log.Printf("envwatch: Unable to read the environment of %s: %v", id, err)
+		return
+	}
+	// the file is compared to the environment rather than to its last
+	// contents, so that a daemon restart doesn't reapply an applied file
+	changes := containers.DiffEnvironment(current, env.Variables, false)
+	if len(changes) == 0 {
+		watch.checksum = checksum
+		return
+	}
+
+	signal, err := containers.GetEnvReloadSignal(id)
+	if err != nil {
+		log.Printf("envwatch: Unable to read the unit of %s: %v", id, err)
+		return
+	}
+	log.Printf("envwatch: Applying %s to %s, %d variables changed", watch.path, id, len(changes))
+	patch := &cjobs.PatchEnvironmentRequest{
+		EnvironmentDescription: env,
+		EnvironmentReload: cjobs.EnvironmentReload{
+			Reload:       signal != "" && !watch.restart,
+			DockerSocket: w.DockerSocket,
+		},
+	}
+	if err := w.Execute(patch); err != nil {
+		log.Printf("envwatch: Unable to apply %s to %s: %v", watch.path, id, err)
+		return
+	}
+	watch.checksum = checksum
+
+	if watch.restart && w.Active(id) {
+		log.Printf("envwatch: Restarting %s", id)
+		if err := w.Execute(&cjobs.RestartContainerRequest{Id: id}); err != nil {
+			log.Printf("envwatch: Unable to restart %s: %v", id, err)
+		}
+	}
+}
+
+// Read the file if it is still inside the directory files may be watched
+// in, opening it without following a final symlink.
+func readWatchedFile(path string) ([]byte, error) {
+	if err := containers.CheckEnvFileWatchPath(path); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+func unitActive(id containers.Identifier) bool {
+	props, err := systemd.Connection().GetUnitProperties(id.UnitNameFor())
+	if err != nil {
+		return false
+	}
+	switch props["ActiveState"] {
+	case "active", "activating", "reloading":
+		return true
+	}
+	return false
+}
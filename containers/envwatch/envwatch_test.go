@@ -0,0 +1,173 @@
+package envwatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+)
+
+// Records the requests executed, and writes the patched environment where
+// the watcher reads it.
+type fakeExecutor struct {
+	t        *testing.T
+	requests []string
+}
+
+func (f *fakeExecutor) Execute(request interface{}) error {
+	switch r := request.(type) {
+	case *cjobs.PatchEnvironmentRequest:
+		current, err := containers.ReadEnvironmentVariables(r.Id)
+		if os.IsNotExist(err) {
+			current = map[string]string{}
+		} else if err != nil {
+			return err
+		}
+		vars := []string{}
+		for _, v := range r.Variables {
+			current[v.Name] = v.Value
+			vars = append(vars, v.Name+"="+v.Value)
+		}
+		sort.Strings(vars)
+		writeEnvironment(f.t, r.Id, current)
+		f.requests = append(f.requests, fmt.Sprintf("patch %s %s reload=%t", r.Id, strings.Join(vars, " "), r.Reload))
+	case *cjobs.RestartContainerRequest:
+		f.requests = append(f.requests, fmt.Sprintf("restart %s", r.Id))
+	default:
+		f.t.Fatalf("Unexpected request %#v", request)
+	}
+	return nil
+}
+
+func (f *fakeExecutor) expect(expected ...string) {
+	if len(expected) == 0 {
+		expected = nil
+	}
+	if !reflect.DeepEqual(f.requests, expected) {
+		f.t.Fatalf("Expected requests %v, got %v", expected, f.requests)
+	}
+	f.requests = nil
+}
+
+func writeEnvironment(t *testing.T, id containers.Identifier, vars map[string]string) {
+	buf := ""
+	for k, v := range vars {
+		buf += k + "=" + v + "\n"
+	}
+	os.MkdirAll(filepath.Dir(id.EnvironmentPathFor()), 0750)
+	if err := ioutil.WriteFile(id.EnvironmentPathFor(), []byte(buf), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeUnit(t *testing.T, id containers.Identifier, lines ...string) {
+	os.MkdirAll(filepath.Dir(id.UnitPathFor()), 0750)
+	content := "X-ContainerId=" + string(id) + "\n" + strings.Join(lines, "\n") + "\n"
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherAppliesChangedFile(t *testing.T) {
+	base, err := ioutil.TempDir("", "geard-base")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(base)
+	defer config.SetContainerBasePath(config.ContainerBasePath())
+	config.SetContainerBasePath(base)
+	defer func() { containers.EnvFileWatchDir = "" }()
+	containers.EnvFileWatchDir = base
+
+	webSecrets := filepath.Join(base, "web.env")
+	apiSecrets := filepath.Join(base, "api.env")
+	writeUnit(t, "test-web", "X-EnvFileWatch="+webSecrets, "X-EnvFileWatchRestart=true")
+	writeUnit(t, "test-api", "X-EnvReloadSignal=SIGHUP", "X-EnvFileWatch="+apiSecrets)
+	writeUnit(t, "test-db")
+	writeEnvironment(t, "test-web", map[string]string{"PASSWORD": "one", "PORT": "8080"})
+	ioutil.WriteFile(webSecrets, []byte("PASSWORD=one\n"), 0600)
+
+	f := &fakeExecutor{t: t}
+	w := NewWatcher(nil)
+	w.Execute = f.Execute
+	w.Active = func(id containers.Identifier) bool { return true }
+
+	// a file that matches the environment, or doesn't exist yet, is left
+	if err := w.Check(); err != nil {
+		t.Fatal(err)
+	}
+	f.expect()
+
+	ioutil.WriteFile(webSecrets, []byte("# rotated\nPASSWORD=two\n"), 0600)
+	ioutil.WriteFile(apiSecrets, []byte("TOKEN=abc\n"), 0600)
+	w.Check()
+	f.expect("patch test-api TOKEN=abc reload=true", "patch test-web PASSWORD=two reload=false", "restart test-web")
+	if env, _ := containers.ReadEnvironmentVariables("test-web"); env["PORT"] != "8080" {
+		t.Errorf("Expected the file to be merged into the environment, got %v", env)
+	}
+
+	// unchanged files are not applied again
+	w.Check()
+	f.expect()
+
+	// a removed container is no longer watched
+	os.Remove(containers.Identifier("test-web").UnitPathFor())
+	ioutil.WriteFile(webSecrets, []byte("PASSWORD=three\n"), 0600)
+	w.Check()
+	f.expect()
+	if _, ok := w.watches["test-web"]; ok || len(w.watches) != 1 {
+		t.Errorf("Expected only test-api to be watched, got %v", w.watches)
+	}
+}
+
+func TestWatcherOnlyReadsWatchDir(t *testing.T) {
+	base, err := ioutil.TempDir("", "geard-base")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(base)
+	defer config.SetContainerBasePath(config.ContainerBasePath())
+	config.SetContainerBasePath(base)
+	watchDir := filepath.Join(base, "secrets")
+	os.MkdirAll(watchDir, 0700)
+	defer func() { containers.EnvFileWatchDir = "" }()
+	containers.EnvFileWatchDir = watchDir
+
+	// the environment of another container, outside the watch directory
+	writeEnvironment(t, "test-db", map[string]string{"DB_PASSWORD": "secret"})
+	outside := containers.Identifier("test-db").EnvironmentPathFor()
+	linked := filepath.Join(watchDir, "web.env")
+	if err := os.Symlink(outside, linked); err != nil {
+		t.Fatal(err)
+	}
+	writeUnit(t, "test-web", "X-EnvFileWatch="+linked)
+	writeUnit(t, "test-api", "X-EnvFileWatch="+outside)
+
+	f := &fakeExecutor{t: t}
+	w := NewWatcher(nil)
+	w.Execute = f.Execute
+	w.Active = func(id containers.Identifier) bool { return false }
+	w.Check()
+	f.expect()
+	if !strings.Contains(w.watches["test-web"].failure, "links outside") || !strings.Contains(w.watches["test-api"].failure, "must be inside") {
+		t.Errorf("Expected files outside the watch directory to be refused, got %q and %q", w.watches["test-web"].failure, w.watches["test-api"].failure)
+	}
+
+	for path, allowed := range map[string]bool{linked: false, outside: false, filepath.Join(watchDir, "missing.env"): true, watchDir: false, watchDir + "-other/a.env": false} {
+		if err := containers.CheckEnvFileWatchPath(path); (err == nil) != allowed {
+			t.Errorf("Expected %s allowed=%t, got %v", path, allowed, err)
+		}
+	}
+	containers.EnvFileWatchDir = ""
+	if err := containers.CheckEnvFileWatchPath(filepath.Join(watchDir, "web.env")); err == nil {
+		t.Error("Expected no file to be watched without a watch directory")
+	}
+}
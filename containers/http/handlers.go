@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"strconv"
 
 	"github.com/openshift/geard/containers"
 	cjobs "github.com/openshift/geard/containers/jobs"
 	"github.com/openshift/geard/http"
 	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
 	"github.com/openshift/go-json-rest"
 )
 
@@ -27,17 +29,27 @@ func (h *HttpExtension) Routes() []http.HttpJobHandler {
 		&HttpStartContainerRequest{},
 		&HttpStopContainerRequest{},
 		&HttpRestartContainerRequest{},
+		&HttpPauseContainerRequest{},
+		&HttpUnpauseContainerRequest{},
+		&HttpResetFailedContainerRequest{},
+		&HttpInitPreStartRequest{},
+		&HttpInitPostStartRequest{},
+		&HttpReassignPortRequest{},
+		&HttpRenameContainerRequest{},
+		&HttpExecRequest{},
 
 		&HttpLinkContainersRequest{},
 
 		&HttpListContainersRequest{},
 		&HttpListImagesRequest{},
 		&HttpListBuildsRequest{},
+		&HttpHostStatusRequest{},
 
 		&HttpBuildImageRequest{},
 
 		&HttpPatchEnvironmentRequest{},
 		&HttpPutEnvironmentRequest{},
+		&HttpCopyEnvironmentRequest{},
 
 		&HttpContentRequest{},
 		&HttpContentRequest{ContentRequest: cjobs.ContentRequest{Subpath: "*"}},
@@ -55,10 +67,26 @@ func (h *HttpExtension) HttpJobFor(job interface{}) (exc http.RemoteExecutable,
 		exc = &HttpStopContainerRequest{StoppedContainerStateRequest: *j}
 	case *cjobs.RestartContainerRequest:
 		exc = &HttpRestartContainerRequest{RestartContainerRequest: *j}
+	case *cjobs.PausedContainerStateRequest:
+		exc = &HttpPauseContainerRequest{PausedContainerStateRequest: *j}
+	case *cjobs.UnpausedContainerStateRequest:
+		exc = &HttpUnpauseContainerRequest{UnpausedContainerStateRequest: *j}
+	case *cjobs.ResetFailedContainerRequest:
+		exc = &HttpResetFailedContainerRequest{ResetFailedContainerRequest: *j}
+	case *cjobs.InitPreStartRequest:
+		exc = &HttpInitPreStartRequest{InitPreStartRequest: *j}
+	case *cjobs.InitPostStartRequest:
+		exc = &HttpInitPostStartRequest{InitPostStartRequest: *j}
+	case *cjobs.ReassignPortRequest:
+		exc = &HttpReassignPortRequest{ReassignPortRequest: *j}
+	case *cjobs.RenameContainerRequest:
+		exc = &HttpRenameContainerRequest{RenameContainerRequest: *j}
 	case *cjobs.PutEnvironmentRequest:
 		exc = &HttpPutEnvironmentRequest{PutEnvironmentRequest: *j}
 	case *cjobs.PatchEnvironmentRequest:
 		exc = &HttpPatchEnvironmentRequest{PatchEnvironmentRequest: *j}
+	case *cjobs.CopyEnvironmentRequest:
+		exc = &HttpCopyEnvironmentRequest{CopyEnvironmentRequest: *j}
 	case *cjobs.ContainerStatusRequest:
 		exc = &HttpContainerStatusRequest{ContainerStatusRequest: *j}
 	case *cjobs.ContentRequest:
@@ -69,6 +97,12 @@ func (h *HttpExtension) HttpJobFor(job interface{}) (exc http.RemoteExecutable,
 		exc = &HttpLinkContainersRequest{LinkContainersRequest: *j}
 	case *cjobs.ListContainersRequest:
 		exc = &HttpListContainersRequest{ListContainersRequest: *j}
+	case *cjobs.ExecRequest:
+		exc = &HttpExecRequest{ExecRequest: *j}
+	case *cjobs.BuildImageRequest:
+		exc = &HttpBuildImageRequest{BuildImageRequest: *j}
+	case *cjobs.HostStatusRequest:
+		exc = &HttpHostStatusRequest{HostStatusRequest: *j}
 	default:
 		err = jobs.ErrNoJobForRequest
 	}
@@ -127,6 +161,7 @@ func (h *HttpInstallContainerRequest) Handler(conf *http.HttpConfiguration) http
 		}
 		data.Id = id
 		data.RequestIdentifier = context.Id
+		data.DockerSocket = conf.Docker.Socket
 
 		if err := data.Check(); err != nil {
 			return nil, err
@@ -163,7 +198,24 @@ func (h *HttpListContainersRequest) HttpMethod() string { return "GET" }
 func (h *HttpListContainersRequest) HttpPath() string   { return "/containers" }
 func (h *HttpListContainersRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
 	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
-		return &cjobs.ListContainersRequest{}, nil
+		selector, err := containers.ParseSelector(r.URL.Query().Get("selector"))
+		if err != nil {
+			return nil, err
+		}
+		return &cjobs.ListContainersRequest{Selector: selector}, nil
+	}
+}
+
+type HttpHostStatusRequest struct {
+	cjobs.HostStatusRequest
+	http.DefaultRequest
+}
+
+func (h *HttpHostStatusRequest) HttpMethod() string { return "GET" }
+func (h *HttpHostStatusRequest) HttpPath() string   { return "/host/status" }
+func (h *HttpHostStatusRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		return &cjobs.HostStatusRequest{}, nil
 	}
 }
 
@@ -211,7 +263,7 @@ type HttpContainerStatusRequest struct {
 }
 
 func (h *HttpContainerStatusRequest) HttpMethod() string { return "GET" }
-func (h *HttpContainerStatusRequest) Streamable() bool   { return true }
+func (h *HttpContainerStatusRequest) Streamable() bool   { return !h.Structured }
 func (h *HttpContainerStatusRequest) HttpPath() string {
 	return http.Inline("/container/:id/status", string(h.Id))
 }
@@ -221,7 +273,11 @@ func (h *HttpContainerStatusRequest) Handler(conf *http.HttpConfiguration) http.
 		if errg != nil {
 			return nil, errg
 		}
-		return &cjobs.ContainerStatusRequest{Id: id}, nil
+		return &cjobs.ContainerStatusRequest{
+			Id:           id,
+			Structured:   r.URL.Query().Get("structured") == "true",
+			DockerSocket: conf.Docker.Socket,
+		}, nil
 	}
 }
 
@@ -259,7 +315,18 @@ func (h *HttpStartContainerRequest) Handler(conf *http.HttpConfiguration) http.J
 		if errg != nil {
 			return nil, errg
 		}
-		return &cjobs.StartedContainerStateRequest{id}, nil
+		data := &cjobs.StartedContainerStateRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return data, nil
 	}
 }
 
@@ -279,7 +346,19 @@ func (h *HttpStopContainerRequest) Handler(conf *http.HttpConfiguration) http.Jo
 		if errg != nil {
 			return nil, errg
 		}
-		return &cjobs.StoppedContainerStateRequest{id}, nil
+		var timeout *int
+		if s := r.URL.Query().Get("t"); s != "" {
+			t, err := strconv.Atoi(s)
+			if err != nil || t < 0 {
+				return nil, errors.New("The stop timeout must be zero or more seconds.")
+			}
+			timeout = &t
+		}
+		return &cjobs.StoppedContainerStateRequest{
+			Id:           id,
+			Timeout:      timeout,
+			DockerSocket: conf.Docker.Socket,
+		}, nil
 	}
 }
 
@@ -303,7 +382,222 @@ func (h *HttpRestartContainerRequest) Handler(conf *http.HttpConfiguration) http
 	}
 }
 
-type HttpBuildImageRequest cjobs.BuildImageRequest
+type HttpPauseContainerRequest struct {
+	cjobs.PausedContainerStateRequest
+	http.DefaultRequest
+}
+
+func (h *HttpPauseContainerRequest) HttpMethod() string { return "PUT" }
+func (h *HttpPauseContainerRequest) Streamable() bool   { return true }
+func (h *HttpPauseContainerRequest) HttpPath() string {
+	return http.Inline("/container/:id/paused", string(h.Id))
+}
+func (h *HttpPauseContainerRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.PausedContainerStateRequest{Id: id, DockerSocket: conf.Docker.Socket}, nil
+	}
+}
+
+type HttpUnpauseContainerRequest struct {
+	cjobs.UnpausedContainerStateRequest
+	http.DefaultRequest
+}
+
+func (h *HttpUnpauseContainerRequest) HttpMethod() string { return "PUT" }
+func (h *HttpUnpauseContainerRequest) Streamable() bool   { return true }
+func (h *HttpUnpauseContainerRequest) HttpPath() string {
+	return http.Inline("/container/:id/unpaused", string(h.Id))
+}
+func (h *HttpUnpauseContainerRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.UnpausedContainerStateRequest{Id: id, DockerSocket: conf.Docker.Socket}, nil
+	}
+}
+
+type HttpResetFailedContainerRequest struct {
+	cjobs.ResetFailedContainerRequest
+	http.DefaultRequest
+}
+
+func (h *HttpResetFailedContainerRequest) HttpMethod() string { return "PUT" }
+func (h *HttpResetFailedContainerRequest) Streamable() bool   { return true }
+func (h *HttpResetFailedContainerRequest) HttpPath() string {
+	return http.Inline("/container/:id/reset-failed", string(h.Id))
+}
+func (h *HttpResetFailedContainerRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.ResetFailedContainerRequest{Id: id}, nil
+	}
+}
+
+type HttpInitPreStartRequest struct {
+	cjobs.InitPreStartRequest
+	http.DefaultRequest
+}
+
+func (h *HttpInitPreStartRequest) HttpMethod() string { return "PUT" }
+func (h *HttpInitPreStartRequest) Streamable() bool   { return true }
+func (h *HttpInitPreStartRequest) HttpPath() string {
+	return http.Inline("/container/:id/init/pre", string(h.Id))
+}
+func (h *HttpInitPreStartRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		data := &cjobs.InitPreStartRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		data.Image = r.URL.Query().Get("image")
+		data.DockerSocket = conf.Docker.Socket
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+type HttpInitPostStartRequest struct {
+	cjobs.InitPostStartRequest
+	http.DefaultRequest
+}
+
+func (h *HttpInitPostStartRequest) HttpMethod() string { return "PUT" }
+func (h *HttpInitPostStartRequest) Streamable() bool   { return true }
+func (h *HttpInitPostStartRequest) HttpPath() string {
+	return http.Inline("/container/:id/init/post", string(h.Id))
+}
+func (h *HttpInitPostStartRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		return &cjobs.InitPostStartRequest{Id: id, DockerSocket: conf.Docker.Socket}, nil
+	}
+}
+
+type HttpReassignPortRequest struct {
+	cjobs.ReassignPortRequest
+	http.DefaultRequest
+}
+
+func (h *HttpReassignPortRequest) HttpMethod() string { return "POST" }
+func (h *HttpReassignPortRequest) HttpPath() string {
+	return http.Inline("/container/:id/reassign-port", string(h.Id))
+}
+func (h *HttpReassignPortRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		data := &cjobs.ReassignPortRequest{RequestIdentifier: context.Id, Id: id}
+		query := r.URL.Query()
+		for name, value := range map[string]*port.Port{"internal": &data.Internal, "external": &data.External} {
+			if s := query.Get(name); s != "" {
+				p, err := port.NewPortFromString(s)
+				if err != nil {
+					return nil, jobs.NewInvalidError("The %s port is not valid: %s", name, err.Error())
+				}
+				*value = p
+			}
+		}
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+type HttpRenameContainerRequest struct {
+	cjobs.RenameContainerRequest
+	http.DefaultRequest
+}
+
+func (h *HttpRenameContainerRequest) HttpMethod() string { return "POST" }
+func (h *HttpRenameContainerRequest) HttpPath() string {
+	return http.Inline("/container/:id/rename", string(h.Id))
+}
+func (h *HttpRenameContainerRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		name, errn := containers.NewIdentifier(r.URL.Query().Get("name"))
+		if errn != nil {
+			return nil, jobs.NewInvalidError("The new name is not valid: %s", errn.Error())
+		}
+		data := &cjobs.RenameContainerRequest{
+			RequestIdentifier: context.Id,
+			Id:                id,
+			NewId:             name,
+			DockerSocket:      conf.Docker.Socket,
+		}
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+type HttpExecRequest struct {
+	cjobs.ExecRequest
+	http.DefaultRequest
+}
+
+func (h *HttpExecRequest) HttpMethod() string { return "POST" }
+func (h *HttpExecRequest) Streamable() bool   { return true }
+func (h *HttpExecRequest) HttpPath() string {
+	return http.Inline("/container/:id/exec", string(h.Id))
+}
+func (h *HttpExecRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		query := r.URL.Query()
+		data := &cjobs.ExecRequest{
+			Id:           id,
+			Command:      query["cmd"],
+			Interactive:  query.Get("interactive") == "true",
+			Tty:          query.Get("tty") == "true",
+			DockerSocket: conf.Docker.Socket,
+		}
+		if data.Interactive && r.Body != nil {
+			data.Stdin = r.Body
+		}
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+type HttpBuildImageRequest struct {
+	cjobs.BuildImageRequest
+	http.DefaultRequest
+}
 
 func (h *HttpBuildImageRequest) HttpMethod() string { return "POST" }
 func (h *HttpBuildImageRequest) HttpPath() string   { return "/build-image" }
@@ -311,7 +605,13 @@ func (h *HttpBuildImageRequest) Streamable() bool   { return true }
 func (h *HttpBuildImageRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
 	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
 		data := &cjobs.BuildImageRequest{}
-		if r.Body != nil {
+		if r.Header.Get("Content-Type") == buildContextContentType {
+			// the body is a build context for Docker
+			query := r.URL.Query()
+			data.Id = containers.Identifier(query.Get("id"))
+			data.Context = r.Body
+			data.DockerSocket = conf.Docker.Socket
+		} else if r.Body != nil {
 			dec := json.NewDecoder(r.Body)
 			if err := dec.Decode(data); err != nil && err != io.EOF {
 				return nil, err
@@ -335,6 +635,7 @@ func (h *HttpPutEnvironmentRequest) HttpMethod() string { return "PUT" }
 func (h *HttpPutEnvironmentRequest) HttpPath() string {
 	return http.Inline("/environment/:id", string(h.Id))
 }
+func (h *HttpPutEnvironmentRequest) HttpIfMatch() string { return h.IfMatch }
 func (h *HttpPutEnvironmentRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
 	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
 		id, errg := containers.NewIdentifier(r.PathParam("id"))
@@ -342,19 +643,25 @@ func (h *HttpPutEnvironmentRequest) Handler(conf *http.HttpConfiguration) http.J
 			return nil, errg
 		}
 
-		data := containers.EnvironmentDescription{}
+		body := environmentBody{}
 		if r.Body != nil {
 			dec := json.NewDecoder(limitedBodyReader(r))
-			if err := dec.Decode(&data); err != nil && err != io.EOF {
+			if err := dec.Decode(&body); err != nil && err != io.EOF {
 				return nil, err
 			}
 		}
+		data := body.EnvironmentDescription
 		if err := data.Check(); err != nil {
 			return nil, err
 		}
+		if body.Schema != nil {
+			if err := body.Schema.Check(); err != nil {
+				return nil, err
+			}
+		}
 		data.Id = id
 
-		return &cjobs.PutEnvironmentRequest{data}, nil
+		return &cjobs.PutEnvironmentRequest{EnvironmentDescription: data, EnvironmentReload: environmentReloadFor(conf, r), Schema: body.Schema, IfMatch: http.IfMatchPreconditions(r.Header.Get("If-Match"))}, nil
 	}
 }
 
@@ -367,6 +674,7 @@ func (h *HttpPatchEnvironmentRequest) HttpMethod() string { return "PATCH" }
 func (h *HttpPatchEnvironmentRequest) HttpPath() string {
 	return http.Inline("/environment/:id", string(h.Id))
 }
+func (h *HttpPatchEnvironmentRequest) HttpIfMatch() string { return h.IfMatch }
 func (h *HttpPatchEnvironmentRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
 	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
 		id, errg := containers.NewIdentifier(r.PathParam("id"))
@@ -374,19 +682,73 @@ func (h *HttpPatchEnvironmentRequest) Handler(conf *http.HttpConfiguration) http
 			return nil, errg
 		}
 
-		data := containers.EnvironmentDescription{}
+		body := environmentBody{}
 		if r.Body != nil {
 			dec := json.NewDecoder(limitedBodyReader(r))
-			if err := dec.Decode(&data); err != nil && err != io.EOF {
+			if err := dec.Decode(&body); err != nil && err != io.EOF {
 				return nil, err
 			}
 		}
+		data := body.EnvironmentDescription
 		if err := data.Check(); err != nil {
 			return nil, err
 		}
+		if body.Schema != nil {
+			if err := body.Schema.Check(); err != nil {
+				return nil, err
+			}
+		}
+		data.Id = id
+
+		return &cjobs.PatchEnvironmentRequest{EnvironmentDescription: data, EnvironmentReload: environmentReloadFor(conf, r), Schema: body.Schema, IfMatch: http.IfMatchPreconditions(r.Header.Get("If-Match"))}, nil
+	}
+}
+
+// The body of a request to replace or change an environment, with the
+// schema the environment must match afterwards, if any.
+type environmentBody struct {
+	containers.EnvironmentDescription
+	Schema *containers.EnvironmentSchema `json:"Schema,omitempty"`
+}
+
+func environmentReloadFor(conf *http.HttpConfiguration, r *rest.Request) cjobs.EnvironmentReload {
+	return cjobs.EnvironmentReload{Reload: r.URL.Query().Get("reload") == "true", DockerSocket: conf.Docker.Socket}
+}
+
+type HttpCopyEnvironmentRequest struct {
+	cjobs.CopyEnvironmentRequest
+	http.DefaultRequest
+}
+
+func (h *HttpCopyEnvironmentRequest) HttpMethod() string { return "POST" }
+func (h *HttpCopyEnvironmentRequest) HttpPath() string {
+	return http.Inline("/environment/:id/copy", string(h.Id))
+}
+func (h *HttpCopyEnvironmentRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+
+		data := cjobs.CopyEnvironmentRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(limitedBodyReader(r))
+			if err := dec.Decode(&data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		for _, source := range data.AllSources() {
+			if _, err := containers.NewIdentifier(string(source)); err != nil {
+				return nil, err
+			}
+		}
 		data.Id = id
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
 
-		return &cjobs.PatchEnvironmentRequest{data}, nil
+		return &data, nil
 	}
 }
 
@@ -404,11 +766,13 @@ func (h *HttpContentRequest) HttpPath() string {
 	default:
 		base = "/content/:id"
 	}
+	path := http.Inline(base, h.ContentRequest.Locator)
 	if h.Subpath != "" {
-		return base + "/" + h.Subpath
+		return path + "/" + h.Subpath
 	}
-	return http.Inline(base, h.ContentRequest.Locator)
+	return path
 }
+func (h *HttpContentRequest) HttpIfNoneMatch() string { return h.IfNoneMatch }
 func (h *HttpContentRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
 	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
 		if r.PathParam("id") == "" {
@@ -423,9 +787,11 @@ func (h *HttpContentRequest) Handler(conf *http.HttpConfiguration) http.JobHandl
 		}
 
 		return &cjobs.ContentRequest{
-			contentType,
-			r.PathParam("id"),
-			r.PathParam("*"),
+			Type:        contentType,
+			Locator:     r.PathParam("id"),
+			Subpath:     r.PathParam("*"),
+			Keys:        r.URL.Query()["key"],
+			IfNoneMatch: r.Header.Get("If-None-Match"),
 		}, nil
 	}
 }
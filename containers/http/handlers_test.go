@@ -0,0 +1,271 @@
+// +build linux
+
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/http"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/go-json-rest"
+)
+
+var registerTestExtensions sync.Once
+
+// A server for the container routes, with a container base path that is
+// removed when it is closed.
+func containerServer(t *testing.T) (*httptest.Server, func()) {
+	registerTestExtensions.Do(func() {
+		http.AddHttpExtension(&HttpExtension{})
+		jobs.AddJobExtension(jobs.JobExtensionFunc(func(r interface{}) (jobs.Job, error) {
+			if job, ok := r.(jobs.Job); ok {
+				return job, nil
+			}
+			return nil, jobs.ErrNoJobForRequest
+		}))
+	})
+	dir, err := ioutil.TempDir("", "geard-base")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+
+	d := &dispatcher.Dispatcher{QueueFast: 1, QueueSlow: 1, Concurrent: 1, TrackDuplicateIds: 10}
+	d.Start()
+	conf := &http.HttpConfiguration{Dispatcher: d}
+	conf.Docker.Socket = "unix:///test/docker.sock"
+	handler, err := conf.Handler()
+	if err != nil {
+		t.Fatalf("Unable to create handler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	return server, func() {
+		server.Close()
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func installUnit(t *testing.T, id containers.Identifier) {
+	if err := os.MkdirAll(filepath.Dir(id.UnitPathFor()), 0750); err != nil {
+		t.Fatalf("Unable to create the unit directory: %v", err)
+	}
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\n"), 0640); err != nil {
+		t.Fatalf("Unable to write the unit: %v", err)
+	}
+}
+
+// Send a job to the server through the HTTP transport, returning its output
+func sendJob(t *testing.T, server *httptest.Server, job func(id containers.Identifier) cmd.JobRequest) (string, []error) {
+	locators, err := cmd.NewContainerLocators(http.NewHttpTransport(), strings.TrimPrefix(server.URL, "http://")+"/test-web")
+	if err != nil {
+		t.Fatalf("Unable to create a locator: %v", err)
+	}
+	out := &bytes.Buffer{}
+	errs := cmd.Executor{
+		On: locators,
+		Serial: func(on cmd.Locator) cmd.JobRequest {
+			return job(cmd.AsIdentifier(on))
+		},
+		Output:    out,
+		Transport: http.NewHttpTransport(),
+	}.Stream()
+	return out.String(), errs
+}
+
+func TestInitPreStartOverHttp(t *testing.T) {
+	server, closeServer := containerServer(t)
+	defer closeServer()
+	id := containers.Identifier("test-web")
+	installUnit(t, id)
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\nX-ContainerImage=openshift/busybox-http-app\n"), 0640); err != nil {
+		t.Fatalf("Unable to write the unit: %v", err)
+	}
+
+	var calls []string
+	var initEnv containers.EnvironmentVariables
+	defer func(previous func(string, containers.Identifier, string, containers.EnvironmentVariables) error) {
+		cjobs.InitPreStart = previous
+	}(cjobs.InitPreStart)
+	cjobs.InitPreStart = func(dockerSocket string, id containers.Identifier, imageName string, env containers.EnvironmentVariables) error {
+		calls = append(calls, dockerSocket+" "+string(id)+" "+imageName)
+		initEnv = env
+		return nil
+	}
+
+	out, errs := sendJob(t, server, func(id containers.Identifier) cmd.JobRequest {
+		return &cjobs.InitPreStartRequest{Id: id, Image: "openshift/busybox-http-app", Environment: containers.EnvironmentVariables{{"LOG_LEVEL", "debug"}}}
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(calls) != 1 || calls[0] != "unix:///test/docker.sock test-web openshift/busybox-http-app" {
+		t.Errorf("Expected the server to initialize the container with its image and socket, got %v", calls)
+	}
+	if len(initEnv) != 1 || initEnv[0] != (containers.Environment{"LOG_LEVEL", "debug"}) {
+		t.Errorf("Expected the init environment to be sent to the server, got %v", initEnv)
+	}
+	if !strings.Contains(out, "Initialized test-web before it starts") {
+		t.Errorf("Expected the output of the server, got %q", out)
+	}
+
+	_, errs = sendJob(t, server, func(id containers.Identifier) cmd.JobRequest {
+		return &cjobs.InitPreStartRequest{Id: id, Image: "attacker/image"}
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "was installed with the image openshift/busybox-http-app") {
+		t.Errorf("Expected an image other than the one installed to be rejected, got %v", errs)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Expected the container not to be initialized with another image, got %v", calls)
+	}
+}
+
+func TestInitPostStartOverHttp(t *testing.T) {
+	server, closeServer := containerServer(t)
+	defer closeServer()
+	installUnit(t, containers.Identifier("test-web"))
+
+	var calls []string
+	defer func(previous func(string, containers.Identifier) error) { cjobs.InitPostStart = previous }(cjobs.InitPostStart)
+	cjobs.InitPostStart = func(dockerSocket string, id containers.Identifier) error {
+		calls = append(calls, dockerSocket+" "+string(id))
+		return nil
+	}
+
+	out, errs := sendJob(t, server, func(id containers.Identifier) cmd.JobRequest {
+		return &cjobs.InitPostStartRequest{Id: id}
+	})
+	if len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(calls) != 1 || calls[0] != "unix:///test/docker.sock test-web" {
+		t.Errorf("Expected the server to initialize the started container, got %v", calls)
+	}
+	if !strings.Contains(out, "Initialized test-web after it started") {
+		t.Errorf("Expected the output of the server, got %q", out)
+	}
+}
+
+func TestInitOnlyManagedContainers(t *testing.T) {
+	server, closeServer := containerServer(t)
+	defer closeServer()
+
+	called := false
+	defer func(previous func(string, containers.Identifier) error) { cjobs.InitPostStart = previous }(cjobs.InitPostStart)
+	cjobs.InitPostStart = func(string, containers.Identifier) error {
+		called = true
+		return nil
+	}
+
+	_, errs := sendJob(t, server, func(id containers.Identifier) cmd.JobRequest {
+		return &cjobs.InitPostStartRequest{Id: id}
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "does not exist") {
+		t.Errorf("Expected a container the server didn't install to be rejected, got %v", errs)
+	}
+	if called {
+		t.Errorf("Expected the container not to be initialized")
+	}
+
+	_, errs = sendJob(t, server, func(id containers.Identifier) cmd.JobRequest {
+		return &cjobs.InitPreStartRequest{Id: id}
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "image is required") {
+		t.Errorf("Expected an image to be required before start, got %v", errs)
+	}
+
+	_, errs = sendJob(t, server, func(id containers.Identifier) cmd.JobRequest {
+		return &cjobs.InitPreStartRequest{Id: id, Image: "openshift/busybox-http-app", Environment: containers.EnvironmentVariables{{"LOG-LEVEL", "debug"}}}
+	})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "not a valid shell variable name") {
+		t.Errorf("Expected an init environment key that can't be exported to be rejected, got %v", errs)
+	}
+}
+
+func TestEnvironmentIfMatchOverHttp(t *testing.T) {
+	server, closeServer := containerServer(t)
+	defer closeServer()
+	id := containers.Identifier("test-web")
+	installUnit(t, id)
+	env := containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"LOG_LEVEL", "info"}}}
+	if err := env.Write(false); err != nil {
+		t.Fatalf("Unable to write the environment: %v", err)
+	}
+	patch := func(ifMatch string) []error {
+		_, errs := sendJob(t, server, func(id containers.Identifier) cmd.JobRequest {
+			return &cjobs.PatchEnvironmentRequest{
+				EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"WORKERS", "2"}}},
+				IfMatch:                ifMatch,
+			}
+		})
+		return errs
+	}
+
+	// the API version the client always sends is not an ETag precondition
+	if errs := patch(""); len(errs) != 0 {
+		t.Fatalf("Expected a change without --if-match to succeed, got %v", errs)
+	}
+	if errs := patch(`"0123"`); len(errs) != 1 || !strings.Contains(errs[0].Error(), "has changed") {
+		t.Errorf("Expected a stale ETag to be rejected, got %v", errs)
+	}
+	data, err := ioutil.ReadFile(id.EnvironmentPathFor())
+	if err != nil {
+		t.Fatalf("Unable to read the environment: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if errs := patch(`"` + hex.EncodeToString(sum[:]) + `"`); len(errs) != 0 {
+		t.Errorf("Expected the current ETag to match, got %v", errs)
+	}
+}
+
+func TestIfMatchPreconditions(t *testing.T) {
+	for header, expected := range map[string]string{
+		"":                     "",
+		"api=1":                "",
+		`api=1, "abc"`:         `"abc"`,
+		`"abc",api=1, W/"def"`: `"abc", W/"def"`,
+		` api=1 , * `:          "*",
+	} {
+		if actual := http.IfMatchPreconditions(header); actual != expected {
+			t.Errorf("Expected the preconditions of %q to be %q, got %q", header, expected, actual)
+		}
+	}
+}
+
+func TestStopTimeoutOverHttp(t *testing.T) {
+	conf := &http.HttpConfiguration{}
+	handler := (&HttpStopContainerRequest{}).Handler(conf)
+
+	for _, timeout := range []*int{nil, new(int)} {
+		query := &url.Values{}
+		(&HttpStopContainerRequest{StoppedContainerStateRequest: cjobs.StoppedContainerStateRequest{Timeout: timeout}}).MarshalUrlQuery(query)
+		r := &rest.Request{httptest.NewRequest("PUT", "/container/test-stop/stopped?"+query.Encode(), nil), map[string]string{"id": "test-stop"}}
+		job, err := handler(&jobs.JobContext{}, r)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		parsed := job.(*cjobs.StoppedContainerStateRequest).Timeout
+		switch {
+		case timeout == nil && parsed != nil:
+			t.Errorf("Expected no timeout to be sent without one, got %d", *parsed)
+		case timeout != nil && (parsed == nil || *parsed != 0):
+			t.Errorf("Expected a timeout of zero to be sent, got %v", parsed)
+		}
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"errors"
 	"io"
 	nethttp "net/http"
+	"net/url"
+	"strconv"
 
+	"github.com/openshift/geard/containers"
 	cjobs "github.com/openshift/geard/containers/jobs"
 	"github.com/openshift/geard/http"
 	"github.com/openshift/geard/port"
@@ -16,6 +19,124 @@ func (h *HttpRunContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
 	return encoder.Encode(h.RunContainerRequest)
 }
 
+func (h *HttpExecRequest) MarshalUrlQuery(query *url.Values) {
+	for i := range h.Command {
+		query.Add("cmd", h.Command[i])
+	}
+	if h.Interactive {
+		query.Set("interactive", "true")
+	}
+	if h.Tty {
+		query.Set("tty", "true")
+	}
+}
+func (h *HttpExecRequest) MarshalHttpRequestBody(w io.Writer) error {
+	if h.Interactive && h.Stdin != nil {
+		_, err := io.Copy(w, h.Stdin)
+		return err
+	}
+	return nil
+}
+
+// Build contexts are sent as a tar archive rather than JSON
+const buildContextContentType = "application/x-tar"
+
+func (h *HttpBuildImageRequest) HttpContentType() string {
+	if h.Context != nil {
+		return buildContextContentType
+	}
+	return ""
+}
+func (h *HttpBuildImageRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Context != nil {
+		query.Set("id", string(h.Id))
+	}
+}
+func (h *HttpBuildImageRequest) MarshalHttpRequestBody(w io.Writer) error {
+	if h.Context != nil {
+		_, err := io.Copy(w, h.Context)
+		return err
+	}
+	return json.NewEncoder(w).Encode(h.BuildImageRequest)
+}
+
+func (h *HttpStopContainerRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Timeout != nil {
+		query.Set("t", strconv.Itoa(*h.Timeout))
+	}
+}
+
+func (h *HttpStartContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
+	if len(h.Environment) == 0 {
+		return nil
+	}
+	return json.NewEncoder(w).Encode(h.StartedContainerStateRequest)
+}
+
+func (h *HttpInitPreStartRequest) MarshalUrlQuery(query *url.Values) {
+	query.Set("image", h.Image)
+}
+func (h *HttpInitPreStartRequest) MarshalHttpRequestBody(w io.Writer) error {
+	if len(h.Environment) == 0 {
+		return nil
+	}
+	return json.NewEncoder(w).Encode(h.InitPreStartRequest)
+}
+
+func (h *HttpContainerStatusRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Structured {
+		query.Set("structured", "true")
+	}
+}
+func (h *HttpContainerStatusRequest) UnmarshalHttpResponse(headers nethttp.Header, r io.Reader, mode http.ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(r)
+	status := &cjobs.ContainerStatusResponse{}
+	if err := decoder.Decode(status); err != nil {
+		return nil, err
+	}
+	status.Server = h.Server
+	return status, nil
+}
+
+func (h *HttpReassignPortRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Internal != 0 {
+		query.Set("internal", h.Internal.String())
+	}
+	if h.External != 0 {
+		query.Set("external", h.External.String())
+	}
+}
+func (h *HttpReassignPortRequest) UnmarshalHttpResponse(headers nethttp.Header, r io.Reader, mode http.ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpReassignPortRequest")
+	}
+	reassigned := &cjobs.ReassignPortResponse{}
+	if err := json.NewDecoder(r).Decode(reassigned); err != nil {
+		return nil, err
+	}
+	reassigned.Server = h.Server
+	return reassigned, nil
+}
+
+func (h *HttpRenameContainerRequest) MarshalUrlQuery(query *url.Values) {
+	query.Set("name", string(h.NewId))
+}
+
+func (h *HttpHostStatusRequest) UnmarshalHttpResponse(headers nethttp.Header, r io.Reader, mode http.ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		return nil, errors.New("Unexpected empty response body to HttpHostStatusRequest")
+	}
+	status := &cjobs.HostStatusResponse{}
+	if err := json.NewDecoder(r).Decode(status); err != nil {
+		return nil, err
+	}
+	status.Server = h.Server
+	return status, nil
+}
+
 func (h *HttpInstallContainerRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	return encoder.Encode(h)
@@ -30,19 +151,81 @@ func (h *HttpInstallContainerRequest) UnmarshalHttpResponse(headers nethttp.Head
 			}
 			pending[cjobs.PendingPortMappingName] = ports
 		}
+		for _, name := range []string{cjobs.PendingImageDigestName, cjobs.PendingPreviousImageDigestName} {
+			if s := headers.Get("X-" + name); s != "" {
+				pending[name] = containers.ImageDigest(s)
+			}
+		}
 		return pending, nil
 	}
 	return nil, errors.New("Unexpected response body to HttpInstallContainerRequest")
 }
 
+func (h *HttpContentRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Type != "" && h.Type != cjobs.ContentTypeEnvironment {
+		query.Set("type", h.Type)
+	}
+	for _, key := range h.Keys {
+		query.Add("key", key)
+	}
+}
+func (h *HttpContentRequest) UnmarshalHttpResponse(headers nethttp.Header, r io.Reader, mode http.ResponseContentMode) (interface{}, error) {
+	if r == nil {
+		pending := make(map[string]interface{})
+		if s := headers.Get(cjobs.PendingETagName); s != "" {
+			pending[cjobs.PendingETagName] = cjobs.ETag(s)
+		}
+		if s := headers.Get("X-" + cjobs.PendingEnvironmentSizeName); s != "" {
+			size, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, err
+			}
+			pending[cjobs.PendingEnvironmentSizeName] = cjobs.EnvironmentSize(size)
+		}
+		return pending, nil
+	}
+	return nil, errors.New("Unexpected response body to HttpContentRequest")
+}
+
 func (h *HttpPutEnvironmentRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
-	return encoder.Encode(h.EnvironmentDescription)
+	return encoder.Encode(environmentBody{h.EnvironmentDescription, h.Schema})
 }
 
+func (h *HttpPutEnvironmentRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Reload {
+		query.Set("reload", "true")
+	}
+}
+func (h *HttpPatchEnvironmentRequest) MarshalUrlQuery(query *url.Values) {
+	if h.Reload {
+		query.Set("reload", "true")
+	}
+}
 func (h *HttpPatchEnvironmentRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
-	return encoder.Encode(h.EnvironmentDescription)
+	return encoder.Encode(environmentBody{h.EnvironmentDescription, h.Schema})
+}
+
+func (h *HttpPutEnvironmentRequest) UnmarshalHttpResponse(headers nethttp.Header, r io.Reader, mode http.ResponseContentMode) (interface{}, error) {
+	return environmentETagFrom(headers), nil
+}
+func (h *HttpPatchEnvironmentRequest) UnmarshalHttpResponse(headers nethttp.Header, r io.Reader, mode http.ResponseContentMode) (interface{}, error) {
+	return environmentETagFrom(headers), nil
+}
+
+// The ETag of an environment after it was changed, as a pending value
+func environmentETagFrom(headers nethttp.Header) map[string]interface{} {
+	pending := make(map[string]interface{})
+	if s := headers.Get(cjobs.PendingETagName); s != "" {
+		pending[cjobs.PendingETagName] = cjobs.ETag(s)
+	}
+	return pending
+}
+
+func (h *HttpCopyEnvironmentRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.CopyEnvironmentRequest)
 }
 
 func (h *HttpLinkContainersRequest) MarshalHttpRequestBody(w io.Writer) error {
@@ -50,6 +233,12 @@ func (h *HttpLinkContainersRequest) MarshalHttpRequestBody(w io.Writer) error {
 	return encoder.Encode(h.LinkContainersRequest)
 }
 
+func (h *HttpListContainersRequest) MarshalUrlQuery(query *url.Values) {
+	if !h.Selector.Empty() {
+		query.Set("selector", h.Selector.String())
+	}
+}
+
 // Apply the "label" from the job to the response
 func (h *HttpListContainersRequest) UnmarshalHttpResponse(headers nethttp.Header, r io.Reader, mode http.ResponseContentMode) (interface{}, error) {
 	if r == nil {
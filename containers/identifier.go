@@ -31,6 +31,48 @@ func NewIdentifier(s string) (Identifier, error) {
 	return Identifier(s), nil
 }
 
+// The identifier of a numbered instance of a container, such as "web-1".
+// The index is joined with a dash rather than '@' because systemd reserves
+// '@' for template units and it is not valid in a login name.
+func NewIndexedIdentifier(base string, index int) (Identifier, error) {
+	return NewIdentifier(fmt.Sprintf("%s-%d", base, index))
+}
+
+// Joins the base name of a container to the name of its variant, as in
+// "web--canary".  A single dash is common in base names, so two are used.
+const VariantSeparator = "--"
+
+var allowedVariant = regexp.MustCompile("\\A[a-z0-9]{1,12}\\z")
+
+func CheckVariant(variant string) error {
+	if !allowedVariant.MatchString(variant) {
+		return errors.New("Variant must match " + allowedVariant.String())
+	}
+	return nil
+}
+
+// The identifier of a variant of a container, such as "web--canary", so
+// that a stable and a canary container can be installed side by side.
+func NewVariantIdentifier(base string, variant string) (Identifier, error) {
+	if err := CheckVariant(variant); err != nil {
+		return InvalidIdentifier, err
+	}
+	if strings.Contains(base, VariantSeparator) {
+		return InvalidIdentifier, fmt.Errorf("%s already names a variant", base)
+	}
+	return NewIdentifier(base + VariantSeparator + variant)
+}
+
+// The base name and variant of the container, or the identifier and an
+// empty variant if it does not name a variant.
+func (i Identifier) Variant() (string, string) {
+	s := string(i)
+	if sep := strings.LastIndex(s, VariantSeparator); sep > 0 && CheckVariant(s[sep+len(VariantSeparator):]) == nil {
+		return s[:sep], s[sep+len(VariantSeparator):]
+	}
+	return s, ""
+}
+
 func NewRandomIdentifier(prefix string) (Identifier, error) {
 	i := make([]byte, (32-4-len(prefix))*4/3)
 	if _, err := rand.Read(i); err != nil {
@@ -52,6 +94,22 @@ func (i Identifier) UnitPathFor() string {
 	return filepath.Join(filepath.Dir(base), i.UnitNameFor())
 }
 
+// The containers with a unit file on this host
+func InstalledIdentifiers() ([]Identifier, error) {
+	paths, err := filepath.Glob(filepath.Join(config.ContainerBasePath(), "units", "*", IdentifierPrefix+"*.service"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]Identifier, 0, len(paths))
+	for _, path := range paths {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), IdentifierPrefix), ".service")
+		if id, err := NewIdentifier(name); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 func (i Identifier) IdleUnitPathFor() string {
 	base := utils.IsolateContentPathWithPerm(filepath.Join(config.ContainerBasePath(), "units"), string(i), "", 0775)
 	return filepath.Join(filepath.Dir(base), i.UnitIdleFlagNameFor())
@@ -65,6 +123,24 @@ func (i Identifier) VersionedUnitPathFor(suffix string) string {
 	return utils.IsolateContentPathWithPerm(filepath.Join(config.ContainerBasePath(), "units"), string(i), suffix, 0775)
 }
 
+// The container a versioned unit definition belongs to, which is also the
+// definition the external ports of the container are reserved for.
+func IdentifierForVersionedUnitPath(path string) (Identifier, bool) {
+	rel, err := filepath.Rel(filepath.Join(config.ContainerBasePath(), "units"), path)
+	if err != nil {
+		return InvalidIdentifier, false
+	}
+	segments := strings.Split(rel, string(filepath.Separator))
+	if len(segments) != 3 {
+		return InvalidIdentifier, false
+	}
+	id, err := NewIdentifier(segments[1])
+	if err != nil || segments[0] != segments[1][0:2] {
+		return InvalidIdentifier, false
+	}
+	return id, true
+}
+
 func (i Identifier) UnitNameFor() string {
 	return fmt.Sprintf("%s%s.service", IdentifierPrefix, i)
 }
@@ -82,6 +158,16 @@ func (i Identifier) SocketUnitNameFor() string {
 	return fmt.Sprintf("%s%s.socket", IdentifierPrefix, i)
 }
 
+// The path unit that restarts the container when its watched path changes
+func (i Identifier) PathUnitPathFor() string {
+	base := utils.IsolateContentPathWithPerm(filepath.Join(config.ContainerBasePath(), "units"), string(i), "", 0775)
+	return filepath.Join(filepath.Dir(base), i.PathUnitNameFor())
+}
+
+func (i Identifier) PathUnitNameFor() string {
+	return fmt.Sprintf("%s%s.path", IdentifierPrefix, i)
+}
+
 func (i Identifier) LoginFor() string {
 	return fmt.Sprintf("%s%s", IdentifierPrefix, i)
 }
@@ -94,10 +180,22 @@ func (i Identifier) EnvironmentPathFor() string {
 	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "env", "contents"), string(i), "")
 }
 
+func (i Identifier) PulledImagePathFor() string {
+	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "images", "pulled"), string(i), "")
+}
+
 func (i Identifier) NetworkLinksPathFor() string {
 	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "ports", "links"), string(i), "")
 }
 
+func (i Identifier) LabelsPathFor() string {
+	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "labels"), string(i), "")
+}
+
+func (i Identifier) TimesPathFor() string {
+	return utils.IsolateContentPath(filepath.Join(config.ContainerBasePath(), "times"), string(i), "")
+}
+
 func (i Identifier) BaseHomePath() string {
 	return utils.IsolateContentPathWithPerm(filepath.Join(config.ContainerBasePath(), "home"), string(i), "", 0775)
 }
@@ -110,6 +208,14 @@ func (i Identifier) RunPathFor() string {
 	return utils.IsolateContentPathWithPerm(config.ContainerRunPath(), string(i), "/", 0775)
 }
 
+func (i Identifier) SecretsPathFor() string {
+	return filepath.Join(i.RunPathFor(), "secrets")
+}
+
+func (i Identifier) StartEnvironmentPathFor() string {
+	return filepath.Join(i.RunPathFor(), "start-env")
+}
+
 func (i Identifier) AuthKeysPathFor() string {
 	return filepath.Join(i.HomePath(), ".ssh", "authorized_keys")
 }
@@ -122,6 +228,12 @@ func (i Identifier) ContainerFor() string {
 	return fmt.Sprintf("%s", i)
 }
 
+// The tag of an image built for the container from a build context.
+// Docker repository names must be lower case.
+func (i Identifier) BuildImageTagFor() string {
+	return "geard/" + strings.ToLower(string(i))
+}
+
 type JobIdentifier []byte
 
 // An identifier for an individual request
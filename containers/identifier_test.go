@@ -1,6 +1,7 @@
 package containers
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -18,3 +19,40 @@ func TestNewIdentifier(t *testing.T) {
 		t.Error("Identifier should disallow special characters")
 	}
 }
+
+func TestIdentifierForVersionedUnitPath(t *testing.T) {
+	_, done := withEncryptedEnvironment(t)
+	defer done()
+
+	path := Identifier("web-1").VersionedUnitPathFor("abc")
+	if id, ok := IdentifierForVersionedUnitPath(path); !ok || id != "web-1" {
+		t.Errorf("Expected the container of %s to be found, got %q", path, id)
+	}
+	for _, path := range []string{
+		Identifier("web-1").UnitPathFor(),
+		Identifier("web-1").EnvironmentPathFor(),
+		filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(path))), "xx", "web-1", "abc"),
+	} {
+		if _, ok := IdentifierForVersionedUnitPath(path); ok {
+			t.Errorf("Expected %s not to be a versioned unit definition", path)
+		}
+	}
+}
+
+func TestVariantIdentifier(t *testing.T) {
+	id, err := NewVariantIdentifier("web-1", "canary")
+	if err != nil || id != "web-1--canary" {
+		t.Fatalf("Expected web-1--canary, got %q %v", id, err)
+	}
+	if base, variant := id.Variant(); base != "web-1" || variant != "canary" {
+		t.Errorf("Expected web-1 and canary, got %q and %q", base, variant)
+	}
+	if base, variant := Identifier("web-1").Variant(); base != "web-1" || variant != "" {
+		t.Errorf("Expected no variant, got %q and %q", base, variant)
+	}
+	for _, bad := range [][2]string{{"web", ""}, {"web", "Canary"}, {"web", "can-ary"}, {"web--canary", "b"}, {"a-very-long-base-name", "canary"}} {
+		if _, err := NewVariantIdentifier(bad[0], bad[1]); err == nil {
+			t.Errorf("Expected the variant %q of %q to be rejected", bad[1], bad[0])
+		}
+	}
+}
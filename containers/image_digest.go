@@ -0,0 +1,40 @@
+package containers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The content digest of an image, sha256:<hex>, which names exactly the
+// image a registry served whatever tags have moved since.
+type ImageDigest string
+
+var allowedImageDigest = regexp.MustCompile("\\Asha256:[a-f0-9]{64}\\z")
+
+func (d ImageDigest) Check() error {
+	if !allowedImageDigest.MatchString(string(d)) {
+		return fmt.Errorf("The image digest '%s' must be of the form sha256:<64 hexadecimal characters>", string(d))
+	}
+	return nil
+}
+
+func (d ImageDigest) ToHeader() string {
+	return string(d)
+}
+
+// Split an image reference pinned to a digest, <name>@<digest>, into its
+// name and digest.  The digest is empty if the image names a tag instead.
+func SplitImageDigest(image string) (string, ImageDigest) {
+	if i := strings.Index(image, "@"); i != -1 {
+		return image[:i], ImageDigest(image[i+1:])
+	}
+	return image, ""
+}
+
+// The digest of the image the container was installed from, or an empty
+// string if it wasn't known when it was installed.
+func GetContainerImageDigest(id Identifier) (ImageDigest, error) {
+	value, err := readUnitValue(id, "X-ContainerImageDigest")
+	return ImageDigest(value), err
+}
@@ -0,0 +1,279 @@
+// Reads the containers installed on a host and compares them with those on
+// another, as 'gear compare-hosts' does.
+package inventory
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/transport"
+)
+
+// What is compared of a container installed on a host.
+type Container struct {
+	Image string
+	Ports port.PortPairs
+	// The names of the variables of the container's environment, sorted.
+	// The values are never read.
+	EnvKeys []string
+}
+
+// The containers installed on a host by id.
+type Inventory map[containers.Identifier]*Container
+
+// List the containers on each host and read the image, ports, and
+// environment of every container, reading the hosts at the same time.  The
+// inventories are in the order of the hosts.
+func ReadAll(t transport.Transport, hosts ...gcmd.Locator) ([]Inventory, []error) {
+	inventories := make([]Inventory, len(hosts))
+	failures := make([][]error, len(hosts))
+	wg := sync.WaitGroup{}
+	for i := range hosts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			inventories[i], failures[i] = Read(t, hosts[i])
+		}(i)
+	}
+	wg.Wait()
+
+	errors := []error{}
+	for i := range failures {
+		errors = append(errors, failures[i]...)
+	}
+	return inventories, errors
+}
+
+// List the containers on a host and read the image, ports, and environment
+// of each.  A container that can't be read is left out of the inventory and
+// its error returned.
+func Read(t transport.Transport, host gcmd.Locator) (Inventory, []error) {
+	data, errors := gcmd.Executor{
+		On: gcmd.Locators{host},
+		Group: func(on ...gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ListContainersRequest{}
+		},
+		Transport: t,
+	}.Gather()
+	if len(errors) > 0 {
+		return Inventory{}, errors
+	}
+
+	ids := gcmd.Locators{}
+	inventory := Inventory{}
+	for i := range data {
+		if list, ok := data[i].(*cjobs.ListContainersResponse); ok {
+			for j := range list.Containers {
+				id, err := containers.NewIdentifier(list.Containers[j].Id)
+				if err != nil {
+					errors = append(errors, err)
+					continue
+				}
+				inventory[id] = &Container{}
+				ids = append(ids, &gcmd.ResourceLocator{Type: gcmd.ResourceTypeContainer, Id: string(id), At: host.TransportLocator()})
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return inventory, errors
+	}
+
+	var lock sync.Mutex
+	unreadable := map[containers.Identifier]bool{}
+	_, failures := gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ContainerStatusRequest{Id: gcmd.AsIdentifier(on), Structured: true}
+		},
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			if status, ok := r.Data.(*cjobs.ContainerStatusResponse); ok {
+				lock.Lock()
+				defer lock.Unlock()
+				inventory[job.(*cjobs.ContainerStatusRequest).Id].Image = status.Image
+			}
+		},
+		OnFailure: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			lock.Lock()
+			defer lock.Unlock()
+			unreadable[job.(*cjobs.ContainerStatusRequest).Id] = true
+		},
+		Transport: t,
+	}.Gather()
+	errors = append(errors, failures...)
+
+	_, failures = gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ContainerPortsRequest{Id: gcmd.AsIdentifier(on)}
+		},
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			if ports, ok := r.Data.(*cjobs.ContainerPortsResponse); ok {
+				sorted := make(port.PortPairs, len(ports.Ports))
+				copy(sorted, ports.Ports)
+				sort.Sort(portPairsByInternal(sorted))
+				lock.Lock()
+				defer lock.Unlock()
+				inventory[job.(*cjobs.ContainerPortsRequest).Id].Ports = sorted
+			}
+		},
+		OnFailure: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			lock.Lock()
+			defer lock.Unlock()
+			unreadable[job.(*cjobs.ContainerPortsRequest).Id] = true
+		},
+		Transport: t,
+	}.Gather()
+	errors = append(errors, failures...)
+
+	// A container without an environment has no keys
+	_, failures = gcmd.Executor{
+		On: ids,
+		Serial: func(on gcmd.Locator) gcmd.JobRequest {
+			return &cjobs.ContentRequest{Locator: string(gcmd.AsIdentifier(on)), Type: cjobs.ContentTypeEnvironment}
+		},
+		OnSuccess: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			id := containers.Identifier(job.(*cjobs.ContentRequest).Locator)
+			env := containers.EnvironmentDescription{}
+			if buf, ok := r.Data.(*bytes.Buffer); ok {
+				if err := env.ReadFrom(buf); err != nil {
+					lock.Lock()
+					defer lock.Unlock()
+					unreadable[id] = true
+					errors = append(errors, fmt.Errorf("Unable to read the environment of %s: %s", id, err.Error()))
+					return
+				}
+			}
+			keys := make([]string, 0, len(env.Variables))
+			for i := range env.Variables {
+				keys = append(keys, env.Variables[i].Name)
+			}
+			sort.Strings(keys)
+			lock.Lock()
+			defer lock.Unlock()
+			inventory[id].EnvKeys = keys
+		},
+		OnFailure: func(r *gcmd.CliJobResponse, w io.Writer, job gcmd.JobRequest) {
+			if !jobs.IsNotFound(r.Error) {
+				lock.Lock()
+				defer lock.Unlock()
+				unreadable[containers.Identifier(job.(*cjobs.ContentRequest).Locator)] = true
+			}
+		},
+		Transport: t,
+	}.Gather()
+	for i := range failures {
+		if !jobs.IsNotFound(failures[i]) {
+			errors = append(errors, failures[i])
+		}
+	}
+
+	for id := range unreadable {
+		delete(inventory, id)
+	}
+	return inventory, errors
+}
+
+type portPairsByInternal port.PortPairs
+
+func (p portPairsByInternal) Len() int      { return len(p) }
+func (p portPairsByInternal) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p portPairsByInternal) Less(i, j int) bool {
+	if p[i].Internal != p[j].Internal {
+		return p[i].Internal < p[j].Internal
+	}
+	return p[i].External < p[j].External
+}
+
+// A way a container differs between two hosts.
+type Difference struct {
+	Id containers.Identifier
+	// "missing", "image", "ports", or "env keys"
+	Field string
+	// What each host has, or "-" for nothing
+	A, B string
+}
+type Differences []Difference
+
+// Compare the containers on two hosts.  A container installed on only one
+// host is reported as missing from the other, and a container on both is
+// compared by image, ports, and the names of its environment variables.
+// The differences are sorted by container id.
+func Compare(a, b Inventory) Differences {
+	ids := make([]string, 0, len(a)+len(b))
+	for id := range a {
+		ids = append(ids, string(id))
+	}
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			ids = append(ids, string(id))
+		}
+	}
+	sort.Strings(ids)
+
+	diffs := Differences{}
+	for _, s := range ids {
+		id := containers.Identifier(s)
+		ca, onA := a[id]
+		cb, onB := b[id]
+		switch {
+		case !onB:
+			diffs = append(diffs, Difference{id, "missing", "installed", "-"})
+			continue
+		case !onA:
+			diffs = append(diffs, Difference{id, "missing", "-", "installed"})
+			continue
+		}
+		if ca.Image != cb.Image {
+			diffs = append(diffs, Difference{id, "image", orNone(ca.Image), orNone(cb.Image)})
+		}
+		if pa, pb := ca.Ports.ToHeader(), cb.Ports.ToHeader(); pa != pb {
+			diffs = append(diffs, Difference{id, "ports", orNone(pa), orNone(pb)})
+		}
+		if onlyA, onlyB := onlyIn(ca.EnvKeys, cb.EnvKeys), onlyIn(cb.EnvKeys, ca.EnvKeys); len(onlyA) > 0 || len(onlyB) > 0 {
+			diffs = append(diffs, Difference{id, "env keys", orNone(strings.Join(onlyA, ",")), orNone(strings.Join(onlyB, ","))})
+		}
+	}
+	return diffs
+}
+
+// Write a table of the differences, with a column for each host.
+func (d Differences) WriteTableTo(w io.Writer, hostA, hostB string) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', tabwriter.DiscardEmptyColumns)
+	fmt.Fprintf(tw, "CONTAINER\tDIFFERENCE\t%s\t%s\n", hostA, hostB)
+	for i := range d {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d[i].Id, d[i].Field, d[i].A, d[i].B)
+	}
+	return tw.Flush()
+}
+
+// The sorted keys of a that are not in b
+func onlyIn(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for i := range b {
+		in[b[i]] = true
+	}
+	only := []string{}
+	for i := range a {
+		if !in[a[i]] {
+			only = append(only, a[i])
+		}
+	}
+	return only
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
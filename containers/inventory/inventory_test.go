@@ -0,0 +1,125 @@
+package inventory
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/transport"
+)
+
+type testLocator struct {
+	Locator string
+}
+
+func (t *testLocator) String() string {
+	return t.Locator
+}
+func (t *testLocator) ResolveHostname() (string, error) {
+	return t.Locator, nil
+}
+
+type testContainer struct {
+	Image string
+	Ports port.PortPairs
+	// Absent if the container has no environment
+	Env string
+}
+
+// Answers the list, status, ports, and environment requests of each host
+// from its containers.
+type hostsTransport map[string]map[string]testContainer
+
+func (t hostsTransport) LocatorFor(value string) (transport.Locator, error) {
+	return &testLocator{value}, nil
+}
+
+func (t hostsTransport) RemoteJobFor(locator transport.Locator, job interface{}) (jobs.Job, error) {
+	host := t[locator.String()]
+	return jobs.JobFunction(func(res jobs.Response) {
+		switch r := job.(type) {
+		case *cjobs.ListContainersRequest:
+			list := &cjobs.ListContainersResponse{}
+			for id := range host {
+				list.Containers = append(list.Containers, cjobs.ContainerUnitResponse{UnitResponse: cjobs.UnitResponse{Id: id}})
+			}
+			res.SuccessWithData(jobs.ResponseOk, list)
+		case *cjobs.ContainerStatusRequest:
+			res.SuccessWithData(jobs.ResponseOk, &cjobs.ContainerStatusResponse{Image: host[string(r.Id)].Image})
+		case *cjobs.ContainerPortsRequest:
+			res.SuccessWithData(jobs.ResponseOk, &cjobs.ContainerPortsResponse{Ports: host[string(r.Id)].Ports})
+		case *cjobs.ContentRequest:
+			env := host[r.Locator].Env
+			if env == "" {
+				res.Failure(jobs.SimpleError{jobs.ResponseNotFound, "No environment"})
+				return
+			}
+			fmt.Fprint(res.SuccessWithWrite(jobs.ResponseOk, false, false), env)
+		default:
+			res.Failure(fmt.Errorf("unexpected job %T", job))
+		}
+	}), nil
+}
+
+func TestCompareHosts(t *testing.T) {
+	trans := hostsTransport{
+		"primary": {
+			"web-1":   {Image: "openshift/web:2", Ports: port.PortPairs{{8080, 4000}, {22, 4001}}, Env: "A=1\nSECRET=x\n"},
+			"db-1":    {Image: "openshift/mysql", Ports: port.PortPairs{{3306, 4002}}},
+			"cache-1": {Image: "openshift/redis", Env: "A=1\n"},
+		},
+		"standby": {
+			"web-1":  {Image: "openshift/web:1", Ports: port.PortPairs{{22, 4001}, {8080, 4000}}, Env: "A=2\nDEBUG=1\n"},
+			"db-1":   {Image: "openshift/mysql", Ports: port.PortPairs{{3306, 4003}}},
+			"cron-1": {Image: "openshift/cron"},
+		},
+	}
+	hosts, err := gcmd.NewHostLocators(trans, "primary", "standby")
+	if err != nil {
+		t.Fatalf("Unable to locate the hosts: %v", err)
+	}
+	inventories, errs := ReadAll(trans, hosts...)
+	if len(errs) != 0 {
+		t.Fatalf("Unable to read the inventories: %v", errs)
+	}
+	if len(inventories[0]) != 3 || len(inventories[1]) != 3 {
+		t.Fatalf("Expected three containers on each host, got %v", inventories)
+	}
+	if keys := inventories[0][containers.Identifier("web-1")].EnvKeys; strings.Join(keys, ",") != "A,SECRET" {
+		t.Errorf("Expected the environment keys of web-1, got %v", keys)
+	}
+
+	diffs := Compare(inventories[0], inventories[1])
+	expected := Differences{
+		{"cache-1", "missing", "installed", "-"},
+		{"cron-1", "missing", "-", "installed"},
+		{"db-1", "ports", "3306:4002", "3306:4003"},
+		{"web-1", "image", "openshift/web:2", "openshift/web:1"},
+		{"web-1", "env keys", "SECRET", "DEBUG"},
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %+v", len(expected), diffs)
+	}
+	for i := range expected {
+		if diffs[i] != expected[i] {
+			t.Errorf("Expected difference %+v, got %+v", expected[i], diffs[i])
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	diffs.WriteTableTo(buf, "primary", "standby")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(strings.Fields(lines[0]), " ") != "CONTAINER DIFFERENCE primary standby" || len(lines) != 6 {
+		t.Errorf("Expected a row per difference, got %q", buf.String())
+	}
+
+	if diffs := Compare(inventories[0], inventories[0]); len(diffs) != 0 {
+		t.Errorf("Expected a host to match itself, got %+v", diffs)
+	}
+}
@@ -3,6 +3,7 @@
 package jobs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -93,12 +94,46 @@ func inStateOrTooSoon(id containers.Identifier, unit string, active, transition
 	return
 }
 
+// Write the overrides of the environment for this start, or remove those
+// of an earlier start.  They are removed when the container stops, but
+// may remain if it never started.
+func writeStartEnvironment(id containers.Identifier, env containers.StartEnvironment) error {
+	if len(env) > 0 {
+		uses, err := containers.UsesStartEnvironment(id)
+		if os.IsNotExist(err) {
+			return ErrContainerNotFound
+		} else if err != nil {
+			log.Printf("alter_container_state: Unable to read the unit of %s: %v", id, err)
+			return ErrContainerStartFailed
+		}
+		if !uses {
+			return ErrStartEnvironmentNotSupported
+		}
+	}
+	if err := env.Write(id.StartEnvironmentPathFor()); err != nil {
+		log.Printf("alter_container_state: Unable to write the start environment of %s: %v", id, err)
+		return ErrContainerStartFailed
+	}
+	return nil
+}
+
 func (j *StartedContainerStateRequest) Execute(resp jobs.Response) {
+	j.ExecuteContext(context.Background(), resp)
+}
+
+// Starts the container, failing with jobs.ErrJobCanceled if ctx is done
+// before the start has been queued with systemd.
+func (j *StartedContainerStateRequest) ExecuteContext(ctx context.Context, resp jobs.Response) {
 	unitName := j.Id.UnitNameFor()
 	unitPath := j.Id.UnitPathFor()
 
 	inState, tooSoon := inStateOrTooSoon(j.Id, unitName, true, false, rateLimitChanges)
 	if inState {
+		// the overrides can't be applied to a container that has started
+		if len(j.Environment) > 0 {
+			resp.Failure(ErrStartEnvironmentRunning)
+			return
+		}
 		w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
 		fmt.Fprintf(w, "Container %s starting\n", j.Id)
 		return
@@ -108,6 +143,11 @@ func (j *StartedContainerStateRequest) Execute(resp jobs.Response) {
 		return
 	}
 
+	if err := writeStartEnvironment(j.Id, j.Environment); err != nil {
+		resp.Failure(err)
+		return
+	}
+
 	if errs := csystemd.SetUnitStartOnBoot(j.Id, true); errs != nil {
 		log.Print("alter_container_state: Unable to persist whether the unit is started on boot: ", errs)
 		resp.Failure(ErrContainerStartFailed)
@@ -124,11 +164,22 @@ func (j *StartedContainerStateRequest) Execute(resp jobs.Response) {
 		return
 	}
 
+	// reloading systemd may be slow, don't start a container the client
+	// has given up on
+	if ctx.Err() != nil {
+		log.Printf("alter_container_state: Not starting %s, the request was cancelled", unitName)
+		resp.Failure(jobs.ErrJobCanceled)
+		return
+	}
+
 	if err := systemd.Connection().StartUnitJob(unitName, "replace"); err != nil {
 		log.Printf("alter_container_state: Could not start container %s: %v", unitName, err)
 		resp.Failure(ErrContainerStartFailed)
 		return
 	}
+	if err := containers.RecordContainerStarted(j.Id, time.Now()); err != nil {
+		log.Printf("alter_container_state: Unable to record the start time of %s: %v", j.Id, err)
+	}
 
 	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
 	fmt.Fprintf(w, "Container %s starting\n", j.Id)
@@ -154,6 +205,12 @@ func (j *StoppedContainerStateRequest) Execute(resp jobs.Response) {
 		return
 	}
 
+	// the timeout of the request is only given to the runtime, which has
+	// stopped the container before systemd stops the unit.  Setting it on
+	// the unit would outlast the request.
+	timeout := j.StopTimeout()
+	unitTimeout := time.Duration(timeout+csystemd.StopTimeoutGrace) * time.Second
+
 	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
 
 	done := make(chan time.Time)
@@ -164,6 +221,9 @@ func (j *StoppedContainerStateRequest) Execute(resp jobs.Response) {
 
 	joberr := make(chan error)
 	go func() {
+		if err := stopContainerWithTimeout(j.Id, j.DockerSocket, timeout); err != nil {
+			log.Printf("alter_container_state: Unable to stop container %s through docker: %v", j.Id, err)
+		}
 		status, err := systemd.Connection().StopUnit(unitName, "replace")
 		if err == nil && status != "done" {
 			err = errors.New(fmt.Sprintf("Job status 'done' != %s", status))
@@ -178,7 +238,7 @@ func (j *StoppedContainerStateRequest) Execute(resp jobs.Response) {
 		close(ioerr)
 	case err = <-joberr:
 		log.Printf("alter_container_state: Stop job done")
-	case <-time.After(15 * time.Second):
+	case <-time.After(unitTimeout + 5*time.Second):
 		log.Printf("alter_container_state: Timeout waiting for stop completion")
 	}
 	close(done)
@@ -201,6 +261,20 @@ func (j *StoppedContainerStateRequest) Execute(resp jobs.Response) {
 	}
 }
 
+// Ask the runtime to stop the container, giving it timeout seconds to exit
+// before it is killed.  The unit's own stop command only knows the timeout
+// it was installed with.
+func stopContainerWithTimeout(id containers.Identifier, dockerSocket string, timeout int) error {
+	runtime, err := containers.NewRuntime(dockerSocket)
+	if err != nil {
+		return err
+	}
+	if err := runtime.StopContainer(id, uint(timeout)); err != nil && err != containers.ErrNoSuchContainer {
+		return err
+	}
+	return nil
+}
+
 func (j *RestartContainerRequest) Execute(resp jobs.Response) {
 	unitName := j.Id.UnitNameFor()
 	unitPath := j.Id.UnitPathFor()
@@ -237,6 +311,9 @@ func (j *RestartContainerRequest) Execute(resp jobs.Response) {
 		resp.Failure(ErrContainerRestartFailed)
 		return
 	}
+	if err := containers.RecordContainerStarted(j.Id, time.Now()); err != nil {
+		log.Printf("alter_container_state: Unable to record the start time of %s: %v", j.Id, err)
+	}
 
 	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
 	fmt.Fprintf(w, "Container %s restarting\n", j.Id)
@@ -0,0 +1,166 @@
+// +build linux
+
+package jobs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+)
+
+func TestStopContainerWithTimeout(t *testing.T) {
+	var stopped string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			fmt.Fprintln(w, `{"ExecutionDriver":"native-0.2"}`)
+		case "/containers/test-stop/stop":
+			stopped = r.URL.Query().Get("t")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected URL: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, timeout := range []int{30, 0} {
+		req := &StoppedContainerStateRequest{Id: "test-stop", Timeout: &timeout}
+		if err := stopContainerWithTimeout(req.Id, server.URL, req.StopTimeout()); err != nil {
+			t.Fatalf("Unexpected error stopping container: %v", err)
+		}
+		if stopped != fmt.Sprint(timeout) {
+			t.Errorf("Expected docker to be given a %d second timeout, got %q", timeout, stopped)
+		}
+	}
+}
+
+func TestStopTimeoutDefault(t *testing.T) {
+	defer withContainerBasePath(t)()
+
+	req := &StoppedContainerStateRequest{Id: "test-stop"}
+	if req.StopTimeout() != DefaultStopTimeout {
+		t.Errorf("Expected the default stop timeout without a unit, got %d", req.StopTimeout())
+	}
+
+	if err := ioutil.WriteFile(req.Id.UnitPathFor(), []byte("[Service]\nX-ContainerId=test-stop\nX-ContainerStopTimeout=0\n"), 0664); err != nil {
+		t.Fatalf("Unable to write the unit: %v", err)
+	}
+	if req.StopTimeout() != 0 {
+		t.Errorf("Expected the stop timeout the container was installed with, got %d", req.StopTimeout())
+	}
+	timeout := 20
+	req.Timeout = &timeout
+	if req.StopTimeout() != 20 {
+		t.Errorf("Expected the timeout of the request to be used, got %d", req.StopTimeout())
+	}
+}
+
+func TestStartEnvironmentAppliesOnce(t *testing.T) {
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	runDir, err := ioutil.TempDir("", "geard-run")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(runDir)
+	previousRun, previousFeatures := config.ContainerRunPath(), config.SystemDockerFeatures
+	config.SetContainerRunPath(runDir)
+	config.SystemDockerFeatures.EnvironmentFile = true
+	defer func() {
+		config.SetContainerRunPath(previousRun)
+		config.SystemDockerFeatures = previousFeatures
+	}()
+
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	id := containers.Identifier("test-start-env")
+	install := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                id,
+		Image:             "testimage",
+		Environment:       &containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"LOG_LEVEL", "info"}}},
+		DockerSocket:      server.URL,
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	install.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+	unit, _ := ioutil.ReadFile(id.UnitPathFor())
+	path := id.StartEnvironmentPathFor()
+	if !strings.Contains(string(unit), `--env-file "`+path+`"`) || !strings.Contains(string(unit), `ExecStopPost=-/bin/rm -f "`+path+`"`) {
+		t.Fatalf("Expected the unit to read the start environment and remove it on stop, got:\n%s", string(unit))
+	}
+
+	start := &StartedContainerStateRequest{Id: id, Environment: containers.StartEnvironment{{"LOG_LEVEL", "debug"}, {"TRACE", "1"}}}
+	if err := start.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the request: %v", err)
+	}
+	if err := writeStartEnvironment(id, start.Environment); err != nil {
+		t.Fatalf("Unexpected error starting: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected a start environment only the owner can read, got %v %v", info, err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "LOG_LEVEL=debug\nTRACE=1\n" {
+		t.Errorf("Unexpected start environment %q", string(data))
+	}
+	if stored := readEnvironment(t, id); stored["LOG_LEVEL"] != "info" || len(stored) != 1 {
+		t.Errorf("Expected the stored environment to be unchanged, got %v", stored)
+	}
+
+	if err := writeStartEnvironment(id, nil); err != nil {
+		t.Fatalf("Unexpected error starting: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the overrides to be removed by the next start, got %v", err)
+	}
+}
+
+func TestStartEnvironmentNotSupported(t *testing.T) {
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	previousFeatures := config.SystemDockerFeatures
+	config.SystemDockerFeatures.EnvironmentFile = false
+	defer func() { config.SystemDockerFeatures = previousFeatures }()
+
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	id := containers.Identifier("test-start-env")
+	if err := writeStartEnvironment(id, containers.StartEnvironment{{"TRACE", "1"}}); err != ErrContainerNotFound {
+		t.Errorf("Expected a missing container to be reported, got %v", err)
+	}
+	install := &InstallContainerRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: id, Image: "testimage", DockerSocket: server.URL}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	install.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+	if err := writeStartEnvironment(id, containers.StartEnvironment{{"TRACE", "1"}}); err != ErrStartEnvironmentNotSupported {
+		t.Errorf("Expected a unit without a start environment to be rejected, got %v", err)
+	}
+}
+
+func TestStartEnvironmentInvalid(t *testing.T) {
+	for _, env := range []containers.StartEnvironment{{{"", "value"}}, {{"KEY", "two\nlines"}}} {
+		req := &StartedContainerStateRequest{Id: "test-start-env", Environment: env}
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected %v to be rejected, got %v", env, err)
+		}
+	}
+}
@@ -3,14 +3,17 @@
 package jobs
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"reflect"
 	"time"
 
 	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/docker"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/systemd"
 	"github.com/openshift/geard/utils"
@@ -22,7 +25,15 @@ const (
 	gearBinaryPath = "/usr/bin/gear"
 )
 
+// The largest build context the server will copy to disk before building
+var MaxBuildContextSize int64 = 1024 * 1024 * 1024
+
 func (j *BuildImageRequest) Execute(resp jobs.Response) {
+	if j.Context != nil {
+		j.buildContext(resp)
+		return
+	}
+
 	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
 
 	fmt.Fprintf(w, "Processing build-image request:\n")
@@ -146,3 +157,64 @@ func (j *BuildImageRequest) Execute(resp jobs.Response) {
 		}
 	}
 }
+
+// Build the image from the build context with Docker, streaming the build
+// output.  Whether the build succeeded is sent in a trailer, as the
+// response has already been accepted.
+func (j *BuildImageRequest) buildContext(resp jobs.Response) {
+	if containers.RuntimeName != containers.RuntimeDocker {
+		resp.Failure(ErrBuildContextNotSupported)
+		return
+	}
+	client, err := docker.GetConnection(j.DockerSocket)
+	if err != nil {
+		log.Printf("build_image: Unable to connect to docker: %v", err)
+		resp.Failure(ErrBuildImageFailed)
+		return
+	}
+
+	// the context must be read before the output is streamed, as an HTTP
+	// request body can't be read once the response has started
+	context, err := ioutil.TempFile("", "geard-build-context")
+	if err != nil {
+		log.Printf("build_image: Unable to create a file for the build context: %v", err)
+		resp.Failure(ErrBuildImageFailed)
+		return
+	}
+	defer os.Remove(context.Name())
+	defer context.Close()
+	n, err := io.Copy(context, io.LimitReader(j.Context, MaxBuildContextSize+1))
+	if err != nil {
+		log.Printf("build_image: Unable to read the build context: %v", err)
+		resp.Failure(ErrBuildImageFailed)
+		return
+	}
+	if n > MaxBuildContextSize {
+		resp.Failure(jobs.SimpleError{jobs.ResponseTooLarge, fmt.Sprintf("The build context is larger than the %d bytes this server accepts.", MaxBuildContextSize)})
+		return
+	}
+	if _, err := context.Seek(0, 0); err != nil {
+		log.Printf("build_image: Unable to read the build context: %v", err)
+		resp.Failure(ErrBuildImageFailed)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	output := &bytes.Buffer{}
+	err = client.BuildImage(j.Tag, context, io.MultiWriter(w, output))
+
+	trailers, _ := resp.(jobs.TrailerResponse)
+	if err != nil {
+		log.Printf("build_image: Unable to build %s for %s: %v\n%s", j.Tag, j.Id, err, output.String())
+		if _, ok := err.(docker.BuildError); !ok {
+			fmt.Fprintf(w, "Unable to build the image: %s\n", err.Error())
+		}
+		if trailers != nil {
+			trailers.WriteTrailer(TrailerBuildError, err.Error())
+		}
+		return
+	}
+	if trailers != nil {
+		trailers.WriteTrailer(TrailerBuildImage, j.Tag)
+	}
+}
@@ -0,0 +1,153 @@
+// +build linux
+
+package jobs
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/jobs"
+)
+
+// A tar archive of the files in a fixture directory
+func buildContextFixture(t *testing.T, dir string) io.Reader {
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unable to read the build context fixture: %v", err)
+	}
+	for _, info := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			t.Fatalf("Unable to read the build context fixture: %v", err)
+		}
+		w.WriteHeader(&tar.Header{Name: info.Name(), Mode: 0644, Size: int64(len(data))})
+		w.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Unable to archive the build context fixture: %v", err)
+	}
+	return buf
+}
+
+type fakeBuildBackend struct {
+	fail  bool
+	tag   string
+	files []string
+}
+
+func (f *fakeBuildBackend) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			fmt.Fprintln(w, `{"ExecutionDriver":"native-0.2"}`)
+		case "/build":
+			f.tag = r.URL.Query().Get("t")
+			archive := tar.NewReader(r.Body)
+			for {
+				header, err := archive.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Errorf("Unable to read the build context: %v", err)
+					return
+				}
+				f.files = append(f.files, header.Name)
+			}
+			fmt.Fprintln(w, `{"stream":"Step 0 : FROM busybox\n"}`)
+			if f.fail {
+				fmt.Fprintln(w, `{"error":"ADD failed: no such file","errorDetail":{"message":"ADD failed: no such file"}}`)
+				return
+			}
+			fmt.Fprintln(w, `{"stream":"Successfully built 0123456789ab\n"}`)
+		default:
+			t.Errorf("Unexpected URL: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestBuildImageFromContext(t *testing.T) {
+	backend := &fakeBuildBackend{}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &BuildImageRequest{Id: "test-build", Context: buildContextFixture(t, "fixtures/build"), DockerSocket: server.URL}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the build: %v", err)
+	}
+	out := &bytes.Buffer{}
+	resp := &cmd.CliJobResponse{Output: out}
+	req.Execute(resp)
+
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error building: %v", resp.Error)
+	}
+	if backend.tag != "geard/test-build" || resp.Trailers[TrailerBuildImage] != "geard/test-build" {
+		t.Errorf("Expected the image to be tagged for the container, got %q and %v", backend.tag, resp.Trailers)
+	}
+	if strings.Join(backend.files, ",") != "Dockerfile,index.html" {
+		t.Errorf("Expected the build context to be sent to docker, got %v", backend.files)
+	}
+	if !strings.Contains(out.String(), "Step 0 : FROM busybox\nSuccessfully built") {
+		t.Errorf("Expected the build output, got:\n%s", out.String())
+	}
+}
+
+func TestBuildImageFromContextFailure(t *testing.T) {
+	backend := &fakeBuildBackend{fail: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &BuildImageRequest{Id: "test-build", Context: buildContextFixture(t, "fixtures/build"), DockerSocket: server.URL}
+	req.Check()
+	out := &bytes.Buffer{}
+	resp := &cmd.CliJobResponse{Output: out}
+	req.Execute(resp)
+
+	if _, ok := resp.Trailers[TrailerBuildImage]; ok {
+		t.Errorf("Expected no image to be reported, got %v", resp.Trailers)
+	}
+	if !strings.Contains(resp.Trailers[TrailerBuildError], "ADD failed: no such file") {
+		t.Errorf("Expected the build error to be reported, got %v", resp.Trailers)
+	}
+	if !strings.Contains(out.String(), "Step 0 : FROM busybox\nADD failed: no such file\n") {
+		t.Errorf("Expected the full build log, got:\n%s", out.String())
+	}
+}
+
+func TestBuildImageFromContextTooLarge(t *testing.T) {
+	backend := &fakeBuildBackend{}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+	defer func(size int64) { MaxBuildContextSize = size }(MaxBuildContextSize)
+	MaxBuildContextSize = 512
+
+	req := &BuildImageRequest{Id: "test-build", Tag: "other/image", Context: buildContextFixture(t, "fixtures/build"), DockerSocket: server.URL}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the build: %v", err)
+	}
+	if req.Tag != "geard/test-build" {
+		t.Errorf("Expected the tag of a client to be replaced by the tag for the container, got %q", req.Tag)
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+
+	if jobs.FailureFor(resp.Error) != jobs.ResponseTooLarge {
+		t.Fatalf("Expected the build context to be rejected as too large, got %v", resp.Error)
+	}
+	if backend.files != nil {
+		t.Errorf("Expected nothing to be sent to docker, got %v", backend.files)
+	}
+}
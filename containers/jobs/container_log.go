@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/systemd"
 )
@@ -16,6 +17,10 @@ func (j *ContainerLogRequest) Execute(resp jobs.Response) {
 		resp.Failure(ErrContainerNotFound)
 		return
 	}
+	if err := checkLogsReadable(j.Id); err != nil {
+		resp.Failure(err)
+		return
+	}
 
 	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
 	err := systemd.WriteLogsTo(w, j.Id.UnitNameFor(), 30, time.After(30*time.Second))
@@ -23,3 +28,17 @@ func (j *ContainerLogRequest) Execute(resp jobs.Response) {
 		log.Printf("job_container_log: Unable to fetch journal logs: %s\n", err.Error())
 	}
 }
+
+// The output of a container whose log driver Docker can't read back is
+// never written to the journal.
+func checkLogsReadable(id containers.Identifier) error {
+	driver, err := containers.GetLogDriver(id)
+	if err != nil {
+		log.Printf("job_container_log: Unable to read the log driver: %v", err)
+		return nil
+	}
+	if !driver.Readable() {
+		return jobs.NewConflictError("The output of the container is sent to the %s log driver, and can't be read by geard.", driver)
+	}
+	return nil
+}
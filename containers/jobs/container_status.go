@@ -3,9 +3,14 @@
 package jobs
 
 import (
+	"bufio"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/systemd"
 )
@@ -17,9 +22,164 @@ func (j *ContainerStatusRequest) Execute(resp jobs.Response) {
 		return
 	}
 
+	if j.Structured {
+		r := ContainerStatusResponse{UnitResponse: UnitResponse{Id: string(j.Id)}}
+		props, err := systemd.Connection().GetUnitProperties(j.Id.UnitNameFor())
+		if err == nil {
+			r.ActiveState, _ = props["ActiveState"].(string)
+			r.SubState, _ = props["SubState"].(string)
+			r.Restarts = systemd.UnitRestarts(systemd.Connection(), j.Id.UnitNameFor())
+		} else {
+			log.Printf("container_status: Unable to read unit properties: %v", err)
+		}
+		r.Installed, r.Started, r.UptimeSeconds = containerTimes(j.Id, props, time.Now())
+		r.Limits, r.Usage = containerResources(j.Id, j.DockerSocket)
+		r.Paused = containerPaused(j.Id, j.DockerSocket)
+		if labels, err := containers.GetExistingLabels(j.Id); err == nil {
+			if len(labels) > 0 {
+				r.Labels = labels
+			}
+		} else {
+			log.Printf("container_status: Unable to read labels: %v", err)
+		}
+		if driver, err := containers.GetLogDriver(j.Id); err == nil {
+			r.LogDriver = driver
+		} else {
+			log.Printf("container_status: Unable to read the log driver: %v", err)
+		}
+		if image, err := containers.GetContainerImage(j.Id); err == nil {
+			r.Image = image
+			r.ImageDigest, _ = containers.GetContainerImageDigest(j.Id)
+			r.Revision, _ = containers.GetContainerRevision(j.Id)
+		} else {
+			log.Printf("container_status: Unable to read the image: %v", err)
+		}
+		if env, err := readEnvironmentMap(j.Id); err == nil {
+			r.EnvironmentSize = containers.EnvironmentSize(env)
+		} else if !os.IsNotExist(err) {
+			log.Printf("container_status: Unable to read environment: %v", err)
+		}
+		resp.SuccessWithData(jobs.ResponseOk, &r)
+		return
+	}
+
 	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
 	err := systemd.WriteStatusTo(w, j.Id.UnitNameFor())
 	if err != nil {
 		log.Printf("container_status: Unable to fetch container status logs: %s\n", err.Error())
 	}
+	if containerPaused(j.Id, j.DockerSocket) {
+		fmt.Fprintf(w, "\nThe container is paused, its processes are frozen until it is unpaused.\n")
+	}
+	if restarts := systemd.UnitRestarts(systemd.Connection(), j.Id.UnitNameFor()); restarts != nil && *restarts > 0 {
+		fmt.Fprintf(w, "\nSystemd has restarted the container %d times, 'gear reset-failed' clears the count.\n", *restarts)
+	}
+	if limits := containerLimits(j.Id); limits.CPUSetCPUs != "" || limits.CPUSetMems != "" {
+		fmt.Fprintf(w, "\nThe container is pinned to %s.\n", limits.cpuSetDescription())
+	}
+	// the journal lines in the status won't include the container output
+	if driver, err := containers.GetLogDriver(j.Id); err == nil && !driver.Readable() {
+		fmt.Fprintf(w, "\nThe output of the container is sent to the %s log driver.\n", driver)
+	}
+	if digest, err := containers.GetContainerImageDigest(j.Id); err == nil && digest != "" {
+		image, _ := containers.GetContainerImage(j.Id)
+		fmt.Fprintf(w, "\nThe image %s resolved to %s when the container was installed.\n", image, digest)
+	}
+	if revision, err := containers.GetContainerRevision(j.Id); err == nil && revision != "" {
+		fmt.Fprintf(w, "\nThe container was installed at revision %s.\n", revision)
+	}
+}
+
+// When the container was installed and last started, and how long it has
+// been running at now if it is active.  Systemd knows when the unit last
+// became active, which is later than the recorded start time if the
+// container was started on boot or restarted after it failed.
+func containerTimes(id containers.Identifier, props map[string]interface{}, now time.Time) (installed, started *time.Time, uptime *int64) {
+	times, err := containers.GetContainerTimes(id)
+	if err != nil {
+		log.Printf("container_status: Unable to read install and start times: %v", err)
+	}
+	if usec, ok := props["ActiveEnterTimestamp"].(uint64); ok && usec > 0 {
+		if entered := time.Unix(0, int64(usec)*int64(time.Microsecond)); entered.After(times.Started) {
+			times.Started = entered
+		}
+	}
+
+	if !times.Installed.IsZero() {
+		installed = &times.Installed
+	}
+	if !times.Started.IsZero() {
+		started = &times.Started
+		if state, _ := props["ActiveState"].(string); state == "active" {
+			seconds := int64(containers.Uptime(times.Started, now) / time.Second)
+			uptime = &seconds
+		}
+	}
+	return
+}
+
+// Read the limits configured on the container unit and the resources
+// the runtime reports the container is using.  Usage is nil if the container
+// is not running.
+func containerResources(id containers.Identifier, dockerSocket string) (ContainerLimits, *ContainerUsage) {
+	limits := containerLimits(id)
+
+	runtime, err := containers.NewRuntime(dockerSocket)
+	if err != nil {
+		log.Printf("container_status: Unable to connect to the runtime: %v", err)
+		return limits, nil
+	}
+	stats, err := runtime.ContainerStats(id)
+	if err != nil {
+		if err != containers.ErrNoSuchContainer {
+			log.Printf("container_status: Unable to read container stats: %v", err)
+		}
+		return limits, nil
+	}
+	return limits, &ContainerUsage{stats.MemoryUsage, stats.MemoryLimit, stats.CPUUsage}
+}
+
+// The limits configured on the container unit
+func containerLimits(id containers.Identifier) ContainerLimits {
+	limits := ContainerLimits{MemoryLimit: LimitUnlimited, CPUShares: LimitUnlimited}
+	if file, err := os.Open(id.UnitPathFor()); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			switch {
+			case strings.HasPrefix(line, "MemoryLimit="):
+				limits.MemoryLimit = limitValue(strings.TrimPrefix(line, "MemoryLimit="))
+			case strings.HasPrefix(line, "CPUShares="):
+				limits.CPUShares = limitValue(strings.TrimPrefix(line, "CPUShares="))
+			case strings.HasPrefix(line, "X-ContainerCPUSetCPUs="):
+				limits.CPUSetCPUs = containers.CPUSet(strings.TrimPrefix(line, "X-ContainerCPUSetCPUs="))
+			case strings.HasPrefix(line, "X-ContainerCPUSetMems="):
+				limits.CPUSetMems = containers.CPUSet(strings.TrimPrefix(line, "X-ContainerCPUSetMems="))
+			}
+		}
+		file.Close()
+	} else {
+		log.Printf("container_status: Unable to read unit file: %v", err)
+	}
+	return limits
+}
+
+// The CPUs and memory nodes the container is pinned to, such as "CPUs 0-3
+// and memory nodes 0".
+func (l ContainerLimits) cpuSetDescription() string {
+	parts := []string{}
+	if l.CPUSetCPUs != "" {
+		parts = append(parts, "CPUs "+string(l.CPUSetCPUs))
+	}
+	if l.CPUSetMems != "" {
+		parts = append(parts, "memory nodes "+string(l.CPUSetMems))
+	}
+	return strings.Join(parts, " and ")
+}
+
+func limitValue(s string) string {
+	if s == "" || s == "infinity" {
+		return LimitUnlimited
+	}
+	return s
 }
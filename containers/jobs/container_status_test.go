@@ -0,0 +1,203 @@
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/systemd"
+)
+
+func fakeStatsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			fmt.Fprintln(w, `{"ExecutionDriver":"native-0.2"}`)
+		case "/containers/test-status/stats":
+			if r.URL.Query().Get("stream") != "false" {
+				t.Errorf("Expected a single stats sample to be requested")
+			}
+			fmt.Fprintln(w, `{"memory_stats":{"usage":104857600,"limit":268435456},"cpu_stats":{"cpu_usage":{"total_usage":1500000000}}}`)
+		case "/containers/test-stopped/stats":
+			http.Error(w, "No such container: test-stopped", http.StatusNotFound)
+		case "/containers/test-status/json":
+			fmt.Fprintln(w, `{"State":{"Running":true,"Paused":false}}`)
+		default:
+			t.Errorf("Unexpected URL: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestContainerResourcesConfiguredAndObserved(t *testing.T) {
+	defer withContainerBasePath(t)()
+	server := fakeStatsServer(t)
+	defer server.Close()
+
+	id := containers.Identifier("test-status")
+	unit := "[Service]\nCPUShares=512\nMemoryLimit=256M\nExecStart=/usr/bin/docker run test\n\n[Install]\nX-ContainerCPUSetCPUs=0-3,5\nX-ContainerCPUSetMems=0\n"
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte(unit), 0664); err != nil {
+		t.Fatalf("Unable to write unit: %v", err)
+	}
+
+	limits, usage := containerResources(id, server.URL)
+	if limits.MemoryLimit != "256M" || limits.CPUShares != "512" {
+		t.Errorf("Expected the configured limits to be read from the unit, got %+v", limits)
+	}
+	if limits.CPUSetCPUs != "0-3,5" || limits.CPUSetMems != "0" {
+		t.Errorf("Expected the cpusets to be read from the unit, got %+v", limits)
+	}
+	if s := limits.cpuSetDescription(); s != "CPUs 0-3,5 and memory nodes 0" {
+		t.Errorf("Unexpected description of the cpusets: %s", s)
+	}
+	if usage == nil {
+		t.Fatal("Expected usage to be reported for a running container")
+	}
+	if usage.MemoryUsage != 104857600 || usage.MemoryLimit != 268435456 || usage.CPUUsage != 1500000000 {
+		t.Errorf("Expected the observed usage to be read from docker, got %+v", usage)
+	}
+}
+
+func TestContainerResourcesUnlimited(t *testing.T) {
+	defer withContainerBasePath(t)()
+	server := fakeStatsServer(t)
+	defer server.Close()
+
+	id := containers.Identifier("test-stopped")
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\nExecStart=/usr/bin/docker run test\n"), 0664); err != nil {
+		t.Fatalf("Unable to write unit: %v", err)
+	}
+
+	limits, usage := containerResources(id, server.URL)
+	if limits.MemoryLimit != LimitUnlimited || limits.CPUShares != LimitUnlimited {
+		t.Errorf("Expected no configured limits to be unlimited, got %+v", limits)
+	}
+	if usage != nil {
+		t.Errorf("Expected no usage for a stopped container, got %+v", usage)
+	}
+}
+
+func TestContainerStatusWithRemoved(t *testing.T) {
+	a := ContainerStatusResponse{UnitResponse: UnitResponse{"a", "active", "running"}, Server: "host1"}
+	b := ContainerStatusResponse{UnitResponse: UnitResponse{"b", "active", "running"}, Server: "host1"}
+	polls := []ContainerStatusResponses{{a, b}, {a}, {a}, {a, b}}
+
+	var previous ContainerStatusResponses
+	states := []string{}
+	for i := range polls {
+		current := polls[i].WithRemoved(previous)
+		state := ""
+		for _, status := range current {
+			if status.Id == "b" {
+				state = status.ActiveState
+			}
+		}
+		states = append(states, state)
+		previous = current
+	}
+
+	expected := []string{"active", ContainerStateRemoved, ContainerStateRemoved, "active"}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Errorf("Poll %d: expected b to be %q, got %q", i, expected[i], states[i])
+		}
+	}
+}
+
+func TestContainerStatusLabels(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Reading the status of a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	server := fakeStatsServer(t)
+	defer server.Close()
+
+	id := containers.Identifier("test-status")
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\nExecStart=/usr/bin/docker run test\n"), 0664); err != nil {
+		t.Fatalf("Unable to write unit: %v", err)
+	}
+	if err := (containers.Labels{"env": "prod", "tier": "web"}).Write(id.LabelsPathFor()); err != nil {
+		t.Fatalf("Unable to write labels: %v", err)
+	}
+
+	req := &ContainerStatusRequest{Id: id, Structured: true, DockerSocket: server.URL}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error reading status: %v", resp.Error)
+	}
+	status, ok := resp.Data.(*ContainerStatusResponse)
+	if !ok {
+		t.Fatalf("Expected a structured status, got %#v", resp.Data)
+	}
+	if status.Labels.String() != "env=prod,tier=web" {
+		t.Errorf("Expected the labels of the container, got %v", status.Labels)
+	}
+
+	data, _ := json.Marshal(status)
+	if !strings.Contains(string(data), `"Labels":{"env":"prod","tier":"web"}`) {
+		t.Errorf("Expected the labels in the json status, got %s", string(data))
+	}
+	buf := &bytes.Buffer{}
+	ContainerStatusResponses{*status}.WriteTableTo(buf)
+	if !strings.Contains(buf.String(), "LABELS") || !strings.Contains(buf.String(), "env=prod,tier=web") {
+		t.Errorf("Expected the labels in the wide status, got:\n%s", buf.String())
+	}
+}
+
+func TestContainerTimes(t *testing.T) {
+	defer withContainerBasePath(t)()
+	id := containers.Identifier("test-times")
+	installedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	startedAt := installedAt.Add(10 * time.Minute)
+	if err := containers.RecordContainerInstalled(id, installedAt); err != nil {
+		t.Fatalf("Unable to record the install time: %v", err)
+	}
+	// reinstalling does not change when the container was created
+	if err := containers.RecordContainerInstalled(id, startedAt); err != nil {
+		t.Fatalf("Unable to record the install time: %v", err)
+	}
+	if err := containers.RecordContainerStarted(id, startedAt); err != nil {
+		t.Fatalf("Unable to record the start time: %v", err)
+	}
+
+	now := startedAt.Add(90 * time.Second)
+	installed, started, uptime := containerTimes(id, map[string]interface{}{"ActiveState": "active"}, now)
+	if installed == nil || !installed.Equal(installedAt) {
+		t.Errorf("Expected the install time %s, got %v", installedAt, installed)
+	}
+	if started == nil || !started.Equal(startedAt) {
+		t.Errorf("Expected the start time %s, got %v", startedAt, started)
+	}
+	if uptime == nil || *uptime != 90 {
+		t.Errorf("Expected an uptime of 90 seconds, got %v", uptime)
+	}
+
+	// the clock was set back since the container started
+	if _, _, uptime := containerTimes(id, map[string]interface{}{"ActiveState": "active"}, startedAt.Add(-time.Minute)); uptime == nil || *uptime != 0 {
+		t.Errorf("Expected the uptime not to be negative, got %v", uptime)
+	}
+
+	// systemd restarted the unit after the recorded start
+	entered := startedAt.Add(time.Minute)
+	props := map[string]interface{}{"ActiveState": "active", "ActiveEnterTimestamp": uint64(entered.UnixNano() / int64(time.Microsecond))}
+	if _, started, uptime := containerTimes(id, props, now); started == nil || !started.Equal(entered) || uptime == nil || *uptime != 30 {
+		t.Errorf("Expected the unit start time %s to be used, got %v and %v", entered, started, uptime)
+	}
+
+	if _, started, uptime := containerTimes(id, map[string]interface{}{"ActiveState": "inactive"}, now); started == nil || uptime != nil {
+		t.Errorf("Expected no uptime for a stopped container, got %v and %v", started, uptime)
+	}
+}
@@ -3,12 +3,19 @@
 package jobs
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
 )
 
 func (j *ContentRequest) Fast() bool {
@@ -23,20 +30,233 @@ func (j *ContentRequest) Execute(resp jobs.Response) {
 			resp.Failure(jobs.SimpleError{jobs.ResponseInvalidRequest, fmt.Sprintf("Invalid environment identifier: %s", errr.Error())})
 			return
 		}
-		file, erro := os.Open(id.EnvironmentPathFor())
+		data, erro := containers.ReadEnvironmentFile(id.EnvironmentPathFor())
+		if e, ok := erro.(containers.EnvironmentDecryptError); ok {
+			log.Printf("job_content: Unable to decrypt environment file: %v", e)
+			resp.Failure(jobs.SimpleError{jobs.ResponseError, e.Error()})
+			return
+		}
 		if erro != nil {
 			resp.Failure(ErrEnvironmentNotFound)
 			return
 		}
+		etag := contentETag(data)
+		env := containers.EnvironmentDescription{}
+		errp := env.ReadFrom(bytes.NewReader(data))
+		if len(j.Keys) > 0 {
+			selected, err := selectEnvironmentKeys(env.Map(), j.Keys)
+			if err != nil {
+				resp.Failure(err)
+				return
+			}
+			data = selected
+		}
+		resp.WritePendingSuccess(PendingETagName, etag)
+		if errp == nil {
+			resp.WritePendingSuccess(PendingEnvironmentSizeName, EnvironmentSize(containers.EnvironmentSize(env.Map())))
+		}
+		if matchesETag(j.IfNoneMatch, etag) {
+			resp.Success(jobs.ResponseNotModified)
+			return
+		}
+		w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
+		if _, err := w.Write(data); err != nil {
+			log.Printf("job_content: Unable to write environment file: %+v", err)
+			return
+		}
+
+	case ContentTypeFile:
+		id, errr := containers.NewIdentifier(j.Locator)
+		if errr != nil {
+			resp.Failure(jobs.SimpleError{jobs.ResponseInvalidRequest, fmt.Sprintf("Invalid container identifier: %s", errr.Error())})
+			return
+		}
+		if _, err := os.Stat(id.UnitPathFor()); err != nil {
+			resp.Failure(ErrContainerNotFound)
+			return
+		}
+		root, path, err := containerFilePath(id.HomePath(), j.Subpath)
+		if err != nil {
+			resp.Failure(err)
+			return
+		}
+		file, err := openContainerFile(root, path, j.Subpath)
+		if err != nil {
+			resp.Failure(err)
+			return
+		}
 		defer file.Close()
+		j.writeFile(file, path, resp)
+
+	default:
+		resp.Failure(jobs.SimpleError{jobs.ResponseInvalidRequest, fmt.Sprintf("Unknown content type '%s'", j.Type)})
+	}
+}
+
+// Write the bytes of a file unchanged, or the names in a directory one per
+// line with a trailing slash on each subdirectory.
+func (j *ContentRequest) writeFile(file *os.File, path string, resp jobs.Response) {
+	info, err := file.Stat()
+	if err != nil {
+		resp.Failure(ErrContainerFileNotFound)
+		return
+	}
+
+	if !info.IsDir() {
 		w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
 		if _, err := io.Copy(w, file); err != nil {
-			log.Printf("job_content: Unable to write environment file: %+v", err)
+			log.Printf("job_content: Unable to write file %s: %+v", path, err)
+		}
+		return
+	}
+
+	entries, err := file.Readdir(-1)
+	if err != nil {
+		log.Printf("job_content: Unable to list directory %s: %+v", path, err)
+		resp.Failure(jobs.SimpleError{jobs.ResponseError, "Unable to list the directory."})
+		return
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
+	for _, name := range names {
+		if _, err := fmt.Fprintln(w, name); err != nil {
+			log.Printf("job_content: Unable to write directory listing %s: %+v", path, err)
 			return
 		}
 	}
 }
 
+// The resolved home directory and path of rel below it, rejecting a path
+// that leaves home either through '..' or through a symlink that points
+// outside of it.  The container may change its files after the path is
+// resolved, so the path must be opened with openContainerFile.
+func containerFilePath(home, rel string) (string, string, error) {
+	if filepath.IsAbs(rel) {
+		return "", "", jobs.NewInvalidError("The path %s must be relative to the home directory of the container", rel)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if part == ".." {
+			return "", "", jobs.NewInvalidError("The path %s may not refer to a parent directory", rel)
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(home)
+	if err != nil {
+		return "", "", ErrContainerFileNotFound
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, filepath.Clean(rel)))
+	if err != nil {
+		return "", "", ErrContainerFileNotFound
+	}
+	if !insideDir(root, path) {
+		return "", "", jobs.NewInvalidError("The path %s is outside of the home directory of the container", rel)
+	}
+	return root, path, nil
+}
+
+// Open a path returned by containerFilePath, failing if a symlink was put
+// in its place since it was resolved.  The last component is opened
+// without following a symlink, and the path the kernel reports for the
+// open file must still be below root, which catches a directory above it
+// that was replaced.  Only regular files and directories may be opened,
+// and the open does not block, so a FIFO put at the path can't hang the
+// job.
+func openContainerFile(root, path, rel string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		if e, ok := err.(*os.PathError); ok && e.Err == syscall.ELOOP {
+			return nil, jobs.NewInvalidError("The path %s is outside of the home directory of the container", rel)
+		}
+		return nil, ErrContainerFileNotFound
+	}
+	opened, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", file.Fd()))
+	if err != nil || !insideDir(root, opened) {
+		file.Close()
+		return nil, jobs.NewInvalidError("The path %s is outside of the home directory of the container", rel)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, ErrContainerFileNotFound
+	}
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		file.Close()
+		return nil, jobs.NewInvalidError("%s is not a regular file or directory", rel)
+	}
+	if err := syscall.SetNonblock(int(file.Fd()), false); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// Whether path is dir or below it
+func insideDir(dir, path string) bool {
+	inside, err := filepath.Rel(dir, path)
+	return err == nil && inside != ".." && !strings.HasPrefix(inside, ".."+string(filepath.Separator))
+}
+
+// The named variables as environment file lines, in the order they are
+// named.  Every variable must be set.
+func selectEnvironmentKeys(values map[string]string, keys []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	missing := []string{}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		value, ok := values[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		fmt.Fprintf(buf, "%s=%s\n", key, value)
+	}
+	if len(missing) > 0 {
+		return nil, jobs.SimpleError{jobs.ResponseNotFound, fmt.Sprintf("The environment has no variable named %s.", strings.Join(missing, ", "))}
+	}
+	return buf.Bytes(), nil
+}
+
+func contentETag(data []byte) ETag {
+	sum := sha256.Sum256(data)
+	return ETag("\"" + hex.EncodeToString(sum[:]) + "\"")
+}
+
+// True if the etag is in an If-None-Match list.  Weak tags compare equal
+// to strong ones, as If-None-Match requires.
+func matchesETag(ifNoneMatch string, etag ETag) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == string(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// True if the etag is in an If-Match list.  Weak tags never match, as
+// If-Match requires a strong comparison.
+func matchesStrongETag(ifMatch string, etag ETag) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == string(etag) {
+			return true
+		}
+	}
+	return false
+}
+
 //
 // A content retrieval job cannot be joined, and so should continue (we allow multiple inflight CR)
 //
@@ -0,0 +1,285 @@
+//go:build linux
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	ghttp "github.com/openshift/geard/http"
+	"github.com/openshift/geard/jobs"
+)
+
+func TestContentETag(t *testing.T) {
+	defer withContainerBasePath(t)()
+	writeEnvironment(t, "etag", containers.Environment{"A", "1"})
+
+	w := httptest.NewRecorder()
+	(&ContentRequest{Type: ContentTypeEnvironment, Locator: "etag"}).Execute(ghttp.NewHttpJobResponse(w, false, ghttp.ResponseTable))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusAccepted || etag == "" || w.Body.Len() == 0 {
+		t.Fatalf("Expected the content with an ETag, got %d %q %q", w.Code, etag, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	(&ContentRequest{Type: ContentTypeEnvironment, Locator: "etag", IfNoneMatch: etag}).Execute(ghttp.NewHttpJobResponse(w, false, ghttp.ResponseTable))
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected unchanged content to not be returned, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Errorf("Expected the ETag %s to be returned, got %q", etag, w.Header().Get("ETag"))
+	}
+
+	writeEnvironment(t, "etag", containers.Environment{"A", "2"})
+	w = httptest.NewRecorder()
+	(&ContentRequest{Type: ContentTypeEnvironment, Locator: "etag", IfNoneMatch: etag}).Execute(ghttp.NewHttpJobResponse(w, false, ghttp.ResponseTable))
+	if w.Code != http.StatusAccepted || w.Header().Get("ETag") == etag {
+		t.Errorf("Expected changed content to be returned with a new ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestContentEnvironmentKeys(t *testing.T) {
+	defer withContainerBasePath(t)()
+	writeEnvironment(t, "keys", containers.Environment{"A", "1"}, containers.Environment{"B", "2"}, containers.Environment{"C", "x=y"})
+
+	w := httptest.NewRecorder()
+	(&ContentRequest{Type: ContentTypeEnvironment, Locator: "keys"}).Execute(ghttp.NewHttpJobResponse(w, false, ghttp.ResponseTable))
+	etag := w.Header().Get("ETag")
+
+	for i, step := range []struct {
+		keys     []string
+		expected string
+	}{
+		{[]string{"B"}, "B=2\n"},
+		{[]string{"C", "A"}, "C=x=y\nA=1\n"},
+		{[]string{"A", "A"}, "A=1\n"},
+	} {
+		w := httptest.NewRecorder()
+		(&ContentRequest{Type: ContentTypeEnvironment, Locator: "keys", Keys: step.keys}).Execute(ghttp.NewHttpJobResponse(w, false, ghttp.ResponseTable))
+		if w.Code != http.StatusAccepted || w.Body.String() != step.expected {
+			t.Errorf("Step %d: expected %q, got %d %q", i, step.expected, w.Code, w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("Step %d: expected the ETag of the whole environment %s, got %q", i, etag, w.Header().Get("ETag"))
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	resp := &cmd.CliJobResponse{Output: buf}
+	(&ContentRequest{Type: ContentTypeEnvironment, Locator: "keys", Keys: []string{"A", "MISSING", "OTHER"}}).Execute(resp)
+	if !jobs.IsNotFound(resp.Error) || !strings.Contains(resp.Error.Error(), "MISSING, OTHER") {
+		t.Errorf("Expected the missing keys to be reported, got %v", resp.Error)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no content when a key is missing, got %q", buf.String())
+	}
+}
+
+func TestMatchesETag(t *testing.T) {
+	etag := ETag(`"abc"`)
+	for value, expected := range map[string]bool{
+		"":             false,
+		`"abc"`:        true,
+		`W/"abc"`:      true,
+		`"def", "abc"`: true,
+		`"def"`:        false,
+		"*":            true,
+	} {
+		if matchesETag(value, etag) != expected {
+			t.Errorf("Expected If-None-Match %q to match %v", value, expected)
+		}
+	}
+}
+
+func writeContainerFile(t *testing.T, id containers.Identifier, rel string, data []byte) {
+	path := filepath.Join(id.HomePath(), rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Unable to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Unable to write file: %v", err)
+	}
+}
+
+func TestContentFile(t *testing.T) {
+	defer withContainerBasePath(t)()
+	id := containers.Identifier("test-file")
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\n"), 0664); err != nil {
+		t.Fatalf("Unable to write unit: %v", err)
+	}
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	writeContainerFile(t, id, "conf/app.conf", []byte("name=app\n"))
+	writeContainerFile(t, id, "data/blob", binary)
+
+	for path, expected := range map[string][]byte{
+		"conf/app.conf":     []byte("name=app\n"),
+		"./conf/app.conf":   []byte("name=app\n"),
+		"data/blob":         binary,
+		"":                  []byte("conf/\ndata/\n"),
+		"conf":              []byte("app.conf\n"),
+		"data/../data/blob": nil,
+	} {
+		buf := &bytes.Buffer{}
+		resp := &cmd.CliJobResponse{Output: buf}
+		(&ContentRequest{Type: ContentTypeFile, Locator: string(id), Subpath: path}).Execute(resp)
+		if expected == nil {
+			if !jobs.IsInvalid(resp.Error) {
+				t.Errorf("Expected %q to be rejected as invalid, got %v", path, resp.Error)
+			}
+			continue
+		}
+		if resp.Error != nil {
+			t.Errorf("Unexpected error reading %q: %v", path, resp.Error)
+			continue
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("Expected %q to return %q, got %q", path, expected, buf.Bytes())
+		}
+	}
+
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	(&ContentRequest{Type: ContentTypeFile, Locator: string(id), Subpath: "missing"}).Execute(resp)
+	if resp.Error != ErrContainerFileNotFound {
+		t.Errorf("Expected a missing file to be reported, got %v", resp.Error)
+	}
+
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	(&ContentRequest{Type: ContentTypeFile, Locator: "test-missing", Subpath: "conf/app.conf"}).Execute(resp)
+	if resp.Error != ErrContainerNotFound {
+		t.Errorf("Expected a missing container to be reported, got %v", resp.Error)
+	}
+}
+
+func TestContentFileTraversal(t *testing.T) {
+	defer withContainerBasePath(t)()
+	id := containers.Identifier("test-file")
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\n"), 0664); err != nil {
+		t.Fatalf("Unable to write unit: %v", err)
+	}
+	writeContainerFile(t, id, "inside", []byte("inside\n"))
+	if err := ioutil.WriteFile(filepath.Join(id.BaseHomePath(), "secret"), []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("Unable to write file: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(id.BaseHomePath(), "secret"), filepath.Join(id.HomePath(), "escape")); err != nil {
+		t.Fatalf("Unable to create symlink: %v", err)
+	}
+	if err := os.Symlink("inside", filepath.Join(id.HomePath(), "link")); err != nil {
+		t.Fatalf("Unable to create symlink: %v", err)
+	}
+
+	for _, path := range []string{"../secret", "../../test-file/secret", "/etc/passwd", "escape"} {
+		buf := &bytes.Buffer{}
+		resp := &cmd.CliJobResponse{Output: buf}
+		(&ContentRequest{Type: ContentTypeFile, Locator: string(id), Subpath: path}).Execute(resp)
+		if !jobs.IsInvalid(resp.Error) {
+			t.Errorf("Expected %q to be rejected as invalid, got %v", path, resp.Error)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("Expected nothing to be written for %q, got %q", path, buf.String())
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	resp := &cmd.CliJobResponse{Output: buf}
+	(&ContentRequest{Type: ContentTypeFile, Locator: string(id), Subpath: "link"}).Execute(resp)
+	if resp.Error != nil || buf.String() != "inside\n" {
+		t.Errorf("Expected a symlink inside the home directory to be followed, got %v %q", resp.Error, buf.String())
+	}
+}
+
+func TestContentFileSymlinkSwap(t *testing.T) {
+	defer withContainerBasePath(t)()
+	id := containers.Identifier("test-file")
+	writeContainerFile(t, id, "conf/app.conf", []byte("name=app\n"))
+	outside := filepath.Join(id.BaseHomePath(), "outside")
+	if err := os.MkdirAll(outside, 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(outside, "app.conf"), []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	root, path, err := containerFilePath(id.HomePath(), "conf/app.conf")
+	if err != nil {
+		t.Fatalf("Unexpected error resolving the path: %v", err)
+	}
+	file, err := openContainerFile(root, path, "conf/app.conf")
+	if err != nil {
+		t.Fatalf("Unexpected error opening the path: %v", err)
+	}
+	file.Close()
+
+	// the container replaces a directory with a symlink after the path is
+	// resolved
+	conf := filepath.Join(id.HomePath(), "conf")
+	if err := os.RemoveAll(conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, conf); err != nil {
+		t.Fatal(err)
+	}
+	if file, err := openContainerFile(root, path, "conf/app.conf"); !jobs.IsInvalid(err) {
+		if file != nil {
+			file.Close()
+		}
+		t.Errorf("Expected a directory replaced by a symlink to be rejected, got %v", err)
+	}
+
+	// or replaces the file itself
+	if err := os.Remove(conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(conf, 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "app.conf"), path); err != nil {
+		t.Fatal(err)
+	}
+	if file, err := openContainerFile(root, path, "conf/app.conf"); !jobs.IsInvalid(err) {
+		if file != nil {
+			file.Close()
+		}
+		t.Errorf("Expected a file replaced by a symlink to be rejected, got %v", err)
+	}
+}
+
+func TestContentFileFIFO(t *testing.T) {
+	defer withContainerBasePath(t)()
+	id := containers.Identifier("test-file")
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\n"), 0664); err != nil {
+		t.Fatalf("Unable to write unit: %v", err)
+	}
+	writeContainerFile(t, id, "inside", []byte("inside\n"))
+	if err := syscall.Mkfifo(filepath.Join(id.HomePath(), "fifo"), 0644); err != nil {
+		t.Fatalf("Unable to create a FIFO: %v", err)
+	}
+
+	done := make(chan *cmd.CliJobResponse)
+	go func() {
+		resp := &cmd.CliJobResponse{Output: &bytes.Buffer{}}
+		(&ContentRequest{Type: ContentTypeFile, Locator: string(id), Subpath: "fifo"}).Execute(resp)
+		done <- resp
+	}()
+	select {
+	case resp := <-done:
+		if !jobs.IsInvalid(resp.Error) {
+			t.Errorf("Expected a FIFO to be rejected as invalid, got %v", resp.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected reading a FIFO not to block")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/openshift/geard/containers"
 	csystemd "github.com/openshift/geard/containers/systemd"
@@ -23,6 +24,9 @@ func (j *DeleteContainerRequest) Execute(resp jobs.Response) {
 	homeDirPath := j.Id.BaseHomePath()
 	runDirPath := j.Id.RunPathFor()
 	networkLinksPath := j.Id.NetworkLinksPathFor()
+	labelsPath := j.Id.LabelsPathFor()
+	pulledImagePath := j.Id.PulledImagePathFor()
+	timesPath := j.Id.TimesPathFor()
 
 	_, err := systemd.Connection().GetUnitProperties(unitName)
 	switch {
@@ -34,6 +38,12 @@ func (j *DeleteContainerRequest) Execute(resp jobs.Response) {
 		return
 	}
 
+	// containers linking to this one could no longer start
+	if err := checkNotLinked(j.Id); err != nil {
+		resp.Failure(err)
+		return
+	}
+
 	if err := systemd.Connection().StopUnitJob(unitName, "fail"); err != nil {
 		log.Printf("delete_container: Unable to queue stop unit job: %v", err)
 	}
@@ -68,10 +78,26 @@ func (j *DeleteContainerRequest) Execute(resp jobs.Response) {
 		log.Printf("delete_container: Unable to remove socket unit path: %v", err)
 	}
 
+	if err := removePathUnit(j.Id); err != nil {
+		log.Printf("delete_container: Unable to remove path unit: %v", err)
+	}
+
 	if err := os.Remove(networkLinksPath); err != nil && !os.IsNotExist(err) {
 		log.Printf("delete_container: Unable to remove network links file: %v", err)
 	}
 
+	if err := os.Remove(labelsPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("delete_container: Unable to remove labels file: %v", err)
+	}
+
+	if err := os.Remove(pulledImagePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("delete_container: Unable to remove pulled image checkpoint: %v", err)
+	}
+
+	if err := os.Remove(timesPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("delete_container: Unable to remove install and start times: %v", err)
+	}
+
 	if err := os.RemoveAll(unitDefinitionsPath); err != nil {
 		log.Printf("delete_container: Unable to remove definitions for container: %v", err)
 	}
@@ -94,3 +120,20 @@ func (j *DeleteContainerRequest) Execute(resp jobs.Response) {
 
 	resp.Success(jobs.ResponseOk)
 }
+
+// Fail with a conflict if other containers link to id.
+func checkNotLinked(id containers.Identifier) error {
+	linked, err := containers.LinkedFrom(id)
+	if err != nil {
+		log.Printf("delete_container: Unable to find the containers linked to %s: %v", id, err)
+		return nil
+	}
+	if len(linked) == 0 {
+		return nil
+	}
+	names := make([]string, len(linked))
+	for i := range linked {
+		names[i] = string(linked[i])
+	}
+	return jobs.NewConflictError("The container %s is linked to by %s, remove them or reinstall them without the link first.", id, strings.Join(names, ", "))
+}
@@ -3,6 +3,11 @@
 package jobs
 
 import (
+	"bytes"
+	"log"
+	"os"
+
+	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
 )
 
@@ -11,18 +16,270 @@ func (j *PutEnvironmentRequest) Execute(resp jobs.Response) {
 		resp.Failure(ErrEnvironmentUpdateFailed)
 		return
 	}
+
+	defer containers.LockEnvironment(j.Id)()
+	if err := checkEnvironmentMatch(j.Id, j.IfMatch); err != nil {
+		resp.Failure(err)
+		return
+	}
+	if err := checkEnvironmentLimits(j.Id, j.Variables, true); err != nil {
+		resp.Failure(err)
+		return
+	}
+	if err := checkEnvironmentSchema(j.Id, j.Variables, j.Schema, true); err != nil {
+		resp.Failure(err)
+		return
+	}
+	signal, err := j.reloadSignal(j.Id)
+	if err != nil {
+		resp.Failure(err)
+		return
+	}
 	if err := j.Write(false); err != nil {
 		resp.Failure(ErrEnvironmentUpdateFailed)
 		return
 	}
+	if err := j.reload(j.Id, signal); err != nil {
+		resp.Failure(err)
+		return
+	}
+	writeEnvironmentETag(j.Id, resp)
 
 	resp.Success(jobs.ResponseOk)
 }
 
 func (j *PatchEnvironmentRequest) Execute(resp jobs.Response) {
+	defer containers.LockEnvironment(j.Id)()
+	if err := checkEnvironmentMatch(j.Id, j.IfMatch); err != nil {
+		resp.Failure(err)
+		return
+	}
+	if err := checkEnvironmentLimits(j.Id, j.Variables, false); err != nil {
+		resp.Failure(err)
+		return
+	}
+	if err := checkEnvironmentSchema(j.Id, j.Variables, j.Schema, false); err != nil {
+		resp.Failure(err)
+		return
+	}
+	signal, err := j.reloadSignal(j.Id)
+	if err != nil {
+		resp.Failure(err)
+		return
+	}
 	if err := j.Write(true); err != nil {
 		resp.Failure(ErrEnvironmentUpdateFailed)
 		return
 	}
+	if err := j.reload(j.Id, signal); err != nil {
+		resp.Failure(err)
+		return
+	}
+	writeEnvironmentETag(j.Id, resp)
+	resp.Success(jobs.ResponseOk)
+}
+
+// The signal the container with id reloads its environment on, if a
+// reload was requested.  Checked before the environment is changed.
+func (r *EnvironmentReload) reloadSignal(id containers.Identifier) (string, error) {
+	if !r.Reload {
+		return "", nil
+	}
+	signal, err := containers.GetEnvReloadSignal(id)
+	if os.IsNotExist(err) {
+		return "", jobs.NewNotFoundError("The container %s does not exist, only the environment of an installed container can be reloaded.", id)
+	}
+	if err != nil {
+		log.Printf("job_environment: Unable to read the unit of %s: %v", id, err)
+		return "", ErrEnvironmentUpdateFailed
+	}
+	if signal == "" {
+		return "", jobs.NewInvalidError("The container %s was not installed with an environment reload signal.", id)
+	}
+	return signal, nil
+}
+
+// Replace the copy of the environment the container reads, and signal it
+// to reload if it is running.  A stopped container reads the copy when it
+// next starts.
+func (r *EnvironmentReload) reload(id containers.Identifier, signal string) error {
+	if signal == "" {
+		return nil
+	}
+	data, err := reloadedEnvironment(id)
+	if err != nil {
+		log.Printf("job_environment: Unable to read environment %s to reload: %v", id, err)
+		return ErrEnvironmentReloadFailed
+	}
+	if err := containers.WriteReloadEnvironment(id, data); err != nil {
+		log.Printf("job_environment: Unable to write the reloadable environment of %s: %v", id, err)
+		return ErrEnvironmentReloadFailed
+	}
+
+	runtime, err := containers.NewRuntime(r.DockerSocket)
+	if err != nil {
+		log.Printf("job_environment: Unable to connect to the runtime: %v", err)
+		return ErrEnvironmentReloadFailed
+	}
+	running, err := runtime.ContainerRunning(id)
+	if err == containers.ErrNoSuchContainer || (err == nil && !running) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("job_environment: Unable to check whether %s is running: %v", id, err)
+		return ErrEnvironmentReloadFailed
+	}
+	if err := runtime.SignalContainer(id, signal); err != nil && err != containers.ErrNoSuchContainer {
+		log.Printf("job_environment: Unable to send %s to %s: %v", signal, id, err)
+		return ErrEnvironmentReloadFailed
+	}
+	return nil
+}
+
+// The environment of the container, merged over the one it inherits from
+// if it does.
+func reloadedEnvironment(id containers.Identifier) ([]byte, error) {
+	parent, err := containers.GetInheritedEnvironment(id)
+	if err != nil {
+		return nil, err
+	}
+	if parent != "" {
+		return containers.ResolveInheritedEnvironment(id, parent)
+	}
+	return containers.ReadEnvironmentFile(id.EnvironmentPathFor())
+}
+
+// Return a conflict if the current environment does not match one of the
+// ETags in ifMatch.  An empty ifMatch always matches.
+func checkEnvironmentMatch(id containers.Identifier, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+	data, err := containers.ReadEnvironmentFile(id.EnvironmentPathFor())
+	if os.IsNotExist(err) {
+		return jobs.NewConflictError("The environment %s does not exist.", id)
+	}
+	if err != nil {
+		log.Printf("job_environment: Unable to read environment %s: %v", id, err)
+		return ErrEnvironmentUpdateFailed
+	}
+	if etag := contentETag(data); !matchesStrongETag(ifMatch, etag) {
+		return jobs.NewConflictError("The environment %s has changed, its current ETag is %s.", id, etag)
+	}
+	return nil
+}
+
+// Return an invalid request error if the variables would take the
+// environment over the size limits.
+func checkEnvironmentLimits(id containers.Identifier, variables []containers.Environment, replace bool) error {
+	var current map[string]string
+	if !replace {
+		var err error
+		if current, err = readEnvironmentMap(id); err != nil && !os.IsNotExist(err) {
+			log.Printf("job_environment: Unable to read environment %s: %v", id, err)
+			return ErrEnvironmentUpdateFailed
+		}
+	}
+	if err := containers.EnvironmentSizeLimits.Check(current, variables, replace); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	return nil
+}
+
+// Return an invalid request error listing each variable that doesn't match
+// the schema once the variables are written to the environment, merged
+// into it unless replace is true.
+func checkEnvironmentSchema(id containers.Identifier, variables []containers.Environment, schema *containers.EnvironmentSchema, replace bool) error {
+	if schema == nil {
+		return nil
+	}
+	next := make(map[string]string)
+	if !replace {
+		current, err := readEnvironmentMap(id)
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("job_environment: Unable to read environment %s: %v", id, err)
+			return ErrEnvironmentUpdateFailed
+		}
+		for name, value := range current {
+			next[name] = value
+		}
+	}
+	for i := range variables {
+		next[variables[i].Name] = variables[i].Value
+	}
+	if err := schema.Validate(next); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	return nil
+}
+
+// The variables in the stored environment of a container.
+func readEnvironmentMap(id containers.Identifier) (map[string]string, error) {
+	data, err := containers.ReadEnvironmentFile(id.EnvironmentPathFor())
+	if err != nil {
+		return nil, err
+	}
+	env := containers.EnvironmentDescription{}
+	if err := env.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return env.Map(), nil
+}
+
+// Report the ETag of the environment after a change, so that a client can
+// make its next change conditional on it.
+func writeEnvironmentETag(id containers.Identifier, resp jobs.Response) {
+	data, err := containers.ReadEnvironmentFile(id.EnvironmentPathFor())
+	if err != nil {
+		log.Printf("job_environment: Unable to read environment %s after writing: %v", id, err)
+		return
+	}
+	resp.WritePendingSuccess(PendingETagName, contentETag(data))
+}
+
+func (j *CopyEnvironmentRequest) Execute(resp jobs.Response) {
+	env := containers.EnvironmentDescription{Id: j.Id}
+	merged := make(map[string]int)
+	for _, source := range j.AllSources() {
+		data, err := containers.ReadEnvironmentFile(source.EnvironmentPathFor())
+		if os.IsNotExist(err) {
+			resp.Failure(ErrEnvironmentNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("job_environment: Unable to open source environment %s: %v", source, err)
+			if e, ok := err.(containers.EnvironmentDecryptError); ok {
+				resp.Failure(jobs.SimpleError{jobs.ResponseError, e.Error()})
+				return
+			}
+			resp.Failure(ErrEnvironmentUpdateFailed)
+			return
+		}
+
+		read := containers.EnvironmentDescription{}
+		if err := read.ReadFrom(bytes.NewReader(data)); err != nil {
+			log.Printf("job_environment: Unable to read source environment %s: %v", source, err)
+			resp.Failure(ErrEnvironmentUpdateFailed)
+			return
+		}
+		for _, v := range read.Variables {
+			if i, ok := merged[v.Name]; ok {
+				env.Variables[i] = v
+				continue
+			}
+			merged[v.Name] = len(env.Variables)
+			env.Variables = append(env.Variables, v)
+		}
+	}
+
+	defer containers.LockEnvironment(j.Id)()
+	if err := checkEnvironmentLimits(j.Id, env.Variables, j.Reset); err != nil {
+		resp.Failure(err)
+		return
+	}
+	if err := env.Write(!j.Reset); err != nil {
+		resp.Failure(ErrEnvironmentUpdateFailed)
+		return
+	}
 	resp.Success(jobs.ResponseOk)
 }
@@ -0,0 +1,435 @@
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+)
+
+func withContainerBasePath(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "geard-base")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+	return func() {
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func writeEnvironment(t *testing.T, id containers.Identifier, vars ...containers.Environment) {
+	env := containers.EnvironmentDescription{Id: id, Variables: vars}
+	if err := env.Write(false); err != nil {
+		t.Fatalf("Unable to write environment %s: %v", id, err)
+	}
+}
+
+func readEnvironment(t *testing.T, id containers.Identifier) map[string]string {
+	file, err := os.Open(id.EnvironmentPathFor())
+	if err != nil {
+		t.Fatalf("Unable to open environment %s: %v", id, err)
+	}
+	defer file.Close()
+	env := containers.EnvironmentDescription{}
+	if err := env.ReadFrom(file); err != nil {
+		t.Fatalf("Unable to read environment %s: %v", id, err)
+	}
+	return env.Map()
+}
+
+func TestCopyEnvironmentMerges(t *testing.T) {
+	defer withContainerBasePath(t)()
+	writeEnvironment(t, "source", containers.Environment{"A", "1"}, containers.Environment{"B", "2"})
+	writeEnvironment(t, "target", containers.Environment{"B", "old"}, containers.Environment{"C", "3"})
+
+	resp := &cmd.CliJobResponse{}
+	(&CopyEnvironmentRequest{Id: "target", Source: "source"}).Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error copying environment: %v", resp.Error)
+	}
+
+	env := readEnvironment(t, "target")
+	if env["A"] != "1" || env["B"] != "2" || env["C"] != "3" {
+		t.Errorf("Expected the source values to be merged into the target, got %v", env)
+	}
+}
+
+func TestCopyEnvironmentReset(t *testing.T) {
+	defer withContainerBasePath(t)()
+	writeEnvironment(t, "source", containers.Environment{"A", "1"})
+	writeEnvironment(t, "target", containers.Environment{"C", "3"})
+
+	resp := &cmd.CliJobResponse{}
+	(&CopyEnvironmentRequest{Id: "target", Source: "source", Reset: true}).Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error copying environment: %v", resp.Error)
+	}
+
+	env := readEnvironment(t, "target")
+	if len(env) != 1 || env["A"] != "1" {
+		t.Errorf("Expected the target to be replaced by the source, got %v", env)
+	}
+}
+
+func TestCopyEnvironmentMergesSources(t *testing.T) {
+	defer withContainerBasePath(t)()
+	writeEnvironment(t, "first", containers.Environment{"A", "1"}, containers.Environment{"B", "1"}, containers.Environment{"C", "1"})
+	writeEnvironment(t, "second", containers.Environment{"B", "2"}, containers.Environment{"D", "2"})
+	writeEnvironment(t, "third", containers.Environment{"C", "3"}, containers.Environment{"D", "3"})
+	writeEnvironment(t, "target", containers.Environment{"A", "old"}, containers.Environment{"E", "old"})
+
+	req := &CopyEnvironmentRequest{Id: "target", Source: "first", Sources: []containers.Identifier{"second", "third"}}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Expected the request to be valid: %v", err)
+	}
+	resp := &cmd.CliJobResponse{}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error merging environments: %v", resp.Error)
+	}
+
+	env := readEnvironment(t, "target")
+	expected := map[string]string{"A": "1", "B": "2", "C": "3", "D": "3", "E": "old"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected the last source to win, got %v", env)
+	}
+
+	req = &CopyEnvironmentRequest{Id: "target", Sources: []containers.Identifier{"first", "target"}}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected merging an environment into itself to be rejected, got %v", err)
+	}
+	req = &CopyEnvironmentRequest{Id: "target", Sources: []containers.Identifier{"first", "missing"}}
+	resp = &cmd.CliJobResponse{}
+	req.Execute(resp)
+	if resp.Error != ErrEnvironmentNotFound {
+		t.Errorf("Expected a missing source to be reported, got %v", resp.Error)
+	}
+	if env := readEnvironment(t, "target"); !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected a failed merge not to change the target, got %v", env)
+	}
+}
+
+// A runtime that records the signals sent to running containers
+type signalRecordingRuntime struct {
+	running bool
+	signals []string
+}
+
+func (r *signalRecordingRuntime) Name() string                   { return "recording" }
+func (r *signalRecordingRuntime) PullImage(image string) error   { return nil }
+func (r *signalRecordingRuntime) UpdateImage(image string) error { return nil }
+func (r *signalRecordingRuntime) ImagePresent(image string) (bool, error) {
+	return true, nil
+}
+func (r *signalRecordingRuntime) ImageDigests(image string) ([]containers.ImageDigest, error) {
+	return nil, nil
+}
+func (r *signalRecordingRuntime) StopContainer(id containers.Identifier, timeout uint) error {
+	return nil
+}
+func (r *signalRecordingRuntime) ContainerRunning(id containers.Identifier) (bool, error) {
+	return r.running, nil
+}
+func (r *signalRecordingRuntime) ContainerStats(id containers.Identifier) (*containers.ContainerStats, error) {
+	return nil, containers.ErrNoSuchContainer
+}
+func (r *signalRecordingRuntime) PauseContainer(id containers.Identifier) error   { return nil }
+func (r *signalRecordingRuntime) UnpauseContainer(id containers.Identifier) error { return nil }
+func (r *signalRecordingRuntime) ContainerPaused(id containers.Identifier) (bool, error) {
+	return false, nil
+}
+func (r *signalRecordingRuntime) SignalContainer(id containers.Identifier, signal string) error {
+	r.signals = append(r.signals, string(id)+" "+signal)
+	return nil
+}
+
+func withRecordingRuntime(r *signalRecordingRuntime) func() {
+	containers.RegisterRuntime("recording", func(socket string) (containers.Runtime, error) {
+		return r, nil
+	})
+	containers.RuntimeName = "recording"
+	return func() { containers.RuntimeName = containers.RuntimeDocker }
+}
+
+func TestPatchEnvironmentReload(t *testing.T) {
+	defer withContainerBasePath(t)()
+	runtime := &signalRecordingRuntime{running: true}
+	defer withRecordingRuntime(runtime)()
+
+	id := containers.Identifier("reload")
+	writeEnvironment(t, id, containers.Environment{"A", "1"})
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\nX-ContainerId=reload\nX-EnvReloadSignal=SIGHUP\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &PatchEnvironmentRequest{
+		EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"B", "2"}}},
+		EnvironmentReload:      EnvironmentReload{Reload: true},
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error patching environment: %v", resp.Error)
+	}
+	if len(runtime.signals) != 1 || runtime.signals[0] != "reload SIGHUP" {
+		t.Errorf("Expected the container to be sent SIGHUP, got %v", runtime.signals)
+	}
+	data, err := ioutil.ReadFile(id.ReloadEnvironmentPathFor())
+	if err != nil {
+		t.Fatalf("Expected the reloadable environment to be written: %v", err)
+	}
+	if s := string(data); !strings.Contains(s, "A=1") || !strings.Contains(s, "B=2") {
+		t.Errorf("Expected the reloadable environment to include the change, got %q", s)
+	}
+
+	// a stopped container reads the environment when it next starts
+	runtime.running = false
+	req.Variables = []containers.Environment{{"C", "3"}}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil || len(runtime.signals) != 1 {
+		t.Errorf("Expected a stopped container not to be signalled, got %v %v", resp.Error, runtime.signals)
+	}
+	if data, _ := ioutil.ReadFile(id.ReloadEnvironmentPathFor()); !strings.Contains(string(data), "C=3") {
+		t.Errorf("Expected the reloadable environment of a stopped container to be updated, got %q", data)
+	}
+}
+
+func TestPatchEnvironmentReloadNotConfigured(t *testing.T) {
+	defer withContainerBasePath(t)()
+	runtime := &signalRecordingRuntime{running: true}
+	defer withRecordingRuntime(runtime)()
+
+	id := containers.Identifier("reload")
+	writeEnvironment(t, id, containers.Environment{"A", "1"})
+	req := &PatchEnvironmentRequest{
+		EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"A", "2"}}},
+		EnvironmentReload:      EnvironmentReload{Reload: true},
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsNotFound(resp.Error) {
+		t.Errorf("Expected reloading the environment of a missing container to fail, got %v", resp.Error)
+	}
+
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\nX-ContainerId=reload\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsInvalid(resp.Error) {
+		t.Errorf("Expected reloading a container without a reload signal to fail, got %v", resp.Error)
+	}
+	if env := readEnvironment(t, id); env["A"] != "1" {
+		t.Errorf("Expected a rejected reload not to change the environment, got %v", env)
+	}
+	if len(runtime.signals) != 0 {
+		t.Errorf("Expected no signals to be sent, got %v", runtime.signals)
+	}
+}
+
+func TestCopyEnvironmentMissingSource(t *testing.T) {
+	defer withContainerBasePath(t)()
+
+	resp := &cmd.CliJobResponse{}
+	(&CopyEnvironmentRequest{Id: "target", Source: "missing"}).Execute(resp)
+	if resp.Error != ErrEnvironmentNotFound {
+		t.Fatalf("Expected a not found error, got %v", resp.Error)
+	}
+}
+
+func TestPatchEnvironmentConcurrent(t *testing.T) {
+	defer withContainerBasePath(t)()
+	// encrypted environments are rewritten on every patch
+	path := filepath.Join(config.ContainerBasePath(), "keys")
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("a"), 32))
+	if err := ioutil.WriteFile(path, []byte("key1 "+key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := containers.ReadEnvironmentKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers.EnvironmentEncryptionKeys = keys
+	defer func() { containers.EnvironmentEncryptionKeys = nil }()
+
+	writeEnvironment(t, "target", containers.Environment{"A", "1"})
+
+	count := 10
+	wg := sync.WaitGroup{}
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := &PatchEnvironmentRequest{EnvironmentDescription: containers.EnvironmentDescription{
+				Id:        "target",
+				Variables: []containers.Environment{{fmt.Sprintf("KEY%d", i), "set"}},
+			}}
+			resp := &cmd.CliJobResponse{Gather: true}
+			req.Execute(resp)
+			if resp.Error != nil {
+				t.Errorf("Unexpected error patching environment: %v", resp.Error)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := containers.ReadEnvironmentFile(containers.Identifier("target").EnvironmentPathFor())
+	if err != nil {
+		t.Fatalf("Unable to read environment: %v", err)
+	}
+	env := containers.EnvironmentDescription{}
+	if err := env.ReadFrom(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	values := env.Map()
+	if values["A"] != "1" {
+		t.Errorf("Expected the original value to survive, got %v", values)
+	}
+	for i := 0; i < count; i++ {
+		if values[fmt.Sprintf("KEY%d", i)] != "set" {
+			t.Errorf("Expected every patch to be applied, got %v", values)
+			break
+		}
+	}
+}
+
+func TestPatchEnvironmentIfMatch(t *testing.T) {
+	defer withContainerBasePath(t)()
+	writeEnvironment(t, "target", containers.Environment{"A", "1"})
+
+	patch := func(ifMatch string, name string) *cmd.CliJobResponse {
+		req := &PatchEnvironmentRequest{
+			EnvironmentDescription: containers.EnvironmentDescription{Id: "target", Variables: []containers.Environment{{name, "set"}}},
+			IfMatch:                ifMatch,
+		}
+		resp := &cmd.CliJobResponse{Gather: true}
+		req.Execute(resp)
+		return resp
+	}
+
+	resp := patch("", "B")
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error patching environment: %v", resp.Error)
+	}
+	etag, ok := resp.Pending[PendingETagName].(ETag)
+	if !ok || etag == "" {
+		t.Fatalf("Expected the new ETag to be returned, got %+v", resp.Pending)
+	}
+
+	if resp := patch(etag.String(), "C"); resp.Error != nil {
+		t.Fatalf("Unexpected error patching with a matching ETag: %v", resp.Error)
+	}
+	// the environment changed, so the first ETag is stale
+	if resp := patch(etag.String(), "D"); !jobs.IsConflict(resp.Error) {
+		t.Fatalf("Expected a conflict patching with a stale ETag, got %v", resp.Error)
+	}
+	if resp := patch("W/"+etag.String()+", *", "E"); resp.Error != nil {
+		t.Fatalf("Expected * to match any environment, got %v", resp.Error)
+	}
+
+	env := readEnvironment(t, "target")
+	if env["C"] != "set" || env["E"] != "set" || env["D"] != "" {
+		t.Errorf("Expected only the matching patches to be applied, got %v", env)
+	}
+}
+
+func TestEnvironmentSizeLimits(t *testing.T) {
+	defer withContainerBasePath(t)()
+	previous := containers.EnvironmentSizeLimits
+	containers.EnvironmentSizeLimits = containers.EnvironmentLimits{MaxValueSize: 16, MaxTotalSize: 32}
+	defer func() { containers.EnvironmentSizeLimits = previous }()
+
+	id := containers.Identifier("test-limits")
+	put := &PutEnvironmentRequest{EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"A", "0123456789"}}}}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	put.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error putting an environment under the limits: %v", resp.Error)
+	}
+
+	patch := &PatchEnvironmentRequest{EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"LONG", "0123456789abcdefg"}}}}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	patch.Execute(resp)
+	if jobs.FailureFor(resp.Error) != jobs.ResponseInvalidRequest || !strings.Contains(resp.Error.Error(), "LONG is 17 bytes") {
+		t.Errorf("Expected a value over the limit to be rejected by name, got %v", resp.Error)
+	}
+
+	// A=0123456789 and B=0123456789 are 13 bytes each, C=0123456789 takes
+	// the total to 39
+	patch = &PatchEnvironmentRequest{EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"B", "0123456789"}, {"C", "0123456789"}}}}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	patch.Execute(resp)
+	if jobs.FailureFor(resp.Error) != jobs.ResponseInvalidRequest || !strings.Contains(resp.Error.Error(), "Setting C would make the environment 39 bytes") {
+		t.Errorf("Expected a change over the total limit to be rejected, got %v", resp.Error)
+	}
+
+	if env := readEnvironment(t, id); len(env) != 1 || env["A"] != "0123456789" {
+		t.Errorf("Expected the rejected changes not to be written, got %v", env)
+	}
+
+	// replacing the environment does not count the current variables
+	put = &PutEnvironmentRequest{EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"B", "0123456789"}, {"C", "01234567"}}}}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	put.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error replacing the environment under the limits: %v", resp.Error)
+	}
+}
+
+func TestEnvironmentSchemaOnPatch(t *testing.T) {
+	defer withContainerBasePath(t)()
+	schema, err := containers.ReadEnvironmentSchema(strings.NewReader(`{"Variables": {"LOG_LEVEL": {"Required": true, "Values": ["debug", "info"]}, "WORKERS": {"Type": "int"}}}`))
+	if err != nil {
+		t.Fatalf("Unable to read the schema: %v", err)
+	}
+
+	id := containers.Identifier("test-schema")
+	put := &PutEnvironmentRequest{EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"WORKERS", "2"}}}, Schema: schema}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	put.Execute(resp)
+	if jobs.FailureFor(resp.Error) != jobs.ResponseInvalidRequest || !strings.Contains(resp.Error.Error(), "LOG_LEVEL is required") {
+		t.Fatalf("Expected an environment without a required variable to be rejected, got %v", resp.Error)
+	}
+
+	put.Variables = []containers.Environment{{"LOG_LEVEL", "info"}, {"WORKERS", "2"}}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	put.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error putting a valid environment: %v", resp.Error)
+	}
+
+	// the current variables count toward the environment being checked
+	patch := &PatchEnvironmentRequest{EnvironmentDescription: containers.EnvironmentDescription{Id: id, Variables: []containers.Environment{{"WORKERS", "4"}}}, Schema: schema}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	patch.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error patching a valid environment: %v", resp.Error)
+	}
+
+	patch.Variables = []containers.Environment{{"LOG_LEVEL", "verbose"}, {"WORKERS", "many"}}
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	patch.Execute(resp)
+	if jobs.FailureFor(resp.Error) != jobs.ResponseInvalidRequest || !strings.Contains(resp.Error.Error(), "LOG_LEVEL must be one of debug, info; WORKERS must be of type int") {
+		t.Errorf("Expected each invalid variable to be reported, got %v", resp.Error)
+	}
+	if env := readEnvironment(t, id); env["LOG_LEVEL"] != "info" || env["WORKERS"] != "4" {
+		t.Errorf("Expected the rejected patch not to be written, got %v", env)
+	}
+}
@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
 )
 
 var (
@@ -10,8 +11,12 @@ var (
 	ErrContainerStartFailed    = jobs.SimpleError{jobs.ResponseError, "Unable to start this container."}
 	ErrContainerStopFailed     = jobs.SimpleError{jobs.ResponseError, "Unable to stop this container."}
 	ErrContainerRestartFailed  = jobs.SimpleError{jobs.ResponseError, "Unable to restart this container."}
+	ErrContainerPauseFailed    = jobs.SimpleError{jobs.ResponseError, "Unable to pause this container."}
+	ErrContainerUnpauseFailed  = jobs.SimpleError{jobs.ResponseError, "Unable to unpause this container."}
+	ErrContainerResetFailed    = jobs.SimpleError{jobs.ResponseError, "Unable to reset the failed state of this container."}
 	ErrEnvironmentNotFound     = jobs.SimpleError{jobs.ResponseNotFound, "Unable to find the requested environment."}
 	ErrEnvironmentUpdateFailed = jobs.SimpleError{jobs.ResponseError, "Unable to update the specified environment."}
+	ErrEnvironmentReloadFailed = jobs.SimpleError{jobs.ResponseError, "The environment was updated, but the container could not be signalled to reload it."}
 	ErrListImagesFailed        = jobs.SimpleError{jobs.ResponseError, "Unable to list docker images."}
 	ErrListContainersFailed    = jobs.SimpleError{jobs.ResponseError, "Unable to list the installed containers."}
 	ErrStartRequestThrottled   = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to start."}
@@ -19,7 +24,30 @@ var (
 	ErrRestartRequestThrottled = jobs.SimpleError{jobs.ResponseRateLimit, "It has been too soon since the last request to restart or the state is currently changing."}
 	ErrLinkContainersFailed    = jobs.SimpleError{jobs.ResponseError, "Not all links could be set."}
 	ErrDeleteContainerFailed   = jobs.SimpleError{jobs.ResponseError, "Unable to delete the container."}
+	ErrContainerNotRunning     = jobs.SimpleError{jobs.ResponseInvalidRequest, "The specified container is not running."}
+	ErrContainerExecFailed     = jobs.SimpleError{jobs.ResponseError, "Unable to run the command in the container."}
+	ErrReassignPortFailed      = jobs.SimpleError{jobs.ResponseError, "Unable to reassign the port of the container."}
+	ErrRenameContainerFailed   = jobs.SimpleError{jobs.ResponseError, "Unable to rename the container, it has been left unchanged."}
+	ErrBuildImageFailed        = jobs.SimpleError{jobs.ResponseError, "Unable to build the image."}
+	ErrInspectContainerFailed  = jobs.SimpleError{jobs.ResponseError, "Unable to read the state of the container."}
+	ErrContainerFileNotFound   = jobs.SimpleError{jobs.ResponseNotFound, "The requested file does not exist in the home directory of the container."}
+	ErrContainerInitFailed     = jobs.SimpleError{jobs.ResponseError, "Unable to initialize the container."}
 
+	ErrContainerPullFailed                = jobs.SimpleError{jobs.ResponseError, "Unable to pull the image for this container."}
+	ErrImageNotPresent                    = jobs.SimpleError{jobs.ResponseNotFound, "The image is not present on this server, and the pull policy 'never' does not allow it to be pulled."}
 	ErrContainerCreateFailed              = jobs.SimpleError{jobs.ResponseError, "Unable to create container."}
 	ErrContainerCreateFailedPortsReserved = jobs.SimpleError{jobs.ResponseError, "Unable to create container: some ports could not be reserved."}
+	ErrSecretsNotSupported                = jobs.SimpleError{jobs.ResponseInvalidRequest, "Secrets can only be passed to a container by a version of Docker that supports --env-file."}
+	ErrBuildContextNotSupported           = jobs.SimpleError{jobs.ResponseInvalidRequest, "Images can only be built from a build context by Docker."}
+	ErrExecNotSupported                   = jobs.SimpleError{jobs.ResponseInvalidRequest, "Commands can only be run in containers managed by Docker."}
+	ErrInitNotSupported                   = jobs.SimpleError{jobs.ResponseInvalidRequest, "Containers can't be initialized remotely by this server."}
+	ErrDockerArgsNotAllowed               = jobs.SimpleError{jobs.ResponseInvalidRequest, "This server does not accept docker arguments, start the daemon with --allow-docker-args to allow them."}
+	ErrStartEnvironmentRunning            = jobs.SimpleError{jobs.ResponseInvalidRequest, "The container is already running, stop it before starting it with an overridden environment."}
+	ErrStartEnvironmentNotSupported       = jobs.SimpleError{jobs.ResponseInvalidRequest, "The container must be reinstalled before it can be started with an overridden environment."}
 )
+
+// No external port is free on the server.  The failure is reported as
+// unavailable (503) since ports may be released later.
+func portsExhaustedError(err port.ExhaustedError) jobs.SimpleError {
+	return jobs.SimpleError{jobs.ResponseUnavailable, err.Error()}
+}
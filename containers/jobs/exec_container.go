@@ -0,0 +1,65 @@
+// +build linux
+
+package jobs
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/jobs"
+)
+
+func (j *ExecRequest) Execute(resp jobs.Response) {
+	if containers.RuntimeName != containers.RuntimeDocker {
+		resp.Failure(ErrExecNotSupported)
+		return
+	}
+
+	client, err := docker.GetConnection(j.DockerSocket)
+	if err != nil {
+		log.Printf("exec_container: Unable to connect to docker: %v", err)
+		resp.Failure(ErrContainerExecFailed)
+		return
+	}
+
+	container, err := client.InspectContainer(j.Id.ContainerFor())
+	switch {
+	case err == docker.ErrNoSuchContainer:
+		resp.Failure(ErrContainerNotFound)
+		return
+	case err != nil:
+		log.Printf("exec_container: Unable to inspect container %s: %v", j.Id, err)
+		resp.Failure(ErrContainerExecFailed)
+		return
+	case !container.State.Running:
+		resp.Failure(ErrContainerNotRunning)
+		return
+	}
+
+	var stdin io.Reader
+	if j.Interactive {
+		stdin = j.Stdin
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	code, err := client.ExecInContainer(j.Id.ContainerFor(), docker.ExecOptions{
+		Cmd:    j.Command,
+		Tty:    j.Tty,
+		Stdin:  stdin,
+		Stdout: w,
+		Stderr: w,
+	})
+	if err != nil {
+		log.Printf("exec_container: Unable to run command in %s: %v", j.Id, err)
+		fmt.Fprintf(w, "Unable to run the command in the container: %s\n", err.Error())
+		return
+	}
+
+	if trailers, ok := resp.(jobs.TrailerResponse); ok {
+		trailers.WriteTrailer(TrailerExitCode, strconv.Itoa(code))
+	}
+}
@@ -0,0 +1,101 @@
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+)
+
+// A fake Docker daemon that implements just enough of the exec API
+// to run a single command.
+type fakeExecBackend struct {
+	running  bool
+	exitCode int
+	output   string
+
+	created []string
+}
+
+func (f *fakeExecBackend) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			fmt.Fprintln(w, `{"ExecutionDriver":"native-0.2"}`)
+		case "/containers/test-exec/json":
+			fmt.Fprintf(w, `{"ID":"abcdef","Name":"test-exec","State":{"Running":%t}}`, f.running)
+		case "/containers/test-exec/exec":
+			body := execCreateBody{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("Unable to decode exec create: %v", err)
+			}
+			f.created = body.Cmd
+			fmt.Fprintln(w, `{"Id":"exec1"}`)
+		case "/exec/exec1/start":
+			w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+			header := make([]byte, 8)
+			header[0] = 1
+			binary.BigEndian.PutUint32(header[4:], uint32(len(f.output)))
+			w.Write(header)
+			w.Write([]byte(f.output))
+		case "/exec/exec1/json":
+			fmt.Fprintf(w, `{"Id":"exec1","Running":false,"ExitCode":%d}`, f.exitCode)
+		default:
+			t.Errorf("Unexpected URL: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}
+}
+
+type execCreateBody struct {
+	Cmd []string
+}
+
+func TestExecStreamsOutputAndExitCode(t *testing.T) {
+	backend := &fakeExecBackend{running: true, exitCode: 3, output: "hello from exec\n"}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	out := &bytes.Buffer{}
+	resp := &cmd.CliJobResponse{Output: out}
+	req := &ExecRequest{Id: "test-exec", Command: []string{"echo", "hello"}, DockerSocket: server.URL}
+	req.Execute(resp)
+
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error from exec: %v", resp.Error)
+	}
+	if len(backend.created) != 2 || backend.created[0] != "echo" || backend.created[1] != "hello" {
+		t.Errorf("Expected the command to be passed to docker, got %v", backend.created)
+	}
+	if out.String() != backend.output {
+		t.Errorf("Expected output %q, got %q", backend.output, out.String())
+	}
+	if code := resp.Trailers[TrailerExitCode]; code != "3" {
+		t.Errorf("Expected exit code 3 to be reported, got %q", code)
+	}
+}
+
+func TestExecFailsWhenNotRunning(t *testing.T) {
+	backend := &fakeExecBackend{running: false}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req := &ExecRequest{Id: "test-exec", Command: []string{"true"}, DockerSocket: server.URL}
+	req.Execute(resp)
+
+	if resp.Error != ErrContainerNotRunning {
+		t.Fatalf("Expected a not running error, got %v", resp.Error)
+	}
+	if backend.created != nil {
+		t.Error("Should not create an exec for a stopped container")
+	}
+}
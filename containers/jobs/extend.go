@@ -47,6 +47,7 @@ func init() {
 	config.AddRequiredDirectory(
 		0750,
 		filepath.Join(config.ContainerBasePath(), "env", "contents"),
+		filepath.Join(config.ContainerBasePath(), "images", "pulled"),
 		filepath.Join(config.ContainerBasePath(), "ports", "descriptions"),
 		filepath.Join(config.ContainerBasePath(), "ports", "interfaces"),
 	)
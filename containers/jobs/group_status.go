@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/openshift/geard/containers"
+)
+
+// The health of a group of containers as a whole
+const (
+	// Every member is active
+	GroupAllUp = "all-up"
+	// Some members are active and some are not
+	GroupDegraded = "degraded"
+	// No member is active
+	GroupDown = "down"
+)
+
+// A container labelled group=<name> belongs to the group <name>, as do the
+// container named <name> and its variants.
+const GroupLabel = "group"
+
+// Whether the container is the one named base or one of its variants,
+// such as base--canary.
+func IsGroupMember(base string, id containers.Identifier) bool {
+	b, _ := id.Variant()
+	return b == base
+}
+
+// The status of each container of a group, rolled up into the health of
+// the group.
+type GroupStatusResponse struct {
+	Name   string
+	Health string
+	// The number of members that are active and not paused
+	Up      int
+	Members ContainerStatusResponses
+}
+
+func NewGroupStatus(name string, members ContainerStatusResponses) *GroupStatusResponse {
+	g := &GroupStatusResponse{Name: name, Members: members}
+	g.Members.GroupByVariant()
+	for i := range members {
+		if members[i].ActiveState == "active" && !members[i].Paused {
+			g.Up++
+		}
+	}
+	switch {
+	case g.Up == 0:
+		g.Health = GroupDown
+	case g.Up == len(members):
+		g.Health = GroupAllUp
+	default:
+		g.Health = GroupDegraded
+	}
+	return g
+}
+
+func (g *GroupStatusResponse) WriteTableTo(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Group %s is %s: %d of %d containers are up\n\n", g.Name, g.Health, g.Up, len(g.Members)); err != nil {
+		return err
+	}
+	return g.Members.WriteGroupedTableTo(w)
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/containers"
+)
+
+func TestGroupStatusDegraded(t *testing.T) {
+	members := ContainerStatusResponses{
+		{UnitResponse: UnitResponse{Id: "web-worker", ActiveState: "failed", SubState: "failed"}},
+		{UnitResponse: UnitResponse{Id: "web--canary", ActiveState: "active", SubState: "running"}},
+		{UnitResponse: UnitResponse{Id: "web", ActiveState: "active", SubState: "running"}},
+	}
+	g := NewGroupStatus("web", members)
+	if g.Health != GroupDegraded || g.Up != 2 {
+		t.Fatalf("Expected a group with a member down to be degraded with 2 up, got %s with %d", g.Health, g.Up)
+	}
+	if g.Members[0].Id != "web" || g.Members[1].Id != "web--canary" {
+		t.Errorf("Expected the members grouped by variant, got %s, %s", g.Members[0].Id, g.Members[1].Id)
+	}
+
+	buf := &bytes.Buffer{}
+	g.WriteTableTo(buf)
+	if !strings.HasPrefix(buf.String(), "Group web is degraded: 2 of 3 containers are up\n") {
+		t.Errorf("Expected the roll-up first, got:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "web-worker") {
+		t.Errorf("Expected a row for each member, got:\n%s", buf.String())
+	}
+}
+
+func TestGroupStatusRollUp(t *testing.T) {
+	up := ContainerStatusResponses{
+		{UnitResponse: UnitResponse{Id: "db", ActiveState: "active"}},
+		{UnitResponse: UnitResponse{Id: "db--replica", ActiveState: "active"}},
+	}
+	if g := NewGroupStatus("db", up); g.Health != GroupAllUp {
+		t.Errorf("Expected every member active to be all-up, got %s", g.Health)
+	}
+
+	down := ContainerStatusResponses{
+		{UnitResponse: UnitResponse{Id: "db", ActiveState: "inactive"}},
+		{UnitResponse: UnitResponse{Id: "db--replica", ActiveState: "active"}, Paused: true},
+	}
+	if g := NewGroupStatus("db", down); g.Health != GroupDown || g.Up != 0 {
+		t.Errorf("Expected a group with no member running to be down, got %s with %d up", g.Health, g.Up)
+	}
+
+	for id, member := range map[containers.Identifier]bool{"db": true, "db--replica": true, "db-backup": false, "dbx--replica": false} {
+		if IsGroupMember("db", id) != member {
+			t.Errorf("Expected membership of %s in db to be %t", id, member)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// +build linux
+
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+)
+
+func (j *HostStatusRequest) Fast() bool {
+	return true
+}
+
+func (j *HostStatusRequest) Execute(resp jobs.Response) {
+	ids, err := containers.InstalledIdentifiers()
+	if err != nil {
+		log.Printf("host_status: Unable to find installed containers: %v", err)
+		resp.Failure(jobs.SimpleError{jobs.ResponseError, "Unable to find the installed containers."})
+		return
+	}
+
+	var states map[containers.Identifier]string
+	if units, err := systemd.Connection().ListUnits(); err == nil {
+		states = make(map[containers.Identifier]string)
+		for i := range units {
+			if matched := reContainerUnits.FindStringSubmatch(units[i].Name); matched != nil {
+				states[containers.Identifier(matched[1])] = units[i].ActiveState
+			}
+		}
+	} else {
+		log.Printf("host_status: Unable to list units from systemd: %v", err)
+	}
+
+	min, max := port.AllocatorRange()
+	r := summarizeHost(ids, states, min, max)
+	reserved, err := port.CountReservedPorts(min, max)
+	if err != nil {
+		log.Printf("host_status: Unable to count reserved ports: %v", err)
+	}
+	r.PortsAllocated = reserved
+	r.PortsAvailable = int(max-min) - reserved
+
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
+
+// Total the limits and states of the containers.  A nil set of states
+// means the state of every container is unknown, while a container
+// missing from it is not loaded in systemd and so is stopped.
+func summarizeHost(ids []containers.Identifier, states map[containers.Identifier]string, min, max port.Port) *HostStatusResponse {
+	r := &HostStatusResponse{
+		Containers: len(ids),
+		PortRange:  fmt.Sprintf("%d-%d", min, max-1),
+	}
+	for _, id := range ids {
+		switch state, ok := states[id]; {
+		case states == nil:
+			r.Unknown++
+		case ok && (state == "active" || state == "activating" || state == "reloading"):
+			r.Running++
+		default:
+			r.Stopped++
+		}
+
+		limits := containerLimits(id)
+		if memory, ok := parseMemoryLimit(limits.MemoryLimit); ok {
+			r.MemoryLimit += memory
+		} else {
+			r.MemoryUnlimited++
+		}
+		if shares, err := strconv.ParseUint(limits.CPUShares, 10, 64); err == nil {
+			r.CPUShares += shares
+		} else {
+			r.CPUUnlimited++
+		}
+	}
+	return r
+}
+
+// Parse a systemd memory limit, which is in bytes or has a K, M, G, or T
+// suffix (powers of 1024).
+func parseMemoryLimit(s string) (uint64, bool) {
+	multiplier := uint64(1)
+	if i := strings.IndexAny(s, "KMGT"); i != -1 && i == len(s)-1 {
+		multiplier = 1 << (10 * uint(strings.IndexByte("KMGT", s[i])+1))
+		s = s[:i]
+	}
+	value, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value * multiplier, true
+}
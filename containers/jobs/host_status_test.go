@@ -0,0 +1,78 @@
+// +build linux
+
+package jobs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/port"
+)
+
+func writeUnit(t *testing.T, id containers.Identifier, limits string) {
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\n"+limits), 0660); err != nil {
+		t.Fatalf("Unable to write unit for %s: %v", id, err)
+	}
+}
+
+func TestHostStatus(t *testing.T) {
+	defer withContainerBasePath(t)()
+	writeUnit(t, "web-1", "MemoryLimit=512M\nCPUShares=512\n")
+	writeUnit(t, "web-2", "MemoryLimit=1G\nCPUShares=1024\n")
+	writeUnit(t, "worker", "")
+
+	ids, err := containers.InstalledIdentifiers()
+	if err != nil || len(ids) != 3 {
+		t.Fatalf("Expected three installed containers, got %v %v", ids, err)
+	}
+
+	for _, p := range []port.Port{4001, 4150, 5000} {
+		parent, path := p.PortPathsFor()
+		os.MkdirAll(parent, 0770)
+		if err := os.Symlink(containers.Identifier("web-1").PortDescriptionPathFor(), path); err != nil {
+			t.Fatalf("Unable to reserve port %d: %v", p, err)
+		}
+	}
+	reserved, err := port.CountReservedPorts(4000, 4200)
+	if err != nil || reserved != 2 {
+		t.Errorf("Expected two ports reserved in range, got %d %v", reserved, err)
+	}
+
+	r := summarizeHost(ids, map[containers.Identifier]string{"web-1": "active", "web-2": "failed"}, 4000, 4200)
+	if r.Containers != 3 || r.Running != 1 || r.Stopped != 2 || r.Unknown != 0 {
+		t.Errorf("Unexpected container counts %+v", r)
+	}
+	if r.MemoryLimit != 1536*1024*1024 || r.MemoryUnlimited != 1 {
+		t.Errorf("Unexpected memory totals %+v", r)
+	}
+	if r.CPUShares != 1536 || r.CPUUnlimited != 1 {
+		t.Errorf("Unexpected CPU totals %+v", r)
+	}
+	if r.PortRange != "4000-4199" {
+		t.Errorf("Unexpected port range %s", r.PortRange)
+	}
+
+	if r := summarizeHost(ids, nil, 4000, 4200); r.Unknown != 3 || r.Running != 0 || r.Stopped != 0 {
+		t.Errorf("Expected all states to be unknown without systemd, got %+v", r)
+	}
+}
+
+func TestParseMemoryLimit(t *testing.T) {
+	for value, expected := range map[string]uint64{
+		"1024": 1024,
+		"2K":   2048,
+		"512M": 512 * 1024 * 1024,
+		"1G":   1024 * 1024 * 1024,
+	} {
+		if actual, ok := parseMemoryLimit(value); !ok || actual != expected {
+			t.Errorf("Expected %s to be %d bytes, got %d", value, expected, actual)
+		}
+	}
+	for _, value := range []string{"", LimitUnlimited, "M", "1.5G"} {
+		if _, ok := parseMemoryLimit(value); ok {
+			t.Errorf("Expected %q to not be a memory limit", value)
+		}
+	}
+}
@@ -22,6 +22,9 @@ func InitializeData() error {
 	if err := initializeSlices(); err != nil {
 		return err
 	}
+	if err := systemd.InitializeSystemdFile(systemd.UnitType, csystemd.RestartUnitName, csystemd.ContainerRestartTemplate, nil, false); err != nil {
+		return err
+	}
 	if err := checkBinaries(); err != nil {
 		log.Printf("WARNING: Unable to find all required binaries - some operations may not be available: %v", err)
 	}
@@ -65,7 +68,7 @@ func initializeSlices() error {
 
 func isSystemdFile(filePath string) bool {
 	extention := filepath.Ext(filePath)
-	systemdExts := []string{".slice", ".service", ".socket", ".target"}
+	systemdExts := []string{".slice", ".service", ".socket", ".path", ".target"}
 	for _, e := range systemdExts {
 		if e == extention {
 			return true
@@ -0,0 +1,67 @@
+// +build linux
+
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+)
+
+// The steps 'gear init' runs before and after a container starts.  They are
+// set by the init command, which depends on this package, and are nil if it
+// isn't part of the binary.
+var (
+	InitPreStart  func(dockerSocket string, id containers.Identifier, imageName string, env containers.EnvironmentVariables) error
+	InitPostStart func(dockerSocket string, id containers.Identifier) error
+)
+
+func (j *InitPreStartRequest) Execute(resp jobs.Response) {
+	initContainer(j.Id, "before it starts", resp, func() error {
+		if InitPreStart == nil {
+			return ErrInitNotSupported
+		}
+		// the unit decides the image, a client may not initialize the
+		// container from another one
+		image, err := containers.GetContainerImage(j.Id)
+		if err != nil {
+			return err
+		}
+		if image != j.Image {
+			return jobs.NewInvalidError("The container %s was installed with the image %s, not %s.", j.Id, image, j.Image)
+		}
+		return InitPreStart(j.DockerSocket, j.Id, image, j.Environment)
+	})
+}
+
+func (j *InitPostStartRequest) Execute(resp jobs.Response) {
+	initContainer(j.Id, "after it started", resp, func() error {
+		if InitPostStart == nil {
+			return ErrInitNotSupported
+		}
+		return InitPostStart(j.DockerSocket, j.Id)
+	})
+}
+
+// Only containers installed by this server are initialized, since init
+// creates users and changes network namespaces.
+func initContainer(id containers.Identifier, when string, resp jobs.Response, fn func() error) {
+	if _, err := os.Stat(id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+	if err := fn(); err != nil {
+		if jobErr, ok := err.(jobs.SimpleError); ok {
+			resp.Failure(jobErr)
+			return
+		}
+		log.Printf("init_container: Unable to initialize %s %s: %v", id, when, err)
+		resp.Failure(ErrContainerInitFailed)
+		return
+	}
+	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+	fmt.Fprintf(w, "Initialized %s %s\n", id, when)
+}
@@ -0,0 +1,102 @@
+// +build linux
+
+package jobs
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+)
+
+func (j *InspectContainerRequest) Execute(resp jobs.Response) {
+	unit, err := ioutil.ReadFile(j.Id.UnitPathFor())
+	if err != nil {
+		if os.IsNotExist(err) {
+			resp.Failure(ErrContainerNotFound)
+			return
+		}
+		log.Printf("inspect_container: Unable to read the unit file: %v", err)
+		resp.Failure(ErrInspectContainerFailed)
+		return
+	}
+
+	r := InspectContainerResponse{
+		Id:       j.Id,
+		Metadata: containerMetadata(j.Id),
+		Unit:     string(unit),
+		Paths: ContainerPaths{
+			Unit:    j.Id.UnitPathFor(),
+			DataDir: j.Id.HomePath(),
+			RunDir:  j.Id.RunPathFor(),
+		},
+	}
+
+	props, err := systemd.Connection().GetUnitProperties(j.Id.UnitNameFor())
+	if err == nil {
+		r.Status.LoadState, _ = props["LoadState"].(string)
+		r.Status.ActiveState, _ = props["ActiveState"].(string)
+		r.Status.SubState, _ = props["SubState"].(string)
+	} else {
+		r.Status.Error = err.Error()
+	}
+	r.Metadata.Installed, r.Metadata.Started, _ = containerTimes(j.Id, props, time.Now())
+
+	if ports, err := containers.GetExistingPorts(j.Id); err == nil {
+		r.Ports = ports
+	} else {
+		log.Printf("inspect_container: Unable to read the ports: %v", err)
+	}
+
+	resp.SuccessWithData(jobs.ResponseOk, &r)
+}
+
+// Read what the install recorded about the container.  Values that can't
+// be read are logged and left empty so the rest can still be reported.
+func containerMetadata(id containers.Identifier) ContainerMetadata {
+	m := ContainerMetadata{}
+	var err error
+	if m.Image, err = containers.GetContainerImage(id); err != nil {
+		log.Printf("inspect_container: Unable to read the image: %v", err)
+	}
+	if m.ImageDigest, err = containers.GetContainerImageDigest(id); err != nil {
+		log.Printf("inspect_container: Unable to read the image digest: %v", err)
+	}
+	if m.Type, err = containers.GetContainerType(id); err != nil {
+		log.Printf("inspect_container: Unable to read the container type: %v", err)
+	}
+	if m.RequestId, err = containers.GetContainerRequestId(id); err != nil {
+		log.Printf("inspect_container: Unable to read the request id: %v", err)
+	}
+	if m.Revision, err = containers.GetContainerRevision(id); err != nil {
+		log.Printf("inspect_container: Unable to read the revision: %v", err)
+	}
+	if m.LogDriver, err = containers.GetLogDriver(id); err != nil {
+		log.Printf("inspect_container: Unable to read the log driver: %v", err)
+	}
+	if labels, err := containers.GetExistingLabels(id); err == nil {
+		if len(labels) > 0 {
+			m.Labels = labels
+		}
+	} else {
+		log.Printf("inspect_container: Unable to read labels: %v", err)
+	}
+	if links, err := containers.GetContainerAliases(id); err == nil {
+		if len(links) > 0 {
+			m.Links = links
+		}
+	} else {
+		log.Printf("inspect_container: Unable to read links: %v", err)
+	}
+	if m.InheritEnvironment, err = containers.GetInheritedEnvironment(id); err != nil {
+		log.Printf("inspect_container: Unable to read the inherited environment: %v", err)
+	}
+	if m.EnvReloadSignal, err = containers.GetEnvReloadSignal(id); err != nil {
+		log.Printf("inspect_container: Unable to read the reload signal: %v", err)
+	}
+	return m
+}
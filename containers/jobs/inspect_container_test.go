@@ -0,0 +1,104 @@
+// +build linux
+
+package jobs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+)
+
+func TestInspectContainerCombinesEachSection(t *testing.T) {
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+
+	install := &InstallContainerRequest{
+		RequestIdentifier: []byte("0123456789abcdef"),
+		Id:                "test-inspect",
+		Image:             "testimage@" + testImageDigest,
+		Isolate:           true,
+		LogDriver:         "json-file",
+	}
+	file, err := os.Create(install.Id.UnitPathFor())
+	if err != nil {
+		t.Fatalf("Unable to create the unit: %v", err)
+	}
+	_, digest := containers.SplitImageDigest(install.Image)
+	err = install.WriteUnit(file, port.PortPairs{{Internal: 8080, External: 14000}}, nil, digest)
+	file.Close()
+	if err != nil {
+		t.Fatalf("Unable to write the unit: %v", err)
+	}
+	if err := (containers.Labels{"tier": "web"}).Write(install.Id.LabelsPathFor()); err != nil {
+		t.Fatalf("Unable to write the labels: %v", err)
+	}
+	installed := time.Date(2014, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := containers.RecordContainerInstalled(install.Id, installed); err != nil {
+		t.Fatalf("Unable to record the install time: %v", err)
+	}
+
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+	(&InspectContainerRequest{Id: install.Id}).Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Expected the container to be inspected, got %v", resp.Error)
+	}
+	r, ok := resp.Data.(*InspectContainerResponse)
+	if !ok {
+		t.Fatalf("Expected an inspect response, got %#v", resp.Data)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Unable to marshal the response: %v", err)
+	}
+	document := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Unable to read the document back: %v", err)
+	}
+	for _, section := range []string{"Id", "Metadata", "Unit", "Status", "Ports", "Paths"} {
+		if _, ok := document[section]; !ok {
+			t.Errorf("Expected the document to have a %s section: %s", section, data)
+		}
+	}
+
+	m := r.Metadata
+	if m.Image != install.Image || m.ImageDigest != testImageDigest || m.Type != "isolated" || m.RequestId != install.RequestIdentifier.String() {
+		t.Errorf("Expected the recorded metadata, got %+v", m)
+	}
+	if m.LogDriver != "json-file" || m.Labels["tier"] != "web" {
+		t.Errorf("Expected the log driver and labels, got %+v", m)
+	}
+	if m.Installed == nil || !m.Installed.Equal(installed) {
+		t.Errorf("Expected the install time, got %v", m.Installed)
+	}
+	if !strings.Contains(r.Unit, "X-ContainerId=test-inspect\n") {
+		t.Errorf("Expected the contents of the unit file, got %q", r.Unit)
+	}
+	if r.Status.ActiveState == "" && r.Status.Error == "" {
+		t.Errorf("Expected the systemd state or why it couldn't be read, got %+v", r.Status)
+	}
+	if len(r.Ports) != 1 || r.Ports[0].External != 14000 || r.Ports[0].Internal != 8080 {
+		t.Errorf("Expected the allocated port, got %v", r.Ports)
+	}
+	if r.Paths.Unit != install.Id.UnitPathFor() || r.Paths.DataDir != install.Id.HomePath() || r.Paths.RunDir == "" {
+		t.Errorf("Expected the paths of the container, got %+v", r.Paths)
+	}
+}
+
+func TestInspectMissingContainer(t *testing.T) {
+	defer withContainerBasePath(t)()
+
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+	(&InspectContainerRequest{Id: "test-missing"}).Execute(resp)
+	if !jobs.IsNotFound(resp.Error) {
+		t.Errorf("Expected a missing container to be not found, got %v", resp.Error)
+	}
+}
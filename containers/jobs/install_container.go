@@ -4,11 +4,16 @@ package jobs
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/openshift/geard/config"
 	"github.com/openshift/geard/containers"
@@ -19,29 +24,117 @@ import (
 	"github.com/openshift/geard/utils"
 )
 
-func dockerPortSpec(p port.PortPairs) string {
-	var portSpec bytes.Buffer
-	for i := range p {
-		portSpec.WriteString(fmt.Sprintf("-p %d:%d ", p[i].External, p[i].Internal))
-	}
-	return portSpec.String()
+func (req *InstallContainerRequest) Execute(resp jobs.Response) {
+	req.ExecuteContext(context.Background(), resp)
 }
 
-func (req *InstallContainerRequest) Execute(resp jobs.Response) {
+// Installs the container, failing with jobs.ErrJobCanceled if ctx is done
+// before the image has been pulled.
+func (req *InstallContainerRequest) ExecuteContext(ctx context.Context, resp jobs.Response) {
 	id := req.Id
+
+	if other, ok := req.Network.Container(); ok && !req.PullOnly {
+		if _, err := os.Stat(other.UnitPathFor()); err != nil {
+			resp.Failure(jobs.NewNotFoundError("The container %s whose network was requested does not exist.", other))
+			return
+		}
+	}
+
+	if !req.PullOnly {
+		for _, link := range req.Links {
+			if _, err := os.Stat(link.Id.UnitPathFor()); err != nil {
+				resp.Failure(jobs.NewNotFoundError("The container %s to link to as %s does not exist.", link.Id, link.Alias))
+				return
+			}
+		}
+	}
+
+	if req.WatchPath != "" && !req.PullOnly {
+		home := id.BaseHomePath()
+		if rel, err := filepath.Rel(home, req.WatchPath); err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			resp.Failure(jobs.NewInvalidError("The watched path %s must be within the home directory of the container, %s.", req.WatchPath, home))
+			return
+		}
+	}
+
+	if req.InheritEnvironment != "" && !req.PullOnly {
+		if _, err := os.Stat(req.InheritEnvironment.EnvironmentPathFor()); err != nil {
+			resp.Failure(jobs.NewNotFoundError("The environment %s to inherit from does not exist.", req.InheritEnvironment))
+			return
+		}
+	}
+
+	if len(req.Secrets) > 0 && !req.PullOnly && containers.RuntimeName == containers.RuntimeDocker && !config.SystemDockerFeatures.EnvironmentFile {
+		resp.Failure(ErrSecretsNotSupported)
+		return
+	}
+
+	if err := req.UnitProperties.CheckSections(); err != nil {
+		resp.Failure(jobs.NewInvalidError("%s", err.Error()))
+		return
+	}
+
+	if len(req.DockerArgs) > 0 && !containers.AllowDockerArgs {
+		resp.Failure(ErrDockerArgsNotAllowed)
+		return
+	}
+
+	if req.EnvFileWatch != "" && !req.PullOnly {
+		if err := containers.CheckEnvFileWatchPath(req.EnvFileWatch); err != nil {
+			resp.Failure(jobs.NewInvalidError("%s", err.Error()))
+			return
+		}
+	}
+
+	if err := req.checkRuntime(); err != nil {
+		resp.Failure(err)
+		return
+	}
+
+	// pull the image before any unit state is touched, so that a failed
+	// pull can be retried without recreating the unit.  The unit pulls it
+	// instead if the pull is deferred until start.
+	if !req.PullAtStart {
+		if err := req.pullImage(ctx); err != nil {
+			if err == jobs.ErrJobCanceled {
+				log.Printf("install_container: Stopped waiting for image %s to be pulled", req.Image)
+				resp.Failure(err)
+				return
+			}
+			if err == ErrImageNotPresent {
+				resp.Failure(err)
+				return
+			}
+			log.Printf("install_container: Unable to pull image %s: %v", req.Image, err)
+			resp.Failure(ErrContainerPullFailed)
+			return
+		}
+	}
+	// an image pulled when the container starts is pulled by the digest it
+	// is pinned to, if any
+	_, digest := containers.SplitImageDigest(req.Image)
+	if !req.PullAtStart {
+		var err error
+		if digest, err = req.resolveImageDigest(); err != nil {
+			resp.Failure(err)
+			return
+		}
+	}
+	if req.PullOnly {
+		if digest != "" {
+			resp.WritePendingSuccess(PendingImageDigestName, digest)
+		}
+		w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+		fmt.Fprintf(w, "Image %s is available for %s\n", req.Image, id)
+		return
+	}
+
 	unitName := id.UnitNameFor()
 	unitPath := id.UnitPathFor()
 	unitVersionPath := id.VersionedUnitPathFor(req.RequestIdentifier.String())
 
 	socketUnitName := id.SocketUnitNameFor()
 	socketUnitPath := id.SocketUnitPathFor()
-	var socketActivationType string
-	if req.SocketActivation {
-		socketActivationType = "enabled"
-		if !req.SkipSocketProxy {
-			socketActivationType = "proxied"
-		}
-	}
 
 	// attempt to download the environment if it is remote
 	env := req.Environment
@@ -89,6 +182,10 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 	reserved, erra := port.AtomicReserveExternalPorts(unitVersionPath, req.Ports, existingPorts)
 	if erra != nil {
 		log.Printf("install_container: Unable to reserve external ports: %+v", erra)
+		if exhausted, ok := erra.(port.ExhaustedError); ok {
+			resp.Failure(portsExhaustedError(exhausted))
+			return
+		}
 		resp.Failure(ErrContainerCreateFailedPortsReserved)
 		return
 	}
@@ -96,21 +193,36 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 		resp.WritePendingSuccess(PendingPortMappingName, reserved)
 	}
 
-	var portSpec string
-	if req.Simple && len(reserved) == 0 {
-		portSpec = "-P"
-	} else {
-		portSpec = dockerPortSpec(reserved)
+	// report the digest, and whether the image the container had resolves
+	// to another digest than it did when the container was installed
+	if digest != "" {
+		resp.WritePendingSuccess(PendingImageDigestName, digest)
+		if exists {
+			image, _ := containers.GetContainerImage(id)
+			previous, _ := containers.GetContainerImageDigest(id)
+			if image == req.Image && previous != "" && previous != digest {
+				resp.WritePendingSuccess(PendingPreviousImageDigestName, previous)
+			}
+		}
 	}
 
 	// write the environment to disk
-	var environmentPath string
 	if env != nil {
 		if errw := env.Write(false); errw != nil {
 			resp.Failure(ErrContainerCreateFailed)
 			return
 		}
-		environmentPath = env.Id.EnvironmentPathFor()
+	}
+
+	// write the secrets (if any) to the run directory, removing any left
+	// from an earlier install
+	if len(req.Secrets) > 0 {
+		if errw := req.Secrets.Write(id.SecretsPathFor()); errw != nil {
+			resp.Failure(ErrContainerCreateFailed)
+			return
+		}
+	} else if err := os.Remove(id.SecretsPathFor()); err != nil && !os.IsNotExist(err) {
+		log.Printf("install_container: Unable to remove secrets: %v", err)
 	}
 
 	// write the network links (if any) to disk
@@ -121,43 +233,26 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 		}
 	}
 
-	slice := "container-small"
-
-	// write the definition unit file
-	args := csystemd.ContainerUnit{
-		Id:       id,
-		Image:    req.Image,
-		PortSpec: portSpec,
-		Slice:    slice + ".slice",
-
-		Isolate: req.Isolate,
-
-		ReqId: req.RequestIdentifier.String(),
-
-		HomeDir:         id.HomePath(),
-		RunDir:          id.RunPathFor(),
-		EnvironmentPath: environmentPath,
-		ExecutablePath:  filepath.Join("/", "usr", "bin", "gear"),
-		IncludePath:     "",
-
-		PortPairs:            reserved,
-		SocketUnitName:       socketUnitName,
-		SocketActivationType: socketActivationType,
-
-		DockerFeatures: config.SystemDockerFeatures,
+	// write the labels (if any) to disk
+	if req.Labels != nil {
+		if errw := req.Labels.Write(id.LabelsPathFor()); errw != nil {
+			resp.Failure(ErrContainerCreateFailed)
+			return
+		}
 	}
 
-	var templateName string
-	switch {
-	case req.SocketActivation:
-		templateName = "SOCKETACTIVATED"
-	case config.SystemDockerFeatures.ForegroundRun:
-		templateName = "FOREGROUND"
-	default:
-		templateName = "SIMPLE"
+	// the slice of a variant is created by its first install
+	if req.Variant != "" {
+		if err := systemd.InitializeSystemdFile(systemd.SliceType, req.SliceName(), csystemd.SliceUnitTemplate, csystemd.SliceUnit{req.SliceName(), "container-small"}, false); err != nil {
+			log.Printf("install_container: Unable to create the slice of variant %s: %v", req.Variant, err)
+			resp.Failure(ErrContainerCreateFailed)
+			return
+		}
 	}
 
-	if erre := csystemd.ContainerUnitTemplate.ExecuteTemplate(unit, templateName, args); erre != nil {
+	// write the definition unit file
+	args := req.Unit(reserved, env, digest)
+	if erre := csystemd.ContainerUnitTemplate.ExecuteTemplate(unit, req.UnitTemplateName(), args); erre != nil {
 		log.Printf("install_container: Unable to output template: %+v", erre)
 		resp.Failure(ErrContainerCreateFailed)
 		defer os.Remove(unitVersionPath)
@@ -178,6 +273,13 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 	}
 	state.Close()
 
+	if err := containers.RecordContainerInstalled(id, time.Now()); err != nil {
+		log.Printf("install_container: Unable to record the install time: %v", err)
+	}
+	if req.Revision != "" {
+		log.Printf("install_container: Installed %s from %s at revision %s (request %s)", id, req.Image, req.Revision, req.RequestIdentifier.String())
+	}
+
 	// write whether this container should be started on next boot
 	if req.Started {
 		if errs := csystemd.SetUnitStartOnBoot(id, true); errs != nil {
@@ -190,17 +292,41 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 	// Generate the socket file and ignore failures
 	paths := []string{unitPath}
 	if req.SocketActivation {
-		if err := writeSocketUnit(socketUnitPath, &args); err == nil {
+		if err := writeTemplateUnit(socketUnitPath, csystemd.ContainerSocketTemplate, &args); err == nil {
 			paths = []string{unitPath, socketUnitPath}
 		}
 	}
 
+	// Generate the path unit that restarts the container, or remove the one
+	// an earlier install created
+	pathUnitName := id.PathUnitNameFor()
+	pathUnitPath := id.PathUnitPathFor()
+	if req.WatchPath != "" {
+		if err := writeTemplateUnit(pathUnitPath, csystemd.ContainerPathTemplate, &args); err != nil {
+			resp.Failure(ErrContainerCreateFailed)
+			return
+		}
+		paths = append(paths, pathUnitPath)
+	} else if err := removePathUnit(id); err != nil {
+		log.Printf("install_container: Unable to remove the path unit: %v", err)
+	}
+
 	if err := systemd.EnableAndReloadUnit(systemd.Connection(), unitName, paths...); err != nil {
 		log.Printf("install_container: Could not enable container %s (%v): %v", unitName, paths, err)
 		resp.Failure(ErrContainerCreateFailed)
 		return
 	}
 
+	if req.WatchPath != "" {
+		if err := systemd.Connection().StartUnitJob(pathUnitName, "replace"); err != nil {
+			log.Printf("install_container: Could not start the path unit %s: %v", pathUnitName, err)
+			resp.Failure(ErrContainerCreateFailed)
+			return
+		}
+	}
+
+	// the attempts to start the container, reported with the result
+	attempts := &bytes.Buffer{}
 	if req.Started {
 		if req.SocketActivation {
 			// Start the socket file, not the service and ignore failures
@@ -209,17 +335,34 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 				resp.Failure(ErrContainerCreateFailed)
 				return
 			}
+		} else if req.StartRetries > 0 {
+			interval := time.Duration(req.StartRetryInterval) * time.Second
+			if err := startUnitWithRetries(ctx, systemd.Connection(), unitName, req.StartRetries, interval, attempts, time.After); err != nil {
+				log.Printf("install_container: Could not start container %s: %v\n%s", unitName, err, attempts.String())
+				if ctx.Err() != nil {
+					resp.Failure(jobs.ErrJobCanceled)
+					return
+				}
+				resp.Failure(jobs.SimpleError{jobs.ResponseError, fmt.Sprintf("The container was installed, but %s", err.Error())})
+				return
+			}
+			if err := containers.RecordContainerStarted(id, time.Now()); err != nil {
+				log.Printf("install_container: Unable to record the start time: %v", err)
+			}
 		} else {
 			if err := systemd.Connection().StartUnitJob(unitName, "replace"); err != nil {
 				log.Printf("install_container: Could not start container %s: %v", unitName, err)
 				resp.Failure(ErrContainerCreateFailed)
 				return
 			}
-
+			if err := containers.RecordContainerStarted(id, time.Now()); err != nil {
+				log.Printf("install_container: Unable to record the start time: %v", err)
+			}
 		}
 	}
 
 	w := resp.SuccessWithWrite(jobs.ResponseAccepted, true, false)
+	attempts.WriteTo(w)
 	if req.Started {
 		fmt.Fprintf(w, "Container %s is starting\n", id)
 	} else {
@@ -227,23 +370,157 @@ func (req *InstallContainerRequest) Execute(resp jobs.Response) {
 	}
 }
 
-func writeSocketUnit(path string, args *csystemd.ContainerUnit) error {
-	socketUnit, err := os.Create(path)
+// Containerd runs the container directly on the host network, without the
+// data container, port mappings, or init process Docker units rely on.
+func (req *InstallContainerRequest) checkRuntime() error {
+	if containers.RuntimeName != containers.RuntimeContainerd || req.PullOnly {
+		return nil
+	}
+	switch {
+	case len(req.Ports) > 0:
+		return jobs.NewInvalidError("Ports cannot be mapped by the containerd runtime, the container shares the host network.")
+	case !req.Network.Default() && req.Network != containers.NetworkHost:
+		return jobs.NewInvalidError("The network %s is not supported by the containerd runtime.", req.Network)
+	case req.SocketActivation:
+		return jobs.NewInvalidError("Socket activation is not supported by the containerd runtime.")
+	case req.Isolate:
+		return jobs.NewInvalidError("Isolated containers are not supported by the containerd runtime.")
+	case req.Entrypoint != "":
+		return jobs.NewInvalidError("The entrypoint cannot be overridden by the containerd runtime.")
+	case req.NetworkLinks != nil && len(*req.NetworkLinks) > 0:
+		return jobs.NewInvalidError("Network links are not supported by the containerd runtime.")
+	case req.EnvReloadSignal != "":
+		return jobs.NewInvalidError("Reloading the environment is not supported by the containerd runtime.")
+	case len(req.DNS) > 0 || len(req.ExtraHosts) > 0:
+		return jobs.NewInvalidError("DNS servers and host entries are not supported by the containerd runtime, the container shares the host network.")
+	case len(req.Links) > 0:
+		return jobs.NewInvalidError("Links to other containers are not supported by the containerd runtime.")
+	case req.LogDriver != "" || len(req.LogOptions) > 0:
+		return jobs.NewInvalidError("Log drivers are not supported by the containerd runtime.")
+	case len(req.Ulimits) > 0:
+		return jobs.NewInvalidError("Ulimits are not supported by the containerd runtime.")
+	case req.CPUSetCPUs != "" || req.CPUSetMems != "":
+		return jobs.NewInvalidError("Cpusets are not supported by the containerd runtime.")
+	case req.PullAtStart:
+		return jobs.NewInvalidError("Pulling the image when the container starts is not supported by the containerd runtime.")
+	case len(req.DockerArgs) > 0:
+		return jobs.NewInvalidError("Docker arguments are not supported by the containerd runtime.")
+	}
+	return nil
+}
+
+// Ensure the image is present in the runtime as the pull policy allows,
+// and record a checkpoint once it is so that a retried install does not
+// need to contact the daemon.  Returns ErrImageNotPresent if the policy is
+// never and the image is missing.
+func (req *InstallContainerRequest) pullImage(ctx context.Context) error {
+	policy := req.PullPolicy.OrDefault()
+	checkpointPath := req.Id.PulledImagePathFor()
+	// only an image pinned to a digest is checkpointed, as the tag of any
+	// other may have moved since it was pulled.  The checkpoint still can't
+	// tell whether the image was removed since.
+	_, pinned := containers.SplitImageDigest(req.Image)
+	if policy == containers.PullIfMissing && pinned != "" {
+		if pulled, err := ioutil.ReadFile(checkpointPath); err == nil && string(pulled) == req.Image {
+			return nil
+		}
+	}
+
+	runtime, err := containers.NewRuntime(req.DockerSocket)
+	if err != nil {
+		return err
+	}
+	if policy == containers.PullNever {
+		present, err := runtime.ImagePresent(req.Image)
+		if err != nil {
+			return err
+		}
+		if !present {
+			return ErrImageNotPresent
+		}
+		return nil
+	}
+
+	// a pull can't be interrupted, if ctx is done first it is left to
+	// finish in the background and a later install finds the image
+	pulled := make(chan error, 1)
+	go func() {
+		if policy == containers.PullAlways {
+			pulled <- runtime.UpdateImage(req.Image)
+		} else {
+			pulled <- runtime.PullImage(req.Image)
+		}
+	}()
+	select {
+	case err := <-pulled:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return jobs.ErrJobCanceled
+	}
+
+	if pinned != "" {
+		if err := ioutil.WriteFile(checkpointPath, []byte(req.Image), 0660); err != nil {
+			log.Printf("install_container: Unable to record pulled image: %v", err)
+		}
+	}
+	return nil
+}
+
+// The digest the pulled image resolved to, if the runtime knows it.  An
+// image pinned to a digest must resolve to that digest, so that a registry
+// serving other content fails the install instead of running it.
+func (req *InstallContainerRequest) resolveImageDigest() (containers.ImageDigest, error) {
+	_, pinned := containers.SplitImageDigest(req.Image)
+
+	runtime, err := containers.NewRuntime(req.DockerSocket)
+	var digests []containers.ImageDigest
+	if err == nil {
+		digests, err = runtime.ImageDigests(req.Image)
+	}
+	if err != nil {
+		log.Printf("install_container: Unable to read the digest of image %s: %v", req.Image, err)
+		if pinned != "" {
+			return "", ErrContainerPullFailed
+		}
+		return "", nil
+	}
+
+	if pinned == "" {
+		if len(digests) > 0 {
+			return digests[0], nil
+		}
+		return "", nil
+	}
+	for _, digest := range digests {
+		if digest == pinned {
+			return pinned, nil
+		}
+	}
+	if len(digests) == 0 {
+		return "", jobs.NewConflictError("The image %s has no digest from a registry to compare with the digest it is pinned to.", req.Image)
+	}
+	return "", jobs.NewConflictError("The image %s resolved to the digest %s instead of the digest it is pinned to.", req.Image, digests[0])
+}
+
+// Write a unit that accompanies the container unit, such as its socket.
+func writeTemplateUnit(path string, t *template.Template, args *csystemd.ContainerUnit) error {
+	unit, err := os.Create(path)
 	if err != nil {
-		log.Print("install_container: Unable to open socket file: ", err)
+		log.Printf("install_container: Unable to open %s: %v", filepath.Base(path), err)
 		return err
 	}
-	defer socketUnit.Close()
+	defer unit.Close()
 
-	socketTemplate := csystemd.ContainerSocketTemplate
-	if err := socketTemplate.Execute(socketUnit, args); err != nil {
-		log.Printf("install_container: Unable to output socket template: %+v", err)
+	if err := t.Execute(unit, args); err != nil {
+		log.Printf("install_container: Unable to output %s template: %+v", t.Name(), err)
 		defer os.Remove(path)
 		return err
 	}
 
-	if err := socketUnit.Close(); err != nil {
-		log.Printf("install_container: Unable to finish writing socket: %+v", err)
+	if err := unit.Close(); err != nil {
+		log.Printf("install_container: Unable to finish writing %s: %+v", filepath.Base(path), err)
 		defer os.Remove(path)
 		return err
 	}
@@ -251,6 +528,24 @@ func writeSocketUnit(path string, args *csystemd.ContainerUnit) error {
 	return nil
 }
 
+// Stop, disable, and remove the path unit of a container, if it has one.
+func removePathUnit(id containers.Identifier) error {
+	path := id.PathUnitPathFor()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := systemd.Connection().StopUnitJob(id.PathUnitNameFor(), "replace"); err != nil {
+		log.Printf("Unable to stop the path unit of %s: %v", id, err)
+	}
+	if _, err := systemd.Connection().DisableUnitFiles([]string{path}, false); err != nil {
+		log.Printf("Unable to disable the path unit of %s: %v", id, err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func (j *InstallContainerRequest) Join(job jobs.Job, complete <-chan bool) (joined bool, done <-chan bool, err error) {
 	if old, ok := job.(*InstallContainerRequest); !ok {
 		if old == nil {
@@ -0,0 +1,1107 @@
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+)
+
+// A fake Docker daemon that only knows about images, and can be told
+// to fail pulls.  The image is known by the digests it was given, however
+// it is named.
+type fakePullBackend struct {
+	failPull bool
+	present  bool
+	pulls    int
+	digests  []string
+}
+
+func (f *fakePullBackend) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == "/info":
+			fmt.Fprintln(w, `{"ExecutionDriver":"native-0.2"}`)
+		case path == "/images/testimage/json" || strings.HasPrefix(path, "/images/testimage@") && strings.HasSuffix(path, "/json"):
+			if !f.present {
+				http.NotFound(w, r)
+				return
+			}
+			refs := make([]string, len(f.digests))
+			for i := range f.digests {
+				refs[i] = "testimage@" + f.digests[i]
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "abcdef", "RepoDigests": refs})
+		case path == "/images/create":
+			f.pulls++
+			if f.failPull {
+				http.Error(w, "connection reset by registry", http.StatusInternalServerError)
+				return
+			}
+			f.present = true
+		default:
+			t.Errorf("Unexpected URL: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func TestInstallPullOnly(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{Id: "test-pull", Image: "testimage", PullOnly: true, DockerSocket: server.URL}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error from pull only install: %v", resp.Error)
+	}
+	if backend.pulls != 1 {
+		t.Errorf("Expected the image to be pulled once, got %d", backend.pulls)
+	}
+	if _, err := ioutil.ReadFile(req.Id.UnitPathFor()); err == nil {
+		t.Error("A pull only install should not create a unit")
+	}
+	if _, err := os.Stat(req.Id.PulledImagePathFor()); !os.IsNotExist(err) {
+		t.Errorf("Expected no checkpoint for a tag, which may move: %v", err)
+	}
+
+	// a tag is looked up again, and pulled only if it is missing
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil || backend.pulls != 1 {
+		t.Fatalf("Expected the present image not to be pulled again, got %v after %d pulls", resp.Error, backend.pulls)
+	}
+}
+
+func TestInstallPullOnlyCheckpointsDigest(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{digests: []string{testImageDigest}}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{Id: "test-pull", Image: "testimage@" + testImageDigest, PullOnly: true, DockerSocket: server.URL}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error from pull only install: %v", resp.Error)
+	}
+	if pulled, err := ioutil.ReadFile(req.Id.PulledImagePathFor()); err != nil || string(pulled) != req.Image {
+		t.Errorf("Expected a checkpoint for the pulled digest, got %q (%v)", string(pulled), err)
+	}
+
+	// the digest is not pulled again
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil || backend.pulls != 1 {
+		t.Fatalf("Expected the checkpoint to be used, got %v after %d pulls", resp.Error, backend.pulls)
+	}
+}
+
+func TestInstallResumesAfterPullFailure(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{failPull: true, digests: []string{testImageDigest}}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{Id: "test-pull", Image: "testimage@" + testImageDigest, PullOnly: true, DockerSocket: server.URL}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+
+	if resp.Error != ErrContainerPullFailed {
+		t.Fatalf("Expected the pull to fail, got %v", resp.Error)
+	}
+	if _, err := ioutil.ReadFile(req.Id.PulledImagePathFor()); err == nil {
+		t.Fatal("A failed pull should not be checkpointed")
+	}
+	if _, err := ioutil.ReadFile(req.Id.UnitPathFor()); err == nil {
+		t.Fatal("A failed pull should not create a unit")
+	}
+
+	backend.failPull = false
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+
+	if resp.Error != nil {
+		t.Fatalf("Expected the retried pull to succeed, got %v", resp.Error)
+	}
+	if backend.pulls != 2 {
+		t.Errorf("Expected the image to be pulled twice, got %d", backend.pulls)
+	}
+	if pulled, err := ioutil.ReadFile(req.Id.PulledImagePathFor()); err != nil || string(pulled) != req.Image {
+		t.Errorf("Expected a checkpoint for the pulled image, got %q (%v)", string(pulled), err)
+	}
+}
+
+func TestInstallPullPolicy(t *testing.T) {
+	defer withContainerBasePath(t)()
+
+	for _, test := range []struct {
+		policy  containers.PullPolicy
+		present bool
+		pulls   int
+		err     error
+	}{
+		{"", false, 1, nil},
+		{"", true, 0, nil},
+		{containers.PullIfMissing, false, 1, nil},
+		{containers.PullIfMissing, true, 0, nil},
+		{containers.PullAlways, false, 1, nil},
+		{containers.PullAlways, true, 1, nil},
+		{containers.PullNever, true, 0, nil},
+		{containers.PullNever, false, 0, ErrImageNotPresent},
+	} {
+		backend := &fakePullBackend{present: test.present}
+		server := httptest.NewServer(backend.handler(t))
+		req := &InstallContainerRequest{Id: "test-policy", Image: "testimage", PullOnly: true, PullPolicy: test.policy, DockerSocket: server.URL}
+		os.Remove(req.Id.PulledImagePathFor())
+		resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+		req.Execute(resp)
+		server.Close()
+
+		if resp.Error != test.err {
+			t.Errorf("Expected the %q policy with the image present=%t to fail with %v, got %v", test.policy, test.present, test.err, resp.Error)
+		}
+		if backend.pulls != test.pulls {
+			t.Errorf("Expected the %q policy with the image present=%t to pull %d times, got %d", test.policy, test.present, test.pulls, backend.pulls)
+		}
+	}
+}
+
+func TestInstallPullPolicyIgnoresCheckpoint(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{digests: []string{testImageDigest}}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{Id: "test-policy", Image: "testimage@" + testImageDigest, PullOnly: true, DockerSocket: server.URL}
+	req.Execute(&cmd.CliJobResponse{Output: ioutil.Discard})
+	req.Execute(&cmd.CliJobResponse{Output: ioutil.Discard})
+	if backend.pulls != 1 {
+		t.Fatalf("Expected the checkpoint to prevent a second pull, got %d pulls", backend.pulls)
+	}
+
+	// the always policy pulls again
+	req.PullPolicy = containers.PullAlways
+	req.Execute(&cmd.CliJobResponse{Output: ioutil.Discard})
+	if backend.pulls != 2 {
+		t.Errorf("Expected the always policy to pull despite the checkpoint, got %d pulls", backend.pulls)
+	}
+
+	// an image removed since the checkpoint is reported
+	backend.present = false
+	req.PullPolicy = containers.PullNever
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != ErrImageNotPresent || backend.pulls != 2 {
+		t.Errorf("Expected the never policy to check the image is present, got %v after %d pulls", resp.Error, backend.pulls)
+	}
+}
+
+func TestInstallPullPolicyNeverCreatesNoUnit(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: "test-policy", Image: "testimage", PullPolicy: containers.PullNever, DockerSocket: server.URL}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != ErrImageNotPresent {
+		t.Fatalf("Expected a missing image to fail the install, got %v", resp.Error)
+	}
+	if _, err := os.Stat(req.Id.UnitPathFor()); !os.IsNotExist(err) {
+		t.Errorf("Expected no unit to be created, got %v", err)
+	}
+}
+
+func TestInstallPullPolicyChecked(t *testing.T) {
+	req := &InstallContainerRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: "test-policy", Image: "testimage", PullPolicy: "sometimes"}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected an unknown pull policy to be rejected, got %v", err)
+	}
+	req.PullPolicy, req.PullAtStart = containers.PullAlways, true
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected the always policy to be rejected when pulling at start, got %v", err)
+	}
+	req.PullPolicy = containers.PullIfMissing
+	if err := req.Check(); err != nil {
+		t.Errorf("Expected the missing policy to be allowed when pulling at start, got %v", err)
+	}
+}
+
+func TestInstallPullAtStart(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	// the image is not present and can't be pulled now
+	backend := &fakePullBackend{failPull: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-pull-later",
+		Image:             "testimage",
+		PullAtStart:       true,
+		DockerSocket:      server.URL,
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the request: %v", err)
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+	if backend.pulls != 0 {
+		t.Errorf("Expected the image not to be pulled during the install, got %d pulls", backend.pulls)
+	}
+	unit, err := ioutil.ReadFile(req.Id.UnitPathFor())
+	if err != nil {
+		t.Fatalf("Expected the unit to be created: %v", err)
+	}
+	if !strings.Contains(string(unit), `|| exec /usr/bin/docker pull "testimage"`) {
+		t.Errorf("Expected the unit to pull the image when it starts, got:\n%s", string(unit))
+	}
+
+	req.PullOnly = true
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected a pull only install that defers the pull to be rejected, got %v", err)
+	}
+}
+
+func TestInstallReportsAssignedPorts(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-ports",
+		Image:             "testimage",
+		Ports:             port.PortPairs{{Internal: 8080}},
+		DockerSocket:      server.URL,
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+	pairs, ok := req.PortMappingsFrom(resp.Pending)
+	if !ok || len(pairs) != 1 {
+		t.Fatalf("Expected the port mappings to be reported, got %+v", resp.Pending)
+	}
+	if pairs[0].Internal != 8080 || pairs[0].External == 0 {
+		t.Errorf("Expected an external port to be assigned to 8080, got %s", pairs)
+	}
+}
+
+func TestInstallScaledInstances(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	units := make(map[string]bool)
+	logins := make(map[string]bool)
+	external := make(map[port.Port]bool)
+	for index := 1; index <= 3; index++ {
+		id, err := containers.NewIndexedIdentifier("web", index)
+		if err != nil {
+			t.Fatalf("Unexpected error creating instance %d: %v", index, err)
+		}
+		req := &InstallContainerRequest{
+			RequestIdentifier: jobs.NewRequestIdentifier(),
+			Id:                id,
+			Image:             "testimage",
+			Ports:             port.PortPairs{{Internal: 8080}},
+			DockerSocket:      server.URL,
+		}
+		resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+		req.Execute(resp)
+		if resp.Error != nil {
+			t.Fatalf("Unexpected error installing %s: %v", id, resp.Error)
+		}
+		if _, err := os.Stat(id.UnitPathFor()); err != nil {
+			t.Errorf("Expected a unit file for %s: %v", id, err)
+		}
+		pairs, ok := req.PortMappingsFrom(resp.Pending)
+		if !ok || len(pairs) != 1 || pairs[0].External == 0 {
+			t.Fatalf("Expected an external port to be assigned to %s, got %+v", id, resp.Pending)
+		}
+		units[id.UnitNameFor()] = true
+		logins[id.LoginFor()] = true
+		external[pairs[0].External] = true
+	}
+
+	if len(units) != 3 || !units["ctr-web-2.service"] {
+		t.Errorf("Expected 3 distinct units, got %v", units)
+	}
+	if len(logins) != 3 {
+		t.Errorf("Expected 3 distinct logins, got %v", logins)
+	}
+	if len(external) != 3 {
+		t.Errorf("Expected 3 distinct external ports, got %v", external)
+	}
+}
+
+func TestInstallNetworkRejectsPorts(t *testing.T) {
+	for _, mode := range []containers.NetworkMode{containers.NetworkHost, containers.NetworkNone, "container:test-db"} {
+		req := &InstallContainerRequest{
+			RequestIdentifier: jobs.NewRequestIdentifier(),
+			Id:                "test-net",
+			Image:             "testimage",
+			Network:           mode,
+			Ports:             port.PortPairs{{Internal: 8080}},
+		}
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected ports to be rejected for the %s network, got %v", mode, err)
+		}
+		req.Ports = nil
+		if err := req.Check(); err != nil {
+			t.Errorf("Expected the %s network to be allowed without ports, got %v", mode, err)
+		}
+	}
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-net",
+		Image:             "testimage",
+		Network:           "container:test-net",
+	}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected a container sharing its own network to be rejected, got %v", err)
+	}
+	req.Network = "overlay"
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected an unknown network mode to be rejected, got %v", err)
+	}
+}
+
+func TestInstallDNSAndHosts(t *testing.T) {
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-dns",
+		Image:             "testimage",
+		DNS:               containers.DNSServers{"10.0.0.2"},
+		ExtraHosts:        containers.HostEntries{{"db", "10.0.0.5"}},
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Expected DNS servers and host entries to be allowed, got %v", err)
+	}
+	for _, mode := range []containers.NetworkMode{containers.NetworkHost, "container:test-db"} {
+		req.Network = mode
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected DNS servers and host entries to be rejected for the %s network, got %v", mode, err)
+		}
+	}
+	req.Network = ""
+
+	req.DNS = containers.DNSServers{"10.0.0.256"}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected an invalid DNS server to be rejected, got %v", err)
+	}
+	req.DNS = nil
+	req.ExtraHosts = containers.HostEntries{{"db", "db.example.com"}}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected a host entry without an IP address to be rejected, got %v", err)
+	}
+}
+
+func TestInstallLogDriver(t *testing.T) {
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-logs",
+		Image:             "testimage",
+		LogDriver:         "json-file",
+		LogOptions:        containers.LogOptions{{"max-size", "10m"}},
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Expected the log driver to be allowed, got %v", err)
+	}
+	req.LogDriver = "custom"
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected an unknown log driver to be rejected, got %v", err)
+	}
+	req.LogDriver = ""
+	req.LogOptions = containers.LogOptions{{"max-size", ""}}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected a log option without a value to be rejected, got %v", err)
+	}
+}
+
+func TestInstallUlimits(t *testing.T) {
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-ulimits",
+		Image:             "testimage",
+		Ulimits:           containers.Ulimits{{"nofile", 1024, 4096}, {"nproc", 512, 512}},
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Expected the ulimits to be allowed, got %v", err)
+	}
+	req.Ulimits = containers.Ulimits{{"files", 1024, 1024}}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected an unknown ulimit to be rejected, got %v", err)
+	}
+	req.Ulimits = containers.Ulimits{{"nofile", 4096, 1024}}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected a soft limit above the hard limit to be rejected, got %v", err)
+	}
+}
+
+func TestInstallInheritEnvironment(t *testing.T) {
+	defer withContainerBasePath(t)()
+	req := &InstallContainerRequest{
+		RequestIdentifier:  jobs.NewRequestIdentifier(),
+		Id:                 "test-child",
+		Image:              "testimage",
+		InheritEnvironment: "test-child",
+	}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected a container inheriting its own environment to be rejected, got %v", err)
+	}
+
+	req.InheritEnvironment = "test-base"
+	if err := req.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the request: %v", err)
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsNotFound(resp.Error) {
+		t.Errorf("Expected a missing environment to inherit from to be reported, got %v", resp.Error)
+	}
+}
+
+func TestInstallDockerArgs(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-docker-args",
+		Image:             "testimage",
+		DockerArgs:        containers.DockerArgs{"--cap-add=NET_ADMIN", "--ulimit", "nofile=1024:2048"},
+		DockerSocket:      server.URL,
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the request: %v", err)
+	}
+
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != ErrDockerArgsNotAllowed {
+		t.Fatalf("Expected docker arguments to be rejected unless the server allows them, got %v", resp.Error)
+	}
+	if _, err := ioutil.ReadFile(req.Id.UnitPathFor()); err == nil {
+		t.Fatal("A rejected install should not create a unit")
+	}
+
+	containers.AllowDockerArgs = true
+	defer func() { containers.AllowDockerArgs = false }()
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+	unit, err := ioutil.ReadFile(req.Id.UnitPathFor())
+	if err != nil {
+		t.Fatalf("Expected the unit to be created: %v", err)
+	}
+	if !strings.Contains(string(unit), " --cap-add=NET_ADMIN --ulimit nofile=1024:2048 ") {
+		t.Errorf("Expected the arguments to be passed to docker run, got:\n%s", string(unit))
+	}
+
+	req.DockerArgs = containers.DockerArgs{"--label=a;rm -rf /"}
+	if err := req.Check(); !jobs.IsInvalid(err) {
+		t.Errorf("Expected an argument with shell metacharacters to be rejected, got %v", err)
+	}
+}
+
+func TestInstallWatchPath(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	id := containers.Identifier("test-watch")
+	watched := filepath.Join(id.BaseHomePath(), "config", "app.yml")
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                id,
+		Image:             "testimage",
+		WatchPath:         watched,
+		DockerSocket:      server.URL,
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the request: %v", err)
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+	if _, err := os.Stat(id.UnitPathFor()); err != nil {
+		t.Errorf("Expected the container unit to be created: %v", err)
+	}
+	unit, err := ioutil.ReadFile(id.PathUnitPathFor())
+	if err != nil {
+		t.Fatalf("Expected the path unit to be created: %v", err)
+	}
+	if !strings.Contains(string(unit), "PathChanged="+watched+"\n") || !strings.Contains(string(unit), "Unit=container-restart@test-watch.service\n") {
+		t.Errorf("Expected the path unit to restart the container when %s changes, got:\n%s", watched, string(unit))
+	}
+
+	// reinstalling without a watched path removes the path unit
+	req.RequestIdentifier = jobs.NewRequestIdentifier()
+	req.WatchPath = ""
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error reinstalling: %v", resp.Error)
+	}
+	if _, err := os.Stat(id.PathUnitPathFor()); !os.IsNotExist(err) {
+		t.Errorf("Expected the path unit to be removed, got %v", err)
+	}
+	if _, err := os.Stat(id.UnitPathFor()); err != nil {
+		t.Errorf("Expected the container unit to remain: %v", err)
+	}
+
+	req.RequestIdentifier = jobs.NewRequestIdentifier()
+	req.WatchPath = filepath.Join(filepath.Dir(id.BaseHomePath()), "other", "app.yml")
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsInvalid(resp.Error) {
+		t.Errorf("Expected a path outside the home directory to be rejected, got %v", resp.Error)
+	}
+	for _, path := range []string{"config/app.yml", watched + "/../../x", watched + "%i"} {
+		req.WatchPath = path
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected the watched path %q to be rejected, got %v", path, err)
+		}
+	}
+}
+
+func TestContainerLogUnreadableDriver(t *testing.T) {
+	defer withContainerBasePath(t)()
+	id := containers.Identifier("test-logs")
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\nX-ContainerId=test-logs\nX-ContainerLogDriver=syslog\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	(&ContainerLogRequest{Id: id}).Execute(resp)
+	if !jobs.IsConflict(resp.Error) || !strings.Contains(resp.Error.Error(), "syslog") {
+		t.Errorf("Expected the logs of a container using syslog to be unavailable, got %v", resp.Error)
+	}
+}
+
+func TestInstallNetworkRequiresContainer(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-net",
+		Image:             "testimage",
+		Network:           "container:test-db",
+		DockerSocket:      server.URL,
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsNotFound(resp.Error) {
+		t.Fatalf("Expected the missing container to be reported, got %v", resp.Error)
+	}
+	if _, err := os.Stat(req.Id.UnitPathFor()); err == nil {
+		t.Error("A container sharing a missing network should not create a unit")
+	}
+}
+
+func TestInstallLinks(t *testing.T) {
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-web",
+		Image:             "testimage",
+		Links:             containers.ContainerAliases{{"test-db", "db"}},
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Expected a link to be allowed, got %v", err)
+	}
+	for _, mode := range []containers.NetworkMode{containers.NetworkHost, containers.NetworkNone, "container:test-db"} {
+		req.Network = mode
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected a link to be rejected for the %s network, got %v", mode, err)
+		}
+	}
+	req.Network = ""
+
+	for _, links := range []containers.ContainerAliases{
+		{{"test-web", "self"}},
+		{{"test-db", "db"}, {"test-cache", "db"}},
+		{{"test-db", "db_1"}},
+	} {
+		req.Links = links
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected the links %s to be rejected, got %v", links, err)
+		}
+	}
+}
+
+func TestInstallLinkRequiresContainer(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-web",
+		Image:             "testimage",
+		Links:             containers.ContainerAliases{{"test-db", "db"}},
+		DockerSocket:      server.URL,
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsNotFound(resp.Error) {
+		t.Fatalf("Expected the missing linked container to be reported, got %v", resp.Error)
+	}
+	if !strings.Contains(resp.Error.Error(), "test-db") {
+		t.Errorf("Expected the error to name the missing container: %v", resp.Error)
+	}
+	if _, err := os.Stat(req.Id.UnitPathFor()); err == nil {
+		t.Error("A container linking to a missing container should not create a unit")
+	}
+}
+
+func TestInstallSecretsNotStored(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	runDir, err := ioutil.TempDir("", "geard-run")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(runDir)
+	previousRun, previousFeatures := config.ContainerRunPath(), config.SystemDockerFeatures
+	config.SetContainerRunPath(runDir)
+	config.SystemDockerFeatures.EnvironmentFile = true
+	defer func() {
+		config.SetContainerRunPath(previousRun)
+		config.SystemDockerFeatures = previousFeatures
+	}()
+
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-secrets",
+		Image:             "testimage",
+		Environment:       &containers.EnvironmentDescription{Id: "test-secrets", Variables: []containers.Environment{{"PLAIN", "visible"}}},
+		Secrets:           containers.Secrets{{"TOKEN", "s3cret"}},
+		DockerSocket:      server.URL,
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Unexpected error checking the request: %v", err)
+	}
+	if strings.Contains(fmt.Sprintf("%+v", req), "s3cret") {
+		t.Error("Expected the secret to be hidden when the request is printed")
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+
+	stored, err := ioutil.ReadFile(req.Environment.Id.EnvironmentPathFor())
+	if err != nil || !strings.Contains(string(stored), "PLAIN=visible") {
+		t.Fatalf("Expected the environment to be stored, got %q %v", string(stored), err)
+	}
+	if strings.Contains(string(stored), "s3cret") || strings.Contains(string(stored), "TOKEN") {
+		t.Errorf("Expected the secret to not be in the stored environment, got %q", string(stored))
+	}
+
+	secretsPath := req.Id.SecretsPathFor()
+	info, err := os.Stat(secretsPath)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected a secrets file only the owner can read, got %v %v", info, err)
+	}
+	if data, _ := ioutil.ReadFile(secretsPath); string(data) != "TOKEN=s3cret\n" {
+		t.Errorf("Unexpected secrets file %q", string(data))
+	}
+	unit, _ := ioutil.ReadFile(req.Id.UnitPathFor())
+	if !strings.Contains(string(unit), `--env-file "`+secretsPath+`"`) || strings.Contains(string(unit), "s3cret") {
+		t.Errorf("Expected the unit to pass the secrets file to docker, got:\n%s", string(unit))
+	}
+
+	// reinstalling without secrets removes them
+	req.RequestIdentifier = jobs.NewRequestIdentifier()
+	req.Secrets = nil
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error reinstalling: %v", resp.Error)
+	}
+	if _, err := os.Stat(secretsPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the secrets to be removed, got %v", err)
+	}
+}
+
+func TestInstallContainerResponseJSON(t *testing.T) {
+	req := &InstallContainerRequest{Id: "test-json", Image: "testimage", Started: true}
+
+	pending := map[string]interface{}{PendingPortMappingName: port.PortPairs{{Internal: 8080, External: 4000}}}
+	data, _ := json.Marshal(req.ResponseFor("host:43273", pending, nil))
+	if expected := `{"Id":"test-json","Server":"host:43273","Image":"testimage","Ports":[{"Internal":8080,"External":4000}],"Started":true}`; string(data) != expected {
+		t.Errorf("Unexpected success response\n%s\nexpected\n%s", string(data), expected)
+	}
+
+	data, _ = json.Marshal(req.ResponseFor("", nil, ErrContainerCreateFailed))
+	if expected := `{"Id":"test-json","Image":"testimage","Ports":[],"Started":false,"Error":"Unable to create container."}`; string(data) != expected {
+		t.Errorf("Unexpected failure response\n%s\nexpected\n%s", string(data), expected)
+	}
+}
+
+const (
+	testImageDigest      = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	testOtherImageDigest = "sha256:60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+)
+
+func requireStubSystemd(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+}
+
+func TestInstallImageDigest(t *testing.T) {
+	for _, image := range []string{"testimage@sha256:abc", "@" + testImageDigest, "testimage@md5:" + testImageDigest[7:]} {
+		req := &InstallContainerRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: "test-digest", Image: image}
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected the image %s to be rejected, got %v", image, err)
+		}
+	}
+
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true, digests: []string{testOtherImageDigest, testImageDigest}}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-digest",
+		Image:             "testimage@" + testImageDigest,
+		DockerSocket:      server.URL,
+	}
+	if err := req.Check(); err != nil {
+		t.Fatalf("Expected an image pinned to a digest to be allowed, got %v", err)
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Expected the pinned digest to match, got %v", resp.Error)
+	}
+	if digest := resp.Pending[PendingImageDigestName]; digest != containers.ImageDigest(testImageDigest) {
+		t.Errorf("Expected the install to report the pinned digest, got %v", digest)
+	}
+	if digest, err := containers.GetContainerImageDigest(req.Id); err != nil || digest != testImageDigest {
+		t.Errorf("Expected the unit to record the digest, got %q (%v)", digest, err)
+	}
+}
+
+func TestInstallImageDigestMismatch(t *testing.T) {
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true, digests: []string{testOtherImageDigest}}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-digest",
+		Image:             "testimage@" + testImageDigest,
+		DockerSocket:      server.URL,
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsConflict(resp.Error) || !strings.Contains(resp.Error.Error(), testOtherImageDigest) {
+		t.Fatalf("Expected the install to fail with the digest the image resolved to, got %v", resp.Error)
+	}
+	if _, err := os.Stat(req.Id.UnitPathFor()); !os.IsNotExist(err) {
+		t.Errorf("Expected no unit to be created for a mismatched digest, got %v", err)
+	}
+
+	backend.digests = nil
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsConflict(resp.Error) {
+		t.Errorf("Expected an image without a digest to fail a pinned install, got %v", resp.Error)
+	}
+}
+
+func TestInstallReportsChangedImageDigest(t *testing.T) {
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true, digests: []string{testImageDigest}}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-digest",
+		Image:             "testimage",
+		DockerSocket:      server.URL,
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+	if _, ok := resp.Pending[PendingPreviousImageDigestName]; ok {
+		t.Error("Expected no previous digest for a new container")
+	}
+
+	// the tag was moved to another image
+	backend.digests = []string{testOtherImageDigest}
+	req.RequestIdentifier = jobs.NewRequestIdentifier()
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error reinstalling: %v", resp.Error)
+	}
+	r := req.ResponseFor("", resp.Pending, nil)
+	if r.ImageDigest != testOtherImageDigest || r.PreviousImageDigest != testImageDigest {
+		t.Errorf("Expected the reinstall to report the digest changed, got %+v", r)
+	}
+	if digest, _ := containers.GetContainerImageDigest(req.Id); digest != testOtherImageDigest {
+		t.Errorf("Expected the unit to record the new digest, got %q", digest)
+	}
+}
+
+func TestInstallVariantsGroupedInStatus(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Installing a unit requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	if err := os.MkdirAll(filepath.Join(config.ContainerBasePath(), "slices"), 0750); err != nil {
+		t.Fatalf("Unable to create the slices directory: %v", err)
+	}
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+	// the containers are installed but not running
+	stopped := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			fmt.Fprintln(w, `{"ExecutionDriver":"native-0.2"}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer stopped.Close()
+
+	canary, err := containers.NewVariantIdentifier("test-web", "canary")
+	if err != nil {
+		t.Fatalf("Unexpected error naming the variant: %v", err)
+	}
+	installs := []*InstallContainerRequest{
+		{Id: canary, Variant: "canary", Image: "testimage"},
+		{Id: "test-web", Image: "testimage"},
+		{Id: "test-api", Image: "testimage"},
+	}
+	statuses := ContainerStatusResponses{}
+	for _, req := range installs {
+		req.RequestIdentifier = jobs.NewRequestIdentifier()
+		req.DockerSocket = server.URL
+		if err := req.Check(); err != nil {
+			t.Fatalf("Unexpected error checking %s: %v", req.Id, err)
+		}
+		resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+		req.Execute(resp)
+		if resp.Error != nil {
+			t.Fatalf("Unexpected error installing %s: %v", req.Id, resp.Error)
+		}
+
+		status := &ContainerStatusRequest{Id: req.Id, Structured: true, DockerSocket: stopped.URL}
+		statusResp := &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+		status.Execute(statusResp)
+		if statusResp.Error != nil {
+			t.Fatalf("Unexpected error reading the status of %s: %v", req.Id, statusResp.Error)
+		}
+		statuses = append(statuses, *statusResp.Data.(*ContainerStatusResponse))
+	}
+
+	unit, err := ioutil.ReadFile(canary.UnitPathFor())
+	if err != nil || !strings.Contains(string(unit), "Slice=container-small-canary.slice") {
+		t.Errorf("Expected the canary to run in a slice of its own, got %v:\n%s", err, unit)
+	}
+	if unit, _ := ioutil.ReadFile(containers.Identifier("test-web").UnitPathFor()); !strings.Contains(string(unit), "Slice=container-small.slice") {
+		t.Errorf("Expected the stable container to run in the shared slice:\n%s", unit)
+	}
+	if _, err := os.Stat(filepath.Join(config.ContainerBasePath(), "slices", "container-small-canary.slice")); err != nil {
+		t.Errorf("Expected the slice of the canary to be created: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	statuses.WriteGroupedTableTo(buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a row for each container:\n%s", buf.String())
+	}
+	for i, expected := range [][]string{{"NAME", "VARIANT"}, {"test-api", "-"}, {"test-web", "-"}, {"test-web", "canary"}} {
+		if fields := strings.Fields(lines[i]); len(fields) < 2 || fields[0] != expected[0] || fields[1] != expected[1] {
+			t.Errorf("Expected row %d to start with %v:\n%s", i, expected, buf.String())
+		}
+	}
+}
+
+func TestInstallVariantMustMatchId(t *testing.T) {
+	req := &InstallContainerRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: "test-web", Variant: "canary", Image: "testimage"}
+	if err := req.Check(); err == nil || !strings.Contains(err.Error(), "must end in --canary") {
+		t.Errorf("Expected an id without the variant to be rejected, got %v", err)
+	}
+}
+
+func TestInstallRevision(t *testing.T) {
+	for _, revision := range []containers.Revision{"-v1", "release 2", containers.Revision(strings.Repeat("a", 129))} {
+		req := &InstallContainerRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: "test-revision", Image: "testimage", Revision: revision}
+		if err := req.Check(); !jobs.IsInvalid(err) {
+			t.Errorf("Expected the revision %q to be rejected, got %v", revision, err)
+		}
+	}
+
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+	stats := fakeStatsServer(t)
+	defer stats.Close()
+
+	status := func(id containers.Identifier) *ContainerStatusResponse {
+		resp := &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+		(&ContainerStatusRequest{Id: id, Structured: true, DockerSocket: stats.URL}).Execute(resp)
+		if resp.Error != nil {
+			t.Fatalf("Unable to read the status: %v", resp.Error)
+		}
+		return resp.Data.(*ContainerStatusResponse)
+	}
+
+	for _, revision := range []containers.Revision{"v1.4.2", "release/2014-06-01+a1b2c3d", ""} {
+		req := &InstallContainerRequest{
+			RequestIdentifier: jobs.NewRequestIdentifier(),
+			Id:                "test-status",
+			Image:             "testimage",
+			Revision:          revision,
+			DockerSocket:      server.URL,
+		}
+		if err := req.Check(); err != nil {
+			t.Fatalf("Expected the revision %q to be allowed, got %v", revision, err)
+		}
+		resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+		req.Execute(resp)
+		if resp.Error != nil {
+			t.Fatalf("Unable to install: %v", resp.Error)
+		}
+
+		if r := status(req.Id); r.Revision != revision {
+			t.Errorf("Expected the status to report the revision %q of the last install, got %q", revision, r.Revision)
+		}
+		if m := containerMetadata(req.Id); m.Revision != revision {
+			t.Errorf("Expected inspect to report the revision %q, got %q", revision, m.Revision)
+		}
+	}
+}
+
+func TestInstallEnvFileWatchOutsideWatchDir(t *testing.T) {
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	watchDir := filepath.Join(config.ContainerBasePath(), "secrets")
+	os.MkdirAll(watchDir, 0700)
+	defer func() { containers.EnvFileWatchDir = "" }()
+
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                "test-env-watch",
+		Image:             "testimage",
+		EnvFileWatch:      "/var/lib/containers/env/contents/te/test-db",
+		DockerSocket:      server.URL,
+	}
+	for _, dir := range []string{"", watchDir} {
+		containers.EnvFileWatchDir = dir
+		resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+		req.Execute(resp)
+		if !jobs.IsInvalid(resp.Error) {
+			t.Errorf("Expected a file outside the watch directory %q to be rejected, got %v", dir, resp.Error)
+		}
+		if _, err := os.Stat(req.Id.UnitPathFor()); err == nil {
+			t.Fatal("A rejected install should not create a unit")
+		}
+	}
+
+	req.EnvFileWatch = filepath.Join(watchDir, "test-env-watch.env")
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing: %v", resp.Error)
+	}
+}
@@ -0,0 +1,198 @@
+package jobs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	csystemd "github.com/openshift/geard/containers/systemd"
+	"github.com/openshift/geard/port"
+)
+
+func dockerPortSpec(p port.PortPairs) string {
+	var portSpec bytes.Buffer
+	for i := range p {
+		portSpec.WriteString(fmt.Sprintf("-p %d:%d ", p[i].External, p[i].Internal))
+	}
+	return portSpec.String()
+}
+
+// The arguments of the unit that runs the container, given the ports
+// reserved for it, the environment written for it, if any, and the digest
+// its image resolved to, if known.  Reads only the request and the
+// configuration of this process, so the unit can be generated without a
+// daemon.
+func (req *InstallContainerRequest) Unit(reserved port.PortPairs, env *containers.EnvironmentDescription, digest containers.ImageDigest) csystemd.ContainerUnit {
+	id := req.Id
+
+	var socketActivationType string
+	if req.SocketActivation {
+		socketActivationType = "enabled"
+		if !req.SkipSocketProxy {
+			socketActivationType = "proxied"
+		}
+	}
+
+	var portSpec string
+	switch {
+	case !req.Network.AllowsPorts():
+		// the container has no ports of its own
+	case req.Simple && len(reserved) == 0:
+		portSpec = "-P"
+	default:
+		portSpec = dockerPortSpec(reserved)
+	}
+
+	var environmentPath, environmentKeyPath string
+	var encryptedEnvironment containers.Identifier
+	if env != nil {
+		environmentPath = env.Id.EnvironmentPathFor()
+		if keys := containers.EnvironmentEncryptionKeys; keys != nil {
+			// the container reads a decrypted copy from its run directory
+			environmentPath = filepath.Join(id.RunPathFor(), "environment")
+			environmentKeyPath = keys.Path
+			encryptedEnvironment = env.Id
+		}
+	}
+	// the container reads its own environment merged over the inherited one,
+	// which is written to the run directory when it starts
+	var ownEnvironment containers.Identifier
+	if req.InheritEnvironment != "" {
+		ownEnvironment = id
+		if env != nil && env.Id != "" {
+			ownEnvironment = env.Id
+		}
+		environmentPath = filepath.Join(id.RunPathFor(), "environment")
+		if keys := containers.EnvironmentEncryptionKeys; keys != nil {
+			environmentKeyPath = keys.Path
+		}
+	}
+	var reloadEnvironmentPath string
+	if req.EnvReloadSignal != "" {
+		reloadEnvironmentPath = id.ReloadEnvironmentPathFor()
+		if keys := containers.EnvironmentEncryptionKeys; keys != nil {
+			environmentKeyPath = keys.Path
+		}
+	}
+
+	var secretsPath string
+	if len(req.Secrets) > 0 {
+		secretsPath = id.SecretsPathFor()
+	}
+	// Docker needs --env-file to read overrides of the environment at start
+	var startEnvironmentPath string
+	if containers.RuntimeName != containers.RuntimeDocker || config.SystemDockerFeatures.EnvironmentFile {
+		startEnvironmentPath = id.StartEnvironmentPathFor()
+	}
+
+	return csystemd.ContainerUnit{
+		Id:       id,
+		Image:    req.Image,
+		PortSpec: portSpec,
+		Slice:    req.SliceName() + ".slice",
+
+		Description:   req.Description,
+		Documentation: req.Documentation,
+
+		Isolate: req.Isolate,
+
+		ReqId:       req.RequestIdentifier.String(),
+		ImageDigest: digest,
+		Revision:    req.Revision,
+
+		HomeDir:         id.HomePath(),
+		RunDir:          id.RunPathFor(),
+		EnvironmentPath: environmentPath,
+		ExecutablePath:  filepath.Join("/", "usr", "bin", "gear"),
+		IncludePath:     "",
+
+		EncryptedEnvironment: encryptedEnvironment,
+		EnvironmentKeyPath:   environmentKeyPath,
+		InheritEnvironment:   req.InheritEnvironment,
+		OwnEnvironment:       ownEnvironment,
+
+		SecretsPath:          secretsPath,
+		StartEnvironmentPath: startEnvironmentPath,
+		PullAtStart:          req.PullAtStart,
+
+		EnvReloadSignal:       req.EnvReloadSignal,
+		ReloadEnvironmentPath: reloadEnvironmentPath,
+
+		PortPairs:            reserved,
+		SocketUnitName:       id.SocketUnitNameFor(),
+		SocketActivationType: socketActivationType,
+
+		WatchPath: req.WatchPath,
+
+		EnvFileWatch:        req.EnvFileWatch,
+		EnvFileWatchRestart: req.EnvFileWatchRestart,
+
+		StopTimeout: req.stopTimeout(),
+
+		NetworkMode: req.Network,
+		DNS:         req.DNS,
+		ExtraHosts:  req.ExtraHosts,
+		Links:       req.Links,
+
+		LogDriver:  req.LogDriver,
+		LogOptions: req.LogOptions,
+		Ulimits:    req.Ulimits,
+		CPUSetCPUs: req.CPUSetCPUs,
+		CPUSetMems: req.CPUSetMems,
+
+		Entrypoint: req.Entrypoint,
+		Cmd:        req.Cmd,
+		WorkingDir: req.WorkingDir,
+
+		Properties: req.UnitProperties,
+		DockerArgs: req.DockerArgs,
+
+		DockerFeatures: config.SystemDockerFeatures,
+
+		ContainerdCommand: containers.ContainerdCommand,
+		ContainerdAddress: containers.ContainerdAddress,
+	}
+}
+
+// Seconds the container is given to exit before it is killed.
+func (req *InstallContainerRequest) stopTimeout() int {
+	if req.StopTimeout == nil {
+		return DefaultStopTimeout
+	}
+	return *req.StopTimeout
+}
+
+// The slice the container runs in.  Each variant of a container has a
+// slice of its own, within the slice of every other container, so that
+// the resources used by a canary can be told apart.
+func (req *InstallContainerRequest) SliceName() string {
+	if req.Variant != "" {
+		return "container-small-" + req.Variant
+	}
+	return "container-small"
+}
+
+// The template of csystemd.ContainerUnitTemplate the unit of the container
+// is generated from.
+func (req *InstallContainerRequest) UnitTemplateName() string {
+	switch {
+	case containers.RuntimeName == containers.RuntimeContainerd:
+		return "CONTAINERD"
+	case req.SocketActivation:
+		return "SOCKETACTIVATED"
+	case config.SystemDockerFeatures.ForegroundRun:
+		return "FOREGROUND"
+	default:
+		return "SIMPLE"
+	}
+}
+
+// Write the unit file that runs the container to w, exactly as the install
+// would.
+func (req *InstallContainerRequest) WriteUnit(w io.Writer, reserved port.PortPairs, env *containers.EnvironmentDescription, digest containers.ImageDigest) error {
+	args := req.Unit(reserved, env, digest)
+	return csystemd.ContainerUnitTemplate.ExecuteTemplate(w, req.UnitTemplateName(), args)
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/port"
+)
+
+// The base path units are compared with, in place of the temporary one
+// the test writes to
+const goldenUnitBasePath = "/var/lib/containers"
+
+var goldenUnits = map[string]*InstallContainerRequest{
+	"simple": {
+		Id:      "test-web",
+		Image:   "openshift/busybox-http-app",
+		Started: true,
+		Ports:   port.PortPairs{{Internal: 8080, External: 14000}},
+	},
+	"isolated-env-limits": {
+		Id:      "test-web",
+		Image:   "openshift/busybox-http-app",
+		Isolate: true,
+		Ports:   port.PortPairs{{Internal: 8080, External: 14000}, {Internal: 8443, External: 0}},
+		Environment: &containers.EnvironmentDescription{
+			Id:        "test-env",
+			Variables: []containers.Environment{{Name: "MODE", Value: "production"}},
+		},
+		UnitProperties: containers.UnitProperties{
+			{Section: "Service", Key: "MemoryLimit", Value: "512M"},
+			{Section: "Service", Key: "CPUShares", Value: "256"},
+			{Section: "Service", Key: "Restart", Value: "always"},
+		},
+	},
+	"network-overrides": {
+		Id:          "test-web",
+		Image:       "openshift/busybox-http-app",
+		Network:     containers.NetworkBridge,
+		DNS:         containers.DNSServers{"10.0.0.2"},
+		ExtraHosts:  containers.HostEntries{{Name: "db.local", IP: "10.0.0.3"}},
+		Links:       containers.ContainerAliases{{Id: "test-db", Alias: "db"}},
+		LogDriver:   "json-file",
+		LogOptions:  containers.LogOptions{{Key: "max-size", Value: "10m"}},
+		Entrypoint:  "/bin/sh",
+		Cmd:         []string{"-c", "echo \"hello world\""},
+		WorkingDir:  "/srv",
+		Secrets:     containers.Secrets{{Name: "TOKEN", Value: "secret"}},
+		PullAtStart: true,
+	},
+	"pinned-digest": {
+		Id:    "test-web",
+		Image: "openshift/busybox-http-app@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Ports: port.PortPairs{{Internal: 8080, External: 14000}},
+	},
+	"stop-timeout": {
+		Id:          "test-web",
+		Image:       "openshift/busybox-http-app",
+		Ports:       port.PortPairs{{Internal: 8080, External: 14000}},
+		StopTimeout: new(int),
+	},
+	"socket-activated": {
+		Id:               "test-web",
+		Image:            "openshift/busybox-http-app",
+		SocketActivation: true,
+		Ports:            port.PortPairs{{Internal: 8080, External: 14000}},
+		EnvReloadSignal:  "HUP",
+	},
+}
+
+func TestWriteUnitMatchesGoldenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-units")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+	features := config.SystemDockerFeatures
+	config.SystemDockerFeatures = config.DockerFeatures{EnvironmentFile: true}
+	defer func() {
+		config.SetContainerBasePath(previous)
+		config.SystemDockerFeatures = features
+		os.RemoveAll(dir)
+	}()
+
+	for name, req := range goldenUnits {
+		req.RequestIdentifier = []byte("0123456789abcdef")
+		_, digest := containers.SplitImageDigest(req.Image)
+		buf := &bytes.Buffer{}
+		if err := req.WriteUnit(buf, req.Ports, req.Environment, digest); err != nil {
+			t.Errorf("Unable to write the %s unit: %v", name, err)
+			continue
+		}
+		unit := strings.Replace(buf.String(), dir, goldenUnitBasePath, -1)
+
+		expected, err := ioutil.ReadFile(filepath.Join("fixtures", "units", name+".service"))
+		if err != nil {
+			t.Errorf("Unable to read the %s golden file: %v", name, err)
+			continue
+		}
+		if unit != string(expected) {
+			t.Errorf("Expected the %s unit to match its golden file, got:\n%s", name, unit)
+		}
+	}
+}
+
+func TestUnitTemplateName(t *testing.T) {
+	features := config.SystemDockerFeatures
+	defer func() { config.SystemDockerFeatures = features }()
+
+	config.SystemDockerFeatures = config.DockerFeatures{}
+	if name := goldenUnits["simple"].UnitTemplateName(); name != "SIMPLE" {
+		t.Errorf("Expected a simple unit, got %s", name)
+	}
+	if name := goldenUnits["socket-activated"].UnitTemplateName(); name != "SOCKETACTIVATED" {
+		t.Errorf("Expected a socket activated unit, got %s", name)
+	}
+	config.SystemDockerFeatures.ForegroundRun = true
+	if name := goldenUnits["simple"].UnitTemplateName(); name != "FOREGROUND" {
+		t.Errorf("Expected a foreground unit, got %s", name)
+	}
+}
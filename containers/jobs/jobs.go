@@ -5,8 +5,12 @@
 package jobs
 
 import (
-	"errors"
+	"io"
 	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/jobs"
@@ -55,6 +59,11 @@ type InstallContainerRequest struct {
 	Id    containers.Identifier
 	Image string
 
+	// The variant of a container this is, such as "canary".  The id must
+	// end in the variant, as created by containers.NewVariantIdentifier,
+	// and the container runs in a slice of its own.
+	Variant string `json:"Variant,omitempty"`
+
 	// A simple container is allowed to default to normal Docker
 	// options like -P.  If simple is true no user or home
 	// directory is created and SSH is not available
@@ -74,8 +83,106 @@ type InstallContainerRequest struct {
 	Environment  *containers.EnvironmentDescription
 	NetworkLinks *containers.NetworkLinks
 
+	// A stored environment the container inherits variables from.  It is
+	// read each time the container starts, and variables in the container's
+	// own environment replace those of the same name.
+	InheritEnvironment containers.Identifier `json:"InheritEnvironment,omitempty"`
+
+	// Passed to the container when it starts but never written to the
+	// environment store
+	Secrets containers.Secrets `json:"Secrets,omitempty"`
+
+	// Labels used to select the container, replacing any it already has
+	Labels containers.Labels `json:"Labels,omitempty"`
+
+	// The network the container is attached to, bridge by default
+	Network containers.NetworkMode `json:"Network,omitempty"`
+	// Name servers and /etc/hosts entries for the container, which must
+	// have a network of its own
+	DNS        containers.DNSServers  `json:"DNS,omitempty"`
+	ExtraHosts containers.HostEntries `json:"ExtraHosts,omitempty"`
+	// Docker links to other containers on the same host, which must
+	// already be installed.  The container reaches each by its alias.
+	Links containers.ContainerAliases `json:"Links,omitempty"`
+
+	// The Docker logging driver and its options, such as json-file with
+	// max-size and max-file to rotate the output of the container
+	LogDriver  containers.LogDriver  `json:"LogDriver,omitempty"`
+	LogOptions containers.LogOptions `json:"LogOptions,omitempty"`
+
+	// Resource limits on the processes of the container, such as the
+	// number of files they may open
+	Ulimits containers.Ulimits `json:"Ulimits,omitempty"`
+	// The CPUs and memory nodes the container may run on, any if empty
+	CPUSetCPUs containers.CPUSet `json:"CPUSetCPUs,omitempty"`
+	CPUSetMems containers.CPUSet `json:"CPUSetMems,omitempty"`
+
+	// The description and documentation URIs of the generated unit, shown
+	// by systemctl status.  The description is "Container <id>" if empty.
+	Description   containers.UnitDescription   `json:"Description,omitempty"`
+	Documentation containers.UnitDocumentation `json:"Documentation,omitempty"`
+
 	// Should the container be started by default
 	Started bool
+	// Start the container again if it fails to start, up to this many more
+	// times, waiting StartRetryInterval seconds between attempts.  The
+	// install waits for the result of each start instead of only queueing
+	// it.
+	StartRetries       int `json:"StartRetries,omitempty"`
+	StartRetryInterval int `json:"StartRetryInterval,omitempty"`
+
+	// Seconds the container is given to exit when it is stopped before it
+	// is killed, by systemd and by a stop without a timeout of its own.
+	// Defaults to DefaultStopTimeout, zero kills it immediately.
+	StopTimeout *int `json:"StopTimeout,omitempty"`
+
+	// Override the entrypoint, command, and working directory of the
+	// image.  The command is passed to the entrypoint exactly as given.
+	Entrypoint string   `json:"Entrypoint,omitempty"`
+	Cmd        []string `json:"Cmd,omitempty"`
+	WorkingDir string   `json:"WorkingDir,omitempty"`
+
+	// Additional directives for the generated unit, limited to the
+	// sections the server allows
+	UnitProperties containers.UnitProperties `json:"UnitProperties,omitempty"`
+
+	// Arguments appended verbatim to docker run, accepted only if the
+	// server allows them
+	DockerArgs containers.DockerArgs `json:"DockerArgs,omitempty"`
+
+	// The signal, such as "SIGHUP", sent to the container when its
+	// environment is changed with a reload.  The container reads its
+	// current environment from a file under containers.EnvReloadMountPath.
+	EnvReloadSignal string `json:"EnvReloadSignal,omitempty"`
+
+	// Restart the container whenever this file or directory changes.  The
+	// path must be within the home directory geard keeps for the container.
+	WatchPath string `json:"WatchPath,omitempty"`
+
+	// A file on the host of environment variables, such as secrets rotated
+	// by an external agent.  The daemon watches it and patches the
+	// environment of the container whenever its variables change.
+	EnvFileWatch string `json:"EnvFileWatch,omitempty"`
+	// Restart the container, if it is running, after the watched file is
+	// applied, instead of signalling it to reload its environment
+	EnvFileWatchRestart bool `json:"EnvFileWatchRestart,omitempty"`
+
+	// Defer pulling the image until the container is started, instead of
+	// during the install.  An image that is already present is not pulled.
+	PullAtStart bool `json:"PullAtStart,omitempty"`
+
+	// Whether the install pulls the image if it is already present, or
+	// pulls it at all.  Defaults to pulling it only if it is missing.
+	PullPolicy containers.PullPolicy `json:"PullPolicy,omitempty"`
+
+	// The deployment or release the container is installed from, shown by
+	// status and inspect until the container is reinstalled
+	Revision containers.Revision `json:"Revision,omitempty"`
+
+	// Only download the image, leaving any existing unit untouched
+	PullOnly bool
+	// The Docker daemon the image is pulled into
+	DockerSocket string `json:"-"`
 }
 
 func (req *InstallContainerRequest) Check() error {
@@ -83,32 +190,306 @@ func (req *InstallContainerRequest) Check() error {
 		req.SocketActivation = false
 	}
 	if len(req.RequestIdentifier) == 0 {
-		return errors.New("A request identifier is required to create this item.")
+		return jobs.NewInvalidError("A request identifier is required to create this item.")
 	}
 	if req.Image == "" {
-		return errors.New("A container must have an image identifier")
+		return jobs.NewInvalidError("A container must have an image identifier")
+	}
+	if name, digest := containers.SplitImageDigest(req.Image); name == "" {
+		return jobs.NewInvalidError("The image %s must be named before its digest.", req.Image)
+	} else if digest != "" {
+		if err := digest.Check(); err != nil {
+			return jobs.NewInvalidError("%s", err.Error())
+		}
+	}
+	if req.PullOnly && req.PullAtStart {
+		return jobs.NewInvalidError("An install can't both only pull the image and defer pulling it until the container starts.")
+	}
+	if err := req.PullPolicy.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if req.PullAtStart && req.PullPolicy.OrDefault() != containers.PullIfMissing {
+		return jobs.NewInvalidError("An image pulled when the container starts is only pulled if it is missing, the pull policy '%s' can't be used.", req.PullPolicy)
 	}
 	if req.Environment != nil && !req.Environment.Empty() {
 		if err := req.Environment.Check(); err != nil {
 			return err
 		}
 		if req.Environment.Id == containers.InvalidIdentifier {
-			return errors.New("You must specify an environment identifier on creation.")
+			return jobs.NewInvalidError("You must specify an environment identifier on creation.")
 		}
 	}
+	if err := req.Secrets.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.checkInheritEnvironment(); err != nil {
+		return err
+	}
 	if req.NetworkLinks != nil {
 		if err := req.NetworkLinks.Check(); err != nil {
 			return err
 		}
 	}
+	if err := req.Labels.Check(); err != nil {
+		return err
+	}
+	if err := req.checkNetwork(); err != nil {
+		return err
+	}
+	if err := req.checkOverrides(); err != nil {
+		return err
+	}
+	if err := req.LogDriver.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.Revision.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.LogOptions.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.Ulimits.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.CPUSetCPUs.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.CPUSetMems.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.Description.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.Documentation.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.UnitProperties.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.DockerArgs.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.checkEnvReload(); err != nil {
+		return err
+	}
+	if err := req.checkWatchPath(); err != nil {
+		return err
+	}
+	if err := req.checkEnvFileWatch(); err != nil {
+		return err
+	}
+	if err := req.checkStartRetries(); err != nil {
+		return err
+	}
+	if req.StopTimeout != nil && *req.StopTimeout < 0 {
+		return jobs.NewInvalidError("The stop timeout must be zero or more seconds.")
+	}
+	if req.Variant != "" {
+		if _, variant := req.Id.Variant(); variant != req.Variant {
+			return jobs.NewInvalidError("The id of the %s variant of a container must end in %s%s", req.Variant, containers.VariantSeparator, req.Variant)
+		}
+	}
 	if req.Ports == nil {
 		req.Ports = make([]port.PortPair, 0)
 	}
 	return nil
 }
 
+func (req *InstallContainerRequest) checkNetwork() error {
+	if err := req.Network.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.DNS.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.ExtraHosts.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	if err := req.Links.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	for i := range req.Links {
+		if req.Links[i].Id == req.Id {
+			return jobs.NewInvalidError("A container can't link to itself.")
+		}
+	}
+	if len(req.Links) > 0 && (!req.Network.Default() || req.SocketActivation) {
+		return jobs.NewInvalidError("Links to other containers require the bridge network and can't be used by socket activated containers.")
+	}
+	if !req.Network.AllowsResolverConfig() && (len(req.DNS) > 0 || len(req.ExtraHosts) > 0) {
+		return jobs.NewInvalidError("DNS servers and host entries can't be set for a container using the %s network mode, which does not have its own resolver configuration.", req.Network)
+	}
+	if req.Network.AllowsPorts() {
+		return nil
+	}
+	if len(req.Ports) > 0 {
+		return jobs.NewInvalidError("Ports can't be mapped for a container using the %s network mode, remove the port mappings or use the bridge network.", req.Network)
+	}
+	if req.SocketActivation {
+		return jobs.NewInvalidError("Socket activated containers must use the bridge network.")
+	}
+	if other, ok := req.Network.Container(); ok && other == req.Id {
+		return jobs.NewInvalidError("A container can't share its own network.")
+	}
+	return nil
+}
+
+func (req *InstallContainerRequest) checkWatchPath() error {
+	if req.WatchPath == "" {
+		return nil
+	}
+	if !filepath.IsAbs(req.WatchPath) || filepath.Clean(req.WatchPath) != req.WatchPath {
+		return jobs.NewInvalidError("The watched path %s must be an absolute path without '.' or '..' elements.", req.WatchPath)
+	}
+	if strings.ContainsAny(req.WatchPath, "%\\\r\n") {
+		return jobs.NewInvalidError("The watched path may not contain '%%', '\\', or a newline.")
+	}
+	return nil
+}
+
+// The most times an install will retry a failed start
+const MaxStartRetries = 10
+
+func (req *InstallContainerRequest) checkStartRetries() error {
+	if req.StartRetries == 0 {
+		if req.StartRetryInterval != 0 {
+			return jobs.NewInvalidError("A start retry interval requires start retries.")
+		}
+		return nil
+	}
+	switch {
+	case !req.Started:
+		return jobs.NewInvalidError("Start retries require the container to be started.")
+	case req.SocketActivation:
+		return jobs.NewInvalidError("Start retries can't be used with socket activation, the container is started by its first connection.")
+	case req.StartRetries < 0 || req.StartRetries > MaxStartRetries:
+		return jobs.NewInvalidError("Start retries must be between 0 and %d.", MaxStartRetries)
+	case req.StartRetryInterval < 1:
+		return jobs.NewInvalidError("The start retry interval must be at least one second.")
+	}
+	return nil
+}
+
+func (req *InstallContainerRequest) checkEnvFileWatch() error {
+	if req.EnvFileWatch == "" {
+		if req.EnvFileWatchRestart {
+			return jobs.NewInvalidError("A container can only be restarted when a watched environment file changes if it watches one.")
+		}
+		return nil
+	}
+	if !filepath.IsAbs(req.EnvFileWatch) || filepath.Clean(req.EnvFileWatch) != req.EnvFileWatch {
+		return jobs.NewInvalidError("The watched environment file %s must be an absolute path without '.' or '..' elements.", req.EnvFileWatch)
+	}
+	if strings.ContainsAny(req.EnvFileWatch, "%\\\r\n") {
+		return jobs.NewInvalidError("The watched environment file may not contain '%%', '\\', or a newline.")
+	}
+	// the file is applied to the environment named for the container
+	if req.Environment != nil && req.Environment.Id != "" && req.Environment.Id != req.Id {
+		return jobs.NewInvalidError("A container that watches an environment file must use its own environment, not %s.", req.Environment.Id)
+	}
+	return nil
+}
+
+func (req *InstallContainerRequest) checkInheritEnvironment() error {
+	if req.InheritEnvironment == "" {
+		return nil
+	}
+	if _, err := containers.NewIdentifier(string(req.InheritEnvironment)); err != nil {
+		return jobs.NewInvalidError("The inherited environment id is not valid: %s", err.Error())
+	}
+	own := req.Id
+	if req.Environment != nil && req.Environment.Id != "" {
+		own = req.Environment.Id
+	}
+	if req.InheritEnvironment == own {
+		return jobs.NewInvalidError("The environment %s can't inherit from itself.", own)
+	}
+	return nil
+}
+
+func (req *InstallContainerRequest) checkEnvReload() error {
+	if req.EnvReloadSignal == "" {
+		return nil
+	}
+	signal, err := containers.NewReloadSignal(req.EnvReloadSignal)
+	if err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	req.EnvReloadSignal = signal
+	if req.SocketActivation {
+		return jobs.NewInvalidError("Socket activated containers can't reload their environment.")
+	}
+	// a reload rewrites the copy of the environment named for the container
+	if req.Environment != nil && req.Environment.Id != "" && req.Environment.Id != req.Id {
+		return jobs.NewInvalidError("A container that reloads its environment must use its own environment, not %s.", req.Environment.Id)
+	}
+	return nil
+}
+
+func (req *InstallContainerRequest) checkOverrides() error {
+	if req.Entrypoint == "" && req.Cmd == nil && req.WorkingDir == "" {
+		return nil
+	}
+	if req.Isolate || req.SocketActivation {
+		return jobs.NewInvalidError("The entrypoint, command, and working directory can't be overridden for isolated or socket activated containers.")
+	}
+	if req.Entrypoint != "" && strings.TrimSpace(req.Entrypoint) == "" {
+		return jobs.NewInvalidError("The entrypoint may not be blank.")
+	}
+	if req.WorkingDir != "" && !filepath.IsAbs(req.WorkingDir) {
+		return jobs.NewInvalidError("The working directory must be an absolute path.")
+	}
+	for _, arg := range append([]string{req.Entrypoint, req.WorkingDir}, req.Cmd...) {
+		if strings.ContainsAny(arg, "\x00\n\r") {
+			return jobs.NewInvalidError("The entrypoint, command, and working directory may not contain line breaks or null characters.")
+		}
+	}
+	return nil
+}
+
 const PendingPortMappingName = "PortMapping"
 
+// The digest the image of an install resolved to, and the digest the
+// container was installed with before if the same image now resolves to
+// another
+const (
+	PendingImageDigestName         = "ImageDigest"
+	PendingPreviousImageDigestName = "PreviousImageDigest"
+)
+
+// The result of an install as reported to a client, including any
+// external ports assigned by the server.
+type InstallContainerResponse struct {
+	Id      containers.Identifier
+	Server  string `json:"Server,omitempty"`
+	Image   string
+	Ports   port.PortPairs
+	Started bool
+	// The digest the image resolved to, if known, and the digest the
+	// container had before if a reinstall of the same image changed it
+	ImageDigest         containers.ImageDigest `json:"ImageDigest,omitempty"`
+	PreviousImageDigest containers.ImageDigest `json:"PreviousImageDigest,omitempty"`
+	// Set if the install failed
+	Error string `json:"Error,omitempty"`
+}
+
+// The result of this install on a server, given the pending values and
+// error returned by the job.
+func (j *InstallContainerRequest) ResponseFor(server string, pending map[string]interface{}, err error) InstallContainerResponse {
+	r := InstallContainerResponse{Id: j.Id, Server: server, Image: j.Image, Ports: port.PortPairs{}}
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if pairs, ok := j.PortMappingsFrom(pending); ok {
+		r.Ports = pairs
+	}
+	r.Started = j.Started && !j.PullOnly
+	r.ImageDigest, _ = pending[PendingImageDigestName].(containers.ImageDigest)
+	r.PreviousImageDigest, _ = pending[PendingPreviousImageDigestName].(containers.ImageDigest)
+	return r
+}
+
 func (j *InstallContainerRequest) PortMappingsFrom(pending map[string]interface{}) (port.PortPairs, bool) {
 	p, ok := pending[PendingPortMappingName].(port.PortPairs)
 	return p, ok
@@ -116,16 +497,96 @@ func (j *InstallContainerRequest) PortMappingsFrom(pending map[string]interface{
 
 type StartedContainerStateRequest struct {
 	Id containers.Identifier
+	// Variables that override the environment of the container until it
+	// next stops, without changing the stored environment
+	Environment containers.StartEnvironment `json:"Environment,omitempty"`
+}
+
+func (req *StartedContainerStateRequest) Check() error {
+	if err := req.Environment.Check(); err != nil {
+		return jobs.NewInvalidError("%s", err.Error())
+	}
+	return nil
 }
 
+// Seconds a container is given to exit before it is killed
+const DefaultStopTimeout = 10
+
 type StoppedContainerStateRequest struct {
 	Id containers.Identifier
+	// Seconds to wait for the container to exit before it is killed, zero
+	// to kill it immediately.  Defaults to the timeout the container was
+	// installed with.
+	Timeout      *int
+	DockerSocket string `json:"-"`
+}
+
+func (j *StoppedContainerStateRequest) StopTimeout() int {
+	if j.Timeout != nil {
+		return *j.Timeout
+	}
+	if timeout, err := containers.GetContainerStopTimeout(j.Id); err == nil {
+		return timeout
+	}
+	return DefaultStopTimeout
 }
 
 type RestartContainerRequest struct {
 	Id containers.Identifier
 }
 
+// Freeze the processes of a running container without stopping it
+type PausedContainerStateRequest struct {
+	Id           containers.Identifier
+	DockerSocket string `json:"-"`
+}
+
+// Thaw the processes of a paused container
+type UnpausedContainerStateRequest struct {
+	Id           containers.Identifier
+	DockerSocket string `json:"-"`
+}
+
+// Clear the failed state and restart count of a container's unit, so that
+// a container that hit its start limit can be started again.
+type ResetFailedContainerRequest struct {
+	Id containers.Identifier
+}
+
+// Prepare the user, home directory and init scripts of a container before
+// it starts, as 'gear init --pre' does on the server itself.
+type InitPreStartRequest struct {
+	Id containers.Identifier
+	// Must be the image the container was installed with
+	Image string
+	// Exported to the init script of the container, but unset before its
+	// command runs
+	Environment  containers.EnvironmentVariables `json:"Environment,omitempty"`
+	DockerSocket string                          `json:"-"`
+}
+
+func (req *InitPreStartRequest) Check() error {
+	if req.Image == "" {
+		return jobs.NewInvalidError("An image is required to initialize a container before it starts.")
+	}
+	for i := range req.Environment {
+		if err := req.Environment[i].Check(); err != nil {
+			return jobs.NewInvalidError("The init environment is not valid: %s", err.Error())
+		}
+		if err := req.Environment[i].CheckShellName(); err != nil {
+			return jobs.NewInvalidError("The init environment is not valid: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// Link the network of a container once it has started, as
+// 'gear init --post' does on the server itself.
+type InitPostStartRequest struct {
+	Id           containers.Identifier
+	DockerSocket string `json:"-"`
+}
+
 type BuildImageRequest struct {
 	Name         string
 	Source       string
@@ -135,22 +596,49 @@ type BuildImageRequest struct {
 	Clean        bool
 	Verbose      bool
 	CallbackUrl  string
+
+	// If set, a tar archive of a Docker build context that is built by
+	// Docker rather than STI.  The image is always tagged for the container
+	// Id, so a client can't replace the image of another container.
+	Context      io.Reader             `json:"-"`
+	Id           containers.Identifier `json:"-"`
+	DockerSocket string                `json:"-"`
+}
+
+// A build context is read while the job runs
+func (e *BuildImageRequest) StreamsInput() bool {
+	return e.Context != nil
 }
 
+// The name of the image built from a build context, sent after the build
+// output when it succeeds
+const TrailerBuildImage = "Build-Image"
+
+// The reason a build from a build context failed, sent after the build
+// output
+const TrailerBuildError = "Build-Error"
+
 func (e *BuildImageRequest) Check() error {
+	if e.Context != nil {
+		if _, err := containers.NewIdentifier(string(e.Id)); err != nil {
+			return jobs.NewInvalidError("A valid container identifier is required to build from a build context: %s", err.Error())
+		}
+		e.Tag = e.Id.BuildImageTagFor()
+		return nil
+	}
 	if e.Name == "" {
-		return errors.New("An identifier must be specified for this build")
+		return jobs.NewInvalidError("An identifier must be specified for this build")
 	}
 	if e.BaseImage == "" {
-		return errors.New("A base image is required to start a build")
+		return jobs.NewInvalidError("A base image is required to start a build")
 	}
 	if e.Source == "" {
-		return errors.New("A source input is required to start a build")
+		return jobs.NewInvalidError("A source input is required to start a build")
 	}
 	if e.CallbackUrl != "" {
 		_, err := url.ParseRequestURI(e.CallbackUrl)
 		if err != nil {
-			return errors.New("The callbackUrl was an invalid URL")
+			return jobs.NewInvalidError("The callbackUrl was an invalid URL")
 		}
 	}
 	return nil
@@ -168,16 +656,233 @@ type ContainerPortsResponse struct {
 	Ports port.PortPairs
 }
 
+// Move the external port of a container to a new port without reinstalling
+// it.  The container must be restarted to listen on the new port.
+type ReassignPortRequest struct {
+	jobs.RequestIdentifier `json:"-"`
+
+	Id containers.Identifier
+	// The internal port whose mapping is changed, may be omitted if the
+	// container has only one port
+	Internal port.Port `json:"Internal,omitempty"`
+	// The new external port, or 0 to allocate one
+	External port.Port `json:"External,omitempty"`
+}
+
+func (req *ReassignPortRequest) Check() error {
+	if len(req.RequestIdentifier) == 0 {
+		return jobs.NewInvalidError("A request identifier is required to reassign a port.")
+	}
+	if req.Internal != 0 {
+		if err := req.Internal.Check(); err != nil {
+			return jobs.NewInvalidError("The internal port is not valid: %s", err.Error())
+		}
+	}
+	if req.External != 0 {
+		if err := req.External.Check(); err != nil {
+			return jobs.NewInvalidError("The external port is not valid: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+type ReassignPortResponse struct {
+	Id       containers.Identifier
+	Internal port.Port
+	External port.Port
+	// The external port before the change
+	Previous port.Port
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+
+// Give a container a new id, keeping its environment, home directory, data
+// container and ports.  A running container is stopped while it is moved
+// and started again under the new id.
+type RenameContainerRequest struct {
+	jobs.RequestIdentifier `json:"-"`
+
+	Id    containers.Identifier
+	NewId containers.Identifier
+
+	DockerSocket string `json:"-"`
+}
+
+func (req *RenameContainerRequest) Check() error {
+	if len(req.RequestIdentifier) == 0 {
+		return jobs.NewInvalidError("A request identifier is required to rename a container.")
+	}
+	if _, err := containers.NewIdentifier(string(req.Id)); err != nil {
+		return jobs.NewInvalidError("The container id is not valid: %s", err.Error())
+	}
+	if _, err := containers.NewIdentifier(string(req.NewId)); err != nil {
+		return jobs.NewInvalidError("The new container id is not valid: %s", err.Error())
+	}
+	if req.Id == req.NewId {
+		return jobs.NewInvalidError("The new container id must be different from the current one.")
+	}
+	return nil
+}
+
 type ContainerStatusRequest struct {
 	Id containers.Identifier
+	// Return the unit state and resource limits as data instead of
+	// the systemd status output
+	Structured   bool
+	DockerSocket string `json:"-"`
+}
+
+// Reported for a limit that has not been configured on the unit
+const LimitUnlimited = "unlimited"
+
+// Limits set on the container unit
+type ContainerLimits struct {
+	MemoryLimit string
+	CPUShares   string
+	// The CPUs and memory nodes the container is pinned to, absent if it
+	// may use any
+	CPUSetCPUs containers.CPUSet `json:"CPUSetCPUs,omitempty"`
+	CPUSetMems containers.CPUSet `json:"CPUSetMems,omitempty"`
+}
+
+// Resources observed in use by the running container
+type ContainerUsage struct {
+	MemoryUsage uint64
+	MemoryLimit uint64
+	// Nanoseconds of CPU time consumed
+	CPUUsage uint64
 }
 
-const ContentTypeEnvironment = "env"
+type ContainerStatusResponse struct {
+	UnitResponse
+	Limits ContainerLimits
+	// Absent if the container is not running
+	Usage  *ContainerUsage   `json:"Usage,omitempty"`
+	Labels containers.Labels `json:"Labels,omitempty"`
+	// The logging driver of the container, absent if it uses the default
+	LogDriver containers.LogDriver `json:"LogDriver,omitempty"`
+	// The image the container was installed from, and the digest it
+	// resolved to if known
+	Image       string                 `json:"Image,omitempty"`
+	ImageDigest containers.ImageDigest `json:"ImageDigest,omitempty"`
+	// The revision the container was installed with, absent if none
+	Revision containers.Revision `json:"Revision,omitempty"`
+	// The size in bytes of the container's environment, absent if it has
+	// none
+	EnvironmentSize int `json:"EnvironmentSize,omitempty"`
+	// When the container was installed and last started, absent if not
+	// known
+	Installed *time.Time `json:"Installed,omitempty"`
+	Started   *time.Time `json:"Started,omitempty"`
+	// Seconds since the container was started, absent unless it is active
+	UptimeSeconds *int64 `json:"UptimeSeconds,omitempty"`
+	// Whether the processes of the running container are frozen by
+	// 'gear pause'.  Systemd still reports a paused container as active.
+	Paused bool `json:"Paused,omitempty"`
+	// The number of times systemd has restarted the container since it was
+	// started or reset, absent if systemd doesn't report it
+	Restarts *uint32 `json:"Restarts,omitempty"`
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+type ContainerStatusResponses []ContainerStatusResponse
+
+// Everything this server knows about an installed container in one
+// document, for debugging.  Only implemented locally.
+type InspectContainerRequest struct {
+	Id containers.Identifier
+}
+
+// What was recorded about the container when it was installed
+type ContainerMetadata struct {
+	Image       string
+	ImageDigest containers.ImageDigest `json:"ImageDigest,omitempty"`
+	// "isolated" or "simple"
+	Type               string
+	RequestId          string                      `json:"RequestId,omitempty"`
+	Revision           containers.Revision         `json:"Revision,omitempty"`
+	LogDriver          containers.LogDriver        `json:"LogDriver,omitempty"`
+	Labels             containers.Labels           `json:"Labels,omitempty"`
+	Links              containers.ContainerAliases `json:"Links,omitempty"`
+	InheritEnvironment containers.Identifier       `json:"InheritEnvironment,omitempty"`
+	EnvReloadSignal    string                      `json:"EnvReloadSignal,omitempty"`
+	Installed          *time.Time                  `json:"Installed,omitempty"`
+	Started            *time.Time                  `json:"Started,omitempty"`
+}
+
+// The state systemd reports for the unit of the container
+type ContainerSystemdStatus struct {
+	LoadState   string
+	ActiveState string
+	SubState    string
+	// Why the state could not be read, if it couldn't
+	Error string `json:"Error,omitempty"`
+}
+
+// Where the files of the container are kept on the host
+type ContainerPaths struct {
+	Unit    string
+	DataDir string
+	RunDir  string
+}
+
+type InspectContainerResponse struct {
+	Id       containers.Identifier
+	Metadata ContainerMetadata
+	// The contents of the unit file
+	Unit   string
+	Status ContainerSystemdStatus
+	// The external ports allocated to the container
+	Ports port.PortPairs
+	Paths ContainerPaths
+}
+type InspectContainerResponses []InspectContainerResponse
+
+const (
+	ContentTypeEnvironment = "env"
+	// A file or directory below the home directory of a container, named
+	// by Subpath
+	ContentTypeFile = "file"
+)
 
 type ContentRequest struct {
 	Type    string
 	Locator string
 	Subpath string
+	// Only return these variables of an environment, failing if any is
+	// not set.  The ETag and size are still those of the whole environment.
+	Keys []string `json:"Keys,omitempty"`
+
+	// The content is only returned if its ETag does not match one of
+	// these (a comma delimited list, or "*")
+	IfNoneMatch string `json:"-"`
+}
+
+// The ETag of the returned content, sent before it, or of an environment
+// after it is changed
+const PendingETagName = "ETag"
+
+// An opaque, quoted identifier for a version of content
+type ETag string
+
+func (e ETag) ToHeader() string {
+	return string(e)
+}
+func (e ETag) String() string {
+	return string(e)
+}
+
+// The size in bytes of an environment, one NAME=value line per variable,
+// sent before its content
+const PendingEnvironmentSizeName = "Environment-Size"
+
+type EnvironmentSize int
+
+func (s EnvironmentSize) ToHeader() string {
+	return strconv.Itoa(int(s))
+}
+func (s EnvironmentSize) String() string {
+	return strconv.Itoa(int(s))
 }
 
 type DeleteContainerRequest struct {
@@ -186,10 +891,69 @@ type DeleteContainerRequest struct {
 
 type PutEnvironmentRequest struct {
 	containers.EnvironmentDescription
+	EnvironmentReload
+	// If set, the environment after the change must match the schema
+	Schema *containers.EnvironmentSchema `json:"Schema,omitempty"`
+
+	// Only replace the environment if its current ETag matches one of
+	// these (a comma delimited list, or "*")
+	IfMatch string `json:"-"`
+}
+
+// After an environment is changed, send the container with the same id
+// the signal it was installed with so that it can reload its environment
+// without a restart.
+type EnvironmentReload struct {
+	Reload       bool   `json:"-"`
+	DockerSocket string `json:"-"`
 }
 
 type PatchEnvironmentRequest struct {
 	containers.EnvironmentDescription
+	EnvironmentReload
+	// If set, the environment after the change must match the schema
+	Schema *containers.EnvironmentSchema `json:"Schema,omitempty"`
+
+	// Only change the environment if its current ETag matches one of
+	// these (a comma delimited list, or "*")
+	IfMatch string `json:"-"`
+}
+
+// Apply the stored environment of one container to another on the
+// same server.
+type CopyEnvironmentRequest struct {
+	Id     containers.Identifier
+	Source containers.Identifier
+	// Further environments merged after Source, in order.  A variable set
+	// by more than one source takes the value from the last.
+	Sources []containers.Identifier `json:"Sources,omitempty"`
+	// Replace the target environment instead of merging into it
+	Reset bool
+}
+
+// The environments to merge, in order
+func (req *CopyEnvironmentRequest) AllSources() []containers.Identifier {
+	sources := make([]containers.Identifier, 0, len(req.Sources)+1)
+	if req.Source != "" {
+		sources = append(sources, req.Source)
+	}
+	return append(sources, req.Sources...)
+}
+
+func (req *CopyEnvironmentRequest) Check() error {
+	if req.Id == "" {
+		return jobs.NewInvalidError("A target environment identifier is required to copy an environment.")
+	}
+	sources := req.AllSources()
+	if len(sources) == 0 {
+		return jobs.NewInvalidError("A source environment identifier is required to copy an environment.")
+	}
+	for _, source := range sources {
+		if source == req.Id {
+			return jobs.NewInvalidError("The source and target environments must be different.")
+		}
+	}
+	return nil
 }
 
 type LinkContainersRequest struct {
@@ -201,6 +965,8 @@ type ListImagesRequest struct {
 }
 
 type ListContainersRequest struct {
+	// Only list the containers whose labels match
+	Selector containers.Selector `json:"Selector,omitempty"`
 }
 
 type UnitResponse struct {
@@ -228,6 +994,35 @@ type ListBuildsResponse struct {
 	Builds UnitResponses
 }
 
+// Summarize the resources claimed by the containers on a host
+type HostStatusRequest struct{}
+
+type HostStatusResponse struct {
+	Containers int
+	Running    int
+	Stopped    int
+	// Containers whose state could not be read from systemd
+	Unknown int `json:"Unknown,omitempty"`
+
+	// The sum of the memory limits in bytes, and the number of containers
+	// without a limit
+	MemoryLimit     uint64
+	MemoryUnlimited int
+	// The sum of the CPU shares, and the number of containers without
+	// shares set
+	CPUShares    uint64
+	CPUUnlimited int
+
+	// External ports reserved and still free in the allocated range
+	PortsAllocated int
+	PortsAvailable int
+	PortRange      string
+
+	// Used by consumers
+	Server string `json:"Server,omitempty"`
+}
+type HostStatusResponses []HostStatusResponse
+
 type PurgeContainersRequest struct{}
 
 type RunContainerRequest struct {
@@ -239,10 +1034,43 @@ type RunContainerRequest struct {
 
 func (e *RunContainerRequest) Check() error {
 	if e.Name == "" {
-		return errors.New("A name must be specified for this container execution")
+		return jobs.NewInvalidError("A name must be specified for this container execution")
 	}
 	if e.Image == "" {
-		return errors.New("An image must be specified for this container execution")
+		return jobs.NewInvalidError("An image must be specified for this container execution")
 	}
 	return nil
 }
+
+// The name of the trailer that carries the exit code of an exec.
+const TrailerExitCode = "Exit-Code"
+
+// Run a command inside an already running container and stream
+// the output back to the caller.
+type ExecRequest struct {
+	Id      containers.Identifier
+	Command []string
+
+	// Forward Stdin to the command
+	Interactive bool
+	// Allocate a pseudo-TTY for the command
+	Tty bool
+
+	Stdin        io.Reader `json:"-"`
+	DockerSocket string    `json:"-"`
+}
+
+func (e *ExecRequest) Check() error {
+	if e.Id == "" {
+		return jobs.NewInvalidError("A container identifier is required to run a command")
+	}
+	if len(e.Command) == 0 {
+		return jobs.NewInvalidError("A command must be specified to run in the container")
+	}
+	return nil
+}
+
+// Stdin is read while the command runs
+func (e *ExecRequest) StreamsInput() bool {
+	return e.Stdin != nil
+}
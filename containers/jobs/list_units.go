@@ -38,6 +38,16 @@ func (j *ListContainersRequest) Execute(resp jobs.Response) {
 		if unit.LoadState == "not-found" || unit.LoadState == "masked" {
 			return
 		}
+		if !j.Selector.Empty() {
+			labels, err := containers.GetExistingLabels(containers.Identifier(name))
+			if err != nil {
+				log.Printf("list_units: Unable to read labels for %s: %v", name, err)
+				return
+			}
+			if !j.Selector.Matches(labels) {
+				return
+			}
+		}
 		r.Containers = append(r.Containers, ContainerUnitResponse{
 			UnitResponse{
 				name,
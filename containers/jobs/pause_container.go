@@ -0,0 +1,86 @@
+// +build linux
+
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+)
+
+func (j *PausedContainerStateRequest) Execute(resp jobs.Response) {
+	changePausedState(j.Id, j.DockerSocket, true, resp)
+}
+
+func (j *UnpausedContainerStateRequest) Execute(resp jobs.Response) {
+	changePausedState(j.Id, j.DockerSocket, false, resp)
+}
+
+// Freeze or thaw the processes of a running container.  A container that
+// is already in the requested state is left alone.
+func changePausedState(id containers.Identifier, dockerSocket string, pause bool, resp jobs.Response) {
+	failed, action := ErrContainerUnpauseFailed, "unpaused"
+	if pause {
+		failed, action = ErrContainerPauseFailed, "paused"
+	}
+
+	if _, err := os.Stat(id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+	runtime, err := containers.NewRuntime(dockerSocket)
+	if err != nil {
+		log.Printf("pause_container: Unable to connect to the runtime: %v", err)
+		resp.Failure(failed)
+		return
+	}
+
+	paused, err := runtime.ContainerPaused(id)
+	switch {
+	case err == containers.ErrNoSuchContainer:
+		resp.Failure(ErrContainerNotRunning)
+		return
+	case err != nil:
+		log.Printf("pause_container: Unable to read whether %s is paused: %v", id, err)
+		resp.Failure(failed)
+		return
+	case paused == pause:
+		w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+		fmt.Fprintf(w, "Container %s is already %s\n", id, action)
+		return
+	}
+
+	if pause {
+		err = runtime.PauseContainer(id)
+	} else {
+		err = runtime.UnpauseContainer(id)
+	}
+	switch {
+	case err == containers.ErrNoSuchContainer:
+		resp.Failure(ErrContainerNotRunning)
+		return
+	case err != nil:
+		log.Printf("pause_container: Unable to change whether %s is paused: %v", id, err)
+		resp.Failure(failed)
+		return
+	}
+	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+	fmt.Fprintf(w, "Container %s is %s\n", id, action)
+}
+
+// Whether the processes of the container are frozen, false if it is not
+// running or the runtime can't be reached.
+func containerPaused(id containers.Identifier, dockerSocket string) bool {
+	runtime, err := containers.NewRuntime(dockerSocket)
+	if err != nil {
+		return false
+	}
+	paused, err := runtime.ContainerPaused(id)
+	if err != nil && err != containers.ErrNoSuchContainer {
+		log.Printf("container_status: Unable to read whether the container is paused: %v", err)
+	}
+	return paused
+}
@@ -0,0 +1,176 @@
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+)
+
+// A Docker daemon holding a single container that can be paused
+type fakePauseBackend struct {
+	running bool
+	paused  bool
+	calls   []string
+}
+
+func (b *fakePauseBackend) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			fmt.Fprintln(w, `{"ExecutionDriver":"native-0.2"}`)
+		case "/containers/test-pause/json":
+			if !b.running {
+				http.Error(w, "No such container: test-pause", http.StatusNotFound)
+				return
+			}
+			fmt.Fprintf(w, `{"State":{"Running":true,"Paused":%t}}`, b.paused)
+		case "/containers/test-pause/pause", "/containers/test-pause/unpause":
+			if r.Method != "POST" {
+				t.Errorf("Expected a POST to %s, got %s", r.URL.Path, r.Method)
+			}
+			pause := strings.HasSuffix(r.URL.Path, "/pause")
+			b.calls = append(b.calls, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			if pause == b.paused {
+				http.Error(w, "Container test-pause is already in that state", http.StatusInternalServerError)
+				return
+			}
+			b.paused = pause
+			w.WriteHeader(http.StatusNoContent)
+		case "/containers/test-pause/stats":
+			http.Error(w, "No such container: test-pause", http.StatusNotFound)
+		default:
+			t.Errorf("Unexpected URL: %s", r.URL)
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func writePauseUnit(t *testing.T) containers.Identifier {
+	id := containers.Identifier("test-pause")
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Service]\nExecStart=/usr/bin/docker run test\n"), 0664); err != nil {
+		t.Fatalf("Unable to write unit: %v", err)
+	}
+	return id
+}
+
+func TestPauseAndUnpauseContainer(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePauseBackend{running: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+	id := writePauseUnit(t)
+
+	for i, step := range []struct {
+		pause    bool
+		output   string
+		paused   bool
+		numCalls int
+	}{
+		{true, "Container test-pause is paused\n", true, 1},
+		{true, "Container test-pause is already paused\n", true, 1},
+		{false, "Container test-pause is unpaused\n", false, 2},
+		{false, "Container test-pause is already unpaused\n", false, 2},
+	} {
+		buf := &bytes.Buffer{}
+		resp := &cmd.CliJobResponse{Output: buf}
+		if step.pause {
+			(&PausedContainerStateRequest{Id: id, DockerSocket: server.URL}).Execute(resp)
+		} else {
+			(&UnpausedContainerStateRequest{Id: id, DockerSocket: server.URL}).Execute(resp)
+		}
+		if resp.Error != nil {
+			t.Fatalf("Step %d: unexpected error: %v", i, resp.Error)
+		}
+		if buf.String() != step.output {
+			t.Errorf("Step %d: expected %q, got %q", i, step.output, buf.String())
+		}
+		if backend.paused != step.paused || len(backend.calls) != step.numCalls {
+			t.Errorf("Step %d: expected the container to be paused=%t after %d calls, got %t after %v", i, step.paused, step.numCalls, backend.paused, backend.calls)
+		}
+	}
+}
+
+func TestPauseContainerNotRunning(t *testing.T) {
+	defer withContainerBasePath(t)()
+	backend := &fakePauseBackend{}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	(&PausedContainerStateRequest{Id: "test-pause", DockerSocket: server.URL}).Execute(resp)
+	if resp.Error != ErrContainerNotFound {
+		t.Errorf("Expected a container that isn't installed to be reported, got %v", resp.Error)
+	}
+
+	id := writePauseUnit(t)
+	for _, pause := range []bool{true, false} {
+		resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+		if pause {
+			(&PausedContainerStateRequest{Id: id, DockerSocket: server.URL}).Execute(resp)
+		} else {
+			(&UnpausedContainerStateRequest{Id: id, DockerSocket: server.URL}).Execute(resp)
+		}
+		if resp.Error != ErrContainerNotRunning {
+			t.Errorf("Expected a stopped container to be reported as not running, got %v", resp.Error)
+		}
+	}
+	if len(backend.calls) != 0 {
+		t.Errorf("Expected a stopped container to be left alone, got %v", backend.calls)
+	}
+}
+
+func TestContainerStatusPaused(t *testing.T) {
+	requireStubSystemd(t)
+	defer withContainerBasePath(t)()
+	backend := &fakePauseBackend{running: true, paused: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+	id := writePauseUnit(t)
+
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+	(&ContainerStatusRequest{Id: id, Structured: true, DockerSocket: server.URL}).Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error reading status: %v", resp.Error)
+	}
+	status, ok := resp.Data.(*ContainerStatusResponse)
+	if !ok {
+		t.Fatalf("Expected a structured status, got %#v", resp.Data)
+	}
+	if !status.Paused {
+		t.Errorf("Expected the status to report the container is paused, got %+v", status)
+	}
+	buf := &bytes.Buffer{}
+	ContainerStatusResponses{*status}.WriteTableTo(buf)
+	if !strings.Contains(buf.String(), " "+ContainerStatePaused+" ") {
+		t.Errorf("Expected the table to show the container is paused:\n%s", buf.String())
+	}
+
+	buf = &bytes.Buffer{}
+	(&ContainerStatusRequest{Id: id, DockerSocket: server.URL}).Execute(&cmd.CliJobResponse{Output: buf})
+	if !strings.Contains(buf.String(), "The container is paused") {
+		t.Errorf("Expected the status to say the container is paused, got %q", buf.String())
+	}
+
+	backend.paused = false
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+	(&ContainerStatusRequest{Id: id, Structured: true, DockerSocket: server.URL}).Execute(resp)
+	if status, ok := resp.Data.(*ContainerStatusResponse); !ok || status.Paused {
+		t.Errorf("Expected a running container not to be reported as paused, got %#v", resp.Data)
+	}
+
+	backend.running = false
+	resp = &cmd.CliJobResponse{Output: ioutil.Discard, Gather: true}
+	(&ContainerStatusRequest{Id: id, Structured: true, DockerSocket: server.URL}).Execute(resp)
+	if status, ok := resp.Data.(*ContainerStatusResponse); !ok || status.Paused {
+		t.Errorf("Expected a stopped container not to be reported as paused, got %#v", resp.Data)
+	}
+}
@@ -0,0 +1,153 @@
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+)
+
+func (req *ReassignPortRequest) Execute(resp jobs.Response) {
+	id := req.Id
+	unitPath := id.UnitPathFor()
+	unitVersionPath := id.VersionedUnitPathFor(req.RequestIdentifier.String())
+	socketUnitPath := id.SocketUnitPathFor()
+
+	if _, err := os.Stat(unitPath); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	// lock the unit to prevent simultaneous updates
+	state, _, err := utils.OpenFileExclusive(unitPath, 0664)
+	if err != nil {
+		log.Print("reassign_port: Unable to lock unit file: ", err)
+		resp.Failure(ErrReassignPortFailed)
+		return
+	}
+	defer state.Close()
+
+	existing, err := ioutil.ReadAll(state)
+	if err != nil {
+		log.Print("reassign_port: Unable to read unit file: ", err)
+		resp.Failure(ErrReassignPortFailed)
+		return
+	}
+	ports, err := containers.GetExistingPorts(id)
+	if err != nil {
+		log.Print("reassign_port: Unable to read existing ports: ", err)
+		resp.Failure(ErrReassignPortFailed)
+		return
+	}
+
+	var pair *port.PortPair
+	switch {
+	case req.Internal != 0:
+		found, ok := ports.Find(req.Internal)
+		if !ok {
+			resp.Failure(jobs.NewNotFoundError("The container %s does not expose the internal port %d.", id, req.Internal))
+			return
+		}
+		pair = found
+	case len(ports) == 1:
+		pair = &ports[0]
+	case len(ports) == 0:
+		resp.Failure(jobs.NewInvalidError("The container %s has no ports to reassign.", id))
+		return
+	default:
+		resp.Failure(jobs.NewInvalidError("The container %s has more than one port, specify the internal port to reassign.", id))
+		return
+	}
+
+	r := &ReassignPortResponse{Id: id, Internal: pair.Internal, External: pair.External, Previous: pair.External}
+	if req.External == pair.External {
+		resp.SuccessWithData(jobs.ResponseOk, r)
+		return
+	}
+
+	unit, err := utils.CreateFileExclusive(unitVersionPath, 0664)
+	if err != nil {
+		log.Print("reassign_port: Unable to open unit file definition: ", err)
+		resp.Failure(ErrReassignPortFailed)
+		return
+	}
+	defer unit.Close()
+
+	reserved, err := port.ReserveExternalPort(unitVersionPath, req.External)
+	if err != nil {
+		os.Remove(unitVersionPath)
+		if exhausted, ok := err.(port.ExhaustedError); ok {
+			resp.Failure(portsExhaustedError(exhausted))
+			return
+		}
+		switch err {
+		case port.ErrPortReserved:
+			resp.Failure(jobs.NewConflictError("The port %d is already reserved.", req.External))
+		default:
+			log.Printf("reassign_port: Unable to reserve an external port: %v", err)
+			resp.Failure(ErrReassignPortFailed)
+		}
+		return
+	}
+	r.External = reserved
+
+	// write the new definition and swap it with the old one
+	_, err = unit.Write(reassignUnitPort(existing, pair.Internal, pair.External, reserved))
+	if err == nil {
+		err = unit.Close()
+	}
+	if err == nil {
+		err = utils.AtomicReplaceLink(unitVersionPath, unitPath)
+	}
+	if err != nil {
+		log.Printf("reassign_port: Unable to write the new unit: %v", err)
+		port.ReleaseExternalPorts(port.PortPairs{port.PortPair{pair.Internal, reserved}})
+		os.Remove(unitVersionPath)
+		resp.Failure(ErrReassignPortFailed)
+		return
+	}
+	state.Close()
+
+	log.Printf("reassign_port: Moved %s port %d from %d to %d", id, pair.Internal, pair.External, reserved)
+	if err := port.ReleaseExternalPorts(port.PortPairs{*pair}); err != nil {
+		log.Printf("reassign_port: Unable to release port %d: %v", pair.External, err)
+	}
+
+	if socket, err := ioutil.ReadFile(socketUnitPath); err == nil {
+		from := []byte(fmt.Sprintf("ListenStream=%d\n", pair.External))
+		to := []byte(fmt.Sprintf("ListenStream=%d\n", reserved))
+		if err := ioutil.WriteFile(socketUnitPath, bytes.Replace(socket, from, to, -1), 0664); err != nil {
+			log.Printf("reassign_port: Unable to update socket unit: %v", err)
+		}
+	}
+
+	if err := systemd.Connection().Reload(); err != nil {
+		log.Printf("reassign_port: Unable to reload systemd: %v", err)
+		resp.Failure(ErrReassignPortFailed)
+		return
+	}
+
+	resp.SuccessWithData(jobs.ResponseOk, r)
+}
+
+// Change the external port of a mapping in the contents of a unit, both in
+// the port recorded for geard and in the arguments passed to Docker.
+func reassignUnitPort(unit []byte, internal, from, to port.Port) []byte {
+	replacements := [][2]string{
+		{fmt.Sprintf("X-PortMapping=%d:%d\n", internal, from), fmt.Sprintf("X-PortMapping=%d:%d\n", internal, to)},
+		{fmt.Sprintf("-p %d:%d ", from, internal), fmt.Sprintf("-p %d:%d ", to, internal)},
+	}
+	for _, r := range replacements {
+		unit = bytes.Replace(unit, []byte(r[0]), []byte(r[1]), -1)
+	}
+	return unit
+}
@@ -0,0 +1,121 @@
+// +build linux
+
+package jobs
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+)
+
+func installWithPort(t *testing.T, id containers.Identifier, socket string) port.PortPair {
+	req := &InstallContainerRequest{
+		RequestIdentifier: jobs.NewRequestIdentifier(),
+		Id:                id,
+		Image:             "testimage",
+		Ports:             port.PortPairs{{Internal: 8080}},
+		DockerSocket:      socket,
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error installing %s: %v", id, resp.Error)
+	}
+	pairs, ok := req.PortMappingsFrom(resp.Pending)
+	if !ok || len(pairs) != 1 {
+		t.Fatalf("Expected the port mappings to be reported, got %+v", resp.Pending)
+	}
+	return pairs[0]
+}
+
+func portReserved(p port.Port) bool {
+	_, direct := p.PortPathsFor()
+	_, err := os.Lstat(direct)
+	return err == nil
+}
+
+func TestReassignPort(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Reassigning a port requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	id := containers.Identifier("test-reassign")
+	previous := installWithPort(t, id, server.URL)
+
+	req := &ReassignPortRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: id}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error reassigning: %v", resp.Error)
+	}
+	r, ok := resp.Data.(*ReassignPortResponse)
+	if !ok {
+		t.Fatalf("Expected a reassign response, got %#v", resp.Data)
+	}
+	if r.Internal != 8080 || r.Previous != previous.External || r.External == 0 || r.External == previous.External {
+		t.Fatalf("Expected 8080 to move from %d to a new port, got %+v", previous.External, r)
+	}
+
+	ports, err := containers.GetExistingPorts(id)
+	if err != nil || len(ports) != 1 || ports[0] != (port.PortPair{8080, r.External}) {
+		t.Errorf("Expected the unit to map 8080 to %d, got %v (%v)", r.External, ports, err)
+	}
+	unit, _ := ioutil.ReadFile(id.UnitPathFor())
+	if strings.Contains(string(unit), previous.External.String()+":8080") || !strings.Contains(string(unit), "-p "+r.External.String()+":8080 ") {
+		t.Errorf("Expected Docker to be passed the new port:\n%s", unit)
+	}
+	if !portReserved(r.External) {
+		t.Errorf("Expected the new port %d to be reserved", r.External)
+	}
+	if portReserved(previous.External) {
+		t.Errorf("Expected the old port %d to be released", previous.External)
+	}
+}
+
+func TestReassignPortTaken(t *testing.T) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Reassigning a port requires a stub systemd connection")
+		}
+	}
+	defer withContainerBasePath(t)()
+	backend := &fakePullBackend{present: true}
+	server := httptest.NewServer(backend.handler(t))
+	defer server.Close()
+
+	id := containers.Identifier("test-reassign")
+	previous := installWithPort(t, id, server.URL)
+	taken := installWithPort(t, "test-other", server.URL)
+
+	req := &ReassignPortRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: id, External: taken.External}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	if !jobs.IsConflict(resp.Error) {
+		t.Fatalf("Expected a conflict reassigning to a reserved port, got %v", resp.Error)
+	}
+
+	ports, err := containers.GetExistingPorts(id)
+	if err != nil || len(ports) != 1 || ports[0] != previous {
+		t.Errorf("Expected the unit to be unchanged, got %v (%v)", ports, err)
+	}
+	if !portReserved(previous.External) {
+		t.Errorf("Expected the port %d to remain reserved", previous.External)
+	}
+	if _, err := os.Stat(id.VersionedUnitPathFor(req.RequestIdentifier.String())); !os.IsNotExist(err) {
+		t.Errorf("Expected the new unit definition to be removed, got %v", err)
+	}
+}
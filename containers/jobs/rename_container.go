@@ -0,0 +1,317 @@
+// +build linux
+
+package jobs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/openshift/geard/containers"
+	csystemd "github.com/openshift/geard/containers/systemd"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+	"github.com/openshift/geard/utils"
+)
+
+// The steps taken so far, undone in reverse order if a later one fails.
+type renameSteps []func()
+
+func (s *renameSteps) undo(fn func()) {
+	*s = append(*s, fn)
+}
+
+func (s renameSteps) rollback() {
+	for i := len(s) - 1; i >= 0; i-- {
+		s[i]()
+	}
+}
+
+func (req *RenameContainerRequest) Execute(resp jobs.Response) {
+	from, to := req.Id, req.NewId
+	unitPath := from.UnitPathFor()
+	newUnitPath := to.UnitPathFor()
+
+	if _, err := os.Stat(unitPath); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	// lock the unit to prevent simultaneous updates
+	state, _, err := utils.OpenFileExclusive(unitPath, 0664)
+	if err != nil {
+		log.Print("rename_container: Unable to lock unit file: ", err)
+		resp.Failure(ErrRenameContainerFailed)
+		return
+	}
+	defer state.Close()
+
+	existing, err := ioutil.ReadAll(state)
+	if err != nil {
+		log.Print("rename_container: Unable to read unit file: ", err)
+		resp.Failure(ErrRenameContainerFailed)
+		return
+	}
+	if bytes.Contains(existing, []byte("\nX-ContainerType=isolated\n")) {
+		resp.Failure(jobs.NewInvalidError("The container %s runs as its own user and can't be renamed, reinstall it under the new id instead.", from))
+		return
+	}
+	if activated, _, err := csystemd.GetSocketActivation(from); err != nil {
+		log.Print("rename_container: Unable to read the socket activation of the container: ", err)
+		resp.Failure(ErrRenameContainerFailed)
+		return
+	} else if activated {
+		resp.Failure(jobs.NewInvalidError("The container %s is socket activated and can't be renamed, reinstall it under the new id instead.", from))
+		return
+	}
+	if err := checkNotLinked(from); err != nil {
+		resp.Failure(err)
+		return
+	}
+	image, err := containers.GetContainerImage(from)
+	if err != nil {
+		log.Print("rename_container: Unable to read the image of the container: ", err)
+		resp.Failure(ErrRenameContainerFailed)
+		return
+	}
+	ports, err := containers.GetExistingPorts(from)
+	if err != nil {
+		log.Print("rename_container: Unable to read existing ports: ", err)
+		resp.Failure(ErrRenameContainerFailed)
+		return
+	}
+
+	// claim the new id, which fails if a container already has it
+	if renameTargetExists(to) {
+		resp.Failure(jobs.NewConflictError("A container or environment with the id %s already exists.", to))
+		return
+	}
+	claim, err := utils.CreateFileExclusive(newUnitPath, 0664)
+	if err != nil {
+		if os.IsExist(err) {
+			resp.Failure(jobs.NewConflictError("A container or environment with the id %s already exists.", to))
+			return
+		}
+		log.Print("rename_container: Unable to claim the new unit: ", err)
+		resp.Failure(ErrRenameContainerFailed)
+		return
+	}
+	claim.Close()
+	newVersionPath := to.VersionedUnitPathFor(req.RequestIdentifier.String())
+
+	steps := renameSteps{}
+	steps.undo(func() {
+		os.Remove(newUnitPath)
+		os.RemoveAll(to.VersionedUnitsPathFor())
+	})
+	fail := func(format string, args ...interface{}) {
+		log.Printf("rename_container: "+format+", rolling back", args...)
+		steps.rollback()
+		resp.Failure(ErrRenameContainerFailed)
+	}
+
+	// the container may not write to its files while they are moved
+	wasActive := false
+	if props, err := systemd.Connection().GetUnitProperties(from.UnitNameFor()); err == nil {
+		switch props["ActiveState"] {
+		case "active", "activating", "reloading":
+			wasActive = true
+		}
+	}
+	if wasActive {
+		if status, err := systemd.Connection().StopUnit(from.UnitNameFor(), "replace"); err != nil || status != "done" {
+			fail("Unable to stop %s (%s): %v", from, status, err)
+			return
+		}
+		steps.undo(func() {
+			if err := systemd.Connection().StartUnitJob(from.UnitNameFor(), "replace"); err != nil {
+				log.Printf("rename_container: Unable to start %s again: %v", from, err)
+			}
+		})
+	}
+
+	// write the definition for the new id and move the ports to it
+	if err := ioutil.WriteFile(newVersionPath, renameUnit(existing, from, to, image), 0664); err != nil {
+		fail("Unable to write the new unit definition: %v", err)
+		return
+	}
+	previous, err := port.MoveExternalPorts(ports, newVersionPath)
+	if err != nil {
+		fail("Unable to move the ports of %s: %v", from, err)
+		return
+	}
+	steps.undo(func() {
+		if err := port.RestoreExternalPorts(previous); err != nil {
+			log.Printf("rename_container: Unable to restore the ports of %s: %v", from, err)
+		}
+	})
+
+	for _, paths := range [][2]string{
+		{from.EnvironmentPathFor(), to.EnvironmentPathFor()},
+		{from.BaseHomePath(), to.BaseHomePath()},
+		{from.RunPathFor(), to.RunPathFor()},
+		{from.NetworkLinksPathFor(), to.NetworkLinksPathFor()},
+		{from.LabelsPathFor(), to.LabelsPathFor()},
+		{from.PulledImagePathFor(), to.PulledImagePathFor()},
+		{from.TimesPathFor(), to.TimesPathFor()},
+		{from.PortDescriptionPathFor(), to.PortDescriptionPathFor()},
+		{from.IdleUnitPathFor(), to.IdleUnitPathFor()},
+	} {
+		source, dest := paths[0], paths[1]
+		if _, err := os.Lstat(source); os.IsNotExist(err) {
+			continue
+		}
+		// directories like the run path are created when first named,
+		// an empty one is replaced
+		if info, err := os.Lstat(dest); err == nil && info.IsDir() {
+			os.Remove(dest)
+		}
+		if err := os.Rename(source, dest); err != nil {
+			fail("Unable to move %s to %s: %v", source, dest, err)
+			return
+		}
+		steps.undo(func() {
+			if err := os.Rename(dest, source); err != nil {
+				log.Printf("rename_container: Unable to move %s back to %s: %v", dest, source, err)
+			}
+		})
+	}
+
+	pathUnits := []string{newUnitPath}
+	if unit, err := ioutil.ReadFile(from.PathUnitPathFor()); err == nil {
+		if err := ioutil.WriteFile(to.PathUnitPathFor(), renameUnit(unit, from, to, image), 0664); err != nil {
+			fail("Unable to write the new path unit: %v", err)
+			return
+		}
+		steps.undo(func() { os.Remove(to.PathUnitPathFor()) })
+		pathUnits = append(pathUnits, to.PathUnitPathFor())
+	}
+
+	// the data container holds the volumes of the container
+	if containers.RuntimeName == containers.RuntimeDocker {
+		client, err := docker.GetConnection(req.DockerSocket)
+		if err != nil {
+			fail("Unable to connect to docker: %v", err)
+			return
+		}
+		switch err := client.RenameContainer(string(from)+"-data", string(to)+"-data"); {
+		case err == docker.ErrNoSuchContainer:
+		case err != nil:
+			fail("Unable to rename the data container of %s: %v", from, err)
+			return
+		default:
+			steps.undo(func() {
+				if err := client.RenameContainer(string(to)+"-data", string(from)+"-data"); err != nil {
+					log.Printf("rename_container: Unable to rename the data container of %s back: %v", from, err)
+				}
+			})
+		}
+	}
+
+	if err := utils.AtomicReplaceLink(newVersionPath, newUnitPath); err != nil {
+		fail("Unable to activate the new unit: %v", err)
+		return
+	}
+
+	startOnBoot, err := csystemd.UnitStartOnBoot(from)
+	if err != nil {
+		log.Printf("rename_container: Unable to read whether %s starts on boot: %v", from, err)
+	}
+	if startOnBoot {
+		if err := csystemd.SetUnitStartOnBoot(to, true); err != nil {
+			fail("Unable to write the boot link of %s: %v", to, err)
+			return
+		}
+		steps.undo(func() { csystemd.SetUnitStartOnBoot(to, false) })
+	}
+
+	if err := systemd.EnableAndReloadUnit(systemd.Connection(), to.UnitNameFor(), pathUnits...); err != nil {
+		fail("Unable to enable %s: %v", to, err)
+		return
+	}
+
+	// the new unit is in place, remove the old one
+	if err := removePathUnit(from); err != nil {
+		log.Printf("rename_container: Unable to remove the old path unit: %v", err)
+	}
+	if _, err := systemd.Connection().DisableUnitFiles([]string{unitPath}, false); err != nil {
+		log.Printf("rename_container: Unable to disable the old unit: %v", err)
+	}
+	if err := csystemd.SetUnitStartOnBoot(from, false); err != nil {
+		log.Printf("rename_container: Unable to clear the old boot state: %v", err)
+	}
+	if err := os.Remove(unitPath); err != nil {
+		log.Printf("rename_container: Unable to remove the old unit: %v", err)
+	}
+	if err := os.RemoveAll(from.VersionedUnitsPathFor()); err != nil {
+		log.Printf("rename_container: Unable to remove the old unit definitions: %v", err)
+	}
+	state.Close()
+	if err := systemd.Connection().Reload(); err != nil {
+		log.Printf("rename_container: Unable to reload systemd: %v", err)
+	}
+
+	if len(pathUnits) > 1 {
+		if err := systemd.Connection().StartUnitJob(to.PathUnitNameFor(), "replace"); err != nil {
+			log.Printf("rename_container: Could not start the path unit of %s: %v", to, err)
+		}
+	}
+	if wasActive {
+		if err := systemd.Connection().StartUnitJob(to.UnitNameFor(), "replace"); err != nil {
+			log.Printf("rename_container: Could not start %s: %v", to, err)
+		}
+	}
+
+	log.Printf("rename_container: Renamed %s to %s", from, to)
+	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+	if wasActive {
+		fmt.Fprintf(w, "Container %s renamed to %s and is starting\n", from, to)
+	} else {
+		fmt.Fprintf(w, "Container %s renamed to %s\n", from, to)
+	}
+}
+
+// True if a container, or an environment, already uses id.
+func renameTargetExists(id containers.Identifier) bool {
+	for _, path := range []string{id.UnitPathFor(), id.VersionedUnitsPathFor(), id.EnvironmentPathFor(), id.BaseHomePath()} {
+		if _, err := os.Lstat(path); err == nil || !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// Change the id a unit was written for, in the paths and names that
+// include it.  Values that only happen to equal the id, such as an image
+// of the same name, are left alone.
+func renameUnit(unit []byte, from, to containers.Identifier, image string) []byte {
+	quoted := func(id containers.Identifier) string { return "\"" + string(id) + "\"" }
+	replacements := [][2]string{
+		{from.EnvironmentPathFor(), to.EnvironmentPathFor()},
+		{from.BaseHomePath() + "/", to.BaseHomePath() + "/"},
+		{from.RunPathFor() + "/", to.RunPathFor() + "/"},
+		{"PathChanged=" + from.BaseHomePath() + "\n", "PathChanged=" + to.BaseHomePath() + "\n"},
+		{quoted(from + "-data"), quoted(to + "-data")},
+		{"--name " + quoted(from), "--name " + quoted(to)},
+		{"init --pre " + quoted(from) + " ", "init --pre " + quoted(to) + " "},
+		{"init --post " + quoted(from) + " ", "init --post " + quoted(to) + " "},
+		// the last argument of docker rm and stop, and of ctr
+		{" " + quoted(from) + "\n", " " + quoted(to) + "\n"},
+		// the environment decrypt-env reads, written to the run directory
+		{" " + quoted(from) + " \"" + to.RunPathFor() + "/", " " + quoted(to) + " \"" + to.RunPathFor() + "/"},
+		{"\"" + containers.ContainerdImageRef(image) + "\" " + quoted(from) + " ", "\"" + containers.ContainerdImageRef(image) + "\" " + quoted(to) + " "},
+		{"Description=Container " + string(from) + "\n", "Description=Container " + string(to) + "\n"},
+		{"Description=Container watch " + string(from) + "\n", "Description=Container watch " + string(to) + "\n"},
+		{"X-ContainerId=" + string(from) + "\n", "X-ContainerId=" + string(to) + "\n"},
+		{csystemd.RestartUnitNameFor(from), csystemd.RestartUnitNameFor(to)},
+		{from.LoginFor() + ".", to.LoginFor() + "."},
+	}
+	for _, r := range replacements {
+		unit = bytes.Replace(unit, []byte(r[0]), []byte(r[1]), -1)
+	}
+	return unit
+}
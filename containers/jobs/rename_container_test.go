@@ -0,0 +1,218 @@
+// +build linux
+
+package jobs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+)
+
+// Accepts the rename of data containers, or fails them if failRename is set.
+type fakeRenameBackend struct {
+	fakePullBackend
+	failRename bool
+	renames    []string
+}
+
+func (f *fakeRenameBackend) handler(t *testing.T) http.HandlerFunc {
+	pull := f.fakePullBackend.handler(t)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || !strings.HasSuffix(r.URL.Path, "/rename") {
+			pull(w, r)
+			return
+		}
+		if f.failRename {
+			http.Error(w, "rename failed", http.StatusInternalServerError)
+			return
+		}
+		f.renames = append(f.renames, strings.TrimPrefix(r.URL.Path, "/containers/")+" "+r.URL.Query().Get("name"))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func renameSetup(t *testing.T, backend *fakeRenameBackend) (*httptest.Server, func()) {
+	if err := systemd.StartConnection(); err == nil {
+		if _, ok := systemd.Connection().(*systemd.StubSystemd); !ok {
+			t.Skip("Renaming a container requires a stub systemd connection")
+		}
+	}
+	restore := withContainerBasePath(t)
+	backend.present = true
+	server := httptest.NewServer(backend.handler(t))
+	return server, func() {
+		server.Close()
+		restore()
+	}
+}
+
+func renameContainer(id, to containers.Identifier, socket string) *cmd.CliJobResponse {
+	req := &RenameContainerRequest{RequestIdentifier: jobs.NewRequestIdentifier(), Id: id, NewId: to, DockerSocket: socket}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	return resp
+}
+
+func TestRenameContainer(t *testing.T) {
+	backend := &fakeRenameBackend{}
+	server, done := renameSetup(t, backend)
+	defer done()
+
+	from, to := containers.Identifier("test-rename"), containers.Identifier("test-renamed")
+	pair := installWithPort(t, from, server.URL)
+	writeEnvironment(t, from, containers.Environment{Name: "A", Value: "1"})
+	if err := os.MkdirAll(from.HomePath(), 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(from.HomePath(), "data"), []byte("kept"), 0664); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := renameContainer(from, to, server.URL); resp.Error != nil {
+		t.Fatalf("Unexpected error renaming: %v", resp.Error)
+	}
+
+	for _, path := range []string{from.UnitPathFor(), from.VersionedUnitsPathFor(), from.EnvironmentPathFor(), from.BaseHomePath()} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed: %v", path, err)
+		}
+	}
+	if env := readEnvironment(t, to); env["A"] != "1" {
+		t.Errorf("Expected the environment to move, got %v", env)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(to.HomePath(), "data")); err != nil || string(data) != "kept" {
+		t.Errorf("Expected the home directory to move: %q %v", data, err)
+	}
+
+	unit, err := ioutil.ReadFile(to.UnitPathFor())
+	if err != nil {
+		t.Fatalf("Expected a unit for the new id: %v", err)
+	}
+	for _, s := range []string{"X-ContainerId=test-renamed\n", "--name \"test-renamed\"", "\"test-renamed-data\"", "init --post \"test-renamed\""} {
+		if !strings.Contains(string(unit), s) {
+			t.Errorf("Expected the unit to contain %q:\n%s", s, unit)
+		}
+	}
+	if strings.Contains(string(unit), "test-rename\"") || strings.Contains(string(unit), "test-rename-data") || strings.Contains(string(unit), "test-rename/") {
+		t.Errorf("Expected the unit to no longer refer to the old id:\n%s", unit)
+	}
+
+	ports, err := containers.GetExistingPorts(to)
+	if err != nil || len(ports) != 1 || ports[0] != pair {
+		t.Errorf("Expected the new id to keep port %v, got %v (%v)", pair, ports, err)
+	}
+	_, direct := pair.External.PortPathsFor()
+	if target, err := os.Readlink(direct); err != nil || !strings.HasPrefix(target, to.VersionedUnitsPathFor()+"/") {
+		t.Errorf("Expected the port to be reserved for the new id, got %s %v", target, err)
+	}
+
+	if len(backend.renames) != 1 || backend.renames[0] != "test-rename-data/rename test-renamed-data" {
+		t.Errorf("Expected the data container to be renamed, got %v", backend.renames)
+	}
+}
+
+func TestRenameContainerCollision(t *testing.T) {
+	backend := &fakeRenameBackend{}
+	server, done := renameSetup(t, backend)
+	defer done()
+
+	from, to := containers.Identifier("test-rename"), containers.Identifier("test-taken")
+	pair := installWithPort(t, from, server.URL)
+	installWithPort(t, to, server.URL)
+	before, _ := ioutil.ReadFile(from.UnitPathFor())
+
+	resp := renameContainer(from, to, server.URL)
+	if resp.Error == nil {
+		t.Fatal("Expected renaming onto an existing container to fail")
+	}
+	if e, ok := resp.Error.(jobs.JobError); !ok || e.ResponseFailure() != jobs.ResponseAlreadyExists {
+		t.Errorf("Expected a conflict, got %#v", resp.Error)
+	}
+
+	if after, err := ioutil.ReadFile(from.UnitPathFor()); err != nil || string(after) != string(before) {
+		t.Errorf("Expected the container to be unchanged: %v", err)
+	}
+	if ports, err := containers.GetExistingPorts(from); err != nil || len(ports) != 1 || ports[0] != pair {
+		t.Errorf("Expected the container to keep its port, got %v (%v)", ports, err)
+	}
+	if len(backend.renames) != 0 {
+		t.Errorf("Expected no data container to be renamed, got %v", backend.renames)
+	}
+
+	writeEnvironment(t, "test-env-only", containers.Environment{Name: "A", Value: "1"})
+	if resp := renameContainer(from, "test-env-only", server.URL); resp.Error == nil {
+		t.Error("Expected renaming onto an existing environment to fail")
+	}
+}
+
+func TestRenameContainerRollback(t *testing.T) {
+	backend := &fakeRenameBackend{failRename: true}
+	server, done := renameSetup(t, backend)
+	defer done()
+
+	from, to := containers.Identifier("test-rename"), containers.Identifier("test-renamed")
+	pair := installWithPort(t, from, server.URL)
+	writeEnvironment(t, from, containers.Environment{Name: "A", Value: "1"})
+	before, _ := ioutil.ReadFile(from.UnitPathFor())
+
+	resp := renameContainer(from, to, server.URL)
+	if resp.Error != ErrRenameContainerFailed {
+		t.Fatalf("Expected the rename to fail, got %v", resp.Error)
+	}
+
+	if after, err := ioutil.ReadFile(from.UnitPathFor()); err != nil || string(after) != string(before) {
+		t.Errorf("Expected the unit to be unchanged: %v", err)
+	}
+	if env := readEnvironment(t, from); env["A"] != "1" {
+		t.Errorf("Expected the environment to be restored, got %v", env)
+	}
+	_, direct := pair.External.PortPathsFor()
+	if target, err := os.Readlink(direct); err != nil || !strings.HasPrefix(target, from.VersionedUnitsPathFor()+"/") {
+		t.Errorf("Expected the port to be reserved for the old id again, got %s %v", target, err)
+	}
+	for _, path := range []string{to.UnitPathFor(), to.VersionedUnitsPathFor(), to.EnvironmentPathFor()} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed: %v", path, err)
+		}
+	}
+}
+
+func TestRenameSocketActivatedContainer(t *testing.T) {
+	backend := &fakeRenameBackend{}
+	server, done := renameSetup(t, backend)
+	defer done()
+
+	from, to := containers.Identifier("test-rename"), containers.Identifier("test-renamed")
+	installWithPort(t, from, server.URL)
+	unit, err := ioutil.ReadFile(from.UnitPathFor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	unit = append(unit, []byte("X-SocketActivated=proxied\n")...)
+	if err := ioutil.WriteFile(from.UnitPathFor(), unit, 0664); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := renameContainer(from, to, server.URL)
+	if !jobs.IsInvalid(resp.Error) || !strings.Contains(resp.Error.Error(), "socket activated") {
+		t.Fatalf("Expected renaming a socket activated container to be rejected, got %v", resp.Error)
+	}
+	if after, err := ioutil.ReadFile(from.UnitPathFor()); err != nil || string(after) != string(unit) {
+		t.Errorf("Expected the container to be unchanged: %v", err)
+	}
+	if _, err := os.Lstat(to.UnitPathFor()); !os.IsNotExist(err) {
+		t.Errorf("Expected no unit for the new id: %v", err)
+	}
+	if len(backend.renames) != 0 {
+		t.Errorf("Expected no data container to be renamed, got %v", backend.renames)
+	}
+}
@@ -0,0 +1,43 @@
+// +build linux
+
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/systemd"
+)
+
+func (j *ResetFailedContainerRequest) Execute(resp jobs.Response) {
+	if _, err := os.Stat(j.Id.UnitPathFor()); err != nil {
+		resp.Failure(ErrContainerNotFound)
+		return
+	}
+
+	unitName := j.Id.UnitNameFor()
+	restarts, err := resetFailedUnit(systemd.Connection(), unitName)
+	if err != nil {
+		log.Printf("reset_failed: Unable to reset %s: %v", unitName, err)
+		resp.Failure(ErrContainerResetFailed)
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+	if restarts != nil {
+		fmt.Fprintf(w, "Reset the failed state of %s, its restart count is %d\n", j.Id, *restarts)
+	} else {
+		fmt.Fprintf(w, "Reset the failed state of %s\n", j.Id)
+	}
+}
+
+// Reset a unit and return the restart count systemd reports for it
+// afterwards, nil if it doesn't report one.
+func resetFailedUnit(conn systemd.Systemd, unitName string) (*uint32, error) {
+	if err := systemd.ResetFailedUnit(conn, unitName); err != nil {
+		return nil, err
+	}
+	return systemd.UnitRestarts(conn, unitName), nil
+}
@@ -0,0 +1,60 @@
+// +build linux
+
+package jobs
+
+import (
+	"testing"
+
+	"github.com/openshift/geard/systemd"
+)
+
+// A systemd whose units have restarted until they are reset, recording each
+// reset.
+type crashLoopSystemd struct {
+	*systemd.StubSystemd
+	restarts uint32
+	resets   []string
+}
+
+func (s *crashLoopSystemd) ResetFailedUnit(name string) error {
+	s.resets = append(s.resets, name)
+	s.restarts = 0
+	return nil
+}
+
+func (s *crashLoopSystemd) GetUnitProperties(unit string) (map[string]interface{}, error) {
+	state := "active"
+	if s.restarts > 0 {
+		state = "failed"
+	}
+	return map[string]interface{}{"ActiveState": state}, nil
+}
+
+func (s *crashLoopSystemd) GetUnitTypeProperties(unit string, unitType string) (map[string]interface{}, error) {
+	if unitType != "Service" {
+		return map[string]interface{}{}, nil
+	}
+	return map[string]interface{}{"NRestarts": s.restarts}, nil
+}
+
+func TestResetFailedUnit(t *testing.T) {
+	conn := &crashLoopSystemd{StubSystemd: systemd.NewStubSystemd(), restarts: 5}
+	if restarts := systemd.UnitRestarts(conn, "ctr-test-web.service"); restarts == nil || *restarts != 5 {
+		t.Fatalf("Expected the restart count of the crash looping unit, got %v", restarts)
+	}
+
+	restarts, err := resetFailedUnit(conn, "ctr-test-web.service")
+	if err != nil {
+		t.Fatalf("Unable to reset the unit: %v", err)
+	}
+	if len(conn.resets) != 1 || conn.resets[0] != "ctr-test-web.service" {
+		t.Errorf("Expected one reset of the unit, got %v", conn.resets)
+	}
+	if restarts == nil || *restarts != 0 {
+		t.Errorf("Expected the restart count to be cleared, got %v", restarts)
+	}
+
+	if restarts := systemd.UnitRestarts(systemd.NewStubSystemd(), "ctr-test-web.service"); restarts != nil {
+		t.Errorf("Expected no restart count from an older systemd, got %d", *restarts)
+	}
+}
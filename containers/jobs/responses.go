@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
 	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/geard/containers"
 )
 
 func (c UnitResponses) Less(a, b int) bool {
@@ -54,16 +58,224 @@ type ListServerContainersResponse struct {
 }
 
 func (l *ListServerContainersResponse) WriteTableTo(w io.Writer) error {
+	return l.writeTable(w, false)
+}
+
+// Write the containers grouped by GroupByVariant, with the base name and
+// variant of each in columns of their own.
+func (l *ListServerContainersResponse) WriteGroupedTableTo(w io.Writer) error {
+	l.GroupByVariant()
+	return l.writeTable(w, true)
+}
+
+func (l *ListServerContainersResponse) writeTable(w io.Writer, grouped bool) error {
 	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
-	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", "ID", "SERVER", "ACTIVE", "SUB", "LOAD", "TYPE"); err != nil {
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", idHeader(grouped), "SERVER", "ACTIVE", "SUB", "LOAD", "TYPE"); err != nil {
 		return err
 	}
 	for i := range l.Containers {
 		container := &l.Containers[i]
-		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", container.Id, container.Server, container.ActiveState, container.SubState, container.LoadState, container.JobType); err != nil {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", idColumns(container.Id, grouped), container.Server, container.ActiveState, container.SubState, container.LoadState, container.JobType); err != nil {
 			return err
 		}
 	}
 	tw.Flush()
 	return nil
 }
+
+func (c ContainerStatusResponses) WriteTableTo(w io.Writer) error {
+	return c.writeTable(w, false)
+}
+
+// Write the statuses grouped by GroupByVariant, with the base name and
+// variant of each container in columns of their own.
+func (c ContainerStatusResponses) WriteGroupedTableTo(w io.Writer) error {
+	c.GroupByVariant()
+	return c.writeTable(w, true)
+}
+
+func (c ContainerStatusResponses) writeTable(w io.Writer, grouped bool) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", idHeader(grouped), "SERVER", "ACTIVE", "SUB", "INSTALLED", "REVISION", "UPTIME", "RESTARTS", "MEM USED", "MEM LIMIT", "CPU SHARES", "CPUSET", "CPU TIME", "ENV SIZE", "LABELS"); err != nil {
+		return err
+	}
+	for i := range c {
+		status := &c[i]
+		memory, cpu := "-", "-"
+		if status.Usage != nil {
+			memory = fmt.Sprintf("%.1fM", float64(status.Usage.MemoryUsage)/(1024*1024))
+			cpu = (time.Duration(status.Usage.CPUUsage) / time.Millisecond * time.Millisecond).String()
+		}
+		installed, uptime := "-", "-"
+		if status.Installed != nil {
+			installed = status.Installed.Local().Format("2006-01-02 15:04")
+		}
+		if status.UptimeSeconds != nil {
+			uptime = (time.Duration(*status.UptimeSeconds) * time.Second).String()
+		}
+		env := "-"
+		if status.EnvironmentSize > 0 {
+			env = fmt.Sprintf("%dB", status.EnvironmentSize)
+		}
+		labels := "-"
+		if len(status.Labels) > 0 {
+			labels = status.Labels.String()
+		}
+		sub := status.SubState
+		if status.Paused {
+			sub = ContainerStatePaused
+		}
+		cpuset := "-"
+		if status.Limits.CPUSetCPUs != "" {
+			cpuset = string(status.Limits.CPUSetCPUs)
+		}
+		restarts := "-"
+		if status.Restarts != nil {
+			restarts = fmt.Sprintf("%d", *status.Restarts)
+		}
+		revision := "-"
+		if status.Revision != "" {
+			revision = string(status.Revision)
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", idColumns(status.Id, grouped), status.Server, status.ActiveState, sub, installed, revision, uptime, restarts, memory, status.Limits.MemoryLimit, status.Limits.CPUShares, cpuset, cpu, env, labels); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+func (r *HostStatusResponse) WriteTableTo(w io.Writer) error {
+	return HostStatusResponses{*r}.WriteTableTo(w)
+}
+
+func (c HostStatusResponses) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", "SERVER", "CONTAINERS", "RUNNING", "STOPPED", "MEM LIMIT", "CPU SHARES", "PORTS USED", "PORTS FREE", "PORT RANGE"); err != nil {
+		return err
+	}
+	for i := range c {
+		status := &c[i]
+		server := status.Server
+		if server == "" {
+			server = "local"
+		}
+		memory := fmt.Sprintf("%.1fM", float64(status.MemoryLimit)/(1024*1024))
+		if status.MemoryUnlimited > 0 {
+			memory = fmt.Sprintf("%s (+%d unlimited)", memory, status.MemoryUnlimited)
+		}
+		cpu := strconv.FormatUint(status.CPUShares, 10)
+		if status.CPUUnlimited > 0 {
+			cpu = fmt.Sprintf("%s (+%d unlimited)", cpu, status.CPUUnlimited)
+		}
+		stopped := strconv.Itoa(status.Stopped)
+		if status.Unknown > 0 {
+			stopped = fmt.Sprintf("%s (%d unknown)", stopped, status.Unknown)
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%d\t%d\t%s\n", server, status.Containers, status.Running, stopped, memory, cpu, status.PortsAllocated, status.PortsAvailable, status.PortRange); err != nil {
+			return err
+		}
+	}
+	tw.Flush()
+	return nil
+}
+
+// The state shown for a container that was reported by an earlier poll
+// but no longer exists.
+const ContainerStateRemoved = "removed"
+
+// The sub state shown for a running container whose processes are frozen.
+const ContainerStatePaused = "paused"
+
+// Return the statuses along with an entry, marked as removed, for each
+// container in previous that is no longer reported.
+func (c ContainerStatusResponses) WithRemoved(previous ContainerStatusResponses) ContainerStatusResponses {
+	found := make(map[string]bool, len(c))
+	for i := range c {
+		found[c[i].Server+"/"+c[i].Id] = true
+	}
+	for i := range previous {
+		if !found[previous[i].Server+"/"+previous[i].Id] {
+			c = append(c, ContainerStatusResponse{
+				UnitResponse: UnitResponse{Id: previous[i].Id, ActiveState: ContainerStateRemoved, SubState: ContainerStateRemoved},
+				Limits:       previous[i].Limits,
+				Labels:       previous[i].Labels,
+				Installed:    previous[i].Installed,
+				Server:       previous[i].Server,
+			})
+		}
+	}
+	return c
+}
+
+// Order the statuses so that the variants of each container, such as web
+// and web--canary, are listed together after the container without a
+// variant.
+func (c ContainerStatusResponses) GroupByVariant() {
+	sort.Stable(statusesByVariant(c))
+}
+
+// Order the containers as ContainerStatusResponses.GroupByVariant does.
+func (r *ListContainersResponse) GroupByVariant() {
+	sort.Stable(unitsByVariant(r.Containers))
+}
+
+type statusesByVariant ContainerStatusResponses
+
+func (c statusesByVariant) Less(a, b int) bool {
+	if c[a].Id == c[b].Id {
+		return c[a].Server < c[b].Server
+	}
+	return variantLess(c[a].Id, c[b].Id)
+}
+func (c statusesByVariant) Len() int {
+	return len(c)
+}
+func (c statusesByVariant) Swap(a, b int) {
+	c[a], c[b] = c[b], c[a]
+}
+
+type unitsByVariant ContainerUnitResponses
+
+func (c unitsByVariant) Less(a, b int) bool {
+	if c[a].Id == c[b].Id {
+		return c[a].Server < c[b].Server
+	}
+	return variantLess(c[a].Id, c[b].Id)
+}
+func (c unitsByVariant) Len() int {
+	return len(c)
+}
+func (c unitsByVariant) Swap(a, b int) {
+	c[a], c[b] = c[b], c[a]
+}
+
+func variantLess(a, b string) bool {
+	baseA, variantA := containers.Identifier(a).Variant()
+	baseB, variantB := containers.Identifier(b).Variant()
+	if baseA != baseB {
+		return baseA < baseB
+	}
+	return variantA < variantB
+}
+
+// The header of the columns naming a container in a table
+func idHeader(grouped bool) string {
+	if grouped {
+		return "NAME\tVARIANT"
+	}
+	return "ID"
+}
+
+// The columns naming a container in a table, split into its base name and
+// variant when grouped
+func idColumns(id string, grouped bool) string {
+	if !grouped {
+		return id
+	}
+	base, variant := containers.Identifier(id).Variant()
+	if variant == "" {
+		variant = "-"
+	}
+	return base + "\t" + variant
+}
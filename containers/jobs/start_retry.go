@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/openshift/geard/systemd"
+)
+
+// Start a unit and wait for systemd to report the result, starting it again
+// after interval if it failed, up to retries more times.  Each failed
+// attempt is reported to w.  A start fails if its job doesn't finish as
+// done, or if the unit is failed or inactive once it has.  Waiting between
+// attempts stops when ctx is done.  The after function is time.After,
+// except in tests.
+func startUnitWithRetries(ctx context.Context, conn systemd.Systemd, unitName string, retries int, interval time.Duration, w io.Writer, after func(time.Duration) <-chan time.Time) error {
+	attempts := retries + 1
+	for attempt := 1; ; attempt++ {
+		err := startUnitAndCheck(conn, unitName)
+		if err == nil {
+			if attempt > 1 {
+				fmt.Fprintf(w, "Started %s on attempt %d of %d\n", unitName, attempt, attempts)
+			}
+			return nil
+		}
+		fmt.Fprintf(w, "Attempt %d of %d to start %s failed: %s\n", attempt, attempts, unitName, err.Error())
+		if attempt == attempts {
+			return fmt.Errorf("%s failed to start after %d attempts: %s", unitName, attempts, err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-after(interval):
+		}
+	}
+}
+
+func startUnitAndCheck(conn systemd.Systemd, unitName string) error {
+	status, err := conn.StartUnit(unitName, "replace")
+	if err != nil {
+		return err
+	}
+	if status != "done" {
+		return fmt.Errorf("the start job finished as %s", status)
+	}
+	// the unit may have exited as soon as it was started
+	if props, err := conn.GetUnitProperties(unitName); err == nil {
+		switch state, _ := props["ActiveState"].(string); state {
+		case "failed", "inactive":
+			return fmt.Errorf("the unit is %s", state)
+		}
+	}
+	return nil
+}
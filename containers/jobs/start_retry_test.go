@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/systemd"
+)
+
+// A systemd whose units fail to start until they have been started a
+// number of times.  The first failure is reported by the start job, later
+// ones by the unit exiting as soon as it started.
+type flakySystemd struct {
+	*systemd.StubSystemd
+	failures int
+	starts   int
+}
+
+func (s *flakySystemd) StartUnit(name string, mode string) (string, error) {
+	s.starts++
+	if s.starts == 1 && s.failures > 0 {
+		return "failed", nil
+	}
+	return "done", nil
+}
+
+func (s *flakySystemd) GetUnitProperties(unit string) (map[string]interface{}, error) {
+	if s.starts <= s.failures {
+		return map[string]interface{}{"ActiveState": "failed"}, nil
+	}
+	return map[string]interface{}{"ActiveState": "active"}, nil
+}
+
+// Records each wait instead of sleeping
+type recordedWaits []time.Duration
+
+func (r *recordedWaits) after(d time.Duration) <-chan time.Time {
+	*r = append(*r, d)
+	c := make(chan time.Time, 1)
+	c <- time.Now()
+	return c
+}
+
+func TestStartUnitWithRetries(t *testing.T) {
+	conn := &flakySystemd{StubSystemd: systemd.NewStubSystemd(), failures: 2}
+	waits := &recordedWaits{}
+	out := &bytes.Buffer{}
+	if err := startUnitWithRetries(context.Background(), conn, "ctr-test-web.service", 4, 5*time.Second, out, waits.after); err != nil {
+		t.Fatalf("Expected the unit to start on the third attempt, got %v", err)
+	}
+	if conn.starts != 3 {
+		t.Errorf("Expected 3 starts, got %d", conn.starts)
+	}
+	if !reflect.DeepEqual([]time.Duration(*waits), []time.Duration{5 * time.Second, 5 * time.Second}) {
+		t.Errorf("Expected to wait the interval after each failure, got %v", *waits)
+	}
+	for _, s := range []string{
+		"Attempt 1 of 5 to start ctr-test-web.service failed: the start job finished as failed\n",
+		"Attempt 2 of 5 to start ctr-test-web.service failed: the unit is failed\n",
+		"Started ctr-test-web.service on attempt 3 of 5\n",
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected the attempts to be reported with %q:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestStartUnitWithRetriesExhausted(t *testing.T) {
+	conn := &flakySystemd{StubSystemd: systemd.NewStubSystemd(), failures: 3}
+	waits := &recordedWaits{}
+	err := startUnitWithRetries(context.Background(), conn, "ctr-test-web.service", 1, time.Second, &bytes.Buffer{}, waits.after)
+	if err == nil || !strings.Contains(err.Error(), "failed to start after 2 attempts") {
+		t.Errorf("Expected the start to fail after 2 attempts, got %v", err)
+	}
+	if conn.starts != 2 || len(*waits) != 1 {
+		t.Errorf("Expected 2 starts and 1 wait, got %d and %v", conn.starts, *waits)
+	}
+}
+
+func TestStartUnitWithRetriesCancelled(t *testing.T) {
+	conn := &flakySystemd{StubSystemd: systemd.NewStubSystemd(), failures: 3}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	never := func(time.Duration) <-chan time.Time { return nil }
+	if err := startUnitWithRetries(ctx, conn, "ctr-test-web.service", 3, time.Second, &bytes.Buffer{}, never); err != context.Canceled {
+		t.Errorf("Expected the retries to stop when cancelled, got %v", err)
+	}
+	if conn.starts != 1 {
+		t.Errorf("Expected a single start, got %d", conn.starts)
+	}
+}
+
+func TestInstallStartRetriesChecked(t *testing.T) {
+	for _, req := range []InstallContainerRequest{
+		{StartRetries: 2, StartRetryInterval: 1},
+		{Started: true, StartRetries: 2},
+		{Started: true, StartRetries: MaxStartRetries + 1, StartRetryInterval: 1},
+		{Started: true, StartRetryInterval: 1},
+	} {
+		if err := req.checkStartRetries(); err == nil {
+			t.Errorf("Expected %+v to be rejected", req)
+		}
+	}
+	req := InstallContainerRequest{Started: true, StartRetries: 2, StartRetryInterval: 5}
+	if err := req.checkStartRetries(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
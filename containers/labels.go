@@ -0,0 +1,170 @@
+package containers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Labels are key value pairs attached to a container when it is installed,
+// used to select groups of containers.
+type Labels map[string]string
+
+var (
+	allowedLabelKey   = regexp.MustCompile("\\A[a-zA-Z0-9]([a-zA-Z0-9_\\-\\./]{0,61}[a-zA-Z0-9])?\\z")
+	allowedLabelValue = regexp.MustCompile("\\A([a-zA-Z0-9]([a-zA-Z0-9_\\-\\.]{0,61}[a-zA-Z0-9])?)?\\z")
+)
+
+func CheckLabelKey(key string) error {
+	if !allowedLabelKey.MatchString(key) {
+		return fmt.Errorf("The label key '%s' must match %s", key, allowedLabelKey.String())
+	}
+	return nil
+}
+
+func CheckLabelValue(value string) error {
+	if !allowedLabelValue.MatchString(value) {
+		return fmt.Errorf("The label value '%s' must match %s", value, allowedLabelValue.String())
+	}
+	return nil
+}
+
+func (l Labels) Check() error {
+	for key, value := range l {
+		if err := CheckLabelKey(key); err != nil {
+			return err
+		}
+		if err := CheckLabelValue(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse a label of the form <key>=<value>
+func NewLabelFromString(s string) (key, value string, err error) {
+	pair := strings.SplitN(s, "=", 2)
+	if len(pair) != 2 {
+		return "", "", fmt.Errorf("The label '%s' must be of the form <key>=<value>", s)
+	}
+	key, value = strings.TrimSpace(pair[0]), strings.TrimSpace(pair[1])
+	if err := CheckLabelKey(key); err != nil {
+		return "", "", err
+	}
+	if err := CheckLabelValue(value); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func (l Labels) Keys() []string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The labels as sorted <key>=<value> pairs separated by commas
+func (l Labels) String() string {
+	keys := l.Keys()
+	for i := range keys {
+		keys[i] = keys[i] + "=" + l[keys[i]]
+	}
+	return strings.Join(keys, ",")
+}
+
+func (l Labels) Write(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		log.Print("labels: Unable to open labels file: ", err)
+		return err
+	}
+	defer file.Close()
+
+	for _, key := range l.Keys() {
+		if _, err := fmt.Fprintf(file, "%s=%s\n", key, l[key]); err != nil {
+			log.Print("labels: Unable to write labels: ", err)
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		log.Print("labels: Unable to close labels file: ", err)
+		return err
+	}
+	return nil
+}
+
+// Read labels written one <key>=<value> pair per line.  Blank lines and
+// lines starting with '#' are ignored.
+func ReadLabelsFrom(r io.Reader) (Labels, error) {
+	labels := make(Labels)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		key, value, err := NewLabelFromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		labels[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// Read labels from a file of <key>=<value> lines.
+func ReadLabelsFile(path string) (Labels, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	labels, err := ReadLabelsFrom(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return labels, nil
+}
+
+// Return the combination of both sets of labels, preferring the value in
+// other when a key is in both.
+func (l Labels) Merge(other Labels) Labels {
+	if l == nil && other == nil {
+		return nil
+	}
+	merged := make(Labels, len(l)+len(other))
+	for key, value := range l {
+		merged[key] = value
+	}
+	for key, value := range other {
+		merged[key] = value
+	}
+	return merged
+}
+
+// The labels of an installed container.  A container without labels
+// returns an empty set.
+func GetExistingLabels(id Identifier) (Labels, error) {
+	file, err := os.Open(id.LabelsPathFor())
+	if os.IsNotExist(err) {
+		return Labels{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadLabelsFrom(file)
+}
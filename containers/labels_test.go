@@ -0,0 +1,37 @@
+package containers
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestLabelsFileMerge(t *testing.T) {
+	file, err := ioutil.TempFile("", "labels")
+	if err != nil {
+		t.Fatalf("Unable to create a labels file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("# shared labels\nenv=qa\ntier=web\n")
+	file.Close()
+
+	fromFile, err := ReadLabelsFile(file.Name())
+	if err != nil {
+		t.Fatalf("Unable to read labels file: %v", err)
+	}
+	merged := fromFile.Merge(Labels{"env": "prod", "team": "core"})
+	if s := merged.String(); s != "env=prod,team=core,tier=web" {
+		t.Errorf("Expected inline labels to take precedence over the file, got %s", s)
+	}
+	if fromFile["env"] != "qa" {
+		t.Error("Merging should not modify the labels read from the file")
+	}
+	if Labels(nil).Merge(nil) != nil {
+		t.Error("Expected merging no labels to return no labels")
+	}
+
+	ioutil.WriteFile(file.Name(), []byte("env=qa\nbad key=value\n"), 0644)
+	if _, err := ReadLabelsFile(file.Name()); err == nil {
+		t.Error("Expected an invalid label key in the file to be rejected")
+	}
+}
@@ -0,0 +1,110 @@
+package containers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The Docker logging driver that stores the output of a container, the
+// Docker default if empty.
+type LogDriver string
+
+// The logging drivers a container may use, and whether Docker still
+// passes the output of the container to the unit (and so to the journal)
+// when it is used.  Docker can only attach to the output of drivers it
+// can read back.
+var logDrivers = map[LogDriver]bool{
+	"json-file": true,
+	"local":     true,
+	"journald":  true,
+	"syslog":    false,
+	"gelf":      false,
+	"fluentd":   false,
+	"awslogs":   false,
+	"splunk":    false,
+	"none":      false,
+}
+
+func (d LogDriver) Check() error {
+	if d == "" {
+		return nil
+	}
+	if _, ok := logDrivers[d]; !ok {
+		names := make([]string, 0, len(logDrivers))
+		for name := range logDrivers {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		return fmt.Errorf("The log driver '%s' must be one of %s", string(d), strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// True if the output of the container is written to the journal of its
+// unit, where the logs and status of the container are read from.
+func (d LogDriver) Readable() bool {
+	return d == "" || logDrivers[d]
+}
+
+// An option passed to the logging driver, such as max-size=10m
+type LogOption struct {
+	Key   string
+	Value string
+}
+
+type LogOptions []LogOption
+
+var allowedLogOptionKey = regexp.MustCompile("\\A[a-z0-9][a-z0-9\\-_.]*\\z")
+
+// Parse an option of the form <key>=<value>
+func NewLogOptionFromString(s string) (LogOption, error) {
+	pair := strings.SplitN(s, "=", 2)
+	if len(pair) != 2 {
+		return LogOption{}, fmt.Errorf("The log option '%s' must be of the form <key>=<value>", s)
+	}
+	o := LogOption{pair[0], pair[1]}
+	if err := o.Check(); err != nil {
+		return LogOption{}, err
+	}
+	return o, nil
+}
+
+func (o LogOption) Check() error {
+	if !allowedLogOptionKey.MatchString(o.Key) {
+		return fmt.Errorf("The log option key '%s' may only contain lowercase letters, numbers, '-', '_', and '.'", o.Key)
+	}
+	if o.Value == "" || strings.ContainsAny(o.Value, "\r\n") {
+		return fmt.Errorf("The log option '%s' must have a value on a single line", o.Key)
+	}
+	return nil
+}
+
+func (o LogOption) String() string {
+	return o.Key + "=" + o.Value
+}
+
+func (o LogOptions) Check() error {
+	for i := range o {
+		if err := o[i].Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o LogOptions) String() string {
+	options := make([]string, len(o))
+	for i := range o {
+		options[i] = o[i].String()
+	}
+	return strings.Join(options, " ")
+}
+
+// The logging driver the container was installed with, or an empty
+// string if it uses the Docker default.
+func GetLogDriver(id Identifier) (LogDriver, error) {
+	driver, err := readUnitValue(id, "X-ContainerLogDriver")
+	return LogDriver(driver), err
+}
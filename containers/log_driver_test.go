@@ -0,0 +1,48 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestLogDriver(t *testing.T) {
+	for _, driver := range []LogDriver{"", "json-file", "journald", "syslog", "none"} {
+		if err := driver.Check(); err != nil {
+			t.Errorf("Expected the log driver %q to be valid: %v", driver, err)
+		}
+	}
+	for _, driver := range []LogDriver{"json", "JSON-FILE", "custom-plugin"} {
+		if err := driver.Check(); err == nil {
+			t.Errorf("Expected the log driver %q to be rejected", driver)
+		}
+	}
+	if !LogDriver("").Readable() || !LogDriver("json-file").Readable() || LogDriver("syslog").Readable() || LogDriver("none").Readable() {
+		t.Error("Expected only the default and readable drivers to write to the journal")
+	}
+}
+
+func TestLogOption(t *testing.T) {
+	for value, expected := range map[string]LogOption{
+		"max-size=10m":                {"max-size", "10m"},
+		"labels=a,b":                  {"labels", "a,b"},
+		"syslog-address=udp://h:514":  {"syslog-address", "udp://h:514"},
+		"tag={{.Name}}={{.ID}}":       {"tag", "{{.Name}}={{.ID}}"},
+		"env-regex=^APP_[A-Z]+$":      {"env-regex", "^APP_[A-Z]+$"},
+		"fluentd-async-connect=true":  {"fluentd-async-connect", "true"},
+		"gelf-compression-type=gzip":  {"gelf-compression-type", "gzip"},
+		"awslogs-group=my.log_group1": {"awslogs-group", "my.log_group1"},
+	} {
+		option, err := NewLogOptionFromString(value)
+		if err != nil {
+			t.Errorf("Unable to parse log option %q: %v", value, err)
+			continue
+		}
+		if option != expected || option.String() != value {
+			t.Errorf("Expected log option %q to be %+v, got %+v", value, expected, option)
+		}
+	}
+	for _, value := range []string{"max-size", "=10m", "max-size=", "Max-Size=10m", "max size=10m", "tag=a\nb"} {
+		if _, err := NewLogOptionFromString(value); err == nil {
+			t.Errorf("Expected log option %q to be rejected", value)
+		}
+	}
+}
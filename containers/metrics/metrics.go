@@ -0,0 +1,142 @@
+// Gauges about the containers on this host, served on the /metrics
+// endpoint of the daemon.
+package metrics
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/http"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+)
+
+// How long the state of the containers is served before it is read again,
+// so that frequent scrapes don't ask systemd and Docker about every
+// container each time.
+const DefaultTTL = 10 * time.Second
+
+// The state of a container when the metrics were collected
+type Container struct {
+	Id      containers.Identifier
+	Image   string
+	Running bool
+	// The times systemd has restarted the unit, absent if systemd doesn't
+	// report it
+	Restarts *uint32
+	// Bytes of memory in use, absent unless the container is running
+	MemoryUsage *uint64
+	Ports       port.PortPairs
+}
+
+// Collects the state of the containers on this host, at most once per TTL.
+type Collector struct {
+	DockerSocket string
+	TTL          time.Duration
+
+	// Reads the state of every installed container, by default from their
+	// unit files, systemd, and the runtime
+	Read func() []Container
+
+	lock      sync.Mutex
+	collected time.Time
+	cached    []Container
+	now       func() time.Time
+}
+
+func NewCollector(dockerSocket string) *Collector {
+	c := &Collector{DockerSocket: dockerSocket, TTL: DefaultTTL, now: time.Now}
+	c.Read = c.readContainers
+	return c
+}
+
+// The state of the containers, read again if it was collected more than TTL
+// ago.  Scrapes that arrive while it is read wait for the same read.
+func (c *Collector) Containers() []Container {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if now := c.now(); c.collected.IsZero() || now.Sub(c.collected) >= c.TTL {
+		c.cached = c.Read()
+		c.collected = now
+	}
+	return c.cached
+}
+
+func (c *Collector) readContainers() []Container {
+	ids, err := containers.InstalledIdentifiers()
+	if err != nil {
+		log.Printf("metrics: Unable to list the installed containers: %v", err)
+		return nil
+	}
+	runtime, err := containers.NewRuntime(c.DockerSocket)
+	if err != nil {
+		log.Printf("metrics: Unable to connect to the runtime: %v", err)
+	}
+
+	all := make([]Container, 0, len(ids))
+	for _, id := range ids {
+		container := Container{Id: id}
+		container.Image, _ = containers.GetContainerImage(id)
+		if ports, err := containers.GetExistingPorts(id); err == nil {
+			container.Ports = ports
+		}
+		if props, err := systemd.Connection().GetUnitProperties(id.UnitNameFor()); err == nil {
+			container.Running = props["ActiveState"] == "active"
+		}
+		container.Restarts = systemd.UnitRestarts(systemd.Connection(), id.UnitNameFor())
+		if container.Running && runtime != nil {
+			if stats, err := runtime.ContainerStats(id); err == nil {
+				container.MemoryUsage = &stats.MemoryUsage
+			}
+		}
+		all = append(all, container)
+	}
+	return all
+}
+
+// The gauges of the containers, labeled by container id and image.
+func (c *Collector) Gauges() []http.Metric {
+	return []http.Metric{
+		c.gauge("geard_container_running", "Whether the container is running (1) or not (0).", func(container *Container, add func(float64, ...http.Label)) {
+			running := 0.0
+			if container.Running {
+				running = 1
+			}
+			add(running)
+		}),
+		c.gauge("geard_container_restarts", "The times systemd has restarted the container.", func(container *Container, add func(float64, ...http.Label)) {
+			if container.Restarts != nil {
+				add(float64(*container.Restarts))
+			}
+		}),
+		c.gauge("geard_container_memory_usage_bytes", "The memory the running container is using.", func(container *Container, add func(float64, ...http.Label)) {
+			if container.MemoryUsage != nil {
+				add(float64(*container.MemoryUsage))
+			}
+		}),
+		c.gauge("geard_container_port", "The external port allocated to each internal port of the container.", func(container *Container, add func(float64, ...http.Label)) {
+			for _, pair := range container.Ports {
+				add(float64(pair.External), http.Label{"internal", strconv.Itoa(int(pair.Internal))})
+			}
+		}),
+	}
+}
+
+// A gauge whose samples are added by fn for each container, labeled by the
+// id and image of the container and any labels fn adds.
+func (c *Collector) gauge(name, help string, fn func(container *Container, add func(float64, ...http.Label))) http.Gauge {
+	return http.Gauge{name, help, func() []http.Sample {
+		all := c.Containers()
+		samples := make([]http.Sample, 0, len(all))
+		for i := range all {
+			container := &all[i]
+			fn(container, func(value float64, labels ...http.Label) {
+				samples = append(samples, http.Sample{append([]http.Label{{"id", string(container.Id)}, {"image", container.Image}}, labels...), value})
+			})
+		}
+		return samples
+	}}
+}
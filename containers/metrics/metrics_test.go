@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/http"
+	"github.com/openshift/geard/port"
+)
+
+func TestCollectorGauges(t *testing.T) {
+	restarts, memory := uint32(3), uint64(104857600)
+	reads := 0
+	now := time.Date(2014, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := NewCollector("")
+	c.now = func() time.Time { return now }
+	c.Read = func() []Container {
+		reads++
+		return []Container{
+			{Id: "test-web", Image: "openshift/busybox-http-app", Running: true, Restarts: &restarts, MemoryUsage: &memory, Ports: port.PortPairs{{8080, 4000}}},
+			{Id: "test-db", Image: "mysql"},
+		}
+	}
+	handler := http.MetricsHandler(c.Gauges()...)
+
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+	body := scrape()
+	for _, s := range []string{
+		"# TYPE geard_container_running gauge\n",
+		`geard_container_running{id="test-web",image="openshift/busybox-http-app"} 1` + "\n",
+		`geard_container_running{id="test-db",image="mysql"} 0` + "\n",
+		`geard_container_restarts{id="test-web",image="openshift/busybox-http-app"} 3` + "\n",
+		`geard_container_memory_usage_bytes{id="test-web",image="openshift/busybox-http-app"} 104857600` + "\n",
+		`geard_container_port{id="test-web",image="openshift/busybox-http-app",internal="8080"} 4000` + "\n",
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("Expected the metrics to contain %q:\n%s", s, body)
+		}
+	}
+	for _, s := range []string{`geard_container_restarts{id="test-db"`, `geard_container_memory_usage_bytes{id="test-db"`} {
+		if strings.Contains(body, s) {
+			t.Errorf("Expected no sample for a value that isn't known, got:\n%s", body)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("Expected the containers to be read once per scrape, got %d", reads)
+	}
+
+	now = now.Add(DefaultTTL - time.Second)
+	scrape()
+	if reads != 1 {
+		t.Errorf("Expected the containers not to be read again within the TTL, got %d reads", reads)
+	}
+	now = now.Add(time.Second)
+	scrape()
+	if reads != 2 {
+		t.Errorf("Expected the containers to be read again after the TTL, got %d reads", reads)
+	}
+}
@@ -0,0 +1,69 @@
+package containers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The network a container is attached to by Docker.  The default is a
+// private network on the Docker bridge.
+type NetworkMode string
+
+const (
+	NetworkBridge NetworkMode = "bridge"
+	NetworkHost   NetworkMode = "host"
+	NetworkNone   NetworkMode = "none"
+
+	networkContainerPrefix = "container:"
+)
+
+// Share the network of another container
+func NewContainerNetworkMode(id Identifier) NetworkMode {
+	return NetworkMode(networkContainerPrefix + string(id))
+}
+
+func NewNetworkModeFromString(s string) (NetworkMode, error) {
+	mode := NetworkMode(s)
+	if err := mode.Check(); err != nil {
+		return "", err
+	}
+	return mode, nil
+}
+
+func (n NetworkMode) Check() error {
+	switch n {
+	case "", NetworkBridge, NetworkHost, NetworkNone:
+		return nil
+	}
+	if strings.HasPrefix(string(n), networkContainerPrefix) {
+		if _, err := NewIdentifier(strings.TrimPrefix(string(n), networkContainerPrefix)); err != nil {
+			return fmt.Errorf("The network mode '%s' must name a valid container: %s", n, err.Error())
+		}
+		return nil
+	}
+	return fmt.Errorf("The network mode '%s' must be one of bridge, host, none, or container:<name>", n)
+}
+
+// The container whose network is shared, if any
+func (n NetworkMode) Container() (Identifier, bool) {
+	if !strings.HasPrefix(string(n), networkContainerPrefix) {
+		return InvalidIdentifier, false
+	}
+	return Identifier(strings.TrimPrefix(string(n), networkContainerPrefix)), true
+}
+
+func (n NetworkMode) Default() bool {
+	return n == "" || n == NetworkBridge
+}
+
+// Only containers on the bridge have their own ports to map.
+func (n NetworkMode) AllowsPorts() bool {
+	return n.Default()
+}
+
+// Containers that share the network of the host or another container also
+// share its DNS servers and hosts file.
+func (n NetworkMode) AllowsResolverConfig() bool {
+	_, shared := n.Container()
+	return !shared && n != NetworkHost
+}
@@ -0,0 +1,41 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestNetworkMode(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		ports    bool
+		resolver bool
+		other    Identifier
+	}{
+		{"", true, true, ""},
+		{"bridge", true, true, ""},
+		{"host", false, false, ""},
+		{"none", false, true, ""},
+		{"container:db-1", false, false, "db-1"},
+	} {
+		mode, err := NewNetworkModeFromString(test.value)
+		if err != nil {
+			t.Errorf("Unable to parse network mode %q: %v", test.value, err)
+			continue
+		}
+		if mode.AllowsPorts() != test.ports {
+			t.Errorf("Expected network mode %q to allow ports %v", test.value, test.ports)
+		}
+		if mode.AllowsResolverConfig() != test.resolver {
+			t.Errorf("Expected network mode %q to allow DNS and host entries %v", test.value, test.resolver)
+		}
+		if other, ok := mode.Container(); other != test.other || ok != (test.other != "") {
+			t.Errorf("Expected network mode %q to share the network of %q, got %q", test.value, test.other, other)
+		}
+	}
+
+	for _, value := range []string{"overlay", "container:", "container:^^^^", "Host"} {
+		if _, err := NewNetworkModeFromString(value); err == nil {
+			t.Errorf("Expected network mode %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Installs the containers of an application in the order their
+// dependencies require, as 'gear up' does.
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	cjobs "github.com/openshift/geard/containers/jobs"
+)
+
+// A container of a manifest read by 'gear up'.
+type Container struct {
+	// The container to install, optionally prefixed by its server as for
+	// 'gear install', such as 'db' or 'remote:43273/db'
+	Name string
+	// The names of the containers in the manifest that must be installed
+	// before this one
+	After []string `json:"After,omitempty"`
+	// If set, a failure to install the container only skips the containers
+	// after it, and is never rolled back
+	Optional bool `json:"Optional,omitempty"`
+	// The install request, as in a manifest read with --reconcile-dir.  The
+	// id is the name of the container.
+	Install cjobs.InstallContainerRequest
+}
+
+// The containers of an application, such as
+// {"Containers": [{"Name": "db", "Install": {"Image": "mysql"}}]}
+type Manifest struct {
+	Containers []Container
+}
+
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("The manifest is not valid JSON: %s", err.Error())
+	}
+	if len(m.Containers) == 0 {
+		return nil, fmt.Errorf("The manifest must list at least one container")
+	}
+	return m, nil
+}
+
+// A cycle among the dependencies of the containers of a manifest, which
+// can never be installed in order.
+type CycleError struct {
+	// The names around the cycle, starting and ending with the same name
+	Path []string
+}
+
+func (e CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// Check that names are unique, and that dependencies are on containers in
+// the manifest and don't form a cycle.  Nothing is installed from a
+// manifest that doesn't validate.
+func (m *Manifest) Validate() error {
+	byName := make(map[string]*Container, len(m.Containers))
+	for i := range m.Containers {
+		c := &m.Containers[i]
+		if c.Name == "" {
+			return fmt.Errorf("Container %d of the manifest has no name", i+1)
+		}
+		if _, ok := byName[c.Name]; ok {
+			return fmt.Errorf("The container %s is listed more than once", c.Name)
+		}
+		if c.Install.Id != "" {
+			return fmt.Errorf("The container %s sets an id in its install request, the id is taken from its name", c.Name)
+		}
+		byName[c.Name] = c
+	}
+	for i := range m.Containers {
+		c := &m.Containers[i]
+		for _, after := range c.After {
+			if after == c.Name {
+				return CycleError{[]string{c.Name, c.Name}}
+			}
+			if _, ok := byName[after]; !ok {
+				return fmt.Errorf("The container %s is after %s, which is not in the manifest", c.Name, after)
+			}
+		}
+	}
+	if path := findCycle(m.Containers, byName); path != nil {
+		return CycleError{path}
+	}
+	return nil
+}
+
+// The first cycle found by following the dependencies of each container in
+// manifest order, or nil if there is none.
+func findCycle(all []Container, byName map[string]*Container) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(all))
+	stack := []string{}
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, after := range byName[name].After {
+			switch state[after] {
+			case visiting:
+				for i := range stack {
+					if stack[i] == after {
+						return append(append([]string{}, stack[i:]...), after)
+					}
+				}
+			case unvisited:
+				if path := visit(after); path != nil {
+					return path
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for i := range all {
+		if state[all[i].Name] == unvisited {
+			if path := visit(all[i].Name); path != nil {
+				return path
+			}
+		}
+	}
+	return nil
+}
+
+// The containers in an order that installs each one after those it is
+// after.  Containers that don't depend on each other keep the order of the
+// manifest.  The manifest must validate.
+func (m *Manifest) Order() ([]*Container, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	ordered := make([]*Container, 0, len(m.Containers))
+	placed := make(map[string]bool, len(m.Containers))
+	for len(ordered) < len(m.Containers) {
+		for i := range m.Containers {
+			c := &m.Containers[i]
+			if !placed[c.Name] && allPlaced(c.After, placed) {
+				placed[c.Name] = true
+				ordered = append(ordered, c)
+				break
+			}
+		}
+	}
+	return ordered, nil
+}
+
+func allPlaced(names []string, placed map[string]bool) bool {
+	for _, name := range names {
+		if !placed[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// Installs and removes the containers of a pipeline, writing the output
+// of the jobs to out.
+type Installer interface {
+	Install(c *Container, out io.Writer) error
+	Remove(c *Container, out io.Writer) error
+}
+
+// What happened to each container of a pipeline, by name.
+type Result struct {
+	Installed []string
+	Failed    []string
+	// Not installed because a container they are after failed, or because
+	// an atomic pipeline stopped
+	Skipped []string
+	// Installed and then removed again because an atomic pipeline failed
+	RolledBack []string
+	Errors     []error
+}
+
+// Install the ordered containers one at a time, reporting progress to out.
+// A container is skipped if a container it is after failed or was skipped.
+// If atomic is set, a failure of a container that isn't optional stops the
+// pipeline, and every container it installed is removed again, the last
+// installed first.
+func Run(ordered []*Container, installer Installer, atomic bool, out io.Writer) *Result {
+	result := &Result{}
+	unavailable := make(map[string]bool)
+	var critical *Container
+	installed := []*Container{}
+
+	for i, c := range ordered {
+		if critical != nil {
+			result.Skipped = append(result.Skipped, c.Name)
+			fmt.Fprintf(out, "==> [%d/%d] Skipping %s, %s failed\n", i+1, len(ordered), c.Name, critical.Name)
+			continue
+		}
+		if failed := firstUnavailable(c.After, unavailable); failed != "" {
+			unavailable[c.Name] = true
+			result.Skipped = append(result.Skipped, c.Name)
+			fmt.Fprintf(out, "==> [%d/%d] Skipping %s, it is after %s which was not installed\n", i+1, len(ordered), c.Name, failed)
+			continue
+		}
+
+		fmt.Fprintf(out, "==> [%d/%d] Installing %s\n", i+1, len(ordered), c.Name)
+		if err := installer.Install(c, out); err != nil {
+			unavailable[c.Name] = true
+			result.Failed = append(result.Failed, c.Name)
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %s", c.Name, err.Error()))
+			fmt.Fprintf(out, "==> [%d/%d] Failed to install %s: %s\n", i+1, len(ordered), c.Name, err.Error())
+			if atomic && !c.Optional {
+				critical = c
+			}
+			continue
+		}
+		installed = append(installed, c)
+		fmt.Fprintf(out, "==> [%d/%d] Installed %s\n", i+1, len(ordered), c.Name)
+	}
+
+	if critical == nil {
+		for _, c := range installed {
+			result.Installed = append(result.Installed, c.Name)
+		}
+		return result
+	}
+
+	fmt.Fprintf(out, "==> Rolling back %d installed containers\n", len(installed))
+	for i := len(installed) - 1; i >= 0; i-- {
+		c := installed[i]
+		if err := installer.Remove(c, out); err != nil {
+			// left in place, so it is still installed
+			result.Installed = append(result.Installed, c.Name)
+			result.Errors = append(result.Errors, fmt.Errorf("%s: Unable to roll back: %s", c.Name, err.Error()))
+			fmt.Fprintf(out, "==> Failed to remove %s: %s\n", c.Name, err.Error())
+			continue
+		}
+		result.RolledBack = append(result.RolledBack, c.Name)
+		fmt.Fprintf(out, "==> Removed %s\n", c.Name)
+	}
+	return result
+}
+
+func firstUnavailable(names []string, unavailable map[string]bool) string {
+	for _, name := range names {
+		if unavailable[name] {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Records the containers installed and removed, failing the installs of
+// the named containers.
+type fakeInstaller struct {
+	fail  map[string]bool
+	calls []string
+}
+
+func (f *fakeInstaller) Install(c *Container, out io.Writer) error {
+	f.calls = append(f.calls, "install "+c.Name)
+	if f.fail[c.Name] {
+		return errors.New("Unable to pull the image")
+	}
+	return nil
+}
+
+func (f *fakeInstaller) Remove(c *Container, out io.Writer) error {
+	f.calls = append(f.calls, "remove "+c.Name)
+	return nil
+}
+
+// web is after api, which is after db and cache.  worker is after db alone.
+const testManifest = `{"Containers": [
+	{"Name": "web", "After": ["api"], "Install": {"Image": "test/web"}},
+	{"Name": "api", "After": ["db", "cache"], "Install": {"Image": "test/api"}},
+	{"Name": "db", "Install": {"Image": "test/db", "Started": true}},
+	{"Name": "worker", "After": ["db"], "Optional": true, "Install": {"Image": "test/worker"}},
+	{"Name": "cache", "Install": {"Image": "test/cache"}}
+]}`
+
+func orderedTestManifest(t *testing.T) []*Container {
+	m, err := ReadManifest(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatalf("Unable to read the manifest: %v", err)
+	}
+	ordered, err := m.Order()
+	if err != nil {
+		t.Fatalf("Unable to order the manifest: %v", err)
+	}
+	return ordered
+}
+
+func names(ordered []*Container) []string {
+	s := []string{}
+	for _, c := range ordered {
+		s = append(s, c.Name)
+	}
+	return s
+}
+
+func TestManifestOrder(t *testing.T) {
+	ordered := orderedTestManifest(t)
+	if expected := []string{"db", "worker", "cache", "api", "web"}; !reflect.DeepEqual(names(ordered), expected) {
+		t.Errorf("Expected the order %v, got %v", expected, names(ordered))
+	}
+	if ordered[0].Install.Image != "test/db" || !ordered[0].Install.Started {
+		t.Errorf("Expected the install request to be read, got %+v", ordered[0].Install)
+	}
+}
+
+func TestManifestOrderInvalid(t *testing.T) {
+	for manifest, message := range map[string]string{
+		`{"Containers": [{"Name": "a", "After": ["b"]}, {"Name": "b", "After": ["c"]}, {"Name": "c", "After": ["a"]}, {"Name": "d"}]}`: "dependency cycle: a -> b -> c -> a",
+		`{"Containers": [{"Name": "a", "After": ["missing"]}]}`:                                                                        "not in the manifest",
+		`{"Containers": [{"Name": "a", "After": ["a"]}]}`:                                                                              "dependency cycle: a -> a",
+		`{"Containers": [{"Name": "a"}, {"Name": "a"}]}`:                                                                               "more than once",
+		`{"Containers": [{"Name": "a", "Install": {"Id": "b"}}]}`:                                                                      "taken from its name",
+		`{"Containers": [{"After": ["a"]}]}`:                                                                                           "has no name",
+	} {
+		m, err := ReadManifest(strings.NewReader(manifest))
+		if err != nil {
+			t.Fatalf("Unable to read the manifest %s: %v", manifest, err)
+		}
+		if _, err := m.Order(); err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected the manifest %s to be rejected with %q, got %v", manifest, message, err)
+		}
+	}
+	for _, manifest := range []string{`{"Containers": []}`, `{"Containers": [`, `[]`} {
+		if _, err := ReadManifest(strings.NewReader(manifest)); err == nil {
+			t.Errorf("Expected the manifest %s to be rejected", manifest)
+		}
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	for _, manifest := range []string{
+		testManifest,
+		// a diamond is not a cycle
+		`{"Containers": [{"Name": "a", "After": ["b", "c"]}, {"Name": "b", "After": ["d"]}, {"Name": "c", "After": ["d"]}, {"Name": "d"}]}`,
+		`{"Containers": [{"Name": "a"}, {"Name": "b"}]}`,
+	} {
+		m, err := ReadManifest(strings.NewReader(manifest))
+		if err != nil {
+			t.Fatalf("Unable to read the manifest %s: %v", manifest, err)
+		}
+		if err := m.Validate(); err != nil {
+			t.Errorf("Expected the manifest %s to validate, got %v", manifest, err)
+		}
+	}
+
+	for manifest, path := range map[string][]string{
+		`{"Containers": [{"Name": "a", "After": ["b"]}, {"Name": "b", "After": ["a"]}]}`:                                                               {"a", "b", "a"},
+		`{"Containers": [{"Name": "d", "After": ["a"]}, {"Name": "a", "After": ["b"]}, {"Name": "b", "After": ["c"]}, {"Name": "c", "After": ["b"]}]}`: {"b", "c", "b"},
+		`{"Containers": [{"Name": "a", "After": ["b", "c"]}, {"Name": "b"}, {"Name": "c", "After": ["d"]}, {"Name": "d", "After": ["a"]}]}`:            {"a", "c", "d", "a"},
+	} {
+		m, err := ReadManifest(strings.NewReader(manifest))
+		if err != nil {
+			t.Fatalf("Unable to read the manifest %s: %v", manifest, err)
+		}
+		err = m.Validate()
+		cycle, ok := err.(CycleError)
+		if !ok || !reflect.DeepEqual(cycle.Path, path) {
+			t.Errorf("Expected the manifest %s to be rejected with the cycle %v, got %v", manifest, path, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	installer := &fakeInstaller{}
+	out := &bytes.Buffer{}
+	result := Run(orderedTestManifest(t), installer, true, out)
+	if expected := []string{"install db", "install worker", "install cache", "install api", "install web"}; !reflect.DeepEqual(installer.calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, installer.calls)
+	}
+	if len(result.Installed) != 5 || len(result.Errors) != 0 {
+		t.Errorf("Expected every container to be installed, got %+v", result)
+	}
+	if !strings.Contains(out.String(), "==> [1/5] Installing db\n==> [1/5] Installed db\n") {
+		t.Errorf("Expected the progress of each container to be reported:\n%s", out.String())
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	installer := &fakeInstaller{fail: map[string]bool{"api": true}}
+	out := &bytes.Buffer{}
+	result := Run(orderedTestManifest(t), installer, false, out)
+	if expected := []string{"install db", "install worker", "install cache", "install api"}; !reflect.DeepEqual(installer.calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, installer.calls)
+	}
+	if !reflect.DeepEqual(result.Installed, []string{"db", "worker", "cache"}) || !reflect.DeepEqual(result.Failed, []string{"api"}) || !reflect.DeepEqual(result.Skipped, []string{"web"}) {
+		t.Errorf("Expected the containers after api to be skipped and the rest kept, got %+v", result)
+	}
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "api: Unable to pull the image") {
+		t.Errorf("Expected the failure of api to be reported, got %v", result.Errors)
+	}
+	if !strings.Contains(out.String(), "Skipping web, it is after api which was not installed") {
+		t.Errorf("Expected the skipped container to be reported:\n%s", out.String())
+	}
+}
+
+func TestRunAtomicFailure(t *testing.T) {
+	installer := &fakeInstaller{fail: map[string]bool{"api": true}}
+	out := &bytes.Buffer{}
+	result := Run(orderedTestManifest(t), installer, true, out)
+	expected := []string{"install db", "install worker", "install cache", "install api", "remove cache", "remove worker", "remove db"}
+	if !reflect.DeepEqual(installer.calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, installer.calls)
+	}
+	if len(result.Installed) != 0 || !reflect.DeepEqual(result.RolledBack, []string{"cache", "worker", "db"}) || !reflect.DeepEqual(result.Skipped, []string{"web"}) {
+		t.Errorf("Expected every installed container to be rolled back, got %+v", result)
+	}
+	if !strings.Contains(out.String(), "==> Rolling back 3 installed containers") {
+		t.Errorf("Expected the rollback to be reported:\n%s", out.String())
+	}
+}
+
+func TestRunAtomicOptionalFailure(t *testing.T) {
+	installer := &fakeInstaller{fail: map[string]bool{"worker": true}}
+	result := Run(orderedTestManifest(t), installer, true, &bytes.Buffer{})
+	if !reflect.DeepEqual(result.Installed, []string{"db", "cache", "api", "web"}) || !reflect.DeepEqual(result.Failed, []string{"worker"}) || len(result.RolledBack) != 0 {
+		t.Errorf("Expected the failure of an optional container not to be rolled back, got %+v", result)
+	}
+}
@@ -0,0 +1,35 @@
+package containers
+
+import (
+	"fmt"
+)
+
+// When an install pulls the image of a container from its registry, the
+// same choices Kubernetes offers.  Empty is PullIfMissing.
+type PullPolicy string
+
+const (
+	// Pull the image on every install, so that a tag that has moved is
+	// picked up
+	PullAlways PullPolicy = "always"
+	// Pull the image only if it is not present
+	PullIfMissing PullPolicy = "missing"
+	// Never pull the image, failing the install if it is not present
+	PullNever PullPolicy = "never"
+)
+
+func (p PullPolicy) Check() error {
+	switch p {
+	case "", PullAlways, PullIfMissing, PullNever:
+		return nil
+	}
+	return fmt.Errorf("The pull policy '%s' must be one of always, missing or never", string(p))
+}
+
+// The policy, PullIfMissing if it is empty.
+func (p PullPolicy) OrDefault() PullPolicy {
+	if p == "" {
+		return PullIfMissing
+	}
+	return p
+}
@@ -0,0 +1,268 @@
+// Keeps the containers on a host matching a directory of manifests.
+package reconcile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/jobs"
+)
+
+// A manifest is a file ending in ".json" holding an install request, such
+// as {"Image": "openshift/busybox-http-app", "Started": true}.  The
+// container id is the name of the file unless the request sets one.
+const manifestExtension = ".json"
+
+// Installs the containers described by the manifests in a directory,
+// updates them when their manifest changes, and removes them when their
+// manifest is removed.  Only containers installed by the reconciler are
+// ever removed.
+type Reconciler struct {
+	// The directory of manifests
+	Dir string
+	// Where the manifest each container was last installed from is
+	// recorded
+	StatePath string
+	// The Docker daemon images are pulled into
+	DockerSocket string
+
+	// Runs the job for a request, by default the job registered for it
+	// through the dispatcher
+	Execute func(request interface{}) error
+	// If set and true, Run skips reconciling, for instance while the
+	// daemon is in maintenance mode
+	Paused func() bool
+}
+
+func NewReconciler(dir string, d *dispatcher.Dispatcher) *Reconciler {
+	return &Reconciler{
+		Dir:       dir,
+		StatePath: filepath.Join(config.ContainerBasePath(), "reconcile"),
+		Execute:   d.Execute,
+	}
+}
+
+// Reconcile immediately and then every interval until stop is closed.
+func (r *Reconciler) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if r.Paused != nil && r.Paused() {
+			log.Printf("reconcile: Paused, skipping %s", r.Dir)
+		} else if err := r.Reconcile(); err != nil {
+			log.Printf("reconcile: Unable to reconcile %s: %v", r.Dir, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Bring the installed containers in line with the manifests.  A container
+// whose manifest is unchanged and whose unit exists is left alone, so
+// reconciling repeatedly does not restart anything.  Failed actions are
+// logged and retried on the next call.
+func (r *Reconciler) Reconcile() error {
+	if err := os.MkdirAll(r.StatePath, 0750); err != nil {
+		return err
+	}
+	desired, err := r.manifests()
+	if err != nil {
+		return err
+	}
+	applied, err := r.applied()
+	if err != nil {
+		return err
+	}
+
+	ids := make(identifiers, 0, len(desired))
+	for id := range desired {
+		ids = append(ids, id)
+	}
+	sort.Sort(ids)
+	for _, id := range ids {
+		manifest := desired[id]
+		if manifest == nil {
+			// an invalid manifest keeps its container as it is
+			continue
+		}
+		r.apply(id, manifest, applied[id])
+	}
+
+	removed := identifiers{}
+	for id := range applied {
+		if _, ok := desired[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Sort(removed)
+	for _, id := range removed {
+		log.Printf("reconcile: Removing %s, its manifest was removed", id)
+		if err := r.Execute(&cjobs.DeleteContainerRequest{Id: id}); err != nil && !jobs.IsNotFound(err) {
+			log.Printf("reconcile: Unable to remove %s: %v", id, err)
+			continue
+		}
+		if err := os.Remove(r.recordPathFor(id)); err != nil {
+			log.Printf("reconcile: Unable to forget %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) apply(id containers.Identifier, manifest *cjobs.InstallContainerRequest, previous string) {
+	checksum, err := manifestChecksum(manifest)
+	if err != nil {
+		log.Printf("reconcile: Unable to read the manifest for %s: %v", id, err)
+		return
+	}
+	_, errs := os.Stat(id.UnitPathFor())
+	installed := errs == nil
+	if installed && checksum == previous {
+		return
+	}
+
+	update := installed && previous != ""
+	switch {
+	case update:
+		log.Printf("reconcile: Updating %s, its manifest changed", id)
+	case installed:
+		log.Printf("reconcile: Taking over %s from its manifest", id)
+	default:
+		log.Printf("reconcile: Installing %s", id)
+	}
+	manifest.RequestIdentifier = jobs.NewRequestIdentifier()
+	manifest.DockerSocket = r.DockerSocket
+	if err := r.Execute(manifest); err != nil {
+		log.Printf("reconcile: Unable to install %s: %v", id, err)
+		return
+	}
+	// starting a running container has no effect, so a changed one is
+	// restarted to pick up its new definition
+	if update && manifest.Started {
+		log.Printf("reconcile: Restarting %s", id)
+		if err := r.Execute(&cjobs.RestartContainerRequest{Id: id}); err != nil {
+			log.Printf("reconcile: Unable to restart %s: %v", id, err)
+		}
+	}
+	if err := ioutil.WriteFile(r.recordPathFor(id), []byte(checksum), 0640); err != nil {
+		log.Printf("reconcile: Unable to record the manifest of %s: %v", id, err)
+	}
+}
+
+// The install request of each manifest by container id.  Manifests that
+// can't be read are present with a nil request.
+func (r *Reconciler) manifests() (map[containers.Identifier]*cjobs.InstallContainerRequest, error) {
+	paths, err := filepath.Glob(filepath.Join(r.Dir, "*"+manifestExtension))
+	if err != nil {
+		return nil, err
+	}
+	desired := make(map[containers.Identifier]*cjobs.InstallContainerRequest)
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), manifestExtension)
+		manifest, err := readManifest(path)
+		if err != nil {
+			log.Printf("reconcile: Ignoring the manifest %s: %v", path, err)
+			if id, err := containers.NewIdentifier(name); err == nil {
+				if _, ok := desired[id]; !ok {
+					desired[id] = nil
+				}
+			}
+			continue
+		}
+		if manifest.Id == "" {
+			id, err := containers.NewIdentifier(name)
+			if err != nil {
+				log.Printf("reconcile: Ignoring the manifest %s, the file name is not a valid container id: %v", path, err)
+				continue
+			}
+			manifest.Id = id
+		}
+		if desired[manifest.Id] != nil {
+			log.Printf("reconcile: Ignoring the manifest %s, another manifest already describes %s", path, manifest.Id)
+			continue
+		}
+		desired[manifest.Id] = manifest
+	}
+	return desired, nil
+}
+
+func readManifest(path string) (*cjobs.InstallContainerRequest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	manifest := &cjobs.InstallContainerRequest{}
+	if err := json.NewDecoder(file).Decode(manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Id != "" {
+		if _, err := containers.NewIdentifier(string(manifest.Id)); err != nil {
+			return nil, err
+		}
+	}
+	// the request identifier is only required to run the install
+	manifest.RequestIdentifier = jobs.NewRequestIdentifier()
+	if err := manifest.Check(); err != nil {
+		return nil, err
+	}
+	manifest.RequestIdentifier = nil
+	return manifest, nil
+}
+
+// The checksum of each container installed from a manifest, by id
+func (r *Reconciler) applied() (map[containers.Identifier]string, error) {
+	names, err := ioutil.ReadDir(r.StatePath)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[containers.Identifier]string)
+	for _, info := range names {
+		id, err := containers.NewIdentifier(info.Name())
+		if err != nil || info.IsDir() {
+			continue
+		}
+		checksum, err := ioutil.ReadFile(filepath.Join(r.StatePath, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		applied[id] = string(checksum)
+	}
+	return applied, nil
+}
+
+func (r *Reconciler) recordPathFor(id containers.Identifier) string {
+	return filepath.Join(r.StatePath, string(id))
+}
+
+// Manifests are compared by their decoded content, so changes to
+// formatting do not cause an update.
+func manifestChecksum(manifest *cjobs.InstallContainerRequest) (string, error) {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type identifiers []containers.Identifier
+
+func (a identifiers) Len() int           { return len(a) }
+func (a identifiers) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a identifiers) Less(i, j int) bool { return a[i] < a[j] }
+
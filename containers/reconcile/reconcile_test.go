@@ -0,0 +1,147 @@
+package reconcile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
+)
+
+// Records the requests executed and installs or removes the unit file the
+// reconciler looks for.
+type fakeExecutor struct {
+	t        *testing.T
+	requests []string
+}
+
+func (f *fakeExecutor) Execute(request interface{}) error {
+	switch r := request.(type) {
+	case *cjobs.InstallContainerRequest:
+		if len(r.RequestIdentifier) == 0 {
+			f.t.Errorf("Install of %s has no request identifier", r.Id)
+		}
+		if err := os.MkdirAll(filepath.Dir(r.Id.UnitPathFor()), 0750); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(r.Id.UnitPathFor(), []byte(r.Image), 0640); err != nil {
+			return err
+		}
+		f.requests = append(f.requests, fmt.Sprintf("install %s %s", r.Id, r.Image))
+	case *cjobs.RestartContainerRequest:
+		f.requests = append(f.requests, fmt.Sprintf("restart %s", r.Id))
+	case *cjobs.DeleteContainerRequest:
+		os.Remove(r.Id.UnitPathFor())
+		f.requests = append(f.requests, fmt.Sprintf("delete %s", r.Id))
+	default:
+		f.t.Fatalf("Unexpected request %#v", request)
+	}
+	return nil
+}
+
+func (f *fakeExecutor) expect(expected ...string) {
+	if len(f.requests) != len(expected) {
+		f.t.Fatalf("Expected requests %v, got %v", expected, f.requests)
+	}
+	for i := range expected {
+		if f.requests[i] != expected[i] {
+			f.t.Fatalf("Expected requests %v, got %v", expected, f.requests)
+		}
+	}
+	f.requests = nil
+}
+
+func newTestReconciler(t *testing.T) (*Reconciler, *fakeExecutor, func()) {
+	base, err := ioutil.TempDir("", "geard-base")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(base)
+
+	dir := filepath.Join(base, "manifests")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeExecutor{t: t}
+	r := NewReconciler(dir, nil)
+	r.Execute = f.Execute
+	return r, f, func() {
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(base)
+	}
+}
+
+func writeManifest(t *testing.T, r *Reconciler, name, content string) {
+	if err := ioutil.WriteFile(filepath.Join(r.Dir, name), []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func reconcile(t *testing.T, r *Reconciler) {
+	if err := r.Reconcile(); err != nil {
+		t.Fatalf("Unable to reconcile: %v", err)
+	}
+}
+
+func TestReconcileInstallsAndRemoves(t *testing.T) {
+	r, f, cleanup := newTestReconciler(t)
+	defer cleanup()
+
+	writeManifest(t, r, "web1.json", `{"Image":"busybox","Started":true}`)
+	reconcile(t, r)
+	f.expect("install web1 busybox")
+
+	reconcile(t, r)
+	f.expect()
+
+	// reformatting the manifest is not a change
+	writeManifest(t, r, "web1.json", `{ "Started": true, "Image": "busybox" }`)
+	reconcile(t, r)
+	f.expect()
+
+	writeManifest(t, r, "web1.json", `{"Image":"busybox:1","Started":true}`)
+	reconcile(t, r)
+	f.expect("install web1 busybox:1", "restart web1")
+
+	// a container removed outside the reconciler is installed again
+	os.Remove(containers.Identifier("web1").UnitPathFor())
+	reconcile(t, r)
+	f.expect("install web1 busybox:1")
+
+	os.Remove(filepath.Join(r.Dir, "web1.json"))
+	reconcile(t, r)
+	f.expect("delete web1")
+
+	reconcile(t, r)
+	f.expect()
+}
+
+func TestReconcileKeepsUnmanagedAndInvalid(t *testing.T) {
+	r, f, cleanup := newTestReconciler(t)
+	defer cleanup()
+
+	// a container that was never in a manifest is left alone
+	other := containers.Identifier("other1")
+	os.MkdirAll(filepath.Dir(other.UnitPathFor()), 0750)
+	ioutil.WriteFile(other.UnitPathFor(), []byte{}, 0640)
+
+	writeManifest(t, r, "web1.json", `{"Image":"busybox"}`)
+	writeManifest(t, r, "named.json", `{"Id":"db01","Image":"postgres"}`)
+	writeManifest(t, r, "notes.txt", `not a manifest`)
+	reconcile(t, r)
+	f.expect("install db01 postgres", "install web1 busybox")
+
+	// a broken manifest does not remove its container
+	writeManifest(t, r, "web1.json", `{"Image":`)
+	reconcile(t, r)
+	f.expect()
+
+	writeManifest(t, r, "web1.json", `{"Image":"busybox"}`)
+	reconcile(t, r)
+	f.expect()
+}
@@ -0,0 +1,33 @@
+package containers
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// The deployment or release a container was installed from, such as a
+// commit hash or a version like v1.4.2, recorded so that the containers
+// running a release can be found.  Only changed by reinstalling.
+type Revision string
+
+var allowedRevision = regexp.MustCompile("\\A[a-zA-Z0-9][a-zA-Z0-9_.:+/\\-]*\\z")
+
+func (r Revision) Check() error {
+	if r == "" {
+		return nil
+	}
+	if len(r) > 128 {
+		return fmt.Errorf("The revision must be 128 characters or less")
+	}
+	if !allowedRevision.MatchString(string(r)) {
+		return fmt.Errorf("The revision '%s' may only contain letters, numbers, and the characters _.:+/- and must start with a letter or number", string(r))
+	}
+	return nil
+}
+
+// The revision the container was last installed with, empty if it was
+// installed without one.
+func GetContainerRevision(id Identifier) (Revision, error) {
+	value, err := readUnitValue(id, "X-ContainerRevision")
+	return Revision(value), err
+}
@@ -0,0 +1,97 @@
+package containers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+)
+
+// The runtime used for new jobs, set with --runtime.  Units run their
+// container with the runtime they were installed with.
+var RuntimeName = RuntimeDocker
+
+var ErrNoSuchContainer = errors.New("The container does not exist in the runtime.")
+
+// The operations geard performs on containers directly, rather than
+// through their systemd unit.
+type Runtime interface {
+	Name() string
+	// Ensure an image is present, pulling it if necessary
+	PullImage(image string) error
+	// Pull an image even if it is present, to pick up a tag that has moved
+	UpdateImage(image string) error
+	// Whether an image is present, without pulling it
+	ImagePresent(image string) (bool, error)
+	// The digests a pulled image is known by to the registries it came
+	// from, empty if it was built locally
+	ImageDigests(image string) ([]ImageDigest, error)
+	// Stop a container, killing it if it has not exited after timeout
+	// seconds.  Returns ErrNoSuchContainer if it is not running.
+	StopContainer(id Identifier, timeout uint) error
+	// Whether the container is running
+	ContainerRunning(id Identifier) (bool, error)
+	// Send a signal, such as "SIGHUP", to the main process of a running
+	// container.  Returns ErrNoSuchContainer if it is not running.
+	SignalContainer(id Identifier, signal string) error
+	// The current resource usage of a running container
+	ContainerStats(id Identifier) (*ContainerStats, error)
+	// Freeze or thaw the processes of a running container without
+	// stopping it.  Return ErrNoSuchContainer if it is not running.
+	PauseContainer(id Identifier) error
+	UnpauseContainer(id Identifier) error
+	// Whether the processes of a running container are frozen.  Returns
+	// ErrNoSuchContainer if it is not running.
+	ContainerPaused(id Identifier) (bool, error)
+}
+
+// A single sample of the resources used by a running container.
+type ContainerStats struct {
+	// Bytes of memory in use, and the limit enforced by the kernel
+	MemoryUsage uint64
+	MemoryLimit uint64
+	// Total CPU time consumed, in nanoseconds
+	CPUUsage uint64
+}
+
+// Create a runtime that connects to socket, or to its default address if
+// socket is empty.
+type RuntimeFunc func(socket string) (Runtime, error)
+
+var runtimes = make(map[string]RuntimeFunc)
+
+// Make a runtime available to --runtime.  Backends register themselves
+// when the package is initialized.
+func RegisterRuntime(name string, fn RuntimeFunc) {
+	runtimes[name] = fn
+}
+
+// The names of the registered runtimes, in order
+func RuntimeNames() []string {
+	names := make([]string, 0, len(runtimes))
+	for name := range runtimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func CheckRuntimeName(name string) error {
+	if _, ok := runtimes[name]; !ok {
+		return fmt.Errorf("The runtime '%s' is not supported, use one of: %s", name, strings.Join(RuntimeNames(), ", "))
+	}
+	return nil
+}
+
+// Connect to the runtime selected by RuntimeName.
+func NewRuntime(socket string) (Runtime, error) {
+	if err := CheckRuntimeName(RuntimeName); err != nil {
+		return nil, err
+	}
+	return runtimes[RuntimeName](socket)
+}
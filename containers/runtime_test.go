@@ -0,0 +1,98 @@
+package containers
+
+import (
+	"testing"
+)
+
+type stubRuntime struct {
+	socket string
+}
+
+func (r *stubRuntime) Name() string                                    { return "stub" }
+func (r *stubRuntime) PullImage(image string) error                    { return nil }
+func (r *stubRuntime) UpdateImage(image string) error                  { return nil }
+func (r *stubRuntime) ImagePresent(image string) (bool, error)         { return true, nil }
+func (r *stubRuntime) StopContainer(id Identifier, timeout uint) error { return nil }
+func (r *stubRuntime) ContainerRunning(id Identifier) (bool, error)    { return false, nil }
+func (r *stubRuntime) ContainerPaused(id Identifier) (bool, error)     { return false, nil }
+func (r *stubRuntime) PauseContainer(id Identifier) error              { return nil }
+func (r *stubRuntime) UnpauseContainer(id Identifier) error            { return nil }
+func (r *stubRuntime) ImageDigests(image string) ([]ImageDigest, error) {
+	return nil, nil
+}
+func (r *stubRuntime) SignalContainer(id Identifier, signal string) error {
+	return ErrNoSuchContainer
+}
+func (r *stubRuntime) ContainerStats(id Identifier) (*ContainerStats, error) {
+	return nil, ErrNoSuchContainer
+}
+
+func TestNewRuntime(t *testing.T) {
+	if RuntimeName != RuntimeDocker {
+		t.Fatalf("Expected docker to be the default runtime, got %s", RuntimeName)
+	}
+	RegisterRuntime("stub", func(socket string) (Runtime, error) {
+		return &stubRuntime{socket}, nil
+	})
+	defer delete(runtimes, "stub")
+	defer func() { RuntimeName = RuntimeDocker }()
+
+	names := RuntimeNames()
+	if len(names) != 3 || names[0] != RuntimeContainerd || names[1] != RuntimeDocker || names[2] != "stub" {
+		t.Fatalf("Expected the registered runtimes in order, got %v", names)
+	}
+
+	r, err := NewRuntime("unix:///var/run/docker.sock")
+	if err != nil || r.Name() != RuntimeDocker {
+		t.Fatalf("Expected the docker runtime by default, got %v (%v)", r, err)
+	}
+
+	RuntimeName = "stub"
+	r, err = NewRuntime("unix:///tmp/test.sock")
+	if err != nil {
+		t.Fatalf("Unexpected error selecting the stub runtime: %v", err)
+	}
+	if stub, ok := r.(*stubRuntime); !ok || stub.socket != "unix:///tmp/test.sock" {
+		t.Fatalf("Expected the stub runtime to be given the socket, got %#v", r)
+	}
+
+	RuntimeName = RuntimeContainerd
+	if r, err := NewRuntime(""); err != nil || r.Name() != RuntimeContainerd {
+		t.Fatalf("Expected the containerd runtime, got %v (%v)", r, err)
+	}
+
+	RuntimeName = "rkt"
+	if _, err := NewRuntime(""); err == nil {
+		t.Fatal("Expected an unknown runtime to be rejected")
+	}
+}
+
+func TestContainerdImageRef(t *testing.T) {
+	for image, expected := range map[string]string{
+		"busybox":                         "docker.io/library/busybox:latest",
+		"busybox:1.36":                    "docker.io/library/busybox:1.36",
+		"openshift/origin":                "docker.io/openshift/origin:latest",
+		"quay.io/coreos/etcd:v3":          "quay.io/coreos/etcd:v3",
+		"localhost:5000/app":              "localhost:5000/app:latest",
+		"localhost/app:1":                 "localhost/app:1",
+		"busybox@sha256:0123456789abcdef": "docker.io/library/busybox@sha256:0123456789abcdef",
+	} {
+		if ref := ContainerdImageRef(image); ref != expected {
+			t.Errorf("Expected %s to be referenced as %s, got %s", image, expected, ref)
+		}
+	}
+}
+
+func TestParseContainerdMetrics(t *testing.T) {
+	v1 := `{"memory":{"usage":{"usage":1024,"limit":4096}},"cpu":{"usage":{"total":5000}}}`
+	stats, err := parseContainerdMetrics([]byte(v1))
+	if err != nil || *stats != (ContainerStats{1024, 4096, 5000}) {
+		t.Errorf("Expected cgroup v1 metrics to be read, got %+v (%v)", stats, err)
+	}
+
+	v2 := `{"memory":{"usage":2048,"usage_limit":8192},"cpu":{"usage_usec":7}}`
+	stats, err = parseContainerdMetrics([]byte(v2))
+	if err != nil || *stats != (ContainerStats{2048, 8192, 7000}) {
+		t.Errorf("Expected cgroup v2 metrics to be read, got %+v (%v)", stats, err)
+	}
+}
@@ -0,0 +1,61 @@
+package containers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/openshift/geard/utils"
+)
+
+// Environment variables passed to a container when it starts that are
+// never written to the environment store.  They are kept in the run
+// directory of the container, which is expected to be in memory, and are
+// printed with their values hidden.
+type Secrets []Environment
+
+func (s Secrets) Check() error {
+	trackSecretValues(s, true)
+	for i := range s {
+		if err := s[i].Check(); err != nil {
+			return fmt.Errorf("The secret '%s' is not valid: %s", s[i].Name, err.Error())
+		}
+		if strings.ContainsAny(s[i].Value, "\r\n") {
+			return fmt.Errorf("The secret '%s' may not contain a newline", s[i].Name)
+		}
+	}
+	return nil
+}
+
+// The names of the secrets, with their values masked
+func (s Secrets) String() string {
+	names := make([]string, len(s))
+	for i := range s {
+		names[i] = s[i].Name + "=" + utils.MaskSecret(s[i].Value)
+	}
+	return strings.Join(names, ",")
+}
+
+// Write the secrets as an environment file only the owner can read.
+func (s Secrets) Write(path string) error {
+	os.Remove(path)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Print("secrets: Unable to open secrets file: ", err)
+		return err
+	}
+	defer file.Close()
+
+	for i := range s {
+		if _, err := fmt.Fprintf(file, "%s=%s\n", s[i].Name, s[i].Value); err != nil {
+			log.Print("secrets: Unable to write secrets: ", err)
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		log.Print("secrets: Unable to close secrets file: ", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package containers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type SelectorOperator string
+
+const (
+	SelectorEquals       SelectorOperator = "="
+	SelectorNotEquals    SelectorOperator = "!="
+	SelectorIn           SelectorOperator = "in"
+	SelectorNotIn        SelectorOperator = "notin"
+	SelectorExists       SelectorOperator = "exists"
+	SelectorDoesNotExist SelectorOperator = "!"
+)
+
+// A single condition on the labels of a container
+type LabelRequirement struct {
+	Key      string
+	Operator SelectorOperator
+	Values   []string
+}
+
+func (r *LabelRequirement) Matches(labels Labels) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectorEquals, SelectorIn:
+		return ok && r.hasValue(value)
+	case SelectorNotEquals, SelectorNotIn:
+		return !ok || !r.hasValue(value)
+	case SelectorExists:
+		return ok
+	case SelectorDoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+func (r *LabelRequirement) hasValue(value string) bool {
+	for i := range r.Values {
+		if r.Values[i] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LabelRequirement) String() string {
+	switch r.Operator {
+	case SelectorEquals, SelectorNotEquals:
+		return r.Key + string(r.Operator) + r.Values[0]
+	case SelectorIn, SelectorNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	case SelectorDoesNotExist:
+		return "!" + r.Key
+	}
+	return r.Key
+}
+
+// A label selector matches the containers whose labels satisfy all of its
+// requirements.  Requirements are separated by commas and may be:
+//
+//	key=value, key==value    the label is set to value
+//	key!=value               the label is not set to value, or is unset
+//	key in (v1,v2)           the label is set to one of the values
+//	key notin (v1,v2)        the label is not set to any of the values
+//	key                      the label is set
+//	!key                     the label is not set
+//
+// An empty selector matches every container.
+type Selector []LabelRequirement
+
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s Selector) Matches(labels Labels) bool {
+	for i := range s {
+		if !s[i].Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i := range s {
+		parts[i] = s[i].String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func ParseSelector(s string) (Selector, error) {
+	parts, err := splitSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	selector := make(Selector, 0, len(parts))
+	for _, part := range parts {
+		requirement, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, *requirement)
+	}
+	return selector, nil
+}
+
+// Split on the commas that are not inside a set of values
+func splitSelector(s string) ([]string, error) {
+	parts := []string{}
+	depth := 0
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, errors.New("The selector may not contain nested parentheses")
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("The selector has an unmatched ')'")
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("The selector has an unmatched '('")
+	}
+	parts = append(parts, s[start:])
+
+	if len(parts) == 1 && strings.TrimSpace(parts[0]) == "" {
+		return []string{}, nil
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+		if parts[i] == "" {
+			return nil, errors.New("The selector may not contain an empty requirement")
+		}
+	}
+	return parts, nil
+}
+
+func parseRequirement(s string) (*LabelRequirement, error) {
+	if open := strings.Index(s, "("); open != -1 {
+		if !strings.HasSuffix(s, ")") {
+			return nil, fmt.Errorf("The requirement '%s' must end with the set of values", s)
+		}
+		fields := strings.Fields(s[:open])
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("The requirement '%s' must be of the form '<key> in (<value>,...)' or '<key> notin (<value>,...)'", s)
+		}
+		op := SelectorOperator(fields[1])
+		if op != SelectorIn && op != SelectorNotIn {
+			return nil, fmt.Errorf("The operator '%s' is not supported, use 'in' or 'notin'", fields[1])
+		}
+		values := []string{}
+		for _, value := range strings.Split(s[open+1:len(s)-1], ",") {
+			value = strings.TrimSpace(value)
+			if err := CheckLabelValue(value); err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		return newRequirement(fields[0], op, values)
+	}
+
+	for _, op := range []SelectorOperator{SelectorNotEquals, "==", SelectorEquals} {
+		if i := strings.Index(s, string(op)); i != -1 {
+			value := strings.TrimSpace(s[i+len(op):])
+			if err := CheckLabelValue(value); err != nil {
+				return nil, err
+			}
+			if op == "==" {
+				op = SelectorEquals
+			}
+			return newRequirement(strings.TrimSpace(s[:i]), op, []string{value})
+		}
+	}
+
+	if strings.HasPrefix(s, "!") {
+		return newRequirement(strings.TrimSpace(s[1:]), SelectorDoesNotExist, nil)
+	}
+	return newRequirement(s, SelectorExists, nil)
+}
+
+func newRequirement(key string, op SelectorOperator, values []string) (*LabelRequirement, error) {
+	if err := CheckLabelKey(key); err != nil {
+		return nil, err
+	}
+	return &LabelRequirement{key, op, values}, nil
+}
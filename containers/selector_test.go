@@ -0,0 +1,107 @@
+package containers
+
+import (
+	"strings"
+	"testing"
+)
+
+var selectorLabels = Labels{"env": "prod", "tier": "web"}
+
+func TestSelectorEquality(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"":                   true,
+		"env=prod":           true,
+		"env==prod":          true,
+		" env = prod ":       true,
+		"env=qa":             false,
+		"env!=qa":            true,
+		"env!=prod":          false,
+		"missing!=prod":      true,
+		"env=prod,tier=web":  true,
+		"env=prod,tier=api":  false,
+		"env":                true,
+		"!env":               false,
+		"!missing":           true,
+		"env=prod,!missing":  true,
+		"env=prod,tier!=web": false,
+	} {
+		selector, err := ParseSelector(s)
+		if err != nil {
+			t.Errorf("Unable to parse %q: %v", s, err)
+			continue
+		}
+		if selector.Matches(selectorLabels) != expected {
+			t.Errorf("Expected %q to match %v", s, expected)
+		}
+	}
+}
+
+func TestSelectorSets(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"env in (prod,qa)":                  true,
+		"env in (qa)":                       false,
+		"env in ( qa , prod )":              true,
+		"env notin (qa,dev)":                true,
+		"env notin (prod)":                  false,
+		"missing notin (prod)":              true,
+		"missing in (prod)":                 false,
+		"env in (prod,qa),tier notin (api)": true,
+		"env in (prod,qa),tier in (api,db)": false,
+		"tier=web,env in (prod)":            true,
+	} {
+		selector, err := ParseSelector(s)
+		if err != nil {
+			t.Errorf("Unable to parse %q: %v", s, err)
+			continue
+		}
+		if selector.Matches(selectorLabels) != expected {
+			t.Errorf("Expected %q to match %v", s, expected)
+		}
+	}
+}
+
+func TestSelectorString(t *testing.T) {
+	selector, err := ParseSelector("env==prod, tier in (web,api), !debug")
+	if err != nil {
+		t.Fatalf("Unable to parse selector: %v", err)
+	}
+	if s := selector.String(); s != "env=prod,tier in (api,web),!debug" {
+		t.Errorf("Unexpected selector string %q", s)
+	}
+	reparsed, err := ParseSelector(selector.String())
+	if err != nil || reparsed.String() != selector.String() {
+		t.Errorf("Expected the selector string to parse to the same selector: %v", err)
+	}
+}
+
+func TestSelectorInvalid(t *testing.T) {
+	for _, s := range []string{
+		"env=prod,",
+		"env in (prod",
+		"env in prod)",
+		"env within (prod)",
+		"env in (prod,(qa))",
+		"env=pr od",
+		"-env=prod",
+	} {
+		if _, err := ParseSelector(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestReadLabels(t *testing.T) {
+	labels, err := ReadLabelsFrom(strings.NewReader("# comment\nenv=prod\n\n tier = web \n"))
+	if err != nil {
+		t.Fatalf("Unable to read labels: %v", err)
+	}
+	if labels.String() != "env=prod,tier=web" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+	if _, err := ReadLabelsFrom(strings.NewReader("env\n")); err == nil {
+		t.Error("Expected a label without a value to be rejected")
+	}
+	if err := (Labels{"env": "a b"}).Check(); err == nil {
+		t.Error("Expected a label value with a space to be rejected")
+	}
+}
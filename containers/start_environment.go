@@ -0,0 +1,68 @@
+package containers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Environment variables that override the environment of a container for
+// a single start.  They are written to the run directory before the
+// container is started, and removed by the unit when the container stops,
+// so the next start uses the stored environment alone.
+type StartEnvironment []Environment
+
+func (s StartEnvironment) Check() error {
+	for i := range s {
+		if err := s[i].Check(); err != nil {
+			return fmt.Errorf("The start environment variable '%s' is not valid: %s", s[i].Name, err.Error())
+		}
+		if strings.ContainsAny(s[i].Value, "\r\n") {
+			return fmt.Errorf("The start environment variable '%s' may not contain a newline", s[i].Name)
+		}
+	}
+	return nil
+}
+
+// Write the overrides as an environment file only the owner can read, or
+// remove any left from an earlier start if there are none.
+func (s StartEnvironment) Write(path string) error {
+	if len(s) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Print("start_environment: Unable to remove start environment: ", err)
+			return err
+		}
+		return nil
+	}
+	os.Remove(path)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Print("start_environment: Unable to open start environment file: ", err)
+		return err
+	}
+	defer file.Close()
+
+	for i := range s {
+		if _, err := fmt.Fprintf(file, "%s=%s\n", s[i].Name, s[i].Value); err != nil {
+			log.Print("start_environment: Unable to write start environment: ", err)
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		log.Print("start_environment: Unable to close start environment file: ", err)
+		return err
+	}
+	return nil
+}
+
+// Whether the unit of the container reads a start environment.  Units
+// installed before start environments were supported, or for a version of
+// Docker without --env-file, must be reinstalled first.
+func UsesStartEnvironment(id Identifier) (bool, error) {
+	values, err := readUnitValues(id, "X-ContainerStartEnvironment")
+	if err != nil {
+		return false, err
+	}
+	return len(values) > 0, nil
+}
@@ -18,56 +18,115 @@ import (
 
 	"github.com/openshift/geard/cmd"
 	"github.com/openshift/geard/containers"
+	cjobs "github.com/openshift/geard/containers/jobs"
 	"github.com/openshift/geard/containers/systemd"
 	"github.com/openshift/geard/docker"
 	"github.com/openshift/geard/selinux"
 	"github.com/openshift/geard/ssh"
+	"github.com/openshift/geard/transport"
 	"github.com/openshift/geard/utils"
 )
 
 var (
-	pre  bool
-	post bool
+	pre     bool
+	post    bool
+	initEnv cmd.StringList
 )
 
-func RegisterInit(parent *cobra.Command) {
+func init() {
+	cjobs.InitPreStart = InitPreStart
+	cjobs.InitPostStart = InitPostStart
+}
+
+// Init commands send containers on other servers to their daemon
+type Command struct {
+	Transport *transport.TransportFlag
+}
+
+func (e *Command) RegisterInit(parent *cobra.Command) {
 	initGearCmd := &cobra.Command{
 		Use:   "init <name> <image>",
-		Short: "(Local) Setup the environment for a container",
-		Long:  "",
-		Run:   initGear,
+		Short: "Setup the environment for a container",
+		Long:  "Initialize a container before (--pre) or after (--post) it starts.  A container named as <host>/<name> is initialized by the daemon on that host, which must have installed it.",
+		Run:   e.initGear,
 	}
 	initGearCmd.Flags().BoolVarP(&pre, "pre", "", false, "Perform pre-start initialization")
 	initGearCmd.Flags().BoolVarP(&post, "post", "", false, "Perform post-start initialization")
+	initGearCmd.Flags().VarP(&initEnv, "init-env", "", "A KEY=VALUE variable exported to the init script of the container (--pre only), but not to its command.  May be repeated.")
 	parent.AddCommand(initGearCmd)
 }
 
-func initGear(c *cobra.Command, args []string) {
+func (e *Command) initGear(c *cobra.Command, args []string) {
 	if len(args) != 2 || !(pre || post) || (pre && post) {
 		cmd.Fail(1, "Valid arguments: <id> <image_name> (--pre|--post)")
 	}
-	containerId, err := containers.NewIdentifier(args[0])
+	if post && len(initEnv) > 0 {
+		cmd.Fail(1, "--init-env only applies to --pre, which writes the init script")
+	}
+	env, err := initEnvironment(initEnv)
+	if err != nil {
+		cmd.Fail(1, "--init-env is not valid: %s", err.Error())
+	}
+	t := e.Transport.Get()
+	locators, err := cmd.NewContainerLocators(t, args[0])
 	if err != nil {
 		cmd.Fail(1, "Argument 1 must be a valid gear identifier: %s", err.Error())
 	}
+	if locators[0].TransportLocator() != transport.Local {
+		cmd.Executor{
+			On: locators,
+			Serial: func(on cmd.Locator) cmd.JobRequest {
+				if pre {
+					return &cjobs.InitPreStartRequest{Id: cmd.AsIdentifier(on), Image: args[1], Environment: env}
+				}
+				return &cjobs.InitPostStartRequest{Id: cmd.AsIdentifier(on)}
+			},
+			Output:    os.Stdout,
+			Transport: t,
+		}.StreamAndExit()
+	}
+	containerId := cmd.AsIdentifier(locators[0])
 
 	dockerSocket := c.Flags().Lookup("docker-socket").Value.String()
 
 	switch {
 	case pre:
-		if err := initPreStart(dockerSocket, containerId, args[1]); err != nil {
+		if err := InitPreStart(dockerSocket, containerId, args[1], env); err != nil {
 			cmd.Fail(2, "Unable to initialize container %s", err.Error())
 		}
 	case post:
-		if err := initPostStart(dockerSocket, containerId); err != nil {
+		if err := InitPostStart(dockerSocket, containerId); err != nil {
 			cmd.Fail(2, "Unable to initialize container %s", err.Error())
 		}
 	}
 }
 
+// Read the KEY=VALUE arguments of --init-env, whose keys must be valid shell
+// variable names.
+func initEnvironment(values []string) (containers.EnvironmentVariables, error) {
+	env := make(containers.EnvironmentVariables, 0, len(values))
+	for i := range values {
+		v := containers.Environment{}
+		match, err := v.FromString(values[i])
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			return nil, fmt.Errorf("%s must be of the form KEY=VALUE", values[i])
+		}
+		if err := v.CheckShellName(); err != nil {
+			return nil, err
+		}
+		env = append(env, v)
+	}
+	return env, nil
+}
+
 var resolver addressResolver = addressResolver{}
 
-func initPreStart(dockerSocket string, id containers.Identifier, imageName string) error {
+// Create the user, home directory and init scripts of a container, before
+// it starts.  The variables of env are exported to the init script.
+func InitPreStart(dockerSocket string, id containers.Identifier, imageName string, env containers.EnvironmentVariables) error {
 	var (
 		err     error
 		imgInfo *dc.Image
@@ -128,6 +187,7 @@ func initPreStart(dockerSocket string, id containers.Identifier, imageName strin
 		strings.Join(volumes, " "),
 		ports,
 		socketActivationType == "proxied",
+		env,
 	}
 
 	file, _, err := utils.OpenFileExclusive(path.Join(id.RunPathFor(), "container-init.sh"), 0700)
@@ -173,7 +233,9 @@ func createUser(id containers.Identifier) error {
 	return nil
 }
 
-func initPostStart(dockerSocket string, id containers.Identifier) error {
+// Generate the authorized keys of a started container and route its
+// network links.
+func InitPostStart(dockerSocket string, id containers.Identifier) error {
 	var (
 		u         *user.User
 		container *dc.Container
@@ -3,6 +3,7 @@ package init
 import (
 	"text/template"
 
+	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/port"
 )
 
@@ -16,10 +17,14 @@ type ContainerInitScript struct {
 	Volumes        string
 	PortPairs      port.PortPairs
 	UseSocketProxy bool
+	// Exported while the init script runs, from 'gear init --init-env'
+	Environment containers.EnvironmentVariables
 }
 
-var ContainerInitTemplate = template.Must(template.New("container-init.sh").Parse(`#!/bin/bash
-{{ if .CreateUser }}
+// The init environment is unset before the command of the container runs,
+// since su keeps the environment of its caller.
+var ContainerInitTemplate = template.Must(template.New("container-init.sh").Funcs(template.FuncMap{"shellquote": containers.ShellQuote}).Parse(`#!/bin/bash
+{{ template "init-env" . }}{{ if .CreateUser }}
 groupadd -g {{.Gid}} {{.ContainerUser}}
 useradd -u {{.Uid}} -g {{.Gid}} {{.ContainerUser}}
 {{ else }}
@@ -36,8 +41,10 @@ chown -R {{.Uid}}:{{.Gid}} {{.Volumes}}
 {{ if .UseSocketProxy }}
 bash -c 'LISTEN_PID=$$ exec /usr/sbin/systemd-socket-proxyd {{ range .PortPairs }}127.0.0.1:{{ .Internal }}{{ end }}' &
 {{ end }}
-exec su {{.ContainerUser}} -s /bin/bash -c /.container.cmd
-`))
+{{ if .Environment }}unset{{ range .Environment }} {{ .Name }}{{ end }}
+{{ end }}exec su {{.ContainerUser}} -s /bin/bash -c /.container.cmd
+{{ define "init-env" }}{{ range .Environment }}export {{ .Name }}={{ shellquote .Value }}
+{{ end }}{{ end }}`))
 
 var ContainerCmdTemplate = template.Must(template.New("container-cmd.sh").Parse(`#!/bin/bash
 exec {{.Command}}
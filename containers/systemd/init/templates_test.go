@@ -0,0 +1,65 @@
+package init
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/containers"
+)
+
+func TestInitScriptEnvironment(t *testing.T) {
+	data := ContainerInitScript{
+		ContainerUser: "container",
+		Uid:           "1000",
+		Gid:           "1000",
+		Environment:   containers.EnvironmentVariables{{"LOG_LEVEL", "debug"}, {"GREETING", `it's "$HOME" & more`}},
+	}
+
+	// The init script runs the exports before anything else
+	exports := &bytes.Buffer{}
+	if err := ContainerInitTemplate.ExecuteTemplate(exports, "init-env", data); err != nil {
+		t.Fatalf("Unable to render the init environment: %v", err)
+	}
+	out, err := exec.Command("/bin/bash", "-c", exports.String()+"printenv LOG_LEVEL GREETING").Output()
+	if err != nil {
+		t.Fatalf("Unable to run the init environment: %v\n%s", err, exports.String())
+	}
+	if string(out) != "debug\nit's \"$HOME\" & more\n" {
+		t.Errorf("Expected the init script to see the variables, got %q", string(out))
+	}
+
+	script := &bytes.Buffer{}
+	if err := ContainerInitTemplate.Execute(script, data); err != nil {
+		t.Fatalf("Unable to render the init script: %v", err)
+	}
+	s := script.String()
+	if !strings.HasPrefix(s, "#!/bin/bash\n"+exports.String()) {
+		t.Errorf("Expected the variables to be exported first, got:\n%s", s)
+	}
+	if !strings.Contains(s, "unset LOG_LEVEL GREETING\nexec su container") {
+		t.Errorf("Expected the variables to be unset before the command runs, got:\n%s", s)
+	}
+
+	data.Environment = nil
+	script.Reset()
+	if err := ContainerInitTemplate.Execute(script, data); err != nil {
+		t.Fatalf("Unable to render the init script: %v", err)
+	}
+	if strings.Contains(script.String(), "export") || strings.Contains(script.String(), "unset") {
+		t.Errorf("Expected no init environment, got:\n%s", script.String())
+	}
+}
+
+func TestInitEnvironmentFlag(t *testing.T) {
+	env, err := initEnvironment([]string{"LOG_LEVEL=debug", "EMPTY="})
+	if err != nil || len(env) != 2 || env[0] != (containers.Environment{"LOG_LEVEL", "debug"}) || env[1].Name != "EMPTY" {
+		t.Errorf("Expected the variables to be read, got %v %v", env, err)
+	}
+	for _, s := range []string{"LOG_LEVEL", "LOG-LEVEL=debug", "1ST=a", "=a"} {
+		if _, err := initEnvironment([]string{s}); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package reload
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/systemd"
+)
+
+func RegisterReload(parent *cobra.Command) {
+	reloadCmd := &cobra.Command{
+		Use:   "reload",
+		Short: "(Local) Reload the systemd configuration after unit files are edited by hand",
+		Long:  "Run the equivalent of 'systemctl daemon-reload' and report the container units that changed or can no longer be loaded.  Nothing is started or stopped; a changed unit is used the next time its container starts.  Exits non-zero if any unit is invalid.",
+		Run:   reload,
+	}
+	parent.AddCommand(reloadCmd)
+}
+
+func reload(c *cobra.Command, args []string) {
+	if len(args) != 0 {
+		gcmd.Fail(1, "Valid arguments: (none)")
+	}
+	systemd.Require()
+
+	ids, err := containers.InstalledIdentifiers()
+	if err != nil {
+		gcmd.Fail(1, "Unable to list the installed containers: %s", err.Error())
+	}
+	changes, err := Reload(systemd.Connection(), ids)
+	if err != nil {
+		gcmd.Fail(1, "Unable to reload the systemd configuration: %s", systemd.SprintSystemdError(err))
+	}
+	changes.WriteSummaryTo(os.Stdout)
+	if changes.Invalid() > 0 {
+		os.Exit(1)
+	}
+}
+
+// Write a table of the changed units, or a line saying there are none.
+func (c UnitChanges) WriteSummaryTo(out io.Writer) {
+	if len(c) == 0 {
+		fmt.Fprintln(out, "Reloaded the systemd configuration, no container units changed")
+		return
+	}
+	fmt.Fprintf(out, "Reloaded the systemd configuration, container units changed: %d, invalid: %d\n", len(c)-c.Invalid(), c.Invalid())
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "UNIT\tCHANGE\tREASON")
+	for i := range c {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c[i].Id.UnitNameFor(), c[i].Change, c[i].Reason)
+	}
+	w.Flush()
+}
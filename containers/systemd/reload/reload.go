@@ -0,0 +1,93 @@
+// Reload the systemd configuration after container units are edited by
+// hand, and report the units whose definition changed as a result.
+package reload
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/systemd"
+)
+
+type Change string
+
+const (
+	// The unit file changed on disk since systemd loaded it, or the unit
+	// could not be loaded before and now can
+	UnitChanged Change = "changed"
+	// Systemd could not load the unit file after the reload
+	UnitInvalid Change = "invalid"
+)
+
+type UnitChange struct {
+	Id     containers.Identifier
+	Change Change
+	// Why an invalid unit could not be loaded, as systemd reports it
+	Reason string `json:",omitempty"`
+}
+
+type UnitChanges []UnitChange
+
+// Run the equivalent of 'systemctl daemon-reload' and compare the units
+// of the containers before and after.  Units are not started or stopped,
+// a changed unit is used the next time it starts.
+func Reload(conn systemd.Systemd, ids []containers.Identifier) (UnitChanges, error) {
+	before := make(map[containers.Identifier]map[string]interface{}, len(ids))
+	for _, id := range ids {
+		if p, err := conn.GetUnitProperties(id.UnitNameFor()); err == nil {
+			before[id] = p
+		}
+	}
+
+	if err := conn.Reload(); err != nil {
+		return nil, err
+	}
+
+	changes := UnitChanges{}
+	for _, id := range ids {
+		after, err := conn.GetUnitProperties(id.UnitNameFor())
+		if err != nil {
+			continue
+		}
+		state, _ := after["LoadState"].(string)
+		switch {
+		case state != "" && state != "loaded":
+			changes = append(changes, UnitChange{id, UnitInvalid, loadErrorReason(state, after["LoadError"])})
+		case before[id] == nil:
+		case before[id]["NeedDaemonReload"] == true, before[id]["LoadState"] != after["LoadState"]:
+			changes = append(changes, UnitChange{id, UnitChanged, ""})
+		}
+	}
+	sort.Sort(changesById(changes))
+	return changes, nil
+}
+
+// The number of units that could not be loaded.
+func (c UnitChanges) Invalid() int {
+	count := 0
+	for i := range c {
+		if c[i].Change == UnitInvalid {
+			count++
+		}
+	}
+	return count
+}
+
+// The load state and, if systemd reports one, the message of the error
+// that prevented the unit from loading.  LoadError is a (name, message)
+// pair.
+func loadErrorReason(state string, loadError interface{}) string {
+	if pair, ok := loadError.([]interface{}); ok && len(pair) == 2 {
+		if message, ok := pair[1].(string); ok && message != "" {
+			return fmt.Sprintf("%s: %s", state, message)
+		}
+	}
+	return state
+}
+
+type changesById UnitChanges
+
+func (c changesById) Len() int           { return len(c) }
+func (c changesById) Less(i, j int) bool { return c[i].Id < c[j].Id }
+func (c changesById) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
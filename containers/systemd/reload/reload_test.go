@@ -0,0 +1,137 @@
+package reload
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/systemd"
+)
+
+// A systemd that records the calls made to it, and reports the properties
+// of units as they are before and after the daemon is reloaded.
+type recordingSystemd struct {
+	*systemd.StubSystemd
+	calls    []string
+	reloaded bool
+	before   map[string]map[string]interface{}
+	after    map[string]map[string]interface{}
+	failed   error
+}
+
+func (s *recordingSystemd) Reload() error {
+	s.calls = append(s.calls, "daemon-reload")
+	if s.failed != nil {
+		return s.failed
+	}
+	s.reloaded = true
+	return nil
+}
+
+func (s *recordingSystemd) GetUnitProperties(unit string) (map[string]interface{}, error) {
+	props := s.before
+	if s.reloaded {
+		props = s.after
+	}
+	if p, ok := props[unit]; ok {
+		return p, nil
+	}
+	return nil, errors.New("No such unit")
+}
+
+func (s *recordingSystemd) StartUnit(name string, mode string) (string, error) {
+	s.calls = append(s.calls, "start "+name)
+	return "done", nil
+}
+
+func (s *recordingSystemd) StopUnit(name string, mode string) (string, error) {
+	s.calls = append(s.calls, "stop "+name)
+	return "done", nil
+}
+
+func (s *recordingSystemd) RestartUnit(name string, mode string) (string, error) {
+	s.calls = append(s.calls, "restart "+name)
+	return "done", nil
+}
+
+func loaded(needReload bool) map[string]interface{} {
+	return map[string]interface{}{"LoadState": "loaded", "NeedDaemonReload": needReload}
+}
+
+func TestReload(t *testing.T) {
+	conn := &recordingSystemd{
+		StubSystemd: systemd.NewStubSystemd(),
+		before: map[string]map[string]interface{}{
+			"ctr-same.service":    loaded(false),
+			"ctr-edited.service":  loaded(true),
+			"ctr-broken.service":  loaded(true),
+			"ctr-fixed.service":   {"LoadState": "error", "NeedDaemonReload": false},
+			"ctr-unknown.service": loaded(false),
+		},
+		after: map[string]map[string]interface{}{
+			"ctr-same.service":   loaded(false),
+			"ctr-edited.service": loaded(false),
+			"ctr-broken.service": {"LoadState": "bad-setting", "LoadError": []interface{}{"org.freedesktop.systemd1.BadSetting", "Unit ctr-broken.service has a bad unit file setting."}},
+			"ctr-fixed.service":  loaded(false),
+			"ctr-new.service":    loaded(false),
+		},
+	}
+	ids := []containers.Identifier{"same", "edited", "broken", "fixed", "new", "unknown"}
+
+	changes, err := Reload(conn, ids)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(conn.calls, []string{"daemon-reload"}) {
+		t.Errorf("Expected only a daemon-reload, got %v", conn.calls)
+	}
+	expected := UnitChanges{
+		{"broken", UnitInvalid, "bad-setting: Unit ctr-broken.service has a bad unit file setting."},
+		{"edited", UnitChanged, ""},
+		{"fixed", UnitChanged, ""},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %+v, got %+v", expected, changes)
+	}
+	if changes.Invalid() != 1 {
+		t.Errorf("Expected one invalid unit, got %d", changes.Invalid())
+	}
+
+	buf := &bytes.Buffer{}
+	changes.WriteSummaryTo(buf)
+	for _, s := range []string{"container units changed: 2, invalid: 1", "ctr-broken.service  invalid", "ctr-edited.service  changed"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Expected the summary to contain %q:\n%s", s, buf.String())
+		}
+	}
+}
+
+func TestReloadUnchanged(t *testing.T) {
+	conn := &recordingSystemd{
+		StubSystemd: systemd.NewStubSystemd(),
+		before:      map[string]map[string]interface{}{"ctr-same.service": loaded(false)},
+		after:       map[string]map[string]interface{}{"ctr-same.service": loaded(false)},
+	}
+	changes, err := Reload(conn, []containers.Identifier{"same"})
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("Expected no changes, got %+v %v", changes, err)
+	}
+	if len(conn.calls) != 1 {
+		t.Errorf("Expected a single daemon-reload, got %v", conn.calls)
+	}
+	buf := &bytes.Buffer{}
+	changes.WriteSummaryTo(buf)
+	if !strings.Contains(buf.String(), "no container units changed") {
+		t.Errorf("Expected the summary to say nothing changed, got %q", buf.String())
+	}
+}
+
+func TestReloadFailed(t *testing.T) {
+	conn := &recordingSystemd{StubSystemd: systemd.NewStubSystemd(), failed: errors.New("Access denied")}
+	if _, err := Reload(conn, []containers.Identifier{"same"}); err != conn.failed {
+		t.Errorf("Expected the reload error to be returned, got %v", err)
+	}
+}
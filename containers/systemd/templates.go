@@ -1,6 +1,8 @@
 package systemd
 
 import (
+	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/openshift/geard/config"
@@ -17,6 +19,13 @@ type ContainerUnit struct {
 	Isolate  bool
 	User     string
 	ReqId    string
+	// The digest the image resolved to when it was pulled, if known
+	ImageDigest containers.ImageDigest
+	// The release the container was installed from, if given
+	Revision containers.Revision
+	// Shown by systemctl status, "Container <id>" if empty
+	Description   containers.UnitDescription
+	Documentation containers.UnitDocumentation
 
 	HomeDir         string
 	RunDir          string
@@ -24,27 +33,246 @@ type ContainerUnit struct {
 	ExecutablePath  string
 	IncludePath     string
 
+	// If set, the environment is encrypted at rest and a decrypted copy
+	// is written to EnvironmentPath before the container starts
+	EncryptedEnvironment containers.Identifier
+	EnvironmentKeyPath   string
+
+	// If set, the environment OwnEnvironment is merged over the one it
+	// inherits from when the container starts, and the result written to
+	// EnvironmentPath
+	InheritEnvironment containers.Identifier
+	OwnEnvironment     containers.Identifier
+
+	// An environment file of secrets passed to the container after the
+	// stored environment
+	SecretsPath string
+
+	// An environment file of overrides for a single start, passed to the
+	// container last and removed when it stops
+	StartEnvironmentPath string
+
+	// Pull the image before starting the container if it is not present
+	PullAtStart bool
+
+	// If set, the container is sent this signal when its environment
+	// changes, and reads the current environment from a copy at
+	// ReloadEnvironmentPath
+	EnvReloadSignal       string
+	ReloadEnvironmentPath string
+
 	PortPairs            port.PortPairs
 	SocketUnitName       string
 	SocketActivationType string
 
+	// If set, the path unit of the container restarts it when this path
+	// changes
+	WatchPath string
+
+	// If set, the daemon applies the variables in this file to the
+	// environment of the container when it changes, and restarts the
+	// container afterwards if EnvFileWatchRestart is set
+	EnvFileWatch        string
+	EnvFileWatchRestart bool
+
+	// Seconds Docker waits for the container to exit before killing it
+	StopTimeout int
+
+	// The docker network mode, if not the default bridge
+	NetworkMode containers.NetworkMode
+	// Name servers and /etc/hosts entries for the container
+	DNS        containers.DNSServers
+	ExtraHosts containers.HostEntries
+	// Docker links to other containers, whose units are started first
+	Links containers.ContainerAliases
+
+	// The docker logging driver and its options, if not the default
+	LogDriver  containers.LogDriver
+	LogOptions containers.LogOptions
+	// Resource limits on the processes of the container, and the CPUs and
+	// memory nodes they may run on
+	Ulimits    containers.Ulimits
+	CPUSetCPUs containers.CPUSet
+	CPUSetMems containers.CPUSet
+
+	// Overrides for the entrypoint, command, and working directory of the image
+	Entrypoint string
+	Cmd        []string
+	WorkingDir string
+
+	// Additional directives for the unit, and arguments appended verbatim
+	// to docker run
+	Properties containers.UnitProperties
+	DockerArgs containers.DockerArgs
+
 	DockerFeatures config.DockerFeatures
+
+	// The containerd client and address that run the container, if it is
+	// not run by Docker
+	ContainerdCommand string
+	ContainerdAddress string
+}
+
+// The description of the unit.  The default is relied on to recognize
+// the unit when the container is renamed.
+func (u ContainerUnit) UnitDescription() string {
+	if u.Description == "" {
+		return "Container " + string(u.Id)
+	}
+	return u.Description.UnitValue()
+}
+
+// Systemd must wait longer than Docker before it kills a unit, so that
+// Docker has the chance to stop the container cleanly.
+const StopTimeoutGrace = 5
+
+func (u ContainerUnit) TimeoutStopSec() int {
+	return u.StopTimeout + StopTimeoutGrace
+}
+
+// The docker run options overriding the image network, name resolution,
+// entrypoint, and working directory.
+func (u ContainerUnit) RunOverrides() string {
+	args := []string{}
+	if !u.NetworkMode.Default() {
+		args = append(args, "--net", ExecArg(string(u.NetworkMode)))
+	}
+	for _, server := range u.DNS {
+		args = append(args, "--dns", ExecArg(server))
+	}
+	for _, entry := range u.ExtraHosts {
+		args = append(args, "--add-host", ExecArg(entry.String()))
+	}
+	for _, link := range u.Links {
+		args = append(args, "--link", ExecArg(link.String()))
+	}
+	if u.Entrypoint != "" {
+		args = append(args, "--entrypoint", ExecArg(u.Entrypoint))
+	}
+	if u.WorkingDir != "" {
+		args = append(args, "--workdir", ExecArg(u.WorkingDir))
+	}
+	return strings.Join(args, " ")
+}
+
+// The docker run options selecting the logging driver of the container.
+func (u ContainerUnit) LogSpec() string {
+	args := []string{}
+	if u.LogDriver != "" {
+		args = append(args, "--log-driver", ExecArg(string(u.LogDriver)))
+	}
+	for _, option := range u.LogOptions {
+		args = append(args, "--log-opt", ExecArg(option.String()))
+	}
+	return strings.Join(args, " ")
+}
+
+// The docker run options limiting the resources of the container processes.
+func (u ContainerUnit) ResourceSpec() string {
+	args := []string{}
+	for _, limit := range u.Ulimits {
+		args = append(args, "--ulimit", ExecArg(limit.String()))
+	}
+	if u.CPUSetCPUs != "" {
+		args = append(args, "--cpuset-cpus", ExecArg(string(u.CPUSetCPUs)))
+	}
+	if u.CPUSetMems != "" {
+		args = append(args, "--cpuset-mems", ExecArg(string(u.CPUSetMems)))
+	}
+	return strings.Join(args, " ")
+}
+
+// The docker run option mounting the directory of the reloadable copy of
+// the environment, if the container reloads its environment.
+func (u ContainerUnit) ReloadEnvironmentVolume() string {
+	if u.EnvReloadSignal == "" {
+		return ""
+	}
+	return "-v " + ExecArg(filepath.Dir(u.ReloadEnvironmentPath)+":"+containers.EnvReloadMountPath+":ro")
+}
+
+// The ctr global options selecting the containerd address and namespace.
+func (u ContainerUnit) ContainerdArgs() string {
+	return "--address " + ExecArg(u.ContainerdAddress) + " --namespace " + ExecArg(containers.ContainerdNamespace)
+}
+
+// The ctr run options for the network and working directory.  Containerd
+// has no network of its own to attach the container to, so it shares the
+// host's.
+func (u ContainerUnit) ContainerdRunOverrides() string {
+	args := []string{"--net-host"}
+	if u.WorkingDir != "" {
+		args = append(args, "--cwd", ExecArg(u.WorkingDir))
+	}
+	return strings.Join(args, " ")
+}
+
+// The fully qualified image reference containerd runs.
+func (u ContainerUnit) ContainerdImage() string {
+	return containers.ContainerdImageRef(u.Image)
+}
+
+// The command passed to the image entrypoint.
+func (u ContainerUnit) RunCommand() string {
+	args := make([]string, len(u.Cmd))
+	for i := range u.Cmd {
+		args[i] = ExecArg(u.Cmd[i])
+	}
+	return strings.Join(args, " ")
+}
+
+// Sections of additional directives that are not part of the unit
+// template, [Unit] and [Service] being written inline.
+func (u ContainerUnit) ExtraSections() string {
+	sections := []string{}
+	for _, section := range u.Properties.SectionsExcept("Unit", "Service") {
+		sections = append(sections, "["+section+"]\n"+u.Properties.Directives(section))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+var execArgEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$")
+
+// Quote a value as a single argument of a systemd Exec command line, so
+// that it is passed to the process exactly as given.  Systemd would
+// otherwise split on whitespace and expand quotes, escapes, specifiers
+// (%) and environment variables ($).
+func ExecArg(s string) string {
+	return `"` + execArgEscaper.Replace(s) + `"`
 }
 
 var ContainerUnitTemplate = template.Must(template.New("unit.service").Parse(`
 {{define "COMMON_UNIT"}}
 [Unit]
-Description=Container {{.Id}}
+Description={{.UnitDescription}}
+{{ if .Documentation }}Documentation={{.Documentation.UnitValue}}
+{{ end }}{{range .Links}}Wants={{.Id.UnitNameFor}}
+After={{.Id.UnitNameFor}}
+{{end}}{{.Properties.Directives "Unit"}}
 {{end}}
 
 {{define "COMMON_SERVICE"}}
 [Service]
 Type=simple
 TimeoutStartSec=5m
+TimeoutStopSec={{.TimeoutStopSec}}
 {{ if .Slice }}Slice={{.Slice}}{{ end }}
-{{ if .EnvironmentPath }}EnvironmentFile={{.EnvironmentPath}}{{ end }}
+{{ if .InheritEnvironment }}EnvironmentFile=-{{.EnvironmentPath}}
+ExecStartPre={{.ExecutablePath}} decrypt-env {{ if .EnvironmentKeyPath }}--env-encryption-key-file="{{.EnvironmentKeyPath}}" {{ end }}--inherit="{{.InheritEnvironment}}" "{{.OwnEnvironment}}" "{{.EnvironmentPath}}"
+{{ else if .EncryptedEnvironment }}EnvironmentFile=-{{.EnvironmentPath}}
+ExecStartPre={{.ExecutablePath}} decrypt-env --env-encryption-key-file="{{.EnvironmentKeyPath}}" "{{.EncryptedEnvironment}}" "{{.EnvironmentPath}}"
+{{ else if .EnvironmentPath }}EnvironmentFile={{.EnvironmentPath}}{{ end }}
+{{ if .StartEnvironmentPath }}# Overrides of the environment last only until the container stops.  The
+# run directory is emptied on reboot, when systemd starts the container.
+ExecStartPre=/bin/mkdir -p "{{.RunDir}}"
+ExecStartPre=/usr/bin/touch "{{.StartEnvironmentPath}}"
+ExecStopPost=-/bin/rm -f "{{.StartEnvironmentPath}}"{{ end }}
+{{.Properties.Directives "Service"}}
 {{end}}
 
+{{define "PULL_IMAGE"}}{{ if .PullAtStart }}# Pull the image if it is not present
+ExecStartPre=/bin/sh -c '/usr/bin/docker inspect --format="Using image {{"{{.Id}}"}}" "{{.Image}}" || exec /usr/bin/docker pull "{{.Image}}"'{{ end }}{{end}}
+
 {{define "COMMON_CONTAINER"}}
 [Install]
 WantedBy=container.target
@@ -52,10 +280,22 @@ WantedBy=container.target
 # Container information
 X-ContainerId={{.Id}}
 X-ContainerImage={{.Image}}
-X-ContainerUserId={{.User}}
+{{ if .ImageDigest }}X-ContainerImageDigest={{.ImageDigest}}
+{{ end }}X-ContainerUserId={{.User}}
 X-ContainerRequestId={{.ReqId}}
-X-ContainerType={{ if .Isolate }}isolated{{ else }}simple{{ end }}
-{{range .PortPairs}}X-PortMapping={{.Internal}}:{{.External}}
+{{ if .Revision }}X-ContainerRevision={{.Revision}}
+{{ end }}{{ if .StartEnvironmentPath }}X-ContainerStartEnvironment={{.StartEnvironmentPath}}
+{{ end }}X-ContainerType={{ if .Isolate }}isolated{{ else }}simple{{ end }}
+X-ContainerStopTimeout={{.StopTimeout}}
+{{ if .EnvReloadSignal }}X-EnvReloadSignal={{.EnvReloadSignal}}
+{{ end }}{{ if .EnvFileWatch }}X-EnvFileWatch={{.EnvFileWatch}}
+{{ if .EnvFileWatchRestart }}X-EnvFileWatchRestart=true
+{{ end }}{{ end }}{{ if .LogDriver }}X-ContainerLogDriver={{.LogDriver}}
+{{ end }}{{ if .CPUSetCPUs }}X-ContainerCPUSetCPUs={{.CPUSetCPUs}}
+{{ end }}{{ if .CPUSetMems }}X-ContainerCPUSetMems={{.CPUSetMems}}
+{{ end }}{{ if .InheritEnvironment }}X-ContainerInheritEnv={{.InheritEnvironment}}
+{{ end }}{{range .Links}}X-ContainerLink={{.}}
+{{ end }}{{range .PortPairs}}X-PortMapping={{.Internal}}:{{.External}}
 {{end}}
 {{end}}
 
@@ -63,43 +303,51 @@ X-ContainerType={{ if .Isolate }}isolated{{ else }}simple{{ end }}
 {{define "SIMPLE"}}
 {{template "COMMON_UNIT" .}}
 {{template "COMMON_SERVICE" .}}
+{{template "PULL_IMAGE" .}}
 # Create data container
 ExecStartPre=/bin/sh -c '/usr/bin/docker inspect --format="Reusing {{"{{.ID}}"}}" "{{.Id}}-data" || exec docker run --name "{{.Id}}-data" --volumes-from "{{.Id}}-data" --entrypoint true "{{.Image}}"'
 ExecStartPre=-/usr/bin/docker rm "{{.Id}}"
+{{ if .EnvReloadSignal }}# Copy the environment for the container to reload
+ExecStartPre=-{{.ExecutablePath}} decrypt-env {{ if .EnvironmentKeyPath }}--env-encryption-key-file="{{.EnvironmentKeyPath}}" {{ end }}{{ if .InheritEnvironment }}--inherit="{{.InheritEnvironment}}" {{ end }}"{{.Id}}" "{{.ReloadEnvironmentPath}}"{{ end }}
 {{ if .Isolate }}# Initialize user and volumes
 ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ end }}
 ExecStart=/usr/bin/docker run --rm --name "{{.Id}}" \
           --volumes-from "{{.Id}}-data" \
-          {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} \
-          -a stdout -a stderr {{.PortSpec}} {{.RunSpec}} \
+          {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} {{ if .SecretsPath }}--env-file "{{ .SecretsPath }}"{{ end }} {{ if .StartEnvironmentPath }}--env-file "{{ .StartEnvironmentPath }}"{{ end }} \
+          -a stdout -a stderr {{.PortSpec}} {{.RunSpec}} {{.RunOverrides}} {{.LogSpec}} {{.ResourceSpec}} {{.ReloadEnvironmentVolume}} {{.DockerArgs}} \
           {{ if .Isolate }} -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro -v {{.RunDir}}/container-init.sh:/.container.init:ro -u root {{end}} \
-          "{{.Image}}" {{ if .Isolate }} /.container.init {{ end }}
+          "{{.Image}}" {{ if .Isolate }} /.container.init {{ else }}{{.RunCommand}}{{ end }}
 # Set links (requires container have a name)
 ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
-ExecReload=-/usr/bin/docker stop "{{.Id}}"
+ExecReload=-/usr/bin/docker stop -t {{.StopTimeout}} "{{.Id}}"
 ExecReload=-/usr/bin/docker rm "{{.Id}}"
-ExecStop=-/usr/bin/docker stop "{{.Id}}"
+ExecStop=-/usr/bin/docker stop -t {{.StopTimeout}} "{{.Id}}"
 {{template "COMMON_CONTAINER" .}}
+{{.ExtraSections}}
 {{end}}
 
 {{/* A unit that uses Docker with the 'foreground' flag to run an image under the current context */}}
 {{define "FOREGROUND"}}
 {{template "COMMON_UNIT" .}}
 {{template "COMMON_SERVICE" .}}
+{{template "PULL_IMAGE" .}}
 # Create data container
 ExecStartPre=/bin/sh -c '/usr/bin/docker inspect --format="Reusing {{"{{.ID}}"}}" "{{.Id}}-data" || exec docker run --name "{{.Id}}-data" --volumes-from "{{.Id}}-data" --entrypoint true "{{.Image}}"'
 ExecStartPre=-/usr/bin/docker rm "{{.Id}}"
+{{ if .EnvReloadSignal }}# Copy the environment for the container to reload
+ExecStartPre=-{{.ExecutablePath}} decrypt-env {{ if .EnvironmentKeyPath }}--env-encryption-key-file="{{.EnvironmentKeyPath}}" {{ end }}{{ if .InheritEnvironment }}--inherit="{{.InheritEnvironment}}" {{ end }}"{{.Id}}" "{{.ReloadEnvironmentPath}}"{{ end }}
 {{ if .Isolate }}# Initialize user and volumes
 ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"{{ end }}
 ExecStart=/usr/bin/docker run --rm --foreground \
-          {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} \
-          {{.PortSpec}} {{.RunSpec}} \
+          {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} {{ if .SecretsPath }}--env-file "{{ .SecretsPath }}"{{ end }} {{ if .StartEnvironmentPath }}--env-file "{{ .StartEnvironmentPath }}"{{ end }} \
+          {{.PortSpec}} {{.RunSpec}} {{.RunOverrides}} {{.LogSpec}} {{.ResourceSpec}} {{.ReloadEnvironmentVolume}} {{.DockerArgs}} \
           --name "{{.Id}}" --volumes-from "{{.Id}}-data" \
           {{ if .Isolate }} -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro -v {{.RunDir}}/container-init.sh:/.container.init:ro -u root {{end}} \
-          "{{.Image}}" {{ if .Isolate }} /.container.init {{ end }}
+          "{{.Image}}" {{ if .Isolate }} /.container.init {{ else }}{{.RunCommand}}{{ end }}
 # Set links (requires container have a name)
 ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
 {{template "COMMON_CONTAINER" .}}
+{{.ExtraSections}}
 {{end}}
 
 {{/* A unit that exposes socket activation and process isolation */}}
@@ -108,12 +356,13 @@ ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
 BindsTo={{.SocketUnitName}}
 
 {{template "COMMON_SERVICE" .}}
+{{template "PULL_IMAGE" .}}
 ExecStartPre={{.ExecutablePath}} init --pre "{{.Id}}" "{{.Image}}"
 ExecStart=/usr/bin/docker run \
             --name "{{.Id}}" \
             --volumes-from "{{.Id}}" \
-            {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} \
-            -a stdout -a stderr {{.RunSpec}} \
+            {{ if and .EnvironmentPath .DockerFeatures.EnvironmentFile }}--env-file "{{ .EnvironmentPath }}"{{ end }} {{ if .SecretsPath }}--env-file "{{ .SecretsPath }}"{{ end }} {{ if .StartEnvironmentPath }}--env-file "{{ .StartEnvironmentPath }}"{{ end }} \
+            -a stdout -a stderr {{.RunSpec}} {{.LogSpec}} {{.ResourceSpec}} {{.DockerArgs}} \
             --env LISTEN_FDS \
             -v {{.RunDir}}/container-init.sh:/.container.init:ro \
             -v {{.RunDir}}/container-cmd.sh:/.container.cmd:ro \
@@ -123,6 +372,22 @@ ExecStart=/usr/bin/docker run \
 ExecStartPost=-{{.ExecutablePath}} init --post "{{.Id}}" "{{.Image}}"
 {{template "COMMON_CONTAINER" .}}
 X-SocketActivated={{.SocketActivationType}}
+{{.ExtraSections}}
+{{end}}
+
+{{/* A unit that runs the container with containerd on the host network */}}
+{{define "CONTAINERD"}}
+{{template "COMMON_UNIT" .}}
+{{template "COMMON_SERVICE" .}}
+ExecStartPre=-{{.ContainerdCommand}} {{.ContainerdArgs}} containers rm "{{.Id}}"
+ExecStart={{.ContainerdCommand}} {{.ContainerdArgs}} run --rm \
+          {{ if .EnvironmentPath }}--env-file "{{ .EnvironmentPath }}"{{ end }} {{ if .SecretsPath }}--env-file "{{ .SecretsPath }}"{{ end }} {{ if .StartEnvironmentPath }}--env-file "{{ .StartEnvironmentPath }}"{{ end }} \
+          {{.ContainerdRunOverrides}} \
+          "{{.ContainerdImage}}" "{{.Id}}" {{.RunCommand}}
+ExecStop=-{{.ContainerdCommand}} {{.ContainerdArgs}} tasks kill --signal SIGTERM "{{.Id}}"
+{{template "COMMON_CONTAINER" .}}
+X-ContainerRuntime=containerd
+{{.ExtraSections}}
 {{end}}
 
 {{/* Run DEFAULT */}}
@@ -141,6 +406,36 @@ Description=Container socket {{.Id}}
 WantedBy=container-sockets.target
 `))
 
+// The template unit that restarts a container, if it is running, when its
+// path unit is triggered.  Starting a running service does nothing, so the
+// path unit can't trigger the container unit directly.
+const RestartUnitName = "container-restart@"
+
+func RestartUnitNameFor(id containers.Identifier) string {
+	return RestartUnitName + string(id) + ".service"
+}
+
+var ContainerPathTemplate = template.Must(template.New("unit.path").Parse(`
+[Unit]
+Description=Container watch {{.Id}}
+
+[Path]
+PathChanged={{.WatchPath}}
+Unit=` + RestartUnitName + `{{.Id}}.service
+
+[Install]
+WantedBy=container-active.target
+`))
+
+var ContainerRestartTemplate = template.Must(template.New("unit.service").Parse(`
+[Unit]
+Description=Restart container %i
+
+[Service]
+Type=oneshot
+ExecStart=/usr/bin/systemctl try-restart ` + containers.IdentifierPrefix + `%i.service
+`))
+
 type TargetUnit struct {
 	Name     string
 	WantedBy string
@@ -0,0 +1,483 @@
+package systemd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/containers"
+)
+
+func TestContainerUnitStopTimeout(t *testing.T) {
+	unit := ContainerUnit{
+		Id:          "test-stop",
+		Image:       "test/image",
+		StopTimeout: 30,
+	}
+
+	for _, name := range []string{"SIMPLE", "FOREGROUND", "SOCKETACTIVATED"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		if !strings.Contains(buf.String(), "\nTimeoutStopSec=35\n") {
+			t.Errorf("Expected the %s unit to wait for docker to stop the container:\n%s", name, buf.String())
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if !strings.Contains(buf.String(), `ExecStop=-/usr/bin/docker stop -t 30 "test-stop"`) {
+		t.Errorf("Expected the stop timeout to be passed to docker:\n%s", buf.String())
+	}
+}
+
+func TestContainerUnitEncryptedEnvironment(t *testing.T) {
+	unit := ContainerUnit{
+		Id:                   "test-env",
+		Image:                "test/image",
+		EnvironmentPath:      "/var/run/geard/test-env/environment",
+		ExecutablePath:       "/usr/bin/gear",
+		EncryptedEnvironment: "shared-env",
+		EnvironmentKeyPath:   "/etc/geard/env.keys",
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, "\nEnvironmentFile=-/var/run/geard/test-env/environment\n") {
+		t.Errorf("Expected the decrypted environment to be optional until it is written:\n%s", s)
+	}
+	if !strings.Contains(s, `ExecStartPre=/usr/bin/gear decrypt-env --env-encryption-key-file="/etc/geard/env.keys" "shared-env" "/var/run/geard/test-env/environment"`) {
+		t.Errorf("Expected the environment to be decrypted before the container starts:\n%s", s)
+	}
+	if strings.Index(s, "decrypt-env") > strings.Index(s, "ExecStart=") {
+		t.Errorf("Expected the environment to be decrypted before the container runs:\n%s", s)
+	}
+}
+
+func TestContainerUnitRunOverrides(t *testing.T) {
+	unit := ContainerUnit{
+		Id:         "test-cmd",
+		Image:      "test/image",
+		Entrypoint: "/bin/sh",
+		Cmd:        []string{"-c", `echo "a b" \ $HOME 100%`, ""},
+		WorkingDir: "/srv/my app",
+	}
+
+	for _, name := range []string{"SIMPLE", "FOREGROUND"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		if !strings.Contains(s, ` --entrypoint "/bin/sh" --workdir "/srv/my app" `) {
+			t.Errorf("Expected the %s unit to override the entrypoint and working directory:\n%s", name, s)
+		}
+		if !strings.Contains(s, `"test/image" "-c" "echo \"a b\" \\ $$HOME 100%%" ""`+"\n") {
+			t.Errorf("Expected the %s unit to quote each command argument:\n%s", name, s)
+		}
+	}
+
+	unit.Isolate = true
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if strings.Contains(buf.String(), `"-c"`) {
+		t.Errorf("Expected an isolated unit to run its init script:\n%s", buf.String())
+	}
+}
+
+func TestContainerUnitNetworkMode(t *testing.T) {
+	for mode, expected := range map[containers.NetworkMode]string{
+		"":                       "",
+		containers.NetworkBridge: "",
+		containers.NetworkHost:   `--net "host"`,
+		containers.NetworkNone:   `--net "none"`,
+		"container:test-db":      `--net "container:test-db"`,
+	} {
+		unit := ContainerUnit{Id: "test-net", Image: "test/image", NetworkMode: mode}
+		for _, name := range []string{"SIMPLE", "FOREGROUND"} {
+			buf := &bytes.Buffer{}
+			if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+				t.Fatalf("Unable to render %s unit: %v", name, err)
+			}
+			s := buf.String()
+			if expected == "" {
+				if strings.Contains(s, "--net") {
+					t.Errorf("Expected the %s unit to use the default network for %q:\n%s", name, mode, s)
+				}
+				continue
+			}
+			if !strings.Contains(s, " "+expected+" ") {
+				t.Errorf("Expected the %s unit to use %s:\n%s", name, expected, s)
+			}
+		}
+	}
+}
+
+func TestContainerUnitDNSAndHosts(t *testing.T) {
+	unit := ContainerUnit{
+		Id:         "test-dns",
+		Image:      "test/image",
+		DNS:        containers.DNSServers{"10.0.0.2", "fe80::1"},
+		ExtraHosts: containers.HostEntries{{"db", "10.0.0.5"}, {"cache.local", "fe80::2"}},
+	}
+	for _, name := range []string{"SIMPLE", "FOREGROUND"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		if !strings.Contains(s, ` --dns "10.0.0.2" --dns "fe80::1" --add-host "db:10.0.0.5" --add-host "cache.local:fe80::2" `) {
+			t.Errorf("Expected the %s unit to pass the DNS servers and host entries to docker:\n%s", name, s)
+		}
+	}
+
+	unit.DNS, unit.ExtraHosts = nil, nil
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); strings.Contains(s, "--dns") || strings.Contains(s, "--add-host") {
+		t.Errorf("Expected the unit to use the default resolver configuration:\n%s", s)
+	}
+}
+
+func TestContainerUnitLinks(t *testing.T) {
+	unit := ContainerUnit{
+		Id:    "test-web",
+		Image: "test/image",
+		Links: containers.ContainerAliases{{"test-db", "db"}, {"test-cache", "cache"}},
+	}
+	for _, name := range []string{"SIMPLE", "FOREGROUND"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		for _, expected := range []string{
+			` --link "test-db:db" --link "test-cache:cache" `,
+			"\nWants=ctr-test-db.service\nAfter=ctr-test-db.service\n",
+			"\nWants=ctr-test-cache.service\nAfter=ctr-test-cache.service\n",
+			"\nX-ContainerLink=test-db:db\nX-ContainerLink=test-cache:cache\n",
+		} {
+			if !strings.Contains(s, expected) {
+				t.Errorf("Expected the %s unit to contain %q:\n%s", name, expected, s)
+			}
+		}
+	}
+
+	unit.Links = nil
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); strings.Contains(s, "--link") || strings.Contains(s, "Wants=") || strings.Contains(s, "X-ContainerLink") {
+		t.Errorf("Expected the unit to have no links:\n%s", s)
+	}
+}
+
+func TestContainerUnitInheritEnvironment(t *testing.T) {
+	unit := ContainerUnit{
+		Id:                 "test-child",
+		Image:              "test/image",
+		ExecutablePath:     "/usr/bin/gear",
+		EnvironmentPath:    "/var/run/geard/test-child/environment",
+		InheritEnvironment: "test-base",
+		OwnEnvironment:     "test-child",
+	}
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, `ExecStartPre=/usr/bin/gear decrypt-env --inherit="test-base" "test-child" "/var/run/geard/test-child/environment"`) {
+		t.Errorf("Expected the unit to resolve the inherited environment when it starts:\n%s", s)
+	}
+	if !strings.Contains(s, "EnvironmentFile=-/var/run/geard/test-child/environment\n") || !strings.Contains(s, "\nX-ContainerInheritEnv=test-base\n") {
+		t.Errorf("Expected the unit to read the resolved environment:\n%s", s)
+	}
+}
+
+func TestContainerUnitLogDriver(t *testing.T) {
+	unit := ContainerUnit{
+		Id:         "test-logs",
+		Image:      "test/image",
+		LogDriver:  "json-file",
+		LogOptions: containers.LogOptions{{"max-size", "10m"}, {"max-file", "3"}},
+	}
+	for _, name := range []string{"SIMPLE", "FOREGROUND", "SOCKETACTIVATED"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		if !strings.Contains(s, ` --log-driver "json-file" --log-opt "max-size=10m" --log-opt "max-file=3" `) {
+			t.Errorf("Expected the %s unit to pass the log driver and options to docker:\n%s", name, s)
+		}
+		if !strings.Contains(s, "\nX-ContainerLogDriver=json-file\n") {
+			t.Errorf("Expected the %s unit to record the log driver:\n%s", name, s)
+		}
+	}
+
+	unit.LogDriver, unit.LogOptions = "", nil
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); strings.Contains(s, "--log-") || strings.Contains(s, "X-ContainerLogDriver") {
+		t.Errorf("Expected the unit to use the default log driver:\n%s", s)
+	}
+}
+
+func TestContainerUnitUlimits(t *testing.T) {
+	unit := ContainerUnit{
+		Id:      "test-ulimits",
+		Image:   "test/image",
+		Ulimits: containers.Ulimits{{"nofile", 1024, 4096}, {"nproc", 512, 512}},
+	}
+	for _, name := range []string{"SIMPLE", "FOREGROUND", "SOCKETACTIVATED"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		if s := buf.String(); !strings.Contains(s, ` --ulimit "nofile=1024:4096" --ulimit "nproc=512:512" `) {
+			t.Errorf("Expected the %s unit to pass the ulimits to docker:\n%s", name, s)
+		}
+	}
+
+	unit.Ulimits = nil
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); strings.Contains(s, "--ulimit") {
+		t.Errorf("Expected the unit to keep the default limits:\n%s", s)
+	}
+}
+
+func TestContainerUnitCPUSetRange(t *testing.T) {
+	unit := ContainerUnit{
+		Id:         "test-cpuset",
+		Image:      "test/image",
+		CPUSetCPUs: "0-3",
+		CPUSetMems: "0-1",
+	}
+	for _, name := range []string{"SIMPLE", "FOREGROUND", "SOCKETACTIVATED"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		if !strings.Contains(s, ` --cpuset-cpus "0-3" --cpuset-mems "0-1" `) {
+			t.Errorf("Expected the %s unit to pin the container to the cpusets:\n%s", name, s)
+		}
+		if !strings.Contains(s, "\nX-ContainerCPUSetCPUs=0-3\nX-ContainerCPUSetMems=0-1\n") {
+			t.Errorf("Expected the %s unit to record the cpusets:\n%s", name, s)
+		}
+	}
+}
+
+func TestContainerUnitCPUSetList(t *testing.T) {
+	unit := ContainerUnit{
+		Id:         "test-cpuset",
+		Image:      "test/image",
+		Ulimits:    containers.Ulimits{{"nofile", 1024, 4096}},
+		CPUSetCPUs: "1,3,5",
+	}
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, ` --ulimit "nofile=1024:4096" --cpuset-cpus "1,3,5" `) {
+		t.Errorf("Expected the unit to pin the container to the listed CPUs:\n%s", s)
+	}
+	if strings.Contains(s, "--cpuset-mems") || strings.Contains(s, "X-ContainerCPUSetMems") {
+		t.Errorf("Expected the unit to allocate memory from any node:\n%s", s)
+	}
+
+	unit.CPUSetCPUs = ""
+	buf.Reset()
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); strings.Contains(s, "--cpuset") || strings.Contains(s, "X-ContainerCPUSet") {
+		t.Errorf("Expected the unit to run on any CPU:\n%s", s)
+	}
+}
+
+func TestContainerUnitDescription(t *testing.T) {
+	unit := ContainerUnit{
+		Id:            "test-desc",
+		Image:         "test/image",
+		Description:   "Billing API (100% uptime)",
+		Documentation: containers.UnitDocumentation{"https://example.com/runbook", "man:billing(8)"},
+	}
+	for _, name := range []string{"SIMPLE", "FOREGROUND", "SOCKETACTIVATED", "CONTAINERD"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		if !strings.Contains(s, "[Unit]\nDescription=Billing API (100%% uptime)\nDocumentation=https://example.com/runbook man:billing(8)\n") {
+			t.Errorf("Expected the %s unit to have the description and documentation:\n%s", name, s)
+		}
+	}
+
+	unit.Description, unit.Documentation = "", nil
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); !strings.Contains(s, "\nDescription=Container test-desc\n") || strings.Contains(s, "Documentation=") {
+		t.Errorf("Expected the unit to have the default description and no documentation:\n%s", s)
+	}
+}
+
+func TestContainerUnitPullAtStart(t *testing.T) {
+	pull := `ExecStartPre=/bin/sh -c '/usr/bin/docker inspect --format="Using image {{.Id}}" "test/image" || exec /usr/bin/docker pull "test/image"'`
+	unit := ContainerUnit{Id: "test-pull", Image: "test/image", ExecutablePath: "/usr/bin/gear", PullAtStart: true}
+	for _, name := range []string{"SIMPLE", "FOREGROUND", "SOCKETACTIVATED"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		i := strings.Index(s, pull)
+		if i == -1 {
+			t.Errorf("Expected the %s unit to pull the image before starting:\n%s", name, s)
+			continue
+		}
+		// the image must be present before anything else uses it
+		if j := strings.Index(s, "ExecStartPre="); j < i {
+			t.Errorf("Expected the %s unit to pull the image first:\n%s", name, s)
+		}
+	}
+
+	unit.PullAtStart = false
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); strings.Contains(s, "docker pull") {
+		t.Errorf("Expected a unit installed with the image not to pull it:\n%s", s)
+	}
+}
+
+func TestContainerUnitDockerArgs(t *testing.T) {
+	unit := ContainerUnit{Id: "test-args", Image: "test/image", ExecutablePath: "/usr/bin/gear", DockerArgs: containers.DockerArgs{"--cap-add=SYS_PTRACE", "--shm-size=1g"}}
+	for _, name := range []string{"SIMPLE", "FOREGROUND", "SOCKETACTIVATED"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		start := strings.Index(s, "ExecStart=")
+		args := strings.Index(s, " --cap-add=SYS_PTRACE --shm-size=1g ")
+		image := strings.LastIndex(s, `"test/image"`)
+		if start == -1 || args < start || args > image {
+			t.Errorf("Expected the %s unit to pass the arguments to docker run before the image:\n%s", name, s)
+		}
+	}
+}
+
+func TestContainerUnitEnvReload(t *testing.T) {
+	unit := ContainerUnit{
+		Id:                    "test-reload",
+		Image:                 "test/image",
+		ExecutablePath:        "/usr/bin/gear",
+		EnvReloadSignal:       "SIGHUP",
+		ReloadEnvironmentPath: "/var/run/geard/test-reload/env/environment",
+	}
+	for _, name := range []string{"SIMPLE", "FOREGROUND"} {
+		buf := &bytes.Buffer{}
+		if err := ContainerUnitTemplate.ExecuteTemplate(buf, name, unit); err != nil {
+			t.Fatalf("Unable to render %s unit: %v", name, err)
+		}
+		s := buf.String()
+		if !strings.Contains(s, "\nX-EnvReloadSignal=SIGHUP\n") {
+			t.Errorf("Expected the %s unit to record the reload signal:\n%s", name, s)
+		}
+		if !strings.Contains(s, `ExecStartPre=-/usr/bin/gear decrypt-env "test-reload" "/var/run/geard/test-reload/env/environment"`) {
+			t.Errorf("Expected the %s unit to copy the environment before starting:\n%s", name, s)
+		}
+		if !strings.Contains(s, ` -v "/var/run/geard/test-reload/env:/.container.env:ro" `) {
+			t.Errorf("Expected the %s unit to mount the environment copy:\n%s", name, s)
+		}
+	}
+
+	unit.EnvReloadSignal = ""
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	if s := buf.String(); strings.Contains(s, "X-EnvReloadSignal") || strings.Contains(s, "/.container.env") {
+		t.Errorf("Expected a unit without a reload signal not to mount the environment:\n%s", s)
+	}
+}
+
+func TestContainerUnitProperties(t *testing.T) {
+	unit := ContainerUnit{
+		Id:    "test-props",
+		Image: "test/image",
+		Properties: containers.UnitProperties{
+			{"Service", "MemoryLimit", "1G"},
+			{"Unit", "After", "network-online.target"},
+			{"Socket", "Backlog", "64"},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "SIMPLE", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	s := buf.String()
+	unitSection, serviceSection, installSection := strings.Index(s, "[Unit]"), strings.Index(s, "[Service]"), strings.Index(s, "[Install]")
+	if i := strings.Index(s, "\nAfter=network-online.target\n"); i < unitSection || i > serviceSection {
+		t.Errorf("Expected the directive in the [Unit] section:\n%s", s)
+	}
+	if i := strings.Index(s, "\nMemoryLimit=1G\n"); i < serviceSection || i > installSection {
+		t.Errorf("Expected the directive in the [Service] section:\n%s", s)
+	}
+	if i := strings.Index(s, "[Socket]\nBacklog=64"); i < installSection {
+		t.Errorf("Expected other sections after the unit template:\n%s", s)
+	}
+}
+
+func TestContainerUnitContainerd(t *testing.T) {
+	unit := ContainerUnit{
+		Id:                "test-ctrd",
+		Image:             "test/image",
+		EnvironmentPath:   "/var/lib/containers/env/test-ctrd",
+		Cmd:               []string{"serve", "--port=8080"},
+		WorkingDir:        "/srv",
+		ContainerdCommand: "/usr/bin/ctr",
+		ContainerdAddress: "/run/containerd/containerd.sock",
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ContainerUnitTemplate.ExecuteTemplate(buf, "CONTAINERD", unit); err != nil {
+		t.Fatalf("Unable to render unit: %v", err)
+	}
+	s := buf.String()
+	ctr := `/usr/bin/ctr --address "/run/containerd/containerd.sock" --namespace "geard"`
+	if !strings.Contains(s, "ExecStart="+ctr+" run --rm ") {
+		t.Errorf("Expected the container to be run by containerd:\n%s", s)
+	}
+	if !strings.Contains(s, ` --net-host --cwd "/srv" `) || !strings.Contains(s, `--env-file "/var/lib/containers/env/test-ctrd"`) {
+		t.Errorf("Expected the network, working directory, and environment to be passed:\n%s", s)
+	}
+	if !strings.Contains(s, `"docker.io/test/image:latest" "test-ctrd" "serve" "--port=8080"`+"\n") {
+		t.Errorf("Expected the qualified image, container id, and command:\n%s", s)
+	}
+	if strings.Contains(s, "/usr/bin/docker") {
+		t.Errorf("Expected the unit not to use docker:\n%s", s)
+	}
+}
@@ -0,0 +1,78 @@
+package containers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// When a container was installed and when it was last started by geard,
+// stored with the container so that they survive restarts of the daemon.
+// A zero time is not known.
+type ContainerTimes struct {
+	Installed time.Time
+	Started   time.Time
+}
+
+// The recorded times of a container, zero if none have been recorded.
+func GetContainerTimes(id Identifier) (ContainerTimes, error) {
+	times := ContainerTimes{}
+	data, err := ioutil.ReadFile(id.TimesPathFor())
+	if os.IsNotExist(err) {
+		return times, nil
+	}
+	if err != nil {
+		return times, err
+	}
+	err = json.Unmarshal(data, &times)
+	return times, err
+}
+
+// Record that the container was installed at t, unless it was already
+// installed.  Reinstalling a container does not change when it was
+// created.
+func RecordContainerInstalled(id Identifier, t time.Time) error {
+	return updateContainerTimes(id, func(times *ContainerTimes) {
+		if times.Installed.IsZero() {
+			times.Installed = t
+		}
+	})
+}
+
+// Record that the container was started at t.
+func RecordContainerStarted(id Identifier, t time.Time) error {
+	return updateContainerTimes(id, func(times *ContainerTimes) {
+		times.Started = t
+	})
+}
+
+func updateContainerTimes(id Identifier, update func(*ContainerTimes)) error {
+	times, err := GetContainerTimes(id)
+	if err != nil {
+		return err
+	}
+	update(&times)
+	data, err := json.Marshal(&times)
+	if err != nil {
+		return err
+	}
+	path := id.TimesPathFor()
+	if err := ioutil.WriteFile(path+".tmp", data, 0660); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// How long the container has been running at now, given it was started
+// at started.  The wall clock may have been set back since the container
+// started, in which case the uptime is zero rather than negative.
+func Uptime(started, now time.Time) time.Duration {
+	if started.IsZero() {
+		return 0
+	}
+	if d := now.Sub(started); d > 0 {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package containers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A resource limit on the processes of a container, such as the number of
+// files they may open, passed to Docker as --ulimit.
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+type Ulimits []Ulimit
+
+// The limits Docker accepts, named as in ulimit(1) and setrlimit(2).
+var ulimitNames = map[string]bool{
+	"core":       true,
+	"cpu":        true,
+	"data":       true,
+	"fsize":      true,
+	"locks":      true,
+	"memlock":    true,
+	"msgqueue":   true,
+	"nice":       true,
+	"nofile":     true,
+	"nproc":      true,
+	"rss":        true,
+	"rtprio":     true,
+	"rttime":     true,
+	"sigpending": true,
+	"stack":      true,
+}
+
+// Parse a limit of the form <name>=<soft>[:<hard>].  The hard limit is the
+// same as the soft limit if it is omitted.
+func NewUlimitFromString(s string) (Ulimit, error) {
+	pair := strings.SplitN(s, "=", 2)
+	if len(pair) != 2 {
+		return Ulimit{}, fmt.Errorf("The ulimit '%s' must be of the form <name>=<soft>[:<hard>]", s)
+	}
+	values := strings.SplitN(pair[1], ":", 2)
+	soft, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return Ulimit{}, fmt.Errorf("The soft limit of the ulimit '%s' must be a number", s)
+	}
+	hard := soft
+	if len(values) == 2 {
+		if hard, err = strconv.ParseInt(values[1], 10, 64); err != nil {
+			return Ulimit{}, fmt.Errorf("The hard limit of the ulimit '%s' must be a number", s)
+		}
+	}
+	u := Ulimit{pair[0], soft, hard}
+	if err := u.Check(); err != nil {
+		return Ulimit{}, err
+	}
+	return u, nil
+}
+
+func (u Ulimit) Check() error {
+	if !ulimitNames[u.Name] {
+		names := make([]string, 0, len(ulimitNames))
+		for name := range ulimitNames {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("The ulimit '%s' must be one of %s", u.Name, strings.Join(names, ", "))
+	}
+	if u.Soft < 0 || u.Hard < 0 {
+		return fmt.Errorf("The limits of the ulimit '%s' may not be negative", u.Name)
+	}
+	if u.Soft > u.Hard {
+		return fmt.Errorf("The soft limit of the ulimit '%s' (%d) may not be greater than its hard limit (%d)", u.Name, u.Soft, u.Hard)
+	}
+	return nil
+}
+
+func (u Ulimit) String() string {
+	return fmt.Sprintf("%s=%d:%d", u.Name, u.Soft, u.Hard)
+}
+
+func (u Ulimits) Check() error {
+	seen := make(map[string]bool)
+	for i := range u {
+		if err := u[i].Check(); err != nil {
+			return err
+		}
+		if seen[u[i].Name] {
+			return fmt.Errorf("The ulimit '%s' may only be set once", u[i].Name)
+		}
+		seen[u[i].Name] = true
+	}
+	return nil
+}
+
+func (u Ulimits) String() string {
+	limits := make([]string, len(u))
+	for i := range u {
+		limits[i] = u[i].String()
+	}
+	return strings.Join(limits, " ")
+}
@@ -0,0 +1,46 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestUlimit(t *testing.T) {
+	for value, expected := range map[string]Ulimit{
+		"nofile=1024:4096": {"nofile", 1024, 4096},
+		"nproc=512":        {"nproc", 512, 512},
+		"core=0:0":         {"core", 0, 0},
+	} {
+		u, err := NewUlimitFromString(value)
+		if err != nil {
+			t.Errorf("Unable to parse ulimit %q: %v", value, err)
+			continue
+		}
+		if u != expected {
+			t.Errorf("Expected ulimit %q to be %+v, got %+v", value, expected, u)
+		}
+	}
+	if s := (Ulimit{"nproc", 512, 512}).String(); s != "nproc=512:512" {
+		t.Errorf("Expected the soft and hard limits to be written, got %s", s)
+	}
+}
+
+func TestUlimitRejected(t *testing.T) {
+	for _, value := range []string{
+		"files=1024",         // unknown
+		"NOFILE=1024",        // names are lower case
+		"nofile",             // no limits
+		"nofile=",            // no soft limit
+		"nofile=a:10",        // not a number
+		"nofile=10:b",        // not a number
+		"nofile=4096:1024",   // soft above hard
+		"nofile=-1",          // negative
+		"nofile=1024:2048:1", // too many limits
+	} {
+		if _, err := NewUlimitFromString(value); err == nil {
+			t.Errorf("Expected ulimit %q to be rejected", value)
+		}
+	}
+	if err := (Ulimits{{"nofile", 1, 2}, {"nofile", 3, 4}}).Check(); err == nil {
+		t.Error("Expected a ulimit set twice to be rejected")
+	}
+}
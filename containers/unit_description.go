@@ -0,0 +1,58 @@
+package containers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// The description systemd shows for the unit of a container, such as in the
+// first line of 'systemctl status'.
+type UnitDescription string
+
+// The longest description accepted, systemd truncates long descriptions
+// when it shows them
+const maxUnitDescriptionLength = 256
+
+func (d UnitDescription) Check() error {
+	if len(d) > maxUnitDescriptionLength {
+		return fmt.Errorf("The description may not be longer than %d characters", maxUnitDescriptionLength)
+	}
+	if strings.IndexFunc(string(d), unicode.IsControl) != -1 || strings.HasSuffix(string(d), "\\") {
+		return fmt.Errorf("The description may not contain a newline or control character or end with a line continuation")
+	}
+	return nil
+}
+
+// The description as the value of a unit directive, with specifiers escaped
+// so that systemd shows it as given.
+func (d UnitDescription) UnitValue() string {
+	return escapeUnitSpecifiers(string(d))
+}
+
+// The URIs of the documentation of a container, shown by 'systemctl status'
+// and 'systemctl help'.
+type UnitDocumentation []string
+
+// The URI schemes systemd accepts for Documentation=
+var unitDocumentationSchemes = map[string]bool{"http": true, "https": true, "file": true, "info": true, "man": true}
+
+func (d UnitDocumentation) Check() error {
+	for _, uri := range d {
+		u, err := url.Parse(uri)
+		if err != nil || !unitDocumentationSchemes[u.Scheme] || strings.IndexFunc(uri, unicode.IsSpace) != -1 || strings.IndexFunc(uri, unicode.IsControl) != -1 {
+			return fmt.Errorf("The documentation '%s' must be a URI without spaces using one of the schemes http, https, file, info or man", uri)
+		}
+	}
+	return nil
+}
+
+// The URIs as the value of a unit directive, with specifiers escaped.
+func (d UnitDocumentation) UnitValue() string {
+	return escapeUnitSpecifiers(strings.Join(d, " "))
+}
+
+func escapeUnitSpecifiers(s string) string {
+	return strings.Replace(s, "%", "%%", -1)
+}
@@ -0,0 +1,36 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestUnitDescription(t *testing.T) {
+	for _, value := range []UnitDescription{"", "Billing API", "Uses 100% of a core"} {
+		if err := value.Check(); err != nil {
+			t.Errorf("Expected description %q to be accepted: %v", value, err)
+		}
+	}
+	for _, value := range []UnitDescription{"two\nlines", "tab\there", "continued\\", UnitDescription(make([]byte, 257))} {
+		if err := value.Check(); err == nil {
+			t.Errorf("Expected description %q to be rejected", value)
+		}
+	}
+	if s := UnitDescription("100% done").UnitValue(); s != "100%% done" {
+		t.Errorf("Expected specifiers to be escaped, got %q", s)
+	}
+}
+
+func TestUnitDocumentation(t *testing.T) {
+	valid := UnitDocumentation{"https://example.com/docs", "http://example.com", "man:gear(1)", "info:gear", "file:/usr/share/doc/app/README"}
+	if err := valid.Check(); err != nil {
+		t.Errorf("Expected documentation %v to be accepted: %v", valid, err)
+	}
+	for _, value := range []string{"example.com/docs", "ftp://example.com/docs", "https://example.com/two words", "", "javascript:alert(1)"} {
+		if err := (UnitDocumentation{value}).Check(); err == nil {
+			t.Errorf("Expected documentation %q to be rejected", value)
+		}
+	}
+	if s := (UnitDocumentation{"https://example.com/a%20b", "man:gear(1)"}).UnitValue(); s != "https://example.com/a%%20b man:gear(1)" {
+		t.Errorf("Expected the URIs to be joined with specifiers escaped, got %q", s)
+	}
+}
@@ -0,0 +1,74 @@
+package containers
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The value of the first line of the unit file of a container that
+// starts with '<key>=', or an empty string if there is none.
+func readUnitValue(id Identifier, key string) (string, error) {
+	file, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	prefix := key + "="
+	scan := bufio.NewScanner(file)
+	for scan.Scan() {
+		if line := scan.Text(); strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix), nil
+		}
+	}
+	return "", scan.Err()
+}
+
+// The values of every line of the unit file of a container that starts
+// with '<key>=', in order.
+func readUnitValues(id Identifier, key string) ([]string, error) {
+	file, err := os.Open(id.UnitPathFor())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	prefix := key + "="
+	values := []string{}
+	scan := bufio.NewScanner(file)
+	for scan.Scan() {
+		if line := scan.Text(); strings.HasPrefix(line, prefix) {
+			values = append(values, strings.TrimPrefix(line, prefix))
+		}
+	}
+	return values, scan.Err()
+}
+
+// The image the container was installed from.
+func GetContainerImage(id Identifier) (string, error) {
+	return readUnitValue(id, "X-ContainerImage")
+}
+
+// The seconds the container was installed to be given to exit before it
+// is killed.  Units installed before the timeout was recorded have none,
+// and an error is returned.
+func GetContainerStopTimeout(id Identifier) (int, error) {
+	value, err := readUnitValue(id, "X-ContainerStopTimeout")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+// The id of the request that last installed the container.
+func GetContainerRequestId(id Identifier) (string, error) {
+	return readUnitValue(id, "X-ContainerRequestId")
+}
+
+// Whether the container runs as its own user, "isolated", or as root,
+// "simple".
+func GetContainerType(id Identifier) (string, error) {
+	return readUnitValue(id, "X-ContainerType")
+}
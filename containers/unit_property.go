@@ -0,0 +1,164 @@
+package containers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A directive added to the generated unit of a container, for settings
+// that have no dedicated option.
+type UnitProperty struct {
+	Section string
+	Key     string
+	Value   string
+}
+
+type UnitProperties []UnitProperty
+
+// The sections of a container unit that directives may be added to.  The
+// daemon may allow others.
+var AllowedUnitSections = map[string]bool{"Unit": true, "Service": true}
+
+// Directives written by geard that may not be set or added to
+var managedUnitDirectives = map[string]map[string]bool{
+	"Unit": {"Description": true, "BindsTo": true},
+	"Service": {
+		"Type": true, "TimeoutStartSec": true, "TimeoutStopSec": true, "Slice": true, "EnvironmentFile": true,
+		"ExecStartPre": true, "ExecStart": true, "ExecStartPost": true, "ExecReload": true, "ExecStop": true,
+	},
+	"Install": {"WantedBy": true},
+}
+
+// Directives that may be added to the [Service] section.  The service runs
+// as root, and other directives could run a command or change its user or
+// root directory, so only resource limits, restart behavior and the
+// environment may be set.
+var allowedServiceDirectives = map[string]bool{
+	"Environment":       true,
+	"CPUAccounting":     true,
+	"CPUShares":         true,
+	"CPUWeight":         true,
+	"CPUQuota":          true,
+	"MemoryAccounting":  true,
+	"MemoryLimit":       true,
+	"MemoryLow":         true,
+	"MemoryHigh":        true,
+	"MemoryMax":         true,
+	"MemorySwapMax":     true,
+	"BlockIOWeight":     true,
+	"IOWeight":          true,
+	"TasksMax":          true,
+	"Nice":              true,
+	"OOMScoreAdjust":    true,
+	"SuccessExitStatus": true,
+}
+
+// Prefixes of the other directives that may be added to [Service], the
+// Limit* resource limits and the Restart* and StartLimit* restart behavior.
+var allowedServiceDirectivePrefixes = []string{"Limit", "Restart", "StartLimit"}
+
+func allowedServiceDirective(key string) bool {
+	if allowedServiceDirectives[key] {
+		return true
+	}
+	for _, prefix := range allowedServiceDirectivePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	allowedUnitSection = regexp.MustCompile("\\A[A-Za-z][A-Za-z0-9\\-]*\\z")
+	allowedUnitKey     = regexp.MustCompile("\\A[A-Za-z][A-Za-z0-9\\-]*\\z")
+)
+
+// Parse a property of the form <section>.<key>=<value>
+func NewUnitPropertyFromString(s string) (UnitProperty, error) {
+	pair := strings.SplitN(s, "=", 2)
+	name := strings.SplitN(pair[0], ".", 2)
+	if len(pair) != 2 || len(name) != 2 {
+		return UnitProperty{}, fmt.Errorf("The unit property '%s' must be of the form <section>.<key>=<value>", s)
+	}
+	p := UnitProperty{strings.TrimSpace(name[0]), strings.TrimSpace(name[1]), strings.TrimSpace(pair[1])}
+	if err := p.Check(); err != nil {
+		return UnitProperty{}, err
+	}
+	return p, nil
+}
+
+func (p UnitProperty) Check() error {
+	if !allowedUnitSection.MatchString(p.Section) {
+		return fmt.Errorf("The unit section '%s' must match %s", p.Section, allowedUnitSection.String())
+	}
+	if !allowedUnitKey.MatchString(p.Key) {
+		return fmt.Errorf("The unit key '%s' must match %s", p.Key, allowedUnitKey.String())
+	}
+	if strings.ContainsAny(p.Value, "\r\n") || strings.HasSuffix(p.Value, "\\") {
+		return fmt.Errorf("The value of %s may not contain a newline or end with a line continuation", p.Name())
+	}
+	if managedUnitDirectives[p.Section][p.Key] || (p.Section == "Install" && strings.HasPrefix(p.Key, "X-")) {
+		return fmt.Errorf("%s is set by geard and can't be changed", p.Name())
+	}
+	if p.Section == "Service" && !allowedServiceDirective(p.Key) {
+		return fmt.Errorf("%s can't be added, only resource limits and the Restart*, StartLimit* and Environment directives are allowed in the [Service] section", p.Name())
+	}
+	return nil
+}
+
+// The directive as <section>.<key>
+func (p UnitProperty) Name() string {
+	return p.Section + "." + p.Key
+}
+
+func (p UnitProperty) String() string {
+	return p.Name() + "=" + p.Value
+}
+
+func (p UnitProperties) Check() error {
+	for i := range p {
+		if err := p[i].Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return an error if a directive is in a section that is not allowed.
+func (p UnitProperties) CheckSections() error {
+	for i := range p {
+		if !AllowedUnitSections[p[i].Section] {
+			return fmt.Errorf("Directives can't be added to the [%s] section of a unit", p[i].Section)
+		}
+	}
+	return nil
+}
+
+// The directives in a section as unit file lines, in order.
+func (p UnitProperties) Directives(section string) string {
+	lines := []string{}
+	for i := range p {
+		if p[i].Section == section {
+			lines = append(lines, p[i].Key+"="+p[i].Value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// The sections, in the order they first appear, other than those given.
+func (p UnitProperties) SectionsExcept(except ...string) []string {
+	seen := make(map[string]bool)
+	for _, section := range except {
+		seen[section] = true
+	}
+	sections := []string{}
+	for i := range p {
+		if !seen[p[i].Section] {
+			seen[p[i].Section] = true
+			sections = append(sections, p[i].Section)
+		}
+	}
+	return sections
+}
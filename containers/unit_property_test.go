@@ -0,0 +1,69 @@
+package containers
+
+import (
+	"testing"
+)
+
+func TestNewUnitPropertyFromString(t *testing.T) {
+	for value, expected := range map[string]UnitProperty{
+		"Service.MemoryLimit=1G":         {"Service", "MemoryLimit", "1G"},
+		"Unit.After = network.target":    {"Unit", "After", "network.target"},
+		"Service.Environment=A=b c":      {"Service", "Environment", "A=b c"},
+		"Service.LimitNOFILE=":           {"Service", "LimitNOFILE", ""},
+		"Service.RestartSec=5":           {"Service", "RestartSec", "5"},
+		"Service.StartLimitBurst=3":      {"Service", "StartLimitBurst", "3"},
+		"Install.Also=other.socket":      {"Install", "Also", "other.socket"},
+		"X-Custom.Setting=value.with.do": {"X-Custom", "Setting", "value.with.do"},
+	} {
+		p, err := NewUnitPropertyFromString(value)
+		if err != nil {
+			t.Errorf("Expected %q to be accepted: %v", value, err)
+			continue
+		}
+		if p != expected {
+			t.Errorf("Expected %q to be %+v, got %+v", value, expected, p)
+		}
+	}
+
+	for _, value := range []string{
+		"MemoryLimit=1G",
+		"Service.MemoryLimit",
+		"Service.=1G",
+		".MemoryLimit=1G",
+		"Service.Memory Limit=1G",
+		"Service.ExecStart=/bin/sh",
+		"Service.ExecStartPre=/bin/true",
+		"Service.ExecStopPost=/bin/sh -c 'id > /tmp/owned'",
+		"Service.ExecStartPost=/bin/true",
+		"Service.User=root",
+		"Service.RootDirectory=/",
+		"Service.PermissionsStartOnly=true",
+		"Service.EnvironmentFile=/etc/secrets",
+		"Unit.Description=Other",
+		"Install.WantedBy=multi-user.target",
+		"Install.X-ContainerId=other",
+		"Service.ExecStopPost=/bin/true\\",
+		"Service.Environment=A=1\nExecStart=/bin/sh",
+	} {
+		if _, err := NewUnitPropertyFromString(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestUnitPropertiesCheckSections(t *testing.T) {
+	allowed := UnitProperties{{"Service", "MemoryLimit", "1G"}, {"Unit", "After", "network.target"}}
+	if err := allowed.CheckSections(); err != nil {
+		t.Errorf("Expected the Unit and Service sections to be allowed: %v", err)
+	}
+
+	other := UnitProperties{{"Install", "Also", "other.socket"}}
+	if err := other.CheckSections(); err == nil {
+		t.Error("Expected the Install section to be rejected by default")
+	}
+	AllowedUnitSections["Install"] = true
+	defer delete(AllowedUnitSections, "Install")
+	if err := other.CheckSections(); err != nil {
+		t.Errorf("Expected the Install section to be allowed once the server allows it: %v", err)
+	}
+}
@@ -3,7 +3,7 @@
 base=$(dirname $0)
 
 gear stop --with=$base/deploy_parks_map_instances.json
-gear delete --with=$base/deploy_parks_map_instances.json
+gear delete --yes --with=$base/deploy_parks_map_instances.json
 
 docker rm parks-db-1-data
 
@@ -124,5 +124,5 @@ if [ $islocal -eq 0 ]; then
 fi
 
 echo
-echo 'You can delete the artifacts generated by this script by stopping the daemon, running "gear purge",'
+echo 'You can delete the artifacts generated by this script by stopping the daemon, running "gear purge --yes",'
 echo 'and then deleting /var/lib/containers/*'
@@ -5,66 +5,210 @@
 package dispatcher
 
 import (
+	"context"
 	"errors"
 	"log"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/openshift/geard/jobs"
 )
 
+var ErrNoSuchJob = jobs.SimpleError{jobs.ResponseNotFound, "No job with that id is known, it may have expired."}
+
 type Dispatcher struct {
 	QueueFast         int
 	QueueSlow         int
 	Concurrent        int
 	TrackDuplicateIds int
 
-	fastJobs   chan jobTracker
-	slowJobs   chan jobTracker
+	// If set, called with each job that fails.  Called from the worker
+	// that ran the job, so it should not block.
+	OnFailure func(FailedJob)
+	// If set, the lifecycle events of each job are published to it
+	Events *Events
+	// If set, each job is recorded in it
+	History *History
+	// How long a queued job waits before it is treated as one priority
+	// higher, DefaultPriorityAging if zero
+	PriorityAging time.Duration
+
+	timeouts   map[string]time.Duration
+	priorities map[string]Priority
+	fastJobs   *jobQueue
+	slowJobs   *jobQueue
 	recentJobs *RequestIdentifierMap
 }
 
+// The state of a job when it was cancelled
+type JobState string
+
+const (
+	JobQueued   JobState = "queued"
+	JobRunning  JobState = "running"
+	JobFinished JobState = "finished"
+	// The job is running and does not implement jobs.ContextJob, so it
+	// can't be stopped and will run to completion.
+	JobUncancellable JobState = "uncancellable"
+)
+
 type Fast interface {
 	Fast() bool
 }
 
 func (d *Dispatcher) Start() {
 	d.recentJobs = NewRequestIdentifierMap(d.TrackDuplicateIds)
-	d.fastJobs = make(chan jobTracker, d.QueueFast)
-	d.slowJobs = make(chan jobTracker, d.QueueSlow)
+	d.fastJobs = newJobQueue(d.QueueFast, d.PriorityAging)
+	d.slowJobs = newJobQueue(d.QueueSlow, d.PriorityAging)
 	for i := 0; i < d.Concurrent; i++ {
 		d.work(d.fastJobs)
 		d.work(d.slowJobs)
 	}
 }
 
-func (d *Dispatcher) work(queue <-chan jobTracker) {
+func (d *Dispatcher) work(queue *jobQueue) {
 	go func() {
-		for tracker := range queue {
+		for {
+			tracker := queue.pop()
 			id := tracker.id
-			log.Printf("job START %s, %s: %+v", reflect.TypeOf(tracker.job).String(), id.String(), tracker.job)
-			tracker.job.Execute(tracker.response)
-			log.Printf("job END   %s", id.String())
+			if tracker.start() {
+				log.Printf("job START %s, %s: %+v", reflect.TypeOf(tracker.job).String(), id.String(), tracker.job)
+				d.publish(EventStarted, tracker.id, tracker.job, nil)
+				d.execute(tracker)
+				log.Printf("job END   %s", id.String())
+			} else {
+				log.Printf("job CANCELLED %s", id.String())
+				tracker.response.Failure(jobs.ErrJobCanceled)
+			}
+			if err := tracker.failure(); err != nil {
+				d.publish(EventFailed, tracker.id, tracker.job, err)
+			} else {
+				d.publish(EventCompleted, tracker.id, tracker.job, nil)
+			}
+			tracker.cancel()
 			close(tracker.complete)
 			d.recentJobs.Put(id, nil)
 		}
 	}()
 }
 
+// Publish an event in the lifecycle of a job, and record it in the
+// history.
+func (d *Dispatcher) publish(t EventType, id jobs.RequestIdentifier, j jobs.Job, err error) {
+	d.Events.publish(t, id, j, err)
+	d.History.record(t, id, j, err)
+}
+
+// Run the job, cancelling its context if it runs longer than the timeout
+// for its type.
+func (d *Dispatcher) execute(t *jobTracker) {
+	ctx, resp := t.ctx, t.response
+	timeout := d.timeoutFor(t.job)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		resp = newTimeoutResponse(ctx, resp)
+	}
+	if j, ok := t.job.(jobs.ContextJob); ok {
+		j.ExecuteContext(ctx, resp)
+	} else {
+		t.job.Execute(resp)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("job TIMEOUT %s after %s", t.id.String(), timeout)
+	}
+}
+
 type jobTracker struct {
 	id       jobs.RequestIdentifier
 	job      jobs.Job
 	response jobs.Response
 	complete chan bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock     sync.Mutex
+	running  bool
+	canceled bool
+	err      error
+}
+
+// Mark the job as running unless it was cancelled while queued.
+func (t *jobTracker) start() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.canceled {
+		return false
+	}
+	t.running = true
+	return true
+}
+
+func (t *jobTracker) failed(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.err = err
+}
+
+// The first failure the job reported, if any.
+func (t *jobTracker) failure() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.err
+}
+
+func (t *jobTracker) stop() JobState {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.job.(jobs.ContextJob); t.running && !ok {
+		return JobUncancellable
+	}
+	t.canceled = true
+	t.cancel()
+	if t.running {
+		return JobRunning
+	}
+	return JobQueued
+}
+
+// Cancel the job with id.  A queued job is skipped when it reaches the
+// front of the queue and a running job is signalled through its context,
+// which only jobs implementing jobs.ContextJob observe.  Returns the state
+// the job was in, JobUncancellable if it is running and can't observe the
+// cancellation, or ErrNoSuchJob if the job is not known.
+func (d *Dispatcher) Cancel(id jobs.RequestIdentifier) (JobState, error) {
+	existing, found := d.recentJobs.Lookup(id)
+	if !found {
+		return "", ErrNoSuchJob
+	}
+	tracker, ok := existing.(*jobTracker)
+	if !ok {
+		return JobFinished, nil
+	}
+	return tracker.stop(), nil
 }
 
 func (d *Dispatcher) Dispatch(id jobs.RequestIdentifier, j jobs.Job, resp jobs.Response) (done <-chan bool, err error) {
+	return d.DispatchPriority(id, j, resp, d.priorityFor(j))
+}
+
+// Dispatch the job ahead of or behind the other queued jobs according to
+// priority, rather than the priority set for its type.
+func (d *Dispatcher) DispatchPriority(id jobs.RequestIdentifier, j jobs.Job, resp jobs.Response, priority Priority) (done <-chan bool, err error) {
 	complete := make(chan bool)
-	tracker := jobTracker{id, j, resp, complete}
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := &jobTracker{id: id, job: j, response: resp, complete: complete, ctx: ctx, cancel: cancel}
+	if d.OnFailure != nil || d.Events != nil || d.History != nil {
+		tracker.response = d.observeFailure(tracker)
+	}
 
 	if existing, found := d.recentJobs.Put(id, tracker); found {
 		var join jobs.Join
 		if existing != nil {
-			other, _ := existing.(jobTracker)
+			other, _ := existing.(*jobTracker)
 			j, ok := other.job.(jobs.Join)
 			if !ok {
 				err = jobs.ErrRanToCompletion
@@ -94,7 +238,7 @@ func (d *Dispatcher) Dispatch(id jobs.RequestIdentifier, j jobs.Job, resp jobs.R
 		log.Println("Queueing an already existing job ", j)
 	}
 
-	var queue chan jobTracker
+	var queue *jobQueue
 	fast := false
 	if f, ok := j.(Fast); ok {
 		fast = f.Fast()
@@ -105,12 +249,16 @@ func (d *Dispatcher) Dispatch(id jobs.RequestIdentifier, j jobs.Job, resp jobs.R
 		queue = d.slowJobs
 	}
 
-	select {
-	case queue <- tracker:
-	default:
+	// a worker can't start the job until it has been announced, so that
+	// it is never reported started before it is accepted
+	tracker.lock.Lock()
+	if !queue.push(tracker, priority, time.Now()) {
+		tracker.lock.Unlock()
 		err = errors.New("The server is at maximum capacity - please try again shortly")
 		return
 	}
+	d.publish(EventAccepted, id, j, nil)
+	tracker.lock.Unlock()
 
 	done = complete
 	return
@@ -0,0 +1,386 @@
+package dispatcher
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/jobs"
+)
+
+// A job that signals when it starts and runs until released or cancelled.
+type blockingJob struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingJob() *blockingJob {
+	return &blockingJob{make(chan struct{}), make(chan struct{})}
+}
+
+func (j *blockingJob) Execute(resp jobs.Response) {
+	j.ExecuteContext(context.Background(), resp)
+}
+
+func (j *blockingJob) ExecuteContext(ctx context.Context, resp jobs.Response) {
+	close(j.started)
+	select {
+	case <-j.release:
+		resp.Success(jobs.ResponseOk)
+	case <-ctx.Done():
+		resp.Failure(jobs.ErrJobCanceled)
+	}
+}
+
+func startDispatcher() *Dispatcher {
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10}
+	d.Start()
+	return d
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	d := startDispatcher()
+
+	// occupy the only worker so that the next job stays queued
+	running := newBlockingJob()
+	d.Dispatch(jobs.NewRequestIdentifier(), running, &cmd.CliJobResponse{})
+	<-running.started
+
+	queued := newBlockingJob()
+	id := jobs.NewRequestIdentifier()
+	resp := &cmd.CliJobResponse{}
+	done, err := d.Dispatch(id, queued, resp)
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+
+	state, err := d.Cancel(id)
+	if err != nil || state != JobQueued {
+		t.Fatalf("Expected a queued job to be cancelled, got %s %v", state, err)
+	}
+	close(running.release)
+	<-done
+
+	select {
+	case <-queued.started:
+		t.Error("Expected a cancelled job not to run")
+	default:
+	}
+	if resp.Error != jobs.ErrJobCanceled {
+		t.Errorf("Expected the job to fail as cancelled, got %v", resp.Error)
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	d := startDispatcher()
+
+	job := newBlockingJob()
+	id := jobs.NewRequestIdentifier()
+	resp := &cmd.CliJobResponse{}
+	done, err := d.Dispatch(id, job, resp)
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-job.started
+
+	state, err := d.Cancel(id)
+	if err != nil || state != JobRunning {
+		t.Fatalf("Expected a running job to be cancelled, got %s %v", state, err)
+	}
+	<-done
+	if resp.Error != jobs.ErrJobCanceled {
+		t.Errorf("Expected the job to observe the cancellation, got %v", resp.Error)
+	}
+}
+
+// A job that can't observe a cancellation once it runs
+type uncancellableJob struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (j uncancellableJob) Execute(resp jobs.Response) {
+	close(j.started)
+	<-j.release
+	resp.Success(jobs.ResponseOk)
+}
+
+func TestCancelUncancellableJob(t *testing.T) {
+	d := startDispatcher()
+
+	job := uncancellableJob{make(chan struct{}), make(chan struct{})}
+	id := jobs.NewRequestIdentifier()
+	resp := &cmd.CliJobResponse{}
+	done, err := d.Dispatch(id, job, resp)
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-job.started
+
+	state, err := d.Cancel(id)
+	if err != nil || state != JobUncancellable {
+		t.Fatalf("Expected a running job without a context to be reported as uncancellable, got %s %v", state, err)
+	}
+	close(job.release)
+	<-done
+	if resp.Error != nil {
+		t.Errorf("Expected the job to run to completion, got %v", resp.Error)
+	}
+}
+
+func TestCancelFinishedJob(t *testing.T) {
+	d := startDispatcher()
+
+	job := newBlockingJob()
+	close(job.release)
+	id := jobs.NewRequestIdentifier()
+	done, err := d.Dispatch(id, job, &cmd.CliJobResponse{})
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-done
+
+	// the worker forgets the job after signalling completion
+	for i := 0; i < 100; i++ {
+		if v, _ := d.recentJobs.Lookup(id); v == nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if state, err := d.Cancel(id); err != nil || state != JobFinished {
+		t.Errorf("Expected a finished job to be reported, got %s %v", state, err)
+	}
+	if _, err := d.Cancel(jobs.NewRequestIdentifier()); err != ErrNoSuchJob {
+		t.Errorf("Expected an unknown job to be reported, got %v", err)
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	failed := make(chan FailedJob, 1)
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, OnFailure: func(f FailedJob) { failed <- f }}
+	d.SetTimeout(&blockingJob{}, 20*time.Millisecond)
+	d.Start()
+
+	job := newBlockingJob()
+	resp := &cmd.CliJobResponse{}
+	done, err := d.Dispatch(jobs.NewRequestIdentifier(), job, resp)
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the job to be cancelled when its timeout passed")
+	}
+	if resp.Error != jobs.ErrJobTimedOut {
+		t.Errorf("Expected the job to fail as timed out, got %v", resp.Error)
+	}
+	if f := <-failed; f.Err != jobs.ErrJobTimedOut {
+		t.Errorf("Expected the timeout to be reported as a failure, got %v", f.Err)
+	}
+
+	if d.timeoutFor(&failingJob{}) != 0 {
+		t.Error("Expected other types of job not to be limited")
+	}
+}
+
+func TestCancelJobWithTimeout(t *testing.T) {
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10}
+	d.SetTimeout(&blockingJob{}, time.Minute)
+	d.Start()
+
+	// a job cancelled by a client before its timeout is not timed out
+	job := newBlockingJob()
+	id := jobs.NewRequestIdentifier()
+	resp := &cmd.CliJobResponse{}
+	done, err := d.Dispatch(id, job, resp)
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-job.started
+	d.Cancel(id)
+	<-done
+	if resp.Error != jobs.ErrJobCanceled {
+		t.Errorf("Expected the job to fail as cancelled, got %v", resp.Error)
+	}
+}
+
+type failingJob struct {
+	Id string
+}
+
+func (j *failingJob) Execute(resp jobs.Response) {
+	resp.Failure(jobs.NewNotFoundError("The container %s does not exist.", j.Id))
+}
+
+func TestFailureHook(t *testing.T) {
+	failed := make(chan FailedJob, 2)
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, OnFailure: func(f FailedJob) { failed <- f }}
+	d.Start()
+
+	id := jobs.NewRequestIdentifier()
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	done, err := d.Dispatch(id, &failingJob{"test-fail"}, resp)
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-done
+	if !jobs.IsNotFound(resp.Error) {
+		t.Errorf("Expected the failure to reach the response, got %v", resp.Error)
+	}
+	f := <-failed
+	if f.Id.String() != id.String() || f.Type() != "*dispatcher.failingJob" || f.ContainerId() != "test-fail" || f.Err != resp.Error {
+		t.Errorf("Expected the failed job to be reported, got %s %s %s %v", f.Id, f.Type(), f.ContainerId(), f.Err)
+	}
+
+	job := newBlockingJob()
+	close(job.release)
+	if done, err = d.Dispatch(jobs.NewRequestIdentifier(), job, &cmd.CliJobResponse{}); err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-done
+	select {
+	case f := <-failed:
+		t.Errorf("Expected a successful job not to be reported, got %+v", f)
+	default:
+	}
+}
+
+func TestFailureExec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-hook")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "output")
+	hook := filepath.Join(dir, "hook")
+	script := "#!/bin/sh\nprintf '%s|' \"$@\" \"$GEARD_JOB_ID\" \"$GEARD_JOB_TYPE\" \"$GEARD_CONTAINER_ID\" \"$GEARD_JOB_ERROR\" > " + output + ".tmp\nmv " + output + ".tmp " + output + "\n"
+	if err := ioutil.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatalf("Unable to write the hook: %v", err)
+	}
+
+	id := jobs.NewRequestIdentifier()
+	FailureExec(hook, 5*time.Second)(FailedJob{id, &failingJob{"test-fail"}, jobs.ErrNotFound})
+
+	var data []byte
+	for i := 0; i < 500; i++ {
+		if data, err = ioutil.ReadFile(output); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Expected the hook to run: %v", err)
+	}
+	values := []string{id.String(), "*dispatcher.failingJob", "test-fail", jobs.ErrNotFound.Error()}
+	expected := strings.Join(append(values, values...), "|") + "|"
+	if string(data) != expected {
+		t.Errorf("Expected the hook to be passed the job as arguments and environment:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+// Records the order jobs of this type run in
+type orderedJob struct {
+	name  string
+	order chan string
+}
+
+func (j *orderedJob) Execute(resp jobs.Response) {
+	j.order <- j.name
+	resp.Success(jobs.ResponseOk)
+}
+
+// Queue each job behind a running one, release it, and return the names
+// of the jobs in the order they ran.
+func runQueued(t *testing.T, d *Dispatcher, queue func(order chan string)) []string {
+	running := newBlockingJob()
+	d.Dispatch(jobs.NewRequestIdentifier(), running, &cmd.CliJobResponse{})
+	<-running.started
+
+	order := make(chan string, 10)
+	queue(order)
+	close(running.release)
+
+	names := []string{}
+	for len(names) < 2 {
+		select {
+		case name := <-order:
+			names = append(names, name)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the queued jobs to run, got %v", names)
+		}
+	}
+	return names
+}
+
+func TestHighPriorityJobRunsFirst(t *testing.T) {
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 3, Concurrent: 1, TrackDuplicateIds: 10}
+	d.SetPriority(&orderedJob{}, PriorityLow)
+	d.Start()
+
+	names := runQueued(t, d, func(order chan string) {
+		if _, err := d.Dispatch(jobs.NewRequestIdentifier(), &orderedJob{"install", order}, &cmd.CliJobResponse{}); err != nil {
+			t.Fatalf("Unable to queue job: %v", err)
+		}
+		if _, err := d.DispatchPriority(jobs.NewRequestIdentifier(), &orderedJob{"stop", order}, &cmd.CliJobResponse{}, PriorityHigh); err != nil {
+			t.Fatalf("Unable to queue job: %v", err)
+		}
+	})
+	if names[0] != "stop" || names[1] != "install" {
+		t.Errorf("Expected the high priority job to run before the earlier low priority one, got %v", names)
+	}
+}
+
+func TestLowPriorityJobIsNotStarved(t *testing.T) {
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 3, Concurrent: 1, TrackDuplicateIds: 10, PriorityAging: time.Millisecond}
+	d.Start()
+
+	names := runQueued(t, d, func(order chan string) {
+		d.DispatchPriority(jobs.NewRequestIdentifier(), &orderedJob{"install", order}, &cmd.CliJobResponse{}, PriorityLow)
+		// waiting longer than two aging intervals outranks a high priority
+		time.Sleep(5 * time.Millisecond)
+		d.DispatchPriority(jobs.NewRequestIdentifier(), &orderedJob{"stop", order}, &cmd.CliJobResponse{}, PriorityHigh)
+	})
+	if names[0] != "install" || names[1] != "stop" {
+		t.Errorf("Expected a low priority job that has waited long enough to run first, got %v", names)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		if parsed, err := ParsePriority(p.String()); err != nil || parsed != p {
+			t.Errorf("Expected %s to parse, got %s %v", p, parsed, err)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("Expected an unknown priority to be rejected")
+	}
+}
+
+func TestExecuteThroughDispatcher(t *testing.T) {
+	jobs.AddJobExtension(jobs.JobExtensionFunc(func(r interface{}) (jobs.Job, error) {
+		if job, ok := r.(*failingJob); ok {
+			return job, nil
+		}
+		return nil, jobs.ErrNoJobForRequest
+	}))
+	failed := make(chan FailedJob, 1)
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, OnFailure: func(f FailedJob) { failed <- f }}
+	d.Start()
+
+	err := d.Execute(&failingJob{"test-fail"})
+	if !jobs.IsNotFound(err) {
+		t.Errorf("Expected the failure of the job to be returned, got %v", err)
+	}
+	if f := <-failed; f.ContainerId() != "test-fail" {
+		t.Errorf("Expected the job to run through the dispatcher, got %+v", f)
+	}
+	if err := d.Execute(struct{}{}); err != jobs.ErrNoJobForRequest {
+		t.Errorf("Expected a request without a job to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package dispatcher
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/utils"
+)
+
+// A change in the lifecycle of a job.
+type EventType string
+
+const (
+	EventAccepted  EventType = "accepted"
+	EventStarted   EventType = "started"
+	EventCompleted EventType = "completed"
+	EventFailed    EventType = "failed"
+)
+
+type Event struct {
+	Time time.Time
+	Type EventType
+	// The request id of the job
+	Id string
+	// The name of the type of the job, such as "*jobs.InstallContainerRequest"
+	JobType     string
+	ContainerId string `json:",omitempty"`
+	// Why the job failed
+	Error string `json:",omitempty"`
+}
+
+// Selects events by job type and container.  An empty field matches any
+// value.  The job type may be given without its package, such as
+// "InstallContainerRequest".
+type EventFilter struct {
+	JobType     string
+	ContainerId string
+}
+
+func (f EventFilter) Matches(e Event) bool {
+	if f.ContainerId != "" && f.ContainerId != e.ContainerId {
+		return false
+	}
+	if f.JobType != "" && f.JobType != e.JobType {
+		return f.JobType == ShortJobType(e.JobType)
+	}
+	return true
+}
+
+// The name of a job type without its package, such as
+// "InstallContainerRequest".
+func ShortJobType(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// Passes the events of a dispatcher to each subscriber.  Publishing never
+// waits for a subscriber: one whose buffer is full misses the event, which
+// is counted instead.
+type Events struct {
+	lock        sync.Mutex
+	subscribers map[*Subscription]bool
+}
+
+func NewEvents() *Events {
+	return &Events{subscribers: make(map[*Subscription]bool)}
+}
+
+// Receive the events that match filter, buffering up to size of them.
+// The subscription must be closed once it is no longer read.
+func (e *Events) Subscribe(filter EventFilter, size int) *Subscription {
+	s := &Subscription{events: e, filter: filter, c: make(chan Event, size)}
+	s.C = s.c
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.subscribers[s] = true
+	return s
+}
+
+func (e *Events) publish(t EventType, id jobs.RequestIdentifier, j jobs.Job, err error) {
+	if e == nil {
+		return
+	}
+	event := Event{Time: time.Now(), Type: t, Id: id.String(), JobType: jobType(j), ContainerId: jobContainerId(j)}
+	if err != nil {
+		event.Error = utils.MaskSecrets(err.Error())
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for s := range e.subscribers {
+		if !s.filter.Matches(event) {
+			continue
+		}
+		select {
+		case s.c <- event:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+type Subscription struct {
+	// The events, closed when the subscription is closed
+	C <-chan Event
+
+	events  *Events
+	filter  EventFilter
+	c       chan Event
+	dropped int
+}
+
+// The number of events missed because the buffer was full, reset each
+// time it is read.
+func (s *Subscription) Dropped() int {
+	s.events.lock.Lock()
+	defer s.events.lock.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+func (s *Subscription) Close() {
+	s.events.lock.Lock()
+	defer s.events.lock.Unlock()
+	if s.events.subscribers[s] {
+		delete(s.events.subscribers, s)
+		close(s.c)
+	}
+}
@@ -0,0 +1,84 @@
+package dispatcher
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/utils"
+)
+
+func TestEventsSlowSubscriber(t *testing.T) {
+	events := NewEvents()
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, Events: events}
+	d.Start()
+
+	// a subscriber that is never read must not hold up the jobs
+	slow := events.Subscribe(EventFilter{}, 1)
+	defer slow.Close()
+	failures := events.Subscribe(EventFilter{JobType: "failingJob"}, 10)
+	defer failures.Close()
+
+	for i := 0; i < 3; i++ {
+		done, err := d.Dispatch(jobs.NewRequestIdentifier(), &failingJob{"test-fail"}, &cmd.CliJobResponse{Output: ioutil.Discard})
+		if err != nil {
+			t.Fatalf("Unable to queue job: %v", err)
+		}
+		<-done
+	}
+	job := newBlockingJob()
+	close(job.release)
+	done, _ := d.Dispatch(jobs.NewRequestIdentifier(), job, &cmd.CliJobResponse{})
+	<-done
+
+	if e := <-slow.C; e.Type != EventAccepted || e.ContainerId != "test-fail" {
+		t.Errorf("Expected the first event to be buffered, got %+v", e)
+	}
+	if dropped := slow.Dropped(); dropped != 11 {
+		t.Errorf("Expected the other events to be dropped, got %d", dropped)
+	}
+
+	if len(failures.C) != 9 {
+		t.Fatalf("Expected only the events of the failing jobs, got %d", len(failures.C))
+	}
+	for i := 0; i < 9; i++ {
+		e := <-failures.C
+		if e.JobType != "*dispatcher.failingJob" {
+			t.Errorf("Expected the events to be filtered by job type, got %+v", e)
+		}
+		if i%3 == 2 && (e.Type != EventFailed || e.Error == "") {
+			t.Errorf("Expected the job to fail, got %+v", e)
+		}
+	}
+
+	failures.Close()
+	if _, ok := <-failures.C; ok {
+		t.Error("Expected a closed subscription to stop receiving events")
+	}
+}
+
+func TestFailedEventMasksSecretValues(t *testing.T) {
+	events := NewEvents()
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, Events: events}
+	d.Start()
+	subscription := events.Subscribe(EventFilter{}, 10)
+	defer subscription.Close()
+
+	utils.AddSecretValue("s3cret-in-event")
+	done, err := d.Dispatch(jobs.NewRequestIdentifier(), &failingJob{"s3cret-in-event"}, &cmd.CliJobResponse{Output: ioutil.Discard})
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-done
+	for e := range subscription.C {
+		if e.Type != EventFailed {
+			continue
+		}
+		if strings.Contains(e.Error, "s3cret-in-event") || !strings.Contains(e.Error, "***") {
+			t.Errorf("Expected the secret to be masked in the event, got %+v", e)
+		}
+		break
+	}
+}
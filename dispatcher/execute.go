@@ -0,0 +1,78 @@
+package dispatcher
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sync"
+
+	"github.com/openshift/geard/jobs"
+)
+
+// Run the job registered for request through the dispatcher and wait for
+// it to finish, for jobs the daemon starts itself rather than a client.
+// The job is queued, limited, and recorded like any other, its output is
+// discarded, and the failure it reports is returned.  The job keeps the id
+// the request was given, if any.
+func (d *Dispatcher) Execute(request interface{}) error {
+	job, err := jobs.JobFor(request)
+	if err != nil {
+		return err
+	}
+	resp := &discardResponse{}
+	done, err := d.Dispatch(requestIdentifierFor(request), job, resp)
+	if err != nil {
+		return err
+	}
+	<-done
+	return resp.failure()
+}
+
+// The id in the embedded jobs.RequestIdentifier of a request, or a new id
+// if it has none.
+func requestIdentifierFor(request interface{}) jobs.RequestIdentifier {
+	v := reflect.ValueOf(request)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		if field := v.FieldByName("RequestIdentifier"); field.IsValid() && field.CanInterface() {
+			if id, ok := field.Interface().(jobs.RequestIdentifier); ok && len(id) > 0 {
+				return id
+			}
+		}
+	}
+	return jobs.NewRequestIdentifier()
+}
+
+// A response that keeps only the first failure of a job.
+type discardResponse struct {
+	lock sync.Mutex
+	err  error
+}
+
+func (r *discardResponse) StreamResult() bool { return false }
+
+func (r *discardResponse) Success(t jobs.ResponseSuccess) {}
+
+func (r *discardResponse) SuccessWithData(t jobs.ResponseSuccess, data interface{}) {}
+
+func (r *discardResponse) SuccessWithWrite(t jobs.ResponseSuccess, flush, structured bool) io.Writer {
+	return ioutil.Discard
+}
+
+func (r *discardResponse) Failure(reason error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err == nil {
+		r.err = reason
+	}
+}
+
+func (r *discardResponse) WritePendingSuccess(name string, value interface{}) {}
+
+func (r *discardResponse) failure() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
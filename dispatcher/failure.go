@@ -0,0 +1,121 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"reflect"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/utils"
+)
+
+// A job that reported a failure.  Jobs cancelled by a client are not
+// considered failed.
+type FailedJob struct {
+	Id  jobs.RequestIdentifier
+	Job jobs.Job
+	Err error
+}
+
+// The name of the type of the job, such as "*jobs.StartedContainerStateRequest"
+func (f FailedJob) Type() string {
+	return jobType(f.Job)
+}
+
+// The container the job acted on, taken from the Id field of the request
+// if it has one.
+func (f FailedJob) ContainerId() string {
+	return jobContainerId(f.Job)
+}
+
+func jobType(j jobs.Job) string {
+	return reflect.TypeOf(j).String()
+}
+
+func jobContainerId(j jobs.Job) string {
+	v := reflect.ValueOf(j)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if field := v.FieldByName("Id"); field.IsValid() && field.Kind() == reflect.String {
+		return field.String()
+	}
+	return ""
+}
+
+// Report the first failure a job writes to its response.
+func (d *Dispatcher) observeFailure(t *jobTracker) jobs.Response {
+	observed := &failureResponse{Response: t.response, report: func(err error) {
+		t.failed(err)
+		if err == jobs.ErrJobCanceled || d.OnFailure == nil {
+			return
+		}
+		d.OnFailure(FailedJob{t.id, t.job, err})
+	}}
+	if trailers, ok := t.response.(jobs.TrailerResponse); ok {
+		return &failureTrailerResponse{observed, trailers}
+	}
+	return observed
+}
+
+type failureResponse struct {
+	jobs.Response
+	report   func(error)
+	reported bool
+}
+
+func (r *failureResponse) Failure(reason error) {
+	if !r.reported {
+		r.reported = true
+		r.report(reason)
+	}
+	r.Response.Failure(reason)
+}
+
+type failureTrailerResponse struct {
+	*failureResponse
+	jobs.TrailerResponse
+}
+
+// Run the program at path for each failed job, without waiting for it to
+// exit.  The program is passed the request id, the job type, the container
+// id (which may be empty), and the error with secret values masked, both
+// as arguments in that order and as GEARD_JOB_ID, GEARD_JOB_TYPE,
+// GEARD_CONTAINER_ID, and GEARD_JOB_ERROR.  It is killed if it runs longer
+// than timeout.
+func FailureExec(path string, timeout time.Duration) func(FailedJob) {
+	return func(f FailedJob) {
+		go runFailureExec(path, timeout, f)
+	}
+}
+
+func runFailureExec(path string, timeout time.Duration, f FailedJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	id, jobType, containerId, message := f.Id.String(), f.Type(), f.ContainerId(), utils.MaskSecrets(f.Err.Error())
+	cmd := exec.CommandContext(ctx, path, id, jobType, containerId, message)
+	cmd.Env = append(os.Environ(),
+		"GEARD_JOB_ID="+id,
+		"GEARD_JOB_TYPE="+jobType,
+		"GEARD_CONTAINER_ID="+containerId,
+		"GEARD_JOB_ERROR="+message,
+	)
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("did not exit within %s", timeout)
+	}
+	if err != nil {
+		log.Printf("dispatcher: Failure hook %s for job %s failed: %v: %s", path, id, err, out)
+	}
+}
@@ -0,0 +1,144 @@
+package dispatcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/utils"
+)
+
+// A job accepted by the dispatcher, as recorded in its History.
+type JobRecord struct {
+	// The request id of the job
+	Id string
+	// The name of the type of the job, such as "*jobs.InstallContainerRequest"
+	JobType     string
+	ContainerId string `json:",omitempty"`
+	// The last event of the job: accepted while it is queued, started while
+	// it runs, then completed or failed
+	Status   EventType
+	Accepted time.Time
+	Started  *time.Time `json:",omitempty"`
+	Finished *time.Time `json:",omitempty"`
+	// Why the job failed
+	Error string `json:",omitempty"`
+}
+
+// Selects jobs from a History.  An empty field matches any value.  The job
+// type may be given without its package, as for an EventFilter.
+type HistoryFilter struct {
+	Status  EventType
+	JobType string
+	// Only jobs accepted at or after this time
+	Since time.Time
+}
+
+func (f HistoryFilter) Matches(r *JobRecord) bool {
+	if f.Status != "" && f.Status != r.Status {
+		return false
+	}
+	if !f.Since.IsZero() && r.Accepted.Before(f.Since) {
+		return false
+	}
+	return EventFilter{JobType: f.JobType}.Matches(Event{JobType: r.JobType})
+}
+
+// Remembers the jobs a dispatcher accepted so that operators can review
+// them.  Jobs are forgotten, oldest first, once more than MaxJobs are
+// recorded, and once they finished more than MaxAge ago.  A limit of zero
+// is not applied.
+type History struct {
+	MaxJobs int
+	MaxAge  time.Duration
+
+	lock    sync.Mutex
+	records []*JobRecord
+	ids     map[string]*JobRecord
+	now     func() time.Time
+}
+
+func NewHistory(maxJobs int, maxAge time.Duration) *History {
+	return &History{MaxJobs: maxJobs, MaxAge: maxAge, ids: make(map[string]*JobRecord), now: time.Now}
+}
+
+func (h *History) record(t EventType, id jobs.RequestIdentifier, j jobs.Job, err error) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	key := id.String()
+	r, ok := h.ids[key]
+	if t == EventAccepted {
+		// a job queued again under the same id starts a new record
+		if ok {
+			h.remove(r)
+		}
+		r = &JobRecord{Id: key, JobType: jobType(j), ContainerId: jobContainerId(j), Accepted: now}
+		h.records = append(h.records, r)
+		h.ids[key] = r
+	} else if !ok {
+		// forgotten while it was queued or running
+		return
+	}
+
+	r.Status = t
+	switch t {
+	case EventStarted:
+		r.Started = &now
+	case EventCompleted, EventFailed:
+		r.Finished = &now
+	}
+	if err != nil {
+		r.Error = utils.MaskSecrets(err.Error())
+	}
+	h.evict(now)
+}
+
+// The jobs that match filter, in the order they were accepted.
+func (h *History) List(filter HistoryFilter) []JobRecord {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.evict(h.now())
+	list := []JobRecord{}
+	for _, r := range h.records {
+		if filter.Matches(r) {
+			list = append(list, *r)
+		}
+	}
+	return list
+}
+
+func (h *History) evict(now time.Time) {
+	if h.MaxAge > 0 {
+		cutoff := now.Add(-h.MaxAge)
+		kept := h.records[:0]
+		for _, r := range h.records {
+			if r.Finished != nil && r.Finished.Before(cutoff) {
+				delete(h.ids, r.Id)
+				continue
+			}
+			kept = append(kept, r)
+		}
+		h.records = kept
+	}
+	if h.MaxJobs > 0 && len(h.records) > h.MaxJobs {
+		for _, r := range h.records[:len(h.records)-h.MaxJobs] {
+			delete(h.ids, r.Id)
+		}
+		h.records = append([]*JobRecord{}, h.records[len(h.records)-h.MaxJobs:]...)
+	}
+}
+
+func (h *History) remove(r *JobRecord) {
+	delete(h.ids, r.Id)
+	for i := range h.records {
+		if h.records[i] == r {
+			h.records = append(h.records[:i], h.records[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package dispatcher
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/jobs"
+)
+
+func TestHistoryRecordsJobs(t *testing.T) {
+	history := NewHistory(10, 0)
+	d := &Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, History: history}
+	d.Start()
+
+	failed := jobs.NewRequestIdentifier()
+	done, err := d.Dispatch(failed, &failingJob{"test-fail"}, &cmd.CliJobResponse{Output: ioutil.Discard})
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	<-done
+	job := newBlockingJob()
+	running := jobs.NewRequestIdentifier()
+	done, _ = d.Dispatch(running, job, &cmd.CliJobResponse{Output: ioutil.Discard})
+	<-job.started
+
+	list := history.List(HistoryFilter{})
+	if len(list) != 2 {
+		t.Fatalf("Expected both jobs to be recorded, got %+v", list)
+	}
+	if r := list[0]; r.Id != failed.String() || r.Status != EventFailed || r.ContainerId != "test-fail" || r.Error == "" || r.Started == nil || r.Finished == nil {
+		t.Errorf("Expected the first job to have failed, got %+v", r)
+	}
+	if r := list[1]; r.Id != running.String() || r.Status != EventStarted || r.Started == nil || r.Finished != nil {
+		t.Errorf("Expected the second job to be running, got %+v", r)
+	}
+
+	close(job.release)
+	<-done
+	if list := history.List(HistoryFilter{Status: EventCompleted}); len(list) != 1 || list[0].Id != running.String() {
+		t.Errorf("Expected the second job to have completed, got %+v", list)
+	}
+}
+
+// Record a job accepted at the given time, finishing a minute later
+func recordJob(h *History, at time.Time, job jobs.Job, err error) jobs.RequestIdentifier {
+	id := jobs.NewRequestIdentifier()
+	h.now = func() time.Time { return at }
+	h.record(EventAccepted, id, job, nil)
+	h.record(EventStarted, id, job, nil)
+	h.now = func() time.Time { return at.Add(time.Minute) }
+	if err != nil {
+		h.record(EventFailed, id, job, err)
+	} else {
+		h.record(EventCompleted, id, job, nil)
+	}
+	return id
+}
+
+func TestHistoryFilter(t *testing.T) {
+	h := NewHistory(0, 0)
+	start := time.Date(2014, 5, 1, 12, 0, 0, 0, time.UTC)
+	early := recordJob(h, start, &failingJob{"test-1"}, jobs.ErrNotFound)
+	late := recordJob(h, start.Add(time.Hour), &failingJob{"test-2"}, nil)
+	other := recordJob(h, start.Add(2*time.Hour), newBlockingJob(), nil)
+
+	for _, test := range []struct {
+		filter   HistoryFilter
+		expected []jobs.RequestIdentifier
+	}{
+		{HistoryFilter{}, []jobs.RequestIdentifier{early, late, other}},
+		{HistoryFilter{Status: EventFailed}, []jobs.RequestIdentifier{early}},
+		{HistoryFilter{JobType: "failingJob"}, []jobs.RequestIdentifier{early, late}},
+		{HistoryFilter{JobType: "*dispatcher.blockingJob"}, []jobs.RequestIdentifier{other}},
+		{HistoryFilter{Since: start.Add(30 * time.Minute)}, []jobs.RequestIdentifier{late, other}},
+		{HistoryFilter{Status: EventCompleted, JobType: "failingJob", Since: start.Add(3 * time.Hour)}, []jobs.RequestIdentifier{}},
+	} {
+		list := h.List(test.filter)
+		if len(list) != len(test.expected) {
+			t.Errorf("Expected %d jobs for %+v, got %+v", len(test.expected), test.filter, list)
+			continue
+		}
+		for i := range list {
+			if list[i].Id != test.expected[i].String() {
+				t.Errorf("Expected job %d for %+v to be %s, got %s", i, test.filter, test.expected[i], list[i].Id)
+			}
+		}
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	start := time.Date(2014, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	h := NewHistory(2, 0)
+	recordJob(h, start, &failingJob{"test-1"}, nil)
+	second := recordJob(h, start.Add(time.Minute), &failingJob{"test-2"}, nil)
+	third := recordJob(h, start.Add(2*time.Minute), &failingJob{"test-3"}, nil)
+	if list := h.List(HistoryFilter{}); len(list) != 2 || list[0].Id != second.String() || list[1].Id != third.String() {
+		t.Errorf("Expected only the two most recent jobs to be kept, got %+v", list)
+	}
+	if len(h.ids) != 2 {
+		t.Errorf("Expected the evicted job to be forgotten, %d are indexed", len(h.ids))
+	}
+
+	h = NewHistory(0, time.Hour)
+	recordJob(h, start, &failingJob{"test-1"}, nil)
+	recent := recordJob(h, start.Add(time.Hour), &failingJob{"test-2"}, nil)
+	running := jobs.NewRequestIdentifier()
+	h.record(EventAccepted, running, &failingJob{"test-3"}, nil)
+	h.now = func() time.Time { return start.Add(90 * time.Minute) }
+	list := h.List(HistoryFilter{})
+	if len(list) != 2 || list[0].Id != recent.String() || list[1].Id != running.String() {
+		t.Errorf("Expected jobs that finished over an hour ago to be evicted, got %+v", list)
+	}
+
+	// a job that is unfinished is kept however long ago it was accepted
+	h.now = func() time.Time { return start.Add(24 * time.Hour) }
+	if list := h.List(HistoryFilter{}); len(list) != 1 || list[0].Id != running.String() {
+		t.Errorf("Expected only the queued job to be kept, got %+v", list)
+	}
+}
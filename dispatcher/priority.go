@@ -0,0 +1,135 @@
+package dispatcher
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+)
+
+// How soon a queued job runs relative to the other jobs in its queue.
+// Jobs of the same priority run in the order they were queued.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// How long a queued job waits before it is treated as one priority
+// higher, unless Dispatcher.PriorityAging is set.
+const DefaultPriorityAging = time.Minute
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("%d", int(p))
+}
+
+func ParsePriority(s string) (Priority, error) {
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return PriorityNormal, fmt.Errorf("The priority '%s' must be one of low, normal or high", s)
+}
+
+// Queue jobs of the same type as example ahead of or behind other jobs.
+// Jobs are of normal priority unless set.  Must be called before Start.
+func (d *Dispatcher) SetPriority(example jobs.Job, priority Priority) {
+	if d.priorities == nil {
+		d.priorities = make(map[string]Priority)
+	}
+	d.priorities[jobType(example)] = priority
+}
+
+// The priority of a job that was not given one when it was dispatched.
+func (d *Dispatcher) priorityFor(j jobs.Job) Priority {
+	return d.priorities[jobType(j)]
+}
+
+// A bounded queue of jobs that releases the most urgent job first.  A job
+// is ordered as if it had been queued one aging interval earlier for each
+// level of priority, so that a job waiting behind more urgent work is
+// eventually run however much of that work arrives.
+type jobQueue struct {
+	lock     sync.Mutex
+	ready    *sync.Cond
+	capacity int
+	aging    time.Duration
+	items    queuedJobs
+	next     uint64
+}
+
+type queuedJob struct {
+	tracker *jobTracker
+	// Nanoseconds; lower runs first
+	order int64
+	seq   uint64
+}
+
+func newJobQueue(capacity int, aging time.Duration) *jobQueue {
+	if aging <= 0 {
+		aging = DefaultPriorityAging
+	}
+	q := &jobQueue{capacity: capacity, aging: aging}
+	q.ready = sync.NewCond(&q.lock)
+	return q
+}
+
+// Queue the tracker, or return false if the queue is full.
+func (q *jobQueue) push(t *jobTracker, priority Priority, now time.Time) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.items) >= q.capacity {
+		return false
+	}
+	order := now.UnixNano() - int64(priority)*int64(q.aging)
+	heap.Push(&q.items, queuedJob{t, order, q.next})
+	q.next++
+	q.ready.Signal()
+	return true
+}
+
+// Wait for a job and remove the most urgent one from the queue.
+func (q *jobQueue) pop() *jobTracker {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for len(q.items) == 0 {
+		q.ready.Wait()
+	}
+	return heap.Pop(&q.items).(queuedJob).tracker
+}
+
+// Implements heap.Interface
+type queuedJobs []queuedJob
+
+func (h queuedJobs) Len() int { return len(h) }
+func (h queuedJobs) Less(i, j int) bool {
+	if h[i].order != h[j].order {
+		return h[i].order < h[j].order
+	}
+	return h[i].seq < h[j].seq
+}
+func (h queuedJobs) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *queuedJobs) Push(x interface{}) {
+	*h = append(*h, x.(queuedJob))
+}
+
+func (h *queuedJobs) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
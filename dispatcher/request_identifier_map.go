@@ -21,7 +21,7 @@ func NewRequestIdentifierMap(size int) *RequestIdentifierMap {
 	}
 }
 
-func (m RequestIdentifierMap) Get(id jobs.RequestIdentifier) interface{} {
+func (m *RequestIdentifierMap) Get(id jobs.RequestIdentifier) interface{} {
 	key := string(id)
 
 	m.lock.RLock()
@@ -30,7 +30,18 @@ func (m RequestIdentifierMap) Get(id jobs.RequestIdentifier) interface{} {
 	return m.keys[key]
 }
 
-func (m RequestIdentifierMap) Put(id jobs.RequestIdentifier, v interface{}) (interface{}, bool) {
+// Return the value for id and whether id is tracked at all.
+func (m *RequestIdentifierMap) Lookup(id jobs.RequestIdentifier) (interface{}, bool) {
+	key := string(id)
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	v, ok := m.keys[key]
+	return v, ok
+}
+
+func (m *RequestIdentifierMap) Put(id jobs.RequestIdentifier, v interface{}) (interface{}, bool) {
 	key := string(id)
 
 	m.lock.Lock()
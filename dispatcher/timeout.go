@@ -0,0 +1,56 @@
+package dispatcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+)
+
+// Limit how long jobs of the same type as example may run once they have
+// started, or remove the limit if timeout is zero.  The context of a job is
+// cancelled when its limit passes, and a job that then fails with
+// jobs.ErrJobCanceled is reported as jobs.ErrJobTimedOut.  Only jobs that
+// implement jobs.ContextJob can be stopped.  Must be called before Start.
+func (d *Dispatcher) SetTimeout(example jobs.Job, timeout time.Duration) {
+	if d.timeouts == nil {
+		d.timeouts = make(map[string]time.Duration)
+	}
+	if timeout <= 0 {
+		delete(d.timeouts, jobType(example))
+		return
+	}
+	d.timeouts[jobType(example)] = timeout
+}
+
+// The longest a job may run, or zero if it is not limited.
+func (d *Dispatcher) timeoutFor(j jobs.Job) time.Duration {
+	return d.timeouts[jobType(j)]
+}
+
+func newTimeoutResponse(ctx context.Context, resp jobs.Response) jobs.Response {
+	r := &timeoutResponse{resp, ctx}
+	if trailers, ok := resp.(jobs.TrailerResponse); ok {
+		return &timeoutTrailerResponse{r, trailers}
+	}
+	return r
+}
+
+// Reports a job that stops because its time limit passed as timed out
+// rather than cancelled.
+type timeoutResponse struct {
+	jobs.Response
+	ctx context.Context
+}
+
+func (r *timeoutResponse) Failure(reason error) {
+	if reason == jobs.ErrJobCanceled && r.ctx.Err() == context.DeadlineExceeded {
+		reason = jobs.ErrJobTimedOut
+	}
+	r.Response.Failure(reason)
+}
+
+type timeoutTrailerResponse struct {
+	*timeoutResponse
+	jobs.TrailerResponse
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httputil"
+	"net/url"
+)
+
+// A build that Docker reported as failed, after the output of the build
+// was written.
+type BuildError struct {
+	Message string
+}
+
+func (e BuildError) Error() string {
+	return "docker: build failed: " + e.Message
+}
+
+type buildMessage struct {
+	Stream string `json:"stream"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// Build an image tagged as tag from a tar archive of a build context, and
+// write the build output to output as it is reported.  Intermediate
+// containers are removed.  Returns a BuildError if a step of the build
+// failed.
+func (d *DockerClient) BuildImage(tag string, context io.Reader, output io.Writer) error {
+	query := url.Values{}
+	query.Set("t", tag)
+	query.Set("rm", "1")
+	_, clientconn, req, err := d.newRequest("POST", "/build?"+query.Encode(), context)
+	if err != nil {
+		return err
+	}
+	defer clientconn.Close()
+	req.Header.Set("Content-Type", "application/tar")
+
+	resp, err := clientconn.Do(req)
+	if err != nil && err != httputil.ErrPersistEOF {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		message := buildMessage{}
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch {
+		case message.Error != "":
+			fmt.Fprintln(output, message.Error)
+			return BuildError{message.Error}
+		case message.Stream != "":
+			io.WriteString(output, message.Stream)
+		case message.Status != "":
+			fmt.Fprintln(output, message.Status)
+		}
+	}
+}
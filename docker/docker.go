@@ -6,6 +6,7 @@ import (
 	gdocker "github.com/fsouza/go-dockerclient"
 	"github.com/fsouza/go-dockerclient/engine"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -19,6 +20,7 @@ type containerLookupResult struct {
 
 type DockerClient struct {
 	client          *gdocker.Client
+	endpoint        string
 	executionDriver string
 }
 
@@ -26,6 +28,59 @@ func (d *DockerClient) ListContainers() ([]gdocker.APIContainers, error) {
 	return d.client.ListContainers(gdocker.ListContainersOptions{All: true})
 }
 
+// Stop a container, killing it if it has not exited after timeout seconds.
+func (d *DockerClient) StopContainer(ID string, timeout uint) error {
+	err := d.client.StopContainer(ID, timeout)
+	if _, ok := err.(*gdocker.NoSuchContainer); ok {
+		err = ErrNoSuchContainer
+	}
+	return err
+}
+
+// Send the signal with the given number to the main process of a
+// container.
+func (d *DockerClient) KillContainer(ID string, signal int) error {
+	err := d.client.KillContainer(gdocker.KillContainerOptions{ID: ID, Signal: gdocker.Signal(signal)})
+	if _, ok := err.(*gdocker.NoSuchContainer); ok {
+		err = ErrNoSuchContainer
+	}
+	return err
+}
+
+// Give a container a new name.  Returns ErrNoSuchContainer if there is no
+// container with the current name.
+func (d *DockerClient) RenameContainer(ID, name string) error {
+	return d.doJson("POST", "/containers/"+url.QueryEscape(ID)+"/rename?name="+url.QueryEscape(name), nil, nil)
+}
+
+// Freeze the processes of a running container.  Returns ErrNoSuchContainer
+// if there is no container with the name.
+func (d *DockerClient) PauseContainer(ID string) error {
+	return d.doJson("POST", "/containers/"+url.QueryEscape(ID)+"/pause", nil, nil)
+}
+
+// Thaw the processes of a paused container.  Returns ErrNoSuchContainer if
+// there is no container with the name.
+func (d *DockerClient) UnpauseContainer(ID string) error {
+	return d.doJson("POST", "/containers/"+url.QueryEscape(ID)+"/unpause", nil, nil)
+}
+
+// Whether a container is running, and whether its processes are frozen.
+// A paused container is still running.  The state returned by
+// InspectContainer does not include whether it is paused.
+func (d *DockerClient) ContainerPausedState(ID string) (running bool, paused bool, err error) {
+	var container struct {
+		State struct {
+			Running bool
+			Paused  bool
+		}
+	}
+	if err := d.doJson("GET", "/containers/"+url.QueryEscape(ID)+"/json", nil, &container); err != nil {
+		return false, false, err
+	}
+	return container.State.Running, container.State.Paused, nil
+}
+
 func (d *DockerClient) ForceCleanContainer(ID string) error {
 	if err := d.client.KillContainer(gdocker.KillContainerOptions{ID: ID}); err != nil {
 		return err
@@ -52,7 +107,7 @@ func GetConnection(dockerSocket string) (*DockerClient, error) {
 	}
 	executionDriver = info.Get("ExecutionDriver")
 
-	return &DockerClient{client, executionDriver}, nil
+	return &DockerClient{client, dockerSocket, executionDriver}, nil
 }
 
 var ErrNoSuchContainer = errors.New("can't find container")
@@ -79,6 +134,36 @@ func (d *DockerClient) GetImage(imageName string) (*gdocker.Image, error) {
 	}
 }
 
+// Whether the image is present, without pulling it.
+func (d *DockerClient) HasImage(imageName string) (bool, error) {
+	if _, err := d.client.InspectImage(imageName); err != nil {
+		if err == gdocker.ErrNoSuchImage {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Pull the image from its registry even if it is present, so that a tag
+// that has moved is updated.
+func (d *DockerClient) PullImage(imageName string) error {
+	return d.client.PullImage(gdocker.PullImageOptions{imageName, "", "", os.Stdout}, gdocker.AuthConfiguration{})
+}
+
+// The references of the form <repository>@<digest> the registries an image
+// was pulled from know it by.  Empty for an image that was built or loaded
+// locally.
+func (d *DockerClient) GetImageRepoDigests(imageName string) ([]string, error) {
+	var image struct {
+		RepoDigests []string
+	}
+	if err := d.doJson("GET", "/images/"+imageName+"/json", nil, &image); err != nil {
+		return nil, err
+	}
+	return image.RepoDigests, nil
+}
+
 func (d *DockerClient) GetContainerIPs(ids []string) (map[string]string, error) {
 	ips := make(map[string]string)
 	for _, id := range ids {
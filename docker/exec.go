@@ -0,0 +1,213 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient/utils"
+)
+
+// Options for running a command inside of an existing container.
+type ExecOptions struct {
+	Cmd []string
+	// Allocate a pseudo-TTY for the command.  Output is not
+	// multiplexed and stderr is delivered on Stdout.
+	Tty bool
+	// If set, the contents are forwarded to the command's stdin
+	// until EOF.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+type execCreateRequest struct {
+	AttachStdin  bool
+	AttachStdout bool
+	AttachStderr bool
+	Tty          bool
+	Cmd          []string
+}
+
+type execStartRequest struct {
+	Detach bool
+	Tty    bool
+}
+
+type execInspectResponse struct {
+	Id       string
+	Running  bool
+	ExitCode int
+}
+
+// Execute a command in a running container, streaming the output of the
+// command to the provided writers.  Returns the exit code of the command
+// once it has completed.
+func (d *DockerClient) ExecInContainer(container string, opts ExecOptions) (int, error) {
+	if len(opts.Cmd) == 0 {
+		return 0, errors.New("docker: a command is required to exec in a container")
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = ioutil.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = opts.Stdout
+	}
+
+	created := execInspectResponse{}
+	if err := d.doJson("POST", "/containers/"+url.QueryEscape(container)+"/exec", &execCreateRequest{
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          opts.Tty,
+		Cmd:          opts.Cmd,
+	}, &created); err != nil {
+		return 0, err
+	}
+	if created.Id == "" {
+		return 0, errors.New("docker: no exec instance was created")
+	}
+
+	if err := d.startExec(created.Id, opts); err != nil {
+		return 0, err
+	}
+
+	inspect := execInspectResponse{}
+	if err := d.doJson("GET", "/exec/"+created.Id+"/json", nil, &inspect); err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
+
+func (d *DockerClient) startExec(id string, opts ExecOptions) error {
+	body, err := json.Marshal(&execStartRequest{Tty: opts.Tty})
+	if err != nil {
+		return err
+	}
+	conn, clientconn, req, err := d.newRequest("POST", "/exec/"+id+"/start", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer clientconn.Close()
+
+	resp, err := clientconn.Do(req)
+	if err != nil && err != httputil.ErrPersistEOF {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+
+	// the connection is hijacked by the daemon, anything written after the
+	// request is forwarded to the process
+	if opts.Stdin != nil {
+		go func() {
+			io.Copy(conn, opts.Stdin)
+			if c, ok := conn.(interface {
+				CloseWrite() error
+			}); ok {
+				c.CloseWrite()
+			}
+		}()
+	}
+
+	if opts.Tty {
+		_, err = io.Copy(opts.Stdout, resp.Body)
+	} else {
+		_, err = utils.StdCopy(opts.Stdout, opts.Stderr, &splitEOFReader{r: resp.Body})
+	}
+	return err
+}
+
+// StdCopy discards any data returned alongside io.EOF, so the final frame
+// of a stream must be delivered before the EOF is reported.
+type splitEOFReader struct {
+	r   io.Reader
+	err error
+}
+
+func (s *splitEOFReader) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := s.r.Read(p)
+	if n > 0 && err != nil {
+		s.err = err
+		return n, nil
+	}
+	return n, err
+}
+
+func (d *DockerClient) doJson(method, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	_, clientconn, req, err := d.newRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer clientconn.Close()
+
+	resp, err := clientconn.Do(req)
+	if err != nil && err != httputil.ErrPersistEOF {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Open a dedicated connection to the Docker daemon - requests that hijack
+// the connection cannot share the default client.
+func (d *DockerClient) newRequest(method, path string, body io.Reader) (net.Conn, *httputil.ClientConn, *http.Request, error) {
+	u, err := url.Parse(d.endpoint)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	protocol, address := "tcp", u.Host
+	if u.Scheme == "unix" {
+		protocol, address = "unix", u.Path
+		u.Host = "docker"
+	}
+
+	req, err := http.NewRequest(method, "http://"+u.Host+path, body)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	conn, err := net.Dial(protocol, address)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return conn, httputil.NewClientConn(conn, nil), req, nil
+}
+
+func responseError(resp *http.Response) error {
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4*1024))
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNoSuchContainer
+	}
+	return fmt.Errorf("docker: API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(message)))
+}
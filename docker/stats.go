@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"net/url"
+)
+
+// A single sample of the resources used by a running container.
+type ContainerStats struct {
+	// Bytes of memory in use, and the limit enforced by the kernel
+	MemoryUsage uint64
+	MemoryLimit uint64
+	// Total CPU time consumed, in nanoseconds
+	CPUUsage uint64
+}
+
+type statsResponse struct {
+	MemoryStats struct {
+		Usage uint64 `json:"usage"`
+		Limit uint64 `json:"limit"`
+	} `json:"memory_stats"`
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+	} `json:"cpu_stats"`
+}
+
+// Return the current resource usage of a running container.
+func (d *DockerClient) ContainerStats(container string) (*ContainerStats, error) {
+	stats := statsResponse{}
+	if err := d.doJson("GET", "/containers/"+url.QueryEscape(container)+"/stats?stream=false", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &ContainerStats{
+		MemoryUsage: stats.MemoryStats.Usage,
+		MemoryLimit: stats.MemoryStats.Limit,
+		CPUUsage:    stats.CPUStats.CPUUsage.TotalUsage,
+	}, nil
+}
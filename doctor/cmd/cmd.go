@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/doctor"
+)
+
+var (
+	all bool
+	fix bool
+)
+
+func RegisterDoctor(parent *cobra.Command) {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor <name>...",
+		Short: "(Local) Check the files of containers for problems that prevent them from starting",
+		Long:  "Check that the unit file of each container is valid and, for isolated containers, that the home directory and authorized keys are owned by the container user and are not writable by others.  Exits non-zero if any problem remains.",
+		Run:   diagnose,
+	}
+	doctorCmd.Flags().BoolVar(&all, "all", false, "Check every container installed on this host")
+	doctorCmd.Flags().BoolVar(&fix, "fix", false, "Repair the problems that can be fixed automatically")
+	parent.AddCommand(doctorCmd)
+}
+
+func diagnose(c *cobra.Command, args []string) {
+	if all == (len(args) > 0) {
+		gcmd.Fail(1, "Valid arguments: <name>... or --all")
+	}
+
+	var ids []containers.Identifier
+	if all {
+		installed, err := containers.InstalledIdentifiers()
+		if err != nil {
+			gcmd.Fail(1, "Unable to list the installed containers: %s", err.Error())
+		}
+		ids = installed
+	} else {
+		for _, arg := range args {
+			id, err := containers.NewIdentifier(arg)
+			if err != nil {
+				gcmd.Fail(1, "'%s' is not a valid container name: %s", arg, err.Error())
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	remaining := 0
+	for _, id := range ids {
+		d, err := doctor.Diagnose(id)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			remaining++
+			continue
+		}
+		if fix {
+			d.Fix()
+		}
+		d.WriteSummaryTo(os.Stdout)
+		remaining += d.Remaining()
+	}
+	if remaining > 0 {
+		os.Exit(1)
+	}
+}
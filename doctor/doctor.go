@@ -0,0 +1,207 @@
+// Check that the files geard keeps for a container on disk are in the
+// state the container needs to start, and repair the ones that are not.
+package doctor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/ssh"
+)
+
+// Replaced by tests, which can't create container users
+var lookupUser = user.Lookup
+
+// Something wrong with a file of a container.  Problems that can be fixed
+// are repaired by Fix.
+type Problem struct {
+	Path    string
+	Message string
+
+	Fixed bool
+	// The reason a fix failed
+	FixError error
+
+	fix func() error
+}
+
+func (p *Problem) Fixable() bool {
+	return p.fix != nil
+}
+
+// The problems found with a single container
+type Diagnosis struct {
+	Id       containers.Identifier
+	Problems []*Problem
+}
+
+func (d *Diagnosis) add(path string, fix func() error, format string, args ...interface{}) {
+	d.Problems = append(d.Problems, &Problem{Path: path, Message: fmt.Sprintf(format, args...), fix: fix})
+}
+
+// Attempt to repair every fixable problem, recording whether each fix
+// succeeded.
+func (d *Diagnosis) Fix() {
+	for _, p := range d.Problems {
+		if p.Fixed || !p.Fixable() {
+			continue
+		}
+		if err := p.fix(); err != nil {
+			p.FixError = err
+			continue
+		}
+		p.Fixed = true
+	}
+}
+
+// The number of problems that have not been fixed
+func (d *Diagnosis) Remaining() int {
+	count := 0
+	for _, p := range d.Problems {
+		if !p.Fixed {
+			count++
+		}
+	}
+	return count
+}
+
+func (d *Diagnosis) WriteSummaryTo(w io.Writer) {
+	if len(d.Problems) == 0 {
+		fmt.Fprintf(w, "Container %s: ok\n", d.Id)
+		return
+	}
+	fmt.Fprintf(w, "Container %s: %d problem(s)\n", d.Id, len(d.Problems))
+	for _, p := range d.Problems {
+		var state string
+		switch {
+		case p.Fixed:
+			state = "fixed"
+		case p.FixError != nil:
+			state = "unable to fix: " + p.FixError.Error()
+		case p.Fixable():
+			state = "fixable with --fix"
+		default:
+			state = "must be fixed manually"
+		}
+		fmt.Fprintf(w, "  %s: %s (%s)\n", p.Path, p.Message, state)
+	}
+}
+
+// Check the unit file of a container and, for isolated containers, the
+// ownership and modes of its home directory and authorized keys.
+func Diagnose(id containers.Identifier) (*Diagnosis, error) {
+	d := &Diagnosis{Id: id}
+
+	unitPath := id.UnitPathFor()
+	values, err := readUnitValues(unitPath)
+	if os.IsNotExist(err) {
+		if _, errl := os.Lstat(unitPath); errl == nil {
+			d.add(unitPath, nil, "The unit file links to a definition that does not exist, reinstall the container")
+			return d, nil
+		}
+		return nil, fmt.Errorf("The container %s is not installed on this host", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case values["X-ContainerId"] != string(id):
+		d.add(unitPath, nil, "The unit file is for container '%s', reinstall the container", values["X-ContainerId"])
+	case values["[Service]"] == "" || values["ExecStart"] == "":
+		d.add(unitPath, nil, "The unit file has no command to start the container, reinstall the container")
+	}
+
+	if values["X-ContainerType"] == "isolated" {
+		d.checkIsolated(id)
+	}
+	return d, nil
+}
+
+func (d *Diagnosis) checkIsolated(id containers.Identifier) {
+	u, err := lookupUser(id.LoginFor())
+	if err != nil {
+		d.add(id.HomePath(), nil, "The container user %s does not exist, start the container to create it: %v", id.LoginFor(), err)
+		return
+	}
+	uid, _ := strconv.Atoi(u.Uid)
+	gid, _ := strconv.Atoi(u.Gid)
+
+	home := id.HomePath()
+	if _, err := os.Stat(home); os.IsNotExist(err) {
+		d.add(home, func() error {
+			if err := os.MkdirAll(home, 0700); err != nil {
+				return err
+			}
+			return os.Chown(home, uid, gid)
+		}, "The home directory does not exist")
+		return
+	}
+	d.checkOwnership(home, uid, gid, 0022)
+
+	sshDir := filepath.Join(home, ".ssh")
+	if _, err := os.Stat(sshDir); err == nil {
+		d.checkOwnership(sshDir, uid, gid, 0077)
+	}
+
+	authKeys := id.AuthKeysPathFor()
+	if _, err := os.Stat(authKeys); os.IsNotExist(err) {
+		if granted, _ := filepath.Glob(filepath.Join(ssh.SshAccessBasePath(id), "*")); len(granted) > 0 {
+			d.add(authKeys, func() error {
+				return ssh.GenerateAuthorizedKeysFor(u, true, false)
+			}, "The authorized keys file does not exist, but %d key(s) have been granted access", len(granted))
+		}
+		return
+	}
+	d.checkOwnership(authKeys, uid, gid, 0077)
+}
+
+// Report a path that is not owned by the container user, or whose mode
+// includes any of the disallowed permission bits.  SSH refuses keys in a
+// file others can change.
+func (d *Diagnosis) checkOwnership(path string, uid, gid int, disallowed os.FileMode) {
+	info, err := os.Stat(path)
+	if err != nil {
+		d.add(path, nil, "Unable to read: %v", err)
+		return
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && (int(stat.Uid) != uid || int(stat.Gid) != gid) {
+		d.add(path, func() error {
+			return os.Chown(path, uid, gid)
+		}, "Owned by %d:%d instead of the container user %d:%d", stat.Uid, stat.Gid, uid, gid)
+	}
+	if mode := info.Mode().Perm(); mode&disallowed != 0 {
+		d.add(path, func() error {
+			return os.Chmod(path, mode&^disallowed)
+		}, "Mode %04o allows access by other users", mode)
+	}
+}
+
+// The last value of each key in a unit file, and "true" for each section
+// header.
+func readUnitValues(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scan := bufio.NewScanner(file)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if strings.HasPrefix(line, "[") {
+			values[line] = "true"
+		} else if i := strings.Index(line, "="); i > 0 && !strings.HasPrefix(line, "#") {
+			values[line[:i]] = line[i+1:]
+		}
+	}
+	return values, scan.Err()
+}
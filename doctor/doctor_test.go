@@ -0,0 +1,132 @@
+package doctor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+)
+
+const (
+	testUid = 4321
+	testGid = 4322
+)
+
+func withContainer(t *testing.T, id containers.Identifier, unit string) func() {
+	if os.Geteuid() != 0 {
+		t.Skip("Changing the owner of files requires root")
+	}
+	dir, err := ioutil.TempDir("", "geard-doctor")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+	lookupUser = func(name string) (*user.User, error) {
+		if name != id.LoginFor() {
+			return nil, user.UnknownUserError(name)
+		}
+		return &user.User{Uid: fmt.Sprint(testUid), Gid: fmt.Sprint(testGid), Username: name, Name: "Container user"}, nil
+	}
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte(unit), 0664); err != nil {
+		t.Fatalf("Unable to write the unit file: %v", err)
+	}
+	return func() {
+		lookupUser = user.Lookup
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func isolatedUnit(id containers.Identifier) string {
+	return fmt.Sprintf("[Unit]\nDescription=Container %s\n\n[Service]\nExecStart=/usr/bin/docker run --rm --name \"%s\" \"test/image\"\n\n[Install]\nWantedBy=container.target\n\nX-ContainerId=%s\nX-ContainerType=isolated\n", id, id, id)
+}
+
+func writeHome(t *testing.T, id containers.Identifier, uid, gid int, keysMode os.FileMode) {
+	sshDir := filepath.Join(id.HomePath(), ".ssh")
+	if err := os.MkdirAll(sshDir, 0700); err != nil {
+		t.Fatalf("Unable to create the home directory: %v", err)
+	}
+	if err := ioutil.WriteFile(id.AuthKeysPathFor(), []byte{}, 0600); err != nil {
+		t.Fatalf("Unable to write the authorized keys: %v", err)
+	}
+	for _, path := range []string{id.HomePath(), sshDir, id.AuthKeysPathFor()} {
+		if err := os.Chown(path, uid, gid); err != nil {
+			t.Fatalf("Unable to change the owner of %s: %v", path, err)
+		}
+	}
+	os.Chmod(id.HomePath(), 0700)
+	os.Chmod(sshDir, 0700)
+	os.Chmod(id.AuthKeysPathFor(), keysMode)
+}
+
+func TestDiagnoseHealthyContainer(t *testing.T) {
+	id := containers.Identifier("test-doctor")
+	defer withContainer(t, id, isolatedUnit(id))()
+	writeHome(t, id, testUid, testGid, 0600)
+
+	d, err := Diagnose(id)
+	if err != nil {
+		t.Fatalf("Unable to diagnose the container: %v", err)
+	}
+	if len(d.Problems) != 0 {
+		t.Errorf("Expected no problems, got %+v", d.Problems)
+	}
+}
+
+func TestDiagnoseAndFixOwnership(t *testing.T) {
+	id := containers.Identifier("test-doctor")
+	defer withContainer(t, id, isolatedUnit(id))()
+	writeHome(t, id, 0, 0, 0666)
+
+	d, err := Diagnose(id)
+	if err != nil {
+		t.Fatalf("Unable to diagnose the container: %v", err)
+	}
+	// home, .ssh, and authorized_keys owners, and the authorized_keys mode
+	if len(d.Problems) != 4 {
+		t.Fatalf("Expected 4 problems, got %+v", d.Problems)
+	}
+	for _, p := range d.Problems {
+		if !p.Fixable() {
+			t.Errorf("Expected %s to be fixable: %s", p.Path, p.Message)
+		}
+	}
+
+	d.Fix()
+	if d.Remaining() != 0 {
+		t.Errorf("Expected every problem to be fixed, got %+v", d.Problems)
+	}
+	if d, err = Diagnose(id); err != nil || len(d.Problems) != 0 {
+		t.Errorf("Expected no problems after fixing, got %+v %v", d.Problems, err)
+	}
+	if info, err := os.Stat(id.AuthKeysPathFor()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the authorized keys to only be writable by the owner, got %v %v", info.Mode(), err)
+	}
+}
+
+func TestDiagnoseInvalidUnit(t *testing.T) {
+	id := containers.Identifier("test-doctor")
+	defer withContainer(t, id, "[Unit]\nDescription=Container test-doctor\n\nX-ContainerId=test-doctor\nX-ContainerType=simple\n")()
+
+	d, err := Diagnose(id)
+	if err != nil {
+		t.Fatalf("Unable to diagnose the container: %v", err)
+	}
+	if len(d.Problems) != 1 || d.Problems[0].Fixable() {
+		t.Fatalf("Expected the unit file to be reported as unfixable, got %+v", d.Problems)
+	}
+	d.Fix()
+	if d.Remaining() != 1 {
+		t.Errorf("Expected the unit file problem to remain, got %+v", d.Problems)
+	}
+
+	if _, err := Diagnose("test-missing"); err == nil {
+		t.Error("Expected a container that is not installed to be reported")
+	}
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+)
+
+type AccessLogFormat string
+
+const (
+	AccessLogText AccessLogFormat = "text"
+	AccessLogJSON AccessLogFormat = "json"
+)
+
+func (f AccessLogFormat) Check() error {
+	switch f {
+	case AccessLogText, AccessLogJSON:
+		return nil
+	}
+	return fmt.Errorf("The log format '%s' must be 'text' or 'json'", string(f))
+}
+
+// A single request to the API.  Streamed responses are logged when the
+// stream ends, so the duration covers the whole response.
+type AccessLogEntry struct {
+	Time       time.Time
+	Method     string
+	Path       string
+	Status     int
+	Bytes      int64
+	DurationMs float64
+	// The common name of a verified client certificate, otherwise the
+	// address of the client
+	Client    string
+	RequestId string
+}
+
+func (e *AccessLogEntry) String() string {
+	return fmt.Sprintf("%s %s %s %d %d %.3fms client=%s request=%s", e.Time.UTC().Format(time.RFC3339), e.Method, e.Path, e.Status, e.Bytes, e.DurationMs, e.Client, e.RequestId)
+}
+
+// Log every request to handler.  Requests without an X-Request-Id are
+// assigned one, so that the logged id is the one the job runs with.
+func AccessLogHandler(handler http.Handler, logger *log.Logger, format AccessLogFormat) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get("X-Request-Id")
+		if requestId == "" {
+			requestId = jobs.NewRequestIdentifier().String()
+			r.Header.Set("X-Request-Id", requestId)
+		}
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		entry := &AccessLogEntry{
+			Time:       start,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     status,
+			Bytes:      recorder.bytes,
+			DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+			Client:     clientIdentity(r),
+			RequestId:  requestId,
+		}
+		if format == AccessLogJSON {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				log.Printf("access log: unable to encode entry: %v", err)
+				return
+			}
+			logger.Print(string(data))
+			return
+		}
+		logger.Print(entry.String())
+	})
+}
+
+func clientIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		if cn := r.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return cn
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// Records the first status code written and the size of the body.  The
+// status of a streamed response is written before any output, so it is
+// known even though the response has not finished.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Allows http.ResponseController to reach the underlying connection.
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
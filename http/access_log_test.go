@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/utils"
+)
+
+// Sends each line logged to a channel, since entries are written after
+// the client has read the response.
+type lineWriter chan string
+
+func (w lineWriter) Write(b []byte) (int, error) {
+	w <- string(b)
+	return len(b), nil
+}
+
+func nextLine(t *testing.T, lines lineWriter) string {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a request to be logged")
+	}
+	return ""
+}
+
+func TestAccessLogStreamedResponse(t *testing.T) {
+	lines := make(lineWriter, 1)
+	release := make(chan struct{})
+	var seenId string
+	server := httptest.NewServer(AccessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenId = r.Header.Get("X-Request-Id")
+		out := NewHttpJobResponse(w, false, ResponseTable).SuccessWithWrite(jobs.ResponseOk, true, false)
+		out.Write([]byte("first\n"))
+		<-release
+		out.Write([]byte("second\n"))
+	}), log.New(lines, "", 0), AccessLogJSON))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/container/test/log")
+	if err != nil {
+		t.Fatalf("Unable to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	// the stream must still be flushed through the access log
+	if line, err := r.ReadString('\n'); err != nil || line != "first\n" {
+		t.Fatalf("Expected the first line to be flushed, got %q %v", line, err)
+	}
+	close(release)
+	ioutil.ReadAll(r)
+
+	entry := AccessLogEntry{}
+	if err := json.Unmarshal([]byte(nextLine(t, lines)), &entry); err != nil {
+		t.Fatalf("Expected the entry to be JSON: %v", err)
+	}
+	if entry.Method != "GET" || entry.Path != "/container/test/log" || entry.Status != http.StatusAccepted {
+		t.Errorf("Expected the streamed request to be logged, got %+v", entry)
+	}
+	if entry.Bytes != int64(len("first\nsecond\n")) {
+		t.Errorf("Expected the size of the stream to be logged, got %d", entry.Bytes)
+	}
+	if entry.Client != "127.0.0.1" {
+		t.Errorf("Expected the client address to be logged, got %q", entry.Client)
+	}
+	if entry.RequestId == "" || entry.RequestId != seenId {
+		t.Errorf("Expected the assigned request id %q to be logged, got %q", seenId, entry.RequestId)
+	}
+	if _, err := jobs.NewRequestIdentifierFromString(entry.RequestId); err != nil {
+		t.Errorf("Expected the assigned request id to be valid: %v", err)
+	}
+	if entry.DurationMs <= 0 || entry.Time.IsZero() {
+		t.Errorf("Expected the time of the request to be logged, got %+v", entry)
+	}
+}
+
+func TestAccessLogText(t *testing.T) {
+	lines := make(lineWriter, 1)
+	server := httptest.NewServer(AccessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "missing", http.StatusNotFound)
+	}), log.New(lines, "", 0), AccessLogText))
+	defer server.Close()
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/container/missing", nil)
+	req.Header.Set("X-Request-Id", "0123456789abcdef0123456789abcdef")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	line := nextLine(t, lines)
+	if !strings.Contains(line, " DELETE /container/missing 404 8 ") || !strings.Contains(line, "client=127.0.0.1 request=0123456789abcdef0123456789abcdef") {
+		t.Errorf("Unexpected access log entry %q", line)
+	}
+}
+
+func TestAccessLogFormat(t *testing.T) {
+	for _, format := range []AccessLogFormat{AccessLogText, AccessLogJSON} {
+		if err := format.Check(); err != nil {
+			t.Errorf("Expected %s to be a valid format: %v", format, err)
+		}
+	}
+	if err := AccessLogFormat("xml").Check(); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestAccessLogMasksSecretValues(t *testing.T) {
+	utils.AddSecretValue("s3cret-in-path")
+	lines := make(lineWriter, 1)
+	server := httptest.NewServer(AccessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}), log.New(utils.NewMaskingWriter(lines), "", 0), AccessLogText))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/token/s3cret-in-path")
+	if err != nil {
+		t.Fatalf("Unable to make request: %v", err)
+	}
+	resp.Body.Close()
+	if line := nextLine(t, lines); strings.Contains(line, "s3cret-in-path") || !strings.Contains(line, " /token/*** 404 ") {
+		t.Errorf("Expected the secret to be masked in the access log, got %q", line)
+	}
+}
@@ -0,0 +1,195 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/go-json-rest"
+)
+
+// A job may be run asynchronously by passing async=true in the query or
+// a "Prefer: respond-async" header.  The server queues the job and replies
+// immediately with 202 Accepted and a Location of /jobs/<request id>,
+// where the outcome of the job can be retrieved once it has completed.
+type JobState string
+
+const (
+	JobStatePending   JobState = "pending"
+	JobStateCompleted JobState = "completed"
+)
+
+// The result of cancelling a job
+type JobCancellation struct {
+	Id string
+	// The state of the job when it was cancelled
+	State dispatcher.JobState
+}
+
+type JobStatus struct {
+	Id    string
+	State JobState
+	// The status code the job would have returned if run synchronously
+	StatusCode int `json:",omitempty"`
+	// Values reported by the job before completion, such as port mappings
+	Headers map[string]string `json:",omitempty"`
+	// The output of the job
+	Body string `json:",omitempty"`
+}
+
+func JobStatusPath(id jobs.RequestIdentifier) string {
+	return "/jobs/" + id.String()
+}
+
+func isAsyncRequest(r *http.Request) bool {
+	if r.URL.Query().Get("async") == "true" {
+		return true
+	}
+	for _, value := range r.Header["Prefer"] {
+		for _, preference := range strings.Split(value, ",") {
+			if strings.TrimSpace(preference) == "respond-async" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The outcome of recently queued asynchronous jobs, keyed by request id.
+// Old jobs are forgotten once the store is full.
+type jobStatusStore struct {
+	jobs *dispatcher.RequestIdentifierMap
+}
+
+func newJobStatusStore(size int) *jobStatusStore {
+	return &jobStatusStore{dispatcher.NewRequestIdentifierMap(size)}
+}
+
+func (s *jobStatusStore) Add(id jobs.RequestIdentifier, job *asyncJob) {
+	s.jobs.Put(id, job)
+}
+
+func (s *jobStatusStore) Get(id jobs.RequestIdentifier) (JobStatus, bool) {
+	job, ok := s.jobs.Get(id).(*asyncJob)
+	if !ok {
+		return JobStatus{}, false
+	}
+	return job.Status(), true
+}
+
+// Records the response of a job run asynchronously.
+type asyncJob struct {
+	id       jobs.RequestIdentifier
+	recorder *responseRecorder
+
+	lock      sync.Mutex
+	completed bool
+}
+
+func newAsyncJob(id jobs.RequestIdentifier) *asyncJob {
+	return &asyncJob{id: id, recorder: &responseRecorder{header: make(http.Header)}}
+}
+
+// The response the job writes its result to.
+func (j *asyncJob) Response(mode ResponseContentMode) jobs.Response {
+	return NewHttpJobResponse(j.recorder, false, mode)
+}
+
+func (j *asyncJob) Complete() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.completed = true
+}
+
+func (j *asyncJob) Status() JobStatus {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	status := JobStatus{Id: j.id.String(), State: JobStatePending}
+	if !j.completed {
+		return status
+	}
+	status.State = JobStateCompleted
+	status.StatusCode = j.recorder.code
+	status.Body = j.recorder.body.String()
+	for key := range j.recorder.header {
+		if strings.HasPrefix(key, "X-") {
+			if status.Headers == nil {
+				status.Headers = make(map[string]string)
+			}
+			status.Headers[key[2:]] = j.recorder.header.Get(key)
+		}
+	}
+	return status
+}
+
+// The most output retained for an asynchronous job
+const asyncJobOutputLimit = 1024 * 1024
+
+// Captures the response of a job in memory.  Only written to while the
+// job runs, and only read once it has completed.
+type responseRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	if remaining := asyncJobOutputLimit - r.body.Len(); remaining < len(b) {
+		r.body.Write(b[:remaining])
+		return len(b), nil
+	}
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (conf *HttpConfiguration) handleJobStatus(w *rest.ResponseWriter, r *rest.Request) {
+	id, err := jobs.NewRequestIdentifierFromString(r.PathParam("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status, ok := conf.jobStatus.Get(id)
+	if !ok {
+		http.Error(w, "No job with that id is known", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if status.State == JobStatePending {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(&status)
+}
+
+func (conf *HttpConfiguration) handleCancelJob(w *rest.ResponseWriter, r *rest.Request) {
+	if conf.rejectChanges(w.ResponseWriter, r.Request) {
+		return
+	}
+	id, err := jobs.NewRequestIdentifierFromString(r.PathParam("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	state, err := conf.Dispatcher.Cancel(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&JobCancellation{id.String(), state})
+}
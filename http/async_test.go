@@ -0,0 +1,181 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/transport"
+	"github.com/openshift/go-json-rest"
+)
+
+// A job that does not complete until released.
+type asyncTestRequest struct {
+	release chan struct{}
+	input   bool
+}
+
+func (r *asyncTestRequest) StreamsInput() bool { return r.input }
+
+func (r *asyncTestRequest) Execute(resp jobs.Response) {
+	<-r.release
+	resp.WritePendingSuccess("Result", StringHeader("released"))
+	w := resp.SuccessWithWrite(jobs.ResponseOk, false, false)
+	fmt.Fprint(w, "job output")
+}
+
+// The request served by the test route
+var asyncTestCurrent *asyncTestRequest
+var registerAsyncTest sync.Once
+
+type asyncTestHandler struct{}
+
+func (h asyncTestHandler) HttpMethod() string { return "PUT" }
+func (h asyncTestHandler) HttpPath() string   { return "/test/async" }
+func (h asyncTestHandler) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		return asyncTestCurrent, nil
+	}
+}
+
+type asyncTestExtension struct{}
+
+func (e asyncTestExtension) Routes() []HttpJobHandler {
+	return []HttpJobHandler{asyncTestHandler{}}
+}
+func (e asyncTestExtension) HttpJobFor(request interface{}) (RemoteExecutable, error) {
+	return nil, jobs.ErrNoJobForRequest
+}
+
+func asyncServer(t *testing.T) (*httptest.Server, *asyncTestRequest) {
+	registerAsyncTest.Do(func() {
+		AddHttpExtension(asyncTestExtension{})
+		jobs.AddJobExtension(jobs.JobExtensionFunc(func(r interface{}) (jobs.Job, error) {
+			if job, ok := r.(*asyncTestRequest); ok {
+				return job, nil
+			}
+			return nil, jobs.ErrNoJobForRequest
+		}))
+	})
+	request := &asyncTestRequest{release: make(chan struct{})}
+	asyncTestCurrent = request
+
+	d := &dispatcher.Dispatcher{QueueFast: 1, QueueSlow: 1, Concurrent: 1, TrackDuplicateIds: 10}
+	d.Start()
+	conf := &HttpConfiguration{Dispatcher: d}
+	handler, err := conf.Handler()
+	if err != nil {
+		t.Fatalf("Unable to create handler: %v", err)
+	}
+	return httptest.NewServer(handler), request
+}
+
+func getJobStatus(t *testing.T, url string) (int, JobStatus) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Unable to read job status: %v", err)
+	}
+	defer resp.Body.Close()
+	status := JobStatus{}
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted {
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("Unable to decode job status: %v", err)
+		}
+	}
+	return resp.StatusCode, status
+}
+
+func TestAsyncJob(t *testing.T) {
+	server, request := asyncServer(t)
+	defer server.Close()
+
+	id := jobs.NewRequestIdentifier()
+	req, _ := http.NewRequest("PUT", server.URL+"/test/async?async=true", nil)
+	req.Header.Set("X-Request-Id", id.String())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to queue job: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected the job to be accepted, got %d", resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if location != "/jobs/"+id.String() {
+		t.Fatalf("Expected a location for the job status, got %q", location)
+	}
+
+	code, status := getJobStatus(t, server.URL+location)
+	if code != http.StatusAccepted || status.State != JobStatePending {
+		t.Errorf("Expected the job to be pending, got %d %+v", code, status)
+	}
+
+	close(request.release)
+	for i := 0; i < 100 && status.State != JobStateCompleted; i++ {
+		time.Sleep(10 * time.Millisecond)
+		code, status = getJobStatus(t, server.URL+location)
+	}
+	if code != http.StatusOK || status.State != JobStateCompleted {
+		t.Fatalf("Expected the job to complete, got %d %+v", code, status)
+	}
+	if status.Body != "job output" || status.Headers["Result"] != "released" {
+		t.Errorf("Expected the output of the job to be recorded, got %+v", status)
+	}
+
+	locator, _ := transport.NewHostLocator(strings.TrimPrefix(server.URL, "http://"))
+	remote, err := NewHttpTransport().JobStatus(locator, id)
+	if err != nil {
+		t.Fatalf("Unexpected error reading job status: %v", err)
+	}
+	if remote.State != JobStateCompleted || remote.Id != id.String() {
+		t.Errorf("Expected the transport to report the completed job, got %+v", remote)
+	}
+}
+
+func TestAsyncJobReadingBody(t *testing.T) {
+	server, request := asyncServer(t)
+	defer server.Close()
+	request.input = true
+
+	id := jobs.NewRequestIdentifier()
+	req, _ := http.NewRequest("PUT", server.URL+"/test/async?async=true", strings.NewReader("input"))
+	req.Header.Set("X-Request-Id", id.String())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to send job: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected a job that reads the body to be rejected, got %d", resp.StatusCode)
+	}
+	if code, _ := getJobStatus(t, server.URL+"/jobs/"+id.String()); code != http.StatusNotFound {
+		t.Errorf("Expected the job not to be queued, got %d", code)
+	}
+}
+
+func TestAsyncJobUnknown(t *testing.T) {
+	server, _ := asyncServer(t)
+	defer server.Close()
+
+	if code, _ := getJobStatus(t, server.URL+"/jobs/"+jobs.NewRequestIdentifier().String()); code != http.StatusNotFound {
+		t.Errorf("Expected an unknown job to be not found, got %d", code)
+	}
+}
+
+func TestIsAsyncRequest(t *testing.T) {
+	req, _ := http.NewRequest("PUT", "http://localhost/container/test", nil)
+	if isAsyncRequest(req) {
+		t.Error("Expected a plain request to be synchronous")
+	}
+	req.Header.Set("Prefer", "wait=10, respond-async")
+	if !isAsyncRequest(req) {
+		t.Error("Expected Prefer: respond-async to be asynchronous")
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var ErrNotAuthorized = errors.New("The request is not authorized")
+
+// Decides whether a request to the API may proceed.  Implementations
+// may check a static token, a token file, the identity of a client
+// certificate, or delegate to an external service.
+type Authenticator interface {
+	// Return an error if the request is not authorized
+	Authenticate(r *http.Request) error
+	// The value of the WWW-Authenticate header sent on failure
+	Challenge() string
+}
+
+// Adds credentials to outgoing requests for a server protected by a
+// matching Authenticator.
+type RequestAuthorizer interface {
+	Authorize(r *http.Request)
+}
+
+// Paths that are served without authentication
+var UnauthenticatedPaths = []string{"/healthz"}
+
+// Require requests to be authenticated before they reach handler.  Only
+// UnauthenticatedPaths are allowed through, since reads expose the
+// environment, logs, and content of containers.
+func AuthenticatedHandler(auth Authenticator, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !requiresAuthentication(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if err := auth.Authenticate(r); err != nil {
+			w.Header().Set("WWW-Authenticate", auth.Challenge())
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func requiresAuthentication(r *http.Request) bool {
+	for i := range UnauthenticatedPaths {
+		if r.URL.Path == UnauthenticatedPaths[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// A single shared secret passed as an Authorization bearer token.
+type BearerToken string
+
+func (t BearerToken) Authenticate(r *http.Request) error {
+	value := r.Header.Get("Authorization")
+	if !strings.HasPrefix(value, "Bearer ") {
+		return ErrNotAuthorized
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(value, "Bearer ")), []byte(t)) != 1 {
+		return ErrNotAuthorized
+	}
+	return nil
+}
+
+func (t BearerToken) Challenge() string {
+	return `Bearer realm="geard"`
+}
+
+func (t BearerToken) Authorize(r *http.Request) {
+	r.Header.Set("Authorization", "Bearer "+string(t))
+}
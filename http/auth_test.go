@@ -0,0 +1,104 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+)
+
+func authenticatedServer() *httptest.Server {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return httptest.NewServer(AuthenticatedHandler(BearerToken("secret"), ok))
+}
+
+func doRequest(t *testing.T, method, url string, auth RequestAuthorizer) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("Unable to create request: %v", err)
+	}
+	if auth != nil {
+		auth.Authorize(req)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to send request: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestAuthorizedRequest(t *testing.T) {
+	server := authenticatedServer()
+	defer server.Close()
+
+	resp := doRequest(t, "PUT", server.URL+"/container/test/started", BearerToken("secret"))
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected an authorized request to succeed, got %d", resp.StatusCode)
+	}
+}
+
+func TestUnauthorizedRequest(t *testing.T) {
+	server := authenticatedServer()
+	defer server.Close()
+
+	for _, auth := range []RequestAuthorizer{nil, BearerToken("wrong")} {
+		resp := doRequest(t, "PUT", server.URL+"/container/test/started", auth)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected an unauthorized request to be rejected, got %d", resp.StatusCode)
+		}
+		if resp.Header.Get("WWW-Authenticate") != `Bearer realm="geard"` {
+			t.Errorf("Expected a bearer challenge, got %q", resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestUnauthenticatedPaths(t *testing.T) {
+	server := authenticatedServer()
+	defer server.Close()
+
+	resp := doRequest(t, "GET", server.URL+"/healthz", nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected /healthz to be allowed without a token, got %d", resp.StatusCode)
+	}
+	for _, method := range []string{"GET", "HEAD"} {
+		for _, path := range []string{"/containers", "/container/test/env", "/container/test/log"} {
+			resp := doRequest(t, method, server.URL+path, nil)
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Expected %s %s to require a token, got %d", method, path, resp.StatusCode)
+			}
+		}
+	}
+}
+
+type testRemoteJob struct {
+	DefaultRequest
+}
+
+func (j *testRemoteJob) HttpMethod() string { return "PUT" }
+func (j *testRemoteJob) HttpPath() string   { return "/container/test/started" }
+
+func TestRemoteAttachesToken(t *testing.T) {
+	server := authenticatedServer()
+	defer server.Close()
+	base, _ := url.Parse(server.URL)
+
+	remote := NewHttpTransport()
+	resp := &cmd.CliJobResponse{}
+	if err := remote.ExecuteRemote(base, &testRemoteJob{}, resp); err != ErrNotAuthorized {
+		t.Fatalf("Expected a request without a token to be rejected, got %v", err)
+	}
+
+	remote.SetAuthorizer(BearerToken("secret"))
+	resp = &cmd.CliJobResponse{}
+	if err := remote.ExecuteRemote(base, &testRemoteJob{}, resp); err != nil {
+		t.Fatalf("Expected the token to be sent, got %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("Unexpected failure: %v", resp.Error)
+	}
+}
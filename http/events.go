@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/transport"
+	"github.com/openshift/go-json-rest"
+)
+
+// GET /events streams the lifecycle of the jobs run by the server as
+// Server-Sent Events, one "data:" line of JSON per dispatcher.Event, until
+// the client disconnects.  The query parameters "type" and "container"
+// select the events of a job type or container.  A client that doesn't
+// keep up misses events, which the server reports in a comment line.
+const EventsPath = "/events"
+
+// The number of events buffered for each client before they are dropped
+const eventsBufferSize = 256
+
+func (conf *HttpConfiguration) handleEvents(w *rest.ResponseWriter, r *rest.Request) {
+	if conf.Dispatcher == nil || conf.Dispatcher.Events == nil {
+		http.Error(w, "This server does not publish job events", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	filter := dispatcher.EventFilter{JobType: query.Get("type"), ContainerId: query.Get("container")}
+	subscription := conf.Dispatcher.Events.Subscribe(filter, eventsBufferSize)
+	defer subscription.Close()
+	exemptFromTimeouts(r.Request)
+
+	flusher, _ := w.ResponseWriter.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case event := <-subscription.C:
+			if dropped := subscription.Dropped(); dropped > 0 {
+				fmt.Fprintf(w, ": %d events dropped\n\n", dropped)
+			}
+			data, err := json.Marshal(&event)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Stream the job events of the server at locator that match filter to fn,
+// until the server closes the stream or fn returns an error.  Events the
+// server dropped because the client fell behind are reported to dropped.
+func (h *HttpTransport) Events(locator transport.Locator, filter dispatcher.EventFilter, fn func(dispatcher.Event) error, dropped func(string)) error {
+	if locator == transport.Local {
+		locator = transport.HostLocator("localhost")
+	}
+	baseUrl, err := urlForLocator(locator)
+	if err != nil {
+		return errors.New("The provided host is not valid '" + locator.String() + "': " + err.Error())
+	}
+	baseUrl.Path = EventsPath
+	query := baseUrl.Query()
+	if filter.JobType != "" {
+		query.Set("type", filter.JobType)
+	}
+	if filter.ContainerId != "" {
+		query.Set("container", filter.ContainerId)
+	}
+	baseUrl.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", baseUrl.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("If-Match", "api="+ApiVersion())
+	req.Header.Set("Accept", "text/event-stream")
+	if h.auth != nil {
+		h.auth.Authorize(req)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case 200:
+	case 401:
+		return ErrNotAuthorized
+	default:
+		message, _ := bufio.NewReader(io.LimitReader(resp.Body, 1024)).ReadString('\n')
+		return fmt.Errorf("remote: Unable to stream events (%d): %s", resp.StatusCode, strings.TrimSpace(message))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data: "):
+			event := dispatcher.Event{}
+			if err := json.Unmarshal([]byte(line[6:]), &event); err != nil {
+				return err
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ": ") && dropped != nil:
+			dropped(line[2:])
+		}
+	}
+	return scanner.Err()
+}
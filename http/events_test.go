@@ -0,0 +1,122 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/transport"
+)
+
+type eventTestJob struct {
+	Id   string
+	fail bool
+}
+
+func (j *eventTestJob) Execute(resp jobs.Response) {
+	if j.fail {
+		resp.Failure(jobs.NewNotFoundError("The container %s does not exist.", j.Id))
+		return
+	}
+	resp.Success(jobs.ResponseOk)
+}
+
+func TestEventsStream(t *testing.T) {
+	d := &dispatcher.Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, Events: dispatcher.NewEvents()}
+	d.Start()
+	conf := &HttpConfiguration{Dispatcher: d}
+	handler, err := conf.Handler()
+	if err != nil {
+		t.Fatalf("Unable to create handler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events?container=test-a")
+	if err != nil {
+		t.Fatalf("Unable to request events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	ids := []jobs.RequestIdentifier{}
+	for _, job := range []*eventTestJob{{Id: "test-b"}, {Id: "test-a"}, {Id: "test-a", fail: true}} {
+		id := jobs.NewRequestIdentifier()
+		done, err := d.Dispatch(id, job, NewHttpJobResponse(httptest.NewRecorder(), true, ResponseJson))
+		if err != nil {
+			t.Fatalf("Unable to queue job: %v", err)
+		}
+		<-done
+		if job.Id == "test-a" {
+			ids = append(ids, id)
+		}
+	}
+
+	r := bufio.NewReader(resp.Body)
+	expected := []struct {
+		id        jobs.RequestIdentifier
+		eventType dispatcher.EventType
+	}{
+		{ids[0], dispatcher.EventAccepted}, {ids[0], dispatcher.EventStarted}, {ids[0], dispatcher.EventCompleted},
+		{ids[1], dispatcher.EventAccepted}, {ids[1], dispatcher.EventStarted}, {ids[1], dispatcher.EventFailed},
+	}
+	for _, e := range expected {
+		name, _ := r.ReadString('\n')
+		data, _ := r.ReadString('\n')
+		r.ReadString('\n')
+		if name != fmt.Sprintf("event: %s\n", e.eventType) || !strings.HasPrefix(data, "data: ") {
+			t.Fatalf("Expected a %s event, got %q %q", e.eventType, name, data)
+		}
+		event := dispatcher.Event{}
+		if err := json.Unmarshal([]byte(data[6:]), &event); err != nil {
+			t.Fatalf("Unable to decode event %q: %v", data, err)
+		}
+		if event.Id != e.id.String() || event.Type != e.eventType || event.ContainerId != "test-a" || event.JobType != "*http.eventTestJob" {
+			t.Errorf("Expected job %s to be %s, got %+v", e.id, e.eventType, event)
+		}
+		if (event.Type == dispatcher.EventFailed) != (event.Error != "") {
+			t.Errorf("Expected only a failed event to have an error, got %+v", event)
+		}
+	}
+}
+
+func TestEventsClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != EventsPath || r.URL.Query().Get("type") != "InstallContainerRequest" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: accepted\ndata: {\"Type\":\"accepted\",\"Id\":\"1\"}\n\n")
+		fmt.Fprint(w, ": 3 events dropped\n\n")
+		fmt.Fprint(w, "event: started\ndata: {\"Type\":\"started\",\"Id\":\"1\"}\n\n")
+	}))
+	defer server.Close()
+
+	events := []dispatcher.Event{}
+	dropped := []string{}
+	locator := transport.HostLocator(strings.TrimPrefix(server.URL, "http://"))
+	err := NewHttpTransport().Events(locator, dispatcher.EventFilter{JobType: "InstallContainerRequest"}, func(e dispatcher.Event) error {
+		events = append(events, e)
+		return nil
+	}, func(message string) {
+		dropped = append(dropped, message)
+	})
+	if err != nil {
+		t.Fatalf("Unable to read events: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != dispatcher.EventAccepted || events[1].Type != dispatcher.EventStarted {
+		t.Errorf("Expected the events to be read in order, got %+v", events)
+	}
+	if len(dropped) != 1 || dropped[0] != "3 events dropped" {
+		t.Errorf("Expected the dropped events to be reported, got %v", dropped)
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Responses are compressed with gzip when the client sends
+// "Accept-Encoding: gzip".  Complete responses (200 OK) are always
+// compressed, while streamed output (202 Accepted) is only compressed if
+// the server is configured to - each flush by the job is passed through to
+// the client so streaming continues to work, but every flush costs some
+// compression.
+type gzipHandler struct {
+	http.Handler
+	streams bool
+}
+
+func (h gzipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !acceptsGzip(r) {
+		h.Handler.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	gw := &gzipResponseWriter{ResponseWriter: w, streams: h.streams}
+	defer gw.Close()
+	h.Handler.ServeHTTP(gw, r)
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header["Accept-Encoding"] {
+		for _, coding := range strings.Split(value, ",") {
+			params := strings.Split(coding, ";")
+			if strings.TrimSpace(params[0]) != "gzip" {
+				continue
+			}
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+						return false
+					}
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	streams bool
+
+	wroteHeader bool
+	gz          *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusOK || (code == http.StatusAccepted && w.streams) {
+		header := w.Header()
+		if header.Get("Content-Encoding") == "" {
+			header.Set("Content-Encoding", "gzip")
+			header.Del("Content-Length")
+			w.gz = gzip.NewWriter(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Write out everything compressed so far so the client can decode it.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Allows http.ResponseController to reach the underlying connection.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipResponseWriter) Close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// The body of a response, decompressed if the server compressed it.  The
+// gzip header is not read until the body is, so a streamed response that
+// has not written any output yet does not block the caller.
+func responseBody(resp *http.Response) io.ReadCloser {
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp.Body
+	}
+	return &gzipReadCloser{body: resp.Body}
+}
+
+type gzipReadCloser struct {
+	body io.ReadCloser
+	gz   *gzip.Reader
+}
+
+func (r *gzipReadCloser) Read(b []byte) (int, error) {
+	if r.gz == nil {
+		gz, err := gzip.NewReader(r.body)
+		if err != nil {
+			return 0, err
+		}
+		r.gz = gz
+	}
+	return r.gz.Read(b)
+}
+
+func (r *gzipReadCloser) Close() error {
+	if r.gz != nil {
+		r.gz.Close()
+	}
+	return r.body.Close()
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openshift/geard/jobs"
+)
+
+func getGzip(t *testing.T, url string) *http.Response {
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to make request: %v", err)
+	}
+	return resp
+}
+
+func TestGzipResponse(t *testing.T) {
+	data := map[string]string{"Id": "test", "State": "active"}
+	server := httptest.NewServer(gzipHandler{http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NewHttpJobResponse(w, true, ResponseJson).SuccessWithData(jobs.ResponseOk, data)
+	}), false})
+	defer server.Close()
+
+	resp := getGzip(t, server.URL)
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected the response to be compressed: %v", resp.Header)
+	}
+	body := responseBody(resp)
+	defer body.Close()
+	decoded := map[string]string{}
+	if err := json.NewDecoder(body).Decode(&decoded); err != nil {
+		t.Fatalf("Unable to decode the compressed response: %v", err)
+	}
+	if decoded["Id"] != "test" || decoded["State"] != "active" {
+		t.Errorf("Unexpected response %v", decoded)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Expected a client that did not ask for gzip to get an uncompressed response")
+	}
+}
+
+func TestGzipStreamFlushes(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(gzipHandler{http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := NewHttpJobResponse(w, false, ResponseTable).SuccessWithWrite(jobs.ResponseOk, true, false)
+		out.Write([]byte("first\n"))
+		<-release
+		out.Write([]byte("second\n"))
+	}), true})
+	defer server.Close()
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+
+	resp := getGzip(t, server.URL)
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a compressed stream, got %d %v", resp.StatusCode, resp.Header)
+	}
+	body := responseBody(resp)
+	defer body.Close()
+	r := bufio.NewReader(body)
+
+	// the first line must arrive before the job completes
+	if line, err := r.ReadString('\n'); err != nil || line != "first\n" {
+		t.Fatalf("Expected the first line to be flushed, got %q %v", line, err)
+	}
+	close(release)
+	rest, err := ioutil.ReadAll(r)
+	if err != nil || string(rest) != "second\n" {
+		t.Errorf("Expected the rest of the stream, got %q %v", string(rest), err)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for value, expected := range map[string]bool{
+		"":                    false,
+		"gzip":                true,
+		"deflate, gzip;q=0.5": true,
+		"gzip;q=0":            false,
+		"identity":            false,
+	} {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		if value != "" {
+			req.Header.Set("Accept-Encoding", value)
+		}
+		if acceptsGzip(req) != expected {
+			t.Errorf("Expected Accept-Encoding %q to accept gzip %v", value, expected)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/transport"
+	"github.com/openshift/go-json-rest"
+)
+
+// GET /jobs returns the jobs the server recently accepted as a JSON array
+// of dispatcher.JobRecord, in the order they were accepted.  The query
+// parameters "status", "type", and "since" (an RFC 3339 time) select jobs
+// by their last event, job type, and when they were accepted.
+const JobHistoryPath = "/jobs"
+
+func (conf *HttpConfiguration) handleJobHistory(w *rest.ResponseWriter, r *rest.Request) {
+	if conf.Dispatcher == nil || conf.Dispatcher.History == nil {
+		http.Error(w, "This server does not record job history", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	filter := dispatcher.HistoryFilter{Status: dispatcher.EventType(query.Get("status")), JobType: query.Get("type")}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "The since parameter must be an RFC 3339 time, such as 2014-05-01T12:00:00Z", http.StatusBadRequest)
+			return
+		}
+		filter.Since = t
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conf.Dispatcher.History.List(filter))
+}
+
+// The jobs recently accepted by the server at locator that match filter.
+func (h *HttpTransport) JobHistory(locator transport.Locator, filter dispatcher.HistoryFilter) ([]dispatcher.JobRecord, error) {
+	if locator == transport.Local {
+		locator = transport.HostLocator("localhost")
+	}
+	baseUrl, err := urlForLocator(locator)
+	if err != nil {
+		return nil, errors.New("The provided host is not valid '" + locator.String() + "': " + err.Error())
+	}
+	baseUrl.Path = JobHistoryPath
+	query := baseUrl.Query()
+	if filter.Status != "" {
+		query.Set("status", string(filter.Status))
+	}
+	if filter.JobType != "" {
+		query.Set("type", filter.JobType)
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.UTC().Format(time.RFC3339))
+	}
+	baseUrl.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", baseUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("If-Match", "api="+ApiVersion())
+	req.Header.Set("Accept", "application/json")
+	if h.auth != nil {
+		h.auth.Authorize(req)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case 200:
+	case 401:
+		return nil, ErrNotAuthorized
+	default:
+		message, _ := bufio.NewReader(io.LimitReader(resp.Body, 1024)).ReadString('\n')
+		return nil, fmt.Errorf("remote: Unable to list jobs (%d): %s", resp.StatusCode, strings.TrimSpace(message))
+	}
+
+	records := []dispatcher.JobRecord{}
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/transport"
+)
+
+func TestJobHistory(t *testing.T) {
+	d := &dispatcher.Dispatcher{QueueFast: 1, QueueSlow: 2, Concurrent: 1, TrackDuplicateIds: 10, History: dispatcher.NewHistory(10, time.Hour)}
+	d.Start()
+	conf := &HttpConfiguration{Dispatcher: d}
+	handler, err := conf.Handler()
+	if err != nil {
+		t.Fatalf("Unable to create handler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ids := []jobs.RequestIdentifier{}
+	for _, job := range []*eventTestJob{{Id: "test-a"}, {Id: "test-b", fail: true}} {
+		id := jobs.NewRequestIdentifier()
+		done, err := d.Dispatch(id, job, NewHttpJobResponse(httptest.NewRecorder(), true, ResponseJson))
+		if err != nil {
+			t.Fatalf("Unable to queue job: %v", err)
+		}
+		<-done
+		ids = append(ids, id)
+	}
+
+	remote := NewHttpTransport()
+	locator := transport.HostLocator(strings.TrimPrefix(server.URL, "http://"))
+	records, err := remote.JobHistory(locator, dispatcher.HistoryFilter{})
+	if err != nil {
+		t.Fatalf("Unable to list jobs: %v", err)
+	}
+	if len(records) != 2 || records[0].Id != ids[0].String() || records[0].Status != dispatcher.EventCompleted || records[1].ContainerId != "test-b" {
+		t.Errorf("Expected both jobs to be listed, got %+v", records)
+	}
+
+	records, err = remote.JobHistory(locator, dispatcher.HistoryFilter{Status: dispatcher.EventFailed, JobType: "eventTestJob", Since: time.Now().Add(-time.Minute)})
+	if err != nil || len(records) != 1 || records[0].Id != ids[1].String() || records[0].Error == "" {
+		t.Errorf("Expected only the failed job, got %+v %v", records, err)
+	}
+	if records, err := remote.JobHistory(locator, dispatcher.HistoryFilter{Since: time.Now().Add(time.Minute)}); err != nil || len(records) != 0 {
+		t.Errorf("Expected no jobs since a later time, got %+v %v", records, err)
+	}
+
+	resp, err := http.Get(server.URL + "/jobs?since=yesterday")
+	if err != nil {
+		t.Fatalf("Unable to list jobs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an invalid time to be rejected, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get(server.URL + JobStatusPath(ids[0]))
+	if err != nil {
+		t.Fatalf("Unable to request job status: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the status of a job that was not run asynchronously to be unknown, got %d", resp.StatusCode)
+	}
+}
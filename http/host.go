@@ -0,0 +1,104 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+)
+
+// The placeholder host that requests to a daemon on a unix socket are
+// addressed to.  The transport dials the socket instead of resolving it.
+const unixSocketHost = "unix.socket"
+
+// A daemon named by a URL rather than a host, such as tcp://host:43273,
+// https://host or unix:///var/run/geard.sock.  The port defaults to
+// DefaultHttpPort.
+type URLLocator struct {
+	value  string
+	base   url.URL
+	socket string
+}
+
+// Parse a daemon URL.  tcp:// and http:// are plain http, https:// uses
+// TLS and unix:// is the absolute path of a socket the daemon listens on.
+func NewURLLocator(value string) (*URLLocator, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/" && u.Scheme != "unix") || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("The host URL '%s' may only contain a scheme, host and port", value)
+	}
+	l := &URLLocator{value: value}
+	switch u.Scheme {
+	case "tcp", "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("The host URL '%s' must include a host", value)
+		}
+		host := u.Host
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(u.Host, DefaultHttpPort)
+		}
+		scheme := "http"
+		if u.Scheme == "https" {
+			scheme = "https"
+		}
+		l.base = url.URL{Scheme: scheme, Host: host}
+	case "unix":
+		if u.Host != "" || !filepath.IsAbs(u.Path) {
+			return nil, fmt.Errorf("The host URL '%s' must be an absolute socket path, such as unix:///var/run/geard.sock", value)
+		}
+		l.socket = filepath.Clean(u.Path)
+		l.base = url.URL{Scheme: "http", Host: unixSocketHost}
+	default:
+		return nil, errors.New("The host URL must start with tcp://, https:// or unix://")
+	}
+	return l, nil
+}
+
+func (l *URLLocator) String() string {
+	return l.value
+}
+
+func (l *URLLocator) ResolveHostname() (string, error) {
+	if l.socket != "" {
+		return "localhost", nil
+	}
+	host, _, err := net.SplitHostPort(l.base.Host)
+	return host, err
+}
+
+// The base URL requests to the daemon are sent to.
+func (l *URLLocator) ToURL() *url.URL {
+	u := l.base
+	return &u
+}
+
+// The unix socket the daemon listens on, or empty if it is reached over
+// the network.
+func (l *URLLocator) Socket() string {
+	return l.socket
+}
+
+// Send requests for the local server, and for ids without a host, to
+// the daemon at l instead.  Ids that name a host are still sent to that
+// host.
+func (h *HttpTransport) SetDefaultHost(l *URLLocator) {
+	h.host = l
+	if l == nil || l.socket == "" {
+		h.client = &http.Client{}
+		return
+	}
+	socket := l.socket
+	h.client = &http.Client{Transport: &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			if addr == net.JoinHostPort(unixSocketHost, "80") {
+				return net.Dial("unix", socket)
+			}
+			return net.Dial(network, addr)
+		},
+	}}
+}
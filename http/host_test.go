@@ -0,0 +1,128 @@
+package http
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/transport"
+)
+
+func TestURLLocatorSchemes(t *testing.T) {
+	for value, expected := range map[string]string{
+		"tcp://example.com":          "http://example.com:43273",
+		"tcp://example.com:8080":     "http://example.com:8080",
+		"http://10.0.0.1/":           "http://10.0.0.1:43273",
+		"https://example.com":        "https://example.com:43273",
+		"https://example.com:8443":   "https://example.com:8443",
+		"unix:///var/run/geard.sock": "http://" + unixSocketHost,
+	} {
+		l, err := NewURLLocator(value)
+		if err != nil {
+			t.Errorf("Expected %s to be a valid host: %v", value, err)
+			continue
+		}
+		u, err := urlForLocator(l)
+		if err != nil || u.String() != expected {
+			t.Errorf("Expected %s to be reached at %s, got %v %v", value, expected, u, err)
+		}
+	}
+
+	for _, value := range []string{
+		"example.com",
+		"ftp://example.com",
+		"tcp://",
+		"tcp://example.com/path",
+		"https://user@example.com",
+		"unix://relative.sock",
+		"unix:///var/run/geard.sock?x=1",
+	} {
+		if _, err := NewURLLocator(value); err == nil {
+			t.Errorf("Expected %s to be rejected", value)
+		}
+	}
+}
+
+func TestDefaultHostPrecedence(t *testing.T) {
+	h := NewHttpTransport()
+	l, err := NewURLLocator("https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locator, _ := h.LocatorFor(""); locator != transport.Local {
+		t.Errorf("Expected no host to be local without a default, got %v", locator)
+	}
+
+	h.SetDefaultHost(l)
+	for _, value := range []string{"", transport.Local.String()} {
+		if locator, _ := h.LocatorFor(value); locator != l {
+			t.Errorf("Expected %q to use the default host, got %v", value, locator)
+		}
+	}
+	if locator, _ := h.LocatorFor("other:8080"); locator != transport.HostLocator("other:8080") {
+		t.Errorf("Expected a named host to take precedence, got %v", locator)
+	}
+
+	h.SetDefaultHost(nil)
+	if locator, _ := h.LocatorFor(""); locator != transport.Local {
+		t.Errorf("Expected clearing the default to restore the local server, got %v", locator)
+	}
+}
+
+func TestDefaultHostUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geard-host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "geard.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	l, err := NewURLLocator("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Socket() != socket {
+		t.Errorf("Expected the socket %s, got %s", socket, l.Socket())
+	}
+	h := NewHttpTransport()
+	h.SetDefaultHost(l)
+	locator, err := h.LocatorFor("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Ping(locator, time.Second); err != nil {
+		t.Errorf("Expected the daemon to be reached over the socket: %v", err)
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	unreachable := listener.Addr().String()
+	listener.Close()
+
+	_, err = NewHttpTransport().client.Get("http://" + unreachable)
+	if !IsUnreachable(err) {
+		t.Errorf("Expected a refused connection to be unreachable: %v", err)
+	}
+	if IsUnreachable(ErrNotAuthorized) {
+		t.Error("Expected a failed request not to be unreachable")
+	}
+}
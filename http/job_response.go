@@ -13,6 +13,10 @@ var (
 	ErrContentTypeDoesNotMatch = jobs.SimpleError{jobs.ResponseNotAcceptable, "The content type you requested is not available for this action."}
 )
 
+// Pending values with these names are sent as the standard HTTP header
+// rather than "x-<name>".
+var standardPendingHeaders = map[string]bool{"ETag": true}
+
 type ResponseContentMode int
 
 const (
@@ -95,7 +99,11 @@ func (s *httpJobResponse) success(t jobs.ResponseSuccess, stream, data bool) {
 	if s.pending != nil {
 		header := s.response.Header()
 		for key := range s.pending {
-			header.Add("x-"+key, s.pending[key])
+			if standardPendingHeaders[key] {
+				header.Set(key, s.pending[key])
+			} else {
+				header.Add("x-"+key, s.pending[key])
+			}
 		}
 		s.pending = nil
 	}
@@ -105,6 +113,8 @@ func (s *httpJobResponse) success(t jobs.ResponseSuccess, stream, data bool) {
 
 func (s *httpJobResponse) statusCode(t jobs.ResponseSuccess, stream, data bool) int {
 	switch {
+	case t == jobs.ResponseNotModified:
+		return http.StatusNotModified
 	case stream:
 		return http.StatusAccepted
 	case data:
@@ -125,6 +135,11 @@ func (s *httpJobResponse) WritePendingSuccess(name string, value interface{}) {
 	}
 }
 
+// Trailers are sent after the body of a streaming response as "X-<name>".
+func (s *httpJobResponse) WriteTrailer(name string, value string) {
+	s.response.Header().Set(http.TrailerPrefix+"X-"+name, value)
+}
+
 func (s *httpJobResponse) Failure(err error) {
 	if s.succeeded {
 		panic("May not invoke failure after Success()")
@@ -134,31 +149,67 @@ func (s *httpJobResponse) Failure(err error) {
 	}
 	s.failed = true
 
-	code := http.StatusInternalServerError
-	response := httpFailureResponse{err.Error(), nil}
+	code := StatusCodeFor(jobs.FailureFor(err))
+	response := httpFailureResponse{utils.MaskSecrets(err.Error()), nil}
 	s.response.Header().Set("Content-Type", "application/json")
 
 	if e, ok := err.(jobs.JobError); ok {
 		response.Data = e.ResponseData()
-
-		switch e.ResponseFailure() {
-		case jobs.ResponseAlreadyExists:
-			code = http.StatusConflict
-		case jobs.ResponseNotFound:
-			code = http.StatusNotFound
-		case jobs.ResponseInvalidRequest:
-			code = http.StatusBadRequest
-		case jobs.ResponseNotAcceptable:
-			code = http.StatusNotAcceptable
-		case jobs.ResponseRateLimit:
-			code = 429 // http.statusTooManyRequests
-		}
 	}
 
 	s.response.WriteHeader(code)
 	json.NewEncoder(s.response).Encode(&response)
 }
 
+const statusTooManyRequests = 429
+
+// The HTTP status code returned for a failed job
+func StatusCodeFor(failure jobs.ResponseFailure) int {
+	switch failure {
+	case jobs.ResponseAlreadyExists:
+		return http.StatusConflict
+	case jobs.ResponseNotFound:
+		return http.StatusNotFound
+	case jobs.ResponseInvalidRequest:
+		return http.StatusBadRequest
+	case jobs.ResponseNotAcceptable:
+		return http.StatusNotAcceptable
+	case jobs.ResponseRateLimit:
+		return statusTooManyRequests
+	case jobs.ResponseUnavailable:
+		return http.StatusServiceUnavailable
+	case jobs.ResponseForbidden:
+		return http.StatusForbidden
+	case jobs.ResponseTooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// The kind of failure reported by an HTTP status code, the inverse of
+// StatusCodeFor.
+func FailureForStatusCode(code int) jobs.ResponseFailure {
+	switch code {
+	case http.StatusConflict:
+		return jobs.ResponseAlreadyExists
+	case http.StatusNotFound:
+		return jobs.ResponseNotFound
+	case http.StatusBadRequest:
+		return jobs.ResponseInvalidRequest
+	case http.StatusNotAcceptable:
+		return jobs.ResponseNotAcceptable
+	case statusTooManyRequests:
+		return jobs.ResponseRateLimit
+	case http.StatusServiceUnavailable:
+		return jobs.ResponseUnavailable
+	case http.StatusForbidden:
+		return jobs.ResponseForbidden
+	case http.StatusRequestEntityTooLarge:
+		return jobs.ResponseTooLarge
+	}
+	return jobs.ResponseError
+}
+
 type httpFailureResponse struct {
 	Message string
 	Data    interface{} `json:"Data,omitempty"`
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/utils"
+)
+
+func TestFailureStatusCode(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		code int
+	}{
+		{jobs.ErrNotFound, http.StatusNotFound},
+		{jobs.ErrConflict, http.StatusConflict},
+		{jobs.ErrInvalid, http.StatusBadRequest},
+		{jobs.NewNotFoundError("No container %s", "a"), http.StatusNotFound},
+		{jobs.SimpleError{jobs.ResponseRateLimit, "slow down"}, 429},
+		{jobs.SimpleError{jobs.ResponseTooLarge, "too large"}, http.StatusRequestEntityTooLarge},
+		{jobs.SimpleError{jobs.ResponseError, "failed"}, http.StatusInternalServerError},
+		{errors.New("unknown"), http.StatusInternalServerError},
+	} {
+		w := httptest.NewRecorder()
+		NewHttpJobResponse(w, false, ResponseJson).Failure(test.err)
+		if w.Code != test.code {
+			t.Errorf("Expected %q to return %d, got %d", test.err, test.code, w.Code)
+		}
+		if failure := FailureForStatusCode(w.Code); failure != jobs.FailureFor(test.err) {
+			t.Errorf("Expected status %d to map back to the failure of %q, got %d", w.Code, test.err, failure)
+		}
+	}
+}
+
+func TestFailureMasksSecretValues(t *testing.T) {
+	utils.AddSecretValue("s3cret-in-error")
+	w := httptest.NewRecorder()
+	NewHttpJobResponse(w, false, ResponseJson).Failure(jobs.NewInvalidError("The value s3cret-in-error is not valid"))
+	if body := w.Body.String(); strings.Contains(body, "s3cret-in-error") || !strings.Contains(body, "The value *** is not valid") {
+		t.Errorf("Expected the secret to be masked in the error, got %s", body)
+	}
+}
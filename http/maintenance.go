@@ -0,0 +1,69 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/jobs"
+)
+
+var (
+	ErrMaintenanceMode = jobs.SimpleError{jobs.ResponseUnavailable, "The server is in maintenance mode and is not accepting changes, try again later."}
+	ErrReadOnly        = jobs.SimpleError{jobs.ResponseForbidden, "The server is read-only and does not accept changes."}
+)
+
+// While a server is in maintenance mode it rejects jobs that change state
+// with 503 Service Unavailable.  Reads continue to be served, and jobs that
+// are already running are allowed to complete.  The mode is recorded as a
+// file so that it may be changed while the server runs, and is kept when
+// the server restarts.
+type MaintenanceMode struct {
+	Path string
+}
+
+// The maintenance mode of the server for the current container base path
+func DefaultMaintenanceMode() *MaintenanceMode {
+	return &MaintenanceMode{filepath.Join(config.ContainerBasePath(), "maintenance")}
+}
+
+func (m *MaintenanceMode) Enabled() bool {
+	_, err := os.Stat(m.Path)
+	return err == nil
+}
+
+func (m *MaintenanceMode) Set(enabled bool) error {
+	if !enabled {
+		if err := os.Remove(m.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return ioutil.WriteFile(m.Path, []byte{}, 0644)
+}
+
+// Requests that only read state are allowed during maintenance and on a
+// read-only server
+func isMutatingMethod(method string) bool {
+	return method != "GET" && method != "HEAD"
+}
+
+func (conf *HttpConfiguration) inMaintenance(r *http.Request) bool {
+	return conf.Maintenance != nil && isMutatingMethod(r.Method) && conf.Maintenance.Enabled()
+}
+
+// Fail a request that changes state on a read-only server or during
+// maintenance, returning true if it was rejected.
+func (conf *HttpConfiguration) rejectChanges(w http.ResponseWriter, r *http.Request) bool {
+	if conf.ReadOnly && isMutatingMethod(r.Method) {
+		NewHttpJobResponse(w, true, ResponseJson).Failure(ErrReadOnly)
+		return true
+	}
+	if conf.inMaintenance(r) {
+		NewHttpJobResponse(w, true, ResponseJson).Failure(ErrMaintenanceMode)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/openshift/geard/dispatcher"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/go-json-rest"
+)
+
+type maintenanceTestRequest struct{}
+
+func (r *maintenanceTestRequest) Execute(resp jobs.Response) {
+	resp.SuccessWithData(jobs.ResponseOk, map[string]string{"State": "ok"})
+}
+
+type maintenanceTestHandler struct {
+	method string
+}
+
+func (h maintenanceTestHandler) HttpMethod() string { return h.method }
+func (h maintenanceTestHandler) HttpPath() string   { return "/test/maintenance" }
+func (h maintenanceTestHandler) Handler(conf *HttpConfiguration) JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		return &maintenanceTestRequest{}, nil
+	}
+}
+
+type maintenanceTestExtension struct{}
+
+func (e maintenanceTestExtension) Routes() []HttpJobHandler {
+	return []HttpJobHandler{maintenanceTestHandler{"GET"}, maintenanceTestHandler{"PUT"}}
+}
+func (e maintenanceTestExtension) HttpJobFor(request interface{}) (RemoteExecutable, error) {
+	return nil, jobs.ErrNoJobForRequest
+}
+
+var registerMaintenanceTest sync.Once
+
+func maintenanceServer(t *testing.T, conf *HttpConfiguration) *httptest.Server {
+	registerMaintenanceTest.Do(func() {
+		AddHttpExtension(maintenanceTestExtension{})
+		jobs.AddJobExtension(jobs.JobExtensionFunc(func(r interface{}) (jobs.Job, error) {
+			if job, ok := r.(*maintenanceTestRequest); ok {
+				return job, nil
+			}
+			return nil, jobs.ErrNoJobForRequest
+		}))
+	})
+	d := &dispatcher.Dispatcher{QueueFast: 1, QueueSlow: 1, Concurrent: 1, TrackDuplicateIds: 10}
+	d.Start()
+	conf.Dispatcher = d
+	handler, err := conf.Handler()
+	if err != nil {
+		t.Fatalf("Unable to create handler: %v", err)
+	}
+	return httptest.NewServer(handler)
+}
+
+func maintenanceRequest(t *testing.T, method, url string) (int, string) {
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("X-Request-Id", jobs.NewRequestIdentifier().String())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unable to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	failure := httpFailureResponse{}
+	if resp.StatusCode >= 400 {
+		json.NewDecoder(resp.Body).Decode(&failure)
+	}
+	return resp.StatusCode, failure.Message
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maintenance")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	mode := &MaintenanceMode{filepath.Join(dir, "maintenance")}
+
+	server := maintenanceServer(t, &HttpConfiguration{Maintenance: mode})
+	defer server.Close()
+	url := server.URL + "/test/maintenance"
+
+	if code, _ := maintenanceRequest(t, "PUT", url); code != http.StatusOK {
+		t.Errorf("Expected a change to be allowed outside maintenance, got %d", code)
+	}
+
+	if err := mode.Set(true); err != nil {
+		t.Fatalf("Unable to enter maintenance mode: %v", err)
+	}
+	if !mode.Enabled() {
+		t.Fatal("Expected maintenance mode to be enabled")
+	}
+	if code, message := maintenanceRequest(t, "PUT", url); code != http.StatusServiceUnavailable || message != ErrMaintenanceMode.Error() {
+		t.Errorf("Expected a change to be rejected during maintenance, got %d %q", code, message)
+	}
+	if code, _ := maintenanceRequest(t, "GET", url); code != http.StatusOK {
+		t.Errorf("Expected a read to be allowed during maintenance, got %d", code)
+	}
+	if code, message := maintenanceRequest(t, "DELETE", server.URL+"/jobs/"+jobs.NewRequestIdentifier().String()); code != http.StatusServiceUnavailable || message != ErrMaintenanceMode.Error() {
+		t.Errorf("Expected cancelling a job to be rejected during maintenance, got %d %q", code, message)
+	}
+
+	if err := mode.Set(false); err != nil {
+		t.Fatalf("Unable to leave maintenance mode: %v", err)
+	}
+	if err := mode.Set(false); err != nil {
+		t.Errorf("Expected leaving maintenance mode twice to succeed, got %v", err)
+	}
+	if code, _ := maintenanceRequest(t, "PUT", url); code != http.StatusOK {
+		t.Errorf("Expected a change to be allowed after maintenance, got %d", code)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	server := maintenanceServer(t, &HttpConfiguration{ReadOnly: true})
+	defer server.Close()
+	url := server.URL + "/test/maintenance"
+
+	if code, message := maintenanceRequest(t, "PUT", url); code != http.StatusForbidden || message != ErrReadOnly.Error() {
+		t.Errorf("Expected a change to be rejected on a read-only server, got %d %q", code, message)
+	}
+	if code, message := maintenanceRequest(t, "DELETE", server.URL+"/jobs/"+jobs.NewRequestIdentifier().String()); code != http.StatusForbidden || message != ErrReadOnly.Error() {
+		t.Errorf("Expected cancelling a job to be rejected on a read-only server, got %d %q", code, message)
+	}
+	if code, _ := maintenanceRequest(t, "GET", url); code != http.StatusOK {
+		t.Errorf("Expected a read to be allowed on a read-only server, got %d", code)
+	}
+}
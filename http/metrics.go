@@ -0,0 +1,83 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// A metric served by the /metrics endpoint, a Counter or a Gauge.
+type Metric interface {
+	writeMetric(w io.Writer)
+}
+
+// A counter served by the /metrics endpoint.
+type Counter struct {
+	Name  string
+	Help  string
+	Value func() uint64
+}
+
+func (c Counter) writeMetric(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.Name, c.Help, c.Name, c.Name, c.Value())
+}
+
+// A label of a sample, such as the id of the container it describes.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// A value of a gauge, told apart from its other values by its labels.
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// A gauge served by the /metrics endpoint, whose samples are read on each
+// scrape.
+type Gauge struct {
+	Name    string
+	Help    string
+	Samples func() []Sample
+}
+
+var labelValueEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func (g Gauge) writeMetric(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.Name, g.Help, g.Name)
+	for _, s := range g.Samples() {
+		labels := make([]string, len(s.Labels))
+		for i, l := range s.Labels {
+			labels[i] = fmt.Sprintf("%s=\"%s\"", l.Name, labelValueEscaper.Replace(l.Value))
+		}
+		name := g.Name
+		if len(labels) > 0 {
+			name += "{" + strings.Join(labels, ",") + "}"
+		}
+		fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(s.Value, 'f', -1, 64))
+	}
+}
+
+// Serve metrics in the Prometheus text format, so that a server can be
+// scraped or checked with curl.
+func MetricsHandler(metrics ...Metric) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, m := range metrics {
+			m.writeMetric(w)
+		}
+	})
+}
+
+// Serve metrics behind the same authentication as the API, since the
+// gauges of containers include their ids, images, and ports.
+func (conf *HttpConfiguration) MetricsHandler(metrics ...Metric) http.Handler {
+	handler := MetricsHandler(metrics...)
+	if conf.Auth != nil {
+		return AuthenticatedHandler(conf.Auth, handler)
+	}
+	return handler
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	failures := uint64(2)
+	handler := MetricsHandler(Counter{"geard_test_failures_total", "Failures counted by the test", func() uint64 { return failures }})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	expected := "# HELP geard_test_failures_total Failures counted by the test\n# TYPE geard_test_failures_total counter\ngeard_test_failures_total 2\n"
+	if w.Body.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected a text response, got %s", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestMetricsHandlerGauge(t *testing.T) {
+	handler := MetricsHandler(Gauge{"geard_test_memory_bytes", "Memory measured by the test", func() []Sample {
+		return []Sample{
+			{[]Label{{"id", "test-web"}, {"image", `quoted "image"\path`}}, 1048576},
+			{nil, 0.5},
+		}
+	}})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	expected := "# HELP geard_test_memory_bytes Memory measured by the test\n# TYPE geard_test_memory_bytes gauge\n" +
+		"geard_test_memory_bytes{id=\"test-web\",image=\"quoted \\\"image\\\"\\\\path\"} 1048576\n" +
+		"geard_test_memory_bytes 0.5\n"
+	if w.Body.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, w.Body.String())
+	}
+}
+
+func TestMetricsHandlerAuthenticated(t *testing.T) {
+	conf := &HttpConfiguration{Auth: BearerToken("secret")}
+	server := httptest.NewServer(conf.MetricsHandler(Counter{"geard_test_failures_total", "Failures counted by the test", func() uint64 { return 0 }}))
+	defer server.Close()
+
+	resp := doRequest(t, "GET", server.URL+"/metrics", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected metrics without a token to be rejected, got %d", resp.StatusCode)
+	}
+	resp = doRequest(t, "GET", server.URL+"/metrics", BearerToken("secret"))
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected metrics with a token to be served, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/geard/transport"
+)
+
+// Whether a daemon answered its health check, and how long it took.
+type PingResult struct {
+	Server  string
+	Up      bool
+	Latency time.Duration `json:"-"`
+	// The latency in milliseconds
+	LatencyMs float64 `json:"LatencyMs,omitempty"`
+	Error     string  `json:"Error,omitempty"`
+}
+
+type PingResults []PingResult
+
+// Request /healthz from the daemon at locator, and return the round trip
+// time.  The local locator is checked on localhost.
+func (h *HttpTransport) Ping(locator transport.Locator, timeout time.Duration) (time.Duration, error) {
+	if locator == transport.Local {
+		locator = transport.HostLocator("localhost")
+	}
+	base, err := urlForLocator(locator)
+	if err != nil {
+		return 0, err
+	}
+	base.Path = "/healthz"
+
+	client := &http.Client{Transport: h.client.Transport, Timeout: timeout}
+	start := time.Now()
+	resp, err := client.Get(base.String())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	latency := time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		return latency, fmt.Errorf("health check returned %s", resp.Status)
+	}
+	return latency, nil
+}
+
+// Ping each locator concurrently, returning the results in the same
+// order.
+func (h *HttpTransport) PingAll(locators []transport.Locator, timeout time.Duration) PingResults {
+	results := make(PingResults, len(locators))
+	var wg sync.WaitGroup
+	for i := range locators {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result := PingResult{Server: locators[i].String()}
+			latency, err := h.Ping(locators[i], timeout)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Up = true
+				result.Latency = latency
+				result.LatencyMs = float64(latency) / float64(time.Millisecond)
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// The number of servers that did not answer
+func (r PingResults) Down() int {
+	down := 0
+	for i := range r {
+		if !r[i].Up {
+			down++
+		}
+	}
+	return down
+}
+
+func (r PingResults) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 8, 4, 1, ' ', tabwriter.DiscardEmptyColumns)
+	if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", "SERVER", "STATUS", "LATENCY", "ERROR"); err != nil {
+		return err
+	}
+	for i := range r {
+		status, latency, reason := "down", "-", r[i].Error
+		if r[i].Up {
+			status, latency, reason = "up", (r[i].Latency / time.Microsecond * time.Microsecond).String(), "-"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r[i].Server, status, latency, reason); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/geard/transport"
+)
+
+func TestPingAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			t.Errorf("Expected the health endpoint to be requested, got %s", r.URL.Path)
+		}
+		w.Write([]byte("ok\n"))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	// a port that was listening, but no longer is
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	unreachable := listener.Addr().String()
+	listener.Close()
+
+	results := NewHttpTransport().PingAll([]transport.Locator{transport.HostLocator(u.Host), transport.HostLocator(unreachable)}, time.Second)
+	if len(results) != 2 {
+		t.Fatalf("Expected a result for each server, got %+v", results)
+	}
+	if up := results[0]; up.Server != u.Host || !up.Up || up.Latency <= 0 || up.Error != "" {
+		t.Errorf("Expected %s to be up, got %+v", u.Host, up)
+	}
+	if down := results[1]; down.Server != unreachable || down.Up || down.Error == "" {
+		t.Errorf("Expected %s to be down, got %+v", unreachable, down)
+	}
+	if results.Down() != 1 {
+		t.Errorf("Expected one server to be down, got %d", results.Down())
+	}
+
+	buf := &bytes.Buffer{}
+	results.WriteTableTo(buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], " up ") || !strings.Contains(lines[2], " down ") {
+		t.Errorf("Expected a row for each server:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The PROXY protocol allows a load balancer terminating TCP connections
+// to pass the address of the original client to the server as a header
+// at the start of the connection.  Both the text (v1) and binary (v2)
+// forms are supported.
+//
+// See http://www.haproxy.org/download/1.5/doc/proxy-protocol.txt
+var (
+	ErrProxyProtocolHeaderMissing = errors.New("proxy protocol: connection did not start with a PROXY header")
+	ErrProxyProtocolHeaderInvalid = errors.New("proxy protocol: the PROXY header is not valid")
+)
+
+const proxyV1MaxLength = 107
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// How long a connection may take to send its PROXY header when no other
+// limit is given.  A proxy sends the header as soon as it connects.
+const DefaultProxyHeaderTimeout = 10 * time.Second
+
+// Wrap a listener so that every accepted connection must begin with a
+// PROXY protocol header, sent within timeout (or within
+// DefaultProxyHeaderTimeout if timeout is zero).  The address from the
+// header is reported as the remote address of the connection.  Any other
+// listener (such as TLS) may be layered on top of the returned listener.
+func NewProxyProtocolListener(l net.Listener, timeout time.Duration) net.Listener {
+	if timeout <= 0 {
+		timeout = DefaultProxyHeaderTimeout
+	}
+	return &proxyListener{l, timeout}
+}
+
+type proxyListener struct {
+	net.Listener
+	timeout time.Duration
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{Conn: conn, r: bufio.NewReaderSize(conn, 256), timeout: l.timeout}, nil
+}
+
+// The header is read lazily on the first use of the connection so that
+// a slow client does not block Accept.  net/http asks for the remote
+// address before it sets any deadline of its own, so the header is read
+// under a deadline of the listener's, which is then cleared.
+type proxyConn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	remote net.Addr
+	err    error
+}
+
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		c.remote, c.err = readProxyHeader(c.r)
+		if c.err != nil {
+			c.Conn.Close()
+			return
+		}
+		c.Conn.SetReadDeadline(time.Time{})
+	})
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// Read a PROXY header from r, returning the client address it describes.
+// A nil address is returned if the header does not carry a client address
+// (a health check from the proxy itself, for instance).
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	prefix, err := r.Peek(len(proxyV2Signature))
+	if err == nil && bytes.Equal(prefix, proxyV2Signature) {
+		return readProxyV2Header(r)
+	}
+	if len(prefix) >= 6 && string(prefix[:6]) == "PROXY " {
+		return readProxyV1Header(r)
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return nil, ErrProxyProtocolHeaderMissing
+}
+
+func readProxyV1Header(r *bufio.Reader) (net.Addr, error) {
+	line := make([]byte, 0, proxyV1MaxLength)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= proxyV1MaxLength {
+			return nil, ErrProxyProtocolHeaderInvalid
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrProxyProtocolHeaderInvalid
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrProxyProtocolHeaderInvalid
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil || net.ParseIP(fields[3]) == nil {
+		return nil, ErrProxyProtocolHeaderInvalid
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, ErrProxyProtocolHeaderInvalid
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func readProxyV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, ErrProxyProtocolHeaderInvalid
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	switch header[12] & 0x0f {
+	case 0x0: // LOCAL, sent by the proxy on its own behalf
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, ErrProxyProtocolHeaderInvalid
+	}
+
+	switch header[13] >> 4 {
+	case 0x1: // AF_INET
+		if len(body) < 12 {
+			return nil, ErrProxyProtocolHeaderInvalid
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:10]))}, nil
+	case 0x2: // AF_INET6
+		if len(body) < 36 {
+			return nil, ErrProxyProtocolHeaderInvalid
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}, nil
+	}
+	// other address families carry no usable client address
+	return nil, nil
+}
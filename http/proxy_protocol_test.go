@@ -0,0 +1,129 @@
+package http
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func serveRemoteAddr(t *testing.T) (net.Listener, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	go http.Serve(NewProxyProtocolListener(l, 0), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.RemoteAddr)
+	}))
+	return l, l.Addr().String()
+}
+
+func requestWithHeader(t *testing.T, addr string, header []byte) (string, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.Write(header)
+	fmt.Fprint(conn, "GET / HTTP/1.0\r\n\r\n")
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestProxyProtocolV1(t *testing.T) {
+	l, addr := serveRemoteAddr(t)
+	defer l.Close()
+
+	remote, err := requestWithHeader(t, addr, []byte("PROXY TCP4 192.0.2.10 192.0.2.1 56324 43273\r\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if remote != "192.0.2.10:56324" {
+		t.Errorf("Expected the client address from the header, got %s", remote)
+	}
+
+	remote, err = requestWithHeader(t, addr, []byte("PROXY TCP6 2001:db8::10 2001:db8::1 56324 43273\r\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if remote != "[2001:db8::10]:56324" {
+		t.Errorf("Expected the client address from the header, got %s", remote)
+	}
+}
+
+func TestProxyProtocolV2(t *testing.T) {
+	l, addr := serveRemoteAddr(t)
+	defer l.Close()
+
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x21, 0x11, 0, 12)
+	header = append(header, 198, 51, 100, 7, 192, 0, 2, 1)
+	header = binary.BigEndian.AppendUint16(header, 40000)
+	header = binary.BigEndian.AppendUint16(header, 43273)
+
+	remote, err := requestWithHeader(t, addr, header)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if remote != "198.51.100.7:40000" {
+		t.Errorf("Expected the client address from the header, got %s", remote)
+	}
+}
+
+func TestProxyProtocolLocal(t *testing.T) {
+	l, addr := serveRemoteAddr(t)
+	defer l.Close()
+
+	remote, err := requestWithHeader(t, addr, []byte("PROXY UNKNOWN\r\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if host, _, _ := net.SplitHostPort(remote); host != "127.0.0.1" {
+		t.Errorf("Expected the connection address to be used, got %s", remote)
+	}
+}
+
+func TestProxyProtocolRequiresHeader(t *testing.T) {
+	l, addr := serveRemoteAddr(t)
+	defer l.Close()
+
+	if _, err := requestWithHeader(t, addr, nil); err == nil {
+		t.Fatal("Expected a connection without a PROXY header to be rejected")
+	}
+}
+
+func TestProxyProtocolHeaderTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	server := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the request never to be handled")
+	}), ServerTimeouts{})
+	go server.Serve(NewProxyProtocolListener(l, 100*time.Millisecond))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer conn.Close()
+	// the client never sends a PROXY header
+
+	start := time.Now()
+	conn.SetReadDeadline(start.Add(5 * time.Second))
+	ioutil.ReadAll(conn)
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Fatal("Expected the server to close the connection of a client that doesn't send a PROXY header")
+	}
+}
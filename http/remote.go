@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/openshift/geard/dispatcher"
 	"github.com/openshift/geard/jobs"
 	"github.com/openshift/geard/transport"
 )
@@ -38,14 +39,38 @@ type ServerAware interface {
 }
 
 type HttpTransport struct {
-	client *http.Client
+	client   *http.Client
+	auth     RequestAuthorizer
+	detach   bool
+	priority string
+	host     *URLLocator
 }
 
 func NewHttpTransport() *HttpTransport {
-	return &HttpTransport{&http.Client{}}
+	return &HttpTransport{client: &http.Client{}}
+}
+
+// Attach credentials to every request sent by this transport.
+func (h *HttpTransport) SetAuthorizer(auth RequestAuthorizer) {
+	h.auth = auth
+}
+
+// Ask the server to queue jobs and return immediately rather than wait
+// for them to complete.
+func (h *HttpTransport) SetDetach(detach bool) {
+	h.detach = detach
+}
+
+// Ask the server to queue jobs as low, normal or high priority rather
+// than the priority of their type, unless priority is empty.
+func (h *HttpTransport) SetPriority(priority string) {
+	h.priority = priority
 }
 
 func (h *HttpTransport) LocatorFor(value string) (transport.Locator, error) {
+	if h.host != nil && (value == "" || value == transport.Local.String()) {
+		return h.host, nil
+	}
 	return transport.NewHostLocator(value)
 }
 
@@ -77,6 +102,9 @@ func (h *HttpTransport) RemoteJobFor(locator transport.Locator, j interface{}) (
 }
 
 func urlForLocator(locator transport.Locator) (*url.URL, error) {
+	if remote, ok := locator.(RemoteLocator); ok {
+		return remote.ToURL(), nil
+	}
 	base := locator.String()
 	if strings.Contains(base, ":") {
 		host, port, err := net.SplitHostPort(base)
@@ -92,6 +120,13 @@ func urlForLocator(locator transport.Locator) (*url.URL, error) {
 	return &url.URL{Scheme: "http", Host: base}, nil
 }
 
+// True if err means the server could not be reached or did not answer,
+// rather than that it failed the request.
+func IsUnreachable(err error) bool {
+	_, ok := err.(*url.Error)
+	return ok
+}
+
 func HttpJobFor(job interface{}) (exc RemoteExecutable, err error) {
 	for _, ext := range extensions {
 		req, errr := ext.HttpJobFor(job)
@@ -121,10 +156,30 @@ func (h *HttpTransport) ExecuteRemote(baseUrl *url.URL, job RemoteExecutable, re
 
 	query := &url.Values{}
 	job.MarshalUrlQuery(query)
+	if h.detach {
+		query.Set("async", "true")
+	}
 
 	req := httpreq
 	req.Header.Set("X-Request-Id", id.String())
 	req.Header.Set("If-Match", "api="+ApiVersion())
+	req.Header.Set("Accept-Encoding", "gzip")
+	if h.priority != "" {
+		req.Header.Set("X-Job-Priority", h.priority)
+	}
+	if h.auth != nil {
+		h.auth.Authorize(req)
+	}
+
+	if conditional, ok := job.(HttpConditionalRequest); ok && conditional.HttpIfNoneMatch() != "" {
+		req.Header.Set("If-None-Match", conditional.HttpIfNoneMatch())
+	}
+	if precondition, ok := job.(HttpPreconditionRequest); ok && precondition.HttpIfMatch() != "" {
+		req.Header.Set("If-Match", "api="+ApiVersion()+", "+precondition.HttpIfMatch())
+	}
+	if typed, ok := job.(HttpContentTypeRequest); ok && typed.HttpContentType() != "" {
+		req.Header.Set("Content-Type", typed.HttpContentType())
+	}
 
 	if streamable, ok := job.(HttpStreamable); ok && streamable.Streamable() {
 		req.Header.Set("Accept", "application/json;stream=true")
@@ -148,11 +203,19 @@ func (h *HttpTransport) ExecuteRemote(baseUrl *url.URL, job RemoteExecutable, re
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	body := responseBody(resp)
+	defer body.Close()
 
 	isJson := resp.Header.Get("Content-Type") == "application/json"
 
 	switch code := resp.StatusCode; {
+	case code == 202 && h.detach && resp.Header.Get("Location") != "":
+		status := JobStatus{}
+		if err := json.NewDecoder(body).Decode(&status); err != nil {
+			return err
+		}
+		w := res.SuccessWithWrite(jobs.ResponseOk, false, false)
+		fmt.Fprintf(w, "Job %s queued, check its status with 'gear job status %s/%s'\n", status.Id, baseUrl.Host, status.Id)
 	case code == 202:
 		if isJson {
 			return errors.New("Decoding of streaming JSON has not been implemented")
@@ -167,9 +230,16 @@ func (h *HttpTransport) ExecuteRemote(baseUrl *url.URL, job RemoteExecutable, re
 			}
 		}
 		w := res.SuccessWithWrite(jobs.ResponseOk, false, false)
-		if _, err := io.Copy(w, resp.Body); err != nil {
+		if _, err := io.Copy(w, body); err != nil {
 			return err
 		}
+		if trailers, ok := res.(jobs.TrailerResponse); ok {
+			for k := range resp.Trailer {
+				if strings.HasPrefix(k, "X-") {
+					trailers.WriteTrailer(k[2:], resp.Trailer.Get(k))
+				}
+			}
+		}
 	case code == 204:
 		data, err := job.UnmarshalHttpResponse(resp.Header, nil, ResponseTable)
 		if err != nil {
@@ -181,27 +251,124 @@ func (h *HttpTransport) ExecuteRemote(baseUrl *url.URL, job RemoteExecutable, re
 			}
 		}
 		res.Success(jobs.ResponseOk)
+	case code == 304:
+		data, err := job.UnmarshalHttpResponse(resp.Header, nil, ResponseTable)
+		if err != nil {
+			return err
+		}
+		if pending, ok := data.(map[string]interface{}); ok {
+			for k := range pending {
+				res.WritePendingSuccess(k, pending[k])
+			}
+		}
+		res.Success(jobs.ResponseNotModified)
 	case code >= 200 && code < 300:
 		if !isJson {
 			return errors.New(fmt.Sprintf("remote: Response with %d status code had content type %s (should be application/json)", code, resp.Header.Get("Content-Type")))
 		}
-		data, err := job.UnmarshalHttpResponse(nil, resp.Body, ResponseJson)
+		data, err := job.UnmarshalHttpResponse(nil, body, ResponseJson)
 		if err != nil {
 			return err
 		}
 		res.SuccessWithData(jobs.ResponseOk, data)
+	case code == 401:
+		return ErrNotAuthorized
 	default:
 		if isJson {
-			decoder := json.NewDecoder(resp.Body)
+			decoder := json.NewDecoder(body)
 			data := httpFailureResponse{}
 			if err := decoder.Decode(&data); err != nil {
 				return err
 			}
-			res.Failure(jobs.SimpleError{jobs.ResponseError, data.Message})
+			res.Failure(jobs.SimpleError{FailureForStatusCode(code), data.Message})
 			return nil
 		}
-		io.Copy(os.Stderr, resp.Body)
-		res.Failure(jobs.SimpleError{jobs.ResponseError, "Unable to decode response."})
+		io.Copy(os.Stderr, body)
+		res.Failure(jobs.SimpleError{FailureForStatusCode(code), "Unable to decode response."})
 	}
 	return nil
 }
+
+// Retrieve the status of a job queued asynchronously on a server.
+func (h *HttpTransport) JobStatus(locator transport.Locator, id jobs.RequestIdentifier) (*JobStatus, error) {
+	baseUrl, err := urlForLocator(locator)
+	if err != nil {
+		return nil, errors.New("The provided host is not valid '" + locator.String() + "': " + err.Error())
+	}
+	baseUrl.Path = JobStatusPath(id)
+	req, err := http.NewRequest("GET", baseUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("If-Match", "api="+ApiVersion())
+	req.Header.Set("Accept-Encoding", "gzip")
+	if h.priority != "" {
+		req.Header.Set("X-Job-Priority", h.priority)
+	}
+	if h.auth != nil {
+		h.auth.Authorize(req)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body := responseBody(resp)
+	defer body.Close()
+
+	switch resp.StatusCode {
+	case 200, 202:
+		status := &JobStatus{}
+		if err := json.NewDecoder(body).Decode(status); err != nil {
+			return nil, err
+		}
+		return status, nil
+	case 401:
+		return nil, ErrNotAuthorized
+	case 404:
+		return nil, jobs.SimpleError{jobs.ResponseNotFound, "The job is not known to the server, it may have expired"}
+	}
+	return nil, fmt.Errorf("remote: Unexpected response %d when reading job status", resp.StatusCode)
+}
+
+// Cancel a job queued or running on a server.
+func (h *HttpTransport) CancelJob(locator transport.Locator, id jobs.RequestIdentifier) (*JobCancellation, error) {
+	baseUrl, err := urlForLocator(locator)
+	if err != nil {
+		return nil, errors.New("The provided host is not valid '" + locator.String() + "': " + err.Error())
+	}
+	baseUrl.Path = JobStatusPath(id)
+	req, err := http.NewRequest("DELETE", baseUrl.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("If-Match", "api="+ApiVersion())
+	req.Header.Set("Accept-Encoding", "gzip")
+	if h.priority != "" {
+		req.Header.Set("X-Job-Priority", h.priority)
+	}
+	if h.auth != nil {
+		h.auth.Authorize(req)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body := responseBody(resp)
+	defer body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		cancellation := &JobCancellation{}
+		if err := json.NewDecoder(body).Decode(cancellation); err != nil {
+			return nil, err
+		}
+		return cancellation, nil
+	case 401:
+		return nil, ErrNotAuthorized
+	case 404:
+		return nil, dispatcher.ErrNoSuchJob
+	}
+	return nil, fmt.Errorf("remote: Unexpected response %d when cancelling a job", resp.StatusCode)
+}
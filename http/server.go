@@ -3,6 +3,7 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -23,8 +24,24 @@ func ApiVersion() string {
 type HttpConfiguration struct {
 	Docker     config.DockerConfiguration
 	Dispatcher *dispatcher.Dispatcher
+	// If set, every request except those to UnauthenticatedPaths must be
+	// authenticated
+	Auth Authenticator
+	// Compress streamed output for clients that accept gzip, in addition
+	// to complete responses
+	CompressStreams bool
+	// If set, jobs that change state are rejected while it is enabled
+	Maintenance *MaintenanceMode
+	// If set, jobs that change state are always rejected
+	ReadOnly bool
+
+	jobStatus *jobStatusStore
 }
 
+// The number of asynchronous jobs whose status is retained when the
+// dispatcher does not specify one.
+const defaultTrackedJobs = 1000
+
 type JobHandler func(*jobs.JobContext, *rest.Request) (interface{}, error)
 
 type HttpJobHandler interface {
@@ -36,6 +53,30 @@ type HttpStreamable interface {
 	Streamable() bool
 }
 
+// A parsed request whose job reads the request body while it runs, such
+// as the stdin of an exec.  The job can't be run asynchronously, as the
+// body is closed once the job has been accepted.
+type InputStreamingRequest interface {
+	StreamsInput() bool
+}
+
+// A request that only returns content when it does not match the
+// ETags sent in If-None-Match.
+type HttpConditionalRequest interface {
+	HttpIfNoneMatch() string
+}
+
+// A request that only changes state when the current ETag matches one
+// sent in If-Match.
+type HttpPreconditionRequest interface {
+	HttpIfMatch() string
+}
+
+// A request whose body is not JSON.
+type HttpContentTypeRequest interface {
+	HttpContentType() string
+}
+
 func (conf *HttpConfiguration) Handler() (http.Handler, error) {
 	handler := rest.ResourceHandler{
 		EnableRelaxedContentType: true,
@@ -52,18 +93,46 @@ func (conf *HttpConfiguration) Handler() (http.Handler, error) {
 		}
 	}
 
-	routes := make([]rest.Route, len(handlers))
+	routes := make([]rest.Route, len(handlers), len(handlers)+4)
 	for i := range handlers {
 		routes[i] = conf.jobRestHandler(handlers[i])
 	}
 
+	tracked := defaultTrackedJobs
+	if conf.Dispatcher != nil && conf.Dispatcher.TrackDuplicateIds > 0 {
+		tracked = conf.Dispatcher.TrackDuplicateIds
+	}
+	conf.jobStatus = newJobStatusStore(tracked)
+	routes = append(routes,
+		rest.Route{"GET", JobHistoryPath, conf.handleJobHistory},
+		rest.Route{"GET", "/jobs/:id", conf.handleJobStatus},
+		rest.Route{"DELETE", "/jobs/:id", conf.handleCancelJob},
+		rest.Route{"GET", EventsPath, conf.handleEvents},
+	)
+
 	if err := handler.SetRoutes(routes...); err != nil {
 		for i := range routes {
 			log.Printf("failed: %+v", routes[i])
 		}
 		return nil, err
 	}
-	return &handler, nil
+	compressed := gzipHandler{&handler, conf.CompressStreams}
+	if conf.Auth != nil {
+		return AuthenticatedHandler(conf.Auth, duplexHandler{compressed}), nil
+	}
+	return duplexHandler{compressed}, nil
+}
+
+// Some jobs read from the request body while streaming their response
+// (stdin to an exec, for instance), which requires the server not to
+// drain the body when the response is first written.
+type duplexHandler struct {
+	http.Handler
+}
+
+func (h duplexHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	http.NewResponseController(w).EnableFullDuplex()
+	h.Handler.ServeHTTP(w, withResponseController(w, r))
 }
 
 func (conf *HttpConfiguration) jobRestHandler(handler HttpJobHandler) rest.Route {
@@ -74,19 +143,35 @@ func (conf *HttpConfiguration) jobRestHandler(handler HttpJobHandler) rest.Route
 	}
 }
 
+// The entries of an If-Match header other than the API version every
+// client sends, which are the ETags a job's precondition must match.
+func IfMatchPreconditions(header string) string {
+	tags := []string{}
+	for _, segment := range strings.Split(header, ",") {
+		if segment = strings.TrimSpace(segment); segment != "" && !strings.HasPrefix(segment, "api=") {
+			tags = append(tags, segment)
+		}
+	}
+	return strings.Join(tags, ", ")
+}
+
 func (conf *HttpConfiguration) handleWithMethod(method JobHandler) func(*rest.ResponseWriter, *rest.Request) {
 	return func(w *rest.ResponseWriter, r *rest.Request) {
 		match := r.Header.Get("If-Match")
 		segments := strings.Split(match, ",")
 		for i := range segments {
-			if strings.HasPrefix(segments[i], "api=") {
-				if segments[i][4:] != ApiVersion() {
-					http.Error(w, fmt.Sprintf("Current API version %s does not match requested %s", ApiVersion(), segments[i][4:]), http.StatusPreconditionFailed)
+			if segment := strings.TrimSpace(segments[i]); strings.HasPrefix(segment, "api=") {
+				if segment[4:] != ApiVersion() {
+					http.Error(w, fmt.Sprintf("Current API version %s does not match requested %s", ApiVersion(), segment[4:]), http.StatusPreconditionFailed)
 					return
 				}
 			}
 		}
 
+		if conf.rejectChanges(w.ResponseWriter, r.Request) {
+			return
+		}
+
 		context := &jobs.JobContext{}
 
 		requestId := r.Header.Get("X-Request-Id")
@@ -101,10 +186,25 @@ func (conf *HttpConfiguration) handleWithMethod(method JobHandler) func(*rest.Re
 			context.Id = id
 		}
 
+		// a client may queue its job ahead of or behind others
+		var priority *dispatcher.Priority
+		if value := r.Header.Get("X-Job-Priority"); value != "" {
+			p, err := dispatcher.ParsePriority(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			priority = &p
+		}
+
 		// parse the incoming request into an object
 		jobRequest, errh := method(context, r)
 		if errh != nil {
-			serveRequestError(w, apiRequestError{errh, errh.Error(), http.StatusBadRequest})
+			code := http.StatusBadRequest
+			if _, ok := errh.(jobs.JobError); ok {
+				code = StatusCodeFor(jobs.FailureFor(errh))
+			}
+			serveRequestError(w, apiRequestError{errh, errh.Error(), code})
 			return
 		}
 
@@ -127,11 +227,23 @@ func (conf *HttpConfiguration) handleWithMethod(method JobHandler) func(*rest.Re
 		if acceptHeader == "text/plain" {
 			mode = ResponseTable
 		}
+		if isAsyncRequest(r.Request) {
+			if s, ok := jobRequest.(InputStreamingRequest); ok && s.StreamsInput() {
+				serveRequestError(w, apiRequestError{nil, "This job reads the request body while it runs and can't be run asynchronously", http.StatusBadRequest})
+				return
+			}
+			conf.dispatchAsync(w.ResponseWriter, context.Id, job, mode, priority)
+			return
+		}
+
 		canStream := didClientRequestStreamableResponse(acceptHeader)
 		response := NewHttpJobResponse(w.ResponseWriter, !canStream, mode)
 
+		// the response takes as long as the job, which may read the body
+		exemptFromTimeouts(r.Request)
+
 		// queue / handle the request
-		wait, errd := conf.Dispatcher.Dispatch(context.Id, job, response)
+		wait, errd := conf.dispatch(context.Id, job, response, priority)
 		if errd == jobs.ErrRanToCompletion {
 			http.Error(w, errd.Error(), http.StatusNoContent)
 			return
@@ -143,6 +255,40 @@ func (conf *HttpConfiguration) handleWithMethod(method JobHandler) func(*rest.Re
 	}
 }
 
+// Queue the job with the priority the client asked for, or the priority
+// of its type if it didn't.
+func (conf *HttpConfiguration) dispatch(id jobs.RequestIdentifier, job jobs.Job, resp jobs.Response, priority *dispatcher.Priority) (<-chan bool, error) {
+	if priority != nil {
+		return conf.Dispatcher.DispatchPriority(id, job, resp, *priority)
+	}
+	return conf.Dispatcher.Dispatch(id, job, resp)
+}
+
+// Queue the job and reply with a link to its status rather than waiting
+// for it to complete.
+func (conf *HttpConfiguration) dispatchAsync(w http.ResponseWriter, id jobs.RequestIdentifier, job jobs.Job, mode ResponseContentMode, priority *dispatcher.Priority) {
+	async := newAsyncJob(id)
+	wait, errd := conf.dispatch(id, job, async.Response(mode), priority)
+	if errd == jobs.ErrRanToCompletion {
+		http.Error(w, errd.Error(), http.StatusNoContent)
+		return
+	} else if errd != nil {
+		serveRequestError(w, apiRequestError{errd, errd.Error(), http.StatusServiceUnavailable})
+		return
+	}
+	conf.jobStatus.Add(id, async)
+	go func() {
+		<-wait
+		async.Complete()
+	}()
+
+	w.Header().Set("Location", JobStatusPath(id))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	status := async.Status()
+	json.NewEncoder(w).Encode(&status)
+}
+
 func didClientRequestStreamableResponse(acceptHeader string) bool {
 	result := false
 	mediaTypes := strings.Split(acceptHeader, ",")
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// The first file descriptor systemd passes to a socket activated process
+// (SD_LISTEN_FDS_START).
+const listenFdsStart = 3
+
+// The listening socket systemd passed to this process, following the
+// sd_listen_fds protocol, or nil if there is none.  LISTEN_PID and
+// LISTEN_FDS are removed from the environment so that child processes
+// don't inherit them.
+//
+// See http://www.freedesktop.org/software/systemd/man/sd_listen_fds.html
+func SystemdListener() (net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	return systemdListener(os.Getenv, os.Getpid(), listenFdsStart)
+}
+
+func systemdListener(getenv func(string) string, pid int, firstFd int) (net.Listener, error) {
+	if listenPid, err := strconv.Atoi(getenv("LISTEN_PID")); err != nil || listenPid != pid {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, nil
+	}
+	if count > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, the socket unit must listen on a single stream", count)
+	}
+
+	file := os.NewFile(uintptr(firstFd), "LISTEN_FD_"+strconv.Itoa(firstFd))
+	// the listener holds its own copy of the descriptor
+	defer file.Close()
+	l, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("the socket passed by systemd (fd %d) is not a listening stream socket: %v", firstFd, err)
+	}
+	return l, nil
+}
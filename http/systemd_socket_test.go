@@ -0,0 +1,79 @@
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func listenEnv(pid int, fds int) func(string) string {
+	env := map[string]string{
+		"LISTEN_PID": fmt.Sprint(pid),
+		"LISTEN_FDS": fmt.Sprint(fds),
+	}
+	return func(key string) string { return env[key] }
+}
+
+func TestSystemdListenerInherited(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	defer l.Close()
+	// a duplicate of the socket stands in for the descriptor systemd passes
+	file, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Unable to duplicate the listener: %v", err)
+	}
+	fd, err := syscall.Dup(int(file.Fd()))
+	file.Close()
+	if err != nil {
+		t.Fatalf("Unable to duplicate the listener: %v", err)
+	}
+
+	inherited, err := systemdListener(listenEnv(os.Getpid(), 1), os.Getpid(), fd)
+	if err != nil {
+		t.Fatalf("Unable to use the inherited socket: %v", err)
+	}
+	if inherited == nil {
+		t.Fatal("Expected the inherited socket to be used")
+	}
+	defer inherited.Close()
+	if inherited.Addr().String() != l.Addr().String() {
+		t.Errorf("Expected the inherited socket to listen on %s, got %s", l.Addr(), inherited.Addr())
+	}
+
+	go http.Serve(inherited, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	resp, err := http.Get("http://" + inherited.Addr().String())
+	if err != nil {
+		t.Fatalf("Unable to make a request to the inherited socket: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "ok" {
+		t.Errorf("Unexpected response %q", string(body))
+	}
+}
+
+func TestSystemdListenerNotPassed(t *testing.T) {
+	pid := os.Getpid()
+	for name, getenv := range map[string]func(string) string{
+		"no environment": func(string) string { return "" },
+		"another pid":    listenEnv(pid+1, 1),
+		"no fds":         listenEnv(pid, 0),
+	} {
+		l, err := systemdListener(getenv, pid, listenFdsStart)
+		if err != nil || l != nil {
+			t.Errorf("Expected to fall back to listening with %s, got %v %v", name, l, err)
+		}
+	}
+
+	if _, err := systemdListener(listenEnv(pid, 2), pid, listenFdsStart); err == nil {
+		t.Error("Expected several sockets to be rejected")
+	}
+}
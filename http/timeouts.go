@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Limits on how long the server waits on a client, so that slow or idle
+// clients can't hold connections open and exhaust the daemon.  Zero
+// disables a limit.
+type ServerTimeouts struct {
+	// Reading the headers and body of a request
+	Read time.Duration
+	// Writing a response, from the end of the request headers.  Responses
+	// that wait for a job or stream events are exempt, jobs being limited
+	// by their own timeouts instead.
+	Write time.Duration
+	// Keeping a connection open between requests
+	Idle time.Duration
+}
+
+func (t ServerTimeouts) Check() error {
+	if t.Read < 0 || t.Write < 0 || t.Idle < 0 {
+		return errors.New("Server timeouts must be zero or greater")
+	}
+	return nil
+}
+
+// A server for handler that enforces the timeouts.
+func NewServer(handler http.Handler, t ServerTimeouts) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: t.Read,
+		ReadTimeout:       t.Read,
+		WriteTimeout:      t.Write,
+		IdleTimeout:       t.Idle,
+	}
+}
+
+type responseControllerKey struct{}
+
+// The writers rest passes to route handlers can't be unwrapped, so the
+// controller of the connection is passed to them with the request.
+func withResponseController(w http.ResponseWriter, r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), responseControllerKey{}, http.NewResponseController(w)))
+}
+
+// Lift the read and write deadlines the server set on the connection of
+// r, for a response that waits on a job or streams for as long as the
+// client stays connected.  The body of r may then be read without limit.
+func exemptFromTimeouts(r *http.Request) {
+	rc, ok := r.Context().Value(responseControllerKey{}).(*http.ResponseController)
+	if !ok {
+		return
+	}
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func serveWithTimeouts(t *testing.T, handler http.Handler, timeouts ServerTimeouts) (*http.Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	server := NewServer(handler, timeouts)
+	go server.Serve(l)
+	return server, l.Addr().String()
+}
+
+func TestReadTimeoutClosesSlowClient(t *testing.T) {
+	server, addr := serveWithTimeouts(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the request never to be handled")
+	}), ServerTimeouts{Read: 100 * time.Millisecond})
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unable to connect: %v", err)
+	}
+	defer conn.Close()
+	// the headers are never finished
+	fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\nX-Slow: ")
+
+	start := time.Now()
+	conn.SetReadDeadline(start.Add(5 * time.Second))
+	ioutil.ReadAll(conn)
+	elapsed := time.Since(start)
+	if elapsed >= 5*time.Second {
+		t.Fatal("Expected the server to close the connection of a client that doesn't finish its headers")
+	}
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the connection to stay open until the read timeout, closed after %s", elapsed)
+	}
+}
+
+func TestStreamingResponsesExemptFromWriteTimeout(t *testing.T) {
+	handler := duplexHandler{http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			exemptFromTimeouts(r)
+		}
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, "done")
+	})}
+	server, addr := serveWithTimeouts(t, handler, ServerTimeouts{Write: 100 * time.Millisecond})
+	defer server.Close()
+
+	resp, err := http.Get("http://" + addr + "/stream")
+	if err != nil {
+		t.Fatalf("Expected a streaming response to outlast the write timeout: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "done" {
+		t.Errorf("Expected the full response, got %q", body)
+	}
+
+	if resp, err := http.Get("http://" + addr + "/other"); err == nil {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil && string(body) == "done" {
+			t.Error("Expected a response written after the write timeout to be cut off")
+		}
+	}
+}
+
+func TestServerTimeoutsCheck(t *testing.T) {
+	if err := (ServerTimeouts{time.Minute, 0, time.Second}).Check(); err != nil {
+		t.Errorf("Expected the timeouts to be valid: %v", err)
+	}
+	if err := (ServerTimeouts{Idle: -time.Second}).Check(); err == nil {
+		t.Error("Expected a negative timeout to be rejected")
+	}
+}
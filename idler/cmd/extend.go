@@ -25,7 +25,7 @@ func init() {
 			Short: "(local) A daemon that monitors container traffic and makes idle/unidle decisions",
 			Run:   startIdler,
 		}
-		idlerCmd.PersistentFlags().StringVarP(&hostIp, "host-ip", "H", guessHostIp(), "IP address to listen for traffic on")
+		idlerCmd.PersistentFlags().StringVar(&hostIp, "host-ip", guessHostIp(), "IP address to listen for traffic on")
 		idlerCmd.PersistentFlags().IntVarP(&idleTimeout, "idle-timeout", "T", 60, "Set the number of minutes of inactivity before an application is idled")
 		parent.AddCommand(idlerCmd)
 	}, true)
@@ -1,12 +1,19 @@
 package jobs
 
+import (
+	"fmt"
+)
+
 var (
 	ErrRanToCompletion = SimpleError{ResponseError, "This job has run to completion."}
+	ErrJobCanceled     = SimpleError{ResponseError, "This job was cancelled."}
+	ErrJobTimedOut     = SimpleError{ResponseError, "This job did not finish within its time limit and was cancelled."}
 )
 
 const (
 	ResponseOk ResponseSuccess = iota
 	ResponseAccepted
+	ResponseNotModified
 )
 
 const (
@@ -16,8 +23,53 @@ const (
 	ResponseInvalidRequest
 	ResponseRateLimit
 	ResponseNotAcceptable
+	ResponseUnavailable
+	ResponseForbidden
+	ResponseTooLarge
+)
+
+// Errors that identify the kind of failure rather than its cause.  Callers
+// should test for a kind with IsNotFound, IsConflict, or IsInvalid, which
+// also recognize any other JobError with the same ResponseFailure.
+var (
+	ErrNotFound = SimpleError{ResponseNotFound, "The requested resource does not exist."}
+	ErrConflict = SimpleError{ResponseAlreadyExists, "The request conflicts with the current state of the resource."}
+	ErrInvalid  = SimpleError{ResponseInvalidRequest, "The request is not valid."}
 )
 
+func NewNotFoundError(format string, args ...interface{}) SimpleError {
+	return SimpleError{ResponseNotFound, fmt.Sprintf(format, args...)}
+}
+
+func NewConflictError(format string, args ...interface{}) SimpleError {
+	return SimpleError{ResponseAlreadyExists, fmt.Sprintf(format, args...)}
+}
+
+func NewInvalidError(format string, args ...interface{}) SimpleError {
+	return SimpleError{ResponseInvalidRequest, fmt.Sprintf(format, args...)}
+}
+
+// The kind of failure an error represents.  Errors that are not a JobError
+// are treated as ResponseError.
+func FailureFor(err error) ResponseFailure {
+	if e, ok := err.(JobError); ok {
+		return e.ResponseFailure()
+	}
+	return ResponseError
+}
+
+func IsNotFound(err error) bool {
+	return err != nil && FailureFor(err) == ResponseNotFound
+}
+
+func IsConflict(err error) bool {
+	return err != nil && FailureFor(err) == ResponseAlreadyExists
+}
+
+func IsInvalid(err error) bool {
+	return err != nil && FailureFor(err) == ResponseInvalidRequest
+}
+
 // An error with a code and message to user
 type SimpleError struct {
 	Failure ResponseFailure
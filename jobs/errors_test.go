@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	if !IsNotFound(ErrNotFound) || !IsNotFound(NewNotFoundError("No %s", "container")) {
+		t.Error("Expected not found errors to be recognized")
+	}
+	if !IsConflict(ErrConflict) || IsConflict(ErrNotFound) {
+		t.Error("Expected only conflict errors to be recognized as a conflict")
+	}
+	if !IsInvalid(NewInvalidError("bad")) || IsInvalid(errors.New("bad")) {
+		t.Error("Expected only invalid errors to be recognized as invalid")
+	}
+	if IsNotFound(nil) || FailureFor(errors.New("unknown")) != ResponseError {
+		t.Error("Expected untyped errors to be a generic failure")
+	}
+	if err := NewConflictError("Container %s exists", "a"); err.Error() != "Container a exists" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+}
@@ -2,6 +2,7 @@
 package jobs
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
@@ -23,6 +24,14 @@ func (job JobFunction) Execute(res Response) {
 	job(res)
 }
 
+// A job that can be cancelled while it runs.  ExecuteContext is called
+// instead of Execute, and the job should stop and report a failure once
+// ctx is done.
+type ContextJob interface {
+	Job
+	ExecuteContext(ctx context.Context, resp Response)
+}
+
 // A client may rejoin a running job by re-executing the request,
 // and a job that supports this interface will be notified that
 // a second client has connected.  Typically the join will stream
@@ -49,6 +58,14 @@ type Response interface {
 	WritePendingSuccess(name string, value interface{})
 }
 
+// A response that can accept named values after a streaming write has
+// started, such as the exit code of a process whose output was streamed.
+// Not all responses support trailers - callers should test for this
+// interface.
+type TrailerResponse interface {
+	WriteTrailer(name string, value string)
+}
+
 type ResponseSuccess int
 type ResponseFailure int
 
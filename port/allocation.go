@@ -1,17 +1,24 @@
 package port
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
 const portsPerBlock = Port(100) // changing this breaks disk structure... don't do it!
 const maxReadFailures = 3
 
+const (
+	defaultMinPort = Port(4000)
+	defaultMaxPort = Port(60000)
+)
+
 func StartPortAllocator(min, max Port) {
 	lock.Lock()
 	defer lock.Unlock()
@@ -28,17 +35,66 @@ func StartPortAllocator(min, max Port) {
 	}()
 }
 
+// Prevent the allocator from starting, so that no ports are reserved.
+// Allocations fail as though every port were in use.  Has no effect once
+// the allocator has started.
+func DisablePortAllocator() {
+	lock.Lock()
+	defer lock.Unlock()
+	if started {
+		return
+	}
+	started = true
+	internalPortAllocator.min = defaultMinPort
+	internalPortAllocator.max = defaultMaxPort
+	close(internalPortAllocator.ports)
+}
+
+// The range external ports are allocated from, including min but not max.
+func AllocatorRange() (min, max Port) {
+	lock.Lock()
+	defer lock.Unlock()
+	if !started {
+		return defaultMinPort, defaultMaxPort
+	}
+	return internalPortAllocator.min, internalPortAllocator.max
+}
+
+// Returned when no port in the allocator range is free.  More ports may
+// become available at a later time, but are unlikely to come open now.
+type ExhaustedError struct {
+	Min   Port
+	Max   Port
+	InUse int
+}
+
+func (e ExhaustedError) Error() string {
+	return fmt.Sprintf("The port range %d-%d is exhausted, %d in use.", e.Min, e.Max, e.InUse)
+}
+
+var allocationFailures uint64
+
+// The number of allocations that failed because no port was free since
+// the process started.
+func AllocationFailures() uint64 {
+	return atomic.LoadUint64(&allocationFailures)
+}
+
 //
-// Returns 0 if no port can be allocated.  Consumers
-// should fail when getting 0 - more ports may become
-// available at a later time, but are unlikely to
-// come open now.
+// Returns an ExhaustedError if no port can be allocated.
 //
-func allocatePort() Port {
-	StartPortAllocator(4000, 60000)
+func allocatePort() (Port, error) {
+	StartPortAllocator(defaultMinPort, defaultMaxPort)
 	p := <-internalPortAllocator.ports
+	if p == 0 {
+		atomic.AddUint64(&allocationFailures, 1)
+		min, max := AllocatorRange()
+		inUse, _ := CountReservedPorts(min, max)
+		log.Printf("ports: Unable to allocate a port, %d of %d-%d are in use", inUse, min, max)
+		return 0, ExhaustedError{min, max, inUse}
+	}
 	log.Printf("ports: Reserved port %d", p)
-	return p
+	return p, nil
 }
 
 //
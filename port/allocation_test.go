@@ -0,0 +1,50 @@
+package port
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/geard/config"
+)
+
+// The allocator can only be started once in a process, so this is the only
+// test that allocates ports.
+func TestAllocationExhausted(t *testing.T) {
+	base, err := ioutil.TempDir("", "geard-ports")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(base)
+	defer config.SetContainerBasePath(config.ContainerBasePath())
+	config.SetContainerBasePath(base)
+
+	unit := filepath.Join(base, "units", "fu", "full", "1")
+	for _, p := range []Port{7000, 7001, 7002} {
+		if _, err := ReserveExternalPort(unit, p); err != nil {
+			t.Fatalf("Unable to reserve port %d: %v", p, err)
+		}
+	}
+	StartPortAllocator(7000, 7003)
+
+	failures := AllocationFailures()
+	_, err = ReserveExternalPort(unit, 0)
+	exhausted, ok := err.(ExhaustedError)
+	if !ok {
+		t.Fatalf("Expected the range to be exhausted, got %v", err)
+	}
+	if exhausted != (ExhaustedError{7000, 7003, 3}) {
+		t.Errorf("Unexpected error %+v", exhausted)
+	}
+	if err.Error() != "The port range 7000-7003 is exhausted, 3 in use." {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+	if AllocationFailures() != failures+1 {
+		t.Errorf("Expected the failure to be counted, %d were", AllocationFailures()-failures)
+	}
+
+	if _, err := AtomicReserveExternalPorts(unit, PortPairs{{8080, 0}}, PortPairs{}); err != exhausted {
+		t.Errorf("Expected installs to fail with the same error, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/port"
+)
+
+var dryRun bool
+
+func RegisterPorts(parent *cobra.Command) {
+	portsCmd := &cobra.Command{
+		Use:   "ports",
+		Short: "(Local) List the external ports reserved for containers",
+		Long:  "List each external port reserved on this host and the container it is reserved for, and the number of ports that are free.  Ports reserved for a container that no longer exists are marked as leaked.",
+		Run:   listPorts,
+	}
+	parent.AddCommand(portsCmd)
+
+	reclaimCmd := &cobra.Command{
+		Use:   "reclaim",
+		Short: "(Local) Free the ports reserved for containers that no longer exist",
+		Long:  "Release the external ports whose container has been removed without geard, for instance by deleting its unit file, so that they can be allocated again.",
+		Run:   reclaimPorts,
+	}
+	reclaimCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the ports that would be freed without freeing them")
+	portsCmd.AddCommand(reclaimCmd)
+}
+
+// An allocation is held if its unit definition exists and the container
+// it belongs to is still installed.
+func containerExists(a port.Allocation) bool {
+	if !port.DefinitionExists(a) {
+		return false
+	}
+	id, ok := containers.IdentifierForVersionedUnitPath(a.Target)
+	if !ok {
+		// not a container definition, leave it alone
+		return true
+	}
+	_, err := os.Stat(id.UnitPathFor())
+	return err == nil || !os.IsNotExist(err)
+}
+
+func containerFor(a port.Allocation) string {
+	if id, ok := containers.IdentifierForVersionedUnitPath(a.Target); ok {
+		return string(id)
+	}
+	return a.Target
+}
+
+func listPorts(c *cobra.Command, args []string) {
+	if len(args) != 0 {
+		gcmd.Fail(1, "Valid arguments: (none)")
+	}
+	min, max := port.AllocatorRange()
+	allocations, err := port.ListAllocations(1, 65536)
+	if err != nil {
+		gcmd.Fail(1, "Unable to read the reserved ports: %s", err.Error())
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PORT\tCONTAINER\t")
+	leaked := 0
+	for _, a := range allocations {
+		state := ""
+		if !containerExists(a) {
+			state = "leaked"
+			leaked++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", a.Port, containerFor(a), state)
+	}
+	w.Flush()
+
+	reserved, err := port.CountReservedPorts(min, max)
+	if err != nil {
+		gcmd.Fail(1, "Unable to count the reserved ports: %s", err.Error())
+	}
+	fmt.Fprintf(os.Stdout, "\n%d reserved (%d leaked), %d free in %d-%d\n", len(allocations), leaked, int(max-min)-reserved, min, max-1)
+}
+
+func reclaimPorts(c *cobra.Command, args []string) {
+	if len(args) != 0 {
+		gcmd.Fail(1, "Valid arguments: (none)")
+	}
+	reclaimed, err := port.ReclaimAllocations(1, 65536, containerExists, dryRun)
+	for _, a := range reclaimed {
+		if dryRun {
+			fmt.Fprintf(os.Stdout, "Would free port %d reserved for %s\n", a.Port, containerFor(a))
+		} else {
+			fmt.Fprintf(os.Stdout, "Freed port %d reserved for %s\n", a.Port, containerFor(a))
+		}
+	}
+	if err != nil {
+		gcmd.Fail(1, "Unable to free the leaked ports: %s", err.Error())
+	}
+	if len(reclaimed) == 0 {
+		fmt.Fprintln(os.Stdout, "No leaked ports")
+	}
+}
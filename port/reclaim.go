@@ -0,0 +1,83 @@
+package port
+
+import (
+	"os"
+)
+
+// An external port reserved for a container, and the unit definition the
+// reservation links to.
+type Allocation struct {
+	Port   Port
+	Target string
+}
+
+// Every external port reserved between min and max (exclusive), in order.
+func ListAllocations(min, max Port) ([]Allocation, error) {
+	allocations := []Allocation{}
+	for block := min / portsPerBlock; block*portsPerBlock < max; block++ {
+		parent, _ := (block * portsPerBlock).PortPathsFor()
+		f, err := os.Open(parent)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range namesToPorts(names) {
+			if p < min || p >= max {
+				continue
+			}
+			_, direct := p.PortPathsFor()
+			target, err := os.Readlink(direct)
+			if os.IsNotExist(err) {
+				// released while the block was read
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			allocations = append(allocations, Allocation{p, target})
+		}
+	}
+	return allocations, nil
+}
+
+// True if the unit definition the allocation links to exists.  An
+// allocation whose definition can't be checked is treated as in use.
+func DefinitionExists(a Allocation) bool {
+	_, err := os.Stat(a.Target)
+	return err == nil || !os.IsNotExist(err)
+}
+
+// Release the allocations between min and max that inUse reports are no
+// longer held by a container, and return them.  With dryRun the leaked
+// allocations are returned but not released.  An allocation that is
+// changed to another definition while it is checked is not released.
+func ReclaimAllocations(min, max Port, inUse func(Allocation) bool, dryRun bool) ([]Allocation, error) {
+	allocations, err := ListAllocations(min, max)
+	if err != nil {
+		return nil, err
+	}
+	leaked := []Allocation{}
+	for _, a := range allocations {
+		if inUse(a) {
+			continue
+		}
+		if !dryRun {
+			_, direct := a.Port.PortPathsFor()
+			if target, err := os.Readlink(direct); err != nil || target != a.Target {
+				continue
+			}
+			if err := os.Remove(direct); err != nil && !os.IsNotExist(err) {
+				return leaked, err
+			}
+		}
+		leaked = append(leaked, a)
+	}
+	return leaked, nil
+}
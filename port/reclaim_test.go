@@ -0,0 +1,58 @@
+package port
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/geard/config"
+)
+
+func TestReclaimLeakedAllocation(t *testing.T) {
+	base, err := ioutil.TempDir("", "geard-ports")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(base)
+	defer config.SetContainerBasePath(config.ContainerBasePath())
+	config.SetContainerBasePath(base)
+
+	live := filepath.Join(base, "units", "li", "live", "1")
+	os.MkdirAll(filepath.Dir(live), 0770)
+	if err := ioutil.WriteFile(live, []byte{}, 0660); err != nil {
+		t.Fatalf("Unable to write the unit definition: %v", err)
+	}
+	leaked := filepath.Join(base, "units", "le", "leaked", "1")
+	if _, err := ReserveExternalPort(live, 4001); err != nil {
+		t.Fatalf("Unable to reserve a port: %v", err)
+	}
+	if _, err := ReserveExternalPort(leaked, 4002); err != nil {
+		t.Fatalf("Unable to reserve a port: %v", err)
+	}
+
+	allocations, err := ListAllocations(4000, 5000)
+	if err != nil {
+		t.Fatalf("Unable to list the allocations: %v", err)
+	}
+	if len(allocations) != 2 || allocations[0] != (Allocation{4001, live}) || allocations[1] != (Allocation{4002, leaked}) {
+		t.Fatalf("Unexpected allocations %+v", allocations)
+	}
+
+	reclaimed, err := ReclaimAllocations(4000, 5000, DefinitionExists, true)
+	if err != nil || len(reclaimed) != 1 || reclaimed[0].Port != 4002 {
+		t.Fatalf("Expected the leaked port to be found, got %+v %v", reclaimed, err)
+	}
+	if count, _ := CountReservedPorts(4000, 5000); count != 2 {
+		t.Fatalf("Expected a dry run to leave the ports reserved, %d are", count)
+	}
+
+	reclaimed, err = ReclaimAllocations(4000, 5000, DefinitionExists, false)
+	if err != nil || len(reclaimed) != 1 || reclaimed[0].Port != 4002 {
+		t.Fatalf("Expected the leaked port to be freed, got %+v %v", reclaimed, err)
+	}
+	allocations, _ = ListAllocations(4000, 5000)
+	if len(allocations) != 1 || allocations[0].Port != 4001 {
+		t.Errorf("Expected only the live port to remain reserved, got %+v", allocations)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"strconv"
 )
 
-var ErrAllocationFailed = errors.New("A port could not be allocated.")
+var (
+	ErrPortReserved = errors.New("The port is already reserved.")
+)
 
 func (p Port) PortPathsFor() (base string, path string) {
 	root := Device("1").DevicePath()
@@ -19,6 +21,32 @@ func (p Port) PortPathsFor() (base string, path string) {
 	return
 }
 
+// The number of external ports reserved between min and max (exclusive).
+func CountReservedPorts(min, max Port) (int, error) {
+	count := 0
+	for block := min / portsPerBlock; block*portsPerBlock < max; block++ {
+		parent, _ := (block * portsPerBlock).PortPathsFor()
+		f, err := os.Open(parent)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range namesToPorts(names) {
+			if p >= min && p < max {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
 func AtomicReserveExternalPorts(path string, ports, existing PortPairs) (PortPairs, error) {
 	reservations, errp := ports.reserve()
 	if errp != nil {
@@ -46,6 +74,28 @@ func AtomicReserveExternalPorts(path string, ports, existing PortPairs) (PortPai
 	return reserved, nil
 }
 
+// Reserve an external port for the unit at path, allocating a free port if
+// p is 0.  Returns ErrPortReserved if the requested port is in use, and an
+// ExhaustedError if no port is free.
+func ReserveExternalPort(path string, p Port) (Port, error) {
+	if p == 0 {
+		allocated, err := allocatePort()
+		if err != nil {
+			return 0, err
+		}
+		p = allocated
+	}
+	parent, direct := p.PortPathsFor()
+	os.MkdirAll(parent, 0770)
+	if err := os.Symlink(path, direct); err != nil {
+		if os.IsExist(err) {
+			return 0, ErrPortReserved
+		}
+		return 0, err
+	}
+	return p, nil
+}
+
 func ReleaseExternalPorts(ports PortPairs) error {
 	var err error
 	for i := range ports {
@@ -80,6 +130,62 @@ func ReleaseExternalPorts(ports PortPairs) error {
 	return err
 }
 
+// Point the reservations of ports at the unit definition at path, as when
+// the definition moves.  Returns the definition each port was reserved for
+// before (empty if it was not reserved) so the move can be undone with
+// RestoreExternalPorts.  On failure the ports already moved are restored.
+func MoveExternalPorts(ports PortPairs, path string) (map[Port]string, error) {
+	previous := make(map[Port]string)
+	for i := range ports {
+		p := ports[i].External
+		parent, direct := p.PortPathsFor()
+		target, err := os.Readlink(direct)
+		if err != nil && !os.IsNotExist(err) {
+			RestoreExternalPorts(previous)
+			return nil, err
+		}
+		os.MkdirAll(parent, 0770)
+		if err := replaceLink(path, direct); err != nil {
+			RestoreExternalPorts(previous)
+			return nil, err
+		}
+		previous[p] = target
+	}
+	return previous, nil
+}
+
+// Undo MoveExternalPorts, releasing ports that were not reserved before.
+func RestoreExternalPorts(previous map[Port]string) error {
+	var err error
+	for p, target := range previous {
+		_, direct := p.PortPathsFor()
+		if target == "" {
+			if errr := os.Remove(direct); errr != nil && !os.IsNotExist(errr) {
+				err = errr
+			}
+			continue
+		}
+		if errr := replaceLink(target, direct); errr != nil {
+			log.Printf("ports: Unable to restore the reservation of %d: %v", p, errr)
+			err = errr
+		}
+	}
+	return err
+}
+
+func replaceLink(target, link string) error {
+	tmp := link + ".move.tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 type portReservation struct {
 	PortPair
 	reserved  bool
@@ -174,10 +280,11 @@ func (p portReservations) reuse(existing PortPairs) (PortPairs, error) {
 	for i := range p {
 		res := &p[i]
 		if res.External == 0 {
-			res.External = allocatePort()
-			if res.External == 0 {
-				return unreserve, ErrAllocationFailed
+			allocated, err := allocatePort()
+			if err != nil {
+				return unreserve, err
 			}
+			res.External = allocated
 			res.reserved = true
 		}
 	}
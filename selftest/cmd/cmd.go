@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	gcmd "github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/selftest"
+)
+
+var minFreePorts int
+
+func RegisterSelfTest(parent *cobra.Command) {
+	selfTestCmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "(Local) Check that this host can run the gear agent",
+		Long:  "Check that systemd and the Docker socket are reachable, the data dir is writable, enough external ports are free, and the agent runs with the permissions it needs.  Prints whether each check passed and exits non-zero if any failed.",
+		Run:   selfTest,
+	}
+	selfTestCmd.Flags().IntVar(&minFreePorts, "min-free-ports", 100, "Fail if fewer external ports than this are free")
+	parent.AddCommand(selfTestCmd)
+}
+
+func selfTest(c *cobra.Command, args []string) {
+	if len(args) != 0 {
+		gcmd.Fail(1, "Valid arguments: (none)")
+	}
+	min, max := port.AllocatorRange()
+	report := selftest.Run(selftest.Checks(selftest.Options{
+		DockerSocket: c.Flags().Lookup("docker-socket").Value.String(),
+		PortMin:      min,
+		PortMax:      max,
+		MinFreePorts: minFreePorts,
+	}))
+	report.WriteTableTo(os.Stdout)
+	if report.Failures() > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,172 @@
+// Check that a host has what the gear agent needs to run containers, when
+// a host is being added, with the same functions the agent starts with.
+package selftest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/docker"
+	"github.com/openshift/geard/port"
+	"github.com/openshift/geard/systemd"
+)
+
+// Replaced by tests, which can't control systemd, Docker, or their user
+var (
+	startSystemd  = systemd.Start
+	connectDocker = func(socket string) error {
+		_, err := docker.GetConnection(socket)
+		return err
+	}
+	geteuid = os.Geteuid
+)
+
+// What the checks of a host are run against.
+type Options struct {
+	DockerSocket string
+	// The range external ports are allocated from, including Min but not Max
+	PortMin, PortMax port.Port
+	// Fail the port check if fewer ports than this are free in the range
+	MinFreePorts int
+}
+
+// A check of a host, which describes what it found or returns why the
+// host fails it.
+type Check struct {
+	Name string
+	Run  func() (string, error)
+}
+
+// The checks 'gear selftest' runs, in the order they are reported.
+func Checks(opts Options) []Check {
+	return []Check{
+		{"systemd", checkSystemd},
+		{"docker", func() (string, error) { return checkDocker(opts.DockerSocket) }},
+		{"data dir", checkDataDir},
+		{"ports", func() (string, error) { return checkPorts(opts.PortMin, opts.PortMax, opts.MinFreePorts) }},
+		{"permissions", checkPermissions},
+	}
+}
+
+type Result struct {
+	Name    string
+	Message string
+	Err     error
+}
+
+type Report []Result
+
+// Run each check, even if an earlier one failed.
+func Run(checks []Check) Report {
+	report := make(Report, 0, len(checks))
+	for i := range checks {
+		message, err := checks[i].Run()
+		report = append(report, Result{checks[i].Name, message, err})
+	}
+	return report
+}
+
+// The number of checks that failed
+func (r Report) Failures() int {
+	count := 0
+	for i := range r {
+		if r[i].Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Write a line per check with whether it passed, and a summary.
+func (r Report) WriteTableTo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for i := range r {
+		if r[i].Err != nil {
+			fmt.Fprintf(tw, "FAIL\t%s\t%s\n", r[i].Name, r[i].Err.Error())
+		} else {
+			fmt.Fprintf(tw, "PASS\t%s\t%s\n", r[i].Name, r[i].Message)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failures := r.Failures(); failures > 0 {
+		_, err := fmt.Fprintf(w, "\n%d of %d checks failed\n", failures, len(r))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nAll %d checks passed\n", len(r))
+	return err
+}
+
+func checkSystemd() (string, error) {
+	if err := startSystemd(); err != nil {
+		return "", fmt.Errorf("Unable to connect to systemd over dbus: %s", err.Error())
+	}
+	return "Connected to systemd over dbus", nil
+}
+
+func checkDocker(socket string) (string, error) {
+	if err := connectDocker(socket); err != nil {
+		return "", fmt.Errorf("Unable to reach Docker at %s: %s", socket, err.Error())
+	}
+	return "Reached Docker at " + socket, nil
+}
+
+// The directories the agent requires are created if they are missing, as
+// the agent does when it starts, and a file is written to the data dir.
+func checkDataDir() (string, error) {
+	base := config.ContainerBasePath()
+	if err := config.HasRequiredDirectories(); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(base)
+	if err != nil {
+		return "", fmt.Errorf("The data dir %s does not exist: %s", base, err.Error())
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("The data dir %s is not a directory", base)
+	}
+	file, err := ioutil.TempFile(base, ".selftest")
+	if err != nil {
+		return "", fmt.Errorf("The data dir %s is not writable: %s", base, err.Error())
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return base + " is writable", nil
+}
+
+func checkPorts(min, max port.Port, minFree int) (string, error) {
+	if max <= min {
+		return "", fmt.Errorf("The port range %d-%d is empty", min, max-1)
+	}
+	reserved, err := port.CountReservedPorts(min, max)
+	if err != nil {
+		return "", fmt.Errorf("Unable to count the reserved ports: %s", err.Error())
+	}
+	free := int(max-min) - reserved
+	if free < minFree {
+		return "", fmt.Errorf("Only %d of %d ports are free in %d-%d, at least %d are needed", free, int(max-min), min, max-1, minFree)
+	}
+	return fmt.Sprintf("%d of %d ports are free in %d-%d", free, int(max-min), min, max-1), nil
+}
+
+// The agent creates container users, changes the owner of their files, and
+// manages units, which needs root.  Environments may hold secrets, so the
+// data dir must not be writable by other users.
+func checkPermissions() (string, error) {
+	if uid := geteuid(); uid != 0 {
+		return "", fmt.Errorf("The agent must run as root to create container users and manage units, not as uid %d", uid)
+	}
+	// a missing data dir fails the data dir check
+	base := config.ContainerBasePath()
+	if info, err := os.Stat(base); err == nil {
+		if mode := info.Mode().Perm(); mode&0002 != 0 {
+			return "", fmt.Errorf("The data dir %s has mode %04o, which lets any user change it", base, mode)
+		}
+	}
+	return "Running as root and the data dir is not writable by others", nil
+}
@@ -0,0 +1,124 @@
+package selftest
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/port"
+)
+
+// Run the checks against a temporary data dir, with systemd and Docker
+// reachable and the agent running as root.
+func withHost(t *testing.T) (string, func()) {
+	base, err := ioutil.TempDir("", "geard-selftest")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(base)
+	systemd, docker, euid := startSystemd, connectDocker, geteuid
+	startSystemd = func() error { return nil }
+	connectDocker = func(string) error { return nil }
+	geteuid = func() int { return 0 }
+	return base, func() {
+		startSystemd, connectDocker, geteuid = systemd, docker, euid
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(base)
+	}
+}
+
+func testOptions() Options {
+	return Options{DockerSocket: "unix:///test/docker.sock", PortMin: 7000, PortMax: 7004, MinFreePorts: 2}
+}
+
+func failed(report Report) []string {
+	names := []string{}
+	for i := range report {
+		if report[i].Err != nil {
+			names = append(names, report[i].Name)
+		}
+	}
+	return names
+}
+
+func TestSelfTestPasses(t *testing.T) {
+	_, done := withHost(t)
+	defer done()
+
+	report := Run(Checks(testOptions()))
+	if report.Failures() != 0 {
+		t.Fatalf("Expected every check to pass, got %+v", report)
+	}
+	buf := &bytes.Buffer{}
+	report.WriteTableTo(buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 7 || !strings.HasPrefix(lines[0], "PASS  systemd") || lines[6] != "All 5 checks passed" {
+		t.Errorf("Expected a line per check, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "4 of 4 ports are free in 7000-7003") {
+		t.Errorf("Expected the free ports to be reported, got %q", buf.String())
+	}
+}
+
+func TestSelfTestFailures(t *testing.T) {
+	testCases := []struct {
+		check    string
+		simulate func(t *testing.T, base string)
+		message  string
+	}{
+		{"systemd", func(t *testing.T, base string) {
+			startSystemd = func() error { return errors.New("no such file or directory") }
+		}, "Unable to connect to systemd over dbus"},
+		{"docker", func(t *testing.T, base string) {
+			connectDocker = func(string) error { return errors.New("connection refused") }
+		}, "Unable to reach Docker at unix:///test/docker.sock: connection refused"},
+		{"data dir", func(t *testing.T, base string) {
+			config.SetContainerBasePath(filepath.Join(base, "missing"))
+		}, "does not exist"},
+		{"ports", func(t *testing.T, base string) {
+			unit := filepath.Join(base, "units", "fu", "full", "1")
+			for _, p := range []port.Port{7000, 7001, 7003} {
+				if _, err := port.ReserveExternalPort(unit, p); err != nil {
+					t.Fatalf("Unable to reserve port %d: %v", p, err)
+				}
+			}
+		}, "Only 1 of 4 ports are free in 7000-7003, at least 2 are needed"},
+		{"permissions", func(t *testing.T, base string) {
+			geteuid = func() int { return 1000 }
+		}, "must run as root"},
+		{"permissions", func(t *testing.T, base string) {
+			if err := os.Chmod(base, 0777); err != nil {
+				t.Fatalf("Unable to change the mode of the data dir: %v", err)
+			}
+		}, "has mode 0777"},
+	}
+	for _, tc := range testCases {
+		func() {
+			base, done := withHost(t)
+			defer done()
+			tc.simulate(t, base)
+
+			report := Run(Checks(testOptions()))
+			if names := failed(report); len(names) != 1 || names[0] != tc.check {
+				t.Errorf("Expected only the %s check to fail, got %v", tc.check, names)
+				return
+			}
+			for i := range report {
+				if report[i].Err != nil && !strings.Contains(report[i].Err.Error(), tc.message) {
+					t.Errorf("Expected the %s check to fail with %q, got %q", tc.check, tc.message, report[i].Err.Error())
+				}
+			}
+			buf := &bytes.Buffer{}
+			report.WriteTableTo(buf)
+			if !strings.Contains(buf.String(), "FAIL  "+tc.check) || !strings.HasSuffix(buf.String(), "1 of 5 checks failed\n") {
+				t.Errorf("Expected the failure to be reported, got %q", buf.String())
+			}
+		}()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"strings"
 
 	key "github.com/openshift/geard/pkg/ssh-public-key"
 	"github.com/openshift/geard/utils"
@@ -36,6 +37,18 @@ func (t authorizedKeyType) CreateKey(raw utils.RawMessage) (KeyLocator, error) {
 	return &SimpleKeyLocator{path, fingerprint.ToShortName()}, nil
 }
 
+// Return an error unless value is a single well-formed public key in the
+// authorized_keys format.
+func CheckAuthorizedKey(value string) error {
+	if strings.ContainsAny(strings.TrimRight(value, "\r\n"), "\r\n") {
+		return errors.New("Only one key may be given per line.")
+	}
+	if _, _, _, _, ok := key.ParseAuthorizedKey([]byte(value)); !ok {
+		return errors.New("The key is not a well-formed SSH public key (ssh-rsa, ssh-dss or ecdsa-sha2-*).")
+	}
+	return nil
+}
+
 func KeyFingerprint(key key.PublicKey) utils.Fingerprint {
 	bytes := sha256.Sum256(key.Marshal())
 	return utils.Fingerprint(bytes[:])
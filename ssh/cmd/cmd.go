@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"github.com/spf13/cobra"
 	"io/ioutil"
 	"os"
@@ -18,8 +19,10 @@ import (
 )
 
 var (
-	keyFile string
-	handler serializeContainerPermission
+	keyFile     string
+	pubkeyFiles StringList
+	replaceKeys bool
+	handler     serializeContainerPermission
 )
 
 // Implements the default container permission serialization
@@ -133,6 +136,85 @@ func (e *Command) addSshKeys(cmd *cobra.Command, args []string) {
 	}.StreamAndExit()
 }
 
+func (e *Command) RegisterAddKey(parent *cobra.Command) {
+	addKeyCmd := &cobra.Command{
+		Use:   "add-key <id>...",
+		Short: "Add public keys to the authorized_keys of containers",
+		Long:  "Add the public keys in one or more files to the authorized_keys of the user each container runs as.  The containers must have been installed with --isolate.",
+		Run:   e.addContainerKeys,
+	}
+	addKeyCmd.Flags().Var(&pubkeyFiles, "pubkey-file", "A file of public keys in the sshd AuthorizedKeysFile format (may be repeated)")
+	addKeyCmd.Flags().BoolVar(&replaceKeys, "replace", false, "Replace the keys of each container with the given keys")
+	parent.AddCommand(addKeyCmd)
+}
+
+func (e *Command) addContainerKeys(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		Fail(1, "Valid arguments: <id> ...")
+	}
+	if len(pubkeyFiles) == 0 {
+		Fail(1, "You must pass one or more public key files with --pubkey-file")
+	}
+
+	t := e.Transport.Get()
+
+	ids, err := NewContainerLocators(t, args...)
+	if err != nil {
+		Fail(1, "You must pass 1 or more valid names: %s", err.Error())
+	}
+
+	keys := []jobs.KeyData{}
+	for _, path := range pubkeyFiles {
+		read, err := readPublicKeyFile(path)
+		if err != nil {
+			Fail(1, "%s", err.Error())
+		}
+		keys = append(keys, read...)
+	}
+	if len(keys) == 0 {
+		Fail(1, "The files passed with --pubkey-file contain no keys")
+	}
+
+	Executor{
+		On: ids,
+		Serial: func(on Locator) JobRequest {
+			return &jobs.ContainerKeysRequest{
+				Id:      AsIdentifier(on),
+				Keys:    keys,
+				Replace: replaceKeys,
+			}
+		},
+		Output:    os.Stdout,
+		Transport: t,
+	}.StreamAndExit()
+}
+
+// Read the public keys in a file in the authorized_keys format, skipping
+// blank lines and comments.
+func readPublicKeyFile(path string) ([]jobs.KeyData, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the public key file: %s", err.Error())
+	}
+	keys := []jobs.KeyData{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		value := bytes.TrimSpace(scanner.Bytes())
+		if len(value) == 0 || value[0] == '#' {
+			continue
+		}
+		if err := ssh.CheckAuthorizedKey(string(value)); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err.Error())
+		}
+		key, err := jobs.NewKeyData("authorized_keys", string(value))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, scanner.Err()
+}
+
 func readAuthorizedKeysFile(keyFile string) ([]jobs.KeyData, error) {
 	var (
 		data []byte
@@ -45,6 +45,12 @@ func (c *containerAuthorizedKeys) GenerateAuthorizedKeysFile(user *user.User, fo
 	return generateAuthorizedKeys(id, user, forceCreate, printToStdOut)
 }
 
+// Write the authorized_keys file of the container from the keys that have
+// access to it, replacing any existing file.  The file is owned by u.
+func WriteAuthorizedKeys(id containers.Identifier, u *user.User) error {
+	return generateAuthorizedKeys(id, u, true, false)
+}
+
 // FIXME: Refactor into separate responsibilities for file creation, templating, and disk access
 func generateAuthorizedKeys(id containers.Identifier, u *user.User, forceCreate, printToStdOut bool) error {
 	var (
@@ -84,7 +90,7 @@ func generateAuthorizedKeys(id containers.Identifier, u *user.User, forceCreate,
 			}
 		}
 
-		if destFile, err = os.Create(authKeysPath); err != nil {
+		if destFile, err = os.OpenFile(authKeysPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
 			return err
 		}
 		defer destFile.Close()
@@ -5,6 +5,7 @@ import (
 	"github.com/openshift/go-json-rest"
 	"io"
 
+	"github.com/openshift/geard/containers"
 	"github.com/openshift/geard/http"
 	"github.com/openshift/geard/jobs"
 	sshjobs "github.com/openshift/geard/ssh/jobs"
@@ -15,6 +16,7 @@ type HttpExtension struct{}
 func (h *HttpExtension) Routes() []http.HttpJobHandler {
 	return []http.HttpJobHandler{
 		&HttpCreateKeysRequest{},
+		&HttpContainerKeysRequest{},
 	}
 }
 
@@ -22,6 +24,8 @@ func (h *HttpExtension) HttpJobFor(job interface{}) (exc http.RemoteExecutable,
 	switch j := job.(type) {
 	case *sshjobs.CreateKeysRequest:
 		exc = &HttpCreateKeysRequest{CreateKeysRequest: *j}
+	case *sshjobs.ContainerKeysRequest:
+		exc = &HttpContainerKeysRequest{ContainerKeysRequest: *j}
 	default:
 		err = jobs.ErrNoJobForRequest
 	}
@@ -52,3 +56,33 @@ func (h *HttpCreateKeysRequest) Handler(conf *http.HttpConfiguration) http.JobHa
 		}, nil
 	}
 }
+
+type HttpContainerKeysRequest struct {
+	sshjobs.ContainerKeysRequest
+	http.DefaultRequest
+}
+
+func (h *HttpContainerKeysRequest) HttpMethod() string { return "PUT" }
+func (h *HttpContainerKeysRequest) HttpPath() string {
+	return http.Inline("/container/:id/keys", string(h.Id))
+}
+func (h *HttpContainerKeysRequest) Handler(conf *http.HttpConfiguration) http.JobHandler {
+	return func(context *jobs.JobContext, r *rest.Request) (interface{}, error) {
+		id, errg := containers.NewIdentifier(r.PathParam("id"))
+		if errg != nil {
+			return nil, errg
+		}
+		data := &sshjobs.ContainerKeysRequest{}
+		if r.Body != nil {
+			dec := json.NewDecoder(io.LimitReader(r.Body, 100*1024))
+			if err := dec.Decode(data); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		data.Id = id
+		if err := data.Check(); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+}
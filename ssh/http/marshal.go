@@ -9,3 +9,8 @@ func (h *HttpCreateKeysRequest) MarshalHttpRequestBody(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	return encoder.Encode(h)
 }
+
+func (h *HttpContainerKeysRequest) MarshalHttpRequestBody(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(h.ContainerKeysRequest)
+}
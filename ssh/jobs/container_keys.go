@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	"github.com/openshift/geard/ssh"
+)
+
+// Looks up the user a container runs as.  Replaced in tests.
+var lookupUser = user.Lookup
+
+// Add public keys to the authorized_keys of the user a container runs as,
+// or replace the keys it has with them.
+type ContainerKeysRequest struct {
+	Id      containers.Identifier
+	Keys    []KeyData
+	Replace bool
+}
+
+func (r *ContainerKeysRequest) Check() error {
+	if r.Id == "" {
+		return errors.New("A container id must be specified.")
+	}
+	if len(r.Keys) == 0 {
+		return errors.New("One or more keys must be specified.")
+	}
+	for i := range r.Keys {
+		if r.Keys[i].Type != "authorized_keys" {
+			return errors.New(fmt.Sprintf("Key %d: only keys of type 'authorized_keys' may be added to a container.", i+1))
+		}
+		var value string
+		if err := json.Unmarshal(r.Keys[i].Value, &value); err != nil {
+			return errors.New(fmt.Sprintf("Key %d: the value must be a string in the authorized_keys format.", i+1))
+		}
+		if err := ssh.CheckAuthorizedKey(value); err != nil {
+			return errors.New(fmt.Sprintf("Key %d: %s", i+1, err.Error()))
+		}
+	}
+	return nil
+}
+
+func (r *ContainerKeysRequest) Execute(resp jobs.Response) {
+	id := r.Id
+	if _, err := os.Stat(id.UnitPathFor()); err != nil {
+		resp.Failure(jobs.NewNotFoundError("The container %s does not exist.", id))
+		return
+	}
+	u, err := lookupUser(id.LoginFor())
+	if err != nil {
+		resp.Failure(jobs.NewInvalidError("The container %s does not run as its own user, install it with --isolate to add keys.", id))
+		return
+	}
+
+	locators := make([]ssh.KeyLocator, 0, len(r.Keys))
+	for i := range r.Keys {
+		locator, err := r.Keys[i].Create()
+		if err != nil {
+			resp.Failure(jobs.NewInvalidError("Key %d: %s", i+1, err.Error()))
+			return
+		}
+		locators = append(locators, locator)
+	}
+
+	if r.Replace {
+		if err := ssh.RevokeContainerKeys(id); err != nil {
+			log.Printf("container_keys: Unable to remove the existing keys of %s: %v", id, err)
+			resp.Failure(jobs.SimpleError{jobs.ResponseError, "Unable to remove the existing keys of the container."})
+			return
+		}
+	}
+	permission, err := NewKeyPermission(ssh.ContainerPermissionType, string(id))
+	if err != nil {
+		resp.Failure(err)
+		return
+	}
+	for i := range locators {
+		if err := permission.Create(locators[i]); err != nil {
+			log.Printf("container_keys: Unable to give key %s access to %s: %v", locators[i].NameForKey(), id, err)
+			resp.Failure(jobs.SimpleError{jobs.ResponseError, "Unable to give the keys access to the container."})
+			return
+		}
+	}
+
+	if err := ssh.WriteAuthorizedKeys(id, u); err != nil {
+		log.Printf("container_keys: Unable to write the authorized_keys of %s: %v", id, err)
+		resp.Failure(jobs.SimpleError{jobs.ResponseError, "Unable to write the authorized_keys file of the container."})
+		return
+	}
+
+	w := resp.SuccessWithWrite(jobs.ResponseOk, true, false)
+	if r.Replace {
+		fmt.Fprintf(w, "Replaced the keys of %s with %d key(s)\n", id, len(locators))
+	} else {
+		fmt.Fprintf(w, "Added %d key(s) to %s\n", len(locators), id)
+	}
+}
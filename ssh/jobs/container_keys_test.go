@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/geard/cmd"
+	"github.com/openshift/geard/config"
+	"github.com/openshift/geard/containers"
+	"github.com/openshift/geard/jobs"
+	key "github.com/openshift/geard/pkg/ssh-public-key"
+)
+
+func withContainer(t *testing.T, id containers.Identifier) func() {
+	dir, err := ioutil.TempDir("", "geard-base")
+	if err != nil {
+		t.Fatalf("Unable to create a temporary directory: %v", err)
+	}
+	previous := config.ContainerBasePath()
+	config.SetContainerBasePath(dir)
+	lookup := lookupUser
+	lookupUser = func(string) (*user.User, error) { return user.Current() }
+
+	if err := os.MkdirAll(filepath.Dir(id.UnitPathFor()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(id.UnitPathFor(), []byte("[Unit]\nX-ContainerType=isolated\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		lookupUser = lookup
+		config.SetContainerBasePath(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func newAuthorizedKey(t *testing.T) string {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := key.NewPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(key.MarshalAuthorizedKey(pk)))
+}
+
+func addContainerKeys(t *testing.T, id containers.Identifier, replace bool, values ...string) error {
+	req := &ContainerKeysRequest{Id: id, Replace: replace}
+	for _, value := range values {
+		data, err := NewKeyData("authorized_keys", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Keys = append(req.Keys, *data)
+	}
+	if err := req.Check(); err != nil {
+		return err
+	}
+	resp := &cmd.CliJobResponse{Output: ioutil.Discard}
+	req.Execute(resp)
+	return resp.Error
+}
+
+func TestContainerKeys(t *testing.T) {
+	id := containers.Identifier("test-keys")
+	defer withContainer(t, id)()
+
+	first, second := newAuthorizedKey(t), newAuthorizedKey(t)
+	if err := addContainerKeys(t, id, false, first, second); err != nil {
+		t.Fatalf("Unexpected error adding keys: %v", err)
+	}
+
+	info, err := os.Stat(id.AuthKeysPathFor())
+	if err != nil {
+		t.Fatalf("Expected an authorized_keys file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected authorized_keys to have mode 0600, got %o", info.Mode().Perm())
+	}
+	data, _ := ioutil.ReadFile(id.AuthKeysPathFor())
+	for _, k := range []string{first, second} {
+		if !strings.Contains(string(data), k) {
+			t.Errorf("Expected authorized_keys to contain %q:\n%s", k, data)
+		}
+	}
+
+	third := newAuthorizedKey(t)
+	if err := addContainerKeys(t, id, true, third); err != nil {
+		t.Fatalf("Unexpected error replacing keys: %v", err)
+	}
+	data, _ = ioutil.ReadFile(id.AuthKeysPathFor())
+	if !strings.Contains(string(data), third) || strings.Contains(string(data), first) || strings.Contains(string(data), second) {
+		t.Errorf("Expected authorized_keys to only contain the new key:\n%s", data)
+	}
+	if info, err := os.Stat(id.AuthKeysPathFor()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the replaced authorized_keys to have mode 0600: %v", err)
+	}
+}
+
+func TestContainerKeysInvalid(t *testing.T) {
+	id := containers.Identifier("test-keys")
+	defer withContainer(t, id)()
+
+	if err := addContainerKeys(t, id, false, "ssh-rsa notakey"); err == nil {
+		t.Error("Expected a malformed key to be rejected")
+	}
+	if err := addContainerKeys(t, id, false); err == nil {
+		t.Error("Expected a request without keys to be rejected")
+	}
+	if _, err := os.Stat(id.AuthKeysPathFor()); !os.IsNotExist(err) {
+		t.Errorf("Expected no authorized_keys to be written: %v", err)
+	}
+
+	err := addContainerKeys(t, "test-missing", false, newAuthorizedKey(t))
+	if e, ok := err.(jobs.JobError); !ok || e.ResponseFailure() != jobs.ResponseNotFound {
+		t.Errorf("Expected a missing container to be not found, got %#v", err)
+	}
+}
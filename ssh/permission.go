@@ -55,6 +55,21 @@ func (c containerPermission) CreatePermission(locator KeyLocator, value *utils.R
 	return nil
 }
 
+// Remove the access of every key to the container.  The keys themselves
+// are kept, as other containers may use them.
+func RevokeContainerKeys(id containers.Identifier) error {
+	paths, err := filepath.Glob(filepath.Join(SshAccessBasePath(id), "*"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 func SshAccessBasePath(i containers.Identifier) string {
 	return utils.IsolateContentPathWithPerm(filepath.Join(config.ContainerBasePath(), "access", "containers", "ssh"), string(i), "", 0775)
 }
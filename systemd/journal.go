@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"os/exec"
+	"strconv"
 	"time"
 )
 
@@ -83,3 +84,88 @@ func WriteLogsTo(w io.Writer, unit string, previous int, until <-chan time.Time)
 
 	return err
 }
+
+// Which entries of a unit's journal to read.
+type JournalOptions struct {
+	Unit string
+	// Wait for new entries after the existing ones are read
+	Follow bool
+	// The number of most recent entries to show, or all if less than zero
+	Lines int
+	// Only show entries after this time, in any format journalctl accepts
+	Since string
+}
+
+func (o JournalOptions) Args() []string {
+	args := []string{"-q", "--unit", o.Unit}
+	if o.Follow {
+		args = append(args, "-f")
+	}
+	if o.Lines >= 0 {
+		args = append(args, "-n", strconv.Itoa(o.Lines))
+	} else {
+		args = append(args, "--no-tail")
+	}
+	if o.Since != "" {
+		args = append(args, "--since", o.Since)
+	}
+	return args
+}
+
+// Opens a stream of journal entries.  Closing the stream stops reading.
+type JournalReader interface {
+	ReadJournal(opts JournalOptions) (io.ReadCloser, error)
+}
+
+// Reads the journal with journalctl
+var Journal JournalReader = journalctl{}
+
+type journalctl struct{}
+
+func (journalctl) ReadJournal(opts JournalOptions) (io.ReadCloser, error) {
+	cmd := exec.Command("/usr/bin/journalctl", opts.Args()...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &journalProcess{stdout, cmd}, nil
+}
+
+type journalProcess struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (p *journalProcess) Close() error {
+	p.cmd.Process.Kill()
+	p.ReadCloser.Close()
+	p.cmd.Wait()
+	return nil
+}
+
+// Copy the journal entries selected by opts to w until they are all read
+// or stop is closed.
+func CopyJournal(w io.Writer, r JournalReader, opts JournalOptions, stop <-chan struct{}) error {
+	entries, err := r.ReadJournal(opts)
+	if err != nil {
+		return err
+	}
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, entries)
+		copied <- err
+	}()
+
+	select {
+	case err = <-copied:
+		entries.Close()
+		return err
+	case <-stop:
+		entries.Close()
+		<-copied
+		return nil
+	}
+}
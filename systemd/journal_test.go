@@ -0,0 +1,70 @@
+package systemd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeJournal struct {
+	opts    JournalOptions
+	entries io.ReadCloser
+}
+
+func (j *fakeJournal) ReadJournal(opts JournalOptions) (io.ReadCloser, error) {
+	j.opts = opts
+	return j.entries, nil
+}
+
+func TestJournalOptionsArgs(t *testing.T) {
+	args := JournalOptions{Unit: "geard.service", Follow: true, Lines: 20, Since: "1 hour ago"}.Args()
+	expected := []string{"-q", "--unit", "geard.service", "-f", "-n", "20", "--since", "1 hour ago"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	args = JournalOptions{Unit: "geard.service", Lines: -1}.Args()
+	expected = []string{"-q", "--unit", "geard.service", "--no-tail"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+}
+
+func TestCopyJournal(t *testing.T) {
+	journal := &fakeJournal{entries: ioutil.NopCloser(strings.NewReader("first entry\nsecond entry\n"))}
+	out := &bytes.Buffer{}
+	opts := JournalOptions{Unit: "geard.service", Lines: 10}
+	if err := CopyJournal(out, journal, opts, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if journal.opts != opts {
+		t.Errorf("Expected the options to be passed to the reader, got %+v", journal.opts)
+	}
+	if out.String() != "first entry\nsecond entry\n" {
+		t.Errorf("Expected the journal entries to be copied, got %q", out.String())
+	}
+}
+
+func TestCopyJournalStopsFollowing(t *testing.T) {
+	r, w := io.Pipe()
+	journal := &fakeJournal{entries: r}
+	stop := make(chan struct{})
+	out := &bytes.Buffer{}
+
+	done := make(chan error)
+	go func() {
+		done <- CopyJournal(out, journal, JournalOptions{Unit: "geard.service", Follow: true}, stop)
+	}()
+	w.Write([]byte("entry\n"))
+	close(stop)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != "entry\n" {
+		t.Errorf("Expected the entries read before stopping, got %q", out.String())
+	}
+}
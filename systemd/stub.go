@@ -70,10 +70,19 @@ func (c *StubSystemd) StartTransientUnit(name string, mode string, properties ..
 func (c *StubSystemd) KillUnit(name string, signal int32) {
 }
 
+func (c *StubSystemd) ResetFailedUnit(name string) error {
+	log.Print("stub_systemd: ResetFailedUnit", name)
+	return nil
+}
+
 func (c *StubSystemd) GetUnitProperties(unit string) (map[string]interface{}, error) {
 	return nil, errors.New("Not implemented")
 }
 
+func (c *StubSystemd) GetUnitTypeProperties(unit string, unitType string) (map[string]interface{}, error) {
+	return nil, errors.New("Not implemented")
+}
+
 func (c *StubSystemd) SetUnitProperties(unit string, runtime bool, properties ...dbus.Property) error {
 	return errors.New("Not implemented")
 }
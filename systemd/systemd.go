@@ -57,6 +57,7 @@ type Systemd interface {
 	StartTransientUnit(name string, mode string, properties ...dbus.Property) (string, error)
 	KillUnit(name string, signal int32)
 	GetUnitProperties(unit string) (map[string]interface{}, error)
+	GetUnitTypeProperties(unit string, unitType string) (map[string]interface{}, error)
 	SetUnitProperties(name string, runtime bool, properties ...dbus.Property) error
 	ListUnits() ([]dbus.UnitStatus, error)
 	EnableUnitFiles(files []string, runtime bool, force bool) (bool, []dbus.EnableUnitFileChange, error)
@@ -247,6 +248,39 @@ func WriteStatusTo(w io.Writer, unit string) error {
 	return nil
 }
 
+// A connection that can clear the failed state of a unit itself.  The dbus
+// connection can't, so ResetFailedUnit falls back to systemctl.
+type UnitFailureResetter interface {
+	ResetFailedUnit(name string) error
+}
+
+// Clear the failed state of a unit and its restart count, so that a unit
+// that hit its start limit can be started again.
+func ResetFailedUnit(systemd Systemd, name string) error {
+	if r, ok := systemd.(UnitFailureResetter); ok {
+		return r.ResetFailedUnit(name)
+	}
+	out, err := exec.Command("/usr/bin/systemctl", "reset-failed", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl reset-failed %s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// The number of times systemd has restarted a service, nil if it doesn't
+// report one.  NRestarts is a property of the Service interface rather
+// than the Unit one, and is only reported since systemd 235.
+func UnitRestarts(systemd Systemd, unit string) *uint32 {
+	props, err := systemd.GetUnitTypeProperties(unit, "Service")
+	if err != nil {
+		return nil
+	}
+	if restarts, ok := props["NRestarts"].(uint32); ok {
+		return &restarts
+	}
+	return nil
+}
+
 // Get the custom properties set in the unit file as a map.
 // TODO: Work with upstream to add an API for this.
 func GetUnitFileProperties(path string) (map[string]string, error) {
@@ -671,7 +671,7 @@ func (s *IntegrationTestSuite) TearDownSuite(c *chk.C) {
 	for _, id := range s.containerIds {
 		hostContainerId := fmt.Sprintf("%v/%v", s.daemonURI, id)
 
-		cmd := exec.Command("/usr/bin/gear", "delete", hostContainerId)
+		cmd := exec.Command("/usr/bin/gear", "delete", "--yes", hostContainerId)
 		data, err := cmd.CombinedOutput()
 		c.Log(string(data))
 		if err != nil {
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Written in place of a secret value in logs and errors
+const MaskedValue = "***"
+
+// Show secret values in logs and errors instead of masking them.  For
+// debugging only.
+var ShowSecretValues bool
+
+const (
+	// Shorter values are not masked, so that a trivial secret doesn't
+	// garble every line it happens to appear in
+	minSecretLength = 4
+	// The most values remembered, the oldest are forgotten first
+	maxSecretValues = 4096
+)
+
+var secrets = &secretValues{set: make(map[string]bool)}
+
+type secretValues struct {
+	lock  sync.RWMutex
+	set   map[string]bool
+	added []string
+	// The values longest first, so that a secret containing another is
+	// masked whole
+	longest []string
+}
+
+// Remember value as a secret, so that MaskSecrets hides it wherever it
+// appears.
+func AddSecretValue(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secrets.lock.Lock()
+	defer secrets.lock.Unlock()
+	if secrets.set[value] {
+		return
+	}
+	if len(secrets.added) >= maxSecretValues {
+		delete(secrets.set, secrets.added[0])
+		secrets.added = secrets.added[1:]
+	}
+	secrets.set[value] = true
+	secrets.added = append(secrets.added, value)
+	secrets.longest = append(secrets.longest[:0], secrets.added...)
+	sort.Sort(byLengthDescending(secrets.longest))
+}
+
+// The value, or MaskedValue unless ShowSecretValues is set.
+func MaskSecret(value string) string {
+	if ShowSecretValues {
+		return value
+	}
+	return MaskedValue
+}
+
+// Replace every secret value in s with MaskedValue, unless
+// ShowSecretValues is set.
+func MaskSecrets(s string) string {
+	if ShowSecretValues {
+		return s
+	}
+	secrets.lock.RLock()
+	defer secrets.lock.RUnlock()
+	for _, value := range secrets.longest {
+		if strings.Contains(s, value) {
+			s = strings.Replace(s, value, MaskedValue, -1)
+		}
+	}
+	return s
+}
+
+// A writer that masks secret values before writing to w.  Each write is
+// masked on its own, so a secret split across writes is not hidden - the
+// log package writes each line at once.
+func NewMaskingWriter(w io.Writer) io.Writer {
+	return maskingWriter{w}
+}
+
+type maskingWriter struct {
+	w io.Writer
+}
+
+func (m maskingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(m.w, MaskSecrets(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+type byLengthDescending []string
+
+func (s byLengthDescending) Len() int           { return len(s) }
+func (s byLengthDescending) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
+func (s byLengthDescending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }